@@ -61,6 +61,7 @@ const (
 	ErrInvalidMaxUploads
 	ErrInvalidMaxParts
 	ErrInvalidPartNumberMarker
+	ErrInvalidContinuationToken
 	ErrInvalidRequestBody
 	ErrInvalidCopySource
 	ErrInvalidCopyDest
@@ -102,6 +103,10 @@ const (
 	ErrInvalidQuerySignatureAlgo
 	ErrInvalidQueryParams
 	ErrBucketAlreadyOwnedByYou
+	ErrPreconditionFailed
+	ErrContentSHA256Mismatch
+	ErrSlowDown
+	ErrNoSuchVersion
 	// Add new error codes here.
 
 	// Minio extended errors.
@@ -109,6 +114,14 @@ const (
 	ErrWriteQuorum
 	ErrStorageFull
 	ErrObjectExistsAsDirectory
+	ErrKeyCollision
+	ErrQuotaExceeded
+	ErrManifestMismatch
+	ErrInvalidObjectRetention
+	ErrUnsupportedChecksumAlgorithm
+	ErrNotModified
+	ErrInvalidTag
+	ErrRangeNotDecodable
 )
 
 // error code to APIError structure, these fields carry respective
@@ -149,6 +162,11 @@ var errorCodeResponse = map[APIErrorCode]APIError{
 		Description:    "Argument partNumberMarker must be an integer.",
 		HTTPStatusCode: http.StatusBadRequest,
 	},
+	ErrInvalidContinuationToken: {
+		Code:           "InvalidArgument",
+		Description:    "The continuation token provided is incorrect.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
 	ErrInvalidPolicyDocument: {
 		Code:           "InvalidPolicyDocument",
 		Description:    "The content of the form does not meet the conditions specified in the policy document.",
@@ -394,6 +412,26 @@ var errorCodeResponse = map[APIErrorCode]APIError{
 		Description:    "Your previous request to create the named bucket succeeded and you already own it.",
 		HTTPStatusCode: http.StatusConflict,
 	},
+	ErrPreconditionFailed: {
+		Code:           "PreconditionFailed",
+		Description:    "At least one of the pre-conditions you specified did not hold",
+		HTTPStatusCode: http.StatusPreconditionFailed,
+	},
+	ErrContentSHA256Mismatch: {
+		Code:           "XAmzContentSHA256Mismatch",
+		Description:    "The provided 'x-amz-content-sha256' header does not match what was computed.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrSlowDown: {
+		Code:           "SlowDown",
+		Description:    "Please reduce your request rate.",
+		HTTPStatusCode: http.StatusServiceUnavailable,
+	},
+	ErrNoSuchVersion: {
+		Code:           "NoSuchVersion",
+		Description:    "The specified version does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
 	/// Minio extensions.
 	ErrStorageFull: {
 		Code:           "XMinioStorageFull",
@@ -415,6 +453,46 @@ var errorCodeResponse = map[APIErrorCode]APIError{
 		Description:    "Multiple disks failures, unable to write data.",
 		HTTPStatusCode: http.StatusServiceUnavailable,
 	},
+	ErrKeyCollision: {
+		Code:           "XMinioKeyCollision",
+		Description:    "Object name collides with an existing object on a case-insensitive backend.",
+		HTTPStatusCode: http.StatusConflict,
+	},
+	ErrQuotaExceeded: {
+		Code:           "XMinioQuotaExceeded",
+		Description:    "Bucket has reached its maximum number of objects.",
+		HTTPStatusCode: http.StatusForbidden,
+	},
+	ErrManifestMismatch: {
+		Code:           "XMinioManifestMismatch",
+		Description:    "One or more parts don't match the upload manifest.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidObjectRetention: {
+		Code:           "InvalidArgument",
+		Description:    "The retention mode or retain until date is invalid.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrUnsupportedChecksumAlgorithm: {
+		Code:           "InvalidArgument",
+		Description:    "The requested checksum algorithm is not supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrNotModified: {
+		Code:           "NotModified",
+		Description:    "The object was not modified since the ETag specified.",
+		HTTPStatusCode: http.StatusNotModified,
+	},
+	ErrInvalidTag: {
+		Code:           "InvalidTag",
+		Description:    "The tag set exceeds the allowed number of tags or tag key and value lengths.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrRangeNotDecodable: {
+		Code:           "InvalidRange",
+		Description:    "Ranges can't be requested from an object whose content encoding isn't accepted.",
+		HTTPStatusCode: http.StatusRequestedRangeNotSatisfiable,
+	},
 	// Add your error structure here.
 }
 
@@ -434,6 +512,10 @@ func toAPIErrorCode(err error) (apiErr APIErrorCode) {
 		apiErr = ErrStorageFull
 	case BadDigest:
 		apiErr = ErrBadDigest
+	case InvalidDigest:
+		apiErr = ErrInvalidDigest
+	case SHA256Mismatch:
+		apiErr = ErrContentSHA256Mismatch
 	case IncompleteBody:
 		apiErr = ErrIncompleteBody
 	case ObjectExistsAsDirectory:
@@ -442,7 +524,7 @@ func toAPIErrorCode(err error) (apiErr APIErrorCode) {
 		apiErr = ErrInvalidBucketName
 	case BucketNotFound:
 		apiErr = ErrNoSuchBucket
-	case BucketNotEmpty:
+	case BucketNotEmpty, BucketBusy:
 		apiErr = ErrBucketNotEmpty
 	case BucketExists:
 		apiErr = ErrBucketAlreadyOwnedByYou
@@ -450,16 +532,56 @@ func toAPIErrorCode(err error) (apiErr APIErrorCode) {
 		apiErr = ErrNoSuchKey
 	case ObjectNameInvalid:
 		apiErr = ErrNoSuchKey
+	case VersionNotFound:
+		apiErr = ErrNoSuchVersion
 	case InvalidUploadID:
 		apiErr = ErrNoSuchUpload
 	case InvalidPart:
 		apiErr = ErrInvalidPart
+	case InvalidPartOrder:
+		apiErr = ErrInvalidPartOrder
 	case InsufficientWriteQuorum:
 		apiErr = ErrWriteQuorum
 	case InsufficientReadQuorum:
 		apiErr = ErrReadQuorum
+	case SlowDown:
+		apiErr = ErrSlowDown
 	case PartTooSmall:
 		apiErr = ErrEntityTooSmall
+	case PartTooLarge:
+		apiErr = ErrEntityTooLarge
+	case ObjectTooLarge:
+		apiErr = ErrEntityTooLarge
+	case PrefixAccessDenied:
+		apiErr = ErrAccessDenied
+	case BucketRegionNotAllowed:
+		apiErr = ErrInvalidRegion
+	case KeyCollision:
+		apiErr = ErrKeyCollision
+	case PreconditionFailed:
+		apiErr = ErrPreconditionFailed
+	case ObjectAlreadyExists:
+		apiErr = ErrPreconditionFailed
+	case NotModified:
+		apiErr = ErrNotModified
+	case ObjectUnderLegalHold:
+		apiErr = ErrAccessDenied
+	case ObjectLocked:
+		apiErr = ErrAccessDenied
+	case InvalidObjectRetention:
+		apiErr = ErrInvalidObjectRetention
+	case InvalidObjectTags:
+		apiErr = ErrInvalidTag
+	case UnsupportedChecksumAlgorithm:
+		apiErr = ErrUnsupportedChecksumAlgorithm
+	case QuotaExceeded:
+		apiErr = ErrQuotaExceeded
+	case ManifestMismatch:
+		apiErr = ErrManifestMismatch
+	case InvalidRange:
+		apiErr = ErrInvalidRange
+	case InvalidContinuationToken:
+		apiErr = ErrInvalidContinuationToken
 	default:
 		apiErr = ErrInternalError
 	}
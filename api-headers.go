@@ -67,8 +67,11 @@ func setObjectHeaders(w http.ResponseWriter, objInfo ObjectInfo, contentRange *h
 	w.Header().Set("Last-Modified", lastModified)
 
 	w.Header().Set("Content-Type", objInfo.ContentType)
-	if objInfo.MD5Sum != "" {
-		w.Header().Set("ETag", "\""+objInfo.MD5Sum+"\"")
+	if objInfo.ContentEncoding != "" {
+		w.Header().Set("Content-Encoding", objInfo.ContentEncoding)
+	}
+	if etag := objInfo.ETag(); etag != "" {
+		w.Header().Set("ETag", etag)
 	}
 
 	w.Header().Set("Content-Length", strconv.FormatInt(objInfo.Size, 10))
@@ -276,9 +276,7 @@ func generateListObjectsResponse(bucket, prefix, marker, delimiter string, maxKe
 		}
 		content.Key = object.Name
 		content.LastModified = object.ModTime.UTC().Format(timeFormatAMZ)
-		if object.MD5Sum != "" {
-			content.ETag = "\"" + object.MD5Sum + "\""
-		}
+		content.ETag = object.ETag()
 		content.Size = object.Size
 		content.StorageClass = "STANDARD"
 		content.Owner = owner
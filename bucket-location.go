@@ -0,0 +1,129 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"regexp"
+	"sync"
+)
+
+// defaultBucketRegion - region of buckets made without a location.
+const defaultBucketRegion = "us-east-1"
+
+// validRegionName - lower case words separated by hyphens, as in
+// "us-east-1".
+var validRegionName = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// bucketRegions - regions buckets can be made in, only the default
+// region unless changed.
+type bucketRegions struct {
+	mutex   *sync.Mutex
+	regions map[string]bool
+}
+
+// newBucketRegions - initialize the allowed regions to the default
+// region.
+func newBucketRegions() *bucketRegions {
+	return &bucketRegions{
+		mutex:   &sync.Mutex{},
+		regions: map[string]bool{defaultBucketRegion: true},
+	}
+}
+
+// set - replaces the allowed regions, an empty list allows the default
+// region only. Fails with errInvalidArgument if a region isn't a valid
+// region name.
+func (r *bucketRegions) set(regions []string) error {
+	allowed := map[string]bool{defaultBucketRegion: len(regions) == 0}
+	for _, region := range regions {
+		if !validRegionName.MatchString(region) {
+			return errInvalidArgument
+		}
+		allowed[region] = true
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.regions = allowed
+	return nil
+}
+
+// isAllowed - reports if buckets can be made in region.
+func (r *bucketRegions) isAllowed(region string) bool {
+	// Object layers opened for recovery only know the default region.
+	if r == nil {
+		return region == defaultBucketRegion
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.regions[region]
+}
+
+// makeBucketWithLocation - common function to make a bucket in region
+// for both object layers, an empty region makes it in the default
+// region without saving it.
+func makeBucketWithLocation(storage StorageAPI, regions *bucketRegions, bucket, region string) error {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+	if region != "" && !regions.isAllowed(region) {
+		return BucketRegionNotAllowed{Bucket: bucket, Region: region}
+	}
+	if err := makeBucket(storage, bucket); err != nil {
+		return err
+	}
+	if region == "" {
+		return nil
+	}
+	err := updateBucketMetadata(storage, bucket, func(meta *bucketMetadata) {
+		meta.Region = region
+	})
+	if err != nil {
+		// A bucket in the wrong region is worse than none.
+		if derr := storage.DeleteVol(bucket); derr != nil {
+			errorIf(derr, "Unable to remove bucket "+bucket+" made without its location.", nil)
+		}
+		return toObjectErr(err, bucket)
+	}
+	return nil
+}
+
+// getBucketLocation - common function to fetch the region of a bucket
+// for both object layers, the default region if none was saved.
+func getBucketLocation(storage StorageAPI, bucket string) (string, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return "", BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(storage, bucket) {
+		return "", BucketNotFound{Bucket: bucket}
+	}
+	meta, err := readBucketMetadata(storage, bucket)
+	if err != nil {
+		return "", toObjectErr(err, bucket)
+	}
+	return meta.bucketRegion(), nil
+}
+
+// bucketRegion - region of the bucket, the default region if none was
+// saved.
+func (m bucketMetadata) bucketRegion() string {
+	if m.Region == "" {
+		return defaultBucketRegion
+	}
+	return m.Region
+}
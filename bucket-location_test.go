@@ -0,0 +1,94 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "testing"
+
+// Wrapper for calling bucket location tests for both XL multiple disks and single node setup.
+func TestBucketLocation(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testBucketLocation)
+}
+
+// Tests validate buckets keep the region they are made in, default to
+// us-east-1 and can only be made in the allowed regions.
+func testBucketLocation(obj ObjectLayer, instanceType string, t *testing.T) {
+	checkLocation := func(bucket, expected string) {
+		region, err := obj.GetBucketLocation(bucket)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		if region != expected {
+			t.Fatalf("%s: Expected %s to be in %s, got %s", instanceType, bucket, expected, region)
+		}
+		info, err := obj.GetBucketInfo(bucket)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		if info.Region != expected {
+			t.Fatalf("%s: Expected bucket info of %s to be in %s, got %s", instanceType, bucket, expected, info.Region)
+		}
+	}
+	if err := obj.MakeBucket("legacy"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	checkLocation("legacy", defaultBucketRegion)
+	if err := obj.MakeBucketWithLocation("default", ""); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	checkLocation("default", defaultBucketRegion)
+
+	// Only the default region is allowed unless configured.
+	if err := obj.MakeBucketWithLocation("west", "us-west-2"); err == nil {
+		t.Fatalf("%s: Expected BucketRegionNotAllowed", instanceType)
+	} else if _, ok := err.(BucketRegionNotAllowed); !ok {
+		t.Fatalf("%s: Expected BucketRegionNotAllowed, got %v", instanceType, err)
+	}
+	if exists, err := obj.BucketExists("west"); err != nil || exists {
+		t.Fatalf("%s: Expected no bucket made in a region not allowed, got %t, %v", instanceType, exists, err)
+	}
+	if err := obj.SetAllowedRegions([]string{"us-west-2", "US West"}); err != errInvalidArgument {
+		t.Fatalf("%s: Expected errInvalidArgument, got %v", instanceType, err)
+	}
+	if err := obj.SetAllowedRegions([]string{"us-west-2", "eu-central-1"}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err := obj.MakeBucketWithLocation("west", "us-west-2"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	checkLocation("west", "us-west-2")
+	if err := obj.MakeBucketWithLocation("typo", "us-wset-2"); err == nil {
+		t.Fatalf("%s: Expected BucketRegionNotAllowed for a typo", instanceType)
+	}
+
+	// Importing a configuration doesn't move a bucket.
+	config, err := obj.ExportBucketConfig("west")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err = obj.ImportBucketConfig("legacy", config); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	checkLocation("legacy", defaultBucketRegion)
+
+	if _, err = obj.GetBucketLocation("missing"); err == nil {
+		t.Fatalf("%s: Expected BucketNotFound", instanceType)
+	} else if _, ok := err.(BucketNotFound); !ok {
+		t.Fatalf("%s: Expected BucketNotFound, got %v", instanceType, err)
+	}
+}
@@ -0,0 +1,293 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"strings"
+)
+
+const (
+	// Bucket meta prefix, inside minioMetaBucket.
+	bucketMetaPrefix = "buckets"
+	// Bucket metadata file.
+	bucketMetaFile = "bucket.json"
+	// Schema version of exported bucket configuration.
+	bucketConfigExportVersion = "1"
+)
+
+// bucketMetadata - per bucket configuration, saved inside
+// minioMetaBucket at 'buckets/<bucket>/bucket.json'.
+type bucketMetadata struct {
+	Version string `json:"version"`
+
+	// Region - location the bucket was made in, the default region
+	// if empty, see bucket-location.go.
+	Region string `json:"region,omitempty"`
+
+	// AllowedPrefixes - if non-empty, only object names starting
+	// with one of these prefixes can be written or deleted.
+	AllowedPrefixes []string `json:"allowedPrefixes,omitempty"`
+
+	// Replication - if set, objects written to the bucket are
+	// replicated to a remote target.
+	Replication *bucketReplication `json:"replication,omitempty"`
+
+	// MaxObjects - if non-zero, new objects are rejected once the
+	// bucket holds this many objects.
+	MaxObjects int64 `json:"maxObjects,omitempty"`
+
+	// Dedup - if set, objects put with content identical to another
+	// object share its data, see object-dedup.go.
+	Dedup bool `json:"dedup,omitempty"`
+
+	// Versioning - if set, overwritten and deleted objects are kept
+	// as versions, see object-versioning.go.
+	Versioning bool `json:"versioning,omitempty"`
+}
+
+// readBucketMetadata - reads bucket metadata, returns an empty
+// metadata if none was saved for the bucket.
+func readBucketMetadata(storage StorageAPI, bucket string) (bucketMetadata, error) {
+	bucketMetaPath := path.Join(bucketMetaPrefix, bucket, bucketMetaFile)
+	offset := int64(0)
+	r, err := storage.ReadFile(minioMetaBucket, bucketMetaPath, offset)
+	if err != nil {
+		if err == errFileNotFound {
+			return bucketMetadata{Version: "1"}, nil
+		}
+		return bucketMetadata{}, err
+	}
+	defer r.Close()
+	var meta bucketMetadata
+	decoder := json.NewDecoder(r)
+	if err = decoder.Decode(&meta); err != nil {
+		return bucketMetadata{}, err
+	}
+	return meta, nil
+}
+
+// writeBucketMetadata - saves bucket metadata.
+func writeBucketMetadata(storage StorageAPI, bucket string, meta bucketMetadata) error {
+	bucketMetaPath := path.Join(bucketMetaPrefix, bucket, bucketMetaFile)
+	w, err := storage.CreateFile(minioMetaBucket, bucketMetaPath)
+	if err != nil {
+		return err
+	}
+	meta.Version = "1"
+	encoder := json.NewEncoder(w)
+	if err = encoder.Encode(&meta); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	if err = w.Close(); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	return nil
+}
+
+// updateBucketMetadata - reads bucket metadata, changes it with update
+// and saves it, serialized with other updates of the bucket metadata.
+// The lock is taken apart from the lock XL holds while the metadata
+// file itself is read or written.
+func updateBucketMetadata(storage StorageAPI, bucket string, update func(meta *bucketMetadata)) error {
+	lockPath := path.Join(bucketMetaPrefix, bucket)
+	nsMutex.Lock(minioMetaBucket, lockPath)
+	defer nsMutex.Unlock(minioMetaBucket, lockPath)
+
+	meta, err := readBucketMetadata(storage, bucket)
+	if err != nil {
+		return err
+	}
+	update(&meta)
+	return writeBucketMetadata(storage, bucket, meta)
+}
+
+// deleteBucketMetadata - removes saved bucket metadata if any.
+func deleteBucketMetadata(storage StorageAPI, bucket string) error {
+	bucketMetaPath := path.Join(bucketMetaPrefix, bucket, bucketMetaFile)
+	if err := storage.DeleteFile(minioMetaBucket, bucketMetaPath); err != nil && err != errFileNotFound {
+		return err
+	}
+	return nil
+}
+
+// isObjectPrefixAllowed - verifies if object name is allowed by the
+// bucket's allowed prefixes, an empty list allows everything.
+func (m bucketMetadata) isObjectPrefixAllowed(object string) bool {
+	if len(m.AllowedPrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range m.AllowedPrefixes {
+		if strings.HasPrefix(object, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkObjectPrefixAllowed - validates object name against the allowed
+// prefixes of its bucket metadata, returns PrefixAccessDenied if not
+// allowed.
+func checkObjectPrefixAllowed(meta bucketMetadata, bucket, object string) error {
+	if !meta.isObjectPrefixAllowed(object) {
+		return PrefixAccessDenied{Bucket: bucket, Object: object}
+	}
+	return nil
+}
+
+// setBucketAllowedPrefixes - common function to save allowed prefixes
+// for both object layers, an empty list removes the restriction.
+func setBucketAllowedPrefixes(storage StorageAPI, bucket string, prefixes []string) error {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(storage, bucket) {
+		return BucketNotFound{Bucket: bucket}
+	}
+	for _, prefix := range prefixes {
+		if !IsValidObjectPrefix(prefix) {
+			return ObjectNameInvalid{Bucket: bucket, Object: prefix}
+		}
+	}
+	err := updateBucketMetadata(storage, bucket, func(meta *bucketMetadata) {
+		meta.AllowedPrefixes = prefixes
+	})
+	if err != nil {
+		return toObjectErr(err, bucket)
+	}
+	return nil
+}
+
+// getBucketAllowedPrefixes - common function to fetch allowed prefixes
+// for both object layers.
+func getBucketAllowedPrefixes(storage StorageAPI, bucket string) ([]string, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(storage, bucket) {
+		return nil, BucketNotFound{Bucket: bucket}
+	}
+	meta, err := readBucketMetadata(storage, bucket)
+	if err != nil {
+		return nil, toObjectErr(err, bucket)
+	}
+	return meta.AllowedPrefixes, nil
+}
+
+// bucketConfigExport - portable bucket configuration, as returned by
+// ExportBucketConfig. New configuration fields are added to
+// bucketMetadata, Version is only bumped on incompatible changes.
+type bucketConfigExport struct {
+	Version string         `json:"version"`
+	Bucket  string         `json:"bucket"`
+	Config  bucketMetadata `json:"config"`
+}
+
+// validate - validates configuration values before they are saved,
+// the region must be one of regions.
+func (m bucketMetadata) validate(bucket string, regions *bucketRegions) error {
+	if m.Region != "" && !regions.isAllowed(m.Region) {
+		return InvalidBucketConfig{Bucket: bucket, Reason: "region not allowed " + m.Region}
+	}
+	for _, prefix := range m.AllowedPrefixes {
+		if !IsValidObjectPrefix(prefix) {
+			return InvalidBucketConfig{Bucket: bucket, Reason: "invalid allowed prefix " + prefix}
+		}
+	}
+	if m.MaxObjects < 0 {
+		return InvalidBucketConfig{Bucket: bucket, Reason: "negative object limit"}
+	}
+	if m.Replication != nil {
+		if m.Replication.Target == "" {
+			return InvalidBucketConfig{Bucket: bucket, Reason: "missing replication target"}
+		}
+		if !IsValidBucketName(m.Replication.TargetBucket) {
+			return InvalidBucketConfig{Bucket: bucket, Reason: "invalid replication target bucket " + m.Replication.TargetBucket}
+		}
+	}
+	return nil
+}
+
+// exportBucketConfig - common function to serialize the bucket
+// configuration for both object layers.
+func exportBucketConfig(storage StorageAPI, bucket string) ([]byte, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(storage, bucket) {
+		return nil, BucketNotFound{Bucket: bucket}
+	}
+	meta, err := readBucketMetadata(storage, bucket)
+	if err != nil {
+		return nil, toObjectErr(err, bucket)
+	}
+	return json.MarshalIndent(bucketConfigExport{
+		Version: bucketConfigExportVersion,
+		Bucket:  bucket,
+		Config:  meta,
+	}, "", "\t")
+}
+
+// importBucketConfig - common function to restore an exported bucket
+// configuration for both object layers, replaces the existing
+// configuration of the bucket. The exported region must be one of
+// regions, the bucket keeps its own.
+func importBucketConfig(storage StorageAPI, regions *bucketRegions, bucket string, data []byte) error {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(storage, bucket) {
+		return BucketNotFound{Bucket: bucket}
+	}
+	var export bucketConfigExport
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&export); err != nil {
+		return InvalidBucketConfig{Bucket: bucket, Reason: err.Error()}
+	}
+	if export.Version != bucketConfigExportVersion {
+		return InvalidBucketConfig{Bucket: bucket, Reason: "unsupported version " + export.Version}
+	}
+	if err := export.Config.validate(bucket, regions); err != nil {
+		return err
+	}
+	err := updateBucketMetadata(storage, bucket, func(meta *bucketMetadata) {
+		// The location of a bucket is fixed when it is made.
+		region := meta.Region
+		*meta = export.Config
+		meta.Region = region
+	})
+	if err != nil {
+		return toObjectErr(err, bucket)
+	}
+	// Objects are counted again against an imported object limit.
+	if err = deleteBucketUsage(storage, bucket); err != nil {
+		return toObjectErr(err, bucket)
+	}
+	return nil
+}
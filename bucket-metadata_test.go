@@ -0,0 +1,253 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// Wrapper for calling allowed prefixes tests for both XL multiple disks and single node setup.
+func TestBucketAllowedPrefixes(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testBucketAllowedPrefixes)
+}
+
+// Tests validate objects outside the allowed prefixes of a bucket can't
+// be written or deleted, objects inside them can.
+func testBucketAllowedPrefixes(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello, world")
+	if _, err := obj.PutObject(bucket, "other/object", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	prefixes := []string{"logs/", "tmp/"}
+	if err := obj.SetBucketAllowedPrefixes(bucket, prefixes); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	got, err := obj.GetBucketAllowedPrefixes(bucket)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if !reflect.DeepEqual(got, prefixes) {
+		t.Fatalf("%s: Expected %v, got %v", instanceType, prefixes, got)
+	}
+
+	if _, err = obj.PutObject(bucket, "logs/object", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	for _, object := range []string{"other/object", "log", "object"} {
+		if _, err = obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), nil); err == nil {
+			t.Fatalf("%s: %s: Expected PutObject to be denied", instanceType, object)
+		} else if _, ok := err.(PrefixAccessDenied); !ok {
+			t.Fatalf("%s: %s: Expected PrefixAccessDenied, got %v", instanceType, object, err)
+		}
+		if _, err = obj.NewMultipartUpload(bucket, object, nil); err == nil {
+			t.Fatalf("%s: %s: Expected NewMultipartUpload to be denied", instanceType, object)
+		} else if _, ok := err.(PrefixAccessDenied); !ok {
+			t.Fatalf("%s: %s: Expected PrefixAccessDenied, got %v", instanceType, object, err)
+		}
+	}
+	if err = obj.DeleteObject(bucket, "other/object"); err == nil {
+		t.Fatalf("%s: Expected DeleteObject to be denied", instanceType)
+	} else if _, ok := err.(PrefixAccessDenied); !ok {
+		t.Fatalf("%s: Expected PrefixAccessDenied, got %v", instanceType, err)
+	}
+
+	// Invalid prefixes are refused.
+	if err = obj.SetBucketAllowedPrefixes(bucket, []string{"../logs/"}); err == nil {
+		t.Fatalf("%s: Expected invalid prefix to be refused", instanceType)
+	}
+
+	// An empty list allows everything again.
+	if err = obj.SetBucketAllowedPrefixes(bucket, nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err = obj.DeleteObject(bucket, "other/object"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+}
+
+// Wrapper for calling concurrent bucket metadata update tests for both XL multiple disks and single node setup.
+func TestUpdateBucketMetadataConcurrent(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testUpdateBucketMetadataConcurrent)
+}
+
+// Tests validate bucket configuration set concurrently through
+// different setters is all kept.
+func testUpdateBucketMetadataConcurrent(obj ObjectLayer, instanceType string, t *testing.T) {
+	var storage StorageAPI
+	switch layer := obj.(type) {
+	case fsObjects:
+		storage = layer.storage
+	case xlObjects:
+		storage = layer.storage
+	}
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	setters := []func() error{
+		func() error { return obj.SetBucketAllowedPrefixes(bucket, []string{"logs/"}) },
+		func() error { return obj.SetBucketReplication(bucket, "dr", "replica") },
+		func() error { return obj.SetBucketObjectLimit(bucket, 10) },
+		func() error { return obj.SetBucketDedup(bucket, true) },
+		func() error { return obj.SetBucketVersioning(bucket, true) },
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(setters))
+	for i, setter := range setters {
+		wg.Add(1)
+		go func(i int, setter func() error) {
+			defer wg.Done()
+			errs[i] = setter()
+		}(i, setter)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("%s: setter %d: %s", instanceType, i, err)
+		}
+	}
+	meta, err := readBucketMetadata(storage, bucket)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	expected := bucketMetadata{
+		Version:         "1",
+		AllowedPrefixes: []string{"logs/"},
+		Replication:     &bucketReplication{Target: "dr", TargetBucket: "replica"},
+		MaxObjects:      10,
+		Dedup:           true,
+		Versioning:      true,
+	}
+	if !reflect.DeepEqual(meta, expected) {
+		t.Fatalf("%s: Expected %+v, got %+v", instanceType, expected, meta)
+	}
+}
+
+// Wrapper for calling bucket configuration import tests for both XL multiple disks and single node setup.
+func TestBucketConfigImport(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testBucketConfigImport)
+}
+
+// Tests validate an exported configuration imports into another bucket
+// as is, and invalid configurations are refused without changing the
+// configuration of the bucket.
+func testBucketConfigImport(obj ObjectLayer, instanceType string, t *testing.T) {
+	for _, bucket := range []string{"source", "dest"} {
+		if err := obj.MakeBucket(bucket); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	if err := obj.SetBucketAllowedPrefixes("source", []string{"logs/"}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err := obj.SetBucketReplication("source", "remote", "backup"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err := obj.SetBucketObjectLimit("source", 10); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	exportConfig := func(bucket string) bucketConfigExport {
+		data, err := obj.ExportBucketConfig(bucket)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		var export bucketConfigExport
+		if err = json.Unmarshal(data, &export); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		return export
+	}
+	source := exportConfig("source")
+	data, err := json.Marshal(source)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err = obj.ImportBucketConfig("dest", data); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if dest := exportConfig("dest"); !reflect.DeepEqual(dest.Config, source.Config) {
+		t.Fatalf("%s: Expected %+v, got %+v", instanceType, source.Config, dest.Config)
+	}
+
+	testCases := []struct {
+		version string
+		update  func(meta *bucketMetadata)
+	}{
+		{"2", func(meta *bucketMetadata) {}},
+		{bucketConfigExportVersion, func(meta *bucketMetadata) { meta.Region = "us-west-2" }},
+		{bucketConfigExportVersion, func(meta *bucketMetadata) { meta.AllowedPrefixes = []string{"logs|"} }},
+		{bucketConfigExportVersion, func(meta *bucketMetadata) { meta.MaxObjects = -1 }},
+		{bucketConfigExportVersion, func(meta *bucketMetadata) { meta.Replication.Target = "" }},
+		{bucketConfigExportVersion, func(meta *bucketMetadata) { meta.Replication.TargetBucket = "Invalid_Bucket" }},
+	}
+	for i, testCase := range testCases {
+		export := exportConfig("source")
+		export.Version = testCase.version
+		testCase.update(&export.Config)
+		data, err = json.Marshal(export)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		err = obj.ImportBucketConfig("dest", data)
+		if _, ok := err.(InvalidBucketConfig); !ok {
+			t.Fatalf("%s: Test %d: Expected InvalidBucketConfig, got %v", instanceType, i+1, err)
+		}
+		if dest := exportConfig("dest"); !reflect.DeepEqual(dest.Config, source.Config) {
+			t.Fatalf("%s: Test %d: Expected the configuration unchanged, got %+v", instanceType, i+1, dest.Config)
+		}
+	}
+
+	// Regions allowed on this server are accepted, the bucket stays in
+	// its own region.
+	if err = obj.SetAllowedRegions([]string{"us-west-2"}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	export := exportConfig("source")
+	export.Config.Region = "us-west-2"
+	if data, err = json.Marshal(export); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err = obj.ImportBucketConfig("dest", data); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if region, err := obj.GetBucketLocation("dest"); err != nil || region != defaultBucketRegion {
+		t.Fatalf("%s: Expected region %s, got %s, %v", instanceType, defaultBucketRegion, region, err)
+	}
+
+	if err = obj.ImportBucketConfig("dest", []byte("{")); err == nil {
+		t.Fatalf("%s: Expected an error for malformed configuration", instanceType)
+	}
+	if err = obj.ImportBucketConfig("missing", data); err == nil {
+		t.Fatalf("%s: Expected BucketNotFound", instanceType)
+	} else if _, ok := err.(BucketNotFound); !ok {
+		t.Fatalf("%s: Expected BucketNotFound, got %v", instanceType, err)
+	}
+}
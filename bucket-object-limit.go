@@ -0,0 +1,246 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"path"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Bucket usage file, next to bucketMetaFile. Only maintained for
+// buckets with an object limit.
+const bucketUsageFile = "usage.json"
+
+// bucketUsage - object count of a bucket, counted on first use after
+// the object limit is set and updated as objects are created and
+// deleted.
+type bucketUsage struct {
+	ObjectCount int64 `json:"objectCount"`
+}
+
+// lockBucketUsage - serializes updates of the bucket usage, returns
+// the function releasing the lock. Taken apart from the lock XL holds
+// while the usage file itself is read or written.
+func lockBucketUsage(bucket string) (unlock func()) {
+	lockPath := path.Join(objectLockPrefix, bucket)
+	nsMutex.Lock(minioMetaBucket, lockPath)
+	return func() {
+		nsMutex.Unlock(minioMetaBucket, lockPath)
+	}
+}
+
+// readBucketUsage - reads bucket usage, returns errFileNotFound if none
+// was saved for the bucket.
+func readBucketUsage(storage StorageAPI, bucket string) (bucketUsage, error) {
+	usagePath := path.Join(bucketMetaPrefix, bucket, bucketUsageFile)
+	r, err := storage.ReadFile(minioMetaBucket, usagePath, 0)
+	if err != nil {
+		return bucketUsage{}, err
+	}
+	defer r.Close()
+	var usage bucketUsage
+	decoder := json.NewDecoder(r)
+	if err = decoder.Decode(&usage); err != nil {
+		return bucketUsage{}, err
+	}
+	return usage, nil
+}
+
+// writeBucketUsage - saves bucket usage.
+func writeBucketUsage(storage StorageAPI, bucket string, usage bucketUsage) error {
+	usagePath := path.Join(bucketMetaPrefix, bucket, bucketUsageFile)
+	w, err := storage.CreateFile(minioMetaBucket, usagePath)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	if err = encoder.Encode(&usage); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	if err = w.Close(); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	return nil
+}
+
+// deleteBucketUsage - removes saved bucket usage if any.
+func deleteBucketUsage(storage StorageAPI, bucket string) error {
+	usagePath := path.Join(bucketMetaPrefix, bucket, bucketUsageFile)
+	if err := storage.DeleteFile(minioMetaBucket, usagePath); err != nil && err != errFileNotFound {
+		return err
+	}
+	return nil
+}
+
+// countBucketObjects - counts the objects of a bucket by walking it.
+func countBucketObjects(layer ObjectLayer, bucket string) (int64, error) {
+	var count int64
+	walker := startTreeWalk(layer, bucket, "", "", true)
+	for walkResult := range walker.ch {
+		if walkResult.err != nil {
+			// File not found is an empty bucket.
+			if walkResult.err == errFileNotFound {
+				return 0, nil
+			}
+			return 0, walkResult.err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// countBucketUsage - counts the objects of a bucket and saves the count
+// as its usage, unless the bucket was counted meanwhile. Only the save
+// is done under the usage lock.
+func countBucketUsage(layer ObjectLayer, storage StorageAPI, bucket string) error {
+	count, err := countBucketObjects(layer, bucket)
+	if err != nil {
+		return err
+	}
+	unlock := lockBucketUsage(bucket)
+	defer unlock()
+	if _, err = readBucketUsage(storage, bucket); err != errFileNotFound {
+		return err
+	}
+	return writeBucketUsage(storage, bucket, bucketUsage{ObjectCount: count})
+}
+
+// setBucketObjectLimit - common function to save the maximum number of
+// objects of a bucket for both object layers, zero removes the limit.
+func setBucketObjectLimit(layer ObjectLayer, storage StorageAPI, bucket string, maxObjects int64) error {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(storage, bucket) {
+		return BucketNotFound{Bucket: bucket}
+	}
+	if maxObjects < 0 {
+		return InvalidBucketConfig{Bucket: bucket, Reason: "negative object limit"}
+	}
+
+	unlock := lockBucketUsage(bucket)
+	defer unlock()
+
+	// Usage is only maintained while a limit is set, the saved count
+	// would be stale by now, objects are counted again on next use.
+	if err := deleteBucketUsage(storage, bucket); err != nil {
+		return toObjectErr(err, bucket)
+	}
+	err := updateBucketMetadata(storage, bucket, func(meta *bucketMetadata) {
+		meta.MaxObjects = maxObjects
+	})
+	if err != nil {
+		return toObjectErr(err, bucket)
+	}
+	return nil
+}
+
+// reserveObjectSlot - counts a new object against the object limit of
+// its bucket before the object is written, returns QuotaExceeded if the
+// bucket is full. Overwriting an existing object is always allowed.
+// The returned function gives the slot back, to be called if the write
+// fails.
+func reserveObjectSlot(layer ObjectLayer, storage StorageAPI, meta bucketMetadata, bucket, object string) (release func(), err error) {
+	release = func() {}
+	if meta.MaxObjects == 0 {
+		return release, nil
+	}
+	// Overwrites keep the slot of the object, prefixes take none.
+	if objInfo, err := layer.GetObjectInfo(bucket, object); err == nil {
+		if !objInfo.IsDir {
+			return release, nil
+		}
+	} else if _, ok := err.(ObjectNotFound); !ok {
+		return nil, err
+	}
+
+	if _, err = readBucketUsage(storage, bucket); err == errFileNotFound {
+		// Not counted since the limit was set, the bucket is walked
+		// without holding up writes waiting on the usage lock.
+		if err = countBucketUsage(layer, storage, bucket); err != nil {
+			return nil, toObjectErr(err, bucket)
+		}
+	} else if err != nil {
+		return nil, toObjectErr(err, bucket)
+	}
+
+	unlock := lockBucketUsage(bucket)
+	defer unlock()
+
+	usage, err := readBucketUsage(storage, bucket)
+	if err == errFileNotFound {
+		// Reset by a new limit meanwhile, the object is counted along
+		// with the others next time.
+		return release, nil
+	} else if err != nil {
+		return nil, toObjectErr(err, bucket)
+	}
+	if usage.ObjectCount >= meta.MaxObjects {
+		return nil, QuotaExceeded{Bucket: bucket, MaxObjects: meta.MaxObjects}
+	}
+	if err = updateBucketObjectCount(storage, bucket, 1); err != nil {
+		return nil, toObjectErr(err, bucket)
+	}
+	return func() {
+		unlock := lockBucketUsage(bucket)
+		defer unlock()
+		if err := updateBucketObjectCount(storage, bucket, -1); err != nil {
+			errorIf(err, "Unable to release object slot.", logrus.Fields{
+				"bucket": bucket,
+				"object": object,
+			})
+		}
+	}, nil
+}
+
+// releaseObjectSlot - removes a deleted object from the object count of
+// its bucket, if the bucket has an object limit.
+func releaseObjectSlot(storage StorageAPI, meta bucketMetadata, bucket string) error {
+	if meta.MaxObjects == 0 {
+		return nil
+	}
+	unlock := lockBucketUsage(bucket)
+	defer unlock()
+	return updateBucketObjectCount(storage, bucket, -1)
+}
+
+// updateBucketObjectCount - adds delta to the saved object count, must
+// be called with the bucket usage locked. Nothing to update if objects
+// weren't counted yet.
+func updateBucketObjectCount(storage StorageAPI, bucket string, delta int64) error {
+	usage, err := readBucketUsage(storage, bucket)
+	if err != nil {
+		if err == errFileNotFound {
+			return nil
+		}
+		return err
+	}
+	usage.ObjectCount += delta
+	if usage.ObjectCount < 0 {
+		usage.ObjectCount = 0
+	}
+	return writeBucketUsage(storage, bucket, usage)
+}
@@ -0,0 +1,76 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Wrapper for calling bucket object limit tests for both XL multiple disks and single node setup.
+func TestBucketObjectLimit(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testBucketObjectLimit)
+}
+
+// Tests validate that new objects are rejected once the bucket is full,
+// while overwrites are allowed and deletes free up slots.
+func testBucketObjectLimit(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello")
+	put := func(object string) error {
+		_, err := obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), nil)
+		return err
+	}
+	// Objects written before the limit count against it.
+	if err := put("a"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err := obj.SetBucketObjectLimit(bucket, 2); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err := put("dir/b"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err := put("c"); err == nil {
+		t.Fatalf("%s: Expected QuotaExceeded", instanceType)
+	} else if _, ok := err.(QuotaExceeded); !ok {
+		t.Fatalf("%s: Expected QuotaExceeded, got %v", instanceType, err)
+	}
+	// Overwriting an existing object is allowed.
+	if err := put("a"); err != nil {
+		t.Fatalf("%s: Overwrite failed with %s", instanceType, err.Error())
+	}
+	// A delete makes room for a new object.
+	if err := obj.DeleteObject(bucket, "a"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err := put("c"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	// Removing the limit allows new objects again.
+	if err := obj.SetBucketObjectLimit(bucket, 0); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err := put("d"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+}
@@ -0,0 +1,365 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Bucket stats file, next to bucketMetaFile. Only maintained for
+// buckets whose stats were asked for.
+const bucketStatsFile = "stats.json"
+
+// Saved bucket stats older than this are rebuilt by walking the bucket,
+// correcting any drift such as from updates lost to a crash.
+var bucketStatsMaxAge = 24 * time.Hour
+
+// BucketStats - number of objects of a bucket and their total size.
+type BucketStats struct {
+	Bucket      string
+	ObjectCount int64
+	TotalSize   int64
+	// Time the bucket was last walked to count its objects, kept up
+	// to date by object writes and deletes since.
+	Scanned time.Time
+}
+
+// bucketStats - bucket stats as saved.
+type bucketStats struct {
+	ObjectCount int64     `json:"objectCount"`
+	TotalSize   int64     `json:"totalSize"`
+	Scanned     time.Time `json:"scanned"`
+}
+
+// readBucketStats - reads bucket stats, returns errFileNotFound if none
+// were saved for the bucket.
+func readBucketStats(storage StorageAPI, bucket string) (bucketStats, error) {
+	statsPath := path.Join(bucketMetaPrefix, bucket, bucketStatsFile)
+	r, err := storage.ReadFile(minioMetaBucket, statsPath, 0)
+	if err != nil {
+		return bucketStats{}, err
+	}
+	defer r.Close()
+	var stats bucketStats
+	decoder := json.NewDecoder(r)
+	if err = decoder.Decode(&stats); err != nil {
+		return bucketStats{}, err
+	}
+	return stats, nil
+}
+
+// writeBucketStats - saves bucket stats.
+func writeBucketStats(storage StorageAPI, bucket string, stats bucketStats) error {
+	statsPath := path.Join(bucketMetaPrefix, bucket, bucketStatsFile)
+	w, err := storage.CreateFile(minioMetaBucket, statsPath)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	if err = encoder.Encode(&stats); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	if err = w.Close(); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	return nil
+}
+
+// deleteBucketStats - removes saved bucket stats if any.
+func deleteBucketStats(storage StorageAPI, bucket string) error {
+	statsPath := path.Join(bucketMetaPrefix, bucket, bucketStatsFile)
+	if err := storage.DeleteFile(minioMetaBucket, statsPath); err != nil && err != errFileNotFound {
+		return err
+	}
+	return nil
+}
+
+// scanBucketStats - counts the objects of a bucket and their size by
+// walking it.
+func scanBucketStats(layer ObjectLayer, storage StorageAPI, bucket string) (bucketStats, error) {
+	stats := bucketStats{Scanned: time.Now().UTC()}
+	walker := startTreeWalk(layer, bucket, "", "", true)
+	for walkResult := range walker.ch {
+		if walkResult.err != nil {
+			// File not found is an empty bucket.
+			if walkResult.err == errFileNotFound {
+				return stats, nil
+			}
+			return bucketStats{}, walkResult.err
+		}
+		// Compressed and encrypted objects are counted by the size
+		// of their content.
+		objInfo := ObjectInfo{Name: walkResult.fileInfo.Name, Size: walkResult.fileInfo.Size}
+		objInfo, err := addObjectMetadata(storage, nil, bucket, objInfo, walkResult.fileInfo.MD5Sum)
+		if err != nil {
+			return bucketStats{}, err
+		}
+		stats.ObjectCount++
+		stats.TotalSize += objInfo.Size
+	}
+	return stats, nil
+}
+
+// getBucketStats - common function to get the stats of a bucket for
+// both object layers. Stats are counted by walking the bucket on first
+// use and once older than bucketStatsMaxAge, updated as objects are
+// written and deleted in between, see bucketStatsTracker.
+func getBucketStats(layer ObjectLayer, storage StorageAPI, tracker *bucketStatsTracker, bucket string) (BucketStats, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketStats{}, BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(storage, bucket) {
+		return BucketStats{}, BucketNotFound{Bucket: bucket}
+	}
+
+	unlock := lockBucketUsage(bucket)
+	defer unlock()
+
+	// Changes not saved yet are added first, a scan counts them anyway.
+	delta := tracker.takePending(bucket)
+	stats, err := readBucketStats(storage, bucket)
+	if err == errFileNotFound || (err == nil && time.Since(stats.Scanned) > bucketStatsMaxAge) {
+		stats, err = scanBucketStats(layer, storage, bucket)
+		if err == nil {
+			err = writeBucketStats(storage, bucket, stats)
+		}
+	} else if err == nil && delta != (bucketStatsDelta{}) {
+		stats = stats.add(delta)
+		err = writeBucketStats(storage, bucket, stats)
+	}
+	if err != nil {
+		return BucketStats{}, toObjectErr(err, bucket)
+	}
+	tracker.setTracked(bucket)
+	return BucketStats{
+		Bucket:      bucket,
+		ObjectCount: stats.ObjectCount,
+		TotalSize:   stats.TotalSize,
+		Scanned:     stats.Scanned,
+	}, nil
+}
+
+// bucketStatsDelta - changes of the object count and total size of a
+// bucket.
+type bucketStatsDelta struct {
+	count int64
+	size  int64
+}
+
+// add - returns the stats with delta added.
+func (stats bucketStats) add(delta bucketStatsDelta) bucketStats {
+	stats.ObjectCount += delta.count
+	stats.TotalSize += delta.size
+	if stats.ObjectCount < 0 {
+		stats.ObjectCount = 0
+	}
+	if stats.TotalSize < 0 {
+		stats.TotalSize = 0
+	}
+	return stats
+}
+
+// updateBucketStats - adds delta to the saved bucket stats. Nothing to
+// update if the stats weren't counted yet.
+func updateBucketStats(storage StorageAPI, bucket string, delta bucketStatsDelta) error {
+	unlock := lockBucketUsage(bucket)
+	defer unlock()
+
+	stats, err := readBucketStats(storage, bucket)
+	if err != nil {
+		if err == errFileNotFound {
+			return nil
+		}
+		return err
+	}
+	return writeBucketStats(storage, bucket, stats.add(delta))
+}
+
+// Interval at which object changes are added to the saved stats of
+// their buckets.
+var bucketStatsFlushInterval = 10 * time.Second
+
+// bucketStatsTracker - keeps the saved stats of buckets up to date as
+// objects are written and deleted. Whether the stats of a bucket are
+// tracked is only looked up on first use, changes are summed up in
+// memory and saved every bucketStatsFlushInterval, on the next
+// getBucketStats and on shutdown. Changes not saved by a crash are
+// corrected by the next scan.
+type bucketStatsTracker struct {
+	storage StorageAPI
+	mutex   *sync.Mutex
+	// Buckets whose stats file was looked up, true if it exists.
+	tracked map[string]bool
+	pending map[string]bucketStatsDelta
+}
+
+// newBucketStatsTracker - initialize a tracker of the stats saved on
+// storage.
+func newBucketStatsTracker(storage StorageAPI) *bucketStatsTracker {
+	return &bucketStatsTracker{
+		storage: storage,
+		mutex:   &sync.Mutex{},
+		tracked: make(map[string]bool),
+		pending: make(map[string]bucketStatsDelta),
+	}
+}
+
+// isTracked - returns true if the stats of a bucket were counted and
+// have to be kept up to date.
+func (t *bucketStatsTracker) isTracked(bucket string) bool {
+	t.mutex.Lock()
+	tracked, ok := t.tracked[bucket]
+	t.mutex.Unlock()
+	if ok {
+		return tracked
+	}
+	statsPath := path.Join(bucketMetaPrefix, bucket, bucketStatsFile)
+	_, err := t.storage.StatFile(minioMetaBucket, statsPath)
+	tracked = err == nil
+	if err != nil && err != errFileNotFound {
+		// Looked up again next time.
+		return false
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	// Stats counted meanwhile win.
+	if _, ok = t.tracked[bucket]; !ok {
+		t.tracked[bucket] = tracked
+	}
+	return tracked
+}
+
+// setTracked - marks the stats of a bucket as counted.
+func (t *bucketStatsTracker) setTracked(bucket string) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.tracked[bucket] = true
+}
+
+// forget - drops what is known of a bucket, called once it is deleted.
+func (t *bucketStatsTracker) forget(bucket string) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.tracked, bucket)
+	delete(t.pending, bucket)
+}
+
+// takePending - returns the changes of a bucket not saved yet, they
+// are no longer pending.
+func (t *bucketStatsTracker) takePending(bucket string) bucketStatsDelta {
+	if t == nil {
+		return bucketStatsDelta{}
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delta := t.pending[bucket]
+	delete(t.pending, bucket)
+	return delta
+}
+
+// add - adds a change of a bucket to the pending changes.
+func (t *bucketStatsTracker) add(bucket string, delta bucketStatsDelta) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	pending := t.pending[bucket]
+	pending.count += delta.count
+	pending.size += delta.size
+	t.pending[bucket] = pending
+}
+
+// track - records the size of an object about to be written or
+// deleted, returns the function adding the change to the pending
+// changes of the bucket once done. Overwrites count their size
+// difference only. Objects of buckets whose stats aren't tracked
+// aren't looked at.
+func (t *bucketStatsTracker) track(layer ObjectLayer, bucket, object string) (done func()) {
+	if t == nil || !t.isTracked(bucket) {
+		return func() {}
+	}
+	objectSize := func() (int64, bool) {
+		objInfo, err := layer.GetObjectInfo(bucket, object)
+		if err != nil || objInfo.IsDir {
+			return 0, false
+		}
+		return objInfo.Size, true
+	}
+	oldSize, existed := objectSize()
+	return func() {
+		newSize, exists := objectSize()
+		delta := bucketStatsDelta{size: newSize - oldSize}
+		if exists && !existed {
+			delta.count = 1
+		} else if !exists && existed {
+			delta.count = -1
+		}
+		if delta != (bucketStatsDelta{}) {
+			t.add(bucket, delta)
+		}
+	}
+}
+
+// flush - saves the pending changes of all buckets. Stats are repaired
+// by the next scan if saving them fails, so errors are only logged.
+func (t *bucketStatsTracker) flush() {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	pending := t.pending
+	t.pending = make(map[string]bucketStatsDelta)
+	t.mutex.Unlock()
+	for bucket, delta := range pending {
+		if err := updateBucketStats(t.storage, bucket, delta); err != nil {
+			errorIf(err, "Unable to update bucket stats.", logrus.Fields{
+				"bucket": bucket,
+			})
+		}
+	}
+}
+
+// run - saves the pending changes every bucketStatsFlushInterval,
+// until doneCh is closed.
+func (t *bucketStatsTracker) run(doneCh <-chan struct{}) {
+	ticker := time.NewTicker(bucketStatsFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-doneCh:
+			return
+		case <-ticker.C:
+		}
+		t.flush()
+	}
+}
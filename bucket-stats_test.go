@@ -0,0 +1,142 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"path"
+	"testing"
+	"time"
+)
+
+// Wrapper for calling bucket stats tests for both XL multiple disks and single node setup.
+func TestGetBucketStats(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testGetBucketStats)
+}
+
+// Tests validate bucket stats are counted on first use, kept up to
+// date by writes, overwrites, multipart uploads and deletes, and
+// rebuilt once missing or too old.
+func testGetBucketStats(obj ObjectLayer, instanceType string, t *testing.T) {
+	var storage StorageAPI
+	var tracker *bucketStatsTracker
+	switch l := obj.(type) {
+	case xlObjects:
+		storage, tracker = l.storage, l.bucketStats
+	case fsObjects:
+		storage, tracker = l.storage, l.bucketStats
+	}
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	putObject := func(object string, size int, metadata map[string]string) {
+		if _, err := obj.PutObject(bucket, object, int64(size), bytes.NewReader(bytes.Repeat([]byte("a"), size)), metadata); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	checkStats := func(step string, objectCount, totalSize int64) {
+		stats, err := obj.GetBucketStats(bucket)
+		if err != nil {
+			t.Fatalf("%s: %s: %s", instanceType, step, err.Error())
+		}
+		if stats.ObjectCount != objectCount || stats.TotalSize != totalSize {
+			t.Fatalf("%s: %s: Expected %d objects of %d bytes, got %d objects of %d bytes", instanceType, step, objectCount, totalSize, stats.ObjectCount, stats.TotalSize)
+		}
+	}
+
+	// Objects written before the first use are counted by a walk.
+	putObject("a", 10, nil)
+	putObject("dir/b", 20, nil)
+	// Counted by its content size, not its compressed size.
+	putObject("compressed", 100000, map[string]string{compressKey: "true"})
+	checkStats("Scan", 3, 100030)
+
+	putObject("c", 5, nil)
+	checkStats("Put", 4, 100035)
+	putObject("a", 25, nil)
+	checkStats("Overwrite", 4, 100050)
+
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var parts []completePart
+	for i, size := range []int{5 * 1024 * 1024, 100} {
+		var md5Sum string
+		md5Sum, err = obj.PutObjectPart(bucket, "multipart", uploadID, i+1, int64(size), bytes.NewReader(bytes.Repeat([]byte("m"), size)), "")
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		parts = append(parts, completePart{PartNumber: i + 1, ETag: md5Sum})
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, "multipart", uploadID, parts); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	multipartSize := int64(5*1024*1024 + 100)
+	checkStats("Multipart", 5, 100050+multipartSize)
+
+	if err = obj.DeleteObject(bucket, "dir/b"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	// Deleting a missing object changes nothing.
+	if err = obj.DeleteObject(bucket, "missing"); err == nil {
+		t.Fatalf("%s: Expected deleting a missing object to fail", instanceType)
+	}
+	checkStats("Delete", 4, 100030+multipartSize)
+
+	// Changes are saved in the background rather than by every write.
+	saved, err := readBucketStats(storage, bucket)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	putObject("d", 7, nil)
+	if stats, err := readBucketStats(storage, bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	} else if stats != saved {
+		t.Fatalf("%s: Expected saved stats %v before a flush, got %v", instanceType, saved, stats)
+	}
+	tracker.flush()
+	if stats, err := readBucketStats(storage, bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	} else if stats.ObjectCount != saved.ObjectCount+1 || stats.TotalSize != saved.TotalSize+7 {
+		t.Fatalf("%s: Expected %d objects of %d bytes saved, got %v", instanceType, saved.ObjectCount+1, saved.TotalSize+7, stats)
+	}
+	if err = obj.DeleteObject(bucket, "d"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	checkStats("Flush", 4, 100030+multipartSize)
+
+	// Rebuilt if lost or too old.
+	statsPath := path.Join(bucketMetaPrefix, bucket, bucketStatsFile)
+	if err = storage.DeleteFile(minioMetaBucket, statsPath); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	checkStats("Rescan", 4, 100030+multipartSize)
+	if err = writeBucketStats(storage, bucket, bucketStats{ObjectCount: 1, TotalSize: 1, Scanned: time.Now().UTC().Add(-2 * bucketStatsMaxAge)}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	checkStats("Stale", 4, 100030+multipartSize)
+
+	if _, err = obj.GetBucketStats("missing-bucket"); err == nil {
+		t.Fatalf("%s: Expected BucketNotFound", instanceType)
+	} else if _, ok := err.(BucketNotFound); !ok {
+		t.Fatalf("%s: Expected BucketNotFound, got %v", instanceType, err)
+	}
+}
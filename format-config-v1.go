@@ -19,8 +19,15 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/skyrings/skyring-common/tools/uuid"
 )
 
+// Disk identity marker file, saved on each disk inside minioMetaBucket.
+const diskIDFile = "disk.json"
+
 type fsFormat struct {
 	Version string `json:"version"`
 }
@@ -28,6 +35,23 @@ type fsFormat struct {
 type xlFormat struct {
 	Version string   `json:"version"`
 	Disks   []string `json:"disks"`
+	// DiskIDs - identities of the physical disks, in the same order
+	// as Disks. Empty for backends formatted without identities.
+	DiskIDs []string `json:"diskIds,omitempty"`
+	// DataBlocks, ParityBlocks - erasure split chosen at format time.
+	// Zero for backends formatted with the default N/2 split.
+	DataBlocks   int `json:"dataBlocks,omitempty"`
+	ParityBlocks int `json:"parityBlocks,omitempty"`
+}
+
+// diskIDMarker - identity marker written to each disk at format time.
+type diskIDMarker struct {
+	Version string `json:"version"`
+	ID      string `json:"id"`
+	// Set - identities of all disks in format order, lets the disks
+	// be put back in order before format.json can be read. Empty for
+	// disks marked before disks could be reordered.
+	Set []string `json:"set,omitempty"`
 }
 
 type formatConfigV1 struct {
@@ -39,28 +63,38 @@ type formatConfigV1 struct {
 
 // FIXME: currently we don't check single exportPath which uses FS layer.
 
-// loadFormatXL - load XL format.json.
-func loadFormatXL(storage StorageAPI) (xl *xlFormat, err error) {
+// loadFormatXL - load XL format.json, older versions are migrated
+// reporting to logger.
+func loadFormatXL(logger Logger, storage StorageAPI) (xl *xlFormat, err error) {
 	offset := int64(0)
 	r, err := storage.ReadFile(minioMetaBucket, formatConfigFile, offset)
 	if err != nil {
 		return nil, err
 	}
-	decoder := json.NewDecoder(r)
-	formatXL := formatConfigV1{}
-	err = decoder.Decode(&formatXL)
+	formatData, err := ioutil.ReadAll(r)
 	if err != nil {
+		r.Close()
 		return nil, err
 	}
 	if err = r.Close(); err != nil {
 		return nil, err
 	}
+	formatXL := formatConfigV1{}
+	if err = json.Unmarshal(formatData, &formatXL); err != nil {
+		return nil, err
+	}
 	if formatXL.Version != "1" {
 		return nil, fmt.Errorf("Unsupported version of backend format [%s] found.", formatXL.Version)
 	}
 	if formatXL.Format != "xl" {
 		return nil, fmt.Errorf("Unsupported backend format [%s] found.", formatXL.Format)
 	}
+	if formatXL.XL != nil && formatXL.XL.Version != formatXLVersion {
+		// Upgrade older versions before anyone looks at them.
+		if err = migrateFormatXL(logger, storage, formatData, formatXL.XL); err != nil {
+			return nil, err
+		}
+	}
 	return formatXL.XL, nil
 }
 
@@ -100,3 +134,257 @@ func saveFormatXL(storage StorageAPI, xl *xlFormat) error {
 	}
 	return nil
 }
+
+// writeDiskIDs - generates and saves a new identity marker on each
+// disk, returns the identities in disk order.
+func writeDiskIDs(disks []StorageAPI) ([]string, error) {
+	diskIDs := make([]string, len(disks))
+	for index := range disks {
+		id, err := uuid.New()
+		if err != nil {
+			return nil, err
+		}
+		diskIDs[index] = id.String()
+	}
+	if err := saveDiskIDs(disks, diskIDs); err != nil {
+		return nil, err
+	}
+	return diskIDs, nil
+}
+
+// saveDiskIDs - saves the identity marker of each disk along with the
+// identities of all disks, disks which are offline are skipped.
+func saveDiskIDs(disks []StorageAPI, diskIDs []string) error {
+	for index, disk := range disks {
+		if disk == nil {
+			continue
+		}
+		err := saveDiskIDMarker(disk, diskIDMarker{Version: "1", ID: diskIDs[index], Set: diskIDs})
+		if err != nil && err != errDiskNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveDiskIDMarker - save the identity marker of a disk.
+func saveDiskIDMarker(disk StorageAPI, marker diskIDMarker) error {
+	w, err := disk.CreateFile(minioMetaBucket, diskIDFile)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	if err = encoder.Encode(&marker); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	if err = w.Close(); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	return nil
+}
+
+// readDiskIDMarkers - reads the identity marker of each disk, marker
+// of a disk is empty if it could not be read, corresponding error is
+// set in errs.
+func readDiskIDMarkers(disks []StorageAPI) (markers []diskIDMarker, errs []error) {
+	markers = make([]diskIDMarker, len(disks))
+	errs = make([]error, len(disks))
+	for index, disk := range disks {
+		if disk == nil {
+			errs[index] = errDiskNotFound
+			continue
+		}
+		offset := int64(0)
+		r, err := disk.ReadFile(minioMetaBucket, diskIDFile, offset)
+		if err != nil {
+			errs[index] = err
+			continue
+		}
+		decoder := json.NewDecoder(r)
+		err = decoder.Decode(&markers[index])
+		r.Close()
+		if err != nil {
+			markers[index] = diskIDMarker{}
+			errs[index] = err
+		}
+	}
+	return markers, errs
+}
+
+// readDiskIDs - reads the identity marker of each disk, identity of a
+// disk is empty if its marker could not be read, corresponding error
+// is set in errs.
+func readDiskIDs(disks []StorageAPI) (diskIDs []string, errs []error) {
+	markers, errs := readDiskIDMarkers(disks)
+	diskIDs = make([]string, len(disks))
+	for index, marker := range markers {
+		diskIDs[index] = marker.ID
+	}
+	return diskIDs, errs
+}
+
+// orderDisksByID - works out the formatted position of each disk from
+// the identity markers, regardless of the order the disks were passed
+// in. order[position] is the index of the disk at position. Disks
+// with an unknown identity, like replaced disks, and offline disks
+// take the positions left over in the order they were passed in,
+// unknown lists the positions of the online ones as they need healing.
+// Disks whose markers predate reordering are left in order.
+func orderDisksByID(disks []StorageAPI) (order []int, unknown []int, err error) {
+	markers, errs := readDiskIDMarkers(disks)
+	// Most disks agree on the set, a disk taken over from a different
+	// backend carries a set of its own.
+	setCount := make(map[string]int)
+	var diskIDs []string
+	for _, marker := range markers {
+		if len(marker.Set) == 0 {
+			continue
+		}
+		key := strings.Join(marker.Set, ",")
+		setCount[key]++
+		if diskIDs == nil || setCount[key] > setCount[strings.Join(diskIDs, ",")] {
+			diskIDs = marker.Set
+		}
+	}
+
+	order = make([]int, len(disks))
+	if diskIDs == nil {
+		for index := range disks {
+			order[index] = index
+		}
+		return order, nil, nil
+	}
+	if len(diskIDs) != len(disks) {
+		return nil, nil, fmt.Errorf("Number of disks %d did not match the disk identities %d in the identity markers", len(disks), len(diskIDs))
+	}
+	position := make(map[string]int)
+	for index, id := range diskIDs {
+		position[id] = index
+		order[index] = -1
+	}
+	var leftover []int
+	for index, marker := range markers {
+		pos, ok := position[marker.ID]
+		if errs[index] != nil || !ok {
+			leftover = append(leftover, index)
+			continue
+		}
+		if order[pos] != -1 {
+			return nil, nil, fmt.Errorf("Disks at index %d and %d carry the same identity %s", order[pos], index, marker.ID)
+		}
+		order[pos] = index
+	}
+	for pos := range order {
+		if order[pos] != -1 {
+			continue
+		}
+		index := leftover[0]
+		leftover = leftover[1:]
+		order[pos] = index
+		if errs[index] != errDiskNotFound && errs[index] != errVolumeNotFound {
+			unknown = append(unknown, pos)
+		}
+	}
+	return order, unknown, nil
+}
+
+// checkDiskIDs - validates that each disk carries the identity recorded
+// for its position in format.json, catches disks remounted at a
+// different path. Disks which are offline are skipped.
+func checkDiskIDs(disks []StorageAPI, xl *xlFormat) error {
+	if len(xl.DiskIDs) == 0 {
+		// Formatted without identities, nothing to verify.
+		return nil
+	}
+	if len(xl.DiskIDs) != len(disks) {
+		return fmt.Errorf("Number of disks %d did not match the disk identities %d in backend format", len(disks), len(xl.DiskIDs))
+	}
+	expectedIndex := make(map[string]int)
+	for index, id := range xl.DiskIDs {
+		expectedIndex[id] = index
+	}
+	diskIDs, errs := readDiskIDs(disks)
+	var mismatches []string
+	for index, id := range diskIDs {
+		if errs[index] != nil {
+			if errs[index] == errDiskNotFound || errs[index] == errVolumeNotFound {
+				// Offline disks are handled by quorum.
+				continue
+			}
+			mismatches = append(mismatches, fmt.Sprintf("disk %s at position %d has no identity marker (%s)", xl.Disks[index], index, errs[index]))
+			continue
+		}
+		if id == xl.DiskIDs[index] {
+			continue
+		}
+		if expected, ok := expectedIndex[id]; ok {
+			mismatches = append(mismatches, fmt.Sprintf("disk %s at position %d is the physical disk expected at position %d (%s)", xl.Disks[index], index, expected, xl.Disks[expected]))
+		} else {
+			mismatches = append(mismatches, fmt.Sprintf("disk %s at position %d has unknown identity %s", xl.Disks[index], index, id))
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("Disk identities do not match backend format: %s", strings.Join(mismatches, "; "))
+	}
+	return nil
+}
+
+// updateDiskIDs - gives the disks at the unknown positions a new
+// identity and flags them for healing, validates the identities of all
+// disks and saves the paths the disks are now found at. Markers of
+// disks which predate reordering are upgraded to carry the identities
+// of all disks.
+func updateDiskIDs(logger Logger, xl *XL, format *xlFormat, diskPaths []string, unknown []int) error {
+	if len(format.DiskIDs) == 0 {
+		// Formatted without identities, nothing to update.
+		return nil
+	}
+	changed := false
+	for _, pos := range unknown {
+		id, err := uuid.New()
+		if err != nil {
+			return err
+		}
+		logger.Warnf("Disk %s has an unknown identity, it replaces disk %s and needs healing", diskPaths[pos], format.DiskIDs[pos])
+		format.DiskIDs[pos] = id.String()
+		err = saveDiskIDMarker(xl.storageDisks[pos], diskIDMarker{Version: "1", ID: id.String(), Set: format.DiskIDs})
+		if err != nil {
+			return err
+		}
+		xl.monitor.markNeedsHeal(pos)
+		changed = true
+	}
+	if err := checkDiskIDs(xl.storageDisks, format); err != nil {
+		return err
+	}
+	for index, disk := range format.Disks {
+		if diskPaths[index] != disk {
+			logger.Infof("Disks found in order %s, were formatted in order %s", diskPaths, format.Disks)
+			format.Disks = diskPaths
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		markers, _ := readDiskIDMarkers(xl.storageDisks)
+		for _, marker := range markers {
+			if marker.ID != "" && len(marker.Set) == 0 {
+				changed = true
+				break
+			}
+		}
+		if !changed {
+			return nil
+		}
+	}
+	if err := saveFormatXL(xl, format); err != nil {
+		return err
+	}
+	return saveDiskIDs(xl.storageDisks, format.DiskIDs)
+}
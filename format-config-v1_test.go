@@ -0,0 +1,397 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Tests validate that disks swapped behind the same export paths are
+// put back in order through their identity markers.
+func TestOrderDisksByIDSwappedDisks(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+
+	obj, err := newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("hello, world")
+	if _, err = obj.PutObject("bucket", "object", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	swapDisks(t, erasureDisks[0], erasureDisks[1])
+
+	obj, err = newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := obj.GetObject("bucket", "object", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	got, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("Expected %q, got %q", data, got)
+	}
+	// format.json records the paths the disks are now found at.
+	format, err := loadFormatXL(log, obj.(xlObjects).storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format.Disks[0] != erasureDisks[1] || format.Disks[1] != erasureDisks[0] {
+		t.Fatalf("Expected swapped paths in format, got %s", format.Disks[:2])
+	}
+}
+
+// Tests validate that disks swapped behind the same export paths are
+// detected through identity markers which predate reordering.
+func TestCheckDiskIDsSwappedDisks(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+
+	if _, err := newXLObjects(erasureDisks...); err != nil {
+		t.Fatal(err)
+	}
+	// Restarting with the same disks succeeds.
+	if _, err := newXLObjects(erasureDisks...); err != nil {
+		t.Fatal(err)
+	}
+
+	// Drop the identities of the other disks from the markers.
+	for _, disk := range erasureDisks {
+		storage, err := newPosix(disk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		diskIDs, errs := readDiskIDs([]StorageAPI{storage})
+		if errs[0] != nil {
+			t.Fatal(errs[0])
+		}
+		if err = saveDiskIDMarker(storage, diskIDMarker{Version: "1", ID: diskIDs[0]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	swapDisks(t, erasureDisks[0], erasureDisks[1])
+
+	_, err := newXLObjects(erasureDisks...)
+	if err == nil {
+		t.Fatal("Expected swapped disks to be detected")
+	}
+	if !strings.Contains(err.Error(), "position 0 is the physical disk expected at position 1") {
+		t.Fatalf("Unexpected error %s", err)
+	}
+}
+
+// Tests validate a disk replaced by an empty one gets a new identity
+// and is flagged for healing.
+func TestUpdateDiskIDsReplacedDisk(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+
+	obj, err := newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	format, err := loadFormatXL(log, obj.(xlObjects).storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replacedID := format.DiskIDs[3]
+
+	// Replace the fourth disk with an empty one.
+	if err = os.RemoveAll(erasureDisks[3]); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Mkdir(erasureDisks[3], 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	obj, err = newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, status := range obj.(xlObjects).DiskStatuses() {
+		if status.NeedsHeal != (index == 3) {
+			t.Fatalf("Disk %d: Expected NeedsHeal %v, got %v", index, index == 3, status.NeedsHeal)
+		}
+	}
+	format, err = loadFormatXL(log, obj.(xlObjects).storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format.DiskIDs[3] == replacedID {
+		t.Fatal("Expected the replaced disk to get a new identity")
+	}
+	// The new identity is known on restart.
+	obj, err = newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.(xlObjects).DiskStatuses()[3].NeedsHeal {
+		t.Fatal("Expected the new identity to be known on restart")
+	}
+}
+
+// swapDisks - remounts two disks at each other's path.
+func swapDisks(t *testing.T, disk1, disk2 string) {
+	tmpPath := disk1 + ".swap"
+	if err := os.Rename(disk1, tmpPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(disk2, disk1); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmpPath, disk2); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Tests validate the erasure split is recorded at format time, picked
+// up on restart and validated against the requested split.
+func TestFormatXLErasureSplit(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 8; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+
+	// Split not adding up to the number of disks.
+	if _, err := newXLObjectsWithErasure(6, 3, erasureDisks...); err == nil {
+		t.Fatal("Expected an invalid erasure split to fail")
+	}
+	if _, err := newXLObjectsWithErasure(6, 2, erasureDisks...); err != nil {
+		t.Fatal(err)
+	}
+	// Restarting with a different split fails.
+	if _, err := newXLObjectsWithErasure(4, 4, erasureDisks...); err == nil {
+		t.Fatal("Expected a mismatching erasure split to fail")
+	}
+	// Restarting without a split uses the recorded one.
+	obj, err := newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage := obj.(xlObjects).storage.(*XL)
+	if storage.DataBlocks != 6 || storage.ParityBlocks != 2 {
+		t.Fatalf("Expected 6 data and 2 parity blocks, got %d and %d", storage.DataBlocks, storage.ParityBlocks)
+	}
+
+	if err = obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("hello, world")
+	if _, err = obj.PutObject("bucket", "object", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := obj.GetObject("bucket", "object", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	got, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("Expected %q, got %q", data, got)
+	}
+}
+
+// testLogger - records the errors logged to it.
+type testLogger struct {
+	mutex  *sync.Mutex
+	errors []string
+}
+
+func (l *testLogger) Debugf(format string, args ...interface{}) {}
+func (l *testLogger) Infof(format string, args ...interface{})  {}
+func (l *testLogger) Warnf(format string, args ...interface{})  {}
+func (l *testLogger) Errorf(format string, args ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+
+// Tests validate errors of the initialization of an XL object layer
+// go to the logger it was given.
+func TestXLObjectsWithLogger(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 8; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+
+	logger := &testLogger{mutex: &sync.Mutex{}}
+	obj, err := newXLObjectsWithOptions(6, 2, erasureDisks, WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.(xlObjects).log() != logger {
+		t.Fatal("Expected the layer to log to the given logger")
+	}
+	// So do its background workers.
+	if obj.(xlObjects).replicator.logger != logger || obj.(xlObjects).storage.(*XL).monitor.logger != logger {
+		t.Fatal("Expected replication and disk monitoring to log to the given logger")
+	}
+	if len(logger.errors) != 0 {
+		t.Fatalf("Expected no errors logged, got %v", logger.errors)
+	}
+	// Restarting with a different split logs why it fails.
+	if _, err = newXLObjectsWithOptions(4, 4, erasureDisks, WithLogger(logger)); err == nil {
+		t.Fatal("Expected a mismatching erasure split to fail")
+	}
+	if len(logger.errors) != 1 || !strings.HasPrefix(logger.errors[0], "Erasure split 4 data and 4 parity blocks") {
+		t.Fatalf("Expected the erasure split mismatch logged, got %v", logger.errors)
+	}
+}
+
+// Tests validate initialization failures caused by the disks are typed,
+// keep the storage error and their message, and tell apart failures
+// worth retrying.
+func TestNewXLObjectsInitError(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	// Disks not mounted yet.
+	for _, disk := range erasureDisks {
+		if err := os.RemoveAll(disk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := newXLObjects(erasureDisks...)
+	if !errors.Is(err, errDiskNotFound) {
+		t.Fatalf("Expected errDiskNotFound, got %v", err)
+	}
+	var initErr InitError
+	if !errors.As(err, &initErr) {
+		t.Fatalf("Expected InitError, got %T", err)
+	}
+	if !initErr.Temporary() {
+		t.Fatal("Expected missing disks to be temporary")
+	}
+
+	testCases := []struct {
+		cause     error
+		message   string
+		temporary bool
+	}{
+		{errReadQuorum, "Not all disks [/a /b] are available, did not meet read quroum.", true},
+		{errDiskNotFound, "Disks [/a /b] not found.", true},
+		{errVolumeAccessDenied, "Disks [/a /b] access permission denied.", false},
+	}
+	for i, testCase := range testCases {
+		initErr = InitError{Cause: testCase.cause, Disks: []string{"/a", "/b"}}
+		if initErr.Error() != testCase.message {
+			t.Fatalf("Test %d: Expected %q, got %q", i+1, testCase.message, initErr.Error())
+		}
+		if initErr.Temporary() != testCase.temporary {
+			t.Fatalf("Test %d: Expected temporary %t", i+1, testCase.temporary)
+		}
+		if !errors.Is(initErr, testCase.cause) {
+			t.Fatalf("Test %d: Expected the cause to be kept", i+1)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// formatXLVersion - version of the XL backend format written by this
+// release, older versions are migrated to it when loaded.
+const formatXLVersion = "2"
+
+// Copy of format.json as it was before a migration from version %s.
+const formatBackupFile = "format.json.v%s.bak"
+
+// formatXLMigration - upgrade of the XL backend format from one version
+// to the next.
+type formatXLMigration struct {
+	from    string
+	to      string
+	migrate func(xl *xlFormat) error
+}
+
+// formatXLMigrations - registered upgrades of the XL backend format,
+// applied in order.
+var formatXLMigrations = []formatXLMigration{
+	{from: "1", to: "2", migrate: migrateFormatXLV1ToV2},
+}
+
+// Version '1' to '2' records the erasure split of backends formatted
+// with the default N/2 split, version '2' always carries it.
+func migrateFormatXLV1ToV2(xl *xlFormat) error {
+	if xl.DataBlocks == 0 && xl.ParityBlocks == 0 {
+		xl.DataBlocks, xl.ParityBlocks = len(xl.Disks)/2, len(xl.Disks)/2
+	}
+	return nil
+}
+
+// writeQuorumChecker - storage able to tell if a write of a file would
+// reach write quorum.
+type writeQuorumChecker interface {
+	hasWriteQuorum(volume, path string) bool
+}
+
+// migrateFormatXL - upgrades xl to formatXLVersion through the
+// registered migrations and rewrites format.json in place, a copy of
+// the previous format.json given in formatData is kept. Nothing is
+// rewritten unless enough disks are online to reach write quorum, a
+// write failing part way would otherwise leave disks with different
+// versions behind. Only runs in maintenance mode.
+func migrateFormatXL(logger Logger, storage StorageAPI, formatData []byte, xl *xlFormat) error {
+	if err := checkMaintenanceMode("MigrateFormatXL"); err != nil {
+		return err
+	}
+	fromVersion := xl.Version
+	for _, migration := range formatXLMigrations {
+		if xl.Version != migration.from {
+			continue
+		}
+		if err := migration.migrate(xl); err != nil {
+			return err
+		}
+		xl.Version = migration.to
+	}
+	if xl.Version != formatXLVersion {
+		return fmt.Errorf("Unsupported XL backend format [%s] found.", fromVersion)
+	}
+	if checker, ok := storage.(writeQuorumChecker); ok && !checker.hasWriteQuorum(minioMetaBucket, formatConfigFile) {
+		return fmt.Errorf("Not enough disks online to migrate XL backend format [%s] to [%s].", fromVersion, formatXLVersion)
+	}
+	backupFile := fmt.Sprintf(formatBackupFile, fromVersion)
+	if err := writeMetaFile(storage, backupFile, formatData); err != nil {
+		return err
+	}
+	if err := saveFormatXL(storage, xl); err != nil {
+		// A failed write removes format.json from all disks, put the
+		// previous version back.
+		if rErr := writeMetaFile(storage, formatConfigFile, formatData); rErr != nil {
+			logger.Errorf("Unable to restore %s from %s, failed with %s", formatConfigFile, backupFile, rErr)
+		}
+		return err
+	}
+	logger.Infof("Migrated XL backend format [%s] to [%s], previous format saved as %s", fromVersion, formatXLVersion, backupFile)
+	return nil
+}
+
+// writeMetaFile - save data as path inside minioMetaBucket.
+func writeMetaFile(storage StorageAPI, path string, data []byte) error {
+	w, err := storage.CreateFile(minioMetaBucket, path)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, bytes.NewReader(data)); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	if err = w.Close(); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Tests validate a backend formatted with version '1' comes up on the
+// current version, keeping a copy of the previous format.
+func TestMigrateFormatXLV1ToV2(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+
+	obj, err := newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage := obj.(xlObjects).storage
+	format, err := loadFormatXL(log, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rewrite as version '1', which didn't record the default split.
+	format.Version = "1"
+	format.DataBlocks, format.ParityBlocks = 0, 0
+	if err = saveFormatXL(storage, format); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only migrated in maintenance mode.
+	if _, err = newXLObjects(erasureDisks...); err == nil {
+		t.Fatal("Expected MaintenanceModeRequired")
+	} else if _, ok := err.(MaintenanceModeRequired); !ok {
+		t.Fatalf("Expected MaintenanceModeRequired, got %v", err)
+	}
+	r, err := storage.ReadFile(minioMetaBucket, formatConfigFile, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var formatConfig formatConfigV1
+	err = json.NewDecoder(r).Decode(&formatConfig)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formatConfig.XL.Version != "1" {
+		t.Fatalf("Expected format left at version 1, got %s", formatConfig.XL.Version)
+	}
+	EnterMaintenanceMode()
+	defer ExitMaintenanceMode()
+
+	obj, err = newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage = obj.(xlObjects).storage
+	format, err = loadFormatXL(log, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format.Version != formatXLVersion {
+		t.Fatalf("Expected version %s, got %s", formatXLVersion, format.Version)
+	}
+	if format.DataBlocks != 8 || format.ParityBlocks != 8 {
+		t.Fatalf("Expected 8 data and 8 parity blocks, got %d and %d", format.DataBlocks, format.ParityBlocks)
+	}
+	if _, err = storage.StatFile(minioMetaBucket, fmt.Sprintf(formatBackupFile, "1")); err != nil {
+		t.Fatalf("Expected a backup of the version 1 format, got %s", err)
+	}
+
+	// Versions without a migration are rejected.
+	format.Version = "0"
+	if err = saveFormatXL(storage, format); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = newXLObjects(erasureDisks...); err == nil {
+		t.Fatal("Expected an unknown version to fail")
+	}
+}
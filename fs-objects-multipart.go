@@ -22,14 +22,25 @@ import (
 	"path"
 )
 
+// SetPartSizeLimits - change the sizes parts of multipart uploads have
+// to be within, the last part of an upload may be smaller than minSize.
+func (fs fsObjects) SetPartSizeLimits(minSize, maxSize int64) error {
+	return fs.partSizes.set(minSize, maxSize)
+}
+
+// PartSizeLimits - sizes parts of multipart uploads have to be within.
+func (fs fsObjects) PartSizeLimits() (minSize, maxSize int64) {
+	return fs.partSizes.get()
+}
+
 // ListMultipartUploads - list multipart uploads.
 func (fs fsObjects) ListMultipartUploads(bucket, prefix, keyMarker, uploadIDMarker, delimiter string, maxUploads int) (ListMultipartsInfo, error) {
 	return listMultipartUploadsCommon(fs, bucket, prefix, keyMarker, uploadIDMarker, delimiter, maxUploads)
 }
 
 // NewMultipartUpload - initialize a new multipart upload, returns a unique id.
-func (fs fsObjects) NewMultipartUpload(bucket, object string) (string, error) {
-	return newMultipartUploadCommon(fs.storage, bucket, object)
+func (fs fsObjects) NewMultipartUpload(bucket, object string, metadata map[string]string) (string, error) {
+	return newMultipartUploadCommon(fs.storage, bucket, object, metadata)
 }
 
 // PutObjectPart - writes the multipart upload chunks.
@@ -59,6 +70,33 @@ func (fs fsObjects) CompleteMultipartUpload(bucket string, object string, upload
 	if !isUploadIDExists(fs.storage, bucket, object, uploadID) {
 		return "", InvalidUploadID{UploadID: uploadID}
 	}
+	if err := validateCompleteParts(uploadID, parts); err != nil {
+		return "", err
+	}
+	// Serialize with other writes and deletes of the object.
+	unlock := lockObject(bucket, object)
+	defer unlock()
+	// Objects under legal hold or retention can't be overwritten.
+	if err := checkObjectMutable(fs.storage, bucket, object, fs.bypassGovernance); err != nil {
+		return "", err
+	}
+	meta, err := readBucketMetadata(fs.storage, bucket)
+	if err != nil {
+		return "", toObjectErr(err, bucket)
+	}
+	// Account the object in the bucket stats once written.
+	defer fs.bucketStats.track(fs, bucket, object)()
+	// New objects count against the bucket object limit.
+	releaseSlot, err := reserveObjectSlot(fs, fs.storage, meta, bucket, object)
+	if err != nil {
+		return "", err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			releaseSlot()
+		}
+	}()
 
 	// Calculate s3 compatible md5sum for complete multipart.
 	s3MD5, err := completeMultipartMD5(parts...)
@@ -66,29 +104,56 @@ func (fs fsObjects) CompleteMultipartUpload(bucket string, object string, upload
 		return "", err
 	}
 
-	tempObj := path.Join(tmpMetaPrefix, bucket, object, uploadID, incompleteFile)
-	fileWriter, err := fs.storage.CreateFile(minioMetaBucket, tempObj)
+	// Metadata given when the upload was started.
+	objMetadata, err := readUploadMetadata(fs.storage, bucket, object, uploadID)
 	if err != nil {
 		return "", toObjectErr(err, bucket, object)
 	}
+	// Objects of versioned buckets get a version ID.
+	if objMetadata, err = withVersionID(meta, objMetadata); err != nil {
+		return "", toObjectErr(err, bucket, object)
+	}
 
-	// Loop through all parts, validate them and then commit to disk.
+	// Loop through all parts and validate them before anything is
+	// written.
+	objParts := make([]MultipartPartInfo, len(parts))
 	for i, part := range parts {
 		// Construct part suffix.
 		partSuffix := fmt.Sprintf("%.5d.%s", part.PartNumber, part.ETag)
-		multipartPartFile := path.Join(mpartMetaPrefix, bucket, object, uploadID, partSuffix)
 		var fi FileInfo
-		fi, err = fs.storage.StatFile(minioMetaBucket, multipartPartFile)
+		var encryption map[string]string
+		fi, encryption, err = statUploadedPart(fs.storage, bucket, object, uploadID, partSuffix)
 		if err != nil {
+			// Never uploaded, or uploaded with other data.
 			if err == errFileNotFound {
-				return "", InvalidPart{}
+				return "", InvalidPart{PartNumber: part.PartNumber, ETag: part.ETag}
 			}
 			return "", err
 		}
-		// All parts except the last part has to be atleast 5MB.
-		if (i < len(parts)-1) && !isMinAllowedPartSize(fi.Size) {
-			return "", PartTooSmall{}
+		// All parts except the last part has to be atleast the
+		// minimum part size.
+		if err = fs.partSizes.checkPart(part.PartNumber, fi.Size, i == len(parts)-1); err != nil {
+			return "", err
 		}
+		objParts[i] = MultipartPartInfo{PartNumber: part.PartNumber, Size: fi.Size, Encryption: encryption}
+	}
+
+	tempObj := path.Join(tmpMetaPrefix, bucket, object, uploadID, incompleteFile)
+	fileWriter, err := fs.storage.CreateFile(minioMetaBucket, tempObj)
+	if err != nil {
+		return "", toObjectErr(err, bucket, object)
+	}
+
+	// Parts encrypted at rest are joined as stored.
+	partsEncryption, err := formatPartsEncryption(objParts)
+	if err != nil {
+		return "", err
+	}
+
+	// Loop through all parts and commit them to disk.
+	for _, part := range parts {
+		partSuffix := fmt.Sprintf("%.5d.%s", part.PartNumber, part.ETag)
+		multipartPartFile := path.Join(mpartMetaPrefix, bucket, object, uploadID, partSuffix)
 		var fileReader io.ReadCloser
 		fileReader, err = fs.storage.ReadFile(minioMetaBucket, multipartPartFile, 0)
 		if err != nil {
@@ -96,7 +161,7 @@ func (fs fsObjects) CompleteMultipartUpload(bucket string, object string, upload
 				return "", clErr
 			}
 			if err == errFileNotFound {
-				return "", InvalidPart{}
+				return "", InvalidPart{PartNumber: part.PartNumber, ETag: part.ETag}
 			}
 			return "", err
 		}
@@ -124,15 +189,58 @@ func (fs fsObjects) CompleteMultipartUpload(bucket string, object string, upload
 		return "", err
 	}
 
+	// A dedup blob the overwritten object references loses a reference.
+	oldRef, err := lockDedupReference(fs.storage, bucket, object)
+	if err != nil {
+		if derr := fs.storage.DeleteFile(minioMetaBucket, tempObj); derr != nil {
+			return "", toObjectErr(derr, minioMetaBucket, tempObj)
+		}
+		return "", toObjectErr(err, bucket, object)
+	}
+	defer oldRef.unlock()
+	// An object overwritten in a versioned bucket is retained.
+	version, err := retainReplacedObject(fs, fs.storage, meta, bucket, object, false)
+	if err != nil {
+		if derr := fs.storage.DeleteFile(minioMetaBucket, tempObj); derr != nil {
+			return "", toObjectErr(derr, minioMetaBucket, tempObj)
+		}
+		return "", toObjectErr(err, bucket, object)
+	}
+
 	// Rename the file back to original location, if not delete the
 	// temporary object.
 	err = fs.storage.RenameFile(minioMetaBucket, tempObj, bucket, object)
 	if err != nil {
+		if rerr := version.restore(); rerr != nil {
+			errorIf(rerr, "Unable to restore "+bucket+"/"+object+" from its version.", nil)
+		}
 		if derr := fs.storage.DeleteFile(minioMetaBucket, tempObj); derr != nil {
 			return "", toObjectErr(derr, minioMetaBucket, tempObj)
 		}
 		return "", toObjectErr(err, bucket, object)
 	}
+	committed = true
+	invalidateTreeWalks(fs, bucket, object)
+
+	// Remove sidecar metadata of an overwritten object, then save new metadata.
+	if err = deleteObjectMetadata(fs.storage, bucket, object); err != nil {
+		return "", toObjectErr(err, bucket, object)
+	}
+	// Part boundaries are lost once the parts are joined, keep them.
+	objMetadata = withETag(objMetadata, s3MD5)
+	objMetadata[partsKey] = formatParts(objParts)
+	if partsEncryption != "" {
+		objMetadata[partsEncryptionKey] = partsEncryption
+	}
+	if err = writeObjectMetadata(fs.storage, bucket, object, objMetadata); err != nil {
+		return "", toObjectErr(err, bucket, object)
+	}
+	// A retained version keeps referencing the dedup blob.
+	if version == nil {
+		if err = oldRef.release(); err != nil {
+			return "", toObjectErr(err, bucket, object)
+		}
+	}
 
 	// Cleanup all the parts if everything else has been safely committed.
 	if err = cleanupUploadedParts(fs.storage, bucket, object, uploadID); err != nil {
@@ -143,6 +251,12 @@ func (fs fsObjects) CompleteMultipartUpload(bucket string, object string, upload
 	return s3MD5, nil
 }
 
+// CompleteMultipartUploadWithManifest - completes an upload only if
+// its parts match the sizes and md5sums listed in manifest.
+func (fs fsObjects) CompleteMultipartUploadWithManifest(bucket, object, uploadID string, parts []completePart, manifest []manifestPart) (string, error) {
+	return completeMultipartUploadWithManifest(fs, fs.storage, bucket, object, uploadID, parts, manifest)
+}
+
 // AbortMultipartUpload - aborts a multipart upload.
 func (fs fsObjects) AbortMultipartUpload(bucket, object, uploadID string) error {
 	return abortMultipartUploadCommon(fs.storage, bucket, object, uploadID)
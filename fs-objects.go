@@ -17,14 +17,18 @@
 package main
 
 import (
+	"context"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
+	"hash"
 	"io"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
 
-	"github.com/minio/minio/pkg/mimedb"
+	"github.com/skyrings/skyring-common/tools/uuid"
 )
 
 // fsObjects - Implements fs object layer.
@@ -32,6 +36,26 @@ type fsObjects struct {
 	storage            StorageAPI
 	listObjectMap      map[listParams][]*treeWalker
 	listObjectMapMutex *sync.Mutex
+	listCursors        *listCursors
+	replicator         *replicator
+	bandwidth          *bandwidthAccounting
+	bucketStats        *bucketStatsTracker
+	scheduler          *priorityScheduler
+	// Priority storage access of this layer is scheduled at.
+	priority RequestPriority
+	// Overwrites and deletes override governance retention.
+	bypassGovernance bool
+	partSizes        *partSizeLimits
+	// Largest object PutObject accepts.
+	sizeLimit *objectSizeLimit
+	// Most keys a single listing returns.
+	listLimit *listKeysLimit
+	// Content types of object extensions overriding mimedb.
+	contentTypes *contentTypes
+	// Regions buckets can be made in.
+	regions *bucketRegions
+	// Stops the background goroutines of the layer.
+	shutdown *shutdownSignal
 }
 
 // newFSObjects - initialize new fs object layer.
@@ -56,12 +80,30 @@ func newFSObjects(exportPath string) (ObjectLayer, error) {
 	// cleaning up tmp files etc.
 	initObjectLayer(storage)
 
-	// Return successfully initialized object layer.
-	return fsObjects{
+	fs := fsObjects{
 		storage:            storage,
 		listObjectMap:      make(map[listParams][]*treeWalker),
 		listObjectMapMutex: &sync.Mutex{},
-	}, nil
+		listCursors:        newListCursors(),
+		replicator:         newReplicator(storage, log),
+		bandwidth:          newBandwidthAccounting(storage),
+		bucketStats:        newBucketStatsTracker(storage),
+		scheduler:          newPriorityScheduler(storageConcurrency),
+		partSizes:          newPartSizeLimits(),
+		sizeLimit:          newObjectSizeLimit(),
+		listLimit:          newListKeysLimit(),
+		contentTypes:       newContentTypes(),
+		regions:            newBucketRegions(),
+		shutdown:           newShutdownSignal(),
+	}
+	fs.replicator.start(fs.WithContext(WithRequestPriority(context.Background(), PriorityBackground)), fs.shutdown.done())
+	// Temp files of uploads abandoned while the server runs are only
+	// reclaimed by the sweeper, initObjectLayer clears them on start.
+	go runStaleUploadsSweeper(log, storage, fs.shutdown.done())
+	go fs.bucketStats.run(fs.shutdown.done())
+
+	// Return successfully initialized object layer.
+	return fs, nil
 }
 
 /// Bucket operations
@@ -71,11 +113,41 @@ func (fs fsObjects) MakeBucket(bucket string) error {
 	return makeBucket(fs.storage, bucket)
 }
 
+// MakeBucketWithLocation - make a bucket in region, which must be one
+// of the allowed regions. An empty region is the default region.
+func (fs fsObjects) MakeBucketWithLocation(bucket, region string) error {
+	return makeBucketWithLocation(fs.storage, fs.regions, bucket, region)
+}
+
+// GetBucketLocation - get the region of a bucket, us-east-1 for
+// buckets made without one.
+func (fs fsObjects) GetBucketLocation(bucket string) (string, error) {
+	return getBucketLocation(fs.storage, bucket)
+}
+
+// SetAllowedRegions - change the regions buckets can be made in, an
+// empty list allows the default region only.
+func (fs fsObjects) SetAllowedRegions(regions []string) error {
+	return fs.regions.set(regions)
+}
+
 // GetBucketInfo - get bucket info.
 func (fs fsObjects) GetBucketInfo(bucket string) (BucketInfo, error) {
 	return getBucketInfo(fs.storage, bucket)
 }
 
+// GetBucketStats - get the number of objects of a bucket and their
+// total size.
+func (fs fsObjects) GetBucketStats(bucket string) (BucketStats, error) {
+	return getBucketStats(fs, fs.storage, fs.bucketStats, bucket)
+}
+
+// BucketExists - check if a bucket exists, fails instead of answering
+// when it can't be determined.
+func (fs fsObjects) BucketExists(bucket string) (bool, error) {
+	return bucketExists(fs.storage, bucket)
+}
+
 // ListBuckets - list buckets.
 func (fs fsObjects) ListBuckets() ([]BucketInfo, error) {
 	return listBuckets(fs.storage)
@@ -83,13 +155,175 @@ func (fs fsObjects) ListBuckets() ([]BucketInfo, error) {
 
 // DeleteBucket - delete a bucket.
 func (fs fsObjects) DeleteBucket(bucket string) error {
+	defer fs.bucketStats.forget(bucket)
 	return deleteBucket(fs.storage, bucket)
 }
 
+// DeleteBucketForce - delete a bucket along with all its objects.
+func (fs fsObjects) DeleteBucketForce(bucket string) error {
+	defer fs.bucketStats.forget(bucket)
+	_, err := deleteBucketForceCommon(fs, fs.storage, bucket, false)
+	return err
+}
+
+// DeleteBucketForceDryRun - return the storage paths DeleteBucketForce
+// would remove, without removing anything.
+func (fs fsObjects) DeleteBucketForceDryRun(bucket string) ([]string, error) {
+	return deleteBucketForceCommon(fs, fs.storage, bucket, true)
+}
+
+// SetBucketAllowedPrefixes - restrict object names in a bucket to the given prefixes.
+func (fs fsObjects) SetBucketAllowedPrefixes(bucket string, prefixes []string) error {
+	return setBucketAllowedPrefixes(fs.storage, bucket, prefixes)
+}
+
+// GetBucketAllowedPrefixes - get allowed object name prefixes of a bucket.
+func (fs fsObjects) GetBucketAllowedPrefixes(bucket string) ([]string, error) {
+	return getBucketAllowedPrefixes(fs.storage, bucket)
+}
+
+// ExportBucketConfig - serialize the bucket configuration.
+func (fs fsObjects) ExportBucketConfig(bucket string) ([]byte, error) {
+	return exportBucketConfig(fs.storage, bucket)
+}
+
+// ImportBucketConfig - restore a bucket configuration from ExportBucketConfig.
+func (fs fsObjects) ImportBucketConfig(bucket string, data []byte) error {
+	return importBucketConfig(fs.storage, fs.regions, bucket, data)
+}
+
+// SetBucketReplication - replicate objects of a bucket to targetBucket
+// of the registered replication target, an empty target disables it.
+func (fs fsObjects) SetBucketReplication(bucket, target, targetBucket string) error {
+	return setBucketReplication(fs.storage, bucket, target, targetBucket)
+}
+
+// SetBucketObjectLimit - reject new objects once a bucket holds
+// maxObjects objects, zero removes the limit.
+func (fs fsObjects) SetBucketObjectLimit(bucket string, maxObjects int64) error {
+	return setBucketObjectLimit(fs, fs.storage, bucket, maxObjects)
+}
+
+// SetBucketDedup - turn content deduplication of a bucket on or off,
+// objects put with identical content then share one stored copy.
+func (fs fsObjects) SetBucketDedup(bucket string, enabled bool) error {
+	return setBucketDedup(fs.storage, bucket, enabled)
+}
+
+// SetBucketVersioning - turn versioning of a bucket on or off, while
+// on overwritten and deleted objects are retained as versions.
+func (fs fsObjects) SetBucketVersioning(bucket string, enabled bool) error {
+	return setBucketVersioning(fs.storage, bucket, enabled)
+}
+
+// BucketBandwidthStats - bytes written to and read from a bucket
+// through PutObject and GetObject.
+func (fs fsObjects) BucketBandwidthStats(bucket string) (ingressBytes, egressBytes int64) {
+	return fs.bandwidth.stats(bucket)
+}
+
+// ResetBucketBandwidthStats - restart bandwidth accounting of a bucket.
+func (fs fsObjects) ResetBucketBandwidthStats(bucket string) {
+	fs.bandwidth.reset(bucket)
+}
+
+// WithContext - returns the object layer scheduling its storage access
+// at the priority carried by ctx, overriding governance retention if
+// ctx allows it.
+func (fs fsObjects) WithContext(ctx context.Context) ObjectLayer {
+	fs.priority = RequestPriorityFromContext(ctx)
+	fs.bypassGovernance = GovernanceBypassFromContext(ctx)
+	return fs
+}
+
+// StorageQueueDepths - number of storage reads and writes waiting for
+// admission by priority.
+func (fs fsObjects) StorageQueueDepths() map[string]int {
+	return fs.scheduler.queueDepths()
+}
+
+// Shutdown - stops replicating objects and sweeping stale uploads in
+// background, bucket stats changes not saved yet are saved. Objects
+// still pending replication are left pending.
+func (fs fsObjects) Shutdown() error {
+	fs.shutdown.signal()
+	fs.bucketStats.flush()
+	return nil
+}
+
 /// Object Operations
 
 // GetObject - get an object.
 func (fs fsObjects) GetObject(bucket, object string, startOffset int64) (io.ReadCloser, error) {
+	return fs.GetObjectWithContext(context.Background(), bucket, object, startOffset)
+}
+
+// GetObjectWithContext - get an object, reads fail once ctx is done.
+func (fs fsObjects) GetObjectWithContext(ctx context.Context, bucket, object string, startOffset int64) (io.ReadCloser, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(fs.storage, bucket) {
+		return nil, BucketNotFound{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return nil, ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	fileInfo, err := fs.storage.StatFile(bucket, object)
+	if err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	objReader, err := readObjectReader(fs.storage, bucket, object, fileInfo.Size)
+	if err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	if startOffset < 0 || startOffset > objReader.size {
+		return nil, InvalidRange{Bucket: bucket, Object: object, Offset: startOffset, Size: objReader.size}
+	}
+	fileReader, err := objReader.open(startOffset)
+	if err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	fileReader = newContextReadCloser(ctx, fileReader)
+	return fs.scheduler.reader(fs.bandwidth.egressReader(bucket, fileReader), fs.priority), nil
+}
+
+// GetObjectWithOptions - get an object like GetObject, fs has no read
+// quorum so reads are never degraded.
+func (fs fsObjects) GetObjectWithOptions(bucket, object string, startOffset int64, opts GetObjectOptions) (*DegradedReader, error) {
+	reader, err := fs.GetObject(bucket, object, startOffset)
+	if err != nil {
+		return nil, err
+	}
+	return newDegradedReader(reader, nil), nil
+}
+
+// GetObjectRange - get length bytes of an object starting at
+// startOffset, a length of -1 reads to the end of the object.
+func (fs fsObjects) GetObjectRange(bucket, object string, startOffset, length int64) (io.ReadCloser, error) {
+	fileReader, err := fs.GetObject(bucket, object, startOffset)
+	if err != nil {
+		return nil, err
+	}
+	return newLimitedReadCloser(fileReader, length), nil
+}
+
+// WriteObjectTo - write an object starting at startOffset to w,
+// returns the number of bytes written.
+func (fs fsObjects) WriteObjectTo(bucket, object string, startOffset int64, w io.Writer) (int64, error) {
+	reader, err := fs.GetObject(bucket, object, startOffset)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	return io.Copy(w, reader)
+}
+
+// GetObjectTail - get the last n bytes of an object, all of it if the
+// object is shorter.
+func (fs fsObjects) GetObjectTail(bucket, object string, n int64) (io.ReadCloser, error) {
 	// Verify if bucket is valid.
 	if !IsValidBucketName(bucket) {
 		return nil, BucketNameInvalid{Bucket: bucket}
@@ -101,11 +335,76 @@ func (fs fsObjects) GetObject(bucket, object string, startOffset int64) (io.Read
 	if !IsValidObjectName(object) {
 		return nil, ObjectNameInvalid{Bucket: bucket, Object: object}
 	}
-	fileReader, err := fs.storage.ReadFile(bucket, object, startOffset)
+	// The offset is computed from the size of the object opened,
+	// writes of the object are held off in between.
+	unlock := rlockObject(bucket, object)
+	defer unlock()
+	fileInfo, err := fs.storage.StatFile(bucket, object)
+	if err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	objReader, err := readObjectReader(fs.storage, bucket, object, fileInfo.Size)
+	if err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	startOffset, err := objectTailOffset(bucket, object, objReader.size, n)
+	if err != nil {
+		return nil, err
+	}
+	fileReader, err := objReader.open(startOffset)
+	if err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	return fs.scheduler.reader(fs.bandwidth.egressReader(bucket, fileReader), fs.priority), nil
+}
+
+// GetObjectRanges - get several ranges of an object given as
+// {startOffset, length} pairs as one stream, along with where each
+// range starts in it, such as for a multipart/byteranges response.
+func (fs fsObjects) GetObjectRanges(bucket, object string, ranges [][2]int64) (io.ReadCloser, []ObjectRangePart, error) {
+	return getObjectRangesCommon(fs, bucket, object, ranges)
+}
+
+// GetObjectWithHash - opt-in variant of GetObject for the whole
+// object, the returned reader computes the object hash while streaming.
+func (fs fsObjects) GetObjectWithHash(bucket, object string) (*ObjectHashReader, error) {
+	reader, err := fs.GetObject(bucket, object, 0)
+	if err != nil {
+		return nil, err
+	}
+	// Part boundaries are needed for the composite hash of multipart
+	// objects, they are kept in the metadata once the parts are joined.
+	metadata, err := readObjectMetadata(fs.storage, bucket, object)
 	if err != nil {
+		reader.Close()
 		return nil, toObjectErr(err, bucket, object)
 	}
-	return fileReader, nil
+	var partSizes []int64
+	for _, part := range parseParts(metadata[partsKey]) {
+		partSizes = append(partSizes, part.Size)
+	}
+	return newObjectHashReader(reader, partSizes), nil
+}
+
+// GetObjectVerifiedReaderAt - random access to an object, fs has no
+// redundancy to verify the data against so reads are served as stored.
+func (fs fsObjects) GetObjectVerifiedReaderAt(bucket, object string) (io.ReaderAt, int64, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, 0, BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(fs.storage, bucket) {
+		return nil, 0, BucketNotFound{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return nil, 0, ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	readerAt, size, err := openObjectReaderAt(fs.storage, bucket, object)
+	if err != nil {
+		return nil, 0, toObjectErr(err, bucket, object)
+	}
+	return readerAt, size, nil
 }
 
 // GetObjectInfo - get object info.
@@ -117,115 +416,670 @@ func (fs fsObjects) GetObjectInfo(bucket, object string) (ObjectInfo, error) {
 	if !isBucketExist(fs.storage, bucket) {
 		return ObjectInfo{}, BucketNotFound{Bucket: bucket}
 	}
+	return fs.objectInfo(bucket, object)
+}
+
+// GetObjectsInfo - get info of objects of a bucket, the bucket is
+// validated once for all of them. Infos and errors are returned by
+// position of the object.
+func (fs fsObjects) GetObjectsInfo(bucket string, objects []string) ([]ObjectInfo, []error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return bucketObjectsInfoErr(objects, BucketNameInvalid{Bucket: bucket})
+	}
+	if !isBucketExist(fs.storage, bucket) {
+		return bucketObjectsInfoErr(objects, BucketNotFound{Bucket: bucket})
+	}
+	return getObjectsInfo(bucket, objects, fs.objectInfo)
+}
+
+// objectInfo - get info of an object of a bucket known to exist.
+func (fs fsObjects) objectInfo(bucket, object string) (ObjectInfo, error) {
 	// Verify if object is valid.
 	if !IsValidObjectName(object) {
 		return ObjectInfo{}, (ObjectNameInvalid{Bucket: bucket, Object: object})
 	}
 	fi, err := fs.storage.StatFile(bucket, object)
 	if err != nil {
+		if err == errFileNotFound || err == errIsNotRegular {
+			if info, ok := getPrefixInfo(fs, bucket, object); ok {
+				return info, nil
+			}
+		}
 		return ObjectInfo{}, toObjectErr(err, bucket, object)
 	}
-	contentType := "application/octet-stream"
-	if objectExt := filepath.Ext(object); objectExt != "" {
-		content, ok := mimedb.DB[strings.ToLower(strings.TrimPrefix(objectExt, "."))]
-		if ok {
-			contentType = content.ContentType
-		}
+	metadata, err := readObjectMetadata(fs.storage, bucket, object)
+	if err != nil {
+		return ObjectInfo{}, toObjectErr(err, bucket, object)
+	}
+	if fi.Size, err = objectContentSize(fi.Size, metadata); err != nil {
+		return ObjectInfo{}, toObjectErr(err, bucket, object)
 	}
 	return ObjectInfo{
-		Bucket:      bucket,
-		Name:        object,
-		ModTime:     fi.ModTime,
-		Size:        fi.Size,
-		IsDir:       fi.Mode.IsDir(),
-		ContentType: contentType,
-		MD5Sum:      "", // Read from metadata.
+		Bucket:            bucket,
+		Name:              object,
+		ModTime:           fi.ModTime,
+		Size:              fi.Size,
+		IsDir:             fi.Mode.IsDir(),
+		ContentType:       getContentType(object, metadata, fs.contentTypes),
+		ContentEncoding:   metadata[contentEncodingKey],
+		ReplicationStatus: metadata[replicationStatusKey],
+		MD5Sum:            metadata[etagKey],
+		Parts:             objectParts(parseParts(metadata[partsKey])),
+		UserDefined:       userDefinedMetadata(metadata),
+		VersionID:         metadata[versionIDKey],
 	}, nil
 }
 
+// GetObjectVersion - get a version of an object, an empty versionID
+// reads the current object.
+func (fs fsObjects) GetObjectVersion(bucket, object, versionID string, startOffset int64) (io.ReadCloser, error) {
+	return getObjectVersionCommon(fs, fs.storage, bucket, object, versionID, startOffset, func(versionPath string, startOffset int64) (io.ReadCloser, error) {
+		fileInfo, err := fs.storage.StatFile(minioMetaBucket, versionPath)
+		if err != nil {
+			return nil, toObjectErr(err, bucket, object)
+		}
+		objReader, err := readObjectReader(fs.storage, minioMetaBucket, versionPath, fileInfo.Size)
+		if err != nil {
+			return nil, toObjectErr(err, bucket, object)
+		}
+		fileReader, err := objReader.open(startOffset)
+		if err != nil {
+			return nil, toObjectErr(err, bucket, object)
+		}
+		return fs.scheduler.reader(fs.bandwidth.egressReader(bucket, fileReader), fs.priority), nil
+	})
+}
+
+// GetObjectVersionInfo - get info of a version of an object, an empty
+// versionID is the current object.
+func (fs fsObjects) GetObjectVersionInfo(bucket, object, versionID string) (ObjectInfo, error) {
+	objInfo, _, err := getObjectVersionInfoCommon(fs, fs.storage, bucket, object, versionID)
+	return objInfo, err
+}
+
 // PutObject - create an object.
 func (fs fsObjects) PutObject(bucket string, object string, size int64, data io.Reader, metadata map[string]string) (string, error) {
+	return fs.PutObjectWithContext(context.Background(), bucket, object, size, data, metadata)
+}
+
+// PutObjectWithContext - create an object, the upload is aborted once
+// ctx is done.
+func (fs fsObjects) PutObjectWithContext(ctx context.Context, bucket string, object string, size int64, data io.Reader, metadata map[string]string) (string, error) {
+	result, err := fs.putObject(ctx, bucket, object, size, data, metadata, false, nil)
+	return result.ETag, err
+}
+
+// PutObjectWithChecksums - create an object, returns the checksums
+// requested through checksumAlgorithmKey along with its ETag.
+func (fs fsObjects) PutObjectWithChecksums(bucket string, object string, size int64, data io.Reader, metadata map[string]string) (PutObjectResult, error) {
+	return fs.putObject(context.Background(), bucket, object, size, data, metadata, false, nil)
+}
+
+// PutObjectFromFile - create an object from a file on the server, the
+// file is copied into place by the kernel, see serverFile.
+func (fs fsObjects) PutObjectFromFile(bucket, object, srcPath string, metadata map[string]string) (PutObjectResult, error) {
+	return putObjectFromFile(bucket, object, srcPath, metadata, fs.PutObjectWithChecksums)
+}
+
+// putObject - create an object, see putObjectFunc.
+func (fs fsObjects) putObject(ctx context.Context, bucket string, object string, size int64, data io.Reader, metadata map[string]string, lockHeld bool, precheck func() error) (PutObjectResult, error) {
 	// Verify if bucket is valid.
 	if !IsValidBucketName(bucket) {
-		return "", BucketNameInvalid{Bucket: bucket}
+		return PutObjectResult{}, BucketNameInvalid{Bucket: bucket}
 	}
 	// Check whether the bucket exists.
 	if !isBucketExist(fs.storage, bucket) {
-		return "", BucketNotFound{Bucket: bucket}
+		return PutObjectResult{}, BucketNotFound{Bucket: bucket}
 	}
 	if !IsValidObjectName(object) {
-		return "", ObjectNameInvalid{
+		return PutObjectResult{}, ObjectNameInvalid{
 			Bucket: bucket,
 			Object: object,
 		}
 	}
+	// The bucket configuration is read once for the whole write.
+	meta, err := readBucketMetadata(fs.storage, bucket)
+	if err != nil {
+		return PutObjectResult{}, toObjectErr(err, bucket)
+	}
+	// Verify if object name is allowed by the bucket prefixes.
+	if err = checkObjectPrefixAllowed(meta, bucket, object); err != nil {
+		return PutObjectResult{}, err
+	}
+	if err = checkRetentionMetadata(bucket, object, metadata); err != nil {
+		return PutObjectResult{}, err
+	}
+	// A malformed Content-MD5 is rejected before any data is read.
+	md5Expected, err := getExpectedMD5(metadata)
+	if err != nil {
+		return PutObjectResult{}, err
+	}
+	hashers, err := getChecksumHashers(metadata)
+	if err != nil {
+		return PutObjectResult{}, err
+	}
+	if err = checkDurablePut(fs.storage, metadata); err != nil {
+		return PutObjectResult{}, err
+	}
+	// Data of a server-local file is copied into place by the kernel,
+	// it is read here only for its checksums. Data compressed or
+	// encrypted at rest has to go through the at rest writer instead.
+	srcFile, _ := data.(*serverFile)
+	if isTransformedAtRest(object, metadata) {
+		srcFile = nil
+	}
+	// Objects over the size limit are refused before they are written
+	// in full, including those streamed without a known size.
+	if data, err = fs.sizeLimit.limit(bucket, object, size, data); err != nil {
+		return PutObjectResult{}, err
+	}
+	data = newContextReader(ctx, data)
+	// Stage data with fsync checkpoints, if requested, the staged data
+	// is stored as the object once complete.
+	if getCheckpointInterval(metadata) > 0 {
+		return putObjectCheckpointed(fs.storage, bucket, object, size, data, metadata, func(size int64, data io.Reader, metadata map[string]string) (PutObjectResult, error) {
+			return fs.storeObject(ctx, meta, bucket, object, size, data, metadata, md5Expected, hashers, nil, lockHeld, precheck)
+		})
+	}
+	return fs.storeObject(ctx, meta, bucket, object, size, data, metadata, md5Expected, hashers, srcFile, lockHeld, precheck)
+}
 
-	fileWriter, err := fs.storage.CreateFile(bucket, object)
+// storeObject - stages data and puts it in place of the object, for
+// putObject once the write is validated. Data of srcFile, if set, is
+// copied into place by the kernel and only read from data.
+func (fs fsObjects) storeObject(ctx context.Context, meta bucketMetadata, bucket, object string, size int64, data io.Reader, metadata map[string]string, md5Expected *expectedMD5, hashers map[string]hash.Hash, srcFile *serverFile, lockHeld bool, precheck func() error) (PutObjectResult, error) {
+	isServerFile := srcFile != nil
+	// Identical content is kept once in buckets with dedup set.
+	dedup := isDedupCandidate(meta, object, metadata)
+	// Data is staged apart for each write and renamed in place of the
+	// object once it's complete.
+	id, err := uuid.New()
 	if err != nil {
-		return "", toObjectErr(err, bucket, object)
+		return PutObjectResult{}, err
+	}
+	tempObj := path.Join(tmpMetaPrefix, bucket, object, id.String())
+	fileWriter, err := fs.storage.CreateFile(minioMetaBucket, tempObj)
+	if err != nil {
+		return PutObjectResult{}, toObjectErr(err, bucket, object)
 	}
 
 	// Initialize md5 writer.
 	md5Writer := md5.New()
+	writers := []io.Writer{md5Writer}
+	atRest, err := newAtRestWriter(object, metadata, fs.scheduler.writer(fileWriter, fs.priority))
+	if err != nil {
+		if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
+			return PutObjectResult{}, clErr
+		}
+		return PutObjectResult{}, err
+	}
+	if !isServerFile {
+		writers = append(writers, atRest)
+	}
+
+	// Initialize sha256 writer, only if there is a sha256 to verify.
+	var sha256Writer hash.Hash
+	sha256Hex := metadata["sha256Sum"]
+	if sha256Hex != "" {
+		sha256Writer = sha256.New()
+		writers = append(writers, sha256Writer)
+	}
+	// Additional checksums are computed in the same pass.
+	writers = appendChecksumWriters(writers, hashers)
+	// Dedup blobs are named after the SHA-256 of their content.
+	var dedupWriter hash.Hash
+	if dedup {
+		dedupWriter = sha256.New()
+		writers = append(writers, dedupWriter)
+	}
 
 	// Instantiate a new multi writer.
-	multiWriter := io.MultiWriter(md5Writer, fileWriter)
+	multiWriter := io.MultiWriter(writers...)
 
 	// Instantiate checksum hashers and create a multiwriter.
+	var n int64
 	if size > 0 {
-		if _, err = io.CopyN(multiWriter, data, size); err != nil {
-			if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
-				return "", clErr
-			}
-			return "", toObjectErr(err, bucket, object)
-		}
+		n, err = io.CopyN(multiWriter, data, size)
 	} else {
-		if _, err = io.Copy(multiWriter, data); err != nil {
-			if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
-				return "", clErr
-			}
-			return "", toObjectErr(err, bucket, object)
+		n, err = io.Copy(multiWriter, data)
+	}
+	// Bytes received are accounted even if the upload fails.
+	fs.bandwidth.addIngress(bucket, n)
+	if err != nil {
+		if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
+			return PutObjectResult{}, clErr
 		}
+		return PutObjectResult{}, toObjectErr(err, bucket, object)
 	}
 
 	newMD5Hex := hex.EncodeToString(md5Writer.Sum(nil))
-	// md5Hex representation.
-	var md5Hex string
-	if len(metadata) != 0 {
-		md5Hex = metadata["md5Sum"]
+	if md5Expected != nil {
+		if err = md5Expected.verify(md5Writer.Sum(nil)); err != nil {
+			if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
+				return PutObjectResult{}, clErr
+			}
+			return PutObjectResult{}, err
+		}
 	}
-	if md5Hex != "" {
-		if newMD5Hex != md5Hex {
+	if sha256Writer != nil {
+		newSHA256Hex := hex.EncodeToString(sha256Writer.Sum(nil))
+		if newSHA256Hex != sha256Hex {
 			if err = safeCloseAndRemove(fileWriter); err != nil {
-				return "", err
+				return PutObjectResult{}, err
 			}
-			return "", BadDigest{md5Hex, newMD5Hex}
+			return PutObjectResult{}, SHA256Mismatch{sha256Hex, newSHA256Hex}
 		}
 	}
+	if isServerFile {
+		if err = copyServerFile(fileWriter, srcFile, n); err != nil {
+			if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
+				return PutObjectResult{}, clErr
+			}
+			return PutObjectResult{}, toObjectErr(err, bucket, object)
+		}
+	}
+	// The ETag and checksums remain those of the uploaded data.
+	if metadata, err = atRest.finish(metadata, n); err != nil {
+		if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
+			return PutObjectResult{}, clErr
+		}
+		return PutObjectResult{}, toObjectErr(err, bucket, object)
+	}
 	err = fileWriter.Close()
 	if err != nil {
 		if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
-			return "", clErr
+			return PutObjectResult{}, clErr
+		}
+		return PutObjectResult{}, err
+	}
+	// Staged data is removed if the object isn't put in place.
+	removeStaged := func(err error) (PutObjectResult, error) {
+		if derr := fs.storage.DeleteFile(minioMetaBucket, tempObj); derr != nil && derr != errFileNotFound {
+			return PutObjectResult{}, toObjectErr(derr, bucket, object)
 		}
-		return "", err
+		return PutObjectResult{}, err
+	}
+
+	// Serialize with other writes and deletes of the object from the
+	// checks against the current object on, the data is staged apart
+	// without the lock.
+	if !lockHeld {
+		unlock := lockObject(bucket, object)
+		defer unlock()
+	}
+	if precheck != nil {
+		if err = precheck(); err != nil {
+			return removeStaged(err)
+		}
+	}
+	// Verify if object name collides on a case-insensitive backend.
+	if err = checkKeyCollision(fs.storage, bucket, object); err != nil {
+		return removeStaged(err)
+	}
+	// Objects under legal hold or retention can't be overwritten.
+	bypassGovernance := fs.bypassGovernance || GovernanceBypassFromContext(ctx)
+	if err = checkObjectMutable(fs.storage, bucket, object, bypassGovernance); err != nil {
+		return removeStaged(err)
+	}
+	// Account the object in the bucket stats once written.
+	defer fs.bucketStats.track(fs, bucket, object)()
+	// New objects count against the bucket object limit.
+	releaseSlot, err := reserveObjectSlot(fs, fs.storage, meta, bucket, object)
+	if err != nil {
+		return removeStaged(err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			releaseSlot()
+		}
+	}()
+
+	// Objects of versioned buckets get a version ID.
+	if metadata, err = withVersionID(meta, metadata); err != nil {
+		return removeStaged(toObjectErr(err, bucket, object))
+	}
+	// A dedup blob the overwritten object references loses a reference.
+	oldRef, err := lockDedupReference(fs.storage, bucket, object)
+	if err != nil {
+		return removeStaged(toObjectErr(err, bucket, object))
+	}
+	defer oldRef.unlock()
+	// An object overwritten in a versioned bucket is retained, it is
+	// put back if the new object can't be put in place.
+	version, err := retainReplacedObject(fs, fs.storage, meta, bucket, object, false)
+	if err != nil {
+		return removeStaged(toObjectErr(err, bucket, object))
+	}
+	releaseBlob := func() {}
+	if dedup {
+		sha256Sum := hex.EncodeToString(dedupWriter.Sum(nil))
+		metadata, releaseBlob, err = dedupStaged(fs.storage, tempObj, n, newMD5Hex, sha256Sum, metadata)
+	}
+	if err == nil {
+		if err = fs.storage.RenameFile(minioMetaBucket, tempObj, bucket, object); err != nil {
+			releaseBlob()
+		}
+	}
+	if err != nil {
+		if rerr := version.restore(); rerr != nil {
+			errorIf(rerr, "Unable to restore "+bucket+"/"+object+" from its version.", nil)
+		}
+		return removeStaged(toObjectErr(err, bucket, object))
+	}
+	// Object is in place, keep its slot even if saving metadata fails.
+	committed = true
+	invalidateTreeWalks(fs, bucket, object)
+
+	// Mark the object pending replication if the bucket replicates.
+	metadata, replicate := replicationMetadata(meta, withETag(metadata, newMD5Hex))
+	// Remove sidecar metadata of an overwritten object, then save new metadata.
+	if err = deleteObjectMetadata(fs.storage, bucket, object); err != nil {
+		return PutObjectResult{}, toObjectErr(err, bucket, object)
+	}
+	if err = writeObjectMetadata(fs.storage, bucket, object, metadata); err != nil {
+		return PutObjectResult{}, toObjectErr(err, bucket, object)
+	}
+	// A retained version keeps referencing the dedup blob.
+	if version == nil {
+		if err = oldRef.release(); err != nil {
+			return PutObjectResult{}, toObjectErr(err, bucket, object)
+		}
+	}
+	// Flush the object to stable storage only if asked to.
+	if isDurablePut(metadata) {
+		if err = syncObject(fs.storage, bucket, object, metadata); err != nil {
+			return PutObjectResult{}, toObjectErr(err, bucket, object)
+		}
+	}
+	if replicate {
+		fs.replicator.enqueue(replicationJob{bucket: bucket, object: object})
 	}
 
 	// Return md5sum, successfully wrote object.
-	return newMD5Hex, nil
+	return PutObjectResult{ETag: newMD5Hex, Checksums: checksumsHex(hashers)}, nil
+}
+
+// AppendObject - appends data to an object, the object is put again
+// with data after its current content. Missing objects are created.
+// Appends to an object are serialized.
+func (fs fsObjects) AppendObject(bucket, object string, data io.Reader) (ObjectInfo, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return ObjectInfo{}, BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(fs.storage, bucket) {
+		return ObjectInfo{}, BucketNotFound{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return ObjectInfo{}, ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	unlock := lockObject(bucket, object)
+	defer unlock()
+	return appendObjectRewrite(fs, fs.storage, bucket, object, data, fs.putObject)
+}
+
+// GetPutObjectCheckpoint - returns the offset an interrupted
+// checkpointed PutObject can be resumed from.
+func (fs fsObjects) GetPutObjectCheckpoint(bucket, object string) (int64, error) {
+	return getPutObjectCheckpoint(fs.storage, bucket, object)
+}
+
+// ReplicateObject - queue an object for replication again, such as
+// an object whose replication failed.
+func (fs fsObjects) ReplicateObject(bucket, object string) error {
+	return replicateObject(fs, fs.storage, fs.replicator, bucket, object)
+}
+
+// CopyObject - copy an object on the server, a nil metadata keeps the
+// source metadata. Copying an object onto itself replaces only its
+// metadata.
+func (fs fsObjects) CopyObject(srcBucket, srcObject, dstBucket, dstObject string, metadata map[string]string) (ObjectInfo, error) {
+	return copyObjectCommon(fs, srcBucket, srcObject, dstBucket, dstObject, metadata)
+}
+
+// RenameObject - rename an object within a bucket without copying its
+// data. An existing dstObject is replaced only if overwrite is set.
+func (fs fsObjects) RenameObject(bucket, srcObject, dstObject string, overwrite bool) error {
+	return renameObjectCommon(fs, bucket, srcObject, dstObject, overwrite, func(srcObject, dstObject string, sidecar []byte) error {
+		if err := fs.storage.RenameFile(bucket, srcObject, bucket, dstObject); err != nil {
+			return err
+		}
+		// Metadata of a replaced object goes along with the sidecar.
+		if err := writeObjectSidecar(fs.storage, bucket, dstObject, sidecar); err != nil {
+			if rerr := fs.storage.RenameFile(bucket, dstObject, bucket, srcObject); rerr != nil {
+				errorIf(rerr, "Unable to move "+bucket+"/"+dstObject+" back to "+srcObject+".", nil)
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// FindDuplicateMoves - complete the renames interrupted by a crash,
+// keeping an object found at both names at the destination only.
+func (fs fsObjects) FindDuplicateMoves() ([]DuplicateMove, error) {
+	return findDuplicateMovesCommon(fs, func(bucket, object string) error {
+		_, err := fs.removeLockedObject(bucket, object, false)
+		return err
+	})
+}
+
+// GetObjectConditional - read an object only if its ETag and mod time
+// satisfy cond, fails with PreconditionFailed or NotModified otherwise.
+func (fs fsObjects) GetObjectConditional(bucket, object string, startOffset int64, cond ObjectConditions) (io.ReadCloser, error) {
+	return getObjectConditionalCommon(fs, bucket, object, startOffset, cond)
+}
+
+// PutObjectConditional - create or overwrite an object only if its
+// current ETag and mod time satisfy cond, fails with PreconditionFailed
+// otherwise.
+func (fs fsObjects) PutObjectConditional(bucket, object string, size int64, data io.Reader, metadata map[string]string, cond ObjectConditions) (string, error) {
+	return putObjectConditionalCommon(fs, bucket, object, size, data, metadata, cond, fs.putObject)
+}
+
+// CompareAndSwapObject - atomically replace an object only if its
+// current ETag is expectedETag, an empty expectedETag creates the
+// object only if it doesn't exist.
+func (fs fsObjects) CompareAndSwapObject(bucket, object string, expectedETag string, newData io.Reader, size int64) (string, error) {
+	return compareAndSwapObjectCommon(fs, bucket, object, expectedETag, newData, size, fs.putObject)
+}
+
+// UpdateMetadataPrefix - update metadata of all objects under prefix
+// without rewriting their data, update returns the keys to change for
+// each object, an empty value removes the key.
+func (fs fsObjects) UpdateMetadataPrefix(bucket, prefix string, update func(ObjectInfo) map[string]string, workers int) ([]MetadataUpdateResult, error) {
+	return updateMetadataPrefixCommon(fs, bucket, prefix, update, workers)
+}
+
+// PutObjectLegalHold - place or remove the legal hold of an object, an
+// object under legal hold can't be overwritten or deleted.
+func (fs fsObjects) PutObjectLegalHold(bucket, object string, on bool) error {
+	return putObjectLegalHold(fs, fs.storage, bucket, object, on)
+}
+
+// GetObjectLegalHold - get the legal hold status of an object.
+func (fs fsObjects) GetObjectLegalHold(bucket, object string) (bool, error) {
+	return getObjectLegalHold(fs, fs.storage, bucket, object)
+}
+
+// PutObjectTags - replace the tag set of an object without rewriting
+// its data.
+func (fs fsObjects) PutObjectTags(bucket, object string, tags map[string]string) error {
+	return putObjectTags(fs, fs.storage, bucket, object, tags)
+}
+
+// GetObjectTags - get the tag set of an object.
+func (fs fsObjects) GetObjectTags(bucket, object string) (map[string]string, error) {
+	return getObjectTags(fs, fs.storage, bucket, object)
+}
+
+// DeleteObjectTags - remove all tags of an object.
+func (fs fsObjects) DeleteObjectTags(bucket, object string) error {
+	return putObjectTags(fs, fs.storage, bucket, object, nil)
+}
+
+// RegisterContentType - map objects with extension ext to contentType
+// when they were saved without a content type, instead of the type
+// mimedb knows the extension by. Extensions match regardless of case,
+// an empty contentType removes the mapping.
+func (fs fsObjects) RegisterContentType(ext, contentType string) error {
+	return fs.contentTypes.register(ext, contentType)
+}
+
+// SetMaxObjectSize - change the largest object PutObject accepts, data
+// of unknown size is refused once it goes over.
+func (fs fsObjects) SetMaxObjectSize(maxSize int64) error {
+	return fs.sizeLimit.set(maxSize)
+}
+
+// MaxObjectSize - largest object PutObject accepts.
+func (fs fsObjects) MaxObjectSize() int64 {
+	return fs.sizeLimit.get()
+}
+
+// SetMaxListKeys - change the most keys a single listing returns,
+// listings asking for more are clamped and come back truncated.
+func (fs fsObjects) SetMaxListKeys(maxKeys int) error {
+	return fs.listLimit.set(maxKeys)
+}
+
+// MaxListKeys - most keys a single listing returns.
+func (fs fsObjects) MaxListKeys() int {
+	return fs.listLimit.get()
 }
 
 func (fs fsObjects) DeleteObject(bucket, object string) error {
+	_, err := fs.deleteObjectCommon(bucket, object, false)
+	return err
+}
+
+// DeleteObjectDryRun - validate the delete of an object and return the
+// storage paths it would remove, without removing anything.
+func (fs fsObjects) DeleteObjectDryRun(bucket, object string) ([]string, error) {
+	return fs.deleteObjectCommon(bucket, object, true)
+}
+
+// deleteObjectCommon - validates the bucket of an object to delete.
+func (fs fsObjects) deleteObjectCommon(bucket, object string, dryRun bool) ([]string, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(fs.storage, bucket) {
+		return nil, BucketNotFound{Bucket: bucket}
+	}
+	return fs.removeObject(bucket, object, dryRun)
+}
+
+// DeleteObjects - delete objects of a bucket, the bucket is validated
+// once for all of them. Errors are returned by position of the object,
+// objects which don't exist aren't an error.
+func (fs fsObjects) DeleteObjects(bucket string, objects []string) ([]error, error) {
+	_, errs, err := fs.deleteObjectsCommon(bucket, objects, false)
+	return errs, err
+}
+
+// DeleteObjectsDryRun - validate the delete of objects of a bucket and
+// return the storage paths it would remove, without removing anything.
+func (fs fsObjects) DeleteObjectsDryRun(bucket string, objects []string) ([]string, []error, error) {
+	return fs.deleteObjectsCommon(bucket, objects, true)
+}
+
+// deleteObjectsCommon - validates the bucket of objects to delete.
+func (fs fsObjects) deleteObjectsCommon(bucket string, objects []string, dryRun bool) ([]string, []error, error) {
 	// Verify if bucket is valid.
 	if !IsValidBucketName(bucket) {
-		return BucketNameInvalid{Bucket: bucket}
+		return nil, nil, BucketNameInvalid{Bucket: bucket}
 	}
 	if !isBucketExist(fs.storage, bucket) {
-		return BucketNotFound{Bucket: bucket}
+		return nil, nil, BucketNotFound{Bucket: bucket}
 	}
+	paths, errs := deleteObjects(bucket, objects, dryRun, fs.removeObject)
+	return paths, errs, nil
+}
+
+// removeObject - delete an object of a bucket known to exist, returns
+// the storage paths removed. A dryRun runs all the checks of a delete
+// and returns the paths it would remove.
+func (fs fsObjects) removeObject(bucket, object string, dryRun bool) ([]string, error) {
 	if !IsValidObjectName(object) {
-		return ObjectNameInvalid{Bucket: bucket, Object: object}
+		return nil, ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	if !dryRun {
+		// Serialize with other writes and deletes of the object.
+		unlock := lockObject(bucket, object)
+		defer unlock()
+	}
+	return fs.removeLockedObject(bucket, object, dryRun)
+}
+
+// removeLockedObject - removeObject past taking the object write lock,
+// for callers holding it already.
+func (fs fsObjects) removeLockedObject(bucket, object string, dryRun bool) ([]string, error) {
+	meta, err := readBucketMetadata(fs.storage, bucket)
+	if err != nil {
+		return nil, toObjectErr(err, bucket)
+	}
+	if err = checkObjectPrefixAllowed(meta, bucket, object); err != nil {
+		return nil, err
 	}
-	if err := fs.storage.DeleteFile(bucket, object); err != nil {
+	// Objects under legal hold or retention can't be deleted.
+	if err = checkObjectMutable(fs.storage, bucket, object, fs.bypassGovernance); err != nil {
+		return nil, err
+	}
+	if meta.Versioning {
+		return nil, fs.removeVersionedObject(meta, bucket, object, dryRun)
+	}
+	plan, err := planObjectDelete(fs.storage, bucket, object, false)
+	if err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	if dryRun {
+		return plan.paths(), nil
+	}
+	// Account the deletion in the bucket stats.
+	defer fs.bucketStats.track(fs, bucket, object)()
+	// The dedup blob of the object, if any, loses a reference.
+	ref, err := lockDedupReference(fs.storage, bucket, object)
+	if err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	defer ref.unlock()
+	if err = plan.execute(fs.storage, defaultDeletePartsConcurrency); err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	invalidateTreeWalks(fs, bucket, object)
+	if err = ref.release(); err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	if err = releaseObjectSlot(fs.storage, meta, bucket); err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	return plan.paths(), nil
+}
+
+// removeVersionedObject - removeObject of a versioned bucket, the
+// object is retained along with its dedup blob reference and nothing
+// is removed.
+func (fs fsObjects) removeVersionedObject(meta bucketMetadata, bucket, object string, dryRun bool) error {
+	if dryRun {
+		return retainDeletedObject(fs, fs.storage, bucket, object, true)
+	}
+	// Account the deletion in the bucket stats.
+	defer fs.bucketStats.track(fs, bucket, object)()
+	ref, err := lockDedupReference(fs.storage, bucket, object)
+	if err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+	defer ref.unlock()
+	if err = retainDeletedObject(fs, fs.storage, bucket, object, false); err != nil {
+		return err
+	}
+	invalidateTreeWalks(fs, bucket, object)
+	if err = releaseObjectSlot(fs.storage, meta, bucket); err != nil {
 		return toObjectErr(err, bucket, object)
 	}
 	return nil
@@ -233,5 +1087,64 @@ func (fs fsObjects) DeleteObject(bucket, object string) error {
 
 // ListObjects - list all objects.
 func (fs fsObjects) ListObjects(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error) {
-	return listObjectsCommon(fs, bucket, prefix, marker, delimiter, maxKeys)
+	return listObjectsCommon(fs, bucket, prefix, marker, delimiter, maxKeys, false)
+}
+
+// ListObjectsWithMetadata - opt-in variant of ListObjects which also
+// returns the content type, ETag and tags of the objects, at the cost
+// of reading the metadata of each one.
+func (fs fsObjects) ListObjectsWithMetadata(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error) {
+	return listObjectsCommon(fs, bucket, prefix, marker, delimiter, maxKeys, true)
+}
+
+// ListObjectVersions - list the versions and delete markers of the
+// objects at prefix, in key order and newest first per key.
+func (fs fsObjects) ListObjectVersions(bucket, prefix, keyMarker, versionIDMarker string, maxKeys int) (ListObjectVersionsInfo, error) {
+	return listObjectVersionsCommon(fs, bucket, prefix, keyMarker, versionIDMarker, maxKeys)
+}
+
+// ListObjectShards - split keys at prefix into ranges listable in parallel.
+func (fs fsObjects) ListObjectShards(bucket, prefix string, numShards int) ([]KeyRange, error) {
+	return listObjectShardsCommon(fs, bucket, prefix, numShards)
+}
+
+// ListObjectsV2 - list objects with continuation tokens instead of
+// markers.
+func (fs fsObjects) ListObjectsV2(bucket, prefix, continuationToken, startAfter, delimiter string, maxKeys int) (ListObjectsV2Info, error) {
+	return listObjectsV2Common(fs, bucket, prefix, continuationToken, startAfter, delimiter, maxKeys)
+}
+
+// OpenListCursor - open a listing of bucket kept on the server, pages
+// are read with ListNext until the cursor is closed or expires.
+func (fs fsObjects) OpenListCursor(bucket, prefix, delimiter string) (string, error) {
+	return openListCursorCommon(fs, bucket, prefix, delimiter)
+}
+
+// ListNext - list the next maxKeys entries of a list cursor.
+func (fs fsObjects) ListNext(cursorID string, maxKeys int) (ListObjectsInfo, error) {
+	return listNextCommon(fs, cursorID, maxKeys)
+}
+
+// CloseListCursor - close a list cursor, releasing its tree walk.
+func (fs fsObjects) CloseListCursor(cursorID string) error {
+	return closeListCursorCommon(fs, cursorID)
+}
+
+// ListObjectsFiltered - recursively list objects under prefix matching
+// filter, starting after marker.
+func (fs fsObjects) ListObjectsFiltered(bucket, prefix, marker string, filter func(name string) bool, maxKeys int) (ListObjectsInfo, error) {
+	return listObjectsFilteredCommon(fs, bucket, prefix, marker, filter, maxKeys)
+}
+
+// ListObjectsGlob - recursively list objects under prefix whose name
+// after prefix matches pattern, starting after marker. Supports '*'
+// and '?' wildcards.
+func (fs fsObjects) ListObjectsGlob(bucket, prefix, marker, pattern string, maxKeys int) (ListObjectsInfo, error) {
+	return listObjectsFilteredCommon(fs, bucket, prefix, marker, globFilter(prefix, pattern), maxKeys)
+}
+
+// WalkObjects - streams the objects under prefix recursively until the
+// walk ends or ctx is cancelled.
+func (fs fsObjects) WalkObjects(ctx context.Context, bucket, prefix string) (<-chan ObjectInfo, <-chan error) {
+	return walkObjectsCommon(ctx, fs, bucket, prefix)
 }
@@ -18,6 +18,8 @@ package main
 
 import (
 	"io"
+	"net/http"
+	"strings"
 )
 
 // validates location constraint from the request body.
@@ -52,3 +54,28 @@ func isValidLocationContraint(reqBody io.Reader, serverRegion string) APIErrorCo
 	}
 	return errCode
 }
+
+// userMetadataPrefix - prefix of user defined metadata headers.
+const userMetadataPrefix = "x-amz-meta-"
+
+// extractMetadataFromHeader - metadata of an object to be saved from the
+// request headers, the content type and encoding along with all user
+// defined metadata. Keys are lower cased.
+func extractMetadataFromHeader(header http.Header) map[string]string {
+	metadata := make(map[string]string)
+	if contentType := header.Get("Content-Type"); contentType != "" {
+		metadata["content-type"] = contentType
+	}
+	// Content encoding of the uploaded data, used to negotiate the
+	// encoding on reads.
+	if contentEncoding := header.Get("Content-Encoding"); contentEncoding != "" {
+		metadata[contentEncodingKey] = contentEncoding
+	}
+	for key := range header {
+		lowerKey := strings.ToLower(key)
+		if strings.HasPrefix(lowerKey, userMetadataPrefix) {
+			metadata[lowerKey] = header.Get(key)
+		}
+	}
+	return metadata
+}
@@ -26,6 +26,16 @@ type fields map[string]interface{}
 
 var log = logrus.New() // Default console logger.
 
+// Logger - leveled logger an object layer reports to, such as the
+// logger of an application embedding the server. The package-global
+// log satisfies it and is the default.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
 // logger carries logging configuration for various supported loggers.
 // Currently supported loggers are
 //
@@ -0,0 +1,50 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "sync/atomic"
+
+// globalMaintenanceMode - set while the server is in maintenance mode,
+// destructive admin operations refuse to run otherwise. Regular reads
+// and writes are not affected by it.
+var globalMaintenanceMode int32
+
+// EnterMaintenanceMode - allows destructive admin operations to run.
+func EnterMaintenanceMode() {
+	atomic.StoreInt32(&globalMaintenanceMode, 1)
+}
+
+// ExitMaintenanceMode - refuses destructive admin operations again.
+func ExitMaintenanceMode() {
+	atomic.StoreInt32(&globalMaintenanceMode, 0)
+}
+
+// IsMaintenanceMode - returns true if the server is in maintenance mode.
+func IsMaintenanceMode() bool {
+	return atomic.LoadInt32(&globalMaintenanceMode) == 1
+}
+
+// checkMaintenanceMode - returns MaintenanceModeRequired for operation
+// unless the server is in maintenance mode. To be called first by
+// destructive admin operations, forced bucket deletes and format
+// migration. Heals of single objects are routine and not gated.
+func checkMaintenanceMode(operation string) error {
+	if !IsMaintenanceMode() {
+		return MaintenanceModeRequired{Operation: operation}
+	}
+	return nil
+}
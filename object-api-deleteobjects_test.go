@@ -0,0 +1,84 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Wrapper for calling DeleteObjects tests for both XL multiple disks and single node setup.
+func TestDeleteObjects(t *testing.T) {
+	ExecObjectLayerTest(t, testDeleteObjects)
+}
+
+// Tests validate deleting objects in a batch reports errors by
+// position and treats missing objects as deleted.
+func testDeleteObjects(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if _, err := obj.DeleteObjects(bucket, []string{"object"}); err == nil {
+		t.Fatalf("%s: Expected BucketNotFound", instanceType)
+	} else if _, ok := err.(BucketNotFound); !ok {
+		t.Fatalf("%s: Expected BucketNotFound, but instead found %v", instanceType, err)
+	}
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	data := []byte("hello")
+	for _, object := range []string{"a", "dir/b"} {
+		if _, err := obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	md5Sum, err := obj.PutObjectPart(bucket, "multipart", uploadID, 1, int64(len(data)), bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, "multipart", uploadID, []completePart{{PartNumber: 1, ETag: md5Sum}}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	objects := []string{"a", "missing", "", "dir/b", "multipart"}
+	errs, err := obj.DeleteObjects(bucket, objects)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(errs) != len(objects) {
+		t.Fatalf("%s: Expected %d errors, but instead found %d", instanceType, len(objects), len(errs))
+	}
+	for i, err := range errs {
+		if objects[i] == "" {
+			if _, ok := err.(ObjectNameInvalid); !ok {
+				t.Errorf("%s: Expected ObjectNameInvalid for an empty name, but instead found %v", instanceType, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Expected %s to be deleted, but instead found %s", instanceType, objects[i], err)
+		}
+	}
+	for _, object := range []string{"a", "dir/b", "multipart"} {
+		if _, err = obj.GetObjectInfo(bucket, object); err == nil {
+			t.Errorf("%s: Expected %s to not exist", instanceType, object)
+		}
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"strconv"
 	"testing"
 )
@@ -47,6 +48,9 @@ func testGetObjectInfo(obj ObjectLayer, instanceType string, t *testing.T) {
 		// ObjectInfo -1.
 		// ObjectName set to a existing object in the test case (Test case 14).
 		{Bucket: "test-getobjectinfo", Name: "Asia/asiapics.jpg", ContentType: "image/jpeg", IsDir: false},
+		// ObjectInfo -2.
+		// ObjectName set to a prefix of an existing object (Test case 13).
+		{Bucket: "test-getobjectinfo", Name: "Asia", ContentType: "application/octet-stream", IsDir: true},
 	}
 	testCases := []struct {
 		bucketName string
@@ -74,7 +78,7 @@ func testGetObjectInfo(obj ObjectLayer, instanceType string, t *testing.T) {
 		{"test-getobjectinfo", "Antartica", ObjectInfo{}, ObjectNotFound{Bucket: "test-getobjectinfo", Object: "Antartica"}, false},
 		{"test-getobjectinfo", "Asia/myfile", ObjectInfo{}, ObjectNotFound{Bucket: "test-getobjectinfo", Object: "Asia/myfile"}, false},
 		// Test case with existing bucket but object name set to a directory (Test number 13).
-		{"test-getobjectinfo", "Asia", ObjectInfo{}, ObjectNotFound{Bucket: "test-getobjectinfo", Object: "Asia"}, false},
+		{"test-getobjectinfo", "Asia", resultCases[1], nil, true},
 		// Valid case with existing object (Test number 14).
 		{"test-getobjectinfo", "Asia/asiapics.jpg", resultCases[0], nil, true},
 	}
@@ -159,3 +163,318 @@ func BenchmarkGetObject(b *testing.B) {
 		r.Close()
 	}
 }
+
+// Benchmark GetObjectInfo of missing objects on XL, as for HEAD
+// requests of keys which don't exist.
+func BenchmarkXLGetObjectInfoNotFound(b *testing.B) {
+	initNSLock()
+	var disks []string
+	for i := 0; i < 8; i++ {
+		directory, err := ioutil.TempDir("", "minio-benchmark-getobjectinfo")
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer os.RemoveAll(directory)
+		disks = append(disks, directory)
+	}
+
+	// Create the obj.
+	obj, err := newXLObjects(disks...)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// Make a bucket.
+	err = obj.MakeBucket("bucket")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := obj.GetObjectInfo("bucket", "object"+strconv.Itoa(i%10)); err == nil {
+			b.Error("GetObjectInfo of a missing object succeeded")
+		}
+	}
+}
+
+// Wrapper for calling GetObjectInfo prefix tests for both XL multiple disks and single node setup.
+func TestGetObjectInfoPrefix(t *testing.T) {
+	ExecObjectLayerTest(t, testGetObjectInfoPrefix)
+}
+
+// Tests validate prefixes listed as common prefixes by ListObjects are
+// directories for GetObjectInfo, and nothing else is.
+func testGetObjectInfoPrefix(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	for _, object := range []string{"photos/2016/march/a.jpg", "photos/b.jpg"} {
+		if _, err := obj.PutObject(bucket, object, 1, bytes.NewBufferString("a"), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, "videos/clip.mp4", nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	md5Sum, err := obj.PutObjectPart(bucket, "videos/clip.mp4", uploadID, 1, 1, bytes.NewBufferString("a"), "")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, "videos/clip.mp4", uploadID, []completePart{{PartNumber: 1, ETag: md5Sum}}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	testCases := []struct {
+		object string
+		isDir  bool
+	}{
+		{"photos", true},
+		{"photos/2016", true},
+		{"photos/2016/march", true},
+		{"videos", true},
+		// Partial names of a prefix aren't directories.
+		{"phot", false},
+		{"photos/201", false},
+		{"music", false},
+	}
+	for i, testCase := range testCases {
+		objInfo, err := obj.GetObjectInfo(bucket, testCase.object)
+		if !testCase.isDir {
+			if _, ok := err.(ObjectNotFound); !ok {
+				t.Errorf("%s: Test %d: Expected ObjectNotFound, got %v", instanceType, i+1, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		if !objInfo.IsDir || objInfo.Size != 0 || objInfo.Name != testCase.object {
+			t.Errorf("%s: Test %d: Expected directory %s, got %+v", instanceType, i+1, testCase.object, objInfo)
+		}
+	}
+}
+
+// Wrapper for calling GetObjectInfo content type tests for both XL multiple disks and single node setup.
+func TestGetObjectInfoContentType(t *testing.T) {
+	ExecObjectLayerTest(t, testGetObjectInfoContentType)
+}
+
+// Tests validate a content type given at upload is preserved over the
+// one guessed from the object extension.
+func testGetObjectInfoContentType(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	testCases := []struct {
+		object      string
+		metadata    map[string]string
+		contentType string
+	}{
+		// Explicit type wins over the extension.
+		{"report.dat", map[string]string{"content-type": "application/pdf"}, "application/pdf"},
+		{"photo.jpg", map[string]string{"Content-Type": "image/png"}, "image/png"},
+		// Guessed from the extension.
+		{"photo.jpeg", nil, "image/jpeg"},
+		{"page.html", map[string]string{"content-type": ""}, "text/html"},
+		// No extension and no type.
+		{"noextension", nil, "application/octet-stream"},
+	}
+	for i, testCase := range testCases {
+		data := []byte("data")
+		if _, err := obj.PutObject(bucket, testCase.object, int64(len(data)), bytes.NewReader(data), testCase.metadata); err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		objInfo, err := obj.GetObjectInfo(bucket, testCase.object)
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		if objInfo.ContentType != testCase.contentType {
+			t.Errorf("%s: Test %d: Expected content type %s, got %s", instanceType, i+1, testCase.contentType, objInfo.ContentType)
+		}
+	}
+}
+
+// Wrapper for calling GetObjectInfo parts tests for both XL multiple disks and single node setup.
+func TestGetObjectInfoParts(t *testing.T) {
+	ExecObjectLayerTest(t, testGetObjectInfoParts)
+}
+
+// Tests validate part numbers, sizes and offsets are reported for
+// multipart objects only.
+func testGetObjectInfoParts(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello, world")
+	if _, err := obj.PutObject(bucket, "simple", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	objInfo, err := obj.GetObjectInfo(bucket, "simple")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if objInfo.Parts != nil {
+		t.Fatalf("%s: Expected no parts for a simple object, got %v", instanceType, objInfo.Parts)
+	}
+
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	partSizes := []int64{5 * 1024 * 1024, 5*1024*1024 + 3, 7}
+	var parts []completePart
+	for i, size := range partSizes {
+		// Part numbers needn't be contiguous.
+		partID := 2 * (i + 1)
+		var md5Sum string
+		md5Sum, err = obj.PutObjectPart(bucket, "multipart", uploadID, partID, size, bytes.NewReader(bytes.Repeat([]byte("a"), int(size))), "")
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		parts = append(parts, completePart{PartNumber: partID, ETag: md5Sum})
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, "multipart", uploadID, parts); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	objInfo, err = obj.GetObjectInfo(bucket, "multipart")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(objInfo.Parts) != len(partSizes) {
+		t.Fatalf("%s: Expected %d parts, got %d", instanceType, len(partSizes), len(objInfo.Parts))
+	}
+	offset := int64(0)
+	for i, part := range objInfo.Parts {
+		expected := ObjectPartInfo{Number: 2 * (i + 1), Size: partSizes[i], Offset: offset}
+		if part != expected {
+			t.Errorf("%s: Part %d: Expected %+v, got %+v", instanceType, i+1, expected, part)
+		}
+		offset += partSizes[i]
+	}
+}
+
+// Wrapper for calling GetObjectInfo user-defined metadata tests for both XL multiple disks and single node setup.
+func TestGetObjectInfoUserDefined(t *testing.T) {
+	ExecObjectLayerTest(t, testGetObjectInfoUserDefined)
+}
+
+// Tests validate that user-defined metadata saved at upload is
+// returned as stored, without the keys of the object layer.
+func testGetObjectInfoUserDefined(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello")
+	hasher := md5.New()
+	hasher.Write(data)
+	md5Sum := hex.EncodeToString(hasher.Sum(nil))
+	userDefined := map[string]string{
+		"x-amz-meta-Color": "blue",
+		"x-amz-meta-empty": "",
+		"cache-control":    "no-cache",
+	}
+	metadata := map[string]string{
+		"md5Sum":       md5Sum,
+		"content-type": "text/plain",
+	}
+	for k, v := range userDefined {
+		metadata[k] = v
+	}
+	if _, err := obj.PutObject(bucket, "simple", int64(len(data)), bytes.NewReader(data), metadata); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err := obj.PutObject(bucket, "plain", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", map[string]string{"x-amz-meta-Color": "blue", "content-type": "text/plain"})
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.PutObjectPart(bucket, "multipart", uploadID, 1, int64(len(data)), bytes.NewReader(data), md5Sum); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, "multipart", uploadID, []completePart{{PartNumber: 1, ETag: md5Sum}}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	testCases := []struct {
+		object   string
+		expected map[string]string
+	}{
+		{"simple", userDefined},
+		{"plain", map[string]string{}},
+		{"multipart", map[string]string{"x-amz-meta-Color": "blue"}},
+	}
+	for i, testCase := range testCases {
+		objInfo, err := obj.GetObjectInfo(bucket, testCase.object)
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		if objInfo.UserDefined == nil {
+			t.Fatalf("%s: Test %d: Expected a non nil user-defined metadata", instanceType, i+1)
+		}
+		if !reflect.DeepEqual(objInfo.UserDefined, testCase.expected) {
+			t.Errorf("%s: Test %d: Expected user-defined metadata %v, got %v", instanceType, i+1, testCase.expected, objInfo.UserDefined)
+		}
+	}
+}
+
+// Wrapper for calling GetObjectsInfo tests for both XL multiple disks and single node setup.
+func TestGetObjectsInfo(t *testing.T) {
+	initNSLock()
+	ExecObjectLayerTest(t, testGetObjectsInfo)
+}
+
+// Tests validate GetObjectsInfo returns the info of each object in the
+// position it was asked in, missing objects failing alone.
+func testGetObjectsInfo(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	objects := []string{"a", "missing", "dir/b", "dir", "c"}
+	for _, object := range []string{"a", "dir/b", "c"} {
+		if _, err := obj.PutObject(bucket, object, int64(len(object)), bytes.NewBufferString(object), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	infos, errs := obj.GetObjectsInfo(bucket, objects)
+	if len(infos) != len(objects) || len(errs) != len(objects) {
+		t.Fatalf("%s: Expected %d infos and errors, got %d and %d", instanceType, len(objects), len(infos), len(errs))
+	}
+	for i, object := range objects {
+		expected, expectedErr := obj.GetObjectInfo(bucket, object)
+		if object == "missing" {
+			if _, ok := errs[i].(ObjectNotFound); !ok {
+				t.Errorf("%s: Expected ObjectNotFound for %s, got %v", instanceType, object, errs[i])
+			}
+			if !reflect.DeepEqual(infos[i], ObjectInfo{}) {
+				t.Errorf("%s: Expected no info for %s, got %v", instanceType, object, infos[i])
+			}
+			continue
+		}
+		if errs[i] != nil || expectedErr != nil {
+			t.Fatalf("%s: Expected info of %s, got %v", instanceType, object, errs[i])
+		}
+		if infos[i].Name != object || infos[i].Size != expected.Size || infos[i].IsDir != expected.IsDir || infos[i].MD5Sum != expected.MD5Sum {
+			t.Errorf("%s: Expected info %v for %s, got %v", instanceType, expected, object, infos[i])
+		}
+	}
+
+	// A missing bucket fails every object.
+	infos, errs = obj.GetObjectsInfo("missing-bucket", objects)
+	for i := range objects {
+		if _, ok := errs[i].(BucketNotFound); !ok {
+			t.Errorf("%s: Expected BucketNotFound, got %v", instanceType, errs[i])
+		}
+		if infos[i].Name != "" {
+			t.Errorf("%s: Expected no info, got %v", instanceType, infos[i])
+		}
+	}
+}
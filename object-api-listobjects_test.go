@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -500,14 +501,14 @@ func testListObjects(obj ObjectLayer, instanceType string, t *testing.T) {
 		{"test-bucket-list-object", "obj", "obj1", "", 1, resultCases[21], nil, true},
 		{"test-bucket-list-object", "new", "newPrefix0", "", 2, resultCases[22], nil, true},
 		// Testing with maxKeys set to 0 (48-54).
-		// The parameters have to valid.
-		{"test-bucket-list-object", "", "obj1", "", 0, ListObjectsInfo{}, nil, true},
-		{"test-bucket-list-object", "", "obj0", "", 0, ListObjectsInfo{}, nil, true},
-		{"test-bucket-list-object", "new", "", "", 0, ListObjectsInfo{}, nil, true},
-		{"test-bucket-list-object", "obj", "", "", 0, ListObjectsInfo{}, nil, true},
-		{"test-bucket-list-object", "obj", "obj0", "", 0, ListObjectsInfo{}, nil, true},
-		{"test-bucket-list-object", "obj", "obj1", "", 0, ListObjectsInfo{}, nil, true},
-		{"test-bucket-list-object", "new", "newPrefix0", "", 0, ListObjectsInfo{}, nil, true},
+		// The parameters have to valid, up to the default number of keys is listed.
+		{"test-bucket-list-object", "", "obj1", "", 0, resultCases[12], nil, true},
+		{"test-bucket-list-object", "", "obj0", "", 0, resultCases[11], nil, true},
+		{"test-bucket-list-object", "new", "", "", 0, resultCases[5], nil, true},
+		{"test-bucket-list-object", "obj", "", "", 0, resultCases[6], nil, true},
+		{"test-bucket-list-object", "obj", "obj0", "", 0, resultCases[20], nil, true},
+		{"test-bucket-list-object", "obj", "obj1", "", 0, resultCases[21], nil, true},
+		{"test-bucket-list-object", "new", "newPrefix0", "", 0, resultCases[22], nil, true},
 		// Tests on hierarchical key names as prefix.
 		// Without delimteter the code should recurse into the prefix Dir.
 		// Tests with prefix, but without delimiter (55-56).
@@ -563,6 +564,191 @@ func testListObjects(obj ObjectLayer, instanceType string, t *testing.T) {
 
 }
 
+// Wrapper for calling ListObjectsWithMetadata tests for both XL multiple disks and single node setup.
+func TestListObjectsWithMetadata(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testListObjectsWithMetadata)
+}
+
+// Tests validate listings with metadata carry the content type, ETag,
+// tags and uploaded size of each object, while plain listings don't
+// read the metadata.
+func testListObjectsWithMetadata(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := bytes.Repeat([]byte("hello, world\n"), 1000)
+	testCases := []struct {
+		object      string
+		metadata    map[string]string
+		contentType string
+		tags        map[string]string
+	}{
+		{"dir/page.html", map[string]string{"content-type": "text/html"}, "text/html", map[string]string{"site": "docs"}},
+		// Content type guessed from the extension.
+		{"dir/notes.txt", nil, "text/plain", nil},
+		// Stored compressed, listed by its uploaded size.
+		{"log", map[string]string{compressKey: "true"}, "application/octet-stream", nil},
+	}
+	md5Sums := make(map[string]string)
+	for _, testCase := range testCases {
+		md5Sum, err := obj.PutObject(bucket, testCase.object, int64(len(data)), bytes.NewReader(data), testCase.metadata)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		md5Sums[testCase.object] = md5Sum
+		if testCase.tags != nil {
+			if err = obj.PutObjectTags(bucket, testCase.object, testCase.tags); err != nil {
+				t.Fatalf("%s : %s", instanceType, err.Error())
+			}
+		}
+	}
+
+	result, err := obj.ListObjectsWithMetadata(bucket, "", "", "", 1000)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(result.Objects) != len(testCases) {
+		t.Fatalf("%s: Expected %d objects, got %d", instanceType, len(testCases), len(result.Objects))
+	}
+	listed := make(map[string]ObjectInfo)
+	for _, objInfo := range result.Objects {
+		listed[objInfo.Name] = objInfo
+	}
+	for i, testCase := range testCases {
+		objInfo := listed[testCase.object]
+		if objInfo.ContentType != testCase.contentType {
+			t.Errorf("%s: Test %d: Expected content type %s, got %s", instanceType, i+1, testCase.contentType, objInfo.ContentType)
+		}
+		if objInfo.MD5Sum != md5Sums[testCase.object] {
+			t.Errorf("%s: Test %d: Expected MD5Sum %s, got %s", instanceType, i+1, md5Sums[testCase.object], objInfo.MD5Sum)
+		}
+		if objInfo.Size != int64(len(data)) {
+			t.Errorf("%s: Test %d: Expected size %d, got %d", instanceType, i+1, len(data), objInfo.Size)
+		}
+		if len(objInfo.Tags) != len(testCase.tags) || objInfo.Tags["site"] != testCase.tags["site"] {
+			t.Errorf("%s: Test %d: Expected tags %v, got %v", instanceType, i+1, testCase.tags, objInfo.Tags)
+		}
+	}
+
+	// Prefixes are listed as usual with a delimiter.
+	result, err = obj.ListObjectsWithMetadata(bucket, "", "", slashSeparator, 1000)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(result.Prefixes) != 1 || result.Prefixes[0] != "dir/" || len(result.Objects) != 1 || result.Objects[0].Name != "log" {
+		t.Fatalf("%s: Expected prefix dir/ and object log, got %v and %d objects", instanceType, result.Prefixes, len(result.Objects))
+	}
+
+	// Plain listings leave the metadata out.
+	result, err = obj.ListObjects(bucket, "dir/", "", "", 1000)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	for _, objInfo := range result.Objects {
+		if objInfo.ContentType != "" || objInfo.Tags != nil {
+			t.Fatalf("%s: Expected no metadata in plain listings, got %s and %v", instanceType, objInfo.ContentType, objInfo.Tags)
+		}
+	}
+}
+
+// Wrapper for calling ListObjects maxKeys limit tests for both XL multiple disks and single node setup.
+func TestListObjectsMaxKeys(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testListObjectsMaxKeys)
+}
+
+// Tests validate unset, negative and over flowing maxKeys list up to
+// the configured limit, and that truncated listings page through all
+// objects by their NextMarker.
+func testListObjectsMaxKeys(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var objects []string
+	for i := 0; i < 7; i++ {
+		object := "obj" + strconv.Itoa(i)
+		if _, err := obj.PutObject(bucket, object, int64(len(object)), bytes.NewBufferString(object), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		objects = append(objects, object)
+	}
+
+	if maxKeys := obj.MaxListKeys(); maxKeys != maxObjectList {
+		t.Fatalf("%s: Expected default limit %d, got %d", instanceType, maxObjectList, maxKeys)
+	}
+	if err := obj.SetMaxListKeys(0); err != errInvalidArgument {
+		t.Fatalf("%s: Expected %v, got %v", instanceType, errInvalidArgument, err)
+	}
+	if err := obj.SetMaxListKeys(3); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	defer obj.SetMaxListKeys(maxObjectList)
+
+	testCases := []struct {
+		maxKeys     int
+		listed      int
+		isTruncated bool
+	}{
+		// Unset and negative counts list up to the limit.
+		{0, 3, true},
+		{-1, 3, true},
+		// Counts over the limit are clamped.
+		{100, 3, true},
+		// Counts under the limit are kept.
+		{2, 2, true},
+		{3, 3, true},
+	}
+	for i, testCase := range testCases {
+		result, err := obj.ListObjects(bucket, "", "", "", testCase.maxKeys)
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		if len(result.Objects) != testCase.listed {
+			t.Fatalf("%s: Test %d: Expected %d objects, got %d", instanceType, i+1, testCase.listed, len(result.Objects))
+		}
+		if result.IsTruncated != testCase.isTruncated {
+			t.Errorf("%s: Test %d: Expected IsTruncated %v, got %v", instanceType, i+1, testCase.isTruncated, result.IsTruncated)
+		}
+		if last := result.Objects[len(result.Objects)-1].Name; result.NextMarker != last {
+			t.Errorf("%s: Test %d: Expected NextMarker %s, got %s", instanceType, i+1, last, result.NextMarker)
+		}
+	}
+
+	// Clamped listings page through every object once, with and
+	// without a delimiter.
+	for _, delimiter := range []string{"", slashSeparator} {
+		var listed []string
+		marker := ""
+		for pages := 0; ; pages++ {
+			if pages > len(objects) {
+				t.Fatalf("%s: Listing with delimiter %q doesn't end", instanceType, delimiter)
+			}
+			result, err := obj.ListObjects(bucket, "", marker, delimiter, 1000)
+			if err != nil {
+				t.Fatalf("%s : %s", instanceType, err.Error())
+			}
+			if len(result.Objects) > 3 {
+				t.Fatalf("%s: Expected at most 3 objects, got %d", instanceType, len(result.Objects))
+			}
+			for _, objInfo := range result.Objects {
+				listed = append(listed, objInfo.Name)
+			}
+			if !result.IsTruncated {
+				break
+			}
+			marker = result.NextMarker
+		}
+		if !reflect.DeepEqual(listed, objects) {
+			t.Errorf("%s: Expected %v with delimiter %q, got %v", instanceType, objects, delimiter, listed)
+		}
+	}
+}
+
 func BenchmarkListObjects(b *testing.B) {
 	// Make a temporary directory to use as the obj.
 	directory, err := ioutil.TempDir("", "minio-list-benchmark")
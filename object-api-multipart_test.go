@@ -18,7 +18,13 @@ package main
 
 import (
 	"bytes"
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -35,7 +41,7 @@ func testObjectNewMultipartUpload(obj ObjectLayer, instanceType string, t *testi
 
 	errMsg := "Bucket not found: minio-bucket"
 	// opearation expected to fail since the bucket on which NewMultipartUpload is being initiated doesn't exist.
-	uploadID, err := obj.NewMultipartUpload(bucket, object)
+	uploadID, err := obj.NewMultipartUpload(bucket, object, nil)
 	if err == nil {
 		t.Fatalf("%s: Expected to fail since the NewMultipartUpload is intialized on a non-existant bucket.", instanceType)
 	}
@@ -50,7 +56,7 @@ func testObjectNewMultipartUpload(obj ObjectLayer, instanceType string, t *testi
 		t.Fatalf("%s : %s", instanceType, err.Error())
 	}
 
-	uploadID, err = obj.NewMultipartUpload(bucket, object)
+	uploadID, err = obj.NewMultipartUpload(bucket, object, nil)
 	if err != nil {
 		t.Fatalf("%s : %s", instanceType, err.Error())
 	}
@@ -83,7 +89,7 @@ func testObjectAPIIsUploadIDExists(obj ObjectLayer, instanceType string, t *test
 		t.Fatalf("%s : %s", instanceType, err.Error())
 	}
 
-	_, err = obj.NewMultipartUpload(bucket, object)
+	_, err = obj.NewMultipartUpload(bucket, object, nil)
 	if err != nil {
 		t.Fatalf("%s : %s", instanceType, err.Error())
 	}
@@ -114,7 +120,7 @@ func testObjectAPIPutObjectPart(obj ObjectLayer, instanceType string, t *testing
 		t.Fatalf("%s : %s", instanceType, err.Error())
 	}
 	// Initiate Multipart Upload on the above created bucket.
-	uploadID, err := obj.NewMultipartUpload(bucket, object)
+	uploadID, err := obj.NewMultipartUpload(bucket, object, nil)
 	if err != nil {
 		// Failed to create NewMultipartUpload, abort.
 		t.Fatalf("%s : %s", instanceType, err.Error())
@@ -219,3 +225,366 @@ func testObjectAPIPutObjectPart(obj ObjectLayer, instanceType string, t *testing
 		}
 	}
 }
+
+// Wrapper for calling user metadata tests for both XL multiple disks and single node setup.
+func TestObjectUserMetadata(t *testing.T) {
+	ExecObjectLayerTest(t, testObjectUserMetadata)
+}
+
+// Tests validate metadata given to PutObject and NewMultipartUpload is
+// saved with the object.
+func testObjectUserMetadata(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	metadata := map[string]string{
+		"content-type":     "application/json",
+		"x-amz-meta-foo":   "bar",
+		contentEncodingKey: "gzip",
+	}
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var storage StorageAPI
+	switch l := obj.(type) {
+	case xlObjects:
+		storage = l.storage
+	case fsObjects:
+		storage = l.storage
+	}
+
+	data := []byte("hello")
+	if _, err := obj.PutObject(bucket, "simple", int64(len(data)), bytes.NewReader(data), metadata); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", metadata)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	md5Sum, err := obj.PutObjectPart(bucket, "multipart", uploadID, 1, int64(len(data)), bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, "multipart", uploadID, []completePart{{PartNumber: 1, ETag: md5Sum}}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	for _, object := range []string{"simple", "multipart"} {
+		objMetadata, err := readObjectMetadata(storage, bucket, object)
+		if err != nil {
+			t.Fatalf("%s: %s: %s", instanceType, object, err.Error())
+		}
+		for k, v := range metadata {
+			if objMetadata[k] != v {
+				t.Errorf("%s: %s: Expected %s to be \"%s\", but instead found \"%s\".", instanceType, object, k, v, objMetadata[k])
+			}
+		}
+		objInfo, err := obj.GetObjectInfo(bucket, object)
+		if err != nil {
+			t.Fatalf("%s: %s: %s", instanceType, object, err.Error())
+		}
+		if objInfo.ContentType != "application/json" {
+			t.Errorf("%s: %s: Expected content type application/json, but instead found \"%s\".", instanceType, object, objInfo.ContentType)
+		}
+	}
+}
+
+// Wrapper for calling concurrent part upload tests for both XL multiple disks and single node setup.
+func TestObjectConcurrentPartUploads(t *testing.T) {
+	ExecObjectLayerTest(t, testObjectConcurrentPartUploads)
+}
+
+// Tests validate parts uploaded concurrently, including a part uploaded
+// again, are listed once each and assemble the expected object.
+func testObjectConcurrentPartUploads(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	object := "minio-object"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, object, nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	partData := func(partID int, b byte) []byte {
+		// All parts except the last one need to be at least 5MB.
+		if partID == 4 {
+			return bytes.Repeat([]byte{b}, 10)
+		}
+		return bytes.Repeat([]byte{b}, 5*1024*1024)
+	}
+	// Part 1 is uploaded again below, the first upload is replaced.
+	if _, err = obj.PutObjectPart(bucket, object, uploadID, 1, 5*1024*1024, bytes.NewReader(partData(1, 'x')), ""); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(partID int) {
+			defer wg.Done()
+			data := partData(partID, byte('a'+partID))
+			_, errs[partID-1] = obj.PutObjectPart(bucket, object, uploadID, partID, int64(len(data)), bytes.NewReader(data), "")
+		}(i + 1)
+	}
+	wg.Wait()
+	for _, err = range errs {
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+
+	listInfo, err := obj.ListObjectParts(bucket, object, uploadID, 0, 1000)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(listInfo.Parts) != 4 {
+		t.Fatalf("%s: Expected 4 parts, but instead found %d", instanceType, len(listInfo.Parts))
+	}
+	var parts []completePart
+	var expected []byte
+	for i, part := range listInfo.Parts {
+		data := partData(i+1, byte('a'+i+1))
+		md5Sum := md5.Sum(data)
+		if part.PartNumber != i+1 || part.ETag != hex.EncodeToString(md5Sum[:]) {
+			t.Fatalf("%s: Expected part %d with ETag %x, but instead found part %d with ETag %s", instanceType, i+1, md5Sum, part.PartNumber, part.ETag)
+		}
+		parts = append(parts, completePart{PartNumber: part.PartNumber, ETag: part.ETag})
+		expected = append(expected, data...)
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, object, uploadID, parts); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	reader, err := obj.GetObject(bucket, object, 0)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	defer reader.Close()
+	got, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if !bytes.Equal(got, expected) {
+		t.Fatalf("%s: Object data mismatch", instanceType)
+	}
+}
+
+// Wrapper for calling AbortMultipartUpload cleanup tests for both XL multiple disks and single node setup.
+func TestObjectAbortMultipartUploadCleanup(t *testing.T) {
+	ExecObjectLayerTest(t, testObjectAbortMultipartUploadCleanup)
+}
+
+// Tests validate aborting an upload removes its temporary files.
+func testObjectAbortMultipartUploadCleanup(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	object := "minio-object"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var storage StorageAPI
+	switch l := obj.(type) {
+	case xlObjects:
+		storage = l.storage
+	case fsObjects:
+		storage = l.storage
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, object, nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.PutObjectPart(bucket, object, uploadID, 1, 5, bytes.NewReader([]byte("hello")), ""); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	// A part upload still in progress.
+	tmpPath := path.Join(tmpMetaPrefix, bucket, object)
+	w, err := storage.CreateFile(minioMetaBucket, path.Join(tmpPath, uploadID+".00002.staging"))
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = w.Write([]byte("partial")); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	if err = obj.AbortMultipartUpload(bucket, object, uploadID); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	entries, err := storage.ListDir(minioMetaBucket, tmpPath)
+	if err != nil && err != errFileNotFound {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	for _, entry := range entries {
+		// Only files are checked, empty directories go with the
+		// tmp cleanup on restart.
+		if strings.HasPrefix(entry, uploadID) && !strings.HasSuffix(entry, slashSeparator) {
+			t.Errorf("%s: Expected temporary file %s to be removed", instanceType, entry)
+		}
+	}
+	if _, err = obj.ListObjectParts(bucket, object, uploadID, 0, 1000); err == nil {
+		t.Errorf("%s: Expected InvalidUploadID for an aborted upload", instanceType)
+	}
+}
+
+// Wrapper for calling ListMultipartUploads paging tests for both XL multiple disks and single node setup.
+func TestObjectListMultipartUploadsPaging(t *testing.T) {
+	ExecObjectLayerTest(t, testObjectListMultipartUploadsPaging)
+}
+
+// Tests validate uploads are listed in key order, paging through them
+// one at a time lists each upload exactly once and common prefixes are
+// rolled up with a delimiter.
+func testObjectListMultipartUploadsPaging(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var expected []string
+	for _, object := range []string{"b", "a", "b", "dir/x", "dir/y", "c"} {
+		uploadID, err := obj.NewMultipartUpload(bucket, object, nil)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		expected = append(expected, object+":"+uploadID)
+	}
+	// Key order, uploads of "b" in the order they were initiated.
+	expected = []string{expected[1], expected[0], expected[2], expected[5], expected[3], expected[4]}
+
+	var listed []string
+	keyMarker, uploadIDMarker := "", ""
+	for page := 0; ; page++ {
+		if page > len(expected) {
+			t.Fatalf("%s: Listing did not terminate, got %v", instanceType, listed)
+		}
+		result, err := obj.ListMultipartUploads(bucket, "", keyMarker, uploadIDMarker, "", 1)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		for _, upload := range result.Uploads {
+			listed = append(listed, upload.Object+":"+upload.UploadID)
+			if upload.Initiated.IsZero() {
+				t.Errorf("%s: Expected initiation time for %s", instanceType, upload.Object)
+			}
+		}
+		if !result.IsTruncated {
+			break
+		}
+		keyMarker, uploadIDMarker = result.NextKeyMarker, result.NextUploadIDMarker
+	}
+	if strings.Join(listed, ",") != strings.Join(expected, ",") {
+		t.Fatalf("%s: Expected %v, got %v", instanceType, expected, listed)
+	}
+
+	// Key marker alone lists keys strictly after it.
+	result, err := obj.ListMultipartUploads(bucket, "", "b", "", "", 1000)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(result.Uploads) != 3 || result.Uploads[0].Object != "c" {
+		t.Fatalf("%s: Expected 3 uploads after \"b\", got %v", instanceType, result.Uploads)
+	}
+
+	// Delimiter rolls up the keys under "dir/".
+	result, err = obj.ListMultipartUploads(bucket, "", "", "", "/", 1000)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(result.Uploads) != 4 || len(result.CommonPrefixes) != 1 || result.CommonPrefixes[0] != "dir/" {
+		t.Fatalf("%s: Expected 4 uploads and prefix dir/, got %v and %v", instanceType, result.Uploads, result.CommonPrefixes)
+	}
+	result, err = obj.ListMultipartUploads(bucket, "dir/", "", "", "/", 1000)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(result.Uploads) != 2 || len(result.CommonPrefixes) != 0 || result.Prefix != "dir/" {
+		t.Fatalf("%s: Expected 2 uploads under dir/, got %v and %v", instanceType, result.Uploads, result.CommonPrefixes)
+	}
+}
+
+// Wrapper for calling CompleteMultipartUpload part list validation tests for both XL multiple disks and single node setup.
+func TestObjectCompleteMultipartUploadValidation(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testObjectCompleteMultipartUploadValidation)
+}
+
+// Tests validate part lists out of order, with duplicates, parts never
+// uploaded or ETags not matching the uploaded parts fail identifying
+// the offending part, leave nothing behind, and that a list with gaps
+// completes the upload.
+func testObjectCompleteMultipartUploadValidation(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	object := "minio-object"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, object, nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	// All parts except the last one need to be at least 5MB.
+	partsData := [][]byte{
+		bytes.Repeat([]byte("a"), 5*1024*1024),
+		bytes.Repeat([]byte("b"), 5*1024*1024),
+		[]byte("c"),
+	}
+	var etags []string
+	for i, data := range partsData {
+		md5Sum, err := obj.PutObjectPart(bucket, object, uploadID, i+1, int64(len(data)), bytes.NewReader(data), "")
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		etags = append(etags, md5Sum)
+	}
+	notUploaded := fmt.Sprintf("%x", md5.Sum([]byte("d")))
+
+	testCases := []struct {
+		parts []completePart
+		err   error
+	}{
+		// Empty list.
+		{nil, InvalidPart{}},
+		// Reordered.
+		{[]completePart{{2, etags[1]}, {1, etags[0]}, {3, etags[2]}}, InvalidPartOrder{UploadID: uploadID, PartNumber: 1}},
+		// Duplicate part number.
+		{[]completePart{{1, etags[0]}, {1, etags[0]}, {3, etags[2]}}, InvalidPartOrder{UploadID: uploadID, PartNumber: 1}},
+		// Part never uploaded.
+		{[]completePart{{1, etags[0]}, {2, etags[1]}, {4, notUploaded}}, InvalidPart{PartNumber: 4, ETag: notUploaded}},
+		// ETag of another part.
+		{[]completePart{{1, etags[1]}, {2, etags[1]}, {3, etags[2]}}, InvalidPart{PartNumber: 1, ETag: etags[1]}},
+		// ETag not naming any part.
+		{[]completePart{{1, etags[0]}, {2, "../" + etags[1]}}, InvalidPart{PartNumber: 2, ETag: "../" + etags[1]}},
+		// Part 0 doesn't exist.
+		{[]completePart{{0, etags[0]}, {3, etags[2]}}, InvalidPart{PartNumber: 0, ETag: etags[0]}},
+	}
+	for i, testCase := range testCases {
+		_, err = obj.CompleteMultipartUpload(bucket, object, uploadID, testCase.parts)
+		if err != testCase.err {
+			t.Fatalf("%s: Test %d: Expected %v, got %v", instanceType, i+1, testCase.err, err)
+		}
+		// Nothing is written for an invalid list.
+		if _, err = obj.GetObjectInfo(bucket, object); err != (ObjectNotFound{Bucket: bucket, Object: object}) {
+			t.Fatalf("%s: Test %d: Expected ObjectNotFound, got %v", instanceType, i+1, err)
+		}
+	}
+	if toAPIErrorCode(InvalidPartOrder{UploadID: uploadID, PartNumber: 1}) != ErrInvalidPartOrder {
+		t.Fatalf("%s: Expected InvalidPartOrder to be reported as %s", instanceType, getAPIError(ErrInvalidPartOrder).Code)
+	}
+
+	// Part numbers don't need to be contiguous.
+	if _, err = obj.CompleteMultipartUpload(bucket, object, uploadID, []completePart{{1, etags[0]}, {3, etags[2]}}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	reader, err := obj.GetObject(bucket, object, 0)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	defer reader.Close()
+	got, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if !bytes.Equal(got, append(append([]byte{}, partsData[0]...), partsData[2]...)) {
+		t.Fatalf("%s: Object data mismatch", instanceType)
+	}
+}
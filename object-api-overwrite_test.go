@@ -0,0 +1,180 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sync"
+	"testing"
+)
+
+// Wrapper for calling overwrite tests for both XL multiple disks and single node setup.
+func TestObjectOverwrite(t *testing.T) {
+	initNSLock()
+	ExecObjectLayerTest(t, testObjectOverwrite)
+}
+
+// Tests validate that an object being overwritten, by simple and
+// multipart uploads, is found by concurrent readers throughout.
+func testObjectOverwrite(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	object := "config"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	putObject := func(data []byte, metadata map[string]string) {
+		if _, err := obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), metadata); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	putObject([]byte("version 0"), map[string]string{"content-type": "text/plain"})
+
+	done := make(chan struct{})
+	var readErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if _, err := obj.GetObjectInfo(bucket, object); err != nil {
+				readErr = err
+				return
+			}
+		}
+	}()
+	for i := 1; i <= 20; i++ {
+		putObject([]byte(fmt.Sprintf("version %d", i)), nil)
+	}
+	// Overwrite with a multipart object, then with a simple object again.
+	uploadID, err := obj.NewMultipartUpload(bucket, object, nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	md5Sum, err := obj.PutObjectPart(bucket, object, uploadID, 1, 9, bytes.NewReader([]byte("multipart")), "")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, object, uploadID, []completePart{{PartNumber: 1, ETag: md5Sum}}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	putObject([]byte("final"), nil)
+	close(done)
+	wg.Wait()
+	if readErr != nil {
+		t.Fatalf("%s: Object missing during overwrite: %s", instanceType, readErr)
+	}
+
+	// Only the last version and its metadata remain.
+	reader, err := obj.GetObject(bucket, object, 0)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if string(data) != "final" {
+		t.Fatalf("%s: Expected \"final\", got %q", instanceType, data)
+	}
+	objInfo, err := obj.GetObjectInfo(bucket, object)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if objInfo.ContentType == "text/plain" {
+		t.Fatalf("%s: Metadata of an overwritten object was kept", instanceType)
+	}
+	if xl, ok := obj.(xlObjects); ok {
+		entries, err := xl.storage.ListDir(minioMetaBucket, path.Join(tmpMetaPrefix, trashDir))
+		if err != nil && err != errFileNotFound {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		for _, entry := range entries {
+			if files, _ := xl.storage.ListDir(minioMetaBucket, path.Join(tmpMetaPrefix, trashDir, entry)); len(files) > 0 {
+				t.Fatalf("%s: Replaced object left in trash %s: %v", instanceType, entry, files)
+			}
+		}
+	}
+}
+
+// Tests validate that readers of an XL object being overwritten find
+// the metadata saved along with the data they find.
+func TestXLObjectOverwriteMetadata(t *testing.T) {
+	xl, removeDisks := newTestXLWithOptions(t, 8)
+	defer removeDisks()
+	bucket := "minio-bucket"
+	object := "config"
+	if err := xl.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	// Content type of each version, by the ETag of its data.
+	contentTypes := make(map[string]string)
+	for i := 0; i <= 20; i++ {
+		md5Sum := md5.Sum([]byte(fmt.Sprintf("version %d", i)))
+		contentTypes[hex.EncodeToString(md5Sum[:])] = fmt.Sprintf("application/x-version-%d", i)
+	}
+	putObject := func(i int) {
+		data := []byte(fmt.Sprintf("version %d", i))
+		metadata := map[string]string{"content-type": fmt.Sprintf("application/x-version-%d", i)}
+		if _, err := xl.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), metadata); err != nil {
+			t.Fatal(err)
+		}
+	}
+	putObject(0)
+
+	done := make(chan struct{})
+	var readErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			objInfo, err := xl.GetObjectInfo(bucket, object)
+			if err != nil {
+				readErr = err
+				return
+			}
+			if contentType := contentTypes[objInfo.MD5Sum]; objInfo.ContentType != contentType {
+				readErr = fmt.Errorf("object with ETag %s has content type %s, expected %s", objInfo.MD5Sum, objInfo.ContentType, contentType)
+				return
+			}
+		}
+	}()
+	for i := 1; i <= 20; i++ {
+		putObject(i)
+	}
+	close(done)
+	wg.Wait()
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+}
@@ -0,0 +1,388 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Wrapper for calling PutObject sha256 tests for both XL multiple disks and single node setup.
+func TestPutObjectSHA256(t *testing.T) {
+	ExecObjectLayerTest(t, testPutObjectSHA256)
+}
+
+// Tests validate PutObject verifies the data against a given sha256,
+// with both known and unknown sizes.
+func testPutObjectSHA256(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello, world")
+	sum := sha256.Sum256(data)
+	sha256Hex := hex.EncodeToString(sum[:])
+	badSum := sha256.Sum256([]byte("hello, world!"))
+	badSHA256Hex := hex.EncodeToString(badSum[:])
+
+	testCases := []struct {
+		object    string
+		size      int64
+		sha256Hex string
+		shouldErr bool
+	}{
+		// Test case - 1.
+		// Matching sha256 with a known size.
+		{"obj-1", int64(len(data)), sha256Hex, false},
+		// Test case - 2.
+		// Matching sha256 with a streamed body.
+		{"obj-2", -1, sha256Hex, false},
+		// Test case - 3.
+		// No sha256 given, nothing to verify.
+		{"obj-3", int64(len(data)), "", false},
+		// Test case - 4.
+		// Mismatching sha256 with a known size.
+		{"obj-4", int64(len(data)), badSHA256Hex, true},
+		// Test case - 5.
+		// Mismatching sha256 with a streamed body.
+		{"obj-5", -1, badSHA256Hex, true},
+	}
+	for i, testCase := range testCases {
+		metadata := make(map[string]string)
+		if testCase.sha256Hex != "" {
+			metadata["sha256Sum"] = testCase.sha256Hex
+		}
+		_, err := obj.PutObject(bucket, testCase.object, testCase.size, bytes.NewReader(data), metadata)
+		if !testCase.shouldErr {
+			if err != nil {
+				t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+			}
+			continue
+		}
+		if _, ok := err.(SHA256Mismatch); !ok {
+			t.Fatalf("%s: Test %d: Expected SHA256Mismatch, but instead found %v", instanceType, i+1, err)
+		}
+		// Data failing verification isn't saved.
+		if _, err = obj.GetObjectInfo(bucket, testCase.object); err == nil {
+			t.Errorf("%s: Test %d: Expected object %s to not exist", instanceType, i+1, testCase.object)
+		}
+	}
+}
+
+// Wrapper for calling PutObject Content-MD5 tests for both XL multiple disks and single node setup.
+func TestPutObjectContentMD5(t *testing.T) {
+	ExecObjectLayerTest(t, testPutObjectContentMD5)
+}
+
+// Tests validate PutObject verifies the data against a hex md5Sum or a
+// base64 contentMd5, digest mismatches are reported in the encoding
+// they were given in.
+func testPutObjectContentMD5(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello, world")
+	sum := md5.Sum(data)
+	badSum := md5.Sum([]byte("hello, world!"))
+
+	testCases := []struct {
+		object   string
+		metadata map[string]string
+		// Expected error, nil for success.
+		expectedErr error
+	}{
+		// Test case - 1.
+		// Matching base64 digest.
+		{"obj-1", map[string]string{"contentMd5": base64.StdEncoding.EncodeToString(sum[:])}, nil},
+		// Test case - 2.
+		// Matching hex digest.
+		{"obj-2", map[string]string{"md5Sum": hex.EncodeToString(sum[:])}, nil},
+		// Test case - 3.
+		// Mismatching base64 digest, both digests are base64.
+		{"obj-3", map[string]string{"contentMd5": base64.StdEncoding.EncodeToString(badSum[:])},
+			BadDigest{base64.StdEncoding.EncodeToString(badSum[:]), base64.StdEncoding.EncodeToString(sum[:])}},
+		// Test case - 4.
+		// Mismatching hex digest, both digests are hex.
+		{"obj-4", map[string]string{"md5Sum": hex.EncodeToString(badSum[:])},
+			BadDigest{hex.EncodeToString(badSum[:]), hex.EncodeToString(sum[:])}},
+		// Test case - 5.
+		// Malformed base64.
+		{"obj-5", map[string]string{"contentMd5": "not*base64"}, InvalidDigest{ContentMD5: "not*base64"}},
+		// Test case - 6.
+		// Valid base64 which isn't an MD5.
+		{"obj-6", map[string]string{"contentMd5": base64.StdEncoding.EncodeToString([]byte("short"))},
+			InvalidDigest{ContentMD5: base64.StdEncoding.EncodeToString([]byte("short"))}},
+	}
+	for i, testCase := range testCases {
+		md5Hex, err := obj.PutObject(bucket, testCase.object, int64(len(data)), bytes.NewReader(data), testCase.metadata)
+		if testCase.expectedErr == nil {
+			if err != nil {
+				t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+			}
+			if md5Hex != hex.EncodeToString(sum[:]) {
+				t.Errorf("%s: Test %d: Expected md5Sum %s, got %s", instanceType, i+1, hex.EncodeToString(sum[:]), md5Hex)
+			}
+			continue
+		}
+		if err != testCase.expectedErr {
+			t.Fatalf("%s: Test %d: Expected %v, but instead found %v", instanceType, i+1, testCase.expectedErr, err)
+		}
+		if _, err = obj.GetObjectInfo(bucket, testCase.object); err == nil {
+			t.Errorf("%s: Test %d: Expected object %s to not exist", instanceType, i+1, testCase.object)
+		}
+	}
+}
+
+// Wrapper for calling PutObject checksum tests for both XL multiple disks and single node setup.
+func TestPutObjectWithChecksums(t *testing.T) {
+	ExecObjectLayerTest(t, testPutObjectWithChecksums)
+}
+
+// Tests validate PutObjectWithChecksums returns the requested
+// checksums and rejects unsupported algorithms.
+func testPutObjectWithChecksums(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	object := "minio-object"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello, world")
+	md5Sum := md5.Sum(data)
+	sha256Sum := sha256.Sum256(data)
+	crc32cSum := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc32cSum, crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)))
+
+	result, err := obj.PutObjectWithChecksums(bucket, object, int64(len(data)), bytes.NewReader(data), map[string]string{checksumAlgorithmKey: "CRC32C, sha256"})
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if result.ETag != hex.EncodeToString(md5Sum[:]) {
+		t.Errorf("%s: Expected ETag %x, got %s", instanceType, md5Sum, result.ETag)
+	}
+	if len(result.Checksums) != 2 {
+		t.Errorf("%s: Expected 2 checksums, got %v", instanceType, result.Checksums)
+	}
+	if result.Checksums["crc32c"] != hex.EncodeToString(crc32cSum) {
+		t.Errorf("%s: Expected crc32c %x, got %s", instanceType, crc32cSum, result.Checksums["crc32c"])
+	}
+	if result.Checksums["sha256"] != hex.EncodeToString(sha256Sum[:]) {
+		t.Errorf("%s: Expected sha256 %x, got %s", instanceType, sha256Sum, result.Checksums["sha256"])
+	}
+
+	// No checksums unless requested.
+	result, err = obj.PutObjectWithChecksums(bucket, object, int64(len(data)), bytes.NewReader(data), nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if result.Checksums != nil {
+		t.Errorf("%s: Expected no checksums, got %v", instanceType, result.Checksums)
+	}
+
+	_, err = obj.PutObjectWithChecksums(bucket, object, int64(len(data)), bytes.NewReader(data), map[string]string{checksumAlgorithmKey: "md4"})
+	if _, ok := err.(UnsupportedChecksumAlgorithm); !ok {
+		t.Errorf("%s: Expected UnsupportedChecksumAlgorithm, got %v", instanceType, err)
+	}
+}
+
+// Wrapper for calling PutObjectFromFile tests for both XL multiple disks and single node setup.
+func TestPutObjectFromFile(t *testing.T) {
+	ExecObjectLayerTest(t, testPutObjectFromFile)
+}
+
+// Tests validate PutObjectFromFile stores the file as is, returns its
+// checksums and leaves the source file in place.
+func testPutObjectFromFile(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	object := "minio-object"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	srcDir, err := ioutil.TempDir("", "minio-")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	defer os.RemoveAll(srcDir)
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024+3)
+	srcPath := filepath.Join(srcDir, "source")
+	if err = ioutil.WriteFile(srcPath, data, 0644); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	result, err := obj.PutObjectFromFile(bucket, object, srcPath, map[string]string{checksumAlgorithmKey: "sha256"})
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	md5Sum := md5.Sum(data)
+	sha256Sum := sha256.Sum256(data)
+	if result.ETag != hex.EncodeToString(md5Sum[:]) || result.Checksums["sha256"] != hex.EncodeToString(sha256Sum[:]) {
+		t.Errorf("%s: Expected ETag %x and sha256 %x, got %v", instanceType, md5Sum, sha256Sum, result)
+	}
+	reader, err := obj.GetObject(bucket, object, 0)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	got, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("%s: Object data doesn't match the source file", instanceType)
+	}
+	if src, err := ioutil.ReadFile(srcPath); err != nil || !bytes.Equal(src, data) {
+		t.Errorf("%s: Expected source file to be left in place, %v", instanceType, err)
+	}
+
+	// Digest mismatch leaves no object behind.
+	_, err = obj.PutObjectFromFile(bucket, "mismatch", srcPath, map[string]string{"md5Sum": "d41d8cd98f00b204e9800998ecf8427e"})
+	if _, ok := err.(BadDigest); !ok {
+		t.Errorf("%s: Expected BadDigest, got %v", instanceType, err)
+	}
+	if _, err = obj.GetObjectInfo(bucket, "mismatch"); err == nil {
+		t.Errorf("%s: Expected no object after a digest mismatch", instanceType)
+	}
+
+	// Directories are not regular files.
+	if _, err = obj.PutObjectFromFile(bucket, object, srcDir, nil); err == nil {
+		t.Errorf("%s: Expected an error putting a directory", instanceType)
+	}
+}
+
+// Wrapper for calling adversarial object name tests for both XL multiple disks and single node setup.
+func TestPutObjectReservedNames(t *testing.T) {
+	ExecObjectLayerTest(t, testPutObjectReservedNames)
+}
+
+// Tests validate that names escaping the bucket or colliding with
+// internal files are refused, and nothing is written for them.
+func testPutObjectReservedNames(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err := obj.PutObject(bucket, "object", 4, bytes.NewBufferString("data"), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	names := []string{
+		"../bucket2/object",
+		"../.minio/format.json",
+		"dir/../../object",
+		"./object",
+		"dir//object",
+		".minio/format.json",
+		"object/00000.minio.multipart",
+		"object.minio.multipart",
+	}
+	for i, name := range names {
+		_, err := obj.PutObject(bucket, name, 4, bytes.NewBufferString("evil"), nil)
+		if _, ok := err.(ObjectNameInvalid); !ok {
+			t.Errorf("%s: Test %d: Expected PutObject of %q to fail with ObjectNameInvalid, got %v", instanceType, i+1, name, err)
+		}
+		_, err = obj.GetObject(bucket, name, 0)
+		if _, ok := err.(ObjectNameInvalid); !ok {
+			t.Errorf("%s: Test %d: Expected GetObject of %q to fail with ObjectNameInvalid, got %v", instanceType, i+1, name, err)
+		}
+	}
+
+	// The existing object and the backend format are left untouched.
+	result, err := obj.ListObjects(bucket, "", "", "", 1000)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(result.Objects) != 1 || result.Objects[0].Name != "object" {
+		t.Fatalf("%s: Expected only object to be listed, got %v", instanceType, result.Objects)
+	}
+	reader, err := obj.GetObject(bucket, "object", 0)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil || string(data) != "data" {
+		t.Fatalf("%s: Expected object data to be intact, got %q, %v", instanceType, data, err)
+	}
+
+	// Listing prefixes can't leave the bucket either.
+	if _, err = obj.ListObjects(bucket, "../", "", "", 1000); err == nil {
+		t.Fatalf("%s: Expected listing prefix ../ to fail", instanceType)
+	}
+}
+
+// Wrapper for calling maximum object size tests for both XL multiple disks and single node setup.
+func TestPutObjectMaxObjectSize(t *testing.T) {
+	ExecObjectLayerTest(t, testPutObjectMaxObjectSize)
+}
+
+// Tests validate that objects over the maximum object size are refused,
+// whether their size is known up front or not.
+func testPutObjectMaxObjectSize(obj ObjectLayer, instanceType string, t *testing.T) {
+	if maxSize := obj.MaxObjectSize(); maxSize != maxObjectSize {
+		t.Fatalf("%s: Expected default maximum object size %d, got %d", instanceType, maxObjectSize, maxSize)
+	}
+	if err := obj.SetMaxObjectSize(0); err != errInvalidArgument {
+		t.Fatalf("%s: Expected %v, got %v", instanceType, errInvalidArgument, err)
+	}
+	if err := obj.SetMaxObjectSize(10); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	bucket := "bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	testCases := []struct {
+		object     string
+		data       string
+		size       int64
+		shouldPass bool
+	}{
+		{"known-at-limit", "0123456789", 10, true},
+		{"unknown-at-limit", "0123456789", -1, true},
+		{"known-over-limit", "0123456789a", 11, false},
+		{"unknown-over-limit", "0123456789a", -1, false},
+		{"unknown-far-over-limit", strings.Repeat("a", 64*1024), 0, false},
+	}
+	for i, testCase := range testCases {
+		_, err := obj.PutObject(bucket, testCase.object, testCase.size, bytes.NewBufferString(testCase.data), nil)
+		if testCase.shouldPass {
+			if err != nil {
+				t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+			}
+			continue
+		}
+		expected := ObjectTooLarge{Bucket: bucket, Object: testCase.object, MaxSize: 10}
+		if err != expected {
+			t.Fatalf("%s: Test %d: Expected %v, got %v", instanceType, i+1, expected, err)
+		}
+		// Nothing is left of a refused object.
+		if _, err = obj.GetObjectInfo(bucket, testCase.object); err == nil {
+			t.Fatalf("%s: Test %d: Expected object %s to not exist", instanceType, i+1, testCase.object)
+		}
+	}
+}
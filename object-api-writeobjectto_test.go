@@ -0,0 +1,111 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// Wrapper for calling WriteObjectTo tests for both XL multiple disks and single node setup.
+func TestWriteObjectTo(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testWriteObjectTo)
+}
+
+// limitedWriter - writer failing with errWriterFull once more than
+// size bytes are written.
+type limitedWriter struct {
+	buffer bytes.Buffer
+	size   int
+}
+
+var errWriterFull = errors.New("writer full")
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.buffer.Len()+len(p) > w.size {
+		n, _ := w.buffer.Write(p[:w.size-w.buffer.Len()])
+		return n, errWriterFull
+	}
+	return w.buffer.Write(p)
+}
+
+// Tests validate WriteObjectTo writes the same data GetObject reads
+// for simple and multipart objects, fails like GetObject, and returns
+// the bytes written when the writer fails midway.
+func testWriteObjectTo(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	simpleData := bytes.Repeat([]byte("abcdefgh"), 1000)
+	if _, err := obj.PutObject(bucket, "simple", int64(len(simpleData)), bytes.NewReader(simpleData), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	partSize := 5 * 1024 * 1024
+	multipartData, err := putTestMultipartObject(obj, bucket, "multipart", 3, partSize)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	testCases := []struct {
+		object      string
+		startOffset int64
+		expected    []byte
+		err         error
+	}{
+		{"simple", 0, simpleData, nil},
+		{"simple", 100, simpleData[100:], nil},
+		{"simple", int64(len(simpleData)), nil, nil},
+		{"multipart", 0, multipartData, nil},
+		// Starting in the middle of the second part.
+		{"multipart", int64(partSize + partSize/2), multipartData[partSize+partSize/2:], nil},
+		{"multipart", int64(len(multipartData)), nil, nil},
+		// Fails like GetObject.
+		{"multipart", int64(len(multipartData)) + 1, nil, InvalidRange{Bucket: bucket, Object: "multipart", Offset: int64(len(multipartData)) + 1, Size: int64(len(multipartData))}},
+		{"missing", 0, nil, ObjectNotFound{Bucket: bucket, Object: "missing"}},
+	}
+	for i, testCase := range testCases {
+		var buffer bytes.Buffer
+		n, err := obj.WriteObjectTo(bucket, testCase.object, testCase.startOffset, &buffer)
+		if err != testCase.err {
+			t.Fatalf("%s: Test %d: Expected error %v, got %v", instanceType, i+1, testCase.err, err)
+		}
+		if n != int64(len(testCase.expected)) || !bytes.Equal(buffer.Bytes(), testCase.expected) {
+			t.Errorf("%s: Test %d: Expected %d bytes written, got %d", instanceType, i+1, len(testCase.expected), n)
+		}
+	}
+
+	if _, err = obj.WriteObjectTo("minio-bucket-missing", "simple", 0, &bytes.Buffer{}); err != (BucketNotFound{Bucket: "minio-bucket-missing"}) {
+		t.Fatalf("%s: Expected BucketNotFound, got %v", instanceType, err)
+	}
+
+	// A writer failing midway fails the write with its error, after
+	// the bytes it took.
+	for _, object := range []string{"simple", "multipart"} {
+		writer := &limitedWriter{size: 1000}
+		n, err := obj.WriteObjectTo(bucket, object, 0, writer)
+		if err != errWriterFull {
+			t.Fatalf("%s: Expected %v writing %s, got %v", instanceType, errWriterFull, object, err)
+		}
+		if n != 1000 || writer.buffer.Len() != 1000 {
+			t.Fatalf("%s: Expected 1000 bytes written of %s, got %d", instanceType, object, n)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"io"
+)
+
+// appendObjectRewrite - appends data to an object by putting the object
+// again with data after its current content, keeping its metadata and
+// tags, common function for both object layers. Costs a copy of the
+// object, used where a part can't be added in place. A missing object
+// is created with data. The caller holds the object lock.
+func appendObjectRewrite(layer ObjectLayer, storage StorageAPI, bucket, object string, data io.Reader, putObject putObjectFunc) (ObjectInfo, error) {
+	metadata, err := copyObjectMetadata(storage, bucket, object, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	reader, err := layer.GetObject(bucket, object, 0)
+	if err == nil {
+		defer reader.Close()
+		data = io.MultiReader(reader, data)
+	} else if _, ok := err.(ObjectNotFound); ok {
+		// Nothing to append to, metadata left by a previous object
+		// isn't kept.
+		metadata = nil
+	} else {
+		return ObjectInfo{}, err
+	}
+	if _, err = putObject(context.Background(), bucket, object, -1, data, metadata, true, nil); err != nil {
+		return ObjectInfo{}, err
+	}
+	return layer.GetObjectInfo(bucket, object)
+}
@@ -0,0 +1,244 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Wrapper for calling AppendObject tests for both XL multiple disks and single node setup.
+func TestAppendObject(t *testing.T) {
+	initNSLock()
+	ExecObjectLayerTest(t, testAppendObject)
+}
+
+// Tests validate appends add to the content of an object, keep its
+// metadata, and concurrent appends all land in full.
+func testAppendObject(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	readObject := func(object string) string {
+		r, err := obj.GetObject(bucket, object, 0)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		defer r.Close()
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		return string(data)
+	}
+	appendObject := func(object, data string) ObjectInfo {
+		objInfo, err := obj.AppendObject(bucket, object, strings.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		return objInfo
+	}
+	md5Hex := func(data string) string {
+		sum := md5.Sum([]byte(data))
+		return hex.EncodeToString(sum[:])
+	}
+	_, isXL := obj.(xlObjects)
+
+	// Appending to a missing object creates it.
+	objInfo := appendObject("log", "first\n")
+	if objInfo.Size != 6 || readObject("log") != "first\n" {
+		t.Fatalf("%s: Expected a new object of 6 bytes, got %d", instanceType, objInfo.Size)
+	}
+	metadata := map[string]string{"content-type": "text/plain", "x-amz-meta-Source": "app"}
+	if _, err := obj.PutObject(bucket, "log", 6, strings.NewReader("first\n"), metadata); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	appendObject("log", "second\n")
+	objInfo = appendObject("log", "third\n")
+	expected := "first\nsecond\nthird\n"
+	if got := readObject("log"); got != expected {
+		t.Fatalf("%s: Expected %q, got %q", instanceType, expected, got)
+	}
+	if objInfo.Size != int64(len(expected)) {
+		t.Fatalf("%s: Expected size %d, got %d", instanceType, len(expected), objInfo.Size)
+	}
+	if objInfo.ContentType != "text/plain" || objInfo.UserDefined["x-amz-meta-Source"] != "app" {
+		t.Fatalf("%s: Expected metadata to be kept, got %q, %v", instanceType, objInfo.ContentType, objInfo.UserDefined)
+	}
+	if isXL {
+		// The simple object became a multipart object, each append
+		// adds a part.
+		if len(objInfo.Parts) != 3 || !strings.HasSuffix(objInfo.MD5Sum, "-3") {
+			t.Fatalf("%s: Expected 3 parts and a composite ETag, got %d parts, %s", instanceType, len(objInfo.Parts), objInfo.MD5Sum)
+		}
+		md5Sum, err := completeMultipartMD5(
+			completePart{PartNumber: 1, ETag: md5Hex("first\n")},
+			completePart{PartNumber: 2, ETag: md5Hex("second\n")},
+			completePart{PartNumber: 3, ETag: md5Hex("third\n")},
+		)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		if objInfo.MD5Sum != md5Sum {
+			t.Fatalf("%s: Expected ETag %s, got %s", instanceType, md5Sum, objInfo.MD5Sum)
+		}
+	}
+	// Ranges read across the appended parts.
+	r, err := obj.GetObjectRange(bucket, "log", 3, 8)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || string(data) != expected[3:11] {
+		t.Fatalf("%s: Expected %q, got %q, %v", instanceType, expected[3:11], data, err)
+	}
+
+	// Concurrent appends are serialized, none is lost or interleaved.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := obj.AppendObject(bucket, "concurrent", strings.NewReader(fmt.Sprintf("line %d\n", i))); err != nil {
+				t.Errorf("%s : %s", instanceType, err.Error())
+			}
+		}(i)
+	}
+	wg.Wait()
+	lines := strings.Split(strings.TrimSuffix(readObject("concurrent"), "\n"), "\n")
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		seen[line] = true
+	}
+	if len(lines) != 10 || len(seen) != 10 {
+		t.Fatalf("%s: Expected 10 distinct lines, got %q", instanceType, lines)
+	}
+
+	// Appends can't take an object over the size limit.
+	if err = obj.SetMaxObjectSize(int64(len(expected)) + 2); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.AppendObject(bucket, "log", strings.NewReader("fourth\n")); err == nil {
+		t.Fatalf("%s: Expected ObjectTooLarge", instanceType)
+	} else if _, ok := err.(ObjectTooLarge); !ok {
+		t.Fatalf("%s: Expected ObjectTooLarge, got %v", instanceType, err)
+	}
+	if got := readObject("log"); got != expected {
+		t.Fatalf("%s: Expected a failed append to leave %q, got %q", instanceType, expected, got)
+	}
+	if err = obj.SetMaxObjectSize(maxObjectSize); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	// Objects under legal hold can't be appended to.
+	if err = obj.PutObjectLegalHold(bucket, "log", true); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.AppendObject(bucket, "log", strings.NewReader("fourth\n")); err == nil {
+		t.Fatalf("%s: Expected appending to an object under legal hold to fail", instanceType)
+	}
+	if err = obj.PutObjectLegalHold(bucket, "log", false); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+}
+
+// Wrapper for calling AppendObject tests of versioned and dedup buckets for both XL multiple disks and single node setup.
+func TestAppendObjectVersionedDedup(t *testing.T) {
+	initNSLock()
+	ExecObjectLayerTest(t, testAppendObjectVersionedDedup)
+}
+
+// Tests validate appends in a versioned bucket keep the previous
+// content as a version, and appends to a deduplicated object leave
+// the other objects sharing its data intact.
+func testAppendObjectVersionedDedup(obj ObjectLayer, instanceType string, t *testing.T) {
+	for _, bucket := range []string{"versioned", "dedup"} {
+		if err := obj.MakeBucket(bucket); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	if err := obj.SetBucketVersioning("versioned", true); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err := obj.SetBucketDedup("dedup", true); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	readObject := func(bucket, object string) string {
+		r, err := obj.GetObject(bucket, object, 0)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		defer r.Close()
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		return string(data)
+	}
+
+	content := []byte("shared content\n")
+	for _, object := range []string{"a", "b"} {
+		for _, bucket := range []string{"versioned", "dedup"} {
+			if _, err := obj.PutObject(bucket, object, int64(len(content)), bytes.NewReader(content), nil); err != nil {
+				t.Fatalf("%s : %s", instanceType, err.Error())
+			}
+		}
+	}
+	for _, bucket := range []string{"versioned", "dedup"} {
+		if _, err := obj.AppendObject(bucket, "a", strings.NewReader("more\n")); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		if got := readObject(bucket, "a"); got != string(content)+"more\n" {
+			t.Fatalf("%s: %s: Expected appended content, got %q", instanceType, bucket, got)
+		}
+		if got := readObject(bucket, "b"); got != string(content) {
+			t.Fatalf("%s: %s: Expected other object to be left intact, got %q", instanceType, bucket, got)
+		}
+	}
+	// Removing the object sharing its data keeps the dedup blob.
+	if err := obj.DeleteObject("dedup", "a"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if got := readObject("dedup", "b"); got != string(content) {
+		t.Fatalf("%s: Expected deduplicated object to be left intact, got %q", instanceType, got)
+	}
+
+	result, err := obj.ListObjectVersions("versioned", "a", "", "", 10)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(result.Versions) != 2 {
+		t.Fatalf("%s: Expected the content before the append to be kept as a version, got %d versions", instanceType, len(result.Versions))
+	}
+	r, err := obj.GetObjectVersion("versioned", "a", result.Versions[1].VersionID, 0)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || !bytes.Equal(data, content) {
+		t.Fatalf("%s: Expected previous version %q, got %q, %v", instanceType, content, data, err)
+	}
+}
@@ -0,0 +1,352 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io"
+)
+
+// Object data is compressed, then encrypted at rest, as configured
+// when the object is put. Reads undo both in reverse, offsets of the
+// compressed blocks are offsets into the decrypted data. Parts of
+// multipart objects are encrypted one by one as they are uploaded.
+
+// atRestWriter - compresses and encrypts the content of an object
+// being put on its way to storage.
+type atRestWriter struct {
+	io.Writer
+	compressor *compressWriter
+	encryptor  *encryptWriter
+	// Metadata recording the encryption.
+	encryption map[string]string
+}
+
+// isTransformedAtRest - reports if the content of an object being put
+// is stored other than as uploaded.
+func isTransformedAtRest(object string, metadata map[string]string) bool {
+	return isCompressible(object, metadata) || getKeySource() != nil
+}
+
+// newAtRestWriter - returns the writer storing the content of object
+// into writer.
+func newAtRestWriter(object string, metadata map[string]string, writer io.Writer) (*atRestWriter, error) {
+	w, err := newPartAtRestWriter(writer)
+	if err != nil {
+		return nil, err
+	}
+	if isCompressible(object, metadata) {
+		w.compressor = newCompressWriter(w.Writer)
+		w.Writer = w.compressor
+	}
+	return w, nil
+}
+
+// newPartAtRestWriter - returns the writer storing the data of a part
+// of a multipart object into writer. Parts are encrypted each with a
+// data key of their own, they are not compressed.
+func newPartAtRestWriter(writer io.Writer) (*atRestWriter, error) {
+	w := &atRestWriter{Writer: writer}
+	if source := getKeySource(); source != nil {
+		var err error
+		w.encryptor, w.encryption, err = newEncryptWriter(source, w.Writer)
+		if err != nil {
+			return nil, err
+		}
+		w.Writer = w.encryptor
+	}
+	return w, nil
+}
+
+// finish - flushes the content written, size bytes, to storage and
+// returns metadata along with what's needed to read it back.
+func (w *atRestWriter) finish(metadata map[string]string, size int64) (map[string]string, error) {
+	if w.compressor != nil {
+		if err := w.compressor.Close(); err != nil {
+			return nil, err
+		}
+		var err error
+		if metadata, err = withCompression(metadata, size, w.compressor.Blocks()); err != nil {
+			return nil, err
+		}
+	}
+	if w.encryptor != nil {
+		if err := w.encryptor.Close(); err != nil {
+			return nil, err
+		}
+		objMetadata := make(map[string]string, len(metadata)+len(w.encryption))
+		for k, v := range metadata {
+			objMetadata[k] = v
+		}
+		for k, v := range w.encryption {
+			objMetadata[k] = v
+		}
+		metadata = objMetadata
+	}
+	return metadata, nil
+}
+
+// objectContentSize - size of the content of an object stored in
+// storedSize bytes.
+func objectContentSize(storedSize int64, metadata map[string]string) (int64, error) {
+	if _, ok := metadata[partsEncryptionKey]; ok {
+		size := int64(0)
+		for _, part := range parseParts(metadata[partsKey]) {
+			size += part.Size
+		}
+		return size, nil
+	}
+	sum, blobSize, err := getObjectDedup(metadata)
+	if err != nil {
+		return 0, err
+	}
+	if sum != "" {
+		storedSize = blobSize
+	}
+	compression, err := getObjectCompression(metadata)
+	if err != nil {
+		return 0, err
+	}
+	if compression != nil {
+		return compression.actualSize, nil
+	}
+	if isEncrypted(metadata) {
+		return decryptedSize(storedSize)
+	}
+	return storedSize, nil
+}
+
+// objectReader - opens the content of a stored object at an offset.
+type objectReader struct {
+	size int64
+	open func(offset int64) (io.ReadCloser, error)
+	// Set if the object isn't stored as uploaded.
+	transformed bool
+}
+
+// newObjectReader - returns the reader of the content of an object
+// stored at bucket, object in storedSize bytes.
+func newObjectReader(storage StorageAPI, bucket, object string, storedSize int64, metadata map[string]string) (objectReader, error) {
+	r := objectReader{
+		size: storedSize,
+		open: func(offset int64) (io.ReadCloser, error) {
+			return storage.ReadFile(bucket, object, offset)
+		},
+	}
+	// Parts of a multipart object encrypted at rest are read apart.
+	if _, ok := metadata[partsEncryptionKey]; ok {
+		parts, err := getPartsEncryption(metadata)
+		if err != nil {
+			return objectReader{}, err
+		}
+		readStored := r.open
+		r.size = 0
+		for _, part := range parts {
+			r.size += part.Size
+		}
+		r.open = func(offset int64) (io.ReadCloser, error) {
+			return openParts(readStored, parts, offset)
+		}
+		r.transformed = true
+		return r, nil
+	}
+	// The data of a deduplicated object is its dedup blob.
+	sum, blobSize, err := getObjectDedup(metadata)
+	if err != nil {
+		return objectReader{}, err
+	}
+	if sum != "" {
+		storedSize = blobSize
+		r.size = blobSize
+		r.open = func(offset int64) (io.ReadCloser, error) {
+			return openDedupBlob(storage, sum, offset)
+		}
+		r.transformed = true
+	}
+	encryption, err := getObjectEncryption(metadata)
+	if err != nil {
+		return objectReader{}, err
+	}
+	if encryption != nil {
+		readStored := r.open
+		if r.size, err = decryptedSize(storedSize); err != nil {
+			return objectReader{}, err
+		}
+		size := r.size
+		r.open = func(offset int64) (io.ReadCloser, error) {
+			return encryption.open(readStored, size, offset)
+		}
+		r.transformed = true
+	}
+	compression, err := getObjectCompression(metadata)
+	if err != nil {
+		return objectReader{}, err
+	}
+	if compression != nil {
+		readStored := r.open
+		r.size = compression.actualSize
+		r.open = func(offset int64) (io.ReadCloser, error) {
+			return compression.open(readStored, offset)
+		}
+		r.transformed = true
+	}
+	return r, nil
+}
+
+// readObjectReader - returns the reader of the content of an object
+// stored at bucket, object in storedSize bytes, reading its metadata.
+func readObjectReader(storage StorageAPI, bucket, object string, storedSize int64) (objectReader, error) {
+	metadata, err := readObjectMetadata(storage, bucket, object)
+	if err != nil {
+		return objectReader{}, err
+	}
+	return newObjectReader(storage, bucket, object, storedSize, metadata)
+}
+
+// ReadAt - implements io.ReaderAt, each read opens the content at the
+// offset.
+func (r objectReader) ReadAt(p []byte, offset int64) (int, error) {
+	if offset < 0 || offset > r.size {
+		return 0, errInvalidArgument
+	}
+	reader, err := r.open(offset)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	n, err := io.ReadFull(reader, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// openObjectReaderAt - random access to the content of an object,
+// undoing compression and encryption at rest.
+func openObjectReaderAt(storage StorageAPI, bucket, object string) (io.ReaderAt, int64, error) {
+	readerAt, size, err := openReaderAt(storage, bucket, object)
+	if err != nil {
+		return nil, 0, err
+	}
+	r, err := readObjectReader(storage, bucket, object, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !r.transformed {
+		return readerAt, size, nil
+	}
+	return r, r.size, nil
+}
+
+// partStoredSize - size of the data of part as stored.
+func partStoredSize(part MultipartPartInfo) int64 {
+	if part.Encryption == nil {
+		return part.Size
+	}
+	return encryptedSize(part.Size)
+}
+
+// openPart - opens the content of part from offset, its data as stored
+// is returned by readStored.
+func openPart(readStored func(offset int64) (io.ReadCloser, error), part MultipartPartInfo, offset int64) (io.ReadCloser, error) {
+	if part.Encryption == nil {
+		return readStored(offset)
+	}
+	encryption, err := getObjectEncryption(part.Encryption)
+	if err != nil {
+		return nil, err
+	}
+	return encryption.open(readStored, part.Size, offset)
+}
+
+// partsReader - reads the content of parts stored one after the other.
+type partsReader struct {
+	readStored func(offset int64) (io.ReadCloser, error)
+	parts      []MultipartPartInfo
+	// Index of the part read and offset of its stored data.
+	index        int
+	storedOffset int64
+	current      io.ReadCloser
+}
+
+// openParts - reader of the content of parts from offset, their data
+// as stored one after the other is returned by readStored.
+func openParts(readStored func(offset int64) (io.ReadCloser, error), parts []MultipartPartInfo, offset int64) (io.ReadCloser, error) {
+	r := &partsReader{readStored: readStored, parts: parts}
+	// Skip the parts before offset.
+	for r.index < len(parts) && offset >= parts[r.index].Size {
+		offset -= parts[r.index].Size
+		r.storedOffset += partStoredSize(parts[r.index])
+		r.index++
+	}
+	if r.index < len(parts) {
+		if err := r.open(offset); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// open - opens the part at r.index from offset.
+func (r *partsReader) open(offset int64) error {
+	part, storedOffset := r.parts[r.index], r.storedOffset
+	current, err := openPart(func(offset int64) (io.ReadCloser, error) {
+		stored, err := r.readStored(storedOffset + offset)
+		if err != nil {
+			return nil, err
+		}
+		return newLimitedReadCloser(stored, partStoredSize(part)-offset), nil
+	}, part, offset)
+	if err != nil {
+		return err
+	}
+	r.current = current
+	return nil
+}
+
+// Read - implements io.Reader, moves on to the next part at the end of
+// a part.
+func (r *partsReader) Read(p []byte) (int, error) {
+	for r.current != nil {
+		n, err := r.current.Read(p)
+		if err != io.EOF {
+			return n, err
+		}
+		if err = r.current.Close(); err != nil {
+			return n, err
+		}
+		r.current = nil
+		r.storedOffset += partStoredSize(r.parts[r.index])
+		r.index++
+		if r.index < len(r.parts) {
+			if err = r.open(0); err != nil {
+				return n, err
+			}
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+	return 0, io.EOF
+}
+
+// Close - closes the part being read.
+func (r *partsReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	return r.current.Close()
+}
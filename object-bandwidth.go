@@ -0,0 +1,221 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Bandwidth stats of all buckets, saved in minioMetaBucket.
+const bandwidthStatsFile = "bandwidth.json"
+
+// Interval at which changed bandwidth stats are saved.
+var bandwidthSaveInterval = 5 * time.Minute
+
+// bucketBandwidth - bytes written to and read from a bucket, updated
+// atomically.
+type bucketBandwidth struct {
+	Ingress int64 `json:"ingress"`
+	Egress  int64 `json:"egress"`
+}
+
+// bandwidthAccounting - per bucket bytes transferred through PutObject
+// and GetObject. Stats are loaded when the object layer starts and
+// saved periodically, so only the bytes since the last save are lost
+// on restart.
+type bandwidthAccounting struct {
+	storage StorageAPI
+	mutex   *sync.RWMutex
+	buckets map[string]*bucketBandwidth
+	changed int32
+	once    *sync.Once
+}
+
+// newBandwidthAccounting - initialize bandwidth accounting, loading
+// the stats saved on storage.
+func newBandwidthAccounting(storage StorageAPI) *bandwidthAccounting {
+	b := &bandwidthAccounting{
+		storage: storage,
+		mutex:   &sync.RWMutex{},
+		buckets: make(map[string]*bucketBandwidth),
+		once:    &sync.Once{},
+	}
+	if err := b.load(); err != nil && err != errFileNotFound {
+		errorIf(err, "Unable to load bandwidth stats.", nil)
+	}
+	return b
+}
+
+// bucket - returns the counters of a bucket, creating them if needed.
+func (b *bandwidthAccounting) bucket(bucket string) *bucketBandwidth {
+	b.mutex.RLock()
+	counters, ok := b.buckets[bucket]
+	b.mutex.RUnlock()
+	if ok {
+		return counters
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if counters, ok = b.buckets[bucket]; !ok {
+		counters = &bucketBandwidth{}
+		b.buckets[bucket] = counters
+	}
+	return counters
+}
+
+// markChanged - flags the stats for saving, the saving routine is
+// started on first use.
+func (b *bandwidthAccounting) markChanged() {
+	atomic.StoreInt32(&b.changed, 1)
+	b.once.Do(func() {
+		go b.run()
+	})
+}
+
+// addIngress - accounts n bytes written to bucket.
+func (b *bandwidthAccounting) addIngress(bucket string, n int64) {
+	// Object layers built for one off reads have no accounting.
+	if b == nil || n <= 0 {
+		return
+	}
+	atomic.AddInt64(&b.bucket(bucket).Ingress, n)
+	b.markChanged()
+}
+
+// addEgress - accounts n bytes read from bucket.
+func (b *bandwidthAccounting) addEgress(bucket string, n int64) {
+	if b == nil || n <= 0 {
+		return
+	}
+	atomic.AddInt64(&b.bucket(bucket).Egress, n)
+	b.markChanged()
+}
+
+// egressReader - returns a reader accounting all bytes read from
+// reader as read from bucket.
+func (b *bandwidthAccounting) egressReader(bucket string, reader io.ReadCloser) io.ReadCloser {
+	if b == nil {
+		return reader
+	}
+	return &egressReadCloser{ReadCloser: reader, accounting: b, counters: b.bucket(bucket)}
+}
+
+// stats - bytes written to and read from bucket.
+func (b *bandwidthAccounting) stats(bucket string) (ingressBytes, egressBytes int64) {
+	if b == nil {
+		return 0, 0
+	}
+	b.mutex.RLock()
+	counters, ok := b.buckets[bucket]
+	b.mutex.RUnlock()
+	if !ok {
+		return 0, 0
+	}
+	return atomic.LoadInt64(&counters.Ingress), atomic.LoadInt64(&counters.Egress)
+}
+
+// reset - clears the stats of bucket.
+func (b *bandwidthAccounting) reset(bucket string) {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	delete(b.buckets, bucket)
+	b.mutex.Unlock()
+	b.markChanged()
+}
+
+// load - reads the saved stats.
+func (b *bandwidthAccounting) load() error {
+	r, err := b.storage.ReadFile(minioMetaBucket, bandwidthStatsFile, 0)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	buckets := make(map[string]*bucketBandwidth)
+	if err = json.NewDecoder(r).Decode(&buckets); err != nil {
+		return err
+	}
+	b.mutex.Lock()
+	b.buckets = buckets
+	b.mutex.Unlock()
+	return nil
+}
+
+// save - saves the stats if they changed since the last save.
+func (b *bandwidthAccounting) save() error {
+	if !atomic.CompareAndSwapInt32(&b.changed, 1, 0) {
+		return nil
+	}
+	buckets := make(map[string]bucketBandwidth)
+	b.mutex.RLock()
+	for bucket, counters := range b.buckets {
+		buckets[bucket] = bucketBandwidth{
+			Ingress: atomic.LoadInt64(&counters.Ingress),
+			Egress:  atomic.LoadInt64(&counters.Egress),
+		}
+	}
+	b.mutex.RUnlock()
+	w, err := b.storage.CreateFile(minioMetaBucket, bandwidthStatsFile)
+	if err != nil {
+		return err
+	}
+	if err = json.NewEncoder(w).Encode(buckets); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	if err = w.Close(); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	return nil
+}
+
+// run - saves changed stats every bandwidthSaveInterval.
+func (b *bandwidthAccounting) run() {
+	for range time.Tick(bandwidthSaveInterval) {
+		if err := b.save(); err != nil {
+			// Retry on the next tick.
+			atomic.StoreInt32(&b.changed, 1)
+			errorIf(err, "Unable to save bandwidth stats.", nil)
+		}
+	}
+}
+
+// egressReadCloser - accounts bytes read as egress of a bucket.
+type egressReadCloser struct {
+	io.ReadCloser
+	accounting *bandwidthAccounting
+	counters   *bucketBandwidth
+}
+
+func (r *egressReadCloser) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	if n > 0 {
+		atomic.AddInt64(&r.counters.Egress, int64(n))
+		r.accounting.markChanged()
+	}
+	return n, err
+}
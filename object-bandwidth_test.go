@@ -0,0 +1,90 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+// Wrapper for calling bandwidth accounting tests for both XL multiple disks and single node setup.
+func TestBucketBandwidthStats(t *testing.T) {
+	ExecObjectLayerTest(t, testBucketBandwidthStats)
+}
+
+// Tests validate bytes written and read are accounted per bucket,
+// survive a reload and can be reset.
+func testBucketBandwidthStats(obj ObjectLayer, instanceType string, t *testing.T) {
+	for _, bucket := range []string{"bucket-a", "bucket-b"} {
+		if err := obj.MakeBucket(bucket); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	data := bytes.Repeat([]byte("a"), 1000)
+	if _, err := obj.PutObject("bucket-a", "object", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	// Streamed upload with an unknown size.
+	if _, err := obj.PutObject("bucket-a", "streamed", -1, bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	reader, err := obj.GetObject("bucket-a", "object", 100)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = ioutil.ReadAll(reader); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	reader.Close()
+	reader, err = obj.GetObjectRange("bucket-a", "streamed", 10, 20)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = ioutil.ReadAll(reader); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	reader.Close()
+
+	if ingress, egress := obj.BucketBandwidthStats("bucket-a"); ingress != 2000 || egress != 920 {
+		t.Fatalf("%s: Expected 2000 bytes in and 920 bytes out, but instead found %d and %d", instanceType, ingress, egress)
+	}
+	if ingress, egress := obj.BucketBandwidthStats("bucket-b"); ingress != 0 || egress != 0 {
+		t.Fatalf("%s: Expected no bytes accounted, but instead found %d and %d", instanceType, ingress, egress)
+	}
+
+	// Saved stats are loaded again after a restart.
+	var accounting *bandwidthAccounting
+	switch l := obj.(type) {
+	case xlObjects:
+		accounting = l.bandwidth
+	case fsObjects:
+		accounting = l.bandwidth
+	}
+	if err = accounting.save(); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	reloaded := newBandwidthAccounting(accounting.storage)
+	if ingress, egress := reloaded.stats("bucket-a"); ingress != 2000 || egress != 920 {
+		t.Fatalf("%s: Expected reloaded 2000 bytes in and 920 bytes out, but instead found %d and %d", instanceType, ingress, egress)
+	}
+
+	obj.ResetBucketBandwidthStats("bucket-a")
+	if ingress, egress := obj.BucketBandwidthStats("bucket-a"); ingress != 0 || egress != 0 {
+		t.Fatalf("%s: Expected reset stats, but instead found %d and %d", instanceType, ingress, egress)
+	}
+}
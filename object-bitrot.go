@@ -0,0 +1,88 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"path"
+)
+
+// bitRotVerificationKey - context key disabling bit rot verification.
+type bitRotVerificationKey struct{}
+
+// WithBitRotVerification - returns a copy of ctx for reads checking
+// the part checksums of multipart objects, or skipping the check for
+// latency sensitive reads if verify is false.
+func WithBitRotVerification(ctx context.Context, verify bool) context.Context {
+	return context.WithValue(ctx, bitRotVerificationKey{}, verify)
+}
+
+// BitRotVerificationFromContext - returns true unless ctx disables bit
+// rot verification.
+func BitRotVerificationFromContext(ctx context.Context) bool {
+	verify, ok := ctx.Value(bitRotVerificationKey{}).(bool)
+	return !ok || verify
+}
+
+// partChecksumFile - name of the file keeping the checksum of the part
+// uploaded as partSuffix. The "00000." prefix keeps it out of the parts
+// listed by ListObjectParts.
+func partChecksumFile(partSuffix string) string {
+	return "00000." + partSuffix + ".sha256"
+}
+
+// savePartChecksum - saves the hex SHA-256 checksum of an uploaded part.
+func savePartChecksum(storage StorageAPI, bucket, object, uploadID, partSuffix, checksum string) error {
+	checksumPath := path.Join(mpartMetaPrefix, bucket, object, uploadID, partChecksumFile(partSuffix))
+	w, err := storage.CreateFile(minioMetaBucket, checksumPath)
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write([]byte(checksum)); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	if err = w.Close(); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	return nil
+}
+
+// readPartChecksum - reads the checksum saved for an uploaded part,
+// empty for parts uploaded without one.
+func readPartChecksum(storage StorageAPI, bucket, object, uploadID, partSuffix string) (string, error) {
+	checksumPath := path.Join(mpartMetaPrefix, bucket, object, uploadID, partChecksumFile(partSuffix))
+	r, err := storage.ReadFile(minioMetaBucket, checksumPath, 0)
+	if err != nil {
+		if err == errFileNotFound {
+			return "", nil
+		}
+		return "", err
+	}
+	defer r.Close()
+	checksum, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(checksum), nil
+}
@@ -0,0 +1,127 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Tests validate parts of multipart objects are checked against the
+// checksums saved at upload time, unless the read skips verification.
+func TestXLBitRotDetection(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+	obj, err := newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xl := obj.(xlObjects)
+
+	bucket, object := "bucket", "object"
+	if err = xl.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	uploadID, err := xl.NewMultipartUpload(bucket, object, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data []byte
+	var parts []completePart
+	for i, size := range []int{5 * 1024 * 1024, 17} {
+		partData := bytes.Repeat([]byte{byte('a' + i)}, size)
+		var md5Sum string
+		md5Sum, err = xl.PutObjectPart(bucket, object, uploadID, i+1, int64(size), bytes.NewReader(partData), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, completePart{PartNumber: i + 1, ETag: md5Sum})
+		data = append(data, partData...)
+	}
+	if _, err = xl.CompleteMultipartUpload(bucket, object, uploadID, parts); err != nil {
+		t.Fatal(err)
+	}
+	info, err := getMultipartObjectInfo(xl.storage, bucket, object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range info.Parts {
+		if len(part.Checksum) != 64 {
+			t.Fatalf("Expected a SHA-256 checksum for part %d, got %q", part.PartNumber, part.Checksum)
+		}
+	}
+
+	readObject := func(ctx context.Context) ([]byte, error) {
+		reader, err := xl.GetObjectWithContext(ctx, bucket, object, 0)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return ioutil.ReadAll(reader)
+	}
+	if got, err := readObject(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(got, data) {
+		t.Fatal("Object data mismatch")
+	}
+
+	// Silently corrupt the second part, keeping its size.
+	corruptData := []byte("ccccccccccccccccX")
+	w, err := xl.storage.CreateFile(bucket, pathJoin(object, partNumToPartFileName(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write(corruptData); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = readObject(context.Background())
+	if bitRotErr, ok := err.(BitRotDetected); !ok {
+		t.Fatalf("Expected BitRotDetected, got %v", err)
+	} else if bitRotErr.PartNumber != 2 {
+		t.Fatalf("Expected bit rot in part 2, got part %d", bitRotErr.PartNumber)
+	}
+
+	// Reads skipping verification return the data as is.
+	got, err := readObject(WithBitRotVerification(context.Background(), false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, append(data[:5*1024*1024:5*1024*1024], corruptData...)) {
+		t.Fatal("Expected the corrupt data to be returned unverified")
+	}
+}
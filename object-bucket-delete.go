@@ -0,0 +1,168 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "path"
+
+// Maximum number of passes of DeleteBucketForce over the objects of a
+// bucket, objects still appearing after that are written by a live
+// writer which would otherwise keep the delete going forever.
+var deleteBucketForcePasses = 5
+
+// deleteAllObjects - deletes all objects listed in a bucket in parallel
+// batches, returns the number of objects deleted and the storage paths
+// removed. A dryRun deletes nothing and returns the paths it would
+// remove.
+func deleteAllObjects(layer ObjectLayer, bucket string, dryRun bool) (int, []string, error) {
+	deleted := 0
+	var paths []string
+	marker := ""
+	for {
+		result, err := layer.ListObjects(bucket, "", marker, "", maxObjectList)
+		if err != nil {
+			return deleted, paths, err
+		}
+		if len(result.Objects) == 0 {
+			return deleted, paths, nil
+		}
+		objects := make([]string, 0, len(result.Objects))
+		for _, objInfo := range result.Objects {
+			objects = append(objects, objInfo.Name)
+		}
+		var errs []error
+		if dryRun {
+			var objectPaths []string
+			objectPaths, errs, err = layer.DeleteObjectsDryRun(bucket, objects)
+			paths = append(paths, objectPaths...)
+		} else {
+			errs, err = layer.DeleteObjects(bucket, objects)
+		}
+		if err != nil {
+			return deleted, paths, err
+		}
+		for _, err = range errs {
+			if err != nil {
+				return deleted, paths, err
+			}
+		}
+		deleted += len(objects)
+		if !result.IsTruncated {
+			return deleted, paths, nil
+		}
+		marker = objects[len(objects)-1]
+	}
+}
+
+// abortAllUploads - aborts all multipart uploads in progress in a
+// bucket. A dryRun aborts nothing and returns the storage paths of the
+// parts uploaded so far.
+func abortAllUploads(layer ObjectLayer, storage StorageAPI, bucket string, dryRun bool) ([]string, error) {
+	var paths []string
+	keyMarker, uploadIDMarker := "", ""
+	for {
+		result, err := layer.ListMultipartUploads(bucket, "", keyMarker, uploadIDMarker, "", maxUploadsList)
+		if err != nil {
+			return paths, err
+		}
+		for _, upload := range result.Uploads {
+			if dryRun {
+				if !isUploadIDExists(storage, bucket, upload.Object, upload.UploadID) {
+					continue
+				}
+				var uploadPaths []string
+				uploadPaths, err = planDirDelete(storage, minioMetaBucket, path.Join(mpartMetaPrefix, bucket, upload.Object, upload.UploadID))
+				if err != nil {
+					return paths, toObjectErr(err, bucket, upload.Object)
+				}
+				paths = append(paths, uploadPaths...)
+				continue
+			}
+			if err = layer.AbortMultipartUpload(bucket, upload.Object, upload.UploadID); err != nil {
+				if _, ok := err.(InvalidUploadID); ok {
+					// Completed or aborted in the meantime.
+					continue
+				}
+				return paths, err
+			}
+		}
+		if !result.IsTruncated {
+			return paths, nil
+		}
+		keyMarker, uploadIDMarker = result.NextKeyMarker, result.NextUploadIDMarker
+	}
+}
+
+// deleteBucketForceCommon - common function for both object layers,
+// deletes all objects and uploads in progress of a bucket and then the
+// bucket itself. Deleting a bucket which doesn't exist succeeds, so an
+// interrupted delete can simply be run again. A dryRun runs the same
+// checks and listings without deleting anything and returns the
+// storage paths the delete would remove.
+func deleteBucketForceCommon(layer ObjectLayer, storage StorageAPI, bucket string, dryRun bool) ([]string, error) {
+	if err := checkMaintenanceMode("DeleteBucketForce"); err != nil {
+		return nil, err
+	}
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, BucketNameInvalid{Bucket: bucket}
+	}
+	var paths []string
+	for pass := 0; ; pass++ {
+		if !isBucketExist(storage, bucket) {
+			return paths, nil
+		}
+		if pass == deleteBucketForcePasses {
+			return nil, BucketBusy{Bucket: bucket, Passes: pass}
+		}
+		deleted, objectPaths, err := deleteAllObjects(layer, bucket, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, objectPaths...)
+		// Nothing is deleted by a dry run, a second pass would list
+		// the same objects again.
+		if deleted == 0 || dryRun {
+			break
+		}
+	}
+	uploadPaths, err := abortAllUploads(layer, storage, bucket, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	paths = append(paths, uploadPaths...)
+	// Staged data of puts in progress goes along with the bucket.
+	tmpPath := retainSlash(pathJoin(tmpMetaPrefix, bucket))
+	if dryRun {
+		var tmpPaths, bucketPaths []string
+		if tmpPaths, err = planDirDelete(storage, minioMetaBucket, tmpPath); err != nil {
+			return nil, toObjectErr(err, minioMetaBucket, tmpPath)
+		}
+		if bucketPaths, err = planBucketDelete(storage, bucket); err != nil {
+			return nil, err
+		}
+		return append(append(paths, tmpPaths...), bucketPaths...), nil
+	}
+	if _, err = cleanupTmpFiles(storage, tmpPath, 0); err != nil {
+		return nil, toObjectErr(err, minioMetaBucket, tmpPath)
+	}
+	err = deleteBucket(storage, bucket)
+	if _, ok := err.(BucketNotFound); ok {
+		// Deleted concurrently.
+		return paths, nil
+	}
+	return paths, err
+}
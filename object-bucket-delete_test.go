@@ -0,0 +1,85 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// Wrapper for calling DeleteBucketForce tests for both XL multiple disks and single node setup.
+func TestDeleteBucketForce(t *testing.T) {
+	ExecObjectLayerTest(t, testDeleteBucketForce)
+}
+
+// Tests validate a forced delete removes a bucket holding objects and
+// uploads in progress, and deleting it again succeeds.
+func testDeleteBucketForce(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello, world")
+	for i := 0; i < 10; i++ {
+		object := fmt.Sprintf("dir%d/nested/object%d", i%3, i)
+		if _, err := obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.PutObjectPart(bucket, "multipart", uploadID, 1, int64(len(data)), bytes.NewReader(data), ""); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	// Only allowed in maintenance mode.
+	if err = obj.DeleteBucketForce(bucket); err == nil {
+		t.Fatalf("%s: Expected MaintenanceModeRequired", instanceType)
+	} else if _, ok := err.(MaintenanceModeRequired); !ok {
+		t.Fatalf("%s: Expected MaintenanceModeRequired, got %v", instanceType, err)
+	}
+
+	EnterMaintenanceMode()
+	defer ExitMaintenanceMode()
+
+	if err = obj.DeleteBucketForce(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.GetBucketInfo(bucket); err == nil {
+		t.Fatalf("%s: Expected BucketNotFound", instanceType)
+	} else if _, ok := err.(BucketNotFound); !ok {
+		t.Fatalf("%s: Expected BucketNotFound, got %v", instanceType, err)
+	}
+	// Deleting again is a no-op.
+	if err = obj.DeleteBucketForce(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	// The bucket can be created afresh without old uploads.
+	if err = obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	result, err := obj.ListMultipartUploads(bucket, "", "", "", "", 1000)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(result.Uploads) != 0 {
+		t.Fatalf("%s: Expected no uploads, got %d", instanceType, len(result.Uploads))
+	}
+}
@@ -0,0 +1,197 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"path"
+	"time"
+)
+
+// Object layer operations made of several storage calls, such as
+// conditional writes and metadata updates, are serialized per object
+// on locks inside minioMetaBucket under this prefix, apart from the
+// locks the storage takes for the object files themselves.
+const objectLockPrefix = "locks"
+
+// lockObject - takes the object layer write lock of an object, returns
+// the function releasing it.
+func lockObject(bucket, object string) (unlock func()) {
+	lockPath := path.Join(objectLockPrefix, bucket, object)
+	nsMutex.Lock(minioMetaBucket, lockPath)
+	return func() {
+		nsMutex.Unlock(minioMetaBucket, lockPath)
+	}
+}
+
+// rlockObject - takes the object layer read lock of an object, holding
+// off writes of it, returns the function releasing it.
+func rlockObject(bucket, object string) (unlock func()) {
+	lockPath := path.Join(objectLockPrefix, bucket, object)
+	nsMutex.RLock(minioMetaBucket, lockPath)
+	return func() {
+		nsMutex.RUnlock(minioMetaBucket, lockPath)
+	}
+}
+
+// putObjectFunc - writes an object for an object layer. The data is
+// staged without the object write lock, which is taken for checking
+// the current object and putting the new one in place, unless lockHeld
+// says the caller holds it already. precheck, if set, is run first
+// under the lock and fails the write with its error.
+type putObjectFunc func(ctx context.Context, bucket, object string, size int64, data io.Reader, metadata map[string]string, lockHeld bool, precheck func() error) (PutObjectResult, error)
+
+// getObjectETag - returns the ETag of an object, computing it from the
+// object data on backends which don't save it.
+func getObjectETag(layer ObjectLayer, objInfo ObjectInfo) (string, error) {
+	if objInfo.MD5Sum != "" {
+		return objInfo.MD5Sum, nil
+	}
+	reader, err := layer.GetObjectWithHash(objInfo.Bucket, objInfo.Name)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	if _, err = io.Copy(ioutil.Discard, reader); err != nil {
+		return "", toObjectErr(err, objInfo.Bucket, objInfo.Name)
+	}
+	return reader.Sum()
+}
+
+// ObjectConditions - preconditions of GetObjectConditional and
+// PutObjectConditional, evaluated in the order of RFC 7232. ETags are
+// compared in the form of ObjectInfo.MD5Sum, quoted or not, each
+// condition may list several ETags separated by commas or be "*" to
+// match any existing object. Times are compared against ModTime at
+// second precision, as carried by HTTP dates.
+type ObjectConditions struct {
+	// IfMatch - the object must exist with one of these ETags.
+	IfMatch string
+	// IfNoneMatch - the object must not have any of these ETags, reads
+	// of a matching object fail with NotModified, writes with
+	// PreconditionFailed.
+	IfNoneMatch string
+	// IfModifiedSince - reads of an object not modified after this
+	// time fail with NotModified. Ignored by writes and along with
+	// IfNoneMatch.
+	IfModifiedSince time.Time
+	// IfUnmodifiedSince - the object must not be modified after this
+	// time. Ignored along with IfMatch.
+	IfUnmodifiedSince time.Time
+}
+
+// modifiedSince - reports if modTime is after t at the second precision
+// of HTTP dates, sub-second mod times don't count as modifications.
+func modifiedSince(modTime, t time.Time) bool {
+	return modTime.Truncate(time.Second).After(t.Truncate(time.Second))
+}
+
+// checkObjectConditions - evaluates the preconditions against the
+// current ETag and mod time of an object, write selects the errors
+// returned for writes. A missing object only fails a write requiring
+// a match.
+func checkObjectConditions(layer ObjectLayer, bucket, object string, cond ObjectConditions, write bool) error {
+	checkUnmodified := cond.IfMatch == "" && !cond.IfUnmodifiedSince.IsZero()
+	checkModified := !write && cond.IfNoneMatch == "" && !cond.IfModifiedSince.IsZero()
+	if cond.IfMatch == "" && cond.IfNoneMatch == "" && !checkUnmodified && !checkModified {
+		return nil
+	}
+	exists := true
+	objInfo, err := layer.GetObjectInfo(bucket, object)
+	if err != nil {
+		if _, ok := err.(ObjectNotFound); !ok || !write {
+			return err
+		}
+		exists = false
+	}
+	var etag string
+	if exists && (cond.IfMatch != "" || cond.IfNoneMatch != "") {
+		if etag, err = getObjectETag(layer, objInfo); err != nil {
+			return err
+		}
+	}
+	if cond.IfMatch != "" && (!exists || !etagMatches(etag, cond.IfMatch, false)) {
+		return PreconditionFailed{
+			Bucket:       bucket,
+			Object:       object,
+			ExpectedETag: canonicalETag(cond.IfMatch),
+			ETag:         etag,
+		}
+	}
+	if checkUnmodified && exists && modifiedSince(objInfo.ModTime, cond.IfUnmodifiedSince) {
+		return PreconditionFailed{Bucket: bucket, Object: object, ETag: objInfo.MD5Sum}
+	}
+	if cond.IfNoneMatch != "" && exists && etagMatches(etag, cond.IfNoneMatch, true) {
+		if !write {
+			return NotModified{Bucket: bucket, Object: object, ETag: etag}
+		}
+		return PreconditionFailed{Bucket: bucket, Object: object, ETag: etag}
+	}
+	if checkModified && !modifiedSince(objInfo.ModTime, cond.IfModifiedSince) {
+		return NotModified{Bucket: bucket, Object: object, ETag: objInfo.MD5Sum}
+	}
+	return nil
+}
+
+// getObjectConditionalCommon - common function to read an object only
+// if its preconditions hold for both object layers, no data is read
+// when they don't.
+func getObjectConditionalCommon(layer ObjectLayer, bucket, object string, startOffset int64, cond ObjectConditions) (io.ReadCloser, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, BucketNameInvalid{Bucket: bucket}
+	}
+	if !IsValidObjectName(object) {
+		return nil, ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	if err := checkObjectConditions(layer, bucket, object, cond, false); err != nil {
+		return nil, err
+	}
+	return layer.GetObject(bucket, object, startOffset)
+}
+
+// putObjectConditionalCommon - common function to write an object only
+// if its preconditions hold for both object layers. Checking and
+// writing is atomic with respect to other writes of the object.
+func putObjectConditionalCommon(layer ObjectLayer, bucket, object string, size int64, data io.Reader, metadata map[string]string, cond ObjectConditions, putObject putObjectFunc) (string, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return "", BucketNameInvalid{Bucket: bucket}
+	}
+	if !IsValidObjectName(object) {
+		return "", ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	// Preconditions are checked under the object lock the new object is
+	// put in place with.
+	result, err := putObject(context.Background(), bucket, object, size, data, metadata, false, func() error {
+		return checkObjectConditions(layer, bucket, object, cond, true)
+	})
+	return result.ETag, err
+}
+
+// compareAndSwapObjectCommon - common function to write an object only
+// if its current ETag is expectedETag for both object layers. An empty
+// expectedETag requires the object to not exist.
+func compareAndSwapObjectCommon(layer ObjectLayer, bucket, object, expectedETag string, newData io.Reader, size int64, putObject putObjectFunc) (string, error) {
+	cond := ObjectConditions{IfMatch: expectedETag}
+	if canonicalETag(expectedETag) == "" {
+		cond = ObjectConditions{IfNoneMatch: "*"}
+	}
+	return putObjectConditionalCommon(layer, bucket, object, size, newData, nil, cond, putObject)
+}
@@ -0,0 +1,392 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Wrapper for calling compare-and-swap tests for both XL multiple disks and single node setup.
+func TestCompareAndSwapObject(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testCompareAndSwapObject)
+}
+
+// Tests validate that concurrent compare-and-swap increments of a
+// counter object never lose an update.
+func testCompareAndSwapObject(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	etag, err := obj.CompareAndSwapObject(bucket, "counter", "", bytes.NewReader([]byte("0")), 1)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	// Creating again fails since the object exists.
+	if _, err = obj.CompareAndSwapObject(bucket, "counter", "", bytes.NewReader([]byte("0")), 1); err == nil {
+		t.Fatalf("%s: Expected PreconditionFailed", instanceType)
+	} else if failed, ok := err.(PreconditionFailed); !ok || failed.ETag != etag {
+		t.Fatalf("%s: Expected PreconditionFailed with current ETag, got %v", instanceType, err)
+	}
+
+	// Increment the counter until the swap succeeds.
+	increment := func() error {
+		for {
+			reader, err := obj.GetObjectWithHash(bucket, "counter")
+			if err != nil {
+				return err
+			}
+			data, err := ioutil.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				return err
+			}
+			etag, err := reader.Sum()
+			if err != nil {
+				return err
+			}
+			value, err := strconv.Atoi(string(data))
+			if err != nil {
+				return err
+			}
+			newData := []byte(strconv.Itoa(value + 1))
+			_, err = obj.CompareAndSwapObject(bucket, "counter", "\""+etag+"\"", bytes.NewReader(newData), int64(len(newData)))
+			if _, ok := err.(PreconditionFailed); ok {
+				continue
+			}
+			return err
+		}
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = increment()
+		}(i)
+	}
+	wg.Wait()
+	for _, err = range errs {
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+
+	reader, err := obj.GetObject(bucket, "counter", 0)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if string(data) != strconv.Itoa(len(errs)) {
+		t.Fatalf("%s: Expected counter %d, got %s", instanceType, len(errs), data)
+	}
+}
+
+// Wrapper for calling conditional read and write tests for both XL multiple disks and single node setup.
+func TestObjectConditional(t *testing.T) {
+	initNSLock()
+	ExecObjectLayerTest(t, testObjectConditional)
+}
+
+// Tests validate If-Match and If-None-Match preconditions of
+// conditional reads and writes.
+func testObjectConditional(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	object := "minio-object"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	put := func(data string, cond ObjectConditions) (string, error) {
+		return obj.PutObjectConditional(bucket, object, int64(len(data)), bytes.NewReader([]byte(data)), nil, cond)
+	}
+
+	// If-None-Match "*" creates the object only once.
+	etag, err := put("v1", ObjectConditions{IfNoneMatch: "*"})
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = put("v1", ObjectConditions{IfNoneMatch: "*"}); err == nil {
+		t.Fatalf("%s: Expected PreconditionFailed overwriting with If-None-Match *", instanceType)
+	} else if _, ok := err.(PreconditionFailed); !ok {
+		t.Fatalf("%s: Expected PreconditionFailed, got %v", instanceType, err)
+	}
+
+	// If-Match overwrites only the expected version, quoted or not.
+	if _, err = put("v2", ObjectConditions{IfMatch: "\"0123\""}); err == nil {
+		t.Fatalf("%s: Expected PreconditionFailed for a stale ETag", instanceType)
+	}
+	newETag, err := put("v2", ObjectConditions{IfMatch: "\"" + etag + "\""})
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.PutObjectConditional(bucket, "missing", 1, bytes.NewReader([]byte("x")), nil, ObjectConditions{IfMatch: "*"}); err == nil {
+		t.Fatalf("%s: Expected PreconditionFailed for If-Match on a missing object", instanceType)
+	}
+
+	// Conditional reads.
+	testCases := []struct {
+		cond ObjectConditions
+		// Expected error, nil if the data is returned.
+		err error
+	}{
+		{ObjectConditions{IfMatch: newETag}, nil},
+		{ObjectConditions{IfMatch: etag}, PreconditionFailed{}},
+		{ObjectConditions{IfNoneMatch: etag}, nil},
+		{ObjectConditions{IfNoneMatch: "\"" + newETag + "\""}, NotModified{}},
+		{ObjectConditions{IfNoneMatch: "*"}, NotModified{}},
+	}
+	for i, testCase := range testCases {
+		reader, err := obj.GetObjectConditional(bucket, object, 0, testCase.cond)
+		switch testCase.err.(type) {
+		case nil:
+			if err != nil {
+				t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+			}
+			data, err := ioutil.ReadAll(reader)
+			reader.Close()
+			if err != nil || string(data) != "v2" {
+				t.Errorf("%s: Test %d: Expected v2, got %q, %v", instanceType, i+1, data, err)
+			}
+		case PreconditionFailed:
+			if _, ok := err.(PreconditionFailed); !ok {
+				t.Errorf("%s: Test %d: Expected PreconditionFailed, got %v", instanceType, i+1, err)
+			}
+		case NotModified:
+			if _, ok := err.(NotModified); !ok {
+				t.Errorf("%s: Test %d: Expected NotModified, got %v", instanceType, i+1, err)
+			}
+		}
+	}
+}
+
+// Wrapper for calling conditional read tests on mod times for both XL multiple disks and single node setup.
+func TestObjectConditionalModTime(t *testing.T) {
+	initNSLock()
+	ExecObjectLayerTest(t, testObjectConditionalModTime)
+}
+
+// Tests validate If-Modified-Since and If-Unmodified-Since
+// preconditions compare mod times at second precision, for simple and
+// multipart objects.
+func testObjectConditionalModTime(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello")
+	etag, err := obj.PutObject(bucket, "simple", int64(len(data)), bytes.NewReader(data), nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	md5Sum, err := obj.PutObjectPart(bucket, "multipart", uploadID, 1, int64(len(data)), bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, "multipart", uploadID, []completePart{{PartNumber: 1, ETag: md5Sum}}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	for _, object := range []string{"simple", "multipart"} {
+		objInfo, err := obj.GetObjectInfo(bucket, object)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		// HTTP dates carry whole seconds only.
+		httpModTime := objInfo.ModTime.Truncate(time.Second)
+		before := httpModTime.Add(-time.Second)
+		after := httpModTime.Add(time.Second)
+
+		testCases := []struct {
+			cond ObjectConditions
+			// Expected error, nil if the data is returned.
+			err error
+		}{
+			{ObjectConditions{IfModifiedSince: before}, nil},
+			{ObjectConditions{IfModifiedSince: httpModTime}, NotModified{}},
+			{ObjectConditions{IfModifiedSince: objInfo.ModTime}, NotModified{}},
+			{ObjectConditions{IfModifiedSince: after}, NotModified{}},
+			{ObjectConditions{IfUnmodifiedSince: httpModTime}, nil},
+			{ObjectConditions{IfUnmodifiedSince: after}, nil},
+			{ObjectConditions{IfUnmodifiedSince: before}, PreconditionFailed{}},
+			// ETag conditions take precedence over mod times.
+			{ObjectConditions{IfNoneMatch: "0123", IfModifiedSince: after}, nil},
+			{ObjectConditions{IfMatch: "*", IfUnmodifiedSince: before}, nil},
+		}
+		for i, testCase := range testCases {
+			reader, err := obj.GetObjectConditional(bucket, object, 0, testCase.cond)
+			switch testCase.err.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("%s: %s: Test %d: %s", instanceType, object, i+1, err.Error())
+				}
+				got, err := ioutil.ReadAll(reader)
+				reader.Close()
+				if err != nil || !bytes.Equal(got, data) {
+					t.Errorf("%s: %s: Test %d: Expected %q, got %q, %v", instanceType, object, i+1, data, got, err)
+				}
+			case PreconditionFailed:
+				if _, ok := err.(PreconditionFailed); !ok {
+					t.Errorf("%s: %s: Test %d: Expected PreconditionFailed, got %v", instanceType, object, i+1, err)
+				}
+			case NotModified:
+				if _, ok := err.(NotModified); !ok {
+					t.Errorf("%s: %s: Test %d: Expected NotModified, got %v", instanceType, object, i+1, err)
+				}
+			}
+		}
+	}
+
+	// Writes honor If-Unmodified-Since and ignore If-Modified-Since.
+	objInfo, err := obj.GetObjectInfo(bucket, "simple")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	before := objInfo.ModTime.Truncate(time.Second).Add(-time.Second)
+	after := objInfo.ModTime.Add(time.Second)
+	cond := ObjectConditions{IfUnmodifiedSince: before}
+	if _, err = obj.PutObjectConditional(bucket, "simple", int64(len(data)), bytes.NewReader(data), nil, cond); err == nil {
+		t.Fatalf("%s: Expected PreconditionFailed for If-Unmodified-Since", instanceType)
+	} else if _, ok := err.(PreconditionFailed); !ok {
+		t.Fatalf("%s: Expected PreconditionFailed, got %v", instanceType, err)
+	}
+	cond = ObjectConditions{IfModifiedSince: after}
+	if newETag, err := obj.PutObjectConditional(bucket, "simple", int64(len(data)), bytes.NewReader(data), nil, cond); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	} else if newETag != etag {
+		t.Fatalf("%s: Expected ETag %s, got %s", instanceType, etag, newETag)
+	}
+}
+
+// Wrapper for calling object lock tests for both XL multiple disks and single node setup.
+func TestObjectWritesLockObject(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testObjectWritesLockObject)
+}
+
+// Tests validate puts, multipart completes and deletes of an object
+// wait for its object lock, unless the caller says it holds the lock.
+// The data of a put is received without holding the lock.
+func testObjectWritesLockObject(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	object := "minio-object"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello, world")
+	uploadID, err := obj.NewMultipartUpload(bucket, object, nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	md5Sum, err := obj.PutObjectPart(bucket, object, uploadID, 1, int64(len(data)), bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	testCases := []struct {
+		name string
+		op   func() error
+	}{
+		{"PutObject", func() error {
+			_, err := obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), nil)
+			return err
+		}},
+		{"CompleteMultipartUpload", func() error {
+			_, err := obj.CompleteMultipartUpload(bucket, object, uploadID, []completePart{{PartNumber: 1, ETag: md5Sum}})
+			return err
+		}},
+		{"DeleteObject", func() error {
+			return obj.DeleteObject(bucket, object)
+		}},
+	}
+	for _, testCase := range testCases {
+		unlock := lockObject(bucket, object)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- testCase.op()
+		}()
+		select {
+		case err = <-errCh:
+			unlock()
+			t.Fatalf("%s: %s: Expected to wait for the object lock, got %v", instanceType, testCase.name, err)
+		case <-time.After(50 * time.Millisecond):
+		}
+		unlock()
+		if err = <-errCh; err != nil {
+			t.Fatalf("%s: %s: %s", instanceType, testCase.name, err)
+		}
+	}
+
+	reader, writer := io.Pipe()
+	errCh := make(chan error, 1)
+	go func() {
+		_, perr := obj.PutObject(bucket, object, int64(len(data)), reader, nil)
+		errCh <- perr
+	}()
+	if _, err = writer.Write(data[:1]); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	lockCh := make(chan func(), 1)
+	go func() {
+		lockCh <- lockObject(bucket, object)
+	}()
+	select {
+	case unlock := <-lockCh:
+		unlock()
+	case <-time.After(time.Second):
+		t.Fatalf("%s: Expected the object lock to be free while data is received", instanceType)
+	}
+	if _, err = writer.Write(data[1:]); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	writer.Close()
+	if err = <-errCh; err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	// Callers holding the lock write through it.
+	var putObject putObjectFunc
+	switch layer := obj.(type) {
+	case fsObjects:
+		putObject = layer.putObject
+	case xlObjects:
+		putObject = layer.putObject
+	}
+	unlock := lockObject(bucket, object)
+	defer unlock()
+	if _, err = putObject(context.Background(), bucket, object, int64(len(data)), bytes.NewReader(data), nil, true, nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+}
@@ -0,0 +1,330 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+)
+
+const (
+	// Checkpointed uploads prefix, inside minioMetaBucket. Not under
+	// tmpMetaPrefix since staged data has to survive a restart.
+	checkpointMetaPrefix = "checkpoints"
+	// Checkpoint file, records the durably staged offset.
+	checkpointFile = "checkpoint.json"
+
+	// Metadata key enabling checkpoints on PutObject, value is the
+	// interval in bytes between two fsyncs.
+	checkpointIntervalKey = "checkpointInterval"
+	// Metadata key carrying the offset the data reader resumes from,
+	// must match the offset of the last checkpoint.
+	checkpointOffsetKey = "checkpointOffset"
+)
+
+// putCheckpoint - staged state of a checkpointed upload.
+type putCheckpoint struct {
+	Version string `json:"version"`
+	// Offset - number of bytes durably staged.
+	Offset int64 `json:"offset"`
+	// Segments - sizes of the staged segments, in order.
+	Segments []int64 `json:"segments"`
+}
+
+// getCheckpointInterval - returns the checkpoint interval requested in
+// metadata, 0 if checkpoints are not requested.
+func getCheckpointInterval(metadata map[string]string) int64 {
+	if len(metadata) == 0 {
+		return 0
+	}
+	value, ok := metadata[checkpointIntervalKey]
+	if !ok {
+		return 0
+	}
+	interval, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || interval <= 0 {
+		return 0
+	}
+	return interval
+}
+
+// checkpointDir - directory holding the staged segments of an object.
+func checkpointDir(bucket, object string) string {
+	return path.Join(checkpointMetaPrefix, bucket, object)
+}
+
+// lockCheckpoint - takes the lock serializing the checkpointed uploads
+// of an object, returns the function releasing it.
+func lockCheckpoint(bucket, object string) (unlock func()) {
+	lockPath := checkpointDir(bucket, object)
+	nsMutex.Lock(minioMetaBucket, lockPath)
+	return func() {
+		nsMutex.Unlock(minioMetaBucket, lockPath)
+	}
+}
+
+// checkpointSegment - path of the n'th staged segment of an object.
+func checkpointSegment(bucket, object string, n int) string {
+	return path.Join(checkpointDir(bucket, object), fmt.Sprintf("%.5d.segment", n))
+}
+
+// readCheckpoint - reads the last checkpoint of an object, returns an
+// empty checkpoint if none exists.
+func readCheckpoint(storage StorageAPI, bucket, object string) (putCheckpoint, error) {
+	offset := int64(0)
+	r, err := storage.ReadFile(minioMetaBucket, path.Join(checkpointDir(bucket, object), checkpointFile), offset)
+	if err != nil {
+		if err == errFileNotFound {
+			return putCheckpoint{Version: "1"}, nil
+		}
+		return putCheckpoint{}, err
+	}
+	defer r.Close()
+	var cp putCheckpoint
+	decoder := json.NewDecoder(r)
+	if err = decoder.Decode(&cp); err != nil {
+		return putCheckpoint{}, err
+	}
+	return cp, nil
+}
+
+// syncClose - closes a staged writer of path and flushes the file to
+// stable storage, the file is removed if it can't be flushed.
+func syncClose(storage StorageAPI, path string, w io.WriteCloser) error {
+	if err := w.Close(); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	syncer, ok := storage.(fileSyncer)
+	if !ok {
+		return errSyncNotSupported
+	}
+	if err := syncer.SyncFile(minioMetaBucket, path); err != nil {
+		if derr := storage.DeleteFile(minioMetaBucket, path); derr != nil && derr != errFileNotFound {
+			return derr
+		}
+		return err
+	}
+	return nil
+}
+
+// writeCheckpoint - durably saves the checkpoint of an object.
+func writeCheckpoint(storage StorageAPI, bucket, object string, cp putCheckpoint) error {
+	checkpointPath := path.Join(checkpointDir(bucket, object), checkpointFile)
+	w, err := storage.CreateFile(minioMetaBucket, checkpointPath)
+	if err != nil {
+		return err
+	}
+	cp.Version = "1"
+	encoder := json.NewEncoder(w)
+	if err = encoder.Encode(&cp); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	return syncClose(storage, checkpointPath, w)
+}
+
+// removeCheckpoint - removes the checkpoint and all staged segments.
+func removeCheckpoint(storage StorageAPI, bucket, object string) error {
+	return cleanupDir(storage, minioMetaBucket, checkpointDir(bucket, object))
+}
+
+// getPutObjectCheckpoint - common function returning the offset a
+// checkpointed upload can be resumed from, for both object layers.
+func getPutObjectCheckpoint(storage StorageAPI, bucket, object string) (int64, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return 0, BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(storage, bucket) {
+		return 0, BucketNotFound{Bucket: bucket}
+	}
+	if !IsValidObjectName(object) {
+		return 0, ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	cp, err := readCheckpoint(storage, bucket, object)
+	if err != nil {
+		return 0, toObjectErr(err, bucket, object)
+	}
+	return cp.Offset, nil
+}
+
+// stageCheckpointedUpload - stages data into segments of interval
+// bytes, each segment is fsynced and recorded in the checkpoint before
+// the next one is started. size is the number of bytes remaining from
+// the resume offset, size <= 0 reads until EOF.
+func stageCheckpointedUpload(storage StorageAPI, bucket, object string, size int64, data io.Reader, interval int64, resumeOffset int64) (putCheckpoint, error) {
+	cp, err := readCheckpoint(storage, bucket, object)
+	if err != nil {
+		return putCheckpoint{}, err
+	}
+	if resumeOffset == 0 && cp.Offset != 0 {
+		// Fresh upload, discard the previous staged data.
+		if err = removeCheckpoint(storage, bucket, object); err != nil {
+			return putCheckpoint{}, err
+		}
+		cp = putCheckpoint{Version: "1"}
+	}
+	if resumeOffset != cp.Offset {
+		return putCheckpoint{}, InvalidCheckpointOffset{Bucket: bucket, Object: object, Offset: resumeOffset}
+	}
+	remaining := size
+	for size <= 0 || remaining > 0 {
+		n := interval
+		if size > 0 && remaining < n {
+			n = remaining
+		}
+		segmentPath := checkpointSegment(bucket, object, len(cp.Segments))
+		var w io.WriteCloser
+		w, err = storage.CreateFile(minioMetaBucket, segmentPath)
+		if err != nil {
+			return putCheckpoint{}, err
+		}
+		var written int64
+		written, err = io.CopyN(w, data, n)
+		if err == io.EOF && size <= 0 {
+			// Reached the end of data of unknown size.
+			if written == 0 {
+				if err = safeCloseAndRemove(w); err != nil {
+					return putCheckpoint{}, err
+				}
+				break
+			}
+			err = nil
+		}
+		if err != nil {
+			if clErr := safeCloseAndRemove(w); clErr != nil {
+				return putCheckpoint{}, clErr
+			}
+			return putCheckpoint{}, err
+		}
+		if err = syncClose(storage, segmentPath, w); err != nil {
+			return putCheckpoint{}, err
+		}
+		cp.Segments = append(cp.Segments, written)
+		cp.Offset += written
+		if err = writeCheckpoint(storage, bucket, object, cp); err != nil {
+			return putCheckpoint{}, err
+		}
+		remaining -= written
+		if size <= 0 && written < n {
+			break
+		}
+	}
+	return cp, nil
+}
+
+// checkpointReader - reads the staged segments of an object in order.
+type checkpointReader struct {
+	storage StorageAPI
+	bucket  string
+	object  string
+	cp      putCheckpoint
+	segment int
+	rc      io.ReadCloser
+}
+
+// Read - implements io.Reader, opens segments as they are needed.
+func (r *checkpointReader) Read(p []byte) (int, error) {
+	for {
+		if r.rc == nil {
+			if r.segment >= len(r.cp.Segments) {
+				return 0, io.EOF
+			}
+			offset := int64(0)
+			rc, err := r.storage.ReadFile(minioMetaBucket, checkpointSegment(r.bucket, r.object, r.segment), offset)
+			if err != nil {
+				return 0, err
+			}
+			r.rc = rc
+		}
+		n, err := r.rc.Read(p)
+		if err == io.EOF {
+			r.rc.Close()
+			r.rc = nil
+			r.segment++
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+// Close - closes the currently open segment if any.
+func (r *checkpointReader) Close() error {
+	if r.rc != nil {
+		return r.rc.Close()
+	}
+	return nil
+}
+
+// putObjectCheckpointed - common function for both object layers,
+// stages data with fsync checkpoints and then has store assemble the
+// staged segments into the object, once, and put it in place. Staged
+// data is removed only after store succeeds, on failure the upload can
+// be resumed from the offset returned by GetPutObjectCheckpoint. The
+// upload holds the checkpoint lock of the object throughout, so that
+// concurrent uploads or resumes of it don't mix their segments.
+func putObjectCheckpointed(storage StorageAPI, bucket, object string, size int64, data io.Reader, metadata map[string]string, store func(int64, io.Reader, map[string]string) (PutObjectResult, error)) (PutObjectResult, error) {
+	interval := getCheckpointInterval(metadata)
+	// Checkpoints are worthless unless staged data survives a crash.
+	if _, ok := storage.(fileSyncer); !ok {
+		return PutObjectResult{}, errSyncNotSupported
+	}
+	var resumeOffset int64
+	if value, ok := metadata[checkpointOffsetKey]; ok {
+		var err error
+		resumeOffset, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || resumeOffset < 0 {
+			return PutObjectResult{}, InvalidCheckpointOffset{Bucket: bucket, Object: object, Offset: resumeOffset}
+		}
+	}
+	unlock := lockCheckpoint(bucket, object)
+	defer unlock()
+	cp, err := stageCheckpointedUpload(storage, bucket, object, size, data, interval, resumeOffset)
+	if err != nil {
+		return PutObjectResult{}, toObjectErr(err, bucket, object)
+	}
+
+	// Strip checkpoint keys, rest of the metadata applies to the object.
+	objMetadata := make(map[string]string)
+	for k, v := range metadata {
+		if k == checkpointIntervalKey || k == checkpointOffsetKey {
+			continue
+		}
+		objMetadata[k] = v
+	}
+	reader := &checkpointReader{storage: storage, bucket: bucket, object: object, cp: cp}
+	result, err := store(cp.Offset, reader, objMetadata)
+	reader.Close()
+	if err != nil {
+		return PutObjectResult{}, err
+	}
+	if err = removeCheckpoint(storage, bucket, object); err != nil {
+		return PutObjectResult{}, toObjectErr(err, bucket, object)
+	}
+	return result, nil
+}
@@ -0,0 +1,238 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// Wrapper for calling checkpointed PutObject tests for both XL multiple disks and single node setup.
+func TestPutObjectCheckpoint(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testPutObjectCheckpoint)
+}
+
+// Tests validate that an interrupted checkpointed upload resumes
+// from the last checkpoint.
+func testPutObjectCheckpoint(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	object := "minio-object"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	data := bytes.Repeat([]byte("abcdefghij"), 10)
+	metadata := map[string]string{checkpointIntervalKey: "30"}
+
+	// Interrupt the upload after 70 bytes, two checkpoints are complete.
+	reader := io.MultiReader(bytes.NewReader(data[:70]), iotestErrReader{})
+	if _, err := obj.PutObject(bucket, object, int64(len(data)), reader, metadata); err == nil {
+		t.Fatalf("%s: Expected interrupted upload to fail", instanceType)
+	}
+	offset, err := obj.GetPutObjectCheckpoint(bucket, object)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if offset != 60 {
+		t.Fatalf("%s: Expected checkpoint offset 60, got %d", instanceType, offset)
+	}
+
+	// Resuming from a wrong offset should fail.
+	metadata[checkpointOffsetKey] = "10"
+	_, err = obj.PutObject(bucket, object, int64(len(data)-10), bytes.NewReader(data[10:]), metadata)
+	if _, ok := err.(InvalidCheckpointOffset); !ok {
+		t.Fatalf("%s: Expected InvalidCheckpointOffset, got %v", instanceType, err)
+	}
+
+	// Resume from the checkpoint.
+	metadata[checkpointOffsetKey] = strconv.FormatInt(offset, 10)
+	if _, err = obj.PutObject(bucket, object, int64(len(data))-offset, bytes.NewReader(data[offset:]), metadata); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	r, err := obj.GetObject(bucket, object, 0)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	defer r.Close()
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("%s: Resumed object content mismatch", instanceType)
+	}
+	// Staged data is cleaned up once the object is written.
+	if offset, err = obj.GetPutObjectCheckpoint(bucket, object); err != nil || offset != 0 {
+		t.Fatalf("%s: Expected checkpoint to be removed, got %d %v", instanceType, offset, err)
+	}
+}
+
+// Wrapper for calling concurrent checkpoint resume tests for both XL multiple disks and single node setup.
+func TestPutObjectCheckpointConcurrentResume(t *testing.T) {
+	initNSLock()
+	ExecObjectLayerTest(t, testPutObjectCheckpointConcurrentResume)
+}
+
+// Tests validate concurrent resumes of an upload are serialized, only
+// one of them completes the object and the other finds the checkpoint
+// gone.
+func testPutObjectCheckpointConcurrentResume(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	object := "minio-object"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := bytes.Repeat([]byte("abcdefghij"), 1000)
+	metadata := map[string]string{checkpointIntervalKey: "100"}
+	reader := io.MultiReader(bytes.NewReader(data[:550]), iotestErrReader{})
+	if _, err := obj.PutObject(bucket, object, int64(len(data)), reader, metadata); err == nil {
+		t.Fatalf("%s: Expected interrupted upload to fail", instanceType)
+	}
+	offset, err := obj.GetPutObjectCheckpoint(bucket, object)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if offset != 500 {
+		t.Fatalf("%s: Expected checkpoint offset 500, got %d", instanceType, offset)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resumeMetadata := map[string]string{
+				checkpointIntervalKey: "100",
+				checkpointOffsetKey:   strconv.FormatInt(offset, 10),
+			}
+			_, errs[i] = obj.PutObject(bucket, object, int64(len(data))-offset, bytes.NewReader(data[offset:]), resumeMetadata)
+		}(i)
+	}
+	wg.Wait()
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+		} else if _, ok := err.(InvalidCheckpointOffset); !ok {
+			t.Fatalf("%s: Expected InvalidCheckpointOffset, got %v", instanceType, err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("%s: Expected a single resume to succeed, got %d", instanceType, succeeded)
+	}
+	r, err := obj.GetObject(bucket, object, 0)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	defer r.Close()
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("%s: Resumed object content mismatch", instanceType)
+	}
+}
+
+// iotestErrReader - reader which always fails.
+type iotestErrReader struct{}
+
+func (iotestErrReader) Read(p []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+// unsyncedStorage - storage hiding the SyncFile of the storage it
+// wraps, like a storage unable to flush files.
+type unsyncedStorage struct {
+	StorageAPI
+}
+
+// syncCountingStorage - counts files flushed to stable storage.
+type syncCountingStorage struct {
+	StorageAPI
+	synced []string
+}
+
+func (s *syncCountingStorage) SyncFile(volume, path string) error {
+	s.synced = append(s.synced, path)
+	return s.StorageAPI.(fileSyncer).SyncFile(volume, path)
+}
+
+// Wrapper for calling checkpoint sync tests for both XL multiple disks and single node setup.
+func TestPutObjectCheckpointSync(t *testing.T) {
+	initNSLock()
+	ExecObjectLayerTest(t, testPutObjectCheckpointSync)
+}
+
+// Tests validate every staged segment and checkpoint is flushed to
+// stable storage, and that checkpoints fail on storage unable to.
+func testPutObjectCheckpointSync(obj ObjectLayer, instanceType string, t *testing.T) {
+	var storage StorageAPI
+	switch layer := obj.(type) {
+	case fsObjects:
+		storage = layer.storage
+	case xlObjects:
+		storage = layer.storage
+	}
+	bucket := "minio-bucket"
+	object := "minio-object"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := bytes.Repeat([]byte("abcdefghij"), 10)
+	metadata := map[string]string{checkpointIntervalKey: "30"}
+	store := func(size int64, data io.Reader, metadata map[string]string) (PutObjectResult, error) {
+		return obj.PutObjectWithChecksums(bucket, object, size, data, metadata)
+	}
+
+	_, err := putObjectCheckpointed(unsyncedStorage{storage}, bucket, object, int64(len(data)), bytes.NewReader(data), metadata, func(size int64, data io.Reader, metadata map[string]string) (PutObjectResult, error) {
+		t.Fatalf("%s: Expected nothing to be put", instanceType)
+		return PutObjectResult{}, nil
+	})
+	if err != errSyncNotSupported {
+		t.Fatalf("%s: Expected %v, got %v", instanceType, errSyncNotSupported, err)
+	}
+
+	counting := &syncCountingStorage{StorageAPI: storage}
+	_, err = putObjectCheckpointed(counting, bucket, object, int64(len(data)), bytes.NewReader(data), metadata, store)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	// Four segments of at most 30 bytes, each followed by a checkpoint.
+	expected := []string{
+		checkpointSegment(bucket, object, 0), checkpointDir(bucket, object) + "/" + checkpointFile,
+		checkpointSegment(bucket, object, 1), checkpointDir(bucket, object) + "/" + checkpointFile,
+		checkpointSegment(bucket, object, 2), checkpointDir(bucket, object) + "/" + checkpointFile,
+		checkpointSegment(bucket, object, 3), checkpointDir(bucket, object) + "/" + checkpointFile,
+	}
+	if len(counting.synced) != len(expected) {
+		t.Fatalf("%s: Expected %v synced, got %v", instanceType, expected, counting.synced)
+	}
+	for i := range expected {
+		if counting.synced[i] != expected[i] {
+			t.Fatalf("%s: Expected %v synced, got %v", instanceType, expected, counting.synced)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+// Metadata key selecting additional checksums computed by PutObject,
+// a comma separated list of algorithms.
+const checksumAlgorithmKey = "x-checksum-algorithm"
+
+// newChecksumHash - constructors of the supported checksum algorithms.
+var newChecksumHash = map[string]func() hash.Hash{
+	"crc32":  func() hash.Hash { return crc32.NewIEEE() },
+	"crc32c": func() hash.Hash { return crc32.New(crc32.MakeTable(crc32.Castagnoli)) },
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// PutObjectResult - result of PutObjectWithChecksums, Checksums holds
+// the hex digest of each algorithm requested through
+// checksumAlgorithmKey.
+type PutObjectResult struct {
+	ETag      string
+	Checksums map[string]string
+}
+
+// getChecksumHashers - returns a hasher for each algorithm requested in
+// metadata, nil if none were requested.
+func getChecksumHashers(metadata map[string]string) (map[string]hash.Hash, error) {
+	algorithms := metadata[checksumAlgorithmKey]
+	if algorithms == "" {
+		return nil, nil
+	}
+	hashers := make(map[string]hash.Hash)
+	for _, algorithm := range strings.Split(algorithms, ",") {
+		algorithm = strings.ToLower(strings.TrimSpace(algorithm))
+		newHash, ok := newChecksumHash[algorithm]
+		if !ok {
+			return nil, UnsupportedChecksumAlgorithm{Algorithm: algorithm}
+		}
+		hashers[algorithm] = newHash()
+	}
+	return hashers, nil
+}
+
+// appendChecksumWriters - appends hashers to the writers of the put.
+func appendChecksumWriters(writers []io.Writer, hashers map[string]hash.Hash) []io.Writer {
+	for _, hasher := range hashers {
+		writers = append(writers, hasher)
+	}
+	return writers
+}
+
+// checksumsHex - hex digests of hashers keyed by algorithm.
+func checksumsHex(hashers map[string]hash.Hash) map[string]string {
+	if hashers == nil {
+		return nil
+	}
+	checksums := make(map[string]string, len(hashers))
+	for algorithm, hasher := range hashers {
+		checksums[algorithm] = hex.EncodeToString(hasher.Sum(nil))
+	}
+	return checksums
+}
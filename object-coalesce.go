@@ -0,0 +1,253 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// Objects up to this size are coalesced, the whole object is
+	// buffered in memory while the backend read is in progress.
+	coalesceMaxObjectSize = 8 * 1024 * 1024
+	// Size of each read from the backend.
+	coalesceReadSize = 32 * 1024
+)
+
+// errReadFlightClosed - returned to readers joining a flight whose
+// backend read was abandoned by all its readers.
+var errReadFlightClosed = errors.New("Coalesced read closed by all readers")
+
+// errReadFlightNotShared - ends a flight whose backend read turned out
+// not to be shareable, its readers start reads of their own.
+var errReadFlightNotShared = errors.New("Coalesced read not shared")
+
+// readFlight - a backend read in progress, shared by all concurrent
+// readers of the same object. Data read so far is kept in buf so that
+// each reader consumes it at its own pace, a slow reader never blocks
+// the backend read or the other readers.
+type readFlight struct {
+	mutex   *sync.Mutex
+	cond    *sync.Cond
+	buf     []byte
+	err     error // io.EOF once the backend read completes.
+	readers int
+	// Set once the backend read is opened, readers joining before
+	// wait for it.
+	opened bool
+}
+
+// readCoalescer - coalesces concurrent reads of the same key into a
+// single backend read.
+type readCoalescer struct {
+	mutex   *sync.Mutex
+	flights map[string]*readFlight
+	// Number of reads served by joining an in progress flight.
+	coalesced int64
+}
+
+// newReadCoalescer - initialize a new read coalescer.
+func newReadCoalescer() *readCoalescer {
+	return &readCoalescer{
+		mutex:   &sync.Mutex{},
+		flights: make(map[string]*readFlight),
+	}
+}
+
+// coalescedReads - returns the number of reads which shared a backend
+// read with another reader.
+func (c *readCoalescer) coalescedReads() int64 {
+	return atomic.LoadInt64(&c.coalesced)
+}
+
+// get - returns a reader for key, joins the in progress backend read
+// for the same key if any, otherwise starts a new one with open. Only
+// the reader starting a flight calls open, which reports whether its
+// read may be shared, a read which isn't is returned as is. Waiting
+// for the backend read stops once ctx is done.
+func (c *readCoalescer) get(ctx context.Context, key string, open func() (io.ReadCloser, bool, error)) (io.ReadCloser, error) {
+	for {
+		c.mutex.Lock()
+		f, ok := c.flights[key]
+		if !ok {
+			break
+		}
+		c.mutex.Unlock()
+		if f.join() {
+			atomic.AddInt64(&c.coalesced, 1)
+			return &flightReader{flight: f, ctx: ctx}, nil
+		}
+		// The flight failed, wasn't shared or was abandoned by all
+		// its readers, start a new one in its place.
+		c.mutex.Lock()
+		if c.flights[key] == f {
+			delete(c.flights, key)
+		}
+		c.mutex.Unlock()
+	}
+	f := &readFlight{mutex: &sync.Mutex{}, readers: 1}
+	f.cond = sync.NewCond(f.mutex)
+	c.flights[key] = f
+	c.mutex.Unlock()
+
+	src, shared, err := open()
+	if err != nil {
+		c.finish(key, f, err)
+		return nil, err
+	}
+	if !shared {
+		c.finish(key, f, errReadFlightNotShared)
+		return src, nil
+	}
+	f.mutex.Lock()
+	f.opened = true
+	f.mutex.Unlock()
+	f.cond.Broadcast()
+	go c.fill(key, f, src)
+	return &flightReader{flight: f, ctx: ctx}, nil
+}
+
+// join - waits for the backend read of the flight to be opened and
+// joins it, returns false if it can't be joined.
+func (f *readFlight) join() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for !f.opened && f.err == nil {
+		f.cond.Wait()
+	}
+	if f.err != nil || f.readers == 0 {
+		return false
+	}
+	f.readers++
+	return true
+}
+
+// forget - stops new readers from joining in progress flights of all
+// keys starting with prefix, used when an object is overwritten or
+// deleted.
+func (c *readCoalescer) forget(prefix string) {
+	c.mutex.Lock()
+	for key := range c.flights {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.flights, key)
+		}
+	}
+	c.mutex.Unlock()
+}
+
+// finish - marks the flight as complete and wakes up all its readers.
+func (c *readCoalescer) finish(key string, f *readFlight, err error) {
+	c.mutex.Lock()
+	if c.flights[key] == f {
+		delete(c.flights, key)
+	}
+	c.mutex.Unlock()
+
+	f.mutex.Lock()
+	f.err = err
+	f.mutex.Unlock()
+	f.cond.Broadcast()
+}
+
+// fill - reads src into the flight buffer until EOF, error or until
+// all readers have gone away.
+func (c *readCoalescer) fill(key string, f *readFlight, src io.ReadCloser) {
+	defer src.Close()
+	buf := make([]byte, coalesceReadSize)
+	for {
+		f.mutex.Lock()
+		readers := f.readers
+		f.mutex.Unlock()
+		if readers == 0 {
+			c.finish(key, f, errReadFlightClosed)
+			return
+		}
+		n, err := src.Read(buf)
+		if n > 0 {
+			f.mutex.Lock()
+			f.buf = append(f.buf, buf[:n]...)
+			f.mutex.Unlock()
+			f.cond.Broadcast()
+		}
+		if err != nil {
+			c.finish(key, f, err)
+			return
+		}
+	}
+}
+
+// flightReader - reader of a coalesced flight, keeps its own offset.
+type flightReader struct {
+	flight *readFlight
+	ctx    context.Context
+	offset int
+	closed bool
+}
+
+// Read - implements io.Reader, waits for the backend read to catch up
+// if this reader is ahead of it, until ctx is done.
+func (r *flightReader) Read(p []byte) (int, error) {
+	f := r.flight
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if r.offset >= len(f.buf) && f.err == nil && r.ctx.Done() != nil {
+		// Wake up the wait below once ctx is done.
+		waiting := make(chan struct{})
+		defer close(waiting)
+		go func() {
+			select {
+			case <-r.ctx.Done():
+				// Taking the mutex makes sure the wakeup isn't
+				// lost before the reader waits.
+				f.mutex.Lock()
+				f.mutex.Unlock()
+				f.cond.Broadcast()
+			case <-waiting:
+			}
+		}()
+	}
+	for r.offset >= len(f.buf) && f.err == nil {
+		if err := r.ctx.Err(); err != nil {
+			return 0, err
+		}
+		f.cond.Wait()
+	}
+	if r.offset < len(f.buf) {
+		n := copy(p, f.buf[r.offset:])
+		r.offset += n
+		return n, nil
+	}
+	return 0, f.err
+}
+
+// Close - detaches this reader, the backend read is abandoned once
+// all readers are closed.
+func (r *flightReader) Close() error {
+	f := r.flight
+	f.mutex.Lock()
+	if !r.closed {
+		r.closed = true
+		f.readers--
+	}
+	f.mutex.Unlock()
+	return nil
+}
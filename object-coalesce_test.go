@@ -0,0 +1,291 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Tests validate that concurrent reads of the same key share a single
+// backend read and that closing one reader doesn't affect the others.
+func TestReadCoalescer(t *testing.T) {
+	c := newReadCoalescer()
+	data := bytes.Repeat([]byte("abcdefgh"), 16*1024)
+
+	opens := 0
+	pipeReader, pipeWriter := io.Pipe()
+	open := func() (io.ReadCloser, bool, error) {
+		opens++
+		return pipeReader, true, nil
+	}
+
+	r1, err := c.get(context.Background(), "bucket/object@0", open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := c.get(context.Background(), "bucket/object@0", open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r3, err := c.get(context.Background(), "bucket/object@0", open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opens != 1 {
+		t.Fatalf("Expected a single backend read, got %d", opens)
+	}
+	if c.coalescedReads() != 2 {
+		t.Fatalf("Expected 2 coalesced reads, got %d", c.coalescedReads())
+	}
+
+	// A reader going away early must not break the others.
+	r3.Close()
+
+	go func() {
+		pipeWriter.Write(data)
+		pipeWriter.Close()
+	}()
+	for i, r := range []io.ReadCloser{r1, r2} {
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("Reader %d failed with %s", i+1, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("Reader %d content mismatch", i+1)
+		}
+		r.Close()
+	}
+
+	// Completed flights are not joined, a new backend read is started.
+	pipeReader, pipeWriter = io.Pipe()
+	r4, err := c.get(context.Background(), "bucket/object@0", open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opens != 2 {
+		t.Fatalf("Expected a new backend read, got %d", opens)
+	}
+	r4.Close()
+	pipeWriter.Close()
+}
+
+// Tests validate readers arriving while the backend read is being
+// opened wait for it instead of opening reads of their own, and that
+// reads not shared are handed to each reader as they are.
+func TestReadCoalescerOpening(t *testing.T) {
+	c := newReadCoalescer()
+	data := []byte("hello, world")
+
+	var opens int64
+	release := make(chan struct{})
+	open := func() (io.ReadCloser, bool, error) {
+		atomic.AddInt64(&opens, 1)
+		<-release
+		return ioutil.NopCloser(bytes.NewReader(data)), true, nil
+	}
+	leader := make(chan io.ReadCloser)
+	go func() {
+		r, err := c.get(context.Background(), "bucket/object@0", open)
+		if err != nil {
+			t.Error(err)
+		}
+		leader <- r
+	}()
+	// Wait for the leader to be opening.
+	for atomic.LoadInt64(&opens) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	joined := make(chan io.ReadCloser)
+	go func() {
+		r, err := c.get(context.Background(), "bucket/object@0", open)
+		if err != nil {
+			t.Error(err)
+		}
+		joined <- r
+	}()
+	select {
+	case <-joined:
+		t.Fatal("Expected the reader to wait for the backend read to be opened")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	for i, r := range []io.ReadCloser{<-leader, <-joined} {
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("Reader %d failed with %s", i+1, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("Reader %d content mismatch", i+1)
+		}
+		r.Close()
+	}
+	if opens != 1 {
+		t.Fatalf("Expected a single backend read, got %d", opens)
+	}
+
+	// Reads not shared are opened by every reader.
+	opens = 0
+	unshared := func() (io.ReadCloser, bool, error) {
+		atomic.AddInt64(&opens, 1)
+		return ioutil.NopCloser(bytes.NewReader(data)), false, nil
+	}
+	for i := 0; i < 2; i++ {
+		r, err := c.get(context.Background(), "bucket/large@0", unshared)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := r.(*flightReader); ok {
+			t.Fatalf("Expected the read not to be shared")
+		}
+		r.Close()
+	}
+	if opens != 2 {
+		t.Fatalf("Expected 2 backend reads, got %d", opens)
+	}
+}
+
+// Tests validate a reader waiting for the backend read to catch up
+// returns once its context is cancelled.
+func TestReadCoalescerCancel(t *testing.T) {
+	c := newReadCoalescer()
+	pipeReader, pipeWriter := io.Pipe()
+	defer pipeWriter.Close()
+	open := func() (io.ReadCloser, bool, error) {
+		return pipeReader, true, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r, err := c.get(ctx, "bucket/object@0", open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	done := make(chan error)
+	go func() {
+		_, err := r.Read(make([]byte, 1))
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case err = <-done:
+		if err != context.Canceled {
+			t.Fatalf("Expected %s, got %v", context.Canceled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the read to return once cancelled")
+	}
+}
+
+// lookupCountingStorage - counts stats and reads of files.
+type lookupCountingStorage struct {
+	StorageAPI
+	count *int64
+}
+
+func (s lookupCountingStorage) StatFile(volume, path string) (FileInfo, error) {
+	atomic.AddInt64(s.count, 1)
+	return s.StorageAPI.StatFile(volume, path)
+}
+
+func (s lookupCountingStorage) ReadFile(volume, path string, offset int64) (io.ReadCloser, error) {
+	atomic.AddInt64(s.count, 1)
+	return s.StorageAPI.ReadFile(volume, path, offset)
+}
+
+// Tests validate coalesced XL reads look the object up only once, as
+// many times as a read which is never coalesced.
+func TestXLGetObjectCoalescedLookups(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+	obj, err := newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = obj.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("Jack and Jill went up the hill / To fetch a pail of water.")
+	if _, err = obj.PutObject("bucket", "simple", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatal(err)
+	}
+	uploadID, err := obj.NewMultipartUpload("bucket", "multipart", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md5Hex, err := obj.PutObjectPart("bucket", "multipart", uploadID, 1, int64(len(data)), bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = obj.CompleteMultipartUpload("bucket", "multipart", uploadID, []completePart{{PartNumber: 1, ETag: md5Hex}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int64
+	xl := obj.(xlObjects)
+	xl.storage = lookupCountingStorage{xl.storage, &count}
+	lookups := func(read func(object string) (io.ReadCloser, error), object string) int64 {
+		start := atomic.LoadInt64(&count)
+		reader, err := read(object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader.Close()
+		if !bytes.Equal(got, data) {
+			t.Fatalf("%s: content mismatch", object)
+		}
+		return atomic.LoadInt64(&count) - start
+	}
+	getObject := func(object string) (io.ReadCloser, error) {
+		return xl.GetObject("bucket", object, 0)
+	}
+	// A range covering the whole object is never coalesced.
+	getObjectRange := func(object string) (io.ReadCloser, error) {
+		return xl.GetObjectRange("bucket", object, 0, int64(len(data)))
+	}
+	for _, object := range []string{"simple", "multipart"} {
+		// Warm up the multipart status cache.
+		lookups(getObject, object)
+		if coalesced, plain := lookups(getObject, object), lookups(getObjectRange, object); coalesced != plain {
+			t.Errorf("%s: Expected %d lookups for a coalesced read, got %d", object, plain, coalesced)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"path"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// caseInsensitiveStorage - optional capability of storage backends
+// reporting whether names differing only in case collide.
+type caseInsensitiveStorage interface {
+	IsCaseInsensitive() bool
+}
+
+// checkKeyCollision - on case-insensitive backends verifies that object
+// doesn't collide with an existing object whose name differs only in
+// case or in unicode normalization, writing it would silently overwrite
+// the existing object. Returns KeyCollision if it does.
+func checkKeyCollision(storage StorageAPI, bucket, object string) error {
+	cs, ok := storage.(caseInsensitiveStorage)
+	if !ok || !cs.IsCaseInsensitive() {
+		return nil
+	}
+	objectDir := path.Dir(object)
+	if objectDir == "." {
+		objectDir = ""
+	}
+	entries, err := storage.ListDir(bucket, objectDir)
+	if err != nil {
+		if err == errFileNotFound {
+			// Parent directory doesn't exist yet, nothing to collide with.
+			return nil
+		}
+		return toObjectErr(err, bucket, object)
+	}
+	objectBase := path.Base(object)
+	// Such backends usually normalize names too, composed and decomposed
+	// forms of the same name collide.
+	foldBase := norm.NFC.String(objectBase)
+	for _, entry := range entries {
+		// XL multipart objects are listed as directories.
+		entry = strings.TrimSuffix(entry, slashSeparator)
+		if entry != objectBase && strings.EqualFold(norm.NFC.String(entry), foldBase) {
+			return KeyCollision{
+				Bucket:   bucket,
+				Object:   object,
+				Existing: path.Join(objectDir, entry),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// caseInsensitiveTestStorage - simulates a case-insensitive backend.
+type caseInsensitiveTestStorage struct {
+	StorageAPI
+}
+
+func (caseInsensitiveTestStorage) IsCaseInsensitive() bool {
+	return true
+}
+
+// Wrapper for calling key collision tests for both XL multiple disks and single node setup.
+func TestObjectKeyCollision(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testObjectKeyCollision)
+}
+
+// Tests validate that objects differing only in case or in unicode
+// normalization are rejected on case-insensitive backends.
+func testObjectKeyCollision(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	// Names differing in case are distinct objects on a case-sensitive backend.
+	if _, err := obj.PutObject(bucket, "Case.txt", 1, bytes.NewReader([]byte("a")), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err := obj.PutObject(bucket, "case.txt", 1, bytes.NewReader([]byte("a")), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	switch l := obj.(type) {
+	case fsObjects:
+		l.storage = caseInsensitiveTestStorage{l.storage}
+		obj = l
+	case xlObjects:
+		l.storage = caseInsensitiveTestStorage{l.storage}
+		obj = l
+	}
+
+	testCases := []struct {
+		object    string
+		collision string
+	}{
+		{"Photo.jpg", ""},
+		// Overwriting the same name is allowed.
+		{"Photo.jpg", ""},
+		{"photo.JPG", "Photo.jpg"},
+		{"PHOTO.jpg", "Photo.jpg"},
+		{"dir/Object", ""},
+		{"dir/object", "dir/Object"},
+		{"dir/other", ""},
+		{"photo.jpeg", ""},
+		// Composed and decomposed forms of the same name.
+		{"caf\u00e9", ""},
+		{"cafe\u0301", "caf\u00e9"},
+		{"CAFE\u0301", "caf\u00e9"},
+		{"dir/r\u00e9sum\u00e9", ""},
+		{"dir/Re\u0301sume\u0301", "dir/r\u00e9sum\u00e9"},
+		{"cafe", ""},
+	}
+	for i, testCase := range testCases {
+		_, err := obj.PutObject(bucket, testCase.object, 1, bytes.NewReader([]byte("a")), nil)
+		if testCase.collision == "" {
+			if err != nil {
+				t.Fatalf("%s: Test %d: Expected to succeed, got %s", instanceType, i+1, err)
+			}
+			continue
+		}
+		collision, ok := err.(KeyCollision)
+		if !ok {
+			t.Fatalf("%s: Test %d: Expected KeyCollision, got %v", instanceType, i+1, err)
+		}
+		if collision.Existing != testCase.collision {
+			t.Fatalf("%s: Test %d: Expected collision with %s, got %s", instanceType, i+1, testCase.collision, collision.Existing)
+		}
+	}
+}
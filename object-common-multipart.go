@@ -18,7 +18,9 @@ package main
 
 import (
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -71,7 +73,7 @@ func createUploadsJSON(storage StorageAPI, bucket, object, uploadID string) erro
 
 // newMultipartUploadCommon - initialize a new multipart, is a common
 // function for both object layers.
-func newMultipartUploadCommon(storage StorageAPI, bucket string, object string) (uploadID string, err error) {
+func newMultipartUploadCommon(storage StorageAPI, bucket string, object string, metadata map[string]string) (uploadID string, err error) {
 	// Verify if bucket name is valid.
 	if !IsValidBucketName(bucket) {
 		return "", BucketNameInvalid{Bucket: bucket}
@@ -85,6 +87,17 @@ func newMultipartUploadCommon(storage StorageAPI, bucket string, object string)
 	if !IsValidObjectName(object) {
 		return "", ObjectNameInvalid{Bucket: bucket, Object: object}
 	}
+	meta, err := readBucketMetadata(storage, bucket)
+	if err != nil {
+		return "", toObjectErr(err, bucket)
+	}
+	// Verify if object name is allowed by the bucket prefixes.
+	if err = checkObjectPrefixAllowed(meta, bucket, object); err != nil {
+		return "", err
+	}
+	if err = checkRetentionMetadata(bucket, object, metadata); err != nil {
+		return "", err
+	}
 
 	// Loops through until successfully generates a new unique upload id.
 	for {
@@ -104,11 +117,20 @@ func newMultipartUploadCommon(storage StorageAPI, bucket string, object string)
 			if err != errFileNotFound {
 				return "", toObjectErr(err, minioMetaBucket, uploadIDPath)
 			}
-			// uploadIDPath doesn't exist, so create empty file to reserve the name
+			// uploadIDPath doesn't exist, so create file to reserve the
+			// name, holding the metadata of the object to create.
 			var w io.WriteCloser
 			if w, err = storage.CreateFile(minioMetaBucket, tempUploadIDPath); err != nil {
 				return "", toObjectErr(err, minioMetaBucket, tempUploadIDPath)
 			}
+			if len(metadata) != 0 {
+				if err = json.NewEncoder(w).Encode(metadata); err != nil {
+					if clErr := safeCloseAndRemove(w); clErr != nil {
+						return "", toObjectErr(clErr, minioMetaBucket, tempUploadIDPath)
+					}
+					return "", toObjectErr(err, minioMetaBucket, tempUploadIDPath)
+				}
+			}
 			// Close the writer.
 			if err = w.Close(); err != nil {
 				if clErr := safeCloseAndRemove(w); clErr != nil {
@@ -130,6 +152,77 @@ func newMultipartUploadCommon(storage StorageAPI, bucket string, object string)
 	}
 }
 
+// readUploadMetadata - reads the object metadata given when the
+// upload was started, saved in its placeholder file.
+func readUploadMetadata(storage StorageAPI, bucket, object, uploadID string) (map[string]string, error) {
+	uploadIDPath := path.Join(mpartMetaPrefix, bucket, object, uploadID, incompleteFile)
+	r, err := storage.ReadFile(minioMetaBucket, uploadIDPath, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	metadata := make(map[string]string)
+	// Uploads started without metadata have an empty placeholder.
+	if err = json.NewDecoder(r).Decode(&metadata); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// partEncryptionFile - name of the file keeping the encryption at rest
+// of the part uploaded as partSuffix, see partChecksumFile.
+func partEncryptionFile(partSuffix string) string {
+	return "00000." + partSuffix + ".encryption"
+}
+
+// savePartEncryption - saves the metadata of the encryption at rest of
+// an uploaded part.
+func savePartEncryption(storage StorageAPI, bucket, object, uploadID, partSuffix string, encryption map[string]string) error {
+	data, err := json.Marshal(encryption)
+	if err != nil {
+		return err
+	}
+	return writeSidecarFile(storage, path.Join(mpartMetaPrefix, bucket, object, uploadID, partEncryptionFile(partSuffix)), data)
+}
+
+// statUploadedPart - returns the info of the part uploaded as
+// partSuffix, with the size of the data uploaded, along with the
+// metadata of its encryption at rest, nil if stored in cleartext.
+func statUploadedPart(storage StorageAPI, bucket, object, uploadID, partSuffix string) (FileInfo, map[string]string, error) {
+	fi, err := storage.StatFile(minioMetaBucket, path.Join(mpartMetaPrefix, bucket, object, uploadID, partSuffix))
+	if err != nil {
+		return FileInfo{}, nil, err
+	}
+	r, err := storage.ReadFile(minioMetaBucket, path.Join(mpartMetaPrefix, bucket, object, uploadID, partEncryptionFile(partSuffix)), 0)
+	if err != nil {
+		if err == errFileNotFound {
+			return fi, nil, nil
+		}
+		return FileInfo{}, nil, err
+	}
+	defer r.Close()
+	var encryption map[string]string
+	if err = json.NewDecoder(r).Decode(&encryption); err != nil {
+		return FileInfo{}, nil, err
+	}
+	if fi.Size, err = decryptedSize(fi.Size); err != nil {
+		return FileInfo{}, nil, err
+	}
+	return fi, encryption, nil
+}
+
+// openUploadedPart - opens the data uploaded as partSuffix.
+func openUploadedPart(storage StorageAPI, bucket, object, uploadID, partSuffix string) (io.ReadCloser, error) {
+	fi, encryption, err := statUploadedPart(storage, bucket, object, uploadID, partSuffix)
+	if err != nil {
+		return nil, err
+	}
+	part := MultipartPartInfo{Size: fi.Size, Encryption: encryption}
+	return openPart(func(offset int64) (io.ReadCloser, error) {
+		return storage.ReadFile(minioMetaBucket, path.Join(mpartMetaPrefix, bucket, object, uploadID, partSuffix), offset)
+	}, part, 0)
+}
+
 // putObjectPartCommon - put object part.
 func putObjectPartCommon(storage StorageAPI, bucket string, object string, uploadID string, partID int, size int64, data io.Reader, md5Hex string) (string, error) {
 	// Verify if bucket is valid.
@@ -147,7 +240,12 @@ func putObjectPartCommon(storage StorageAPI, bucket string, object string, uploa
 		return "", InvalidUploadID{UploadID: uploadID}
 	}
 
-	partSuffix := fmt.Sprintf("%s.%.5d", uploadID, partID)
+	// Concurrent uploads of the same part are staged apart.
+	tmpID, err := uuid.New()
+	if err != nil {
+		return "", err
+	}
+	partSuffix := fmt.Sprintf("%s.%.5d.%s", uploadID, partID, tmpID.String())
 	partSuffixPath := path.Join(tmpMetaPrefix, bucket, object, partSuffix)
 	fileWriter, err := storage.CreateFile(minioMetaBucket, partSuffixPath)
 	if err != nil {
@@ -157,10 +255,24 @@ func putObjectPartCommon(storage StorageAPI, bucket string, object string, uploa
 	// Initialize md5 writer.
 	md5Writer := md5.New()
 
+	// Checksum kept with the part to detect bit rot on reads.
+	sha256Writer := sha256.New()
+
+	// The part is stored encrypted at rest, hashes are of the data
+	// uploaded.
+	atRest, err := newPartAtRestWriter(fileWriter)
+	if err != nil {
+		if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
+			return "", toObjectErr(clErr, bucket, object)
+		}
+		return "", toObjectErr(err, bucket, object)
+	}
+
 	// Instantiate a new multi writer.
-	multiWriter := io.MultiWriter(md5Writer, fileWriter)
+	multiWriter := io.MultiWriter(md5Writer, sha256Writer, atRest)
 
 	// Instantiate checksum hashers and create a multiwriter.
+	n := size
 	if size > 0 {
 		if _, err = io.CopyN(multiWriter, data, size); err != nil {
 			if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
@@ -178,7 +290,7 @@ func putObjectPartCommon(storage StorageAPI, bucket string, object string, uploa
 			return "", UnExpectedDataSize{Size: int(size)}
 		}
 	} else {
-		if _, err = io.Copy(multiWriter, data); err != nil {
+		if n, err = io.Copy(multiWriter, data); err != nil {
 			if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
 				return "", toObjectErr(clErr, bucket, object)
 			}
@@ -195,6 +307,13 @@ func putObjectPartCommon(storage StorageAPI, bucket string, object string, uploa
 			return "", BadDigest{md5Hex, newMD5Hex}
 		}
 	}
+	encryption, err := atRest.finish(nil, n)
+	if err != nil {
+		if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
+			return "", toObjectErr(clErr, bucket, object)
+		}
+		return "", toObjectErr(err, bucket, object)
+	}
 	err = fileWriter.Close()
 	if err != nil {
 		if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
@@ -205,6 +324,22 @@ func putObjectPartCommon(storage StorageAPI, bucket string, object string, uploa
 
 	partSuffixMD5 := fmt.Sprintf("%.5d.%s", partID, newMD5Hex)
 	partSuffixMD5Path := path.Join(mpartMetaPrefix, bucket, object, uploadID, partSuffixMD5)
+	// Saved ahead of the part, a completed upload never misses it.
+	checksum := hex.EncodeToString(sha256Writer.Sum(nil))
+	if err = savePartChecksum(storage, bucket, object, uploadID, partSuffixMD5, checksum); err != nil {
+		if derr := storage.DeleteFile(minioMetaBucket, partSuffixPath); derr != nil {
+			return "", toObjectErr(derr, minioMetaBucket, partSuffixPath)
+		}
+		return "", toObjectErr(err, bucket, object)
+	}
+	if encryption != nil {
+		if err = savePartEncryption(storage, bucket, object, uploadID, partSuffixMD5, encryption); err != nil {
+			if derr := storage.DeleteFile(minioMetaBucket, partSuffixPath); derr != nil {
+				return "", toObjectErr(derr, minioMetaBucket, partSuffixPath)
+			}
+			return "", toObjectErr(err, bucket, object)
+		}
+	}
 	err = storage.RenameFile(minioMetaBucket, partSuffixPath, minioMetaBucket, partSuffixMD5Path)
 	if err != nil {
 		if derr := storage.DeleteFile(minioMetaBucket, partSuffixPath); derr != nil {
@@ -238,135 +373,123 @@ func abortMultipartUploadCommon(storage StorageAPI, bucket, object, uploadID str
 	if !isUploadIDExists(storage, bucket, object, uploadID) {
 		return InvalidUploadID{UploadID: uploadID}
 	}
-	return cleanupUploadedParts(storage, bucket, object, uploadID)
+	if err := cleanupUploadedParts(storage, bucket, object, uploadID); err != nil {
+		return err
+	}
+	return cleanupTempUploadFiles(storage, bucket, object, uploadID)
 }
 
-// isIncompleteMultipart - is object incomplete multipart.
-func isIncompleteMultipart(storage StorageAPI, objectPath string) (bool, error) {
-	_, err := storage.StatFile(minioMetaBucket, path.Join(objectPath, uploadsJSONFile))
+// cleanupTempUploadFiles - removes files of an upload left under
+// tmpMetaPrefix, parts still being uploaded and placeholders.
+func cleanupTempUploadFiles(storage StorageAPI, bucket, object, uploadID string) error {
+	tmpPath := path.Join(tmpMetaPrefix, bucket, object)
+	entries, err := storage.ListDir(minioMetaBucket, tmpPath)
 	if err != nil {
 		if err == errFileNotFound {
-			return false, nil
+			return nil
 		}
-		return false, err
+		return toObjectErr(err, minioMetaBucket, tmpPath)
 	}
-	return true, nil
+	for _, entry := range entries {
+		if entry == uploadID+slashSeparator {
+			if err = cleanupDir(storage, minioMetaBucket, path.Join(tmpPath, entry)); err != nil {
+				return toObjectErr(err, minioMetaBucket, path.Join(tmpPath, entry))
+			}
+			continue
+		}
+		if !strings.HasPrefix(entry, uploadID+".") {
+			continue
+		}
+		if err = storage.DeleteFile(minioMetaBucket, path.Join(tmpPath, entry)); err != nil && err != errFileNotFound {
+			return toObjectErr(err, minioMetaBucket, path.Join(tmpPath, entry))
+		}
+	}
+	return nil
 }
 
-// listLeafEntries - lists all entries if a given prefixPath is a leaf
-// directory, returns error if any - returns empty list if prefixPath
-// is not a leaf directory.
-func listLeafEntries(storage StorageAPI, prefixPath string) (entries []string, err error) {
-	var ok bool
-	if ok, err = isIncompleteMultipart(storage, prefixPath); err != nil {
-		return nil, err
-	} else if !ok {
-		return nil, nil
-	}
-	entries, err = storage.ListDir(minioMetaBucket, prefixPath)
+// listKeyUploads - collects the uploads of every key at or below
+// keyPrefix, a key is a directory holding uploadsJSONFile and each of
+// its uploads a sub-directory named by upload ID holding incompleteFile.
+// Other directories are descended into for keys nested under them.
+func listKeyUploads(storage StorageAPI, bucket, keyPrefix string) ([]uploadMetadata, error) {
+	entries, err := storage.ListDir(minioMetaBucket, pathJoin(mpartMetaPrefix, pathJoin(bucket, keyPrefix)))
 	if err != nil {
+		if err == errFileNotFound {
+			return nil, nil
+		}
 		return nil, err
 	}
-	var newEntries []string
+	var uploads []uploadMetadata
 	for _, entry := range entries {
-		if strings.HasSuffix(entry, slashSeparator) {
-			newEntries = append(newEntries, entry)
+		if !strings.HasSuffix(entry, slashSeparator) {
+			continue
 		}
+		entryPath := keyPrefix + entry
+		if _, err = uuid.Parse(strings.TrimSuffix(entry, slashSeparator)); err == nil && keyPrefix != "" {
+			// Uploads live directly under the directory of their key.
+			uploadPath := pathJoin(mpartMetaPrefix, pathJoin(bucket, path.Join(entryPath, incompleteFile)))
+			fi, err := storage.StatFile(minioMetaBucket, uploadPath)
+			if err == nil {
+				uploads = append(uploads, uploadMetadata{
+					Object:    strings.TrimSuffix(keyPrefix, slashSeparator),
+					UploadID:  strings.TrimSuffix(entry, slashSeparator),
+					Initiated: fi.ModTime,
+				})
+				continue
+			}
+			if err != errFileNotFound {
+				return nil, err
+			}
+		}
+		nested, err := listKeyUploads(storage, bucket, entryPath)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, nested...)
 	}
-	return newEntries, nil
+	return uploads, nil
 }
 
-// listMetaBucketMultipartFiles - list all files at a given prefix inside minioMetaBucket.
-func listMetaBucketMultipartFiles(layer ObjectLayer, prefixPath string, markerPath string, recursive bool, maxKeys int) (fileInfos []FileInfo, eof bool, err error) {
-	var storage StorageAPI
-	switch l := layer.(type) {
-	case fsObjects:
-		storage = l.storage
-	case xlObjects:
-		storage = l.storage
-	}
+// byKeyInitiated - sorts uploads by key, uploads of the same key in
+// the order they were initiated, as S3 lists them.
+type byKeyInitiated []uploadMetadata
 
-	walker := lookupTreeWalk(layer, listParams{minioMetaBucket, recursive, markerPath, prefixPath})
-	if walker == nil {
-		walker = startTreeWalk(layer, minioMetaBucket, prefixPath, markerPath, recursive)
+func (u byKeyInitiated) Len() int      { return len(u) }
+func (u byKeyInitiated) Swap(i, j int) { u[i], u[j] = u[j], u[i] }
+func (u byKeyInitiated) Less(i, j int) bool {
+	if u[i].Object != u[j].Object {
+		return u[i].Object < u[j].Object
 	}
+	if !u[i].Initiated.Equal(u[j].Initiated) {
+		return u[i].Initiated.Before(u[j].Initiated)
+	}
+	return u[i].UploadID < u[j].UploadID
+}
 
-	// newMaxKeys tracks the size of entries which are going to be
-	// returned back.
-	var newMaxKeys int
-
-	// Following loop gathers and filters out special files inside
-	// minio meta volume.
-	for {
-		walkResult, ok := <-walker.ch
-		if !ok {
-			// Closed channel.
-			eof = true
-			break
-		}
-		// For any walk error return right away.
-		if walkResult.err != nil {
-			log.WithFields(logrus.Fields{
-				"bucket":    minioMetaBucket,
-				"prefix":    prefixPath,
-				"marker":    markerPath,
-				"recursive": recursive,
-			}).Debugf("Walk resulted in an error %s", walkResult.err)
-			// File not found or Disk not found is a valid case.
-			if walkResult.err == errFileNotFound || walkResult.err == errDiskNotFound {
-				return nil, true, nil
-			}
-			return nil, false, toObjectErr(walkResult.err, minioMetaBucket, prefixPath)
-		}
-		fi := walkResult.fileInfo
-		var entries []string
-		if fi.Mode.IsDir() {
-			// List all the entries if fi.Name is a leaf directory, if
-			// fi.Name is not a leaf directory then the resulting
-			// entries are empty.
-			entries, err = listLeafEntries(storage, fi.Name)
-			if err != nil {
-				log.WithFields(logrus.Fields{
-					"prefixPath": fi.Name,
-				}).Errorf("%s", err)
-				return nil, false, err
-			}
-		}
-		if len(entries) > 0 {
-			// We reach here for non-recursive case and a leaf entry.
-			sort.Strings(entries)
-			for _, entry := range entries {
-				var fileInfo FileInfo
-				incompleteUploadFile := path.Join(fi.Name, entry, incompleteFile)
-				fileInfo, err = storage.StatFile(minioMetaBucket, incompleteUploadFile)
-				if err != nil {
-					return nil, false, err
-				}
-				fileInfo.Name = path.Join(fi.Name, entry)
-				fileInfos = append(fileInfos, fileInfo)
-			}
-		} else {
-			// We reach here for a non-recursive case non-leaf entry
-			// OR recursive case with fi.Name.
-			if !fi.Mode.IsDir() { // Do not skip non-recursive case directory entries.
-				// Validate if 'fi.Name' is incomplete multipart.
-				if !strings.HasSuffix(fi.Name, incompleteFile) {
-					continue
-				}
-				fi.Name = path.Dir(fi.Name)
+// skipToMarker - drops the uploads listed up to and including the
+// position given by keyMarker and uploadIDMarker from sorted uploads.
+// If the upload at uploadIDMarker is gone all uploads of keyMarker are
+// listed again, pages may repeat an upload but never skip one.
+func skipToMarker(uploads []uploadMetadata, keyMarker, uploadIDMarker string) []uploadMetadata {
+	if keyMarker == "" {
+		return uploads
+	}
+	first := sort.Search(len(uploads), func(i int) bool {
+		return uploads[i].Object >= keyMarker
+	})
+	next := first
+	for next < len(uploads) && uploads[next].Object == keyMarker {
+		next++
+	}
+	if uploadIDMarker != "" {
+		for i := first; i < next; i++ {
+			if uploads[i].UploadID == uploadIDMarker {
+				return uploads[i+1:]
 			}
-			fileInfos = append(fileInfos, fi)
-		}
-		newMaxKeys++
-		// If we have reached the maxKeys, it means we have listed
-		// everything that was requested. Return right here.
-		if newMaxKeys == maxKeys {
-			return
 		}
+		return uploads[first:]
 	}
-
-	// Return entries here.
-	return fileInfos, eof, nil
+	return uploads[next:]
 }
 
 // listMultipartUploadsCommon - lists all multipart uploads, common
@@ -421,58 +544,71 @@ func listMultipartUploadsCommon(layer ObjectLayer, bucket, prefix, keyMarker, up
 			}
 		}
 	}
-
-	recursive := true
-	if delimiter == slashSeparator {
-		recursive = false
+	if maxUploads <= 0 || maxUploads > maxUploadsList {
+		maxUploads = maxUploadsList
 	}
 
-	result.IsTruncated = true
+	result.KeyMarker = keyMarker
+	result.UploadIDMarker = uploadIDMarker
+	result.Prefix = prefix
+	result.Delimiter = delimiter
 	result.MaxUploads = maxUploads
 
-	// Not using path.Join() as it strips off the trailing '/'.
-	// Also bucket should always be followed by '/' even if prefix is empty.
-	multipartPrefixPath := pathJoin(mpartMetaPrefix, pathJoin(bucket, prefix))
-	multipartMarkerPath := ""
-	if keyMarker != "" {
-		keyMarkerPath := pathJoin(pathJoin(bucket, keyMarker), uploadIDMarker)
-		multipartMarkerPath = pathJoin(mpartMetaPrefix, keyMarkerPath)
+	// Start from the directory of the prefix, keys in it are filtered
+	// by the remainder of the prefix.
+	keyPrefix := ""
+	if i := strings.LastIndex(prefix, slashSeparator); i >= 0 {
+		keyPrefix = prefix[:i+1]
 	}
-
-	// List all the multipart files at prefixPath, starting with marker keyMarkerPath.
-	fileInfos, eof, err := listMetaBucketMultipartFiles(layer, multipartPrefixPath, multipartMarkerPath, recursive, maxUploads)
+	uploads, err := listKeyUploads(storage, bucket, keyPrefix)
 	if err != nil {
 		log.WithFields(logrus.Fields{
-			"prefixPath": multipartPrefixPath,
-			"markerPath": multipartMarkerPath,
-			"recursive":  recursive,
-			"maxUploads": maxUploads,
-		}).Errorf("listMetaBucketMultipartFiles failed with %s", err)
-		return ListMultipartsInfo{}, err
-	}
-
-	// Loop through all the received files fill in the multiparts result.
-	for _, fi := range fileInfos {
-		var objectName string
-		var uploadID string
-		if fi.Mode.IsDir() {
-			// All directory entries are common prefixes.
-			uploadID = "" // Upload ids are empty for CommonPrefixes.
-			objectName = strings.TrimPrefix(fi.Name, retainSlash(pathJoin(mpartMetaPrefix, bucket)))
-			result.CommonPrefixes = append(result.CommonPrefixes, objectName)
-		} else {
-			uploadID = path.Base(fi.Name)
-			objectName = strings.TrimPrefix(path.Dir(fi.Name), retainSlash(pathJoin(mpartMetaPrefix, bucket)))
-			result.Uploads = append(result.Uploads, uploadMetadata{
-				Object:    objectName,
-				UploadID:  uploadID,
-				Initiated: fi.ModTime,
-			})
-		}
-		result.NextKeyMarker = objectName
-		result.NextUploadIDMarker = uploadID
-	}
-	result.IsTruncated = !eof
+			"bucket": bucket,
+			"prefix": prefix,
+		}).Errorf("listKeyUploads failed with %s", err)
+		return ListMultipartsInfo{}, toObjectErr(err, minioMetaBucket, pathJoin(mpartMetaPrefix, bucket))
+	}
+	sort.Sort(byKeyInitiated(uploads))
+	uploads = skipToMarker(uploads, keyMarker, uploadIDMarker)
+
+	var count int
+	for _, upload := range uploads {
+		if !strings.HasPrefix(upload.Object, prefix) {
+			continue
+		}
+		if delimiter == slashSeparator {
+			// Keys below the prefix are rolled up into one common
+			// prefix per directory.
+			if i := strings.Index(upload.Object[len(prefix):], slashSeparator); i >= 0 {
+				commonPrefix := upload.Object[:len(prefix)+i+1]
+				if commonPrefix == keyMarker {
+					// Already listed on a previous page.
+					continue
+				}
+				n := len(result.CommonPrefixes)
+				if n > 0 && result.CommonPrefixes[n-1] == commonPrefix {
+					continue
+				}
+				if count == maxUploads {
+					result.IsTruncated = true
+					break
+				}
+				result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix)
+				result.NextKeyMarker = commonPrefix
+				result.NextUploadIDMarker = ""
+				count++
+				continue
+			}
+		}
+		if count == maxUploads {
+			result.IsTruncated = true
+			break
+		}
+		result.Uploads = append(result.Uploads, upload)
+		result.NextKeyMarker = upload.Object
+		result.NextUploadIDMarker = upload.UploadID
+		count++
+	}
 	if !result.IsTruncated {
 		result.NextKeyMarker = ""
 		result.NextUploadIDMarker = ""
@@ -508,9 +644,13 @@ func listObjectPartsCommon(storage StorageAPI, bucket, object, uploadID string,
 	}
 	idx := sort.SearchStrings(newEntries, fmt.Sprintf("%.5d.", partNumberMarker+1))
 	newEntries = newEntries[idx:]
-	count := maxParts
+	// Collect one more part than requested to know if the listing is
+	// truncated.
 	for _, entry := range newEntries {
-		fi, err := storage.StatFile(minioMetaBucket, path.Join(mpartMetaPrefix, bucket, object, uploadID, entry))
+		fi, _, err := statUploadedPart(storage, bucket, object, uploadID, entry)
+		if err != nil {
+			return ListPartsInfo{}, err
+		}
 		splitEntry := strings.SplitN(entry, ".", 2)
 		partStr := splitEntry[0]
 		etagStr := splitEntry[1]
@@ -518,24 +658,29 @@ func listObjectPartsCommon(storage StorageAPI, bucket, object, uploadID string,
 		if err != nil {
 			return ListPartsInfo{}, err
 		}
-		result.Parts = append(result.Parts, partInfo{
+		part := partInfo{
 			PartNumber:   partNum,
 			LastModified: fi.ModTime,
 			ETag:         etagStr,
 			Size:         fi.Size,
-		})
-		count--
-		if count == 0 {
+		}
+		// A part uploaded again replaces the earlier upload, entries
+		// of a part are next to each other.
+		if last := len(result.Parts) - 1; last >= 0 && result.Parts[last].PartNumber == partNum {
+			if part.LastModified.After(result.Parts[last].LastModified) {
+				result.Parts[last] = part
+			}
+			continue
+		}
+		if maxParts > 0 && len(result.Parts) == maxParts {
+			// If listed parts are more than maxParts, we set IsTruncated as true.
+			result.IsTruncated = true
+			// Make sure to fill next part number marker if IsTruncated is
+			// true for subsequent listing.
+			result.NextPartNumberMarker = result.Parts[len(result.Parts)-1].PartNumber
 			break
 		}
-	}
-	// If listed entries are more than maxParts, we set IsTruncated as true.
-	if len(newEntries) > len(result.Parts) {
-		result.IsTruncated = true
-		// Make sure to fill next part number marker if IsTruncated is
-		// true for subsequent listing.
-		nextPartNumberMarker := result.Parts[len(result.Parts)-1].PartNumber
-		result.NextPartNumberMarker = nextPartNumberMarker
+		result.Parts = append(result.Parts, part)
 	}
 	result.Bucket = bucket
 	result.Object = object
@@ -544,6 +689,29 @@ func listObjectPartsCommon(storage StorageAPI, bucket, object, uploadID string,
 	return result, nil
 }
 
+// validateCompleteParts - validates the part list completing an upload
+// before any part is looked up. Part numbers must be ascending without
+// duplicates, gaps between them are allowed like on S3. Parts are
+// stored by their ETag, which must be an md5sum to name a part.
+func validateCompleteParts(uploadID string, parts []completePart) error {
+	if len(parts) == 0 {
+		return InvalidPart{}
+	}
+	for i, part := range parts {
+		// Part 0 would be the multipart meta file.
+		if part.PartNumber < 1 {
+			return InvalidPart{PartNumber: part.PartNumber, ETag: part.ETag}
+		}
+		if i > 0 && part.PartNumber <= parts[i-1].PartNumber {
+			return InvalidPartOrder{UploadID: uploadID, PartNumber: part.PartNumber}
+		}
+		if md5Sum, err := hex.DecodeString(part.ETag); err != nil || len(md5Sum) != md5.Size {
+			return InvalidPart{PartNumber: part.PartNumber, ETag: part.ETag}
+		}
+	}
+	return nil
+}
+
 // isUploadIDExists - verify if a given uploadID exists and is valid.
 func isUploadIDExists(storage StorageAPI, bucket, object, uploadID string) bool {
 	uploadIDPath := path.Join(mpartMetaPrefix, bucket, object, uploadID, incompleteFile)
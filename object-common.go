@@ -19,6 +19,7 @@ package main
 import (
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/Sirupsen/logrus"
 )
@@ -43,14 +44,21 @@ func initObjectLayer(storage StorageAPI) error {
 
 // Cleanup a directory recursively.
 func cleanupDir(storage StorageAPI, volume, dirPath string) error {
-	var delFunc func(string) error
-	// Function to delete entries recursively.
-	delFunc = func(entryPath string) error {
+	return walkDirFiles(storage, volume, dirPath, func(entryPath string) error {
+		return storage.DeleteFile(volume, entryPath)
+	})
+}
+
+// walkDirFiles - calls fileFn on every file under a directory
+// recursively, a directory which doesn't exist has no files.
+func walkDirFiles(storage StorageAPI, volume, dirPath string, fileFn func(entryPath string) error) error {
+	var walkFunc func(string) error
+	walkFunc = func(entryPath string) error {
 		if !strings.HasSuffix(entryPath, slashSeparator) {
-			// No trailing "/" means that this is a file which can be deleted.
-			return storage.DeleteFile(volume, entryPath)
+			// No trailing "/" means that this is a file.
+			return fileFn(entryPath)
 		}
-		// If it's a directory, list and call delFunc() for each entry.
+		// If it's a directory, list and call walkFunc() for each entry.
 		entries, err := storage.ListDir(volume, entryPath)
 		if err != nil {
 			if err == errFileNotFound {
@@ -60,14 +68,14 @@ func cleanupDir(storage StorageAPI, volume, dirPath string) error {
 			return err
 		}
 		for _, entry := range entries {
-			err = delFunc(pathJoin(entryPath, entry))
+			err = walkFunc(pathJoin(entryPath, entry))
 			if err != nil {
 				return err
 			}
 		}
 		return nil
 	}
-	return delFunc(retainSlash(pathJoin(dirPath)))
+	return walkFunc(retainSlash(pathJoin(dirPath)))
 }
 
 /// Common object layer functions.
@@ -94,14 +102,36 @@ func getBucketInfo(storage StorageAPI, bucket string) (BucketInfo, error) {
 	if err != nil {
 		return BucketInfo{}, toObjectErr(err, bucket)
 	}
+	meta, err := readBucketMetadata(storage, bucket)
+	if err != nil {
+		return BucketInfo{}, toObjectErr(err, bucket)
+	}
 	return BucketInfo{
 		Name:    bucket,
 		Created: vi.Created,
 		Total:   vi.Total,
 		Free:    vi.Free,
+		Region:  meta.bucketRegion(),
 	}, nil
 }
 
+// bucketExists - reports if a bucket exists without building its
+// BucketInfo, is a common function for both object layers. Fails with
+// InsufficientReadQuorum when too few disks answer to tell either way.
+func bucketExists(storage StorageAPI, bucket string) (bool, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return false, BucketNameInvalid{Bucket: bucket}
+	}
+	if _, err := storage.StatVol(bucket); err != nil {
+		if err == errVolumeNotFound {
+			return false, nil
+		}
+		return false, toObjectErr(err, bucket)
+	}
+	return true, nil
+}
+
 // listBuckets - list all buckets, is a common function for both object layers.
 func listBuckets(storage StorageAPI) ([]BucketInfo, error) {
 	var bucketInfos []BucketInfo
@@ -135,16 +165,35 @@ func deleteBucket(storage StorageAPI, bucket string) error {
 	if err := storage.DeleteVol(bucket); err != nil {
 		return toObjectErr(err, bucket)
 	}
+	// Remove any saved bucket metadata as well.
+	if err := deleteBucketMetadata(storage, bucket); err != nil {
+		return toObjectErr(err, bucket)
+	}
+	if err := deleteBucketUsage(storage, bucket); err != nil {
+		return toObjectErr(err, bucket)
+	}
+	if err := deleteBucketStats(storage, bucket); err != nil {
+		return toObjectErr(err, bucket)
+	}
+	// Sidecars left by objects put without extended attributes.
+	if err := deleteBucketObjectMetadata(storage, bucket); err != nil {
+		return toObjectErr(err, bucket)
+	}
 	return nil
 }
 
-func listObjectsCommon(layer ObjectLayer, bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error) {
+// listObjectsCommon - common function to list objects for both object
+// layers, withMetadata also reads the saved metadata of every object
+// listed to fill in its content type, ETag and tags.
+func listObjectsCommon(layer ObjectLayer, bucket, prefix, marker, delimiter string, maxKeys int, withMetadata bool) (ListObjectsInfo, error) {
 	var storage StorageAPI
+	var types *contentTypes
+	var listLimit *listKeysLimit
 	switch l := layer.(type) {
 	case xlObjects:
-		storage = l.storage
+		storage, types, listLimit = l.storage, l.contentTypes, l.listLimit
 	case fsObjects:
-		storage = l.storage
+		storage, types, listLimit = l.storage, l.contentTypes, l.listLimit
 	}
 
 	// Verify if bucket is valid.
@@ -176,14 +225,9 @@ func listObjectsCommon(layer ObjectLayer, bucket, prefix, marker, delimiter stri
 		}
 	}
 
-	if maxKeys == 0 {
-		return ListObjectsInfo{}, nil
-	}
-
-	// Over flowing count - reset to maxObjectList.
-	if maxKeys < 0 || maxKeys > maxObjectList {
-		maxKeys = maxObjectList
-	}
+	// Unset and over flowing counts list up to the limit, leaving
+	// the listing truncated when there is more.
+	maxKeys = listLimit.clamp(maxKeys)
 
 	// Default is recursive, if delimiter is set then list non recursive.
 	recursive := true
@@ -241,6 +285,11 @@ func listObjectsCommon(layer ObjectLayer, bucket, prefix, marker, delimiter stri
 	}
 
 	result := ListObjectsInfo{IsTruncated: !eof}
+	// A truncated listing continues after the last entry returned,
+	// with or without a delimiter.
+	if !eof {
+		result.NextMarker = nextMarker
+	}
 	for _, fileInfo := range fileInfos {
 		// With delimiter set we fill in NextMarker and Prefixes.
 		if delimiter == slashSeparator {
@@ -250,16 +299,95 @@ func listObjectsCommon(layer ObjectLayer, bucket, prefix, marker, delimiter stri
 				continue
 			}
 		}
-		result.Objects = append(result.Objects, ObjectInfo{
-			Name:    fileInfo.Name,
-			ModTime: fileInfo.ModTime,
-			Size:    fileInfo.Size,
-			IsDir:   false,
-		})
+		objInfo := listedObjectInfo(fileInfo)
+		var err error
+		if withMetadata {
+			objInfo, err = addObjectMetadata(storage, types, bucket, objInfo, fileInfo.MD5Sum)
+		} else {
+			objInfo, err = addObjectSize(storage, bucket, objInfo)
+		}
+		if err != nil {
+			return ListObjectsInfo{}, err
+		}
+		result.Objects = append(result.Objects, objInfo)
 	}
 	return result, nil
 }
 
+// listedObjectInfo - object info of a file found by a tree walk.
+func listedObjectInfo(fileInfo FileInfo) ObjectInfo {
+	return ObjectInfo{
+		Name:    fileInfo.Name,
+		ModTime: fileInfo.ModTime,
+		Size:    fileInfo.Size,
+		IsDir:   false,
+	}
+}
+
+// addObjectSize - replaces the stored size of a listed object by the
+// size of its content, which differs for objects compressed, encrypted
+// or deduplicated at rest.
+func addObjectSize(storage StorageAPI, bucket string, objInfo ObjectInfo) (ObjectInfo, error) {
+	metadata, err := readObjectMetadata(storage, bucket, objInfo.Name)
+	if err != nil {
+		return ObjectInfo{}, toObjectErr(err, bucket, objInfo.Name)
+	}
+	if objInfo.Size, err = objectContentSize(objInfo.Size, metadata); err != nil {
+		return ObjectInfo{}, toObjectErr(err, bucket, objInfo.Name)
+	}
+	return objInfo, nil
+}
+
+// addObjectMetadata - fills in the fields of a listed object which are
+// only known from its saved metadata, md5Sum is the composite ETag of
+// multipart objects if any. Objects deleted since they were listed
+// have no metadata and are returned unchanged.
+func addObjectMetadata(storage StorageAPI, types *contentTypes, bucket string, objInfo ObjectInfo, md5Sum string) (ObjectInfo, error) {
+	metadata, err := readObjectMetadata(storage, bucket, objInfo.Name)
+	if err != nil {
+		return ObjectInfo{}, toObjectErr(err, bucket, objInfo.Name)
+	}
+	// Listed sizes are the stored sizes.
+	if objInfo.Size, err = objectContentSize(objInfo.Size, metadata); err != nil {
+		return ObjectInfo{}, toObjectErr(err, bucket, objInfo.Name)
+	}
+	if md5Sum == "" {
+		md5Sum = metadata[etagKey]
+	}
+	if value, ok := metadata[tagsKey]; ok {
+		if objInfo.Tags, err = parseTags(value); err != nil {
+			return ObjectInfo{}, toObjectErr(err, bucket, objInfo.Name)
+		}
+	}
+	objInfo.MD5Sum = md5Sum
+	objInfo.ContentType = getContentType(objInfo.Name, metadata, types)
+	objInfo.ContentEncoding = metadata[contentEncodingKey]
+	objInfo.ReplicationStatus = metadata[replicationStatusKey]
+	return objInfo, nil
+}
+
+// getPrefixInfo - returns a zero byte directory ObjectInfo if object is
+// only a prefix of other objects, as ListObjects with a delimiter lists
+// it. Returns false if no object is under the prefix.
+func getPrefixInfo(layer ObjectLayer, bucket, object string) (ObjectInfo, bool) {
+	// Stop walking at the first object found.
+	found := false
+	count := 0
+	treeWalk(layer, bucket, object+slashSeparator, "", "", true, nil, func(walkResult treeWalkResult) bool {
+		found = walkResult.err == nil
+		return false
+	}, &count)
+	if !found {
+		return ObjectInfo{}, false
+	}
+	return ObjectInfo{
+		Bucket:      bucket,
+		Name:        object,
+		ContentType: "application/octet-stream",
+		IsDir:       true,
+	}, true
+}
+
 // checks whether bucket exists.
 func isBucketExist(storage StorageAPI, bucketName string) bool {
 	// Check whether bucket exists.
@@ -273,3 +401,94 @@ func isBucketExist(storage StorageAPI, bucketName string) bool {
 	}
 	return true
 }
+
+// Maximum number of objects deleted in parallel by DeleteObjects.
+var deleteObjectsConcurrency = 16
+
+// deleteObjects - deletes objects with deleteObject on a bounded number
+// of routines, common function for both object layers. Errors are
+// returned by position, objects not found are already deleted. Returns
+// the storage paths removed, or only resolved for a dryRun, in the
+// order of the objects.
+func deleteObjects(bucket string, objects []string, dryRun bool, deleteObject func(bucket, object string, dryRun bool) ([]string, error)) ([]string, []error) {
+	paths := make([][]string, len(objects))
+	errs := make([]error, len(objects))
+	workers := deleteObjectsConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(objects) {
+		workers = len(objects)
+	}
+	indices := make(chan int)
+	var wg = &sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indices {
+				objectPaths, err := deleteObject(bucket, objects[index], dryRun)
+				if _, ok := err.(ObjectNotFound); ok {
+					err = nil
+				}
+				paths[index], errs[index] = objectPaths, err
+			}
+		}()
+	}
+	for index := range objects {
+		indices <- index
+	}
+	close(indices)
+	wg.Wait()
+	var allPaths []string
+	for _, objectPaths := range paths {
+		allPaths = append(allPaths, objectPaths...)
+	}
+	return allPaths, errs
+}
+
+// Maximum number of objects GetObjectsInfo resolves in parallel.
+var getObjectsInfoConcurrency = 16
+
+// getObjectsInfo - gets info of objects with getObjectInfo on a bounded
+// number of routines, common function for both object layers. Infos
+// and errors are returned by position, an object failing leaves an
+// empty info in its place.
+func getObjectsInfo(bucket string, objects []string, getObjectInfo func(bucket, object string) (ObjectInfo, error)) ([]ObjectInfo, []error) {
+	infos := make([]ObjectInfo, len(objects))
+	errs := make([]error, len(objects))
+	workers := getObjectsInfoConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(objects) {
+		workers = len(objects)
+	}
+	indices := make(chan int)
+	var wg = &sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indices {
+				infos[index], errs[index] = getObjectInfo(bucket, objects[index])
+			}
+		}()
+	}
+	for index := range objects {
+		indices <- index
+	}
+	close(indices)
+	wg.Wait()
+	return infos, errs
+}
+
+// bucketObjectsInfoErr - fails every object of a GetObjectsInfo with
+// the error of their bucket.
+func bucketObjectsInfoErr(objects []string, err error) ([]ObjectInfo, []error) {
+	errs := make([]error, len(objects))
+	for index := range errs {
+		errs[index] = err
+	}
+	return make([]ObjectInfo, len(objects)), errs
+}
@@ -0,0 +1,281 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Uncompressed size of each independently compressed block.
+	compressBlockSize = 1024 * 1024
+	// Object metadata key holding the block boundaries of a
+	// compressed object.
+	compressBlocksKey = "x-minio-compression-blocks"
+	// PutObject metadata key requesting compression at rest with
+	// "true", or turning it off for a compressible content type with
+	// "false". Never saved, and ignored for multipart uploads.
+	compressKey = "x-minio-compress"
+	// Object metadata keys of the codec and the uncompressed size of
+	// an object compressed at rest.
+	compressionKey = "x-minio-compression"
+	actualSizeKey  = "x-minio-actual-size"
+	// Codec of objects compressed at rest.
+	compressionGzip = "gzip"
+)
+
+// compressibleContentTypes - content types compressed at rest unless
+// turned off per object, a type ending in "/*" matches all its
+// subtypes, e.g. "text/*". None by default.
+var compressibleContentTypes []string
+
+// compressBlock - boundary of a compressed block, each block is a
+// complete gzip member so decompression can start at any block.
+type compressBlock struct {
+	// Offset - offset of the block in the uncompressed content.
+	Offset int64 `json:"offset"`
+	// CompressedOffset - offset of the block in the stored data.
+	CompressedOffset int64 `json:"compressedOffset"`
+}
+
+// compressWriter - compresses data written to it into blocks of
+// compressBlockSize, recording the boundaries of each block.
+type compressWriter struct {
+	writer  io.Writer
+	buf     []byte
+	written int64 // Compressed bytes written.
+	offset  int64 // Uncompressed bytes flushed.
+	blocks  []compressBlock
+}
+
+// newCompressWriter - initialize a new compress writer on top of writer.
+func newCompressWriter(writer io.Writer) *compressWriter {
+	return &compressWriter{writer: writer}
+}
+
+// Write - implements io.Writer, compresses every full block.
+func (w *compressWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		space := compressBlockSize - len(w.buf)
+		if space > len(p) {
+			space = len(p)
+		}
+		w.buf = append(w.buf, p[:space]...)
+		p = p[space:]
+		if len(w.buf) == compressBlockSize {
+			if err := w.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// flush - compresses the buffered data as a single gzip member.
+func (w *compressWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var compressed bytes.Buffer
+	gw := gzip.NewWriter(&compressed)
+	if _, err := gw.Write(w.buf); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	w.blocks = append(w.blocks, compressBlock{Offset: w.offset, CompressedOffset: w.written})
+	n, err := w.writer.Write(compressed.Bytes())
+	if err != nil {
+		return err
+	}
+	w.written += int64(n)
+	w.offset += int64(len(w.buf))
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Close - compresses the last partial block, doesn't close the
+// underlying writer.
+func (w *compressWriter) Close() error {
+	return w.flush()
+}
+
+// Blocks - returns the block boundaries, valid after Close.
+func (w *compressWriter) Blocks() []compressBlock {
+	return w.blocks
+}
+
+// encodeCompressBlocks - encodes block boundaries for object metadata.
+func encodeCompressBlocks(blocks []compressBlock) (string, error) {
+	data, err := json.Marshal(blocks)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// decodeCompressBlocks - decodes block boundaries saved in object
+// metadata, returns nil if the object has none.
+func decodeCompressBlocks(metadata map[string]string) ([]compressBlock, error) {
+	value, ok := metadata[compressBlocksKey]
+	if !ok {
+		return nil, nil
+	}
+	var blocks []compressBlock
+	if err := json.Unmarshal([]byte(value), &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// decompressReader - decompresses stored data, closing the stored data
+// reader on Close.
+type decompressReader struct {
+	*gzip.Reader
+	stored io.ReadCloser
+}
+
+// Close - closes both the decompressor and the stored data reader.
+func (r decompressReader) Close() error {
+	r.Reader.Close()
+	return r.stored.Close()
+}
+
+// newDecompressRangeReader - returns a reader of the uncompressed
+// content starting at startOffset. open returns the stored compressed
+// data starting at the given compressed offset. With block boundaries
+// decompression starts at the nearest block before startOffset,
+// otherwise at the beginning of the object. The prefix up to
+// startOffset is discarded.
+func newDecompressRangeReader(open func(offset int64) (io.ReadCloser, error), blocks []compressBlock, startOffset int64) (io.ReadCloser, error) {
+	var block compressBlock
+	if len(blocks) > 0 {
+		// Last block starting at or before startOffset.
+		index := sort.Search(len(blocks), func(i int) bool {
+			return blocks[i].Offset > startOffset
+		}) - 1
+		if index >= 0 {
+			block = blocks[index]
+		}
+	}
+	stored, err := open(block.CompressedOffset)
+	if err != nil {
+		return nil, err
+	}
+	gr, err := gzip.NewReader(stored)
+	if err != nil {
+		stored.Close()
+		return nil, err
+	}
+	if _, err = io.CopyN(ioutil.Discard, gr, startOffset-block.Offset); err != nil {
+		gr.Close()
+		stored.Close()
+		return nil, err
+	}
+	return decompressReader{Reader: gr, stored: stored}, nil
+}
+
+// isCompressible - reports if an object being put is to be compressed
+// at rest, as requested in metadata or for its content type. Data the
+// client encoded already is stored as is. Compression applies to
+// PutObject only, the parts of multipart objects are never compressed.
+func isCompressible(object string, metadata map[string]string) bool {
+	if metadata[contentEncodingKey] != "" {
+		return false
+	}
+	if value, ok := metadata[compressKey]; ok {
+		compress, err := strconv.ParseBool(value)
+		return err == nil && compress
+	}
+	contentType := getContentType(object, metadata, nil)
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	for _, compressible := range compressibleContentTypes {
+		compressible = strings.ToLower(compressible)
+		if contentType == compressible {
+			return true
+		}
+		if strings.HasSuffix(compressible, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(compressible, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// withCompression - returns a copy of metadata recording the codec,
+// the uncompressed size and the block boundaries of a compressed
+// object.
+func withCompression(metadata map[string]string, actualSize int64, blocks []compressBlock) (map[string]string, error) {
+	encodedBlocks, err := encodeCompressBlocks(blocks)
+	if err != nil {
+		return nil, err
+	}
+	objMetadata := make(map[string]string, len(metadata)+3)
+	for k, v := range metadata {
+		objMetadata[k] = v
+	}
+	objMetadata[compressionKey] = compressionGzip
+	objMetadata[actualSizeKey] = strconv.FormatInt(actualSize, 10)
+	objMetadata[compressBlocksKey] = encodedBlocks
+	return objMetadata, nil
+}
+
+// objectCompression - how an object compressed at rest is stored.
+type objectCompression struct {
+	actualSize int64
+	blocks     []compressBlock
+}
+
+// getObjectCompression - compression of an object from its metadata,
+// nil if the object is stored as is.
+func getObjectCompression(metadata map[string]string) (*objectCompression, error) {
+	codec, ok := metadata[compressionKey]
+	if !ok {
+		return nil, nil
+	}
+	if codec != compressionGzip {
+		return nil, fmt.Errorf("Unsupported compression %s", codec)
+	}
+	actualSize, err := strconv.ParseInt(metadata[actualSizeKey], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := decodeCompressBlocks(metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &objectCompression{actualSize: actualSize, blocks: blocks}, nil
+}
+
+// open - reader of the uncompressed content starting at startOffset,
+// of the compressed data returned by readStored.
+func (c *objectCompression) open(readStored func(offset int64) (io.ReadCloser, error), startOffset int64) (io.ReadCloser, error) {
+	if startOffset == c.actualSize {
+		// Nothing left to decompress, an empty object has no blocks.
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+	return newDecompressRangeReader(readStored, c.blocks, startOffset)
+}
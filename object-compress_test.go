@@ -0,0 +1,227 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Tests validate range reads from the middle of compressed objects,
+// with and without recorded block boundaries.
+func TestDecompressRangeReader(t *testing.T) {
+	path, err := ioutil.TempDir(os.TempDir(), "minio-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	storage, err := newPosix(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.MakeVol("bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Spans several blocks, the last one partial.
+	var content bytes.Buffer
+	for i := 0; content.Len() < 3*compressBlockSize+1000; i++ {
+		fmt.Fprintf(&content, "line %d of the compressed object\n", i)
+	}
+	data := content.Bytes()
+
+	w, err := storage.CreateFile("bucket", "object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw := newCompressWriter(w)
+	if _, err = cw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err = cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(cw.Blocks()) != 4 {
+		t.Fatalf("Expected 4 blocks, got %d", len(cw.Blocks()))
+	}
+
+	// Block boundaries survive the round trip through object metadata.
+	encoded, err := encodeCompressBlocks(cw.Blocks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, err := decodeCompressBlocks(map[string]string{compressBlocksKey: encoded})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var opened []int64
+	open := func(offset int64) (io.ReadCloser, error) {
+		opened = append(opened, offset)
+		return storage.ReadFile("bucket", "object", offset)
+	}
+
+	testCases := []struct {
+		blocks      []compressBlock
+		startOffset int64
+		length      int64
+		// Compressed offset decompression is expected to start at.
+		openOffset int64
+	}{
+		{blocks, 0, 100, 0},
+		{blocks, 12345, 1000, 0},
+		{blocks, compressBlockSize - 10, 20, blocks[0].CompressedOffset},
+		{blocks, compressBlockSize, 100, blocks[1].CompressedOffset},
+		{blocks, 2*compressBlockSize + 777, 5000, blocks[2].CompressedOffset},
+		{blocks, 3*compressBlockSize + 500, 500, blocks[3].CompressedOffset},
+		// Without block boundaries decompress from the beginning.
+		{nil, 2*compressBlockSize + 777, 5000, 0},
+		{nil, 3*compressBlockSize + 500, 500, 0},
+	}
+	for i, testCase := range testCases {
+		opened = nil
+		reader, err := newDecompressRangeReader(open, testCase.blocks, testCase.startOffset)
+		if err != nil {
+			t.Fatalf("Test %d: %s", i+1, err)
+		}
+		got := make([]byte, testCase.length)
+		_, err = io.ReadFull(reader, got)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("Test %d: %s", i+1, err)
+		}
+		if !bytes.Equal(got, data[testCase.startOffset:testCase.startOffset+testCase.length]) {
+			t.Fatalf("Test %d: Range data mismatch", i+1)
+		}
+		if len(opened) != 1 || opened[0] != testCase.openOffset {
+			t.Fatalf("Test %d: Expected read at compressed offset %d, got %v", i+1, testCase.openOffset, opened)
+		}
+	}
+}
+
+// Wrapper for calling compression at rest tests for both XL multiple disks and single node setup.
+func TestPutObjectCompressed(t *testing.T) {
+	ExecObjectLayerTest(t, testPutObjectCompressed)
+}
+
+// Tests validate objects compressed at rest read back as uploaded, in
+// whole and in ranges, and report their uncompressed size and ETag.
+func testPutObjectCompressed(obj ObjectLayer, instanceType string, t *testing.T) {
+	defer func(types []string) { compressibleContentTypes = types }(compressibleContentTypes)
+	compressibleContentTypes = []string{"text/*"}
+
+	var storage StorageAPI
+	switch l := obj.(type) {
+	case xlObjects:
+		storage = l.storage
+	case fsObjects:
+		storage = l.storage
+	}
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var content bytes.Buffer
+	for i := 0; content.Len() < 2*compressBlockSize+1000; i++ {
+		fmt.Fprintf(&content, "line %d of the log\n", i)
+	}
+	data := content.Bytes()
+
+	testCases := []struct {
+		object     string
+		metadata   map[string]string
+		compressed bool
+	}{
+		// Compressible content type.
+		{"log.txt", map[string]string{"content-type": "text/plain; charset=utf-8"}, true},
+		// Requested explicitly.
+		{"log.bin", map[string]string{compressKey: "true"}, true},
+		// Turned off explicitly.
+		{"raw.txt", map[string]string{"content-type": "text/plain", compressKey: "false"}, false},
+		// Encoded by the client.
+		{"log.txt.gz", map[string]string{"content-type": "text/plain", contentEncodingKey: "gzip"}, false},
+		{"log.bin", nil, false},
+	}
+	for i, testCase := range testCases {
+		md5Sum, err := obj.PutObject(bucket, testCase.object, int64(len(data)), bytes.NewReader(data), testCase.metadata)
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		fi, err := storage.StatFile(bucket, testCase.object)
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		if compressed := fi.Size < int64(len(data)); compressed != testCase.compressed {
+			t.Fatalf("%s: Test %d: Expected compressed %v, stored %d of %d bytes", instanceType, i+1, testCase.compressed, fi.Size, len(data))
+		}
+		objInfo, err := obj.GetObjectInfo(bucket, testCase.object)
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		if objInfo.Size != int64(len(data)) || objInfo.MD5Sum != md5Sum {
+			t.Fatalf("%s: Test %d: Expected size %d and MD5Sum %s, got %d and %s", instanceType, i+1, len(data), md5Sum, objInfo.Size, objInfo.MD5Sum)
+		}
+		// Listings report the uncompressed size too.
+		listInfo, err := obj.ListObjects(bucket, testCase.object, "", "", 1)
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		if len(listInfo.Objects) != 1 || listInfo.Objects[0].Size != int64(len(data)) {
+			t.Fatalf("%s: Test %d: Expected listed size %d, got %v", instanceType, i+1, len(data), listInfo.Objects)
+		}
+		ranges := [][2]int64{{0, -1}, {compressBlockSize - 10, 20}, {int64(len(data)) - 5, -1}, {int64(len(data)), -1}}
+		for _, r := range ranges {
+			reader, err := obj.GetObjectRange(bucket, testCase.object, r[0], r[1])
+			if err != nil {
+				t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+			}
+			got, err := ioutil.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+			}
+			end := int64(len(data))
+			if r[1] >= 0 {
+				end = r[0] + r[1]
+			}
+			if !bytes.Equal(got, data[r[0]:end]) {
+				t.Fatalf("%s: Test %d: Range %d-%d mismatch", instanceType, i+1, r[0], end)
+			}
+		}
+	}
+
+	// Empty objects compress to nothing.
+	if _, err := obj.PutObject(bucket, "empty.txt", 0, bytes.NewReader(nil), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	reader, err := obj.GetObject(bucket, "empty.txt", 0)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	got, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil || len(got) != 0 {
+		t.Fatalf("%s: Expected an empty object, got %d bytes and %v", instanceType, len(got), err)
+	}
+}
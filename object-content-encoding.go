@@ -0,0 +1,93 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Content encoding handling for objects uploaded with a
+// 'Content-Encoding' header. The encoding belongs to the client data,
+// unlike compression-at-rest which is transparent to clients, so the
+// object is served as stored unless the client can't accept it.
+
+// contentEncodingKey - object metadata key of the stored content encoding.
+const contentEncodingKey = "content-encoding"
+
+// isDecodableContentEncoding - content encodings the server can decode.
+func isDecodableContentEncoding(encoding string) bool {
+	switch strings.ToLower(encoding) {
+	case "gzip", "x-gzip":
+		return true
+	}
+	return false
+}
+
+// isContentEncodingAccepted - verifies if encoding is acceptable for
+// the given 'Accept-Encoding' header value.
+func isContentEncodingAccepted(encoding, acceptEncoding string) bool {
+	// No header means any encoding is acceptable.
+	if strings.TrimSpace(acceptEncoding) == "" {
+		return true
+	}
+	encoding = strings.ToLower(encoding)
+	accepted := false
+	for _, coding := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(coding, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		qvalue := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					qvalue = q
+				}
+			}
+		}
+		switch {
+		case name == encoding, name == "x-"+encoding, "x-"+name == encoding:
+			// Explicit entry takes precedence over '*'.
+			return qvalue > 0
+		case name == "*":
+			accepted = qvalue > 0
+		}
+	}
+	return accepted
+}
+
+// needsContentDecoding - returns true if the object stored with the
+// given content encoding must be decoded before serving it to a client
+// sending acceptEncoding. Encodings the server can't decode are always
+// served as stored.
+func needsContentDecoding(storedEncoding, acceptEncoding string) bool {
+	if storedEncoding == "" || strings.EqualFold(storedEncoding, "identity") {
+		return false
+	}
+	if !isDecodableContentEncoding(storedEncoding) {
+		return false
+	}
+	return !isContentEncodingAccepted(storedEncoding, acceptEncoding)
+}
+
+// newContentDecodingReader - returns a reader of the decoded content
+// of the stored reader, which must hold the whole stored content.
+func newContentDecodingReader(stored io.Reader) (io.Reader, error) {
+	return gzip.NewReader(stored)
+}
@@ -0,0 +1,78 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"mime"
+	"path"
+	"strings"
+	"sync"
+)
+
+// contentTypes - content types registered for object extensions on an
+// object layer, these take precedence over mimedb when guessing the
+// content type of an object saved without one.
+type contentTypes struct {
+	mutex *sync.RWMutex
+	types map[string]string
+}
+
+// newContentTypes - initialize content types with no extension
+// registered.
+func newContentTypes() *contentTypes {
+	return &contentTypes{
+		mutex: &sync.RWMutex{},
+		types: make(map[string]string),
+	}
+}
+
+// register - map ext, with or without its leading ".", to contentType
+// regardless of case, an empty contentType removes the mapping.
+func (c *contentTypes) register(ext, contentType string) error {
+	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+	if ext == "" || strings.ContainsAny(ext, "./") {
+		return errInvalidArgument
+	}
+	if contentType != "" {
+		if _, _, err := mime.ParseMediaType(contentType); err != nil {
+			return errInvalidArgument
+		}
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if contentType == "" {
+		delete(c.types, ext)
+		return nil
+	}
+	c.types[ext] = contentType
+	return nil
+}
+
+// lookup - content type registered for the extension of object.
+func (c *contentTypes) lookup(object string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	objectExt := path.Ext(object)
+	if objectExt == "" {
+		return "", false
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	contentType, ok := c.types[strings.ToLower(strings.TrimPrefix(objectExt, "."))]
+	return contentType, ok
+}
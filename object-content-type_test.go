@@ -0,0 +1,112 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Wrapper for calling RegisterContentType tests for both XL multiple disks and single node setup.
+func TestRegisterContentType(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testRegisterContentType)
+}
+
+// Tests validate registered content types take precedence over mimedb
+// regardless of the case of the extension, but not over the content
+// type saved with an object.
+func testRegisterContentType(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte(`{"hello": "world"}`)
+	objects := map[string]map[string]string{
+		"events.ndjson": nil,
+		"data.JSON":     nil,
+		"saved.json":    {"content-type": "text/plain"},
+	}
+	for object, metadata := range objects {
+		if _, err := obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), metadata); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	contentTypes := func(expected map[string]string) {
+		for object, contentType := range expected {
+			objInfo, err := obj.GetObjectInfo(bucket, object)
+			if err != nil {
+				t.Fatalf("%s : %s", instanceType, err.Error())
+			}
+			if objInfo.ContentType != contentType {
+				t.Fatalf("%s: %s: Expected %s, got %s", instanceType, object, contentType, objInfo.ContentType)
+			}
+		}
+		result, err := obj.ListObjectsWithMetadata(bucket, "", "", "", 1000)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		for _, objInfo := range result.Objects {
+			if objInfo.ContentType != expected[objInfo.Name] {
+				t.Fatalf("%s: Listed %s: Expected %s, got %s", instanceType, objInfo.Name, expected[objInfo.Name], objInfo.ContentType)
+			}
+		}
+	}
+	contentTypes(map[string]string{
+		"events.ndjson": "application/octet-stream",
+		"data.JSON":     "application/json",
+		"saved.json":    "text/plain",
+	})
+
+	for _, types := range [][2]string{{"NDJSON", "application/x-ndjson"}, {".json", "application/vnd.internal+json"}} {
+		if err := obj.RegisterContentType(types[0], types[1]); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	contentTypes(map[string]string{
+		"events.ndjson": "application/x-ndjson",
+		"data.JSON":     "application/vnd.internal+json",
+		"saved.json":    "text/plain",
+	})
+
+	// Removing a registered type falls back to mimedb.
+	if err := obj.RegisterContentType("json", ""); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	contentTypes(map[string]string{
+		"events.ndjson": "application/x-ndjson",
+		"data.JSON":     "application/json",
+		"saved.json":    "text/plain",
+	})
+
+	testCases := []struct {
+		ext         string
+		contentType string
+	}{
+		{"", "text/plain"},
+		{".", "text/plain"},
+		{"tar.gz", "application/gzip"},
+		{"dir/txt", "text/plain"},
+		{"txt", "not a media type"},
+	}
+	for i, testCase := range testCases {
+		if err := obj.RegisterContentType(testCase.ext, testCase.contentType); err != errInvalidArgument {
+			t.Fatalf("%s: Test %d: Expected errInvalidArgument, got %v", instanceType, i+1, err)
+		}
+	}
+}
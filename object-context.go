@@ -0,0 +1,63 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"io"
+)
+
+// contextReader - fails reads with the context error once its context
+// is done, so that copy loops stop promptly.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+// newContextReader - returns reader reading until ctx is done.
+func newContextReader(ctx context.Context, reader io.Reader) io.Reader {
+	if ctx.Done() == nil {
+		// Never cancelled.
+		return reader
+	}
+	return &contextReader{ctx, reader}
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+// contextReadCloser - a contextReader closing the underlying reader.
+type contextReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// newContextReadCloser - returns reader reading until ctx is done,
+// closing it closes reader.
+func newContextReadCloser(ctx context.Context, reader io.ReadCloser) io.ReadCloser {
+	if ctx.Done() == nil {
+		return reader
+	}
+	return contextReadCloser{
+		Reader: newContextReader(ctx, reader),
+		Closer: reader,
+	}
+}
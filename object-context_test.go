@@ -0,0 +1,103 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// cancelReader - cancels a context once n bytes were read.
+type cancelReader struct {
+	reader io.Reader
+	n      int
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		r.cancel()
+	}
+	if len(p) > r.n && r.n > 0 {
+		p = p[:r.n]
+	}
+	n, err := r.reader.Read(p)
+	r.n -= n
+	return n, err
+}
+
+// Wrapper for calling context cancellation tests for both XL multiple disks and single node setup.
+func TestObjectContextCancel(t *testing.T) {
+	initNSLock()
+	ExecObjectLayerTest(t, testObjectContextCancel)
+}
+
+// Tests validate uploads and downloads stop once their context is
+// cancelled, without leaving a partial object behind.
+func testObjectContextCancel(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	// Upload cancelled midway.
+	data := bytes.Repeat([]byte("a"), 1024*1024)
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := &cancelReader{reader: bytes.NewReader(data), n: 1024, cancel: cancel}
+	_, err := obj.PutObjectWithContext(ctx, bucket, "object", int64(len(data)), reader, nil)
+	if err != context.Canceled {
+		t.Fatalf("%s: Expected context.Canceled, got %v", instanceType, err)
+	}
+	if _, err = obj.GetObjectInfo(bucket, "object"); err == nil {
+		t.Fatalf("%s: Cancelled upload created the object", instanceType)
+	}
+
+	// Download of a multipart object cancelled after the first part.
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	partSize := 5 * 1024 * 1024
+	var parts []completePart
+	for i := 1; i <= 3; i++ {
+		var md5Sum string
+		md5Sum, err = obj.PutObjectPart(bucket, "multipart", uploadID, i, int64(partSize), bytes.NewReader(bytes.Repeat([]byte("b"), partSize)), "")
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		parts = append(parts, completePart{PartNumber: i, ETag: md5Sum})
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, "multipart", uploadID, parts); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	objReader, err := obj.GetObjectWithContext(ctx, bucket, "multipart", 0)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	defer objReader.Close()
+	if _, err = io.CopyN(ioutil.Discard, objReader, int64(partSize)); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	cancel()
+	if _, err = io.Copy(ioutil.Discard, objReader); err != context.Canceled {
+		t.Fatalf("%s: Expected context.Canceled, got %v", instanceType, err)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "io"
+
+// copyObjectMetadata - metadata of a copy, the source metadata unless
+// metadata replaces it. Keys describing the state of the source object
+// rather than its content aren't copied. The source tags are copied
+// unless metadata sets taggingDirectiveKey to taggingDirectiveReplace.
+func copyObjectMetadata(storage StorageAPI, srcBucket, srcObject string, metadata map[string]string) (map[string]string, error) {
+	srcMetadata, err := readObjectMetadata(storage, srcBucket, srcObject)
+	if err != nil {
+		return nil, toObjectErr(err, srcBucket, srcObject)
+	}
+	if metadata == nil {
+		metadata = srcMetadata
+	}
+	objMetadata := make(map[string]string)
+	for k, v := range metadata {
+		if internalMetadataKeys[k] || protectedMetadataKeys[k] || k == replicationStatusKey {
+			continue
+		}
+		objMetadata[k] = v
+	}
+	if tags, ok := srcMetadata[tagsKey]; ok && metadata[taggingDirectiveKey] != taggingDirectiveReplace {
+		objMetadata[tagsKey] = tags
+	}
+	return objMetadata, nil
+}
+
+// copyStorageFile - copies srcVolume/srcPath to dstVolume/dstPath on
+// the storage, the data never leaves the server.
+func copyStorageFile(storage StorageAPI, srcVolume, srcPath, dstVolume, dstPath string) error {
+	reader, err := storage.ReadFile(srcVolume, srcPath, 0)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	writer, err := storage.CreateFile(dstVolume, dstPath)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(writer, reader); err != nil {
+		if clErr := safeCloseAndRemove(writer); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		if clErr := safeCloseAndRemove(writer); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	return nil
+}
+
+// copyObjectCommon - common function to copy an object on the server
+// for both object layers. A nil metadata keeps the source metadata,
+// copying an object onto itself only replaces its metadata.
+func copyObjectCommon(layer ObjectLayer, srcBucket, srcObject, dstBucket, dstObject string, metadata map[string]string) (ObjectInfo, error) {
+	var storage StorageAPI
+	switch l := layer.(type) {
+	case xlObjects:
+		storage = l.storage
+	case fsObjects:
+		storage = l.storage
+	}
+
+	// Verify if both buckets are valid.
+	if !IsValidBucketName(srcBucket) {
+		return ObjectInfo{}, BucketNameInvalid{Bucket: srcBucket}
+	}
+	if !IsValidBucketName(dstBucket) {
+		return ObjectInfo{}, BucketNameInvalid{Bucket: dstBucket}
+	}
+	if !isBucketExist(storage, srcBucket) {
+		return ObjectInfo{}, BucketNotFound{Bucket: srcBucket}
+	}
+	if !isBucketExist(storage, dstBucket) {
+		return ObjectInfo{}, BucketNotFound{Bucket: dstBucket}
+	}
+	if !IsValidObjectName(srcObject) {
+		return ObjectInfo{}, ObjectNameInvalid{Bucket: srcBucket, Object: srcObject}
+	}
+	if !IsValidObjectName(dstObject) {
+		return ObjectInfo{}, ObjectNameInvalid{Bucket: dstBucket, Object: dstObject}
+	}
+	srcInfo, err := layer.GetObjectInfo(srcBucket, srcObject)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	objMetadata, err := copyObjectMetadata(storage, srcBucket, srcObject, metadata)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	if srcBucket == dstBucket && srcObject == dstObject {
+		if metadata == nil {
+			// Nothing changes.
+			return srcInfo, nil
+		}
+		return replaceObjectMetadata(layer, storage, dstBucket, dstObject, objMetadata)
+	}
+
+	if l, ok := layer.(xlObjects); ok {
+		isMultipart, err := l.isMultipart(srcBucket, srcObject)
+		if err != nil {
+			return ObjectInfo{}, toObjectErr(err, srcBucket, srcObject)
+		}
+		if isMultipart {
+			return l.copyMultipartObject(srcBucket, srcObject, dstBucket, dstObject, objMetadata)
+		}
+	}
+
+	// Read through the object layer, compressed or encrypted data is
+	// transformed again for the copy.
+	reader, err := layer.GetObject(srcBucket, srcObject, 0)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	defer reader.Close()
+	md5Sum, err := layer.PutObject(dstBucket, dstObject, srcInfo.Size, reader, objMetadata)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	dstInfo, err := layer.GetObjectInfo(dstBucket, dstObject)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	dstInfo.MD5Sum = md5Sum
+	return dstInfo, nil
+}
+
+// replaceObjectMetadata - replaces the metadata of an object without
+// rewriting its data, keys only changed through dedicated operations
+// such as the legal hold are kept.
+func replaceObjectMetadata(layer ObjectLayer, storage StorageAPI, bucket, object string, metadata map[string]string) (ObjectInfo, error) {
+	unlock := lockObject(bucket, object)
+	defer unlock()
+
+	oldMetadata, err := readObjectMetadata(storage, bucket, object)
+	if err != nil {
+		return ObjectInfo{}, toObjectErr(err, bucket, object)
+	}
+	for k := range protectedMetadataKeys {
+		// Tags are kept by copyObjectMetadata unless replaced.
+		if k == tagsKey {
+			continue
+		}
+		if v, ok := oldMetadata[k]; ok {
+			metadata[k] = v
+		}
+	}
+	if v, ok := oldMetadata[replicationStatusKey]; ok {
+		metadata[replicationStatusKey] = v
+	}
+	if err = saveObjectMetadata(storage, bucket, object, metadata); err != nil {
+		return ObjectInfo{}, toObjectErr(err, bucket, object)
+	}
+	return layer.GetObjectInfo(bucket, object)
+}
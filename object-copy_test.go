@@ -0,0 +1,120 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+// Wrapper for calling CopyObject tests for both XL multiple disks and single node setup.
+func TestCopyObject(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testCopyObject)
+}
+
+// Tests validate copies of simple and multipart objects across
+// buckets, and metadata only copies of an object onto itself.
+func testCopyObject(obj ObjectLayer, instanceType string, t *testing.T) {
+	for _, bucket := range []string{"src-bucket", "dst-bucket"} {
+		if err := obj.MakeBucket(bucket); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	readObject := func(bucket, object string) []byte {
+		reader, err := obj.GetObject(bucket, object, 0)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		defer reader.Close()
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		return data
+	}
+
+	// Simple object, source metadata is kept.
+	data := []byte("hello, world")
+	md5Sum, err := obj.PutObject("src-bucket", "simple", int64(len(data)), bytes.NewReader(data), map[string]string{"content-type": "text/plain"})
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	objInfo, err := obj.CopyObject("src-bucket", "simple", "dst-bucket", "dir/simple", nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if objInfo.MD5Sum != md5Sum || objInfo.ContentType != "text/plain" {
+		t.Fatalf("%s: Expected MD5Sum %s and text/plain, got %s and %s", instanceType, md5Sum, objInfo.MD5Sum, objInfo.ContentType)
+	}
+	if !bytes.Equal(readObject("dst-bucket", "dir/simple"), data) {
+		t.Fatalf("%s: Copied data mismatch", instanceType)
+	}
+
+	// Multipart object, parts and MD5Sum are carried over.
+	uploadID, err := obj.NewMultipartUpload("src-bucket", "multipart", nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var multipartData []byte
+	var parts []completePart
+	for i, size := range []int{5 * 1024 * 1024, 17} {
+		partData := bytes.Repeat([]byte{byte('a' + i)}, size)
+		var partMD5 string
+		partMD5, err = obj.PutObjectPart("src-bucket", "multipart", uploadID, i+1, int64(size), bytes.NewReader(partData), "")
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		parts = append(parts, completePart{PartNumber: i + 1, ETag: partMD5})
+		multipartData = append(multipartData, partData...)
+	}
+	multipartMD5, err := obj.CompleteMultipartUpload("src-bucket", "multipart", uploadID, parts)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	objInfo, err = obj.CopyObject("src-bucket", "multipart", "dst-bucket", "multipart", map[string]string{"x-amz-meta-copy": "1"})
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, ok := obj.(xlObjects); ok && objInfo.MD5Sum != multipartMD5 {
+		t.Fatalf("%s: Expected MD5Sum %s, got %s", instanceType, multipartMD5, objInfo.MD5Sum)
+	}
+	if !bytes.Equal(readObject("dst-bucket", "multipart"), multipartData) {
+		t.Fatalf("%s: Copied multipart data mismatch", instanceType)
+	}
+
+	// Copy onto itself replaces the metadata only.
+	objInfo, err = obj.CopyObject("dst-bucket", "dir/simple", "dst-bucket", "dir/simple", map[string]string{"content-type": "application/json"})
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if objInfo.ContentType != "application/json" {
+		t.Fatalf("%s: Expected application/json, got %s", instanceType, objInfo.ContentType)
+	}
+	if !bytes.Equal(readObject("dst-bucket", "dir/simple"), data) {
+		t.Fatalf("%s: Data changed by metadata copy", instanceType)
+	}
+
+	// Copying a missing object fails.
+	if _, err = obj.CopyObject("src-bucket", "missing", "dst-bucket", "missing", nil); err == nil {
+		t.Fatalf("%s: Expected ObjectNotFound", instanceType)
+	} else if _, ok := err.(ObjectNotFound); !ok {
+		t.Fatalf("%s: Expected ObjectNotFound, got %v", instanceType, err)
+	}
+}
@@ -24,6 +24,8 @@ type BucketInfo struct {
 	Created time.Time
 	Total   int64
 	Free    int64
+	// Region - location of the bucket, set by GetBucketInfo only.
+	Region string
 }
 
 // ObjectInfo - object info.
@@ -32,9 +34,75 @@ type ObjectInfo struct {
 	Name        string
 	ModTime     time.Time
 	ContentType string
-	MD5Sum      string
-	Size        int64
-	IsDir       bool
+	// ContentEncoding - encoding of the object data as uploaded by
+	// the client, empty if none.
+	ContentEncoding string
+	// ReplicationStatus - status of the replication to the bucket's
+	// replication target, empty if the bucket doesn't replicate.
+	ReplicationStatus string
+	MD5Sum            string
+	Size              int64
+	IsDir             bool
+	// MissingParts - number of files of the object below full
+	// redundancy, only set by ListObjectsHeal.
+	MissingParts int
+	// Parts - parts of a multipart object in order, nil for objects
+	// uploaded in one piece. Only set by GetObjectInfo.
+	Parts []ObjectPartInfo
+	// Tags - tag set of the object, only set by
+	// ListObjectsWithMetadata. Use GetObjectTags otherwise.
+	Tags map[string]string
+	// UserDefined - metadata saved with the object by its uploader,
+	// such as x-amz-meta- keys. Only set by GetObjectInfo.
+	UserDefined map[string]string
+	// VersionID - version of the object, empty for objects put while
+	// their bucket wasn't versioned. Only set by GetObjectInfo.
+	VersionID string
+}
+
+// ObjectVersionInfo - a version of an object listed by
+// ListObjectVersions, either object data or a delete marker.
+type ObjectVersionInfo struct {
+	ObjectInfo
+	// IsLatest - the version is the current object, or the delete
+	// marker of an object deleted last.
+	IsLatest bool
+	// DeleteMarker - the version records a delete of the object and
+	// has no data.
+	DeleteMarker bool
+}
+
+// ListObjectVersionsInfo - container for list object versions.
+type ListObjectVersionsInfo struct {
+	IsTruncated bool
+	// NextKeyMarker and NextVersionIDMarker - markers to continue a
+	// truncated listing with.
+	NextKeyMarker       string
+	NextVersionIDMarker string
+	// Versions - by key, newest version of a key first.
+	Versions []ObjectVersionInfo
+}
+
+// ObjectRangePart - range of an object read by GetObjectRanges, Offset
+// is where the range starts within the data returned.
+type ObjectRangePart struct {
+	Start  int64
+	Length int64
+	Offset int64
+}
+
+// ObjectByteRange - Length bytes of an object starting at Offset.
+type ObjectByteRange struct {
+	Offset int64
+	Length int64
+}
+
+// ObjectPartInfo - part of a multipart object, Offset is where the
+// part starts within the object.
+type ObjectPartInfo struct {
+	Number int
+	Size   int64
+	Offset int64
 }
 
 // ListPartsInfo - various types of object resources.
@@ -75,6 +143,40 @@ type ListObjectsInfo struct {
 	Prefixes    []string
 }
 
+// ListObjectsV2Info - container for list objects with continuation
+// tokens.
+type ListObjectsV2Info struct {
+	IsTruncated bool
+	// ContinuationToken - token the listing was continued from.
+	ContinuationToken string
+	// NextContinuationToken - token to continue with, set if the
+	// listing is truncated.
+	NextContinuationToken string
+	// KeyCount - number of objects and prefixes returned.
+	KeyCount int
+	Objects  []ObjectInfo
+	Prefixes []string
+}
+
+// KeyRange - range of object keys returned by ListObjectShards, listed
+// with ListObjects using Marker until a key reaches End.
+type KeyRange struct {
+	// Marker - marker to start listing the range with, empty for the
+	// first range.
+	Marker string
+	// End - first key past the range, empty for the last range.
+	End string
+}
+
+// MetadataUpdateResult - result of the metadata update of an object
+// by UpdateMetadataPrefix.
+type MetadataUpdateResult struct {
+	Object string
+	// Updated - false if the update function returned no changes.
+	Updated bool
+	Err     error
+}
+
 // partInfo - various types of individual part resources.
 type partInfo struct {
 	PartNumber   int
@@ -97,6 +199,14 @@ type completePart struct {
 	ETag       string
 }
 
+// manifestPart - expected size and md5sum of a part, listed by the
+// client before completing an upload.
+type manifestPart struct {
+	PartNumber int
+	Size       int64
+	MD5Sum     string
+}
+
 // completedParts is a sortable interface for Part slice
 type completedParts []completePart
 
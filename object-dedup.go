@@ -0,0 +1,288 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"path"
+	"strconv"
+)
+
+// Objects put in a bucket with dedup set are stored once per distinct
+// content. The data of the first object with some content becomes a
+// blob inside minioMetaBucket, named after the SHA-256 of the content.
+// Objects with the same content, size and MD5 sum get an empty data
+// file and reference the blob in their metadata instead. Each blob has
+// an index record counting its references, the blob is removed along
+// with the last one. Only content stored as uploaded is deduplicated.
+
+const (
+	// Dedup blobs and their index records, inside minioMetaBucket.
+	dedupPrefix = "dedup"
+	// Locks of dedup blobs and of object references to them, apart
+	// from the object locks and from the locks XL holds on files.
+	dedupLockPrefix = "dedup-locks"
+	// Object metadata keys of an object whose data is a dedup blob,
+	// the SHA-256 sum naming the blob and the size of the content.
+	dedupBlobKey = "x-minio-dedup-blob"
+	dedupSizeKey = "x-minio-dedup-size"
+)
+
+// dedupRecord - index record of a dedup blob.
+type dedupRecord struct {
+	Size   int64  `json:"size"`
+	MD5Sum string `json:"md5Sum"`
+	// Refs - number of objects referencing the blob.
+	Refs int64 `json:"refs"`
+}
+
+// dedupBlobPath - path of a dedup blob inside minioMetaBucket.
+func dedupBlobPath(sum string) string {
+	return path.Join(dedupPrefix, sum)
+}
+
+// dedupRecordPath - path of the index record of a dedup blob.
+func dedupRecordPath(sum string) string {
+	return path.Join(dedupPrefix, sum+".json")
+}
+
+// getObjectDedup - dedup blob holding the data of an object from its
+// metadata and the size of the data, an empty sum if the object holds
+// its own data.
+func getObjectDedup(metadata map[string]string) (sum string, size int64, err error) {
+	sum, ok := metadata[dedupBlobKey]
+	if !ok {
+		return "", 0, nil
+	}
+	if size, err = strconv.ParseInt(metadata[dedupSizeKey], 10, 64); err != nil {
+		return "", 0, err
+	}
+	return sum, size, nil
+}
+
+// openDedupBlob - reads a dedup blob starting at offset.
+func openDedupBlob(storage StorageAPI, sum string, offset int64) (io.ReadCloser, error) {
+	return storage.ReadFile(minioMetaBucket, dedupBlobPath(sum), offset)
+}
+
+// lockDedupBlob - serializes changes of a dedup blob and its record,
+// returns the function releasing the lock.
+func lockDedupBlob(sum string) (unlock func()) {
+	lockPath := path.Join(dedupLockPrefix, "blobs", sum)
+	nsMutex.Lock(minioMetaBucket, lockPath)
+	return func() {
+		nsMutex.Unlock(minioMetaBucket, lockPath)
+	}
+}
+
+// readDedupRecord - reads the index record of a dedup blob, returns
+// errFileNotFound if there is no such blob.
+func readDedupRecord(storage StorageAPI, sum string) (dedupRecord, error) {
+	r, err := storage.ReadFile(minioMetaBucket, dedupRecordPath(sum), 0)
+	if err != nil {
+		return dedupRecord{}, err
+	}
+	defer r.Close()
+	var record dedupRecord
+	decoder := json.NewDecoder(r)
+	if err = decoder.Decode(&record); err != nil {
+		return dedupRecord{}, err
+	}
+	return record, nil
+}
+
+// writeDedupRecord - saves the index record of a dedup blob.
+func writeDedupRecord(storage StorageAPI, sum string, record dedupRecord) error {
+	w, err := storage.CreateFile(minioMetaBucket, dedupRecordPath(sum))
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	if err = encoder.Encode(&record); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	if err = w.Close(); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	return nil
+}
+
+// releaseDedupBlob - drops a reference to a dedup blob, the blob and
+// its record are removed with the last one.
+func releaseDedupBlob(storage StorageAPI, sum string) error {
+	unlock := lockDedupBlob(sum)
+	defer unlock()
+	return dropDedupBlobRef(storage, sum)
+}
+
+// dropDedupBlobRef - releaseDedupBlob with the blob lock held.
+func dropDedupBlobRef(storage StorageAPI, sum string) error {
+	record, err := readDedupRecord(storage, sum)
+	if err != nil {
+		return err
+	}
+	if record.Refs > 1 {
+		record.Refs--
+		return writeDedupRecord(storage, sum, record)
+	}
+	if err = storage.DeleteFile(minioMetaBucket, dedupBlobPath(sum)); err != nil && err != errFileNotFound {
+		return err
+	}
+	return storage.DeleteFile(minioMetaBucket, dedupRecordPath(sum))
+}
+
+// dedupReference - dedup blob referenced by an object being replaced
+// or deleted. Its lock serializes replacing and deleting the object,
+// so that the reference is dropped only once.
+type dedupReference struct {
+	storage StorageAPI
+	// Empty if the object holds its own data.
+	sum    string
+	unlock func()
+}
+
+// lockDedupReference - locks an object about to be replaced or
+// deleted and reads the dedup blob it references. The lock is held
+// until unlock is called.
+func lockDedupReference(storage StorageAPI, bucket, object string) (dedupReference, error) {
+	lockPath := path.Join(dedupLockPrefix, "objects", bucket, object)
+	nsMutex.Lock(minioMetaBucket, lockPath)
+	unlock := func() {
+		nsMutex.Unlock(minioMetaBucket, lockPath)
+	}
+	metadata, err := readObjectMetadata(storage, bucket, object)
+	if err != nil {
+		unlock()
+		return dedupReference{}, err
+	}
+	return dedupReference{storage: storage, sum: metadata[dedupBlobKey], unlock: unlock}, nil
+}
+
+// release - drops the reference once the object is replaced or deleted.
+func (r dedupReference) release() error {
+	if r.sum == "" {
+		return nil
+	}
+	return releaseDedupBlob(r.storage, r.sum)
+}
+
+// setBucketDedup - common function to turn dedup of objects put in a
+// bucket on or off for both object layers. Objects already put keep
+// their data as it is.
+func setBucketDedup(storage StorageAPI, bucket string, enabled bool) error {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(storage, bucket) {
+		return BucketNotFound{Bucket: bucket}
+	}
+	err := updateBucketMetadata(storage, bucket, func(meta *bucketMetadata) {
+		meta.Dedup = enabled
+	})
+	if err != nil {
+		return toObjectErr(err, bucket)
+	}
+	return nil
+}
+
+// isDedupCandidate - reports if an object being put is to be
+// deduplicated, content transformed at rest is stored as is.
+func isDedupCandidate(meta bucketMetadata, object string, metadata map[string]string) bool {
+	return meta.Dedup && !isTransformedAtRest(object, metadata)
+}
+
+// dedupStaged - deduplicates the data of an object staged at tmpPath
+// inside minioMetaBucket, size bytes of content with the given MD5 and
+// SHA-256 sums. The data either becomes a new blob or is dropped for
+// an identical blob, an empty file is left at tmpPath to be put in
+// place of the object data. Returns metadata with the reference and
+// the function dropping it again if the object can't be put in place.
+// Content colliding with a blob of another size or MD5 sum is left at
+// tmpPath as is.
+func dedupStaged(storage StorageAPI, tmpPath string, size int64, md5Hex, sha256Hex string, metadata map[string]string) (map[string]string, func(), error) {
+	unlock := lockDedupBlob(sha256Hex)
+	defer unlock()
+
+	record, err := readDedupRecord(storage, sha256Hex)
+	switch {
+	case err == errFileNotFound:
+		if err = storage.RenameFile(minioMetaBucket, tmpPath, minioMetaBucket, dedupBlobPath(sha256Hex)); err != nil {
+			return nil, nil, err
+		}
+		record = dedupRecord{Size: size, MD5Sum: md5Hex, Refs: 1}
+		if err = writeDedupRecord(storage, sha256Hex, record); err != nil {
+			if derr := storage.DeleteFile(minioMetaBucket, dedupBlobPath(sha256Hex)); derr != nil {
+				return nil, nil, derr
+			}
+			return nil, nil, err
+		}
+	case err != nil:
+		return nil, nil, err
+	case record.Size != size || record.MD5Sum != md5Hex:
+		return metadata, func() {}, nil
+	default:
+		record.Refs++
+		if err = writeDedupRecord(storage, sha256Hex, record); err != nil {
+			return nil, nil, err
+		}
+		if err = storage.DeleteFile(minioMetaBucket, tmpPath); err != nil {
+			return nil, nil, dedupUndo(storage, sha256Hex, err)
+		}
+	}
+	release := func() {
+		if err := releaseDedupBlob(storage, sha256Hex); err != nil {
+			errorIf(err, "Unable to release dedup blob.", nil)
+		}
+	}
+
+	// Empty data file the reference is put in place with.
+	w, err := storage.CreateFile(minioMetaBucket, tmpPath)
+	if err != nil {
+		return nil, nil, dedupUndo(storage, sha256Hex, err)
+	}
+	if err = w.Close(); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return nil, nil, dedupUndo(storage, sha256Hex, clErr)
+		}
+		return nil, nil, dedupUndo(storage, sha256Hex, err)
+	}
+
+	objMetadata := make(map[string]string, len(metadata)+2)
+	for k, v := range metadata {
+		objMetadata[k] = v
+	}
+	objMetadata[dedupBlobKey] = sha256Hex
+	objMetadata[dedupSizeKey] = strconv.FormatInt(size, 10)
+	return objMetadata, release, nil
+}
+
+// dedupUndo - drops the reference dedupStaged took while the blob
+// lock is still held, returns err.
+func dedupUndo(storage StorageAPI, sum string, err error) error {
+	if rerr := dropDedupBlobRef(storage, sum); rerr != nil {
+		errorIf(rerr, "Unable to release dedup blob.", nil)
+	}
+	return err
+}
@@ -0,0 +1,152 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"testing"
+)
+
+// Wrapper for calling dedup tests for both XL multiple disks and single node setup.
+func TestObjectDedup(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testObjectDedup)
+}
+
+// Tests validate that objects with identical content share one blob,
+// read back transparently, and that the blob is only removed along
+// with its last reference.
+func testObjectDedup(obj ObjectLayer, instanceType string, t *testing.T) {
+	var storage StorageAPI
+	switch l := obj.(type) {
+	case xlObjects:
+		storage = l.storage
+	case fsObjects:
+		storage = l.storage
+	}
+	if err := obj.SetBucketDedup("missing-bucket", true); err == nil {
+		t.Fatalf("%s: Expected BucketNotFound", instanceType)
+	} else if _, ok := err.(BucketNotFound); !ok {
+		t.Fatalf("%s: Expected BucketNotFound, got %v", instanceType, err)
+	}
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := bytes.Repeat([]byte("dedup"), 1000)
+	sum := sha256.Sum256(data)
+	sha256Hex := hex.EncodeToString(sum[:])
+
+	putObject := func(object string, content []byte) {
+		if _, err := obj.PutObject(bucket, object, int64(len(content)), bytes.NewReader(content), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	checkObject := func(object string, content []byte) {
+		objInfo, err := obj.GetObjectInfo(bucket, object)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		if objInfo.Size != int64(len(content)) {
+			t.Fatalf("%s: Expected %s to be %d bytes, got %d", instanceType, object, len(content), objInfo.Size)
+		}
+		r, err := obj.GetObject(bucket, object, 0)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		got, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		if !bytes.Equal(got, content) {
+			t.Fatalf("%s: Content of %s doesn't match", instanceType, object)
+		}
+	}
+	checkRefs := func(step string, refs int64) {
+		record, err := readDedupRecord(storage, sha256Hex)
+		if refs == 0 {
+			if err != errFileNotFound {
+				t.Fatalf("%s: %s: Expected the blob to be removed, got %v", instanceType, step, err)
+			}
+			if _, err = storage.StatFile(minioMetaBucket, dedupBlobPath(sha256Hex)); err != errFileNotFound {
+				t.Fatalf("%s: %s: Expected the blob to be removed, got %v", instanceType, step, err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("%s: %s: %s", instanceType, step, err.Error())
+		}
+		if record.Refs != refs {
+			t.Fatalf("%s: %s: Expected %d references, got %d", instanceType, step, refs, record.Refs)
+		}
+	}
+
+	// Without dedup objects hold their own data.
+	putObject("plain", data)
+	checkRefs("Disabled", 0)
+
+	if err := obj.SetBucketDedup(bucket, true); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	putObject("a", data)
+	putObject("dir/b", data)
+	putObject("c", data)
+	checkRefs("Put", 3)
+	for _, object := range []string{"plain", "a", "dir/b", "c"} {
+		checkObject(object, data)
+	}
+
+	// Ranged reads resolve the reference too.
+	r, err := obj.GetObject(bucket, "a", 5)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	got, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if !bytes.Equal(got, data[5:]) {
+		t.Fatalf("%s: Content of a from offset 5 doesn't match", instanceType)
+	}
+
+	// Deleting a reference leaves the others readable.
+	if err = obj.DeleteObject(bucket, "a"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	checkRefs("Delete", 2)
+	checkObject("dir/b", data)
+
+	// Overwriting a reference with other content drops it.
+	other := []byte("other content")
+	putObject("c", other)
+	checkRefs("Overwrite", 1)
+	checkObject("c", other)
+	checkObject("dir/b", data)
+
+	// The blob goes along with the last reference.
+	if err = obj.DeleteObject(bucket, "dir/b"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	checkRefs("Delete last", 0)
+	checkObject("plain", data)
+}
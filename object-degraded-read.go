@@ -0,0 +1,89 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"io"
+)
+
+// errRangesIncomplete - MissingRanges() called before the object was
+// read fully.
+var errRangesIncomplete = errors.New("Missing ranges are not known until the object is read fully")
+
+// GetObjectOptions - options of GetObjectWithOptions.
+type GetObjectOptions struct {
+	// AllowDegradedRead - serve an object from fewer disks than read
+	// quorum instead of failing with InsufficientReadQuorum. A read is
+	// degraded when
+	//
+	//  - a file of the object, its data, part or metadata files, is
+	//    agreed upon by exactly one disk less than read quorum. Fewer
+	//    disks still fail with InsufficientReadQuorum.
+	//  - a block of the data is left with fewer readable parts than
+	//    data blocks, so it can't be reconstructed. Its data blocks
+	//    still readable are served, the bytes of the others read as
+	//    zeros and are reported by DegradedReader.MissingRanges.
+	//
+	// Metadata is never served with bytes missing, and objects stored
+	// compressed, encrypted or deduplicated are only served from read
+	// quorum. Parts of multipart objects read with the option aren't
+	// checked for bit rot. Single node setups have no quorum, reads
+	// never degrade.
+	AllowDegradedRead bool
+}
+
+// DegradedReader - object data read by GetObjectWithOptions, bytes
+// which could not be reconstructed read as zeros. MissingRanges()
+// returns them once the reader reached EOF.
+type DegradedReader struct {
+	reader  io.ReadCloser
+	eof     bool
+	missing func() []ObjectByteRange
+}
+
+// newDegradedReader - wraps reader, missing returns the ranges of the
+// object read which are missing once read fully, nil if none can be.
+func newDegradedReader(reader io.ReadCloser, missing func() []ObjectByteRange) *DegradedReader {
+	return &DegradedReader{reader: reader, missing: missing}
+}
+
+// Read - implements io.Reader.
+func (r *DegradedReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err == io.EOF {
+		r.eof = true
+	}
+	return n, err
+}
+
+// Close - implements io.Closer.
+func (r *DegradedReader) Close() error {
+	return r.reader.Close()
+}
+
+// MissingRanges - ranges of the object read as zeros since they could
+// not be reconstructed, in order. Empty unless the read was degraded.
+func (r *DegradedReader) MissingRanges() ([]ObjectByteRange, error) {
+	if !r.eof {
+		return nil, errRangesIncomplete
+	}
+	if r.missing == nil {
+		return nil, nil
+	}
+	return r.missing(), nil
+}
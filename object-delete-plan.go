@@ -0,0 +1,164 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"path"
+	"sync"
+)
+
+// storageFile - a file of a volume on the storage.
+type storageFile struct {
+	volume string
+	path   string
+	// Deleting a file which is already gone isn't an error.
+	optional bool
+}
+
+// deletePlan - the files removed by deleting an object, resolved
+// before anything is removed so that a dry run reports exactly what
+// the delete removes.
+type deletePlan struct {
+	// Parts of a multipart object, removed in parallel first.
+	parts []storageFile
+	// Removed one after another once all the parts are gone.
+	files []storageFile
+}
+
+// paths - storage paths of the files of the plan as volume/path, in
+// the order they are removed.
+func (p deletePlan) paths() []string {
+	paths := make([]string, 0, len(p.parts)+len(p.files))
+	for _, file := range p.parts {
+		paths = append(paths, pathJoin(file.volume, file.path))
+	}
+	for _, file := range p.files {
+		paths = append(paths, pathJoin(file.volume, file.path))
+	}
+	return paths
+}
+
+// Default maximum number of parts of a multipart object deleted in
+// parallel.
+const defaultDeletePartsConcurrency = 16
+
+// execute - removes the files of the plan, with at most workers parts
+// deletes in flight.
+func (p deletePlan) execute(storage StorageAPI, workers int) error {
+	var wg = &sync.WaitGroup{}
+	var errs = make([]error, len(p.parts))
+	indices := make(chan int)
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(p.parts) {
+		workers = len(p.parts)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		// Start deleting parts in routine.
+		go func() {
+			defer wg.Done()
+			for index := range indices {
+				errs[index] = storage.DeleteFile(p.parts[index].volume, p.parts[index].path)
+			}
+		}()
+	}
+	for index := range p.parts {
+		indices <- index
+	}
+	close(indices)
+	// Wait for all the deletes to finish.
+	wg.Wait()
+	// Loop through and validate if any errors, if we are unable to remove any part return
+	// "unexpected" error as returning any other error might be misleading. For ex.
+	// if DeleteFile() had returned errFileNotFound and we return it, then client would see
+	// ObjectNotFound which is misleading.
+	for _, err := range errs {
+		if err != nil {
+			return errUnexpected
+		}
+	}
+	for _, file := range p.files {
+		if err := storage.DeleteFile(file.volume, file.path); err != nil {
+			if err == errFileNotFound && file.optional {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// planObjectDelete - resolves the files of an object, the part files
+// and multipart metadata for a multipart object, and its metadata
+// sidecar if it has one. Fails with errFileNotFound if the object
+// doesn't exist.
+func planObjectDelete(storage StorageAPI, bucket, object string, multipart bool) (deletePlan, error) {
+	var plan deletePlan
+	if multipart {
+		info, err := getMultipartObjectInfo(storage, bucket, object)
+		if err != nil {
+			return deletePlan{}, err
+		}
+		for _, part := range info.Parts {
+			plan.parts = append(plan.parts, storageFile{
+				volume: bucket,
+				path:   pathJoin(object, partNumToPartFileName(part.PartNumber)),
+			})
+		}
+		plan.files = append(plan.files, storageFile{volume: bucket, path: pathJoin(object, multipartMetaFile)})
+	} else {
+		if _, err := storage.StatFile(bucket, object); err != nil {
+			return deletePlan{}, err
+		}
+		plan.files = append(plan.files, storageFile{volume: bucket, path: object})
+	}
+	sidecar := objectMetaSidecar(bucket, object)
+	if _, err := storage.StatFile(minioMetaBucket, sidecar); err == nil {
+		plan.files = append(plan.files, storageFile{volume: minioMetaBucket, path: sidecar, optional: true})
+	} else if err != errFileNotFound {
+		return deletePlan{}, err
+	}
+	return plan, nil
+}
+
+// planBucketDelete - storage paths removed along with a bucket, the
+// bucket itself and the metadata files saved for it.
+func planBucketDelete(storage StorageAPI, bucket string) ([]string, error) {
+	paths := []string{retainSlash(bucket)}
+	for _, metaFile := range []string{bucketMetaFile, bucketUsageFile, bucketStatsFile} {
+		metaPath := path.Join(bucketMetaPrefix, bucket, metaFile)
+		if _, err := storage.StatFile(minioMetaBucket, metaPath); err == nil {
+			paths = append(paths, pathJoin(minioMetaBucket, metaPath))
+		} else if err != errFileNotFound {
+			return nil, toObjectErr(err, bucket)
+		}
+	}
+	return paths, nil
+}
+
+// planDirDelete - storage paths of all the files under a directory, as
+// removed by cleanupDir.
+func planDirDelete(storage StorageAPI, volume, dirPath string) ([]string, error) {
+	var paths []string
+	err := walkDirFiles(storage, volume, dirPath, func(entryPath string) error {
+		paths = append(paths, pathJoin(volume, entryPath))
+		return nil
+	})
+	return paths, err
+}
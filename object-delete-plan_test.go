@@ -0,0 +1,255 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Wrapper for calling dry run delete tests for both XL multiple disks and single node setup.
+func TestDeleteDryRun(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testDeleteDryRun)
+}
+
+// Tests validate dry run deletes report the storage paths of simple and
+// multipart objects and of a whole bucket while leaving them in place,
+// and fail the same way the delete does.
+func testDeleteDryRun(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	contains := func(paths []string, expected string) bool {
+		for _, p := range paths {
+			if p == expected {
+				return true
+			}
+		}
+		return false
+	}
+	data := []byte("hello, world")
+	if _, err := obj.PutObject(bucket, "simple", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var parts []completePart
+	for i, size := range []int{5 * 1024 * 1024, 17} {
+		var partMD5 string
+		partMD5, err = obj.PutObjectPart(bucket, "multipart", uploadID, i+1, int64(size), bytes.NewReader(bytes.Repeat([]byte("a"), size)), "")
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		parts = append(parts, completePart{PartNumber: i + 1, ETag: partMD5})
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, "multipart", uploadID, parts); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	paths, err := obj.DeleteObjectDryRun(bucket, "simple")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if !contains(paths, bucket+"/simple") {
+		t.Fatalf("%s: Expected %s/simple in %v", instanceType, bucket, paths)
+	}
+	// Part lists of multipart objects are expanded.
+	paths, err = obj.DeleteObjectDryRun(bucket, "multipart")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var expected []string
+	if _, ok := obj.(xlObjects); ok {
+		for _, part := range parts {
+			expected = append(expected, bucket+"/multipart/"+partNumToPartFileName(part.PartNumber))
+		}
+		expected = append(expected, bucket+"/multipart/"+multipartMetaFile)
+	} else {
+		expected = append(expected, bucket+"/multipart")
+	}
+	for _, p := range expected {
+		if !contains(paths, p) {
+			t.Fatalf("%s: Expected %s in %v", instanceType, p, paths)
+		}
+	}
+	for _, object := range []string{"simple", "multipart"} {
+		if _, err = obj.GetObjectInfo(bucket, object); err != nil {
+			t.Fatalf("%s: Object %s deleted by dry run: %s", instanceType, object, err)
+		}
+	}
+
+	// Missing objects fail like the delete, and aren't an error in a
+	// batch.
+	if _, err = obj.DeleteObjectDryRun(bucket, "missing"); err == nil {
+		t.Fatalf("%s: Expected ObjectNotFound", instanceType)
+	} else if _, ok := err.(ObjectNotFound); !ok {
+		t.Fatalf("%s: Expected ObjectNotFound, got %v", instanceType, err)
+	}
+	paths, errs, err := obj.DeleteObjectsDryRun(bucket, []string{"simple", "missing", "multipart"})
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err)
+		}
+	}
+	if !contains(paths, bucket+"/simple") || !contains(paths, expected[0]) {
+		t.Fatalf("%s: Expected paths of both objects in %v", instanceType, paths)
+	}
+
+	// Objects under legal hold are refused.
+	if err = obj.PutObjectLegalHold(bucket, "simple", true); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.DeleteObjectDryRun(bucket, "simple"); err == nil {
+		t.Fatalf("%s: Expected ObjectUnderLegalHold", instanceType)
+	} else if _, ok := err.(ObjectUnderLegalHold); !ok {
+		t.Fatalf("%s: Expected ObjectUnderLegalHold, got %v", instanceType, err)
+	}
+	if err = obj.PutObjectLegalHold(bucket, "simple", false); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	// Whole bucket, including the bucket itself and uploads in progress.
+	if _, err = obj.NewMultipartUpload(bucket, "pending", nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	EnterMaintenanceMode()
+	defer ExitMaintenanceMode()
+	paths, err = obj.DeleteBucketForceDryRun(bucket)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	for _, p := range append(expected, bucket+"/simple", bucket+"/") {
+		if !contains(paths, p) {
+			t.Fatalf("%s: Expected %s in %v", instanceType, p, paths)
+		}
+	}
+	if _, err = obj.GetBucketInfo(bucket); err != nil {
+		t.Fatalf("%s: Bucket deleted by dry run: %s", instanceType, err)
+	}
+	result, err := obj.ListMultipartUploads(bucket, "", "", "", "", 1000)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(result.Uploads) != 1 {
+		t.Fatalf("%s: Expected the upload in progress to remain, got %d uploads", instanceType, len(result.Uploads))
+	}
+
+	// Nothing is left to delete once the object is deleted.
+	if err = obj.DeleteObject(bucket, "simple"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.DeleteObjectDryRun(bucket, "simple"); err == nil {
+		t.Fatalf("%s: Expected ObjectNotFound", instanceType)
+	}
+}
+
+// concurrentDeleteStorage - records the most deletes in flight.
+type concurrentDeleteStorage struct {
+	StorageAPI
+	mutex    *sync.Mutex
+	inFlight *int
+	max      *int
+}
+
+func (s concurrentDeleteStorage) DeleteFile(volume, path string) error {
+	s.mutex.Lock()
+	*s.inFlight++
+	if *s.inFlight > *s.max {
+		*s.max = *s.inFlight
+	}
+	s.mutex.Unlock()
+	// Give other deletes the time to start.
+	time.Sleep(5 * time.Millisecond)
+	s.mutex.Lock()
+	*s.inFlight--
+	s.mutex.Unlock()
+	return s.StorageAPI.DeleteFile(volume, path)
+}
+
+// Tests validate the parts deletes in flight are bounded by the delete
+// concurrency the XL object layer is initialized with.
+func TestXLDeleteConcurrency(t *testing.T) {
+	testCases := []struct {
+		opts        []XLOption
+		concurrency int
+		maxInFlight int
+	}{
+		{nil, defaultDeletePartsConcurrency, defaultDeletePartsConcurrency},
+		{[]XLOption{WithDeleteConcurrency(0)}, defaultDeletePartsConcurrency, defaultDeletePartsConcurrency},
+		{[]XLOption{WithDeleteConcurrency(2)}, 2, 2},
+		// Negative counts delete one part at a time.
+		{[]XLOption{WithDeleteConcurrency(-1)}, -1, 1},
+	}
+	for i, testCase := range testCases {
+		xl, removeDisks := newTestXLWithOptions(t, 8, testCase.opts...)
+		if xl.deletePartsConcurrency != testCase.concurrency {
+			removeDisks()
+			t.Fatalf("Test %d: Expected delete concurrency %d, got %d", i+1, testCase.concurrency, xl.deletePartsConcurrency)
+		}
+		bucket, object := "bucket", "object"
+		if err := xl.MakeBucket(bucket); err != nil {
+			removeDisks()
+			t.Fatal(err)
+		}
+		var info MultipartObjectInfo
+		for partNumber := 1; partNumber <= 2*defaultDeletePartsConcurrency; partNumber++ {
+			w, err := xl.storage.CreateFile(bucket, pathJoin(object, partNumToPartFileName(partNumber)))
+			if err != nil {
+				removeDisks()
+				t.Fatal(err)
+			}
+			if _, err = w.Write([]byte("a")); err != nil {
+				removeDisks()
+				t.Fatal(err)
+			}
+			if err = w.Close(); err != nil {
+				removeDisks()
+				t.Fatal(err)
+			}
+			info.Parts = append(info.Parts, MultipartPartInfo{PartNumber: partNumber, Size: 1})
+			info.Size++
+		}
+		if err := saveMultipartObjectInfo(xl.storage, bucket, object, info); err != nil {
+			removeDisks()
+			t.Fatal(err)
+		}
+
+		var inFlight, max int
+		xl.storage = concurrentDeleteStorage{xl.storage, &sync.Mutex{}, &inFlight, &max}
+		_, err := xl.deleteObject(bucket, object, false)
+		removeDisks()
+		if err != nil {
+			t.Fatalf("Test %d: %s", i+1, err)
+		}
+		if max > testCase.maxInFlight {
+			t.Errorf("Test %d: Expected at most %d deletes in flight, got %d", i+1, testCase.maxInFlight, max)
+		}
+		if testCase.maxInFlight > 1 && max < 2 {
+			t.Errorf("Test %d: Expected parts to be deleted in parallel", i+1)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+// Metadata key asking PutObject to flush the object to stable storage
+// before returning success, value is "true". Off by default, an fsync
+// per object costs throughput.
+const durableKey = "durable"
+
+// fileSyncer - storage able to flush a file to stable storage.
+type fileSyncer interface {
+	SyncFile(volume, path string) error
+}
+
+// isDurablePut - returns true if metadata asks for a durable put.
+func isDurablePut(metadata map[string]string) bool {
+	return metadata[durableKey] == "true"
+}
+
+// checkDurablePut - fails a durable put up front, before any data is
+// written, when the storage can't honour it.
+func checkDurablePut(storage StorageAPI, metadata map[string]string) error {
+	if !isDurablePut(metadata) {
+		return nil
+	}
+	if _, ok := storage.(fileSyncer); !ok {
+		return errSyncNotSupported
+	}
+	return nil
+}
+
+// syncObject - flushes the data and metadata of a committed object to
+// stable storage.
+func syncObject(storage StorageAPI, bucket, object string, metadata map[string]string) error {
+	syncer, ok := storage.(fileSyncer)
+	if !ok {
+		return errSyncNotSupported
+	}
+	if err := syncer.SyncFile(bucket, object); err != nil {
+		return err
+	}
+	// Data of a deduplicated object lives in its shared blob, the
+	// object itself is an empty reference.
+	sum, _, err := getObjectDedup(metadata)
+	if err != nil {
+		return err
+	}
+	if sum != "" {
+		if err = syncer.SyncFile(minioMetaBucket, dedupBlobPath(sum)); err != nil {
+			return err
+		}
+		if err = syncer.SyncFile(minioMetaBucket, dedupRecordPath(sum)); err != nil {
+			return err
+		}
+	}
+	// Metadata kept outside the object is in its sidecar, if any.
+	if err = syncer.SyncFile(minioMetaBucket, objectMetaSidecar(bucket, object)); err != nil && err != errFileNotFound {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+// Wrapper for calling durable put tests for both XL multiple disks and single node setup.
+func TestObjectDurablePut(t *testing.T) {
+	initNSLock()
+	ExecObjectLayerTest(t, testObjectDurablePut)
+}
+
+// Tests validate durable puts succeed, including deduplicated objects,
+// and the durable flag isn't saved with the object.
+func testObjectDurablePut(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	content := []byte("durable content")
+	put := func(object string) {
+		metadata := map[string]string{durableKey: "true", "content-type": "text/plain"}
+		if _, err := obj.PutObject(bucket, object, int64(len(content)), bytes.NewReader(content), metadata); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		r, err := obj.GetObject(bucket, object, 0)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		data, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		if !bytes.Equal(data, content) {
+			t.Fatalf("%s: Expected %s to read back %q, got %q", instanceType, object, content, data)
+		}
+		info, err := obj.GetObjectInfo(bucket, object)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		if _, ok := info.UserDefined[durableKey]; ok {
+			t.Fatalf("%s: Expected durable flag of %s not to be saved", instanceType, object)
+		}
+		if info.ContentType != "text/plain" {
+			t.Fatalf("%s: Expected content type of %s to be saved, got %q", instanceType, object, info.ContentType)
+		}
+	}
+	put("dir/object")
+	// Overwrites are as durable as new objects.
+	put("dir/object")
+
+	if err := obj.SetBucketDedup(bucket, true); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	put("first")
+	put("second")
+}
@@ -0,0 +1,372 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+const (
+	// Object metadata keys of objects encrypted at rest, the sealed
+	// data key and the IV are base64 encoded.
+	encryptionKey          = "x-minio-encryption"
+	encryptionKeyIDKey     = "x-minio-encryption-key-id"
+	encryptionSealedKeyKey = "x-minio-encryption-sealed-key"
+	encryptionIVKey        = "x-minio-encryption-iv"
+	// Cipher of objects encrypted at rest.
+	encryptionAES256GCM = "AES256-GCM"
+	// Plaintext size of each independently sealed package, reads
+	// start decrypting at the package holding the offset.
+	encryptPackageSize = 64 * 1024
+	// Bytes added to each package by sealing it.
+	encryptPackageOverhead = 16
+)
+
+var errKeySourceNotSet = errors.New("Object is encrypted at rest but no key source is set")
+var errKeyNotFound = errors.New("Data key was sealed with an unknown key")
+var errEncryptedDataCorrupt = errors.New("Encrypted object data is corrupt")
+
+// KeySource - source of the data keys objects are encrypted with at
+// rest, such as a KMS.
+type KeySource interface {
+	// GenerateKey - returns a new 256-bit data key, the key sealed
+	// for storage in the object metadata and the id of the key it
+	// was sealed with.
+	GenerateKey() (key, sealedKey []byte, keyID string, err error)
+	// UnsealKey - returns the data key sealed with keyID.
+	UnsealKey(keyID string, sealedKey []byte) ([]byte, error)
+}
+
+// Key source of the data keys of objects written, nil if objects are
+// stored in cleartext.
+var globalKeySource = struct {
+	sync.RWMutex
+	source KeySource
+}{}
+
+// SetKeySource - encrypts objects put from now on at rest with data
+// keys from source, a nil source stores them in cleartext. Encrypted
+// objects can only be read while the source can unseal their keys.
+func SetKeySource(source KeySource) {
+	globalKeySource.Lock()
+	defer globalKeySource.Unlock()
+	globalKeySource.source = source
+}
+
+// getKeySource - returns the key source set, nil if none is.
+func getKeySource() KeySource {
+	globalKeySource.RLock()
+	defer globalKeySource.RUnlock()
+	return globalKeySource.source
+}
+
+// masterKeySource - key source sealing data keys with a single master
+// key.
+type masterKeySource struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// NewMasterKeySource - key source sealing data keys with the 256-bit
+// masterKey, identified by id.
+func NewMasterKeySource(id string, masterKey []byte) (KeySource, error) {
+	if len(masterKey) != 32 {
+		return nil, errInvalidArgument
+	}
+	aead, err := newAESGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return masterKeySource{id: id, aead: aead}, nil
+}
+
+// GenerateKey - implements KeySource, the sealed key is prefixed with
+// the nonce it was sealed with.
+func (s masterKeySource) GenerateKey() (key, sealedKey []byte, keyID string, err error) {
+	key = make([]byte, 32)
+	if _, err = io.ReadFull(rand.Reader, key); err != nil {
+		return nil, nil, "", err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, "", err
+	}
+	return key, s.aead.Seal(nonce, nonce, key, nil), s.id, nil
+}
+
+// UnsealKey - implements KeySource.
+func (s masterKeySource) UnsealKey(keyID string, sealedKey []byte) ([]byte, error) {
+	if keyID != s.id {
+		return nil, errKeyNotFound
+	}
+	nonceSize := s.aead.NonceSize()
+	if len(sealedKey) < nonceSize {
+		return nil, errKeyNotFound
+	}
+	key, err := s.aead.Open(nil, sealedKey[:nonceSize], sealedKey[nonceSize:], nil)
+	if err != nil {
+		return nil, errKeyNotFound
+	}
+	return key, nil
+}
+
+// newAESGCM - AES-GCM with key.
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// packageNonce - nonce of package seq, the IV with the package
+// sequence number mixed into its last 8 bytes.
+func packageNonce(iv []byte, seq uint64) []byte {
+	nonce := make([]byte, len(iv))
+	copy(nonce, iv)
+	binary.BigEndian.PutUint64(nonce[4:], binary.BigEndian.Uint64(iv[4:])^seq)
+	return nonce
+}
+
+// packageAAD - additional data of a package, the last package is told
+// apart so that truncated data fails to decrypt.
+func packageAAD(last bool) []byte {
+	if last {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// encryptWriter - seals data written to it in packages of
+// encryptPackageSize.
+type encryptWriter struct {
+	writer io.Writer
+	aead   cipher.AEAD
+	iv     []byte
+	seq    uint64
+	buf    []byte
+}
+
+// Write - implements io.Writer, a full package is only sealed once
+// more data arrives, as the last package is sealed differently.
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if len(w.buf) == encryptPackageSize {
+			if err := w.seal(false); err != nil {
+				return 0, err
+			}
+		}
+		space := encryptPackageSize - len(w.buf)
+		if space > len(p) {
+			space = len(p)
+		}
+		w.buf = append(w.buf, p[:space]...)
+		p = p[space:]
+	}
+	return n, nil
+}
+
+// seal - seals the buffered data as package w.seq.
+func (w *encryptWriter) seal(last bool) error {
+	sealed := w.aead.Seal(nil, packageNonce(w.iv, w.seq), w.buf, packageAAD(last))
+	if _, err := w.writer.Write(sealed); err != nil {
+		return err
+	}
+	w.seq++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Close - seals the last package, even an empty one, doesn't close
+// the underlying writer.
+func (w *encryptWriter) Close() error {
+	return w.seal(true)
+}
+
+// newEncryptWriter - returns a writer encrypting into writer with a new
+// data key from source, along with the metadata to save with the
+// object.
+func newEncryptWriter(source KeySource, writer io.Writer) (*encryptWriter, map[string]string, error) {
+	key, sealedKey, keyID, err := source.GenerateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := newAESGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	iv := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, nil, err
+	}
+	metadata := map[string]string{
+		encryptionKey:          encryptionAES256GCM,
+		encryptionKeyIDKey:     keyID,
+		encryptionSealedKeyKey: base64.StdEncoding.EncodeToString(sealedKey),
+		encryptionIVKey:        base64.StdEncoding.EncodeToString(iv),
+	}
+	return &encryptWriter{writer: writer, aead: aead, iv: iv}, metadata, nil
+}
+
+// decryptedSize - size of the plaintext of storedSize encrypted bytes.
+func decryptedSize(storedSize int64) (int64, error) {
+	sealedSize := int64(encryptPackageSize + encryptPackageOverhead)
+	packages := (storedSize + sealedSize - 1) / sealedSize
+	if packages == 0 || storedSize-(packages-1)*sealedSize < encryptPackageOverhead {
+		return 0, errEncryptedDataCorrupt
+	}
+	return storedSize - packages*encryptPackageOverhead, nil
+}
+
+// encryptedSize - size of size bytes once encrypted, see decryptedSize.
+func encryptedSize(size int64) int64 {
+	packages := (size + encryptPackageSize - 1) / encryptPackageSize
+	if packages == 0 {
+		// The empty package of an empty object.
+		packages = 1
+	}
+	return size + packages*encryptPackageOverhead
+}
+
+// objectEncryption - data key and IV of an object encrypted at rest.
+type objectEncryption struct {
+	aead cipher.AEAD
+	iv   []byte
+}
+
+// isEncrypted - reports if an object is encrypted at rest.
+func isEncrypted(metadata map[string]string) bool {
+	_, ok := metadata[encryptionKey]
+	return ok
+}
+
+// getObjectEncryption - unseals the data key of an object from its
+// metadata, nil if the object is stored in cleartext.
+func getObjectEncryption(metadata map[string]string) (*objectEncryption, error) {
+	if !isEncrypted(metadata) {
+		return nil, nil
+	}
+	if cipherName := metadata[encryptionKey]; cipherName != encryptionAES256GCM {
+		return nil, fmt.Errorf("Unsupported encryption %s", cipherName)
+	}
+	source := getKeySource()
+	if source == nil {
+		return nil, errKeySourceNotSet
+	}
+	sealedKey, err := base64.StdEncoding.DecodeString(metadata[encryptionSealedKeyKey])
+	if err != nil {
+		return nil, err
+	}
+	iv, err := base64.StdEncoding.DecodeString(metadata[encryptionIVKey])
+	if err != nil {
+		return nil, err
+	}
+	key, err := source.UnsealKey(metadata[encryptionKeyIDKey], sealedKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aead.NonceSize() {
+		return nil, errEncryptedDataCorrupt
+	}
+	return &objectEncryption{aead: aead, iv: iv}, nil
+}
+
+// decryptReader - decrypts the packages read from stored.
+type decryptReader struct {
+	stored   io.ReadCloser
+	aead     cipher.AEAD
+	iv       []byte
+	seq      uint64
+	packages uint64
+	sealed   []byte
+	plain    []byte
+}
+
+// Read - implements io.Reader, decrypts one package at a time.
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.seq == r.packages {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(r.stored, r.sealed)
+		last := r.seq == r.packages-1
+		if err == io.ErrUnexpectedEOF && last {
+			err = nil
+		}
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				err = errEncryptedDataCorrupt
+			}
+			return 0, err
+		}
+		r.plain, err = r.aead.Open(r.sealed[:0], packageNonce(r.iv, r.seq), r.sealed[:n], packageAAD(last))
+		if err != nil {
+			return 0, errEncryptedDataCorrupt
+		}
+		r.seq++
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+// Close - closes the stored data reader.
+func (r *decryptReader) Close() error {
+	return r.stored.Close()
+}
+
+// open - reader of the plaintext from startOffset of the size bytes
+// encrypted into the data returned by readStored.
+func (e *objectEncryption) open(readStored func(offset int64) (io.ReadCloser, error), size, startOffset int64) (io.ReadCloser, error) {
+	seq := startOffset / encryptPackageSize
+	packages := (size + encryptPackageSize - 1) / encryptPackageSize
+	if packages == 0 {
+		// The empty package of an empty object.
+		packages = 1
+	}
+	stored, err := readStored(seq * (encryptPackageSize + encryptPackageOverhead))
+	if err != nil {
+		return nil, err
+	}
+	reader := &decryptReader{
+		stored:   stored,
+		aead:     e.aead,
+		iv:       e.iv,
+		seq:      uint64(seq),
+		packages: uint64(packages),
+		sealed:   make([]byte, encryptPackageSize+encryptPackageOverhead),
+	}
+	if _, err = io.CopyN(ioutil.Discard, reader, startOffset-seq*encryptPackageSize); err != nil {
+		stored.Close()
+		return nil, err
+	}
+	return reader, nil
+}
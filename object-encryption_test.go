@@ -0,0 +1,296 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"testing"
+)
+
+// Wrapper for calling encryption at rest tests for both XL multiple disks and single node setup.
+func TestPutObjectEncrypted(t *testing.T) {
+	ExecObjectLayerTest(t, testPutObjectEncrypted)
+}
+
+// Tests validate objects encrypted at rest aren't stored in cleartext,
+// read back as uploaded in whole and in ranges, and report their
+// plaintext size and ETag.
+func testPutObjectEncrypted(obj ObjectLayer, instanceType string, t *testing.T) {
+	source, err := NewMasterKeySource("test-key", bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	SetKeySource(source)
+	defer SetKeySource(nil)
+
+	var storage StorageAPI
+	switch l := obj.(type) {
+	case xlObjects:
+		storage = l.storage
+	case fsObjects:
+		storage = l.storage
+	}
+	bucket := "minio-bucket"
+	if err = obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	secret := []byte("top secret line\n")
+	testCases := []struct {
+		object   string
+		data     []byte
+		metadata map[string]string
+	}{
+		{"empty", nil, nil},
+		{"small", secret, nil},
+		// Exactly one package.
+		{"package", bytes.Repeat([]byte("p"), encryptPackageSize), nil},
+		{"large", bytes.Repeat(secret, 3*encryptPackageSize/len(secret)+7), nil},
+		// Compressed before it's encrypted.
+		{"compressed", bytes.Repeat(secret, 3*compressBlockSize/len(secret)), map[string]string{compressKey: "true"}},
+	}
+	for i, testCase := range testCases {
+		md5Sum, err := obj.PutObject(bucket, testCase.object, int64(len(testCase.data)), bytes.NewReader(testCase.data), testCase.metadata)
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		expectedMD5 := md5.Sum(testCase.data)
+		if md5Sum != hex.EncodeToString(expectedMD5[:]) {
+			t.Fatalf("%s: Test %d: Expected the MD5Sum of the plaintext, got %s", instanceType, i+1, md5Sum)
+		}
+		reader, err := storage.ReadFile(bucket, testCase.object, 0)
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		stored, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		if len(testCase.data) > 0 && bytes.Contains(stored, testCase.data[:len(testCase.data)/2]) {
+			t.Fatalf("%s: Test %d: Object stored in cleartext", instanceType, i+1)
+		}
+		objInfo, err := obj.GetObjectInfo(bucket, testCase.object)
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		size := int64(len(testCase.data))
+		if objInfo.Size != size || objInfo.MD5Sum != md5Sum {
+			t.Fatalf("%s: Test %d: Expected size %d and MD5Sum %s, got %d and %s", instanceType, i+1, size, md5Sum, objInfo.Size, objInfo.MD5Sum)
+		}
+		ranges := [][2]int64{{0, -1}, {size / 2, -1}, {size, -1}}
+		if size > encryptPackageSize {
+			// Across a package boundary.
+			ranges = append(ranges, [2]int64{encryptPackageSize - 3, 10})
+		}
+		for _, r := range ranges {
+			reader, err := obj.GetObjectRange(bucket, testCase.object, r[0], r[1])
+			if err != nil {
+				t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+			}
+			got, err := ioutil.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+			}
+			end := size
+			if r[1] >= 0 {
+				end = r[0] + r[1]
+			}
+			if !bytes.Equal(got, testCase.data[r[0]:end]) {
+				t.Fatalf("%s: Test %d: Range %d-%d mismatch", instanceType, i+1, r[0], end)
+			}
+		}
+	}
+
+	// Copies are readable with their own data key.
+	if _, err = obj.CopyObject(bucket, "compressed", bucket, "copy", nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	reader, err := obj.GetObject(bucket, "copy", 0)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	got, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if !bytes.Equal(got, testCases[len(testCases)-1].data) {
+		t.Fatalf("%s: Copied data mismatch", instanceType)
+	}
+
+	// Unreadable without the key source.
+	SetKeySource(nil)
+	if _, err = obj.GetObject(bucket, "small", 0); err == nil {
+		t.Fatalf("%s: Expected reading without the key source to fail", instanceType)
+	}
+}
+
+// Wrapper for calling multipart encryption at rest tests for both XL multiple disks and single node setup.
+func TestMultipartObjectEncrypted(t *testing.T) {
+	ExecObjectLayerTest(t, testMultipartObjectEncrypted)
+}
+
+// Tests validate parts of multipart objects are encrypted at rest each
+// with a key of their own, along with parts stored in cleartext, and
+// read back as uploaded in whole and in ranges across parts.
+func testMultipartObjectEncrypted(obj ObjectLayer, instanceType string, t *testing.T) {
+	source, err := NewMasterKeySource("test-key", bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	SetKeySource(source)
+	defer SetKeySource(nil)
+
+	bucket, object := "minio-bucket", "multipart"
+	if err = obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err = obj.SetPartSizeLimits(1, 16*encryptPackageSize); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, object, nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	secret := []byte("top secret line\n")
+	partsData := [][]byte{
+		bytes.Repeat(secret, 2*encryptPackageSize/len(secret)+5),
+		[]byte("stored in cleartext"),
+		secret,
+	}
+	var parts []completePart
+	for i, partData := range partsData {
+		// The second part is uploaded without a key source.
+		if i == 1 {
+			SetKeySource(nil)
+		}
+		md5Sum, err := obj.PutObjectPart(bucket, object, uploadID, i+1, int64(len(partData)), bytes.NewReader(partData), "")
+		SetKeySource(source)
+		if err != nil {
+			t.Fatalf("%s: Part %d: %s", instanceType, i+1, err.Error())
+		}
+		parts = append(parts, completePart{PartNumber: i + 1, ETag: md5Sum})
+	}
+	listed, err := obj.ListObjectParts(bucket, object, uploadID, 0, 10)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	for i, part := range listed.Parts {
+		if part.Size != int64(len(partsData[i])) {
+			t.Fatalf("%s: Part %d: Expected size %d, got %d", instanceType, i+1, len(partsData[i]), part.Size)
+		}
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, object, uploadID, parts); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := bytes.Join(partsData, nil)
+
+	// The parts as stored, joined in one file on fs.
+	var stored []byte
+	readStored := func(storage StorageAPI, path string) {
+		reader, err := storage.ReadFile(bucket, path, 0)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		defer reader.Close()
+		storedData, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		stored = append(stored, storedData...)
+	}
+	switch l := obj.(type) {
+	case xlObjects:
+		for i := range partsData {
+			readStored(l.storage, pathJoin(object, partNumToPartFileName(i+1)))
+		}
+	case fsObjects:
+		readStored(l.storage, object)
+	}
+	for i, partData := range partsData {
+		encrypted := i != 1
+		if bytes.Contains(stored, partData[:len(partData)/2]) == encrypted {
+			t.Fatalf("%s: Part %d: Expected encrypted %v", instanceType, i+1, encrypted)
+		}
+	}
+
+	objInfo, err := obj.GetObjectInfo(bucket, object)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	size := int64(len(data))
+	if objInfo.Size != size {
+		t.Fatalf("%s: Expected size %d, got %d", instanceType, size, objInfo.Size)
+	}
+	firstPart := int64(len(partsData[0]))
+	ranges := [][2]int64{
+		{0, -1},
+		{encryptPackageSize - 3, 10},
+		// Across the parts.
+		{firstPart - 3, 10},
+		{firstPart + 2, -1},
+		{size, -1},
+	}
+	for _, r := range ranges {
+		end := size
+		if r[1] >= 0 {
+			end = r[0] + r[1]
+		}
+		reader, err := obj.GetObjectRange(bucket, object, r[0], r[1])
+		if err != nil {
+			t.Fatalf("%s: Range %d-%d: %s", instanceType, r[0], end, err.Error())
+		}
+		got, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("%s: Range %d-%d: %s", instanceType, r[0], end, err.Error())
+		}
+		if !bytes.Equal(got, data[r[0]:end]) {
+			t.Fatalf("%s: Range %d-%d mismatch", instanceType, r[0], end)
+		}
+	}
+	readerAt, readerAtSize, err := obj.GetObjectVerifiedReaderAt(bucket, object)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if readerAtSize != size {
+		t.Fatalf("%s: Expected size %d, got %d", instanceType, size, readerAtSize)
+	}
+	got := make([]byte, 10)
+	if _, err = readerAt.ReadAt(got, firstPart-3); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if !bytes.Equal(got, data[firstPart-3:firstPart+7]) {
+		t.Fatalf("%s: ReadAt across the parts mismatch", instanceType)
+	}
+
+	// Unreadable without the key source, parts may only fail once
+	// they are read.
+	SetKeySource(nil)
+	reader, err := obj.GetObject(bucket, object, 0)
+	if err == nil {
+		_, err = ioutil.ReadAll(reader)
+		reader.Close()
+	}
+	if err == nil {
+		t.Fatalf("%s: Expected reading without the key source to fail", instanceType)
+	}
+}
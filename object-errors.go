@@ -19,6 +19,8 @@ package main
 import (
 	"fmt"
 	"io"
+	"strings"
+	"time"
 )
 
 // Converts underlying storage error. Convenience function written to
@@ -92,6 +94,43 @@ func (e InsufficientWriteQuorum) Error() string {
 	return "Storage resources are insufficient for the write operation."
 }
 
+// SlowDown - too many writes are already waiting for the object layer.
+type SlowDown struct{}
+
+func (e SlowDown) Error() string {
+	return "Too many object writes in progress, please reduce your request rate."
+}
+
+// InitError - the XL object layer couldn't be initialized over its
+// disks, Cause is the storage error such as errDiskNotFound.
+type InitError struct {
+	Cause error
+	Disks []string
+}
+
+func (e InitError) Error() string {
+	switch e.Cause {
+	case errReadQuorum:
+		return fmt.Sprintf("Not all disks %s are available, did not meet read quroum.", e.Disks)
+	case errDiskNotFound:
+		return fmt.Sprintf("Disks %s not found.", e.Disks)
+	case errVolumeAccessDenied:
+		return fmt.Sprintf("Disks %s access permission denied.", e.Disks)
+	}
+	return e.Cause.Error()
+}
+
+// Unwrap - returns the storage error, for errors.Is.
+func (e InitError) Unwrap() error {
+	return e.Cause
+}
+
+// Temporary - reports if initializing may succeed once retried, such
+// as disks still being mounted, as opposed to a misconfiguration.
+func (e InitError) Temporary() bool {
+	return e.Cause == errReadQuorum || e.Cause == errDiskNotFound
+}
+
 // GenericError - generic object layer error.
 type GenericError struct {
 	Bucket string
@@ -105,6 +144,16 @@ func (e BucketNotFound) Error() string {
 	return "Bucket not found: " + e.Bucket
 }
 
+// BucketBusy - objects keep appearing in a bucket being force deleted.
+type BucketBusy struct {
+	Bucket string
+	Passes int
+}
+
+func (e BucketBusy) Error() string {
+	return fmt.Sprintf("Bucket %s still receives new objects after %d delete passes", e.Bucket, e.Passes)
+}
+
 // BucketNotEmpty bucket is not empty.
 type BucketNotEmpty GenericError
 
@@ -126,6 +175,80 @@ func (e ObjectExistsAsDirectory) Error() string {
 	return "Object exists on : " + e.Bucket + " as directory " + e.Object
 }
 
+// PrefixAccessDenied object name is outside the bucket's allowed prefixes.
+type PrefixAccessDenied GenericError
+
+func (e PrefixAccessDenied) Error() string {
+	return "Object name not allowed by bucket prefixes: " + e.Bucket + "#" + e.Object
+}
+
+// BucketRegionNotAllowed bucket location is not one of the allowed
+// regions.
+type BucketRegionNotAllowed struct {
+	Bucket string
+	Region string
+}
+
+func (e BucketRegionNotAllowed) Error() string {
+	return "Region not allowed for bucket " + e.Bucket + ": " + e.Region
+}
+
+// InvalidBucketConfig imported bucket configuration is malformed.
+type InvalidBucketConfig struct {
+	Bucket string
+	Reason string
+}
+
+func (e InvalidBucketConfig) Error() string {
+	return "Invalid bucket configuration for " + e.Bucket + ": " + e.Reason
+}
+
+// ObjectUnderLegalHold object can't be overwritten or deleted while
+// under legal hold.
+type ObjectUnderLegalHold GenericError
+
+func (e ObjectUnderLegalHold) Error() string {
+	return "Object is under legal hold: " + e.Bucket + "#" + e.Object
+}
+
+// ObjectLocked object can't be overwritten or deleted until its
+// retention period elapses.
+type ObjectLocked struct {
+	Bucket      string
+	Object      string
+	RetainUntil time.Time
+}
+
+func (e ObjectLocked) Error() string {
+	return "Object is locked until " + e.RetainUntil.Format(time.RFC3339) + ": " + e.Bucket + "#" + e.Object
+}
+
+// InvalidObjectRetention retention mode or retain until date given
+// for a new object is invalid.
+type InvalidObjectRetention GenericError
+
+func (e InvalidObjectRetention) Error() string {
+	return "Invalid object retention: " + e.Bucket + "#" + e.Object
+}
+
+// InvalidObjectTags tag set of an object exceeds the S3 limits.
+type InvalidObjectTags struct {
+	Bucket string
+	Object string
+	Reason string
+}
+
+func (e InvalidObjectTags) Error() string {
+	return "Invalid tags for object " + e.Bucket + "#" + e.Object + ": " + e.Reason
+}
+
+// ReplicationNotConfigured bucket has no replication rule.
+type ReplicationNotConfigured GenericError
+
+func (e ReplicationNotConfigured) Error() string {
+	return "Replication is not configured for bucket: " + e.Bucket
+}
+
 // BucketExists bucket exists.
 type BucketExists GenericError
 
@@ -143,6 +266,25 @@ func (e BadDigest) Error() string {
 	return "Bad digest: Expected " + e.ExpectedMD5 + " is not valid with what we calculated " + e.CalculatedMD5
 }
 
+// InvalidDigest - Content-MD5 you specified is not a valid MD5.
+type InvalidDigest struct {
+	ContentMD5 string
+}
+
+func (e InvalidDigest) Error() string {
+	return "Invalid digest: " + e.ContentMD5 + " is not a base64 encoded MD5"
+}
+
+// SHA256Mismatch - x-amz-content-sha256 you specified did not match what we received.
+type SHA256Mismatch struct {
+	ExpectedSHA256   string
+	CalculatedSHA256 string
+}
+
+func (e SHA256Mismatch) Error() string {
+	return "Bad sha256: Expected " + e.ExpectedSHA256 + " is not valid with what we calculated " + e.CalculatedSHA256
+}
+
 // UnsupportedDelimiter - unsupported delimiter.
 type UnsupportedDelimiter struct {
 	Delimiter string
@@ -152,6 +294,16 @@ func (e UnsupportedDelimiter) Error() string {
 	return fmt.Sprintf("delimiter '%s' is not supported. Only '/' is supported", e.Delimiter)
 }
 
+// UnsupportedChecksumAlgorithm - checksum algorithm requested for an
+// upload is not supported.
+type UnsupportedChecksumAlgorithm struct {
+	Algorithm string
+}
+
+func (e UnsupportedChecksumAlgorithm) Error() string {
+	return fmt.Sprintf("checksum algorithm '%s' is not supported", e.Algorithm)
+}
+
 // InvalidUploadIDKeyCombination - invalid upload id and key marker combination.
 type InvalidUploadIDKeyCombination struct {
 	UploadIDMarker, KeyMarker string
@@ -234,25 +386,231 @@ func (e InvalidUploadID) Error() string {
 	return "Invalid upload id " + e.UploadID
 }
 
-// InvalidPart One or more of the specified parts could not be found
-type InvalidPart struct{}
+// InvalidPart One or more of the specified parts could not be found,
+// PartNumber and ETag identify the first, both unset for an empty list.
+type InvalidPart struct {
+	PartNumber int
+	ETag       string
+}
 
 func (e InvalidPart) Error() string {
-	return "One or more of the specified parts could not be found"
+	if e.PartNumber == 0 && e.ETag == "" {
+		return "One or more of the specified parts could not be found"
+	}
+	return fmt.Sprintf("Part %d with ETag %s could not be found", e.PartNumber, e.ETag)
 }
 
-// InvalidPartOrder parts are not ordered as Requested
+// InvalidPartOrder parts are not ordered as Requested, PartNumber is
+// the first part not after the part before it.
 type InvalidPartOrder struct {
-	UploadID string
+	UploadID   string
+	PartNumber int
 }
 
 func (e InvalidPartOrder) Error() string {
-	return "Invalid part order sent for " + e.UploadID
+	return fmt.Sprintf("Invalid part order sent for %s, part %d isn't after the part before it", e.UploadID, e.PartNumber)
 }
 
-// PartTooSmall - error if part size is less than 5MB.
-type PartTooSmall struct{}
+// PartTooSmall - error if a part other than the last one is smaller
+// than the minimum part size, 5MB by default.
+type PartTooSmall struct {
+	PartNumber int
+	PartSize   int64
+	MinSize    int64
+}
 
 func (e PartTooSmall) Error() string {
-	return "Part size should be atleast 5MB"
+	return fmt.Sprintf("Part %d of size %d is smaller than the minimum part size %d", e.PartNumber, e.PartSize, e.MinSize)
+}
+
+// PartTooLarge - error if a part is larger than the maximum part size,
+// 5GB by default.
+type PartTooLarge struct {
+	PartNumber int
+	PartSize   int64
+	MaxSize    int64
+}
+
+func (e PartTooLarge) Error() string {
+	return fmt.Sprintf("Part %d of size %d is larger than the maximum part size %d", e.PartNumber, e.PartSize, e.MaxSize)
+}
+
+// ObjectTooLarge - error if an object is larger than the maximum object
+// size, 5GB by default.
+type ObjectTooLarge struct {
+	Bucket  string
+	Object  string
+	MaxSize int64
+}
+
+func (e ObjectTooLarge) Error() string {
+	return fmt.Sprintf("Object %s/%s is larger than the maximum object size %d", e.Bucket, e.Object, e.MaxSize)
+}
+
+// InvalidCheckpointOffset - resume offset doesn't match the last checkpoint.
+type InvalidCheckpointOffset struct {
+	Bucket string
+	Object string
+	Offset int64
+}
+
+func (e InvalidCheckpointOffset) Error() string {
+	return fmt.Sprintf("Invalid checkpoint offset %d for %s#%s", e.Offset, e.Bucket, e.Object)
+}
+
+// MaintenanceModeRequired - destructive operation attempted outside
+// maintenance mode.
+type MaintenanceModeRequired struct {
+	Operation string
+}
+
+func (e MaintenanceModeRequired) Error() string {
+	return "Operation " + e.Operation + " requires maintenance mode, call EnterMaintenanceMode() first"
+}
+
+// KeyCollision - object name collides with an existing object whose
+// name differs only in case, on a case-insensitive backend.
+type KeyCollision struct {
+	Bucket   string
+	Object   string
+	Existing string
+}
+
+func (e KeyCollision) Error() string {
+	return "Object name " + e.Bucket + "#" + e.Object + " collides with existing object " + e.Existing
+}
+
+// PreconditionFailed - current ETag of the object doesn't match the
+// expected ETag of a conditional write.
+type PreconditionFailed struct {
+	Bucket       string
+	Object       string
+	ExpectedETag string
+	ETag         string
+}
+
+func (e PreconditionFailed) Error() string {
+	return "Precondition failed for " + e.Bucket + "#" + e.Object + ", expected ETag \"" + e.ExpectedETag + "\" found \"" + e.ETag + "\""
+}
+
+// ObjectAlreadyExists - destination of an operation not allowed to
+// overwrite an object exists.
+type ObjectAlreadyExists GenericError
+
+func (e ObjectAlreadyExists) Error() string {
+	return "Object already exists: " + e.Bucket + "#" + e.Object
+}
+
+// NotModified - object still has an ETag the client holds, returned by
+// conditional reads instead of the data.
+type NotModified struct {
+	Bucket string
+	Object string
+	ETag   string
+}
+
+func (e NotModified) Error() string {
+	return "Object " + e.Bucket + "#" + e.Object + " not modified, ETag \"" + e.ETag + "\""
+}
+
+// QuotaExceeded - bucket holds its maximum number of objects, no new
+// objects can be created.
+type QuotaExceeded struct {
+	Bucket     string
+	MaxObjects int64
+}
+
+func (e QuotaExceeded) Error() string {
+	return fmt.Sprintf("Bucket %s has reached its limit of %d objects", e.Bucket, e.MaxObjects)
+}
+
+// PartMismatch - part of an upload not matching the manifest.
+type PartMismatch struct {
+	PartNumber int
+	Reason     string
+}
+
+// ManifestMismatch - parts of an upload don't match the manifest given
+// to complete it.
+type ManifestMismatch struct {
+	Bucket     string
+	Object     string
+	UploadID   string
+	Mismatches []PartMismatch
+}
+
+func (e ManifestMismatch) Error() string {
+	var reasons []string
+	for _, mismatch := range e.Mismatches {
+		reasons = append(reasons, fmt.Sprintf("part %d: %s", mismatch.PartNumber, mismatch.Reason))
+	}
+	return fmt.Sprintf("Upload %s of %s/%s doesn't match its manifest, %s", e.UploadID, e.Bucket, e.Object, strings.Join(reasons, ", "))
+}
+
+// ListCursorNotFound - list cursor doesn't exist, it was closed or
+// expired.
+type ListCursorNotFound struct {
+	CursorID string
+}
+
+func (e ListCursorNotFound) Error() string {
+	return "List cursor not found: " + e.CursorID
+}
+
+// InvalidRange - range not satisfiable by the object, also returned
+// for malformed range headers.
+type InvalidRange struct {
+	Bucket string
+	Object string
+	Offset int64
+	Size   int64
+}
+
+func (e InvalidRange) Error() string {
+	return fmt.Sprintf("Invalid range offset %d for object %s/%s of size %d", e.Offset, e.Bucket, e.Object, e.Size)
+}
+
+// InvalidContinuationToken - continuation token wasn't returned by
+// ListObjectsV2.
+type InvalidContinuationToken struct {
+	Token string
+}
+
+func (e InvalidContinuationToken) Error() string {
+	return "Invalid continuation token: " + e.Token
+}
+
+// BitRotDetected - part of a multipart object doesn't match the
+// checksum computed when it was uploaded.
+type BitRotDetected struct {
+	Bucket     string
+	Object     string
+	PartNumber int
+}
+
+func (e BitRotDetected) Error() string {
+	return fmt.Sprintf("Bit rot detected in part %d of object %s/%s", e.PartNumber, e.Bucket, e.Object)
+}
+
+// CorruptMultipartMeta - multipart meta file of an object is
+// truncated or inconsistent, typically left partially written by a
+// crash, the object can't be read until it is healed.
+type CorruptMultipartMeta struct {
+	Bucket string
+	Object string
+}
+
+func (e CorruptMultipartMeta) Error() string {
+	return "Multipart meta file of object " + e.Bucket + "/" + e.Object + " is corrupt"
+}
+
+// VersionNotFound - object has no version with this version ID.
+type VersionNotFound struct {
+	Bucket    string
+	Object    string
+	VersionID string
+}
+
+func (e VersionNotFound) Error() string {
+	return "Version " + e.VersionID + " not found: " + e.Bucket + "#" + e.Object
 }
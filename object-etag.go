@@ -0,0 +1,104 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Metadata key of the ETag saved with an object. Simple objects on both
+// layers and multipart objects on FS save it here, XL multipart objects
+// keep it in MultipartObjectInfo instead.
+const etagKey = "x-minio-etag"
+
+// Prefix of weak entity tags, never generated by the server but
+// accepted in conditional requests.
+const weakETagPrefix = "W/"
+
+// withETag - returns a copy of metadata with the ETag of the object
+// set, the caller's metadata is not modified.
+func withETag(metadata map[string]string, etag string) map[string]string {
+	objMetadata := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		objMetadata[k] = v
+	}
+	objMetadata[etagKey] = etag
+	return objMetadata
+}
+
+// formatETag - S3 form of an ETag, the quoted hex MD5 of a simple
+// object or the quoted md5-of-md5s-N of a multipart object. Empty if
+// the ETag is not known.
+func formatETag(etag string) string {
+	etag = canonicalETag(etag)
+	if etag == "" {
+		return ""
+	}
+	return "\"" + etag + "\""
+}
+
+// canonicalETag - strips the weak marker and the quotes of an ETag,
+// the form returned by PutObject and kept in ObjectInfo.MD5Sum.
+func canonicalETag(etag string) string {
+	etag = strings.TrimPrefix(strings.TrimSpace(etag), weakETagPrefix)
+	return strings.Trim(etag, "\"")
+}
+
+// isMultipartETag - verifies if an ETag is the composite ETag of a
+// multipart object, md5-of-md5s followed by "-" and the part count.
+func isMultipartETag(etag string) bool {
+	etag = canonicalETag(etag)
+	i := strings.LastIndex(etag, "-")
+	if i < 0 {
+		return false
+	}
+	parts, err := strconv.Atoi(etag[i+1:])
+	return err == nil && parts > 0
+}
+
+// etagMatches - verifies if etag matches any entity tag of a
+// conditional header, a comma separated list or "*". Strong comparison
+// never matches weak entity tags, as required for If-Match, weak
+// comparison ignores the weak marker, as used for If-None-Match.
+func etagMatches(etag, condition string, weak bool) bool {
+	etag = canonicalETag(etag)
+	for _, candidate := range strings.Split(condition, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if !weak && strings.HasPrefix(candidate, weakETagPrefix) {
+			continue
+		}
+		if etag != "" && canonicalETag(candidate) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ETag - ETag of the object in S3 form, quoted, empty if not known.
+func (o ObjectInfo) ETag() string {
+	return formatETag(o.MD5Sum)
+}
+
+// IsMultipart - verifies if the object was uploaded with a multipart
+// upload, as told by its composite ETag.
+func (o ObjectInfo) IsMultipart() bool {
+	return isMultipartETag(o.MD5Sum)
+}
@@ -0,0 +1,123 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Wrapper for calling ETag tests for both XL multiple disks and single node setup.
+func TestObjectETag(t *testing.T) {
+	ExecObjectLayerTest(t, testObjectETag)
+}
+
+// Tests validate GetObjectInfo returns the ETag returned by the upload,
+// with the part count suffix only for multipart objects.
+func testObjectETag(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello, world")
+	md5Sum, err := obj.PutObject(bucket, "simple", int64(len(data)), bytes.NewReader(data), nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var parts []completePart
+	for i, size := range []int{5 * 1024 * 1024, 17} {
+		var partMD5 string
+		partMD5, err = obj.PutObjectPart(bucket, "multipart", uploadID, i+1, int64(size), bytes.NewReader(bytes.Repeat([]byte{'a'}, size)), "")
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		parts = append(parts, completePart{PartNumber: i + 1, ETag: partMD5})
+	}
+	multipartMD5, err := obj.CompleteMultipartUpload(bucket, "multipart", uploadID, parts)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if !strings.HasSuffix(multipartMD5, "-2") || strings.Contains(md5Sum, "-") {
+		t.Fatalf("%s: Expected -2 suffix only on the multipart ETag, got %s and %s", instanceType, md5Sum, multipartMD5)
+	}
+
+	testCases := []struct {
+		object    string
+		etag      string
+		multipart bool
+	}{
+		{"simple", md5Sum, false},
+		{"multipart", multipartMD5, true},
+	}
+	for i, testCase := range testCases {
+		objInfo, err := obj.GetObjectInfo(bucket, testCase.object)
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		if objInfo.MD5Sum != testCase.etag {
+			t.Errorf("%s: Test %d: Expected MD5Sum %s, got %s", instanceType, i+1, testCase.etag, objInfo.MD5Sum)
+		}
+		if objInfo.ETag() != "\""+testCase.etag+"\"" {
+			t.Errorf("%s: Test %d: Expected quoted ETag of %s, got %s", instanceType, i+1, testCase.etag, objInfo.ETag())
+		}
+		if objInfo.IsMultipart() != testCase.multipart {
+			t.Errorf("%s: Test %d: Expected multipart %v, got %v", instanceType, i+1, testCase.multipart, objInfo.IsMultipart())
+		}
+		// Metadata only updates keep the ETag.
+		objInfo, err = obj.CopyObject(bucket, testCase.object, bucket, testCase.object, map[string]string{"content-type": "text/plain"})
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		if objInfo.MD5Sum != testCase.etag {
+			t.Errorf("%s: Test %d: Expected MD5Sum %s after a metadata update, got %s", instanceType, i+1, testCase.etag, objInfo.MD5Sum)
+		}
+	}
+}
+
+// Tests validate matching of ETags against conditional headers.
+func TestETagMatches(t *testing.T) {
+	etag := "\"d41d8cd98f00b204e9800998ecf8427e\""
+	testCases := []struct {
+		condition string
+		weak      bool
+		match     bool
+	}{
+		{"\"d41d8cd98f00b204e9800998ecf8427e\"", false, true},
+		{"d41d8cd98f00b204e9800998ecf8427e", false, true},
+		{"*", false, true},
+		{"\"other\", \"d41d8cd98f00b204e9800998ecf8427e\"", false, true},
+		{"\"other\"", false, false},
+		// Weak entity tags only match with weak comparison.
+		{"W/\"d41d8cd98f00b204e9800998ecf8427e\"", false, false},
+		{"W/\"d41d8cd98f00b204e9800998ecf8427e\"", true, true},
+		{"\"d41d8cd98f00b204e9800998ecf8427e-2\"", true, false},
+	}
+	for i, testCase := range testCases {
+		if match := etagMatches(etag, testCase.condition, testCase.weak); match != testCase.match {
+			t.Errorf("Test %d: Expected %q to match %v, got %v", i+1, testCase.condition, testCase.match, match)
+		}
+	}
+	if !isMultipartETag("\"d41d8cd98f00b204e9800998ecf8427e-12\"") || isMultipartETag(etag) {
+		t.Errorf("Expected only the ETag with a part count to be multipart")
+	}
+}
@@ -116,16 +116,29 @@ func (api objectAPIHandlers) GetObjectHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Decode content stored with an encoding the client doesn't accept.
+	decodeContent := needsContentDecoding(objInfo.ContentEncoding, r.Header.Get("Accept-Encoding"))
+
 	var hrange *httpRange
 	hrange, err = getRequestedRange(r.Header.Get("Range"), objInfo.Size)
 	if err != nil {
 		writeErrorResponse(w, r, ErrInvalidRange, r.URL.Path)
 		return
 	}
+	if decodeContent && r.Header.Get("Range") != "" {
+		// Ranges refer to the stored encoded content, they don't
+		// apply to the decoded content.
+		writeErrorResponse(w, r, ErrRangeNotDecodable, r.URL.Path)
+		return
+	}
 
-	// Get the object.
+	// Get the object, only the requested range is read.
 	startOffset := hrange.start
-	readCloser, err := api.ObjectAPI.GetObject(bucket, object, startOffset)
+	length := int64(-1)
+	if hrange.length > 0 {
+		length = hrange.length
+	}
+	readCloser, err := api.ObjectAPI.GetObjectRange(bucket, object, startOffset, length)
 	if err != nil {
 		errorIf(err, "GetObject failed.", nil)
 		apiErr := toAPIErrorCode(err)
@@ -137,20 +150,40 @@ func (api objectAPIHandlers) GetObjectHandler(w http.ResponseWriter, r *http.Req
 	}
 	defer readCloser.Close() // Close after this handler returns.
 
+	// Served content depends on the accepted encodings.
+	if isDecodableContentEncoding(objInfo.ContentEncoding) {
+		w.Header().Set("Vary", "Accept-Encoding")
+	}
+
+	var reader io.Reader = readCloser
+	if decodeContent {
+		reader, err = newContentDecodingReader(readCloser)
+		if err != nil {
+			errorIf(err, "Decoding object content failed.", nil)
+			writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+			return
+		}
+		// Stored encoding doesn't apply to the decoded content.
+		objInfo.ContentEncoding = ""
+	}
+
 	// Set standard object headers.
 	setObjectHeaders(w, objInfo, hrange)
+	if decodeContent {
+		w.Header().Del("Content-Length")
+	}
 
 	// Set any additional requested response headers.
 	setGetRespHeaders(w, r.URL.Query())
 
 	if hrange.length > 0 {
-		if _, err := io.CopyN(w, readCloser, hrange.length); err != nil {
+		if _, err := io.CopyN(w, reader, hrange.length); err != nil {
 			errorIf(err, "Writing to client failed", nil)
 			// Do not send error response here, since client could have died.
 			return
 		}
 	} else {
-		if _, err := io.Copy(w, readCloser); err != nil {
+		if _, err := io.Copy(w, reader); err != nil {
 			errorIf(err, "Writing to client failed", nil)
 			// Do not send error response here, since client could have died.
 			return
@@ -224,7 +257,7 @@ func checkETag(w http.ResponseWriter, r *http.Request) bool {
 		if r.Method != "GET" && r.Method != "HEAD" {
 			return false
 		}
-		if inm == etag || inm == "*" {
+		if etagMatches(etag, inm, true) {
 			h := w.Header()
 			// Remove following headers if already set.
 			delete(h, "Content-Type")
@@ -239,7 +272,7 @@ func checkETag(w http.ResponseWriter, r *http.Request) bool {
 		if r.Method != "GET" && r.Method != "HEAD" {
 			return false
 		}
-		if im != etag {
+		if !etagMatches(etag, im, false) {
 			h := w.Header()
 			// Remove following headers if already set.
 			delete(h, "Content-Type")
@@ -392,40 +425,20 @@ func (api objectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	startOffset := int64(0) // Read the whole file.
-	// Get the object.
-	readCloser, err := api.ObjectAPI.GetObject(sourceBucket, sourceObject, startOffset)
-	if err != nil {
-		errorIf(err, "Reading "+objectSource+" failed.", nil)
-		writeErrorResponse(w, r, toAPIErrorCode(err), objectSource)
-		return
-	}
-	// Size of object.
-	size := objInfo.Size
-
-	// Create the object.
-	md5Sum, err := api.ObjectAPI.PutObject(bucket, object, size, readCloser, nil)
+	// Copy the object on the server, keeping the source metadata.
+	objInfo, err = api.ObjectAPI.CopyObject(sourceBucket, sourceObject, bucket, object, nil)
 	if err != nil {
-		errorIf(err, "PutObject failed.", nil)
+		errorIf(err, "CopyObject failed.", nil)
 		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
 		return
 	}
 
-	objInfo, err = api.ObjectAPI.GetObjectInfo(bucket, object)
-	if err != nil {
-		errorIf(err, "GetObjectInfo failed.", nil)
-		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
-		return
-	}
-
-	response := generateCopyObjectResponse(md5Sum, objInfo.ModTime)
+	response := generateCopyObjectResponse(objInfo.MD5Sum, objInfo.ModTime)
 	encodedSuccessResponse := encodeResponse(response)
 	// write headers
 	setCommonHeaders(w)
 	// write success response.
 	writeSuccessResponse(w, encodedSuccessResponse)
-	// Explicitly close the reader, to avoid fd leaks.
-	readCloser.Close()
 }
 
 // checkCopySource implements x-amz-copy-source-if-modified-since and
@@ -491,7 +504,7 @@ func checkCopySourceETag(w http.ResponseWriter, r *http.Request) bool {
 		if r.Method != "PUT" {
 			return false
 		}
-		if inm == etag || inm == "*" {
+		if etagMatches(etag, inm, true) {
 			h := w.Header()
 			// Remove Content headers if set
 			delete(h, "Content-Type")
@@ -506,7 +519,7 @@ func checkCopySourceETag(w http.ResponseWriter, r *http.Request) bool {
 		if r.Method != "PUT" {
 			return false
 		}
-		if inm != etag {
+		if !etagMatches(etag, inm, false) {
 			h := w.Header()
 			// Remove Content headers if set
 			delete(h, "Content-Type")
@@ -553,6 +566,9 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Save metadata.
+	metadata := extractMetadataFromHeader(r.Header)
+
 	var md5Sum string
 	switch getRequestAuthType(r) {
 	default:
@@ -566,7 +582,7 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 			return
 		}
 		// Create anonymous object.
-		md5Sum, err = api.ObjectAPI.PutObject(bucket, object, size, r.Body, nil)
+		md5Sum, err = api.ObjectAPI.PutObjectWithContext(r.Context(), bucket, object, size, r.Body, metadata)
 	case authTypePresigned, authTypeSigned:
 		// Initialize a pipe for data pipe line.
 		reader, writer := io.Pipe()
@@ -600,12 +616,10 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 			writer.Close()
 		}()
 
-		// Save metadata.
-		metadata := make(map[string]string)
 		// Make sure we hex encode here.
 		metadata["md5Sum"] = hex.EncodeToString(md5Bytes)
 		// Create object.
-		md5Sum, err = api.ObjectAPI.PutObject(bucket, object, size, reader, metadata)
+		md5Sum, err = api.ObjectAPI.PutObjectWithContext(r.Context(), bucket, object, size, reader, metadata)
 	}
 	if err != nil {
 		errorIf(err, "PutObject failed.", nil)
@@ -613,7 +627,7 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 	if md5Sum != "" {
-		w.Header().Set("ETag", "\""+md5Sum+"\"")
+		w.Header().Set("ETag", formatETag(md5Sum))
 	}
 	writeSuccessResponse(w, nil)
 }
@@ -645,7 +659,10 @@ func (api objectAPIHandlers) NewMultipartUploadHandler(w http.ResponseWriter, r
 		}
 	}
 
-	uploadID, err := api.ObjectAPI.NewMultipartUpload(bucket, object)
+	// Save metadata, applied to the object on completion.
+	metadata := extractMetadataFromHeader(r.Header)
+
+	uploadID, err := api.ObjectAPI.NewMultipartUpload(bucket, object, metadata)
 	if err != nil {
 		errorIf(err, "NewMultipartUpload failed.", nil)
 		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
@@ -753,7 +770,7 @@ func (api objectAPIHandlers) PutObjectPartHandler(w http.ResponseWriter, r *http
 		return
 	}
 	if partMD5 != "" {
-		w.Header().Set("ETag", "\""+partMD5+"\"")
+		w.Header().Set("ETag", formatETag(partMD5))
 	}
 	writeSuccessResponse(w, nil)
 }
@@ -0,0 +1,111 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// errHashIncomplete - Sum() called before the object was read fully.
+var errHashIncomplete = errors.New("Object hash is not available until the object is read fully")
+
+// ObjectHashReader - reads object data and computes its hash while it
+// streams, Sum() returns the hash once the reader reached EOF.
+type ObjectHashReader struct {
+	reader io.ReadCloser
+	hasher hash.Hash
+	eof    bool
+
+	// Only set for multipart objects, hash of each part is computed
+	// to build the composite hash.
+	partSizes []int64
+	partIndex int
+	partRead  int64
+	partMD5s  []byte
+}
+
+// newObjectHashReader - wraps reader, partSizes are the sizes of the
+// parts of a multipart object in order, nil for regular objects.
+func newObjectHashReader(reader io.ReadCloser, partSizes []int64) *ObjectHashReader {
+	return &ObjectHashReader{
+		reader:    reader,
+		hasher:    md5.New(),
+		partSizes: partSizes,
+	}
+}
+
+// Read - implements io.Reader.
+func (r *ObjectHashReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.update(p[:n])
+	if err == io.EOF {
+		r.eof = true
+	}
+	return n, err
+}
+
+// update - feeds data into the hash, closing each part hash at the
+// part boundaries of multipart objects.
+func (r *ObjectHashReader) update(data []byte) {
+	if r.partSizes == nil {
+		r.hasher.Write(data)
+		return
+	}
+	for len(data) > 0 && r.partIndex < len(r.partSizes) {
+		n := r.partSizes[r.partIndex] - r.partRead
+		if int64(len(data)) < n {
+			n = int64(len(data))
+		}
+		r.hasher.Write(data[:n])
+		r.partRead += n
+		data = data[n:]
+		if r.partRead == r.partSizes[r.partIndex] {
+			r.partMD5s = r.hasher.Sum(r.partMD5s)
+			r.hasher.Reset()
+			r.partIndex++
+			r.partRead = 0
+		}
+	}
+}
+
+// Close - closes the underlying reader.
+func (r *ObjectHashReader) Close() error {
+	return r.reader.Close()
+}
+
+// Sum - returns the hex encoded md5 of the object, for multipart
+// objects the composite md5 of all parts suffixed with the number of
+// parts, matching the ETag of the object.
+func (r *ObjectHashReader) Sum() (string, error) {
+	if !r.eof {
+		return "", errHashIncomplete
+	}
+	if r.partSizes == nil {
+		return hex.EncodeToString(r.hasher.Sum(nil)), nil
+	}
+	if r.partIndex != len(r.partSizes) {
+		return "", errHashIncomplete
+	}
+	md5Hasher := md5.New()
+	md5Hasher.Write(r.partMD5s)
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(md5Hasher.Sum(nil)), len(r.partSizes)), nil
+}
@@ -0,0 +1,105 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+// Wrapper for calling GetObjectWithHash tests for both XL multiple disks and single node setup.
+func TestGetObjectWithHash(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testGetObjectWithHash)
+}
+
+// Tests validate the hash of simple and multipart objects read with
+// GetObjectWithHash matches their ETag, and is only available once the
+// object is read fully.
+func testGetObjectWithHash(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	simpleData := []byte("hello, world")
+	if _, err := obj.PutObject(bucket, "simple", int64(len(simpleData)), bytes.NewReader(simpleData), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var multipartData []byte
+	var parts []completePart
+	for i, size := range []int{5 * 1024 * 1024, 17} {
+		data := bytes.Repeat([]byte{byte('a' + i)}, size)
+		var partMD5 string
+		partMD5, err = obj.PutObjectPart(bucket, "multipart", uploadID, i+1, int64(size), bytes.NewReader(data), "")
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		parts = append(parts, completePart{PartNumber: i + 1, ETag: partMD5})
+		multipartData = append(multipartData, data...)
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, "multipart", uploadID, parts); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	testCases := []struct {
+		object string
+		data   []byte
+	}{
+		{"simple", simpleData},
+		{"multipart", multipartData},
+	}
+	for i, testCase := range testCases {
+		objInfo, err := obj.GetObjectInfo(bucket, testCase.object)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		reader, err := obj.GetObjectWithHash(bucket, testCase.object)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		if _, err = reader.Sum(); err != errHashIncomplete {
+			t.Errorf("%s: Test %d: Expected %v before reading, got %v", instanceType, i+1, errHashIncomplete, err)
+		}
+		data, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		if !bytes.Equal(data, testCase.data) {
+			t.Fatalf("%s: Test %d: Content mismatch", instanceType, i+1)
+		}
+		sum, err := reader.Sum()
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err)
+		}
+		if sum != objInfo.MD5Sum {
+			t.Errorf("%s: Test %d: Expected hash %s, got %s", instanceType, i+1, objInfo.MD5Sum, sum)
+		}
+	}
+
+	if _, err = obj.GetObjectWithHash(bucket, "missing"); err == nil {
+		t.Fatalf("%s: Expected ObjectNotFound", instanceType)
+	} else if _, ok := err.(ObjectNotFound); !ok {
+		t.Fatalf("%s: Expected ObjectNotFound, got %v", instanceType, err)
+	}
+}
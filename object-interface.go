@@ -16,28 +16,103 @@
 
 package main
 
-import "io"
+import (
+	"context"
+	"io"
+)
 
 // ObjectLayer implements primitives for object API layer.
 type ObjectLayer interface {
 	// Bucket operations.
 	MakeBucket(bucket string) error
+	MakeBucketWithLocation(bucket, region string) error
+	GetBucketLocation(bucket string) (region string, err error)
+	SetAllowedRegions(regions []string) error
 	GetBucketInfo(bucket string) (bucketInfo BucketInfo, err error)
+	GetBucketStats(bucket string) (stats BucketStats, err error)
+	BucketExists(bucket string) (exists bool, err error)
 	ListBuckets() (buckets []BucketInfo, err error)
 	DeleteBucket(bucket string) error
+	DeleteBucketForce(bucket string) error
+	DeleteBucketForceDryRun(bucket string) ([]string, error)
 	ListObjects(bucket, prefix, marker, delimiter string, maxKeys int) (result ListObjectsInfo, err error)
+	ListObjectsWithMetadata(bucket, prefix, marker, delimiter string, maxKeys int) (result ListObjectsInfo, err error)
+	ListObjectVersions(bucket, prefix, keyMarker, versionIDMarker string, maxKeys int) (result ListObjectVersionsInfo, err error)
+	ListObjectsV2(bucket, prefix, continuationToken, startAfter, delimiter string, maxKeys int) (result ListObjectsV2Info, err error)
+	ListObjectShards(bucket, prefix string, numShards int) (shards []KeyRange, err error)
+	ListObjectsFiltered(bucket, prefix, marker string, filter func(name string) bool, maxKeys int) (result ListObjectsInfo, err error)
+	ListObjectsGlob(bucket, prefix, marker, pattern string, maxKeys int) (result ListObjectsInfo, err error)
+	WalkObjects(ctx context.Context, bucket, prefix string) (objects <-chan ObjectInfo, errs <-chan error)
+	OpenListCursor(bucket, prefix, delimiter string) (cursorID string, err error)
+	ListNext(cursorID string, maxKeys int) (result ListObjectsInfo, err error)
+	CloseListCursor(cursorID string) error
+	SetBucketAllowedPrefixes(bucket string, prefixes []string) error
+	GetBucketAllowedPrefixes(bucket string) (prefixes []string, err error)
+	ExportBucketConfig(bucket string) (data []byte, err error)
+	ImportBucketConfig(bucket string, data []byte) error
+	SetBucketReplication(bucket, target, targetBucket string) error
+	SetBucketObjectLimit(bucket string, maxObjects int64) error
+	SetBucketDedup(bucket string, enabled bool) error
+	SetBucketVersioning(bucket string, enabled bool) error
+	BucketBandwidthStats(bucket string) (ingressBytes, egressBytes int64)
+	ResetBucketBandwidthStats(bucket string)
+
+	// Storage scheduling.
+	WithContext(ctx context.Context) ObjectLayer
+	StorageQueueDepths() map[string]int
+	Shutdown() error
 
 	// Object operations.
 	GetObject(bucket, object string, startOffset int64) (reader io.ReadCloser, err error)
+	GetObjectWithContext(ctx context.Context, bucket, object string, startOffset int64) (reader io.ReadCloser, err error)
+	GetObjectWithOptions(bucket, object string, startOffset int64, opts GetObjectOptions) (reader *DegradedReader, err error)
+	GetObjectRange(bucket, object string, startOffset, length int64) (reader io.ReadCloser, err error)
+	WriteObjectTo(bucket, object string, startOffset int64, w io.Writer) (n int64, err error)
+	GetObjectTail(bucket, object string, n int64) (reader io.ReadCloser, err error)
+	GetObjectRanges(bucket, object string, ranges [][2]int64) (reader io.ReadCloser, parts []ObjectRangePart, err error)
+	GetObjectConditional(bucket, object string, startOffset int64, cond ObjectConditions) (reader io.ReadCloser, err error)
+	GetObjectWithHash(bucket, object string) (reader *ObjectHashReader, err error)
+	GetObjectVerifiedReaderAt(bucket, object string) (readerAt io.ReaderAt, size int64, err error)
 	GetObjectInfo(bucket, object string) (objInfo ObjectInfo, err error)
+	GetObjectsInfo(bucket string, objects []string) (objInfos []ObjectInfo, errs []error)
+	GetObjectVersion(bucket, object, versionID string, startOffset int64) (reader io.ReadCloser, err error)
+	GetObjectVersionInfo(bucket, object, versionID string) (objInfo ObjectInfo, err error)
 	PutObject(bucket, object string, size int64, data io.Reader, metadata map[string]string) (md5 string, err error)
+	PutObjectWithContext(ctx context.Context, bucket, object string, size int64, data io.Reader, metadata map[string]string) (md5 string, err error)
+	PutObjectWithChecksums(bucket, object string, size int64, data io.Reader, metadata map[string]string) (result PutObjectResult, err error)
+	PutObjectFromFile(bucket, object, srcPath string, metadata map[string]string) (result PutObjectResult, err error)
+	PutObjectConditional(bucket, object string, size int64, data io.Reader, metadata map[string]string, cond ObjectConditions) (md5 string, err error)
+	AppendObject(bucket, object string, data io.Reader) (objInfo ObjectInfo, err error)
+	CopyObject(srcBucket, srcObject, dstBucket, dstObject string, metadata map[string]string) (objInfo ObjectInfo, err error)
+	RenameObject(bucket, srcObject, dstObject string, overwrite bool) error
+	FindDuplicateMoves() (duplicates []DuplicateMove, err error)
 	DeleteObject(bucket, object string) error
+	DeleteObjects(bucket string, objects []string) ([]error, error)
+	DeleteObjectDryRun(bucket, object string) ([]string, error)
+	DeleteObjectsDryRun(bucket string, objects []string) ([]string, []error, error)
+	GetPutObjectCheckpoint(bucket, object string) (offset int64, err error)
+	ReplicateObject(bucket, object string) error
+	CompareAndSwapObject(bucket, object string, expectedETag string, newData io.Reader, size int64) (newETag string, err error)
+	UpdateMetadataPrefix(bucket, prefix string, update func(ObjectInfo) map[string]string, workers int) (results []MetadataUpdateResult, err error)
+	PutObjectLegalHold(bucket, object string, on bool) error
+	GetObjectLegalHold(bucket, object string) (on bool, err error)
+	PutObjectTags(bucket, object string, tags map[string]string) error
+	GetObjectTags(bucket, object string) (tags map[string]string, err error)
+	DeleteObjectTags(bucket, object string) error
+	RegisterContentType(ext, contentType string) error
+	SetMaxObjectSize(maxSize int64) error
+	MaxObjectSize() int64
+	SetMaxListKeys(maxKeys int) error
+	MaxListKeys() int
 
 	// Multipart operations.
+	SetPartSizeLimits(minSize, maxSize int64) error
+	PartSizeLimits() (minSize, maxSize int64)
 	ListMultipartUploads(bucket, prefix, keyMarker, uploadIDMarker, delimiter string, maxUploads int) (result ListMultipartsInfo, err error)
-	NewMultipartUpload(bucket, object string) (uploadID string, err error)
+	NewMultipartUpload(bucket, object string, metadata map[string]string) (uploadID string, err error)
 	PutObjectPart(bucket, object, uploadID string, partID int, size int64, data io.Reader, md5Hex string) (md5 string, err error)
 	ListObjectParts(bucket, object, uploadID string, partNumberMarker int, maxParts int) (result ListPartsInfo, err error)
 	AbortMultipartUpload(bucket, object, uploadID string) error
 	CompleteMultipartUpload(bucket, object, uploadID string, uploadedParts []completePart) (md5 string, err error)
+	CompleteMultipartUploadWithManifest(bucket, object, uploadID string, uploadedParts []completePart, manifest []manifestPart) (md5 string, err error)
 }
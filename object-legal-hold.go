@@ -0,0 +1,79 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+const (
+	// Object metadata key of the legal hold flag.
+	legalHoldKey = "x-minio-legal-hold"
+	// Value of legalHoldKey while the hold is on.
+	legalHoldOn = "ON"
+)
+
+// checkObjectMutable - verifies that an object can be overwritten or
+// deleted, returns ObjectUnderLegalHold if it is under legal hold and
+// ObjectLocked if its retention hasn't elapsed. A legal hold applies
+// even if bypassGovernance is set. Objects which don't exist yet are
+// mutable.
+func checkObjectMutable(storage StorageAPI, bucket, object string, bypassGovernance bool) error {
+	metadata, err := readObjectMetadata(storage, bucket, object)
+	if err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+	if metadata[legalHoldKey] == legalHoldOn {
+		return ObjectUnderLegalHold{Bucket: bucket, Object: object}
+	}
+	return checkObjectRetention(storage, bucket, object, metadata, bypassGovernance)
+}
+
+// putObjectLegalHold - common function to place or remove the legal
+// hold of an object for both object layers.
+func putObjectLegalHold(layer ObjectLayer, storage StorageAPI, bucket, object string, on bool) error {
+	unlock := lockObject(bucket, object)
+	defer unlock()
+
+	// Verify if the object exists, also validates the names.
+	if _, err := layer.GetObjectInfo(bucket, object); err != nil {
+		return err
+	}
+	metadata, err := readObjectMetadata(storage, bucket, object)
+	if err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+	if on {
+		metadata[legalHoldKey] = legalHoldOn
+	} else {
+		delete(metadata, legalHoldKey)
+	}
+	if err = saveObjectMetadata(storage, bucket, object, metadata); err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+	return nil
+}
+
+// getObjectLegalHold - common function to get the legal hold status of
+// an object for both object layers.
+func getObjectLegalHold(layer ObjectLayer, storage StorageAPI, bucket, object string) (bool, error) {
+	// Verify if the object exists, also validates the names.
+	if _, err := layer.GetObjectInfo(bucket, object); err != nil {
+		return false, err
+	}
+	metadata, err := readObjectMetadata(storage, bucket, object)
+	if err != nil {
+		return false, toObjectErr(err, bucket, object)
+	}
+	return metadata[legalHoldKey] == legalHoldOn, nil
+}
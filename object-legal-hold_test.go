@@ -0,0 +1,128 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Wrapper for calling object legal hold tests for both XL multiple disks and single node setup.
+func TestObjectLegalHold(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testObjectLegalHold)
+}
+
+// Tests validate an object under legal hold can't be overwritten,
+// deleted or renamed, either way, and can once the hold is removed.
+func testObjectLegalHold(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello, world")
+	for _, object := range []string{"held", "other"} {
+		if _, err := obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	checkLegalHold := func(step string, expected bool) {
+		on, err := obj.GetObjectLegalHold(bucket, "held")
+		if err != nil {
+			t.Fatalf("%s: %s: %s", instanceType, step, err.Error())
+		}
+		if on != expected {
+			t.Fatalf("%s: %s: Expected legal hold %v, got %v", instanceType, step, expected, on)
+		}
+	}
+	checkLegalHold("Initial", false)
+
+	if err := obj.PutObjectLegalHold(bucket, "held", true); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	checkLegalHold("Placed", true)
+
+	newData := []byte("new data")
+	testCases := []struct {
+		name string
+		op   func() error
+	}{
+		{"PutObject", func() error {
+			_, err := obj.PutObject(bucket, "held", int64(len(newData)), bytes.NewReader(newData), nil)
+			return err
+		}},
+		{"DeleteObject", func() error {
+			return obj.DeleteObject(bucket, "held")
+		}},
+		// Renaming deletes the object at its old name.
+		{"RenameObject source", func() error {
+			return obj.RenameObject(bucket, "held", "renamed", false)
+		}},
+		{"RenameObject destination", func() error {
+			return obj.RenameObject(bucket, "other", "held", true)
+		}},
+	}
+	for _, testCase := range testCases {
+		if err := testCase.op(); err == nil {
+			t.Fatalf("%s: %s: Expected ObjectUnderLegalHold", instanceType, testCase.name)
+		} else if _, ok := err.(ObjectUnderLegalHold); !ok {
+			t.Fatalf("%s: %s: Expected ObjectUnderLegalHold, got %v", instanceType, testCase.name, err)
+		}
+	}
+	// The held object is left as is.
+	objInfo, err := obj.GetObjectInfo(bucket, "held")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if objInfo.Size != int64(len(data)) {
+		t.Fatalf("%s: Expected size %d, got %d", instanceType, len(data), objInfo.Size)
+	}
+	if _, err = obj.GetObjectInfo(bucket, "other"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	checkLegalHold("Refused", true)
+
+	if err = obj.PutObjectLegalHold(bucket, "held", false); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	checkLegalHold("Removed", false)
+	if _, err = obj.PutObject(bucket, "held", int64(len(newData)), bytes.NewReader(newData), nil); err != nil {
+		t.Fatalf("%s: PutObject: %s", instanceType, err.Error())
+	}
+	if err = obj.RenameObject(bucket, "held", "renamed", false); err != nil {
+		t.Fatalf("%s: RenameObject: %s", instanceType, err.Error())
+	}
+	if objInfo, err = obj.GetObjectInfo(bucket, "renamed"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	} else if objInfo.Size != int64(len(newData)) {
+		t.Fatalf("%s: Expected size %d, got %d", instanceType, len(newData), objInfo.Size)
+	}
+	if err = obj.DeleteObject(bucket, "renamed"); err != nil {
+		t.Fatalf("%s: DeleteObject: %s", instanceType, err.Error())
+	}
+	if _, err = obj.GetObjectInfo(bucket, "renamed"); err == nil {
+		t.Fatalf("%s: Expected the deleted object to be gone", instanceType)
+	}
+
+	// A missing object has no legal hold to place.
+	if err = obj.PutObjectLegalHold(bucket, "missing", true); err == nil {
+		t.Fatalf("%s: Expected ObjectNotFound", instanceType)
+	} else if _, ok := err.(ObjectNotFound); !ok {
+		t.Fatalf("%s: Expected ObjectNotFound, got %v", instanceType, err)
+	}
+}
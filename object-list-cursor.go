@@ -0,0 +1,269 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/skyrings/skyring-common/tools/uuid"
+)
+
+// Inactivity after which a list cursor is closed.
+var listCursorExpiry = 5 * time.Minute
+
+// listCursor - state of a paginated listing kept on the server, pins
+// the tree walker so that each page continues where the previous
+// one stopped.
+type listCursor struct {
+	mutex     *sync.Mutex
+	bucket    string
+	prefix    string
+	delimiter string
+	recursive bool
+	// Name of the last entry returned, the walk restarts after it
+	// if the walker timed out.
+	marker   string
+	walker   *treeWalker
+	eof      bool
+	lastUsed time.Time
+}
+
+// listCursors - open list cursors of an object layer by cursor id.
+type listCursors struct {
+	mutex   *sync.Mutex
+	cursors map[string]*listCursor
+	once    *sync.Once
+}
+
+// newListCursors - initialize list cursors, expired cursors are
+// reaped once the first cursor is opened.
+func newListCursors() *listCursors {
+	return &listCursors{
+		mutex:   &sync.Mutex{},
+		cursors: make(map[string]*listCursor),
+		once:    &sync.Once{},
+	}
+}
+
+// open - saves cursor under a new cursor id.
+func (c *listCursors) open(cursor *listCursor) (string, error) {
+	id, err := uuid.New()
+	if err != nil {
+		return "", err
+	}
+	cursorID := id.String()
+	cursor.lastUsed = time.Now().UTC()
+	c.mutex.Lock()
+	c.cursors[cursorID] = cursor
+	c.mutex.Unlock()
+	c.once.Do(func() {
+		go c.run()
+	})
+	return cursorID, nil
+}
+
+// lookup - returns the cursor of cursorID, nil if it was closed or
+// expired.
+func (c *listCursors) lookup(cursorID string) *listCursor {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	cursor, ok := c.cursors[cursorID]
+	if !ok {
+		return nil
+	}
+	cursor.lastUsed = time.Now().UTC()
+	return cursor
+}
+
+// close - forgets the cursor of cursorID, returns false if there
+// is none.
+func (c *listCursors) close(cursorID string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.cursors[cursorID]; !ok {
+		return false
+	}
+	delete(c.cursors, cursorID)
+	return true
+}
+
+// reap - closes cursors unused since before expiry. Their walkers
+// aren't consumed anymore and time out on their own.
+func (c *listCursors) reap(expiry time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for cursorID, cursor := range c.cursors {
+		if cursor.lastUsed.Before(expiry) {
+			delete(c.cursors, cursorID)
+		}
+	}
+}
+
+// run - reaps expired cursors forever.
+func (c *listCursors) run() {
+	for range time.Tick(listCursorExpiry / 2) {
+		c.reap(time.Now().UTC().Add(-listCursorExpiry))
+	}
+}
+
+// openListCursorCommon - open a list cursor on bucket, common
+// function for both object layers.
+func openListCursorCommon(layer ObjectLayer, bucket, prefix, delimiter string) (string, error) {
+	var storage StorageAPI
+	var cursors *listCursors
+	switch l := layer.(type) {
+	case xlObjects:
+		storage = l.storage
+		cursors = l.listCursors
+	case fsObjects:
+		storage = l.storage
+		cursors = l.listCursors
+	}
+
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return "", BucketNameInvalid{Bucket: bucket}
+	}
+	// Verify whether the bucket exists.
+	if !isBucketExist(storage, bucket) {
+		return "", BucketNotFound{Bucket: bucket}
+	}
+	if !IsValidObjectPrefix(prefix) {
+		return "", ObjectNameInvalid{Bucket: bucket, Object: prefix}
+	}
+	// Verify if delimiter is anything other than '/', which we do not support.
+	if delimiter != "" && delimiter != slashSeparator {
+		return "", UnsupportedDelimiter{
+			Delimiter: delimiter,
+		}
+	}
+	if cursors == nil {
+		return "", errInvalidArgument
+	}
+	// Default is recursive, if delimiter is set then list non recursive.
+	recursive := delimiter != slashSeparator
+	cursorID, err := cursors.open(&listCursor{
+		mutex:     &sync.Mutex{},
+		bucket:    bucket,
+		prefix:    prefix,
+		delimiter: delimiter,
+		recursive: recursive,
+		walker:    startTreeWalk(layer, bucket, prefix, "", recursive),
+	})
+	if err != nil {
+		return "", toObjectErr(err, bucket, prefix)
+	}
+	return cursorID, nil
+}
+
+// listNextCommon - list the next maxKeys entries of a list cursor,
+// common function for both object layers.
+func listNextCommon(layer ObjectLayer, cursorID string, maxKeys int) (ListObjectsInfo, error) {
+	var cursors *listCursors
+	switch l := layer.(type) {
+	case xlObjects:
+		cursors = l.listCursors
+	case fsObjects:
+		cursors = l.listCursors
+	}
+	var cursor *listCursor
+	if cursors != nil {
+		cursor = cursors.lookup(cursorID)
+	}
+	if cursor == nil {
+		return ListObjectsInfo{}, ListCursorNotFound{CursorID: cursorID}
+	}
+
+	// Pages of a cursor are read one at a time.
+	cursor.mutex.Lock()
+	defer cursor.mutex.Unlock()
+
+	if cursor.eof || maxKeys == 0 {
+		return ListObjectsInfo{IsTruncated: !cursor.eof}, nil
+	}
+	// Over flowing count - reset to maxObjectList.
+	if maxKeys < 0 || maxKeys > maxObjectList {
+		maxKeys = maxObjectList
+	}
+	if cursor.walker.timedOut {
+		// Idle for longer than the walker waits for its reader,
+		// continue with a new walk after the last entry returned.
+		cursor.walker = startTreeWalk(layer, cursor.bucket, cursor.prefix, cursor.marker, cursor.recursive)
+	}
+
+	result := ListObjectsInfo{}
+	for i := 0; i < maxKeys; {
+		walkResult, ok := <-cursor.walker.ch
+		if !ok {
+			if cursor.walker.timedOut {
+				// Timed out while buffered results were read.
+				cursor.walker = startTreeWalk(layer, cursor.bucket, cursor.prefix, cursor.marker, cursor.recursive)
+				continue
+			}
+			// Closed channel.
+			cursor.eof = true
+			break
+		}
+		if walkResult.err != nil {
+			// File not found is a valid case.
+			if walkResult.err == errFileNotFound {
+				cursor.eof = true
+				break
+			}
+			return ListObjectsInfo{}, toObjectErr(walkResult.err, cursor.bucket, cursor.prefix)
+		}
+		fileInfo := walkResult.fileInfo
+		cursor.marker = fileInfo.Name
+		if fileInfo.Mode.IsDir() {
+			result.Prefixes = append(result.Prefixes, fileInfo.Name)
+		} else {
+			result.Objects = append(result.Objects, ObjectInfo{
+				Name:    fileInfo.Name,
+				ModTime: fileInfo.ModTime,
+				Size:    fileInfo.Size,
+				IsDir:   false,
+			})
+		}
+		if walkResult.end {
+			cursor.eof = true
+			break
+		}
+		i++
+	}
+	result.IsTruncated = !cursor.eof
+	if cursor.delimiter == slashSeparator && cursor.marker != "" {
+		result.NextMarker = cursor.marker
+	}
+	return result, nil
+}
+
+// closeListCursorCommon - close a list cursor, common function for
+// both object layers.
+func closeListCursorCommon(layer ObjectLayer, cursorID string) error {
+	var cursors *listCursors
+	switch l := layer.(type) {
+	case xlObjects:
+		cursors = l.listCursors
+	case fsObjects:
+		cursors = l.listCursors
+	}
+	if cursors == nil || !cursors.close(cursorID) {
+		return ListCursorNotFound{CursorID: cursorID}
+	}
+	return nil
+}
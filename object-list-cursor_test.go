@@ -0,0 +1,170 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// Wrapper for calling list cursor tests for both XL multiple disks and single node setup.
+func TestListCursor(t *testing.T) {
+	ExecObjectLayerTest(t, testListCursor)
+}
+
+// Tests validate paging through a bucket with a list cursor, with and
+// without delimiter, and the cursor lifecycle.
+func testListCursor(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var names []string
+	for i := 0; i < 10; i++ {
+		names = append(names, fmt.Sprintf("dir/object%d", i))
+	}
+	names = append(names, "top")
+	for _, name := range names {
+		if _, err := obj.PutObject(bucket, name, 1, bytes.NewReader([]byte("a")), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+
+	// Recursive listing in pages of 3.
+	cursorID, err := obj.OpenListCursor(bucket, "", "")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var listed []string
+	for {
+		result, err := obj.ListNext(cursorID, 3)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		for _, objInfo := range result.Objects {
+			listed = append(listed, objInfo.Name)
+		}
+		if !result.IsTruncated {
+			break
+		}
+		if len(result.Objects) != 3 {
+			t.Fatalf("%s: Expected 3 objects in a truncated page, got %d", instanceType, len(result.Objects))
+		}
+	}
+	if fmt.Sprint(listed) != fmt.Sprint(names) {
+		t.Fatalf("%s: Expected %v, got %v", instanceType, names, listed)
+	}
+	// Exhausted cursors keep returning empty pages until closed.
+	if result, err := obj.ListNext(cursorID, 3); err != nil || len(result.Objects) != 0 || result.IsTruncated {
+		t.Fatalf("%s: Expected an empty last page, got %v, %v", instanceType, result, err)
+	}
+	if err = obj.CloseListCursor(cursorID); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.ListNext(cursorID, 3); err == nil {
+		t.Fatalf("%s: Expected ListCursorNotFound after close", instanceType)
+	} else if _, ok := err.(ListCursorNotFound); !ok {
+		t.Fatalf("%s: Expected ListCursorNotFound, got %v", instanceType, err)
+	}
+	if _, ok := obj.CloseListCursor(cursorID).(ListCursorNotFound); !ok {
+		t.Fatalf("%s: Expected ListCursorNotFound closing twice", instanceType)
+	}
+
+	// Delimited listing returns common prefixes.
+	cursorID, err = obj.OpenListCursor(bucket, "", "/")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	result, err := obj.ListNext(cursorID, 10)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(result.Prefixes) != 1 || result.Prefixes[0] != "dir/" || len(result.Objects) != 1 || result.Objects[0].Name != "top" || result.IsTruncated {
+		t.Fatalf("%s: Unexpected delimited listing %+v", instanceType, result)
+	}
+
+	// Invalid arguments.
+	if _, err = obj.OpenListCursor("missing-bucket", "", ""); err == nil {
+		t.Fatalf("%s: Expected BucketNotFound", instanceType)
+	}
+	if _, err = obj.OpenListCursor(bucket, "", "*"); err == nil {
+		t.Fatalf("%s: Expected UnsupportedDelimiter", instanceType)
+	}
+}
+
+// Tests validate a cursor continues after its last entry once its
+// walker timed out, and that idle cursors are reaped.
+func TestListCursorTimedOutWalker(t *testing.T) {
+	initNSLock()
+	directory, err := ioutil.TempDir("", "minio-list-cursor-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	obj, err := newFSObjects(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := obj.(fsObjects)
+	if err = fs.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err = fs.PutObject("bucket", fmt.Sprintf("object%d", i), 1, bytes.NewReader([]byte("a")), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cursorID, err := fs.OpenListCursor("bucket", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = fs.ListNext(cursorID, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace the walker with one which timed out.
+	ch := make(chan treeWalkResult)
+	close(ch)
+	fs.listCursors.lookup(cursorID).walker = &treeWalker{ch: ch, timedOut: true}
+	result, err := fs.ListNext(cursorID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed []string
+	for _, objInfo := range result.Objects {
+		listed = append(listed, objInfo.Name)
+	}
+	if fmt.Sprint(listed) != "[object2 object3 object4]" || result.IsTruncated {
+		t.Fatalf("Expected the listing to continue after object1, got %v", listed)
+	}
+
+	// Cursors unused since the expiry are reaped.
+	fs.listCursors.reap(time.Now().UTC().Add(-time.Hour))
+	if fs.listCursors.lookup(cursorID) == nil {
+		t.Fatal("Cursor used within the expiry was reaped")
+	}
+	fs.listCursors.reap(time.Now().UTC().Add(time.Hour))
+	if _, err = fs.ListNext(cursorID, 10); err == nil {
+		t.Fatal("Expected ListCursorNotFound for a reaped cursor")
+	} else if _, ok := err.(ListCursorNotFound); !ok {
+		t.Fatalf("Expected ListCursorNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "strings"
+
+// globMatch - matches name against pattern, '*' matches any sequence
+// of characters including '/' and '?' matches a single character.
+func globMatch(pattern, name string) bool {
+	// Position to resume from after the last '*', -1 if none yet.
+	starPattern, starName := -1, 0
+	p, n := 0, 0
+	for n < len(name) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == name[n]):
+			p++
+			n++
+		case p < len(pattern) && pattern[p] == '*':
+			starPattern, starName = p, n
+			p++
+		case starPattern != -1:
+			// Let the last '*' absorb one more character.
+			starName++
+			p, n = starPattern+1, starName
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// globFilter - returns a filter matching the part of object names
+// after prefix against pattern.
+func globFilter(prefix, pattern string) func(string) bool {
+	return func(name string) bool {
+		return globMatch(pattern, strings.TrimPrefix(name, prefix))
+	}
+}
+
+// listObjectsFilteredCommon - common function to recursively list the
+// objects under prefix matching filter for both object layers, starting
+// after marker. The filter is applied during the tree walk, so objects
+// not matching are never looked up.
+func listObjectsFilteredCommon(layer ObjectLayer, bucket, prefix, marker string, filter func(string) bool, maxKeys int) (ListObjectsInfo, error) {
+	var storage StorageAPI
+	switch l := layer.(type) {
+	case xlObjects:
+		storage = l.storage
+	case fsObjects:
+		storage = l.storage
+	}
+
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return ListObjectsInfo{}, BucketNameInvalid{Bucket: bucket}
+	}
+	// Verify whether the bucket exists.
+	if !isBucketExist(storage, bucket) {
+		return ListObjectsInfo{}, BucketNotFound{Bucket: bucket}
+	}
+	if !IsValidObjectPrefix(prefix) {
+		return ListObjectsInfo{}, ObjectNameInvalid{Bucket: bucket, Object: prefix}
+	}
+	// Verify if marker has prefix.
+	if marker != "" && !strings.HasPrefix(marker, prefix) {
+		return ListObjectsInfo{}, InvalidMarkerPrefixCombination{
+			Marker: marker,
+			Prefix: prefix,
+		}
+	}
+	if maxKeys == 0 {
+		return ListObjectsInfo{}, nil
+	}
+	// Over flowing count - reset to maxObjectList.
+	if maxKeys < 0 || maxKeys > maxObjectList {
+		maxKeys = maxObjectList
+	}
+
+	// Filtered walks are never saved for reuse, later listings with
+	// the same parameters can have a different filter.
+	walker := startFilteredTreeWalk(layer, bucket, prefix, marker, true, filter)
+	result := ListObjectsInfo{}
+	for len(result.Objects) < maxKeys {
+		walkResult, ok := <-walker.ch
+		if !ok {
+			// Closed channel.
+			return result, nil
+		}
+		if walkResult.err != nil {
+			// File not found is a valid case.
+			if walkResult.err == errFileNotFound {
+				return ListObjectsInfo{}, nil
+			}
+			return ListObjectsInfo{}, toObjectErr(walkResult.err, bucket, prefix)
+		}
+		result.Objects = append(result.Objects, listedObjectInfo(walkResult.fileInfo))
+		if walkResult.end {
+			return result, nil
+		}
+	}
+	// Truncated only if another matching object follows, the walker
+	// isn't reused so it can be consumed.
+	if walkResult, ok := <-walker.ch; !ok || walkResult.err != nil {
+		return result, nil
+	}
+	result.IsTruncated = true
+	result.NextMarker = result.Objects[len(result.Objects)-1].Name
+	return result, nil
+}
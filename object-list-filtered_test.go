@@ -0,0 +1,139 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tests validate glob patterns.
+func TestGlobMatch(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		// Literal patterns.
+		{"", "", true},
+		{"", "a", false},
+		{"abc", "abc", true},
+		{"abc", "abd", false},
+		{"abc", "abcd", false},
+		// '?' matches exactly one character.
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"???", "a/b", true},
+		// '*' matches any sequence, including '/'.
+		{"*", "", true},
+		{"*", "dir/obj", true},
+		{"*.txt", "a.txt", true},
+		{"*.txt", "dir/a.txt", true},
+		{"*.txt", "a.txt.gz", false},
+		{"a*b*c", "aXbYc", true},
+		{"a*b*c", "abcbc", true},
+		{"a*b*c", "acb", false},
+		{"dir/*/obj", "dir/x/y/obj", true},
+		{"**", "anything", true},
+		{"*?", "", false},
+		{"*a", "banana", true},
+		{"*an", "banana", false},
+	}
+	for i, testCase := range testCases {
+		if match := globMatch(testCase.pattern, testCase.name); match != testCase.match {
+			t.Errorf("Test %d: Expected globMatch(%q, %q) to be %v, got %v", i+1, testCase.pattern, testCase.name, testCase.match, match)
+		}
+	}
+}
+
+// Tests validate filtered and glob listings page through every matching
+// object by their NextMarker.
+func TestListObjectsFiltered(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testListObjectsFiltered)
+}
+
+func testListObjectsFiltered(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	objects := []string{
+		"logs/a.txt", "logs/b.gz", "logs/c.txt", "logs/old/d.txt",
+		"logs/old/e.gz", "logs/old/f.txt", "logs/z.txt", "other/g.txt",
+	}
+	for _, object := range objects {
+		if _, err := obj.PutObject(bucket, object, int64(len(object)), bytes.NewBufferString(object), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	expected := []string{"logs/a.txt", "logs/c.txt", "logs/old/d.txt", "logs/old/f.txt", "logs/z.txt"}
+
+	listings := map[string]func(marker string) (ListObjectsInfo, error){
+		"filtered": func(marker string) (ListObjectsInfo, error) {
+			return obj.ListObjectsFiltered(bucket, "logs/", marker, func(name string) bool {
+				return strings.HasSuffix(name, ".txt")
+			}, 2)
+		},
+		"glob": func(marker string) (ListObjectsInfo, error) {
+			return obj.ListObjectsGlob(bucket, "logs/", marker, "*.txt", 2)
+		},
+	}
+	for name, list := range listings {
+		var listed []string
+		marker := ""
+		for pages := 0; ; pages++ {
+			if pages > len(objects) {
+				t.Fatalf("%s: %s listing doesn't end", instanceType, name)
+			}
+			result, err := list(marker)
+			if err != nil {
+				t.Fatalf("%s : %s", instanceType, err.Error())
+			}
+			if len(result.Objects) > 2 {
+				t.Fatalf("%s: %s listing: Expected at most 2 objects, got %d", instanceType, name, len(result.Objects))
+			}
+			for _, objInfo := range result.Objects {
+				listed = append(listed, objInfo.Name)
+			}
+			if !result.IsTruncated {
+				break
+			}
+			marker = result.NextMarker
+		}
+		if !reflect.DeepEqual(listed, expected) {
+			t.Errorf("%s: %s listing: Expected %v, got %v", instanceType, name, expected, listed)
+		}
+	}
+
+	// A marker not matching the filter is valid.
+	result, err := obj.ListObjectsGlob(bucket, "logs/", "logs/old/e.gz", "*.txt", 10)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(result.Objects) != 2 || result.Objects[0].Name != "logs/old/f.txt" || result.IsTruncated {
+		t.Errorf("%s: Unexpected listing after logs/old/e.gz: %v", instanceType, result.Objects)
+	}
+
+	// The marker must be under prefix.
+	if _, err = obj.ListObjectsGlob(bucket, "logs/", "other/g.txt", "*", 10); err == nil {
+		t.Errorf("%s: Expected an error for a marker outside of the prefix", instanceType)
+	}
+}
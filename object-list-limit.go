@@ -0,0 +1,68 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "sync"
+
+// listKeysLimit - most objects and prefixes a single ListObjects
+// call returns, larger requests are clamped and come back truncated.
+type listKeysLimit struct {
+	mutex   *sync.Mutex
+	maxKeys int
+}
+
+// newListKeysLimit - initialize the list limit to the S3 limit.
+func newListKeysLimit() *listKeysLimit {
+	return &listKeysLimit{
+		mutex:   &sync.Mutex{},
+		maxKeys: maxObjectList,
+	}
+}
+
+// get - returns the maximum keys listed at once.
+func (l *listKeysLimit) get() int {
+	// Object layers opened for recovery use the S3 limit.
+	if l == nil {
+		return maxObjectList
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.maxKeys
+}
+
+// set - changes the maximum keys listed at once, fails with
+// errInvalidArgument unless maxKeys is positive.
+func (l *listKeysLimit) set(maxKeys int) error {
+	if maxKeys <= 0 {
+		return errInvalidArgument
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.maxKeys = maxKeys
+	return nil
+}
+
+// clamp - number of keys to list for a request of maxKeys, requests
+// asking for none or a negative number get the limit like requests
+// over it.
+func (l *listKeysLimit) clamp(maxKeys int) int {
+	limit := l.get()
+	if maxKeys <= 0 || maxKeys > limit {
+		return limit
+	}
+	return maxKeys
+}
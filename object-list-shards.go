@@ -0,0 +1,80 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+// listObjectShardsCommon - common function for both object layers,
+// splits the objects under prefix into at most numShards ranges, each
+// range holding roughly the same number of objects. The ranges can be
+// listed in parallel.
+func listObjectShardsCommon(layer ObjectLayer, bucket, prefix string, numShards int) ([]KeyRange, error) {
+	var storage StorageAPI
+	switch l := layer.(type) {
+	case xlObjects:
+		storage = l.storage
+	case fsObjects:
+		storage = l.storage
+	}
+
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, BucketNameInvalid{Bucket: bucket}
+	}
+	// Verify whether the bucket exists.
+	if !isBucketExist(storage, bucket) {
+		return nil, BucketNotFound{Bucket: bucket}
+	}
+	if !IsValidObjectPrefix(prefix) {
+		return nil, ObjectNameInvalid{Bucket: bucket, Object: prefix}
+	}
+	if numShards <= 1 {
+		return []KeyRange{{}}, nil
+	}
+
+	// Count every object under prefix, not only the entries of the
+	// prefix directory, so that a large sub-directory is split too.
+	var keys []string
+	marker := ""
+	for {
+		result, err := layer.ListObjects(bucket, prefix, marker, "", maxObjectList)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range result.Objects {
+			keys = append(keys, object.Name)
+		}
+		if !result.IsTruncated {
+			break
+		}
+		marker = result.NextMarker
+	}
+	if len(keys) < numShards {
+		numShards = len(keys)
+	}
+	if numShards <= 1 {
+		return []KeyRange{{}}, nil
+	}
+
+	shards := make([]KeyRange, numShards)
+	for i := 1; i < numShards; i++ {
+		// Listing with the last key of the previous range as marker
+		// starts right at the first key of this range.
+		boundary := i * len(keys) / numShards
+		shards[i-1].End = keys[boundary]
+		shards[i].Marker = keys[boundary-1]
+	}
+	return shards, nil
+}
@@ -0,0 +1,124 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// Tests validate ListObjectShards balances the ranges by object count
+// and that listing every range covers each object exactly once.
+func TestListObjectShards(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testListObjectShards)
+}
+
+func testListObjectShards(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	// Most objects live in a single directory, "dir-x" sorts between
+	// "dir" and its contents.
+	objects := []string{"a", "dir-x"}
+	for i := 0; i < 10; i++ {
+		objects = append(objects, "dir/"+strconv.Itoa(i))
+	}
+	objects = append(objects, "e")
+	for _, object := range objects {
+		if _, err := obj.PutObject(bucket, object, int64(len(object)), bytes.NewBufferString(object), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	// Page the listings to cover truncated results.
+	if err := obj.SetMaxListKeys(3); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	defer obj.SetMaxListKeys(maxObjectList)
+
+	listShard := func(shard KeyRange) []string {
+		var listed []string
+		marker := shard.Marker
+		for {
+			result, err := obj.ListObjects(bucket, "", marker, "", 1000)
+			if err != nil {
+				t.Fatalf("%s : %s", instanceType, err.Error())
+			}
+			for _, objInfo := range result.Objects {
+				if shard.End != "" && objInfo.Name >= shard.End {
+					return listed
+				}
+				listed = append(listed, objInfo.Name)
+			}
+			if !result.IsTruncated {
+				return listed
+			}
+			marker = result.NextMarker
+		}
+	}
+
+	for _, numShards := range []int{1, 2, 4, 13, 20} {
+		shards, err := obj.ListObjectShards(bucket, "", numShards)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		wantShards := numShards
+		if wantShards > len(objects) {
+			wantShards = len(objects)
+		}
+		if len(shards) != wantShards {
+			t.Fatalf("%s: Expected %d shards, got %d", instanceType, wantShards, len(shards))
+		}
+		var listed []string
+		for i, shard := range shards {
+			keys := listShard(shard)
+			// Every range holds its share of the objects.
+			if min, max := len(objects)/wantShards, (len(objects)+wantShards-1)/wantShards; len(keys) < min || len(keys) > max {
+				t.Errorf("%s: Shard %d of %d: Expected %d to %d objects, got %v", instanceType, i, wantShards, min, max, keys)
+			}
+			listed = append(listed, keys...)
+		}
+		if !reflect.DeepEqual(listed, objects) {
+			t.Errorf("%s: %d shards: Expected %v, got %v", instanceType, numShards, objects, listed)
+		}
+	}
+
+	// Shards of a prefix only cover the objects under it.
+	shards, err := obj.ListObjectShards(bucket, "dir/", 2)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(shards) != 2 || shards[0].End != "dir/5" || shards[1].Marker != "dir/4" {
+		t.Errorf("%s: Unexpected shards %v for prefix dir/", instanceType, shards)
+	}
+
+	// Empty buckets and missing prefixes make a single range.
+	shards, err = obj.ListObjectShards(bucket, "missing/", 4)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if !reflect.DeepEqual(shards, []KeyRange{{}}) {
+		t.Errorf("%s: Expected a single range, got %v", instanceType, shards)
+	}
+	if _, err = obj.ListObjectShards("missing-bucket", "", 4); err == nil {
+		t.Errorf("%s: Expected an error for a missing bucket", instanceType)
+	}
+}
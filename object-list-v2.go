@@ -0,0 +1,64 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "encoding/base64"
+
+// encodeContinuationToken - returns the opaque continuation token of
+// a listing position, the token only depends on the marker so that it
+// is valid across restarts.
+func encodeContinuationToken(marker string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(marker))
+}
+
+// decodeContinuationToken - returns the marker of a continuation token.
+func decodeContinuationToken(token string) (string, error) {
+	marker, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(marker) == 0 {
+		return "", InvalidContinuationToken{Token: token}
+	}
+	return string(marker), nil
+}
+
+// listObjectsV2Common - list objects with continuation tokens, common
+// function for both object layers. The token is mapped to a marker so
+// that listings reuse the saved tree walks of listObjectsCommon,
+// startAfter is only used without a token.
+func listObjectsV2Common(layer ObjectLayer, bucket, prefix, continuationToken, startAfter, delimiter string, maxKeys int) (ListObjectsV2Info, error) {
+	marker := startAfter
+	if continuationToken != "" {
+		var err error
+		if marker, err = decodeContinuationToken(continuationToken); err != nil {
+			return ListObjectsV2Info{}, err
+		}
+	}
+	result, err := listObjectsCommon(layer, bucket, prefix, marker, delimiter, maxKeys, false)
+	if err != nil {
+		return ListObjectsV2Info{}, err
+	}
+	v2Result := ListObjectsV2Info{
+		IsTruncated:       result.IsTruncated,
+		ContinuationToken: continuationToken,
+		KeyCount:          len(result.Objects) + len(result.Prefixes),
+		Objects:           result.Objects,
+		Prefixes:          result.Prefixes,
+	}
+	if result.IsTruncated {
+		v2Result.NextContinuationToken = encodeContinuationToken(result.NextMarker)
+	}
+	return v2Result, nil
+}
@@ -0,0 +1,107 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// Wrapper for calling ListObjectsV2 tests for both XL multiple disks and single node setup.
+func TestListObjectsV2(t *testing.T) {
+	ExecObjectLayerTest(t, testListObjectsV2)
+}
+
+// Tests validate paging with continuation tokens, StartAfter and
+// invalid tokens.
+func testListObjectsV2(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var names []string
+	for i := 0; i < 7; i++ {
+		name := fmt.Sprintf("dir/object%d", i)
+		if _, err := obj.PutObject(bucket, name, 1, bytes.NewReader([]byte("a")), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		names = append(names, name)
+	}
+
+	// Page through with tokens.
+	var listed []string
+	token := ""
+	for {
+		result, err := obj.ListObjectsV2(bucket, "", token, "", "", 3)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		if result.ContinuationToken != token {
+			t.Fatalf("%s: Expected ContinuationToken %q, got %q", instanceType, token, result.ContinuationToken)
+		}
+		if result.KeyCount != len(result.Objects) {
+			t.Fatalf("%s: Expected KeyCount %d, got %d", instanceType, len(result.Objects), result.KeyCount)
+		}
+		for _, objInfo := range result.Objects {
+			listed = append(listed, objInfo.Name)
+		}
+		if !result.IsTruncated {
+			if result.NextContinuationToken != "" {
+				t.Fatalf("%s: Expected no NextContinuationToken on the last page", instanceType)
+			}
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	if fmt.Sprint(listed) != fmt.Sprint(names) {
+		t.Fatalf("%s: Expected %v, got %v", instanceType, names, listed)
+	}
+
+	// Tokens only depend on the position.
+	first, err := obj.ListObjectsV2(bucket, "", "", "", "", 3)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if first.NextContinuationToken != encodeContinuationToken("dir/object2") {
+		t.Fatalf("%s: Unexpected token %q", instanceType, first.NextContinuationToken)
+	}
+
+	// StartAfter is used without a token.
+	result, err := obj.ListObjectsV2(bucket, "", "", "dir/object4", "", 10)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if result.KeyCount != 2 || result.Objects[0].Name != "dir/object5" {
+		t.Fatalf("%s: Expected objects after dir/object4, got %+v", instanceType, result.Objects)
+	}
+
+	// Delimited listings count prefixes.
+	result, err = obj.ListObjectsV2(bucket, "", "", "", "/", 10)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if result.KeyCount != 1 || len(result.Prefixes) != 1 {
+		t.Fatalf("%s: Expected a single prefix, got %+v", instanceType, result)
+	}
+
+	if _, err = obj.ListObjectsV2(bucket, "", "not a token!", "", "", 10); err == nil {
+		t.Fatalf("%s: Expected InvalidContinuationToken", instanceType)
+	} else if _, ok := err.(InvalidContinuationToken); !ok {
+		t.Fatalf("%s: Expected InvalidContinuationToken, got %v", instanceType, err)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "context"
+
+// walkObjectsCommon - common function for both object layers to stream
+// the objects under prefix recursively, in the order ListObjects lists
+// them. The objects channel is closed once the walk ends, after which
+// the error channel yields the error which ended it, if any. Cancelling
+// ctx stops the walk, the error is then ctx.Err().
+func walkObjectsCommon(ctx context.Context, layer ObjectLayer, bucket, prefix string) (<-chan ObjectInfo, <-chan error) {
+	var storage StorageAPI
+	switch l := layer.(type) {
+	case xlObjects:
+		storage = l.storage
+	case fsObjects:
+		storage = l.storage
+	}
+
+	objCh := make(chan ObjectInfo)
+	errCh := make(chan error, 1)
+	fail := func(err error) (<-chan ObjectInfo, <-chan error) {
+		close(objCh)
+		errCh <- err
+		close(errCh)
+		return objCh, errCh
+	}
+
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return fail(BucketNameInvalid{Bucket: bucket})
+	}
+	// Verify whether the bucket exists.
+	if !isBucketExist(storage, bucket) {
+		return fail(BucketNotFound{Bucket: bucket})
+	}
+	if !IsValidObjectPrefix(prefix) {
+		return fail(ObjectNameInvalid{Bucket: bucket, Object: prefix})
+	}
+
+	// The walk is bound to ctx instead of being saved for reuse, it is
+	// stopped rather than timed out when the consumer goes away.
+	walker := startCancelableTreeWalk(layer, bucket, prefix, "", true, nil, ctx.Done())
+	go func() {
+		err := func() error {
+			for {
+				var walkResult treeWalkResult
+				var ok bool
+				select {
+				case walkResult, ok = <-walker.ch:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				if !ok {
+					// Closed channel, early only when ctx is done.
+					return ctx.Err()
+				}
+				if walkResult.err != nil {
+					// File not found is a valid case.
+					if walkResult.err == errFileNotFound {
+						return nil
+					}
+					return toObjectErr(walkResult.err, bucket, prefix)
+				}
+				select {
+				case objCh <- listedObjectInfo(walkResult.fileInfo):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				if walkResult.end {
+					return nil
+				}
+			}
+		}()
+		close(objCh)
+		if err != nil {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+	return objCh, errCh
+}
@@ -0,0 +1,107 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+// Tests validate WalkObjects streaming, cancellation and errors.
+func TestWalkObjects(t *testing.T) {
+	ExecObjectLayerTest(t, testWalkObjects)
+}
+
+func testWalkObjects(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	objects := []string{"a", "dir/b", "dir/c", "dir/sub/d", "e"}
+	for _, object := range objects {
+		if _, err := obj.PutObject(bucket, object, int64(len(object)), bytes.NewBufferString(object), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+
+	// The walk must list exactly what ListObjects lists.
+	listed, err := obj.ListObjects(bucket, "", "", "", 1000)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	objCh, errCh := obj.WalkObjects(context.Background(), bucket, "")
+	var walked []ObjectInfo
+	for objInfo := range objCh {
+		walked = append(walked, objInfo)
+	}
+	if err = <-errCh; err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(walked) != len(listed.Objects) {
+		t.Fatalf("%s: Expected %d objects, got %d", instanceType, len(listed.Objects), len(walked))
+	}
+	for i, objInfo := range walked {
+		if objInfo.Name != listed.Objects[i].Name || objInfo.Size != listed.Objects[i].Size {
+			t.Errorf("%s: Expected object %d to be %s, got %s", instanceType, i, listed.Objects[i].Name, objInfo.Name)
+		}
+	}
+
+	// A prefix restricts the walk.
+	objCh, errCh = obj.WalkObjects(context.Background(), bucket, "dir/")
+	walked = nil
+	for objInfo := range objCh {
+		walked = append(walked, objInfo)
+	}
+	if err = <-errCh; err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(walked) != 3 || walked[0].Name != "dir/b" || walked[2].Name != "dir/sub/d" {
+		t.Fatalf("%s: Unexpected objects under prefix %v", instanceType, walked)
+	}
+
+	// A prefix matching nothing is an empty walk.
+	objCh, errCh = obj.WalkObjects(context.Background(), bucket, "missing/")
+	if _, ok := <-objCh; ok {
+		t.Fatalf("%s: Expected no objects under a missing prefix", instanceType)
+	}
+	if err = <-errCh; err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	// Cancelling stops the walk after the first object.
+	ctx, cancel := context.WithCancel(context.Background())
+	objCh, errCh = obj.WalkObjects(ctx, bucket, "")
+	if objInfo := <-objCh; objInfo.Name != "a" {
+		t.Fatalf("%s: Expected first object a, got %s", instanceType, objInfo.Name)
+	}
+	cancel()
+	for range objCh {
+	}
+	if err = <-errCh; err != context.Canceled {
+		t.Fatalf("%s: Expected %v, got %v", instanceType, context.Canceled, err)
+	}
+
+	// Errors are returned on the error channel.
+	objCh, errCh = obj.WalkObjects(context.Background(), "missing-bucket", "")
+	if _, ok := <-objCh; ok {
+		t.Fatalf("%s: Expected no objects for a missing bucket", instanceType)
+	}
+	if _, ok := (<-errCh).(BucketNotFound); !ok {
+		t.Fatalf("%s: Expected BucketNotFound", instanceType)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+// updateObjectMetadata - applies update to the metadata of an object
+// under the object lock, the object data is not rewritten. Keys
+// returned by update are set, keys with an empty value are removed.
+func updateObjectMetadata(layer ObjectLayer, storage StorageAPI, bucket, object string, update func(ObjectInfo) map[string]string) (bool, error) {
+	unlock := lockObject(bucket, object)
+	defer unlock()
+
+	objInfo, err := layer.GetObjectInfo(bucket, object)
+	if err != nil {
+		return false, err
+	}
+	changes := update(objInfo)
+	if len(changes) == 0 {
+		return false, nil
+	}
+	metadata, err := readObjectMetadata(storage, bucket, object)
+	if err != nil {
+		return false, toObjectErr(err, bucket, object)
+	}
+	for k, v := range changes {
+		if internalMetadataKeys[k] || protectedMetadataKeys[k] {
+			continue
+		}
+		if v == "" {
+			delete(metadata, k)
+			continue
+		}
+		metadata[k] = v
+	}
+	if err = saveObjectMetadata(storage, bucket, object, metadata); err != nil {
+		return false, toObjectErr(err, bucket, object)
+	}
+	return true, nil
+}
+
+// updateMetadataPrefixCommon - common function to update the metadata
+// of all objects under prefix for both object layers, using workers
+// concurrent updates. Results are sorted by object name.
+func updateMetadataPrefixCommon(layer ObjectLayer, bucket, prefix string, update func(ObjectInfo) map[string]string, workers int) ([]MetadataUpdateResult, error) {
+	var storage StorageAPI
+	switch l := layer.(type) {
+	case xlObjects:
+		storage = l.storage
+	case fsObjects:
+		storage = l.storage
+	}
+
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, BucketNameInvalid{Bucket: bucket}
+	}
+	// Verify whether the bucket exists.
+	if !isBucketExist(storage, bucket) {
+		return nil, BucketNotFound{Bucket: bucket}
+	}
+	if !IsValidObjectPrefix(prefix) {
+		return nil, ObjectNameInvalid{Bucket: bucket, Object: prefix}
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	objects := make(chan string)
+	resultCh := make(chan MetadataUpdateResult)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for object := range objects {
+				updated, err := updateObjectMetadata(layer, storage, bucket, object, update)
+				resultCh <- MetadataUpdateResult{Object: object, Updated: updated, Err: err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(resultCh)
+	}()
+
+	// Feed the workers from the tree walk, the walker isn't saved for
+	// reuse since it is always drained.
+	walkErr := make(chan error, 1)
+	go func() {
+		defer close(objects)
+		walker := startTreeWalk(layer, bucket, prefix, "", true)
+		for walkResult := range walker.ch {
+			if walkResult.err != nil {
+				// File not found is a valid case.
+				if walkResult.err != errFileNotFound {
+					walkErr <- toObjectErr(walkResult.err, bucket, prefix)
+				}
+				return
+			}
+			objects <- walkResult.fileInfo.Name
+		}
+	}()
+
+	var results []MetadataUpdateResult
+	for result := range resultCh {
+		results = append(results, result)
+	}
+	sort.Sort(byMetadataUpdateObject(results))
+	select {
+	case err := <-walkErr:
+		return results, err
+	default:
+	}
+	return results, nil
+}
+
+// byMetadataUpdateObject - sorts metadata update results by object name.
+type byMetadataUpdateObject []MetadataUpdateResult
+
+func (r byMetadataUpdateObject) Len() int           { return len(r) }
+func (r byMetadataUpdateObject) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r byMetadataUpdateObject) Less(i, j int) bool { return r[i].Object < r[j].Object }
@@ -0,0 +1,170 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// Wrapper for calling metadata update tests for both XL multiple disks and single node setup.
+func TestUpdateObjectMetadata(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testUpdateObjectMetadata)
+}
+
+// Tests validate metadata updates set and remove user keys without
+// touching the data or the keys owned by other operations.
+func testUpdateObjectMetadata(obj ObjectLayer, instanceType string, t *testing.T) {
+	var storage StorageAPI
+	switch l := obj.(type) {
+	case xlObjects:
+		storage = l.storage
+	case fsObjects:
+		storage = l.storage
+	}
+	bucket := "minio-bucket"
+	object := "object"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello, world")
+	metadata := map[string]string{"x-amz-meta-a": "1", "x-amz-meta-b": "2"}
+	if _, err := obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), metadata); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	before, err := obj.GetObjectInfo(bucket, object)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	// No changes leave the object as is.
+	updated, err := updateObjectMetadata(obj, storage, bucket, object, func(ObjectInfo) map[string]string {
+		return nil
+	})
+	if err != nil || updated {
+		t.Fatalf("%s: Expected no update, got %t, %v", instanceType, updated, err)
+	}
+
+	updated, err = updateObjectMetadata(obj, storage, bucket, object, func(objInfo ObjectInfo) map[string]string {
+		if objInfo.UserDefined["x-amz-meta-a"] != "1" {
+			t.Errorf("%s: Expected the current metadata, got %v", instanceType, objInfo.UserDefined)
+		}
+		return map[string]string{
+			"x-amz-meta-c": "3",
+			// Empty values remove the key.
+			"x-amz-meta-b": "",
+			// Keys owned by other operations are ignored.
+			etagKey:      "bogus",
+			legalHoldKey: "ON",
+			"md5Sum":     "bogus",
+		}
+	})
+	if err != nil || !updated {
+		t.Fatalf("%s: Expected an update, got %t, %v", instanceType, updated, err)
+	}
+	after, err := obj.GetObjectInfo(bucket, object)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	expected := map[string]string{"x-amz-meta-a": "1", "x-amz-meta-c": "3"}
+	if !reflect.DeepEqual(after.UserDefined, expected) {
+		t.Errorf("%s: Expected metadata %v, got %v", instanceType, expected, after.UserDefined)
+	}
+	if after.MD5Sum != before.MD5Sum || after.Size != before.Size {
+		t.Errorf("%s: Expected ETag %s and size %d, got %s and %d", instanceType, before.MD5Sum, before.Size, after.MD5Sum, after.Size)
+	}
+	if legalHold, err := obj.GetObjectLegalHold(bucket, object); err != nil || legalHold {
+		t.Errorf("%s: Expected no legal hold, got %t, %v", instanceType, legalHold, err)
+	}
+	reader, err := obj.GetObject(bucket, object, 0)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	got, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("%s: Expected the object data unchanged, got %q, %v", instanceType, got, err)
+	}
+
+	// Missing objects fail before update is called.
+	_, err = updateObjectMetadata(obj, storage, bucket, "missing", func(ObjectInfo) map[string]string {
+		t.Errorf("%s: Update called for a missing object", instanceType)
+		return nil
+	})
+	if _, ok := err.(ObjectNotFound); !ok {
+		t.Errorf("%s: Expected ObjectNotFound, got %v", instanceType, err)
+	}
+}
+
+// Wrapper for calling prefix metadata update tests for both XL multiple disks and single node setup.
+func TestUpdateMetadataPrefix(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testUpdateMetadataPrefix)
+}
+
+// Tests validate every object under the prefix is updated and reported
+// in name order, objects outside of it are left alone.
+func testUpdateMetadataPrefix(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	objects := []string{"logs/c", "logs/a", "logs/dir/b", "other"}
+	for _, object := range objects {
+		if _, err := obj.PutObject(bucket, object, int64(len(object)), bytes.NewBufferString(object), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	update := func(objInfo ObjectInfo) map[string]string {
+		if objInfo.Name == "logs/c" {
+			// Already up to date.
+			return nil
+		}
+		return map[string]string{"x-amz-meta-owner": "me"}
+	}
+	results, err := obj.UpdateMetadataPrefix(bucket, "logs/", update, 2)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	expected := []MetadataUpdateResult{
+		{Object: "logs/a", Updated: true},
+		{Object: "logs/c", Updated: false},
+		{Object: "logs/dir/b", Updated: true},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("%s: Expected %v, got %v", instanceType, expected, results)
+	}
+	for _, object := range objects {
+		objInfo, err := obj.GetObjectInfo(bucket, object)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		owned := objInfo.UserDefined["x-amz-meta-owner"] == "me"
+		if want := object == "logs/a" || object == "logs/dir/b"; owned != want {
+			t.Errorf("%s: Expected %s updated %t, got %t", instanceType, object, want, owned)
+		}
+	}
+
+	if _, err = obj.UpdateMetadataPrefix("missing-bucket", "", update, 2); err == nil {
+		t.Errorf("%s: Expected BucketNotFound", instanceType)
+	}
+}
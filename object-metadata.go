@@ -0,0 +1,273 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+
+	"github.com/minio/minio/pkg/mimedb"
+)
+
+const (
+	// Object metadata prefix, inside minioMetaBucket.
+	objectMetaPrefix = "meta"
+	// Suffix of the sidecar metadata files.
+	objectMetaSuffix = ".minio.meta"
+	// Extended attribute holding object metadata.
+	objectMetaXattr = "user.minio.meta"
+	// Metadata key of the content type given at upload.
+	contentTypeKey = "content-type"
+)
+
+// objectMetaStorage - optional capability of storage backends which
+// can keep object metadata along with the data file, such as
+// extended attributes.
+type objectMetaStorage interface {
+	SetFileMetadata(volume, path string, metadata map[string]string) error
+	GetFileMetadata(volume, path string) (map[string]string, error)
+}
+
+// Internal keys passed in PutObject metadata, never saved.
+var internalMetadataKeys = map[string]bool{
+	"md5Sum":              true,
+	"contentMd5":          true,
+	"sha256Sum":           true,
+	checkpointIntervalKey: true,
+	checkpointOffsetKey:   true,
+	checksumAlgorithmKey:  true,
+	compressKey:           true,
+	taggingDirectiveKey:   true,
+	durableKey:            true,
+}
+
+// Keys only changed through their dedicated operations, never by
+// generic metadata updates.
+var protectedMetadataKeys = map[string]bool{
+	etagKey:          true,
+	partsKey:         true,
+	legalHoldKey:     true,
+	retentionModeKey: true,
+	retainUntilKey:   true,
+	tagsKey:          true,
+	versionIDKey:     true,
+	// Compressed, encrypted and deduplicated data is only readable
+	// along with these.
+	compressionKey:         true,
+	actualSizeKey:          true,
+	compressBlocksKey:      true,
+	encryptionKey:          true,
+	encryptionKeyIDKey:     true,
+	encryptionSealedKeyKey: true,
+	encryptionIVKey:        true,
+	partsEncryptionKey:     true,
+	dedupBlobKey:           true,
+	dedupSizeKey:           true,
+}
+
+// objectMetaSidecar - path of the sidecar metadata file of an object.
+func objectMetaSidecar(bucket, object string) string {
+	return path.Join(objectMetaPrefix, bucket, object+objectMetaSuffix)
+}
+
+// hasObjectMetaSuffix - returns true if any component of the object
+// name ends with the reserved sidecar suffix.
+func hasObjectMetaSuffix(object string) bool {
+	for _, component := range strings.Split(object, slashSeparator) {
+		if strings.HasSuffix(component, objectMetaSuffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeObjectMetadata - saves metadata of an object, as extended
+// attributes if the storage supports it and the metadata fits,
+// otherwise as a sidecar file. Internal keys are not saved.
+func writeObjectMetadata(storage StorageAPI, bucket, object string, metadata map[string]string) error {
+	objMetadata := make(map[string]string)
+	for k, v := range metadata {
+		if internalMetadataKeys[k] {
+			continue
+		}
+		objMetadata[k] = v
+	}
+	if len(objMetadata) == 0 {
+		return nil
+	}
+	if metaStorage, ok := storage.(objectMetaStorage); ok {
+		err := metaStorage.SetFileMetadata(bucket, object, objMetadata)
+		if err == errXattrTooLarge {
+			// Clear metadata saved earlier, it would hide the sidecar.
+			err = metaStorage.SetFileMetadata(bucket, object, map[string]string{})
+			if err == nil {
+				err = errXattrTooLarge
+			}
+		}
+		if err != errXattrNotSupported && err != errXattrTooLarge {
+			return err
+		}
+	}
+	return writeMetadataFile(storage, objectMetaSidecar(bucket, object), objMetadata)
+}
+
+// stageObjectMetadata - writes metadata of an object about to be
+// renamed in place to a sidecar file at stagePath in minioMetaBucket,
+// see replaceObject. The file is written even if no metadata is left
+// to save, so that it replaces any sidecar of an earlier object.
+func stageObjectMetadata(storage StorageAPI, stagePath string, metadata map[string]string) error {
+	objMetadata := make(map[string]string)
+	for k, v := range metadata {
+		if !internalMetadataKeys[k] {
+			objMetadata[k] = v
+		}
+	}
+	return writeMetadataFile(storage, stagePath, objMetadata)
+}
+
+// writeMetadataFile - writes metadata as a sidecar file at sidecar in
+// minioMetaBucket.
+func writeMetadataFile(storage StorageAPI, sidecar string, metadata map[string]string) error {
+	w, err := storage.CreateFile(minioMetaBucket, sidecar)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	if err = encoder.Encode(metadata); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	if err = w.Close(); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	return nil
+}
+
+// readObjectMetadata - reads metadata of an object from extended
+// attributes or from the sidecar file, whichever the object used.
+// Returns an empty metadata if the object has none.
+func readObjectMetadata(storage StorageAPI, bucket, object string) (map[string]string, error) {
+	if metaStorage, ok := storage.(objectMetaStorage); ok {
+		metadata, err := metaStorage.GetFileMetadata(bucket, object)
+		// Empty extended attributes are left by metadata too large
+		// for them, saved in the sidecar file instead.
+		if err == nil && len(metadata) != 0 {
+			return metadata, nil
+		}
+		if err != nil && err != errXattrNotSupported && err != errFileNotFound {
+			return nil, err
+		}
+	}
+	offset := int64(0)
+	r, err := storage.ReadFile(minioMetaBucket, objectMetaSidecar(bucket, object), offset)
+	if err != nil {
+		// No sidecar can exist for names too long to hold the suffix.
+		if err == errFileNotFound || err == errFileNameTooLong {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+	defer r.Close()
+	metadata := make(map[string]string)
+	decoder := json.NewDecoder(r)
+	if err = decoder.Decode(&metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// saveObjectMetadata - replaces the saved metadata of an existing
+// object, unlike writeObjectMetadata an empty metadata clears it.
+func saveObjectMetadata(storage StorageAPI, bucket, object string, metadata map[string]string) error {
+	if len(metadata) != 0 {
+		return writeObjectMetadata(storage, bucket, object, metadata)
+	}
+	// Nothing left to save, clear whichever way the metadata was saved.
+	if metaStorage, ok := storage.(objectMetaStorage); ok {
+		if err := metaStorage.SetFileMetadata(bucket, object, metadata); err != nil && err != errXattrNotSupported {
+			return err
+		}
+	}
+	return deleteObjectMetadata(storage, bucket, object)
+}
+
+// deleteObjectMetadata - removes the sidecar metadata file of an
+// object if any, extended attributes go away with the data file.
+func deleteObjectMetadata(storage StorageAPI, bucket, object string) error {
+	sidecar := objectMetaSidecar(bucket, object)
+	if _, err := storage.StatFile(minioMetaBucket, sidecar); err != nil {
+		if err == errFileNotFound {
+			return nil
+		}
+		return err
+	}
+	return storage.DeleteFile(minioMetaBucket, sidecar)
+}
+
+// deleteBucketObjectMetadata - removes the sidecar metadata files of
+// all objects of a bucket.
+func deleteBucketObjectMetadata(storage StorageAPI, bucket string) error {
+	return cleanupDir(storage, minioMetaBucket, path.Join(objectMetaPrefix, bucket))
+}
+
+// userDefinedMetadata - returns the saved metadata of an object set by
+// its uploader, without the keys kept for the object layer itself.
+// Never nil, objects saved without metadata have an empty map.
+func userDefinedMetadata(metadata map[string]string) map[string]string {
+	userDefined := make(map[string]string)
+	for k, v := range metadata {
+		if internalMetadataKeys[k] || protectedMetadataKeys[k] {
+			continue
+		}
+		switch strings.ToLower(k) {
+		case contentTypeKey, contentEncodingKey, replicationStatusKey:
+			// Returned in their own ObjectInfo fields.
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(k), "x-minio-") {
+			continue
+		}
+		userDefined[k] = v
+	}
+	return userDefined
+}
+
+// getContentType - returns the content type saved with an object,
+// guesses it from the object extension only if none was saved. Types
+// registered on the object layer are looked up before mimedb.
+func getContentType(object string, metadata map[string]string, types *contentTypes) string {
+	for key, value := range metadata {
+		if value != "" && strings.EqualFold(key, contentTypeKey) {
+			return value
+		}
+	}
+	if contentType, ok := types.lookup(object); ok {
+		return contentType
+	}
+	if objectExt := path.Ext(object); objectExt != "" {
+		if content, ok := mimedb.DB[strings.ToLower(strings.TrimPrefix(objectExt, "."))]; ok {
+			return content.ContentType
+		}
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,130 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sidecarTestStorage - storage unable to keep object metadata along
+// with the data file, metadata is saved in sidecar files.
+type sidecarTestStorage struct {
+	StorageAPI
+}
+
+// newObjectMetadataTestStorage - returns a posix storage with a bucket
+// holding an empty object.
+func newObjectMetadataTestStorage(t *testing.T, bucket, object string) (StorageAPI, string) {
+	path, err := ioutil.TempDir(os.TempDir(), "minio-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage, err := newPosix(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, volume := range []string{bucket, minioMetaBucket} {
+		if err = storage.MakeVol(volume); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w, err := storage.CreateFile(bucket, object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return storage, path
+}
+
+// Tests validate object metadata is saved as extended attributes, and
+// in a sidecar file once too large for them.
+func TestObjectMetadataXattr(t *testing.T) {
+	bucket, object := "bucket", "object"
+	storage, path := newObjectMetadataTestStorage(t, bucket, object)
+	defer os.RemoveAll(path)
+	if !storage.(fsStorage).xattrSupported {
+		t.Skip("Extended attributes not supported by the temp dir filesystem")
+	}
+	sidecar := objectMetaSidecar(bucket, object)
+
+	metadata := map[string]string{"content-type": "text/plain"}
+	if err := writeObjectMetadata(storage, bucket, object, metadata); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := storage.(objectMetaStorage).GetFileMetadata(bucket, object); err != nil || !reflect.DeepEqual(got, metadata) {
+		t.Fatalf("Expected %v in extended attributes, got %v, %v", metadata, got, err)
+	}
+	if _, err := storage.StatFile(minioMetaBucket, sidecar); err != errFileNotFound {
+		t.Fatalf("Expected no sidecar, got %v", err)
+	}
+	if got, err := readObjectMetadata(storage, bucket, object); err != nil || !reflect.DeepEqual(got, metadata) {
+		t.Fatalf("Expected %v, got %v, %v", metadata, got, err)
+	}
+
+	// Over the size limit of extended attribute values.
+	large := map[string]string{"x-amz-meta-large": strings.Repeat("a", 100*1024)}
+	if err := writeObjectMetadata(storage, bucket, object, large); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.StatFile(minioMetaBucket, sidecar); err != nil {
+		t.Fatalf("Expected a sidecar, got %v", err)
+	}
+	if got, err := readObjectMetadata(storage, bucket, object); err != nil || !reflect.DeepEqual(got, large) {
+		t.Fatalf("Expected the large metadata, got %d keys, %v", len(got), err)
+	}
+}
+
+// Tests validate object metadata is saved in a sidecar file on storage
+// without extended attributes, and removed along with the bucket.
+func TestObjectMetadataSidecar(t *testing.T) {
+	bucket, object := "bucket", "dir/object"
+	posix, path := newObjectMetadataTestStorage(t, bucket, object)
+	defer os.RemoveAll(path)
+	storage := sidecarTestStorage{posix}
+	sidecar := objectMetaSidecar(bucket, object)
+
+	if got, err := readObjectMetadata(storage, bucket, object); err != nil || len(got) != 0 {
+		t.Fatalf("Expected no metadata, got %v, %v", got, err)
+	}
+	metadata := map[string]string{"content-type": "text/plain", "md5Sum": "internal"}
+	if err := writeObjectMetadata(storage, bucket, object, metadata); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.StatFile(minioMetaBucket, sidecar); err != nil {
+		t.Fatalf("Expected a sidecar, got %v", err)
+	}
+	expected := map[string]string{"content-type": "text/plain"}
+	if got, err := readObjectMetadata(storage, bucket, object); err != nil || !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected %v, got %v, %v", expected, got, err)
+	}
+
+	if err := storage.DeleteFile(bucket, object); err != nil {
+		t.Fatal(err)
+	}
+	if err := deleteBucket(storage, bucket); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.StatFile(minioMetaBucket, sidecar); err != errFileNotFound {
+		t.Fatalf("Expected the sidecar removed with the bucket, got %v", err)
+	}
+}
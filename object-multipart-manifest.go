@@ -0,0 +1,125 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+)
+
+// verifyPartChecksum - computes the md5sum of the part uploaded as
+// partSuffix.
+func verifyPartChecksum(storage StorageAPI, bucket, object, uploadID, partSuffix string) (string, error) {
+	reader, err := openUploadedPart(storage, bucket, object, uploadID, partSuffix)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	md5Writer := md5.New()
+	if _, err = io.Copy(md5Writer, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(md5Writer.Sum(nil)), nil
+}
+
+// verifyUploadManifest - verifies the parts completing an upload are
+// exactly the parts of the manifest, with the listed sizes and data
+// hashing to the listed md5sums. Returns ManifestMismatch listing every
+// part which doesn't match.
+func verifyUploadManifest(storage StorageAPI, bucket, object, uploadID string, parts []completePart, manifest []manifestPart) error {
+	manifestParts := make(map[int]manifestPart)
+	for _, part := range manifest {
+		manifestParts[part.PartNumber] = part
+	}
+	var mismatches []PartMismatch
+	completed := make(map[int]bool)
+	for _, part := range parts {
+		completed[part.PartNumber] = true
+		expected, ok := manifestParts[part.PartNumber]
+		if !ok {
+			mismatches = append(mismatches, PartMismatch{PartNumber: part.PartNumber, Reason: "not in manifest"})
+			continue
+		}
+		partSuffix := fmt.Sprintf("%.5d.%s", part.PartNumber, part.ETag)
+		partPath := path.Join(mpartMetaPrefix, bucket, object, uploadID, partSuffix)
+		fi, _, err := statUploadedPart(storage, bucket, object, uploadID, partSuffix)
+		if err != nil {
+			if err == errFileNotFound {
+				mismatches = append(mismatches, PartMismatch{PartNumber: part.PartNumber, Reason: "not uploaded"})
+				continue
+			}
+			return toObjectErr(err, minioMetaBucket, partPath)
+		}
+		if fi.Size != expected.Size {
+			mismatches = append(mismatches, PartMismatch{
+				PartNumber: part.PartNumber,
+				Reason:     fmt.Sprintf("size %d, expected %d", fi.Size, expected.Size),
+			})
+			continue
+		}
+		// The data is hashed again, the ETag only tells what it
+		// hashed to when uploaded.
+		md5Sum, err := verifyPartChecksum(storage, bucket, object, uploadID, partSuffix)
+		if err != nil {
+			return toObjectErr(err, minioMetaBucket, partPath)
+		}
+		if md5Sum != expected.MD5Sum {
+			mismatches = append(mismatches, PartMismatch{
+				PartNumber: part.PartNumber,
+				Reason:     fmt.Sprintf("md5sum %s, expected %s", md5Sum, expected.MD5Sum),
+			})
+		}
+	}
+	for _, part := range manifest {
+		if !completed[part.PartNumber] {
+			mismatches = append(mismatches, PartMismatch{PartNumber: part.PartNumber, Reason: "missing from upload"})
+		}
+	}
+	if len(mismatches) > 0 {
+		return ManifestMismatch{Bucket: bucket, Object: object, UploadID: uploadID, Mismatches: mismatches}
+	}
+	return nil
+}
+
+// completeMultipartUploadWithManifest - common function for both object
+// layers, completes an upload only once its parts match manifest.
+func completeMultipartUploadWithManifest(layer ObjectLayer, storage StorageAPI, bucket, object, uploadID string, parts []completePart, manifest []manifestPart) (string, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return "", BucketNameInvalid{Bucket: bucket}
+	}
+	// Verify whether the bucket exists.
+	if !isBucketExist(storage, bucket) {
+		return "", BucketNotFound{Bucket: bucket}
+	}
+	if !IsValidObjectName(object) {
+		return "", ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	if !isUploadIDExists(storage, bucket, object, uploadID) {
+		return "", InvalidUploadID{UploadID: uploadID}
+	}
+	if err := validateCompleteParts(uploadID, parts); err != nil {
+		return "", err
+	}
+	if err := verifyUploadManifest(storage, bucket, object, uploadID, parts, manifest); err != nil {
+		return "", err
+	}
+	return layer.CompleteMultipartUpload(bucket, object, uploadID, parts)
+}
@@ -0,0 +1,109 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"testing"
+)
+
+// Wrapper for calling CompleteMultipartUploadWithManifest tests for both XL multiple disks and single node setup.
+func TestCompleteMultipartUploadWithManifest(t *testing.T) {
+	ExecObjectLayerTest(t, testCompleteMultipartUploadWithManifest)
+}
+
+// Tests validate uploads complete only when their parts match the
+// manifest, and mismatches are reported by part.
+func testCompleteMultipartUploadWithManifest(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	object := "minio-object"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, object, nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	partsData := [][]byte{bytes.Repeat([]byte("a"), 5*1024*1024), []byte("tail")}
+	var parts []completePart
+	var manifest []manifestPart
+	for i, data := range partsData {
+		var md5Sum string
+		md5Sum, err = obj.PutObjectPart(bucket, object, uploadID, i+1, int64(len(data)), bytes.NewReader(data), "")
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		parts = append(parts, completePart{PartNumber: i + 1, ETag: md5Sum})
+		manifest = append(manifest, manifestPart{PartNumber: i + 1, Size: int64(len(data)), MD5Sum: md5Sum})
+	}
+	otherSum := md5.Sum([]byte("other"))
+
+	testCases := []struct {
+		parts    []completePart
+		manifest []manifestPart
+		// Part numbers expected to mismatch.
+		mismatches []int
+	}{
+		// Test case - 1.
+		// Wrong size of part 1.
+		{parts, []manifestPart{{1, 1, manifest[0].MD5Sum}, manifest[1]}, []int{1}},
+		// Test case - 2.
+		// Wrong md5sum of part 2.
+		{parts, []manifestPart{manifest[0], {2, 4, hex.EncodeToString(otherSum[:])}}, []int{2}},
+		// Test case - 3.
+		// Part 2 missing from the manifest.
+		{parts, manifest[:1], []int{2}},
+		// Test case - 4.
+		// Part 3 of the manifest missing from the upload.
+		{parts, append(append([]manifestPart{}, manifest...), manifestPart{3, 1, manifest[1].MD5Sum}), []int{3}},
+		// Test case - 5.
+		// Part never uploaded.
+		{[]completePart{parts[0], {2, hex.EncodeToString(otherSum[:])}}, manifest, []int{2}},
+	}
+	for i, testCase := range testCases {
+		_, err = obj.CompleteMultipartUploadWithManifest(bucket, object, uploadID, testCase.parts, testCase.manifest)
+		mismatchErr, ok := err.(ManifestMismatch)
+		if !ok {
+			t.Fatalf("%s: Test %d: Expected ManifestMismatch, but instead found %v", instanceType, i+1, err)
+		}
+		if len(mismatchErr.Mismatches) != len(testCase.mismatches) {
+			t.Fatalf("%s: Test %d: Expected %d mismatches, but instead found %v", instanceType, i+1, len(testCase.mismatches), mismatchErr.Mismatches)
+		}
+		for j, partNumber := range testCase.mismatches {
+			if mismatchErr.Mismatches[j].PartNumber != partNumber {
+				t.Errorf("%s: Test %d: Expected part %d to mismatch, but instead found %v", instanceType, i+1, partNumber, mismatchErr.Mismatches[j])
+			}
+		}
+	}
+
+	// Nothing was completed by the failed attempts.
+	if _, err = obj.GetObjectInfo(bucket, object); err == nil {
+		t.Fatalf("%s: Expected object to not exist", instanceType)
+	}
+	if _, err = obj.CompleteMultipartUploadWithManifest(bucket, object, uploadID, parts, manifest); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	objInfo, err := obj.GetObjectInfo(bucket, object)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if objInfo.Size != int64(len(partsData[0])+len(partsData[1])) {
+		t.Fatalf("%s: Expected size %d, but instead found %d", instanceType, len(partsData[0])+len(partsData[1]), objInfo.Size)
+	}
+}
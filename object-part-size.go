@@ -0,0 +1,74 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "sync"
+
+// partSizeLimits - sizes every part of a multipart upload but the last
+// has to be within, the last part only has to respect the maximum.
+type partSizeLimits struct {
+	mutex   *sync.Mutex
+	minSize int64
+	maxSize int64
+}
+
+// newPartSizeLimits - initialize part size limits to the S3 limits.
+func newPartSizeLimits() *partSizeLimits {
+	return &partSizeLimits{
+		mutex:   &sync.Mutex{},
+		minSize: minPartSize,
+		maxSize: maxPartSize,
+	}
+}
+
+// get - returns the minimum and maximum part sizes.
+func (l *partSizeLimits) get() (minSize, maxSize int64) {
+	// Object layers opened for recovery use the S3 limits.
+	if l == nil {
+		return minPartSize, maxPartSize
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.minSize, l.maxSize
+}
+
+// set - changes the part size limits, fails with errInvalidArgument
+// unless 0 < minSize <= maxSize.
+func (l *partSizeLimits) set(minSize, maxSize int64) error {
+	if minSize <= 0 || maxSize < minSize {
+		return errInvalidArgument
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.minSize = minSize
+	l.maxSize = maxSize
+	return nil
+}
+
+// checkPart - validates the size of a part being completed, last is
+// set for the last part of the upload which is exempt from the
+// minimum.
+func (l *partSizeLimits) checkPart(partNumber int, size int64, last bool) error {
+	minSize, maxSize := l.get()
+	if !last && size < minSize {
+		return PartTooSmall{PartNumber: partNumber, PartSize: size, MinSize: minSize}
+	}
+	if size > maxSize {
+		return PartTooLarge{PartNumber: partNumber, PartSize: size, MaxSize: maxSize}
+	}
+	return nil
+}
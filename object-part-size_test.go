@@ -0,0 +1,98 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Wrapper for calling part size limit tests for both XL multiple disks and single node setup.
+func TestPartSizeLimits(t *testing.T) {
+	ExecObjectLayerTest(t, testPartSizeLimits)
+}
+
+// Tests validate CompleteMultipartUpload enforces the configured part
+// size limits, the last part being exempt from the minimum only.
+func testPartSizeLimits(obj ObjectLayer, instanceType string, t *testing.T) {
+	if minSize, maxSize := obj.PartSizeLimits(); minSize != minPartSize || maxSize != maxPartSize {
+		t.Fatalf("%s: Expected the S3 part size limits, got %d and %d", instanceType, minSize, maxSize)
+	}
+	if err := obj.SetPartSizeLimits(0, 1024); err != errInvalidArgument {
+		t.Fatalf("%s: Expected errInvalidArgument for a zero minimum, got %v", instanceType, err)
+	}
+	if err := obj.SetPartSizeLimits(2048, 1024); err != errInvalidArgument {
+		t.Fatalf("%s: Expected errInvalidArgument for a maximum below the minimum, got %v", instanceType, err)
+	}
+	if err := obj.SetPartSizeLimits(1024, 4096); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if minSize, maxSize := obj.PartSizeLimits(); minSize != 1024 || maxSize != 4096 {
+		t.Fatalf("%s: Expected limits 1024 and 4096, got %d and %d", instanceType, minSize, maxSize)
+	}
+
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	testCases := []struct {
+		partSizes []int
+		// Part number expected to fail, 0 if the upload completes.
+		tooSmall int
+		tooLarge int
+	}{
+		{[]int{1024, 4096, 1}, 0, 0},
+		{[]int{2048}, 0, 0},
+		{[]int{1024, 1023, 1024}, 2, 0},
+		{[]int{1024, 4097}, 0, 2},
+		// The last part is held to the maximum too.
+		{[]int{4097}, 0, 1},
+	}
+	for i, testCase := range testCases {
+		object := "object"
+		uploadID, err := obj.NewMultipartUpload(bucket, object, nil)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		var parts []completePart
+		for j, size := range testCase.partSizes {
+			var md5Sum string
+			md5Sum, err = obj.PutObjectPart(bucket, object, uploadID, j+1, int64(size), bytes.NewReader(bytes.Repeat([]byte("a"), size)), "")
+			if err != nil {
+				t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+			}
+			parts = append(parts, completePart{PartNumber: j + 1, ETag: md5Sum})
+		}
+		_, err = obj.CompleteMultipartUpload(bucket, object, uploadID, parts)
+		switch {
+		case testCase.tooSmall != 0:
+			expected := PartTooSmall{PartNumber: testCase.tooSmall, PartSize: int64(testCase.partSizes[testCase.tooSmall-1]), MinSize: 1024}
+			if err != expected {
+				t.Fatalf("%s: Test %d: Expected %v, got %v", instanceType, i+1, expected, err)
+			}
+		case testCase.tooLarge != 0:
+			expected := PartTooLarge{PartNumber: testCase.tooLarge, PartSize: int64(testCase.partSizes[testCase.tooLarge-1]), MaxSize: 4096}
+			if err != expected {
+				t.Fatalf("%s: Test %d: Expected %v, got %v", instanceType, i+1, expected, err)
+			}
+		default:
+			if err != nil {
+				t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Metadata key listing the parts of a multipart object as
+// "number:size" pairs, for backends storing it as one file.
+const partsKey = "x-minio-parts"
+
+// Metadata key holding the encryption at rest of each part listed in
+// partsKey, as a JSON list with null for parts stored in cleartext.
+// Set only if some part is encrypted.
+const partsEncryptionKey = "x-minio-parts-encryption"
+
+// objectParts - parts of a multipart object with their offset within
+// the object.
+func objectParts(parts []MultipartPartInfo) []ObjectPartInfo {
+	if len(parts) == 0 {
+		return nil
+	}
+	objParts := make([]ObjectPartInfo, len(parts))
+	offset := int64(0)
+	for i, part := range parts {
+		objParts[i] = ObjectPartInfo{
+			Number: part.PartNumber,
+			Size:   part.Size,
+			Offset: offset,
+		}
+		offset += part.Size
+	}
+	return objParts
+}
+
+// formatParts - value of partsKey for parts.
+func formatParts(parts []MultipartPartInfo) string {
+	pairs := make([]string, len(parts))
+	for i, part := range parts {
+		pairs[i] = fmt.Sprintf("%d:%d", part.PartNumber, part.Size)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// parseParts - parts of a multipart object listed in partsKey, nil if
+// the value is empty or malformed.
+func parseParts(value string) []MultipartPartInfo {
+	if value == "" {
+		return nil
+	}
+	var parts []MultipartPartInfo
+	for _, pair := range strings.Split(value, ",") {
+		fields := strings.SplitN(pair, ":", 2)
+		if len(fields) != 2 {
+			return nil
+		}
+		partNumber, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil
+		}
+		parts = append(parts, MultipartPartInfo{PartNumber: partNumber, Size: size})
+	}
+	return parts
+}
+
+// formatPartsEncryption - value of partsEncryptionKey for parts, empty
+// if no part is encrypted.
+func formatPartsEncryption(parts []MultipartPartInfo) (string, error) {
+	encrypted := false
+	encryption := make([]map[string]string, len(parts))
+	for i, part := range parts {
+		encryption[i] = part.Encryption
+		encrypted = encrypted || part.Encryption != nil
+	}
+	if !encrypted {
+		return "", nil
+	}
+	data, err := json.Marshal(encryption)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// getPartsEncryption - parts of a multipart object listed in partsKey
+// along with their encryption at rest.
+func getPartsEncryption(metadata map[string]string) ([]MultipartPartInfo, error) {
+	parts := parseParts(metadata[partsKey])
+	var encryption []map[string]string
+	if err := json.Unmarshal([]byte(metadata[partsEncryptionKey]), &encryption); err != nil {
+		return nil, err
+	}
+	if len(encryption) != len(parts) {
+		return nil, errEncryptedDataCorrupt
+	}
+	for i := range parts {
+		parts[i].Encryption = encryption[i]
+	}
+	return parts, nil
+}
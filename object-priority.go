@@ -0,0 +1,191 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// RequestPriority - priority of object layer operations when storage
+// access is contended, the zero value is interactive.
+type RequestPriority int
+
+// Request priorities, from highest to lowest.
+const (
+	// Client requests waiting on the response.
+	PriorityInteractive RequestPriority = iota
+	// Bulk operations issued by clients.
+	PriorityBatch
+	// Server internal work like replication.
+	PriorityBackground
+
+	numRequestPriorities
+)
+
+func (p RequestPriority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityBatch:
+		return "batch"
+	case PriorityBackground:
+		return "background"
+	}
+	return "unknown"
+}
+
+// Number of storage reads and writes in flight at once across all
+// priorities, further ones queue by priority.
+var storageConcurrency = 64
+
+// requestPriorityKey - context key of the request priority.
+type requestPriorityKey struct{}
+
+// WithRequestPriority - returns a copy of ctx carrying priority.
+func WithRequestPriority(ctx context.Context, priority RequestPriority) context.Context {
+	return context.WithValue(ctx, requestPriorityKey{}, priority)
+}
+
+// RequestPriorityFromContext - returns the priority carried by ctx,
+// interactive if none.
+func RequestPriorityFromContext(ctx context.Context) RequestPriority {
+	priority, ok := ctx.Value(requestPriorityKey{}).(RequestPriority)
+	if !ok || priority < 0 || priority >= numRequestPriorities {
+		return PriorityInteractive
+	}
+	return priority
+}
+
+// priorityScheduler - bounds concurrent storage access, once the limit
+// is reached waiting operations are admitted highest priority first
+// and in arrival order within a priority.
+type priorityScheduler struct {
+	mutex  *sync.Mutex
+	limit  int
+	active int
+	queues [numRequestPriorities][]chan struct{}
+}
+
+// newPriorityScheduler - initialize a scheduler admitting limit
+// operations at once.
+func newPriorityScheduler(limit int) *priorityScheduler {
+	if limit < 1 {
+		limit = 1
+	}
+	return &priorityScheduler{
+		mutex: &sync.Mutex{},
+		limit: limit,
+	}
+}
+
+// acquire - waits until an operation of priority is admitted, it must
+// be followed by release.
+func (s *priorityScheduler) acquire(priority RequestPriority) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.active < s.limit {
+		s.active++
+		s.mutex.Unlock()
+		return
+	}
+	admit := make(chan struct{})
+	s.queues[priority] = append(s.queues[priority], admit)
+	s.mutex.Unlock()
+	<-admit
+}
+
+// release - ends an admitted operation, its slot is handed over to
+// the first waiting operation of the highest priority.
+func (s *priorityScheduler) release() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for priority := range s.queues {
+		if len(s.queues[priority]) > 0 {
+			admit := s.queues[priority][0]
+			s.queues[priority] = s.queues[priority][1:]
+			close(admit)
+			return
+		}
+	}
+	s.active--
+}
+
+// queueDepths - number of operations waiting by priority name.
+func (s *priorityScheduler) queueDepths() map[string]int {
+	depths := make(map[string]int)
+	for priority := PriorityInteractive; priority < numRequestPriorities; priority++ {
+		depths[priority.String()] = 0
+	}
+	if s == nil {
+		return depths
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for priority, queue := range s.queues {
+		depths[RequestPriority(priority).String()] = len(queue)
+	}
+	return depths
+}
+
+// reader - returns a reader whose reads are admitted at priority.
+func (s *priorityScheduler) reader(reader io.ReadCloser, priority RequestPriority) io.ReadCloser {
+	if s == nil {
+		return reader
+	}
+	return &scheduledReadCloser{reader, s, priority}
+}
+
+// writer - returns a writer whose writes are admitted at priority.
+func (s *priorityScheduler) writer(writer io.Writer, priority RequestPriority) io.Writer {
+	if s == nil {
+		return writer
+	}
+	return &scheduledWriter{writer, s, priority}
+}
+
+// scheduledReadCloser - reads from storage admitted by a scheduler.
+type scheduledReadCloser struct {
+	io.ReadCloser
+	scheduler *priorityScheduler
+	priority  RequestPriority
+}
+
+func (r *scheduledReadCloser) Read(p []byte) (int, error) {
+	r.scheduler.acquire(r.priority)
+	defer r.scheduler.release()
+	return r.ReadCloser.Read(p)
+}
+
+// scheduledWriter - writes to storage admitted by a scheduler.
+type scheduledWriter struct {
+	io.Writer
+	scheduler *priorityScheduler
+	priority  RequestPriority
+}
+
+func (w *scheduledWriter) Write(p []byte) (int, error) {
+	w.scheduler.acquire(w.priority)
+	defer w.scheduler.release()
+	return w.Writer.Write(p)
+}
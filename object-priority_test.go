@@ -0,0 +1,111 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// Tests validate the priority carried by a context.
+func TestRequestPriorityFromContext(t *testing.T) {
+	if priority := RequestPriorityFromContext(context.Background()); priority != PriorityInteractive {
+		t.Fatalf("Expected default priority interactive, got %s", priority)
+	}
+	ctx := WithRequestPriority(context.Background(), PriorityBatch)
+	if priority := RequestPriorityFromContext(ctx); priority != PriorityBatch {
+		t.Fatalf("Expected priority batch, got %s", priority)
+	}
+}
+
+// Tests validate that once the limit is reached waiting operations
+// are admitted by priority, then in arrival order.
+func TestPrioritySchedulerOrder(t *testing.T) {
+	scheduler := newPriorityScheduler(1)
+	scheduler.acquire(PriorityInteractive)
+
+	// waitQueued - waits until n operations of priority are queued.
+	waitQueued := func(priority RequestPriority, n int) {
+		for scheduler.queueDepths()[priority.String()] != n {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	admitted := make(chan string, 3)
+	queue := func(name string, priority RequestPriority) {
+		go func() {
+			scheduler.acquire(priority)
+			admitted <- name
+		}()
+	}
+	queue("background", PriorityBackground)
+	waitQueued(PriorityBackground, 1)
+	queue("batch", PriorityBatch)
+	waitQueued(PriorityBatch, 1)
+	queue("interactive", PriorityInteractive)
+	waitQueued(PriorityInteractive, 1)
+
+	for _, expected := range []string{"interactive", "batch", "background"} {
+		scheduler.release()
+		if name := <-admitted; name != expected {
+			t.Fatalf("Expected %s to be admitted, got %s", expected, name)
+		}
+	}
+	scheduler.release()
+	for priority, depth := range scheduler.queueDepths() {
+		if depth != 0 {
+			t.Fatalf("Expected empty %s queue, got %d", priority, depth)
+		}
+	}
+	if scheduler.active != 0 {
+		t.Fatalf("Expected no active operations, got %d", scheduler.active)
+	}
+}
+
+// Wrapper for calling prioritized object tests for both XL multiple disks and single node setup.
+func TestObjectLayerWithPriority(t *testing.T) {
+	ExecObjectLayerTest(t, testObjectLayerWithPriority)
+}
+
+// Tests validate objects written and read at a lower priority.
+func testObjectLayerWithPriority(obj ObjectLayer, instanceType string, t *testing.T) {
+	batch := obj.WithContext(WithRequestPriority(context.Background(), PriorityBatch))
+	if err := batch.MakeBucket("bucket"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello, world")
+	if _, err := batch.PutObject("bucket", "object", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	reader, err := batch.GetObject("bucket", "object", 0)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	got, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("%s: Expected %q, got %q", instanceType, data, got)
+	}
+	if len(batch.StorageQueueDepths()) != int(numRequestPriorities) {
+		t.Fatalf("%s: Expected queue depths of %d priorities, got %v", instanceType, numRequestPriorities, batch.StorageQueueDepths())
+	}
+}
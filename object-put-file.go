@@ -0,0 +1,71 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// serverFile - a file on the server handed to PutObjectFromFile.
+//
+// Object layers storing the object data as is, the FS layer, read a
+// serverFile only for its checksums and have the kernel copy it into
+// place with copyServerFile, saving the userspace copy of the streaming
+// path. On Linux the copy is a copy_file_range(2), which needs no data
+// transfer at all on filesystems sharing extents (btrfs, xfs) when the
+// file lives on the same filesystem as the disk, across filesystems it
+// falls back to an ordinary copy. XL, which erasure codes the data, and
+// checkpointed uploads always stream it.
+type serverFile struct {
+	*os.File
+}
+
+// copyServerFile - copies size bytes of src from its start to w, w is
+// expected to be the file created by the storage layer so the copy
+// stays in the kernel.
+func copyServerFile(w io.Writer, src *serverFile, size int64) error {
+	if _, err := src.Seek(0, 0); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(w, src.File, size); err != nil {
+		if err == io.EOF {
+			// Truncated since it was read for its checksums.
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
+
+// putObjectFromFile - common function for both object layers, puts
+// the regular file at srcPath with putObject.
+func putObjectFromFile(bucket, object, srcPath string, metadata map[string]string, putObject func(string, string, int64, io.Reader, map[string]string) (PutObjectResult, error)) (PutObjectResult, error) {
+	file, err := os.Open(srcPath)
+	if err != nil {
+		return PutObjectResult{}, err
+	}
+	defer file.Close()
+	st, err := file.Stat()
+	if err != nil {
+		return PutObjectResult{}, err
+	}
+	if !st.Mode().IsRegular() {
+		return PutObjectResult{}, errIsNotRegular
+	}
+	return putObject(bucket, object, st.Size(), &serverFile{file}, metadata)
+}
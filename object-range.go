@@ -0,0 +1,177 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io"
+	"sort"
+)
+
+// limitedReadCloser - reads at most a fixed number of bytes, closing
+// it closes the underlying reader.
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// newLimitedReadCloser - returns a reader stopping after length bytes
+// of reader, a negative length reads to EOF.
+func newLimitedReadCloser(reader io.ReadCloser, length int64) io.ReadCloser {
+	if length < 0 {
+		return reader
+	}
+	return limitedReadCloser{
+		Reader: io.LimitReader(reader, length),
+		Closer: reader,
+	}
+}
+
+// objectTailOffset - returns the offset of the last n bytes of an
+// object of size bytes, like a "bytes=-n" range. The whole object is
+// read if it is shorter than n bytes.
+func objectTailOffset(bucket, object string, size, n int64) (int64, error) {
+	if n < 0 {
+		return 0, InvalidRange{Bucket: bucket, Object: object, Offset: n, Size: size}
+	}
+	if n > size {
+		return 0, nil
+	}
+	return size - n, nil
+}
+
+// multiRangeReader - reads ranges of an object one after the other,
+// each range is only opened once the previous one is read.
+type multiRangeReader struct {
+	layer  ObjectLayer
+	bucket string
+	object string
+	parts  []ObjectRangePart
+	// Reader of parts[0], nil until opened.
+	reader io.ReadCloser
+}
+
+func (r *multiRangeReader) Read(p []byte) (int, error) {
+	for len(r.parts) > 0 {
+		if r.reader == nil {
+			reader, err := r.layer.GetObjectRange(r.bucket, r.object, r.parts[0].Start, r.parts[0].Length)
+			if err != nil {
+				return 0, err
+			}
+			r.reader = reader
+		}
+		n, err := r.reader.Read(p)
+		if err == io.EOF {
+			r.reader.Close()
+			r.reader = nil
+			r.parts = r.parts[1:]
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+	return 0, io.EOF
+}
+
+func (r *multiRangeReader) Close() error {
+	if r.reader == nil {
+		return nil
+	}
+	err := r.reader.Close()
+	r.reader = nil
+	r.parts = nil
+	return err
+}
+
+// byRangeStart - sorts ranges by their start offset.
+type byRangeStart []ObjectRangePart
+
+func (r byRangeStart) Len() int           { return len(r) }
+func (r byRangeStart) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r byRangeStart) Less(i, j int) bool { return r[i].Start < r[j].Start }
+
+// objectRangeParts - validates ranges of an object of size bytes given
+// as {startOffset, length} pairs, a length of -1 reads to the end. As
+// allowed by RFC 7233, ranges overlapping or out of order are sorted
+// and overlapping ones coalesced, the parts returned describe the
+// ranges actually served.
+func objectRangeParts(bucket, object string, size int64, ranges [][2]int64) ([]ObjectRangePart, error) {
+	if len(ranges) == 0 {
+		return nil, InvalidRange{Bucket: bucket, Object: object, Size: size}
+	}
+	parts := make([]ObjectRangePart, 0, len(ranges))
+	ordered := true
+	for _, r := range ranges {
+		startOffset, length := r[0], r[1]
+		if length < 0 {
+			length = size - startOffset
+		}
+		if startOffset < 0 || startOffset >= size || length <= 0 || startOffset+length > size {
+			return nil, InvalidRange{Bucket: bucket, Object: object, Offset: startOffset, Size: size}
+		}
+		if n := len(parts); n > 0 && startOffset < parts[n-1].Start+parts[n-1].Length {
+			ordered = false
+		}
+		parts = append(parts, ObjectRangePart{Start: startOffset, Length: length})
+	}
+	if !ordered {
+		sort.Sort(byRangeStart(parts))
+		coalesced := parts[:1]
+		for _, part := range parts[1:] {
+			last := &coalesced[len(coalesced)-1]
+			if part.Start > last.Start+last.Length {
+				coalesced = append(coalesced, part)
+				continue
+			}
+			if end := part.Start + part.Length; end > last.Start+last.Length {
+				last.Length = end - last.Start
+			}
+		}
+		parts = coalesced
+	}
+	var offset int64
+	for i := range parts {
+		parts[i].Offset = offset
+		offset += parts[i].Length
+	}
+	return parts, nil
+}
+
+// getObjectRangesCommon - common function for both object layers, reads
+// several ranges of an object as one stream, see objectRangeParts.
+func getObjectRangesCommon(layer ObjectLayer, bucket, object string, ranges [][2]int64) (io.ReadCloser, []ObjectRangePart, error) {
+	objInfo, err := layer.GetObjectInfo(bucket, object)
+	if err != nil {
+		return nil, nil, err
+	}
+	parts, err := objectRangeParts(bucket, object, objInfo.Size, ranges)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Open the first range right away so that errors show up here.
+	reader, err := layer.GetObjectRange(bucket, object, parts[0].Start, parts[0].Length)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &multiRangeReader{
+		layer:  layer,
+		bucket: bucket,
+		object: object,
+		parts:  append([]ObjectRangePart(nil), parts...),
+		reader: reader,
+	}, parts, nil
+}
@@ -0,0 +1,411 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+// Wrapper for calling GetObjectRange tests for both XL multiple disks and single node setup.
+func TestGetObjectRange(t *testing.T) {
+	ExecObjectLayerTest(t, testGetObjectRange)
+}
+
+// Tests validate ranged reads of a multipart object, including ranges
+// within a part, spanning parts and reading to the end.
+func testGetObjectRange(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	object := "minio-object"
+
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, object, nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	// All parts except the last one need to be at least 5MB.
+	partSizes := []int{5 * 1024 * 1024, 5*1024*1024 + 7, 11}
+	var data []byte
+	var parts []completePart
+	for i, size := range partSizes {
+		partID := i + 1
+		partData := bytes.Repeat([]byte{byte('a' + i)}, size)
+		partData[0] = byte('A' + i)
+		var md5Sum string
+		md5Sum, err = obj.PutObjectPart(bucket, object, uploadID, partID, int64(size), bytes.NewReader(partData), "")
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		parts = append(parts, completePart{PartNumber: partID, ETag: md5Sum})
+		data = append(data, partData...)
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, object, uploadID, parts); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	firstPart := int64(partSizes[0])
+	secondPart := int64(partSizes[1])
+	testCases := []struct {
+		startOffset int64
+		length      int64
+	}{
+		// Within the first part.
+		{0, 10},
+		// Last byte of the first part.
+		{firstPart - 1, 1},
+		// Spanning the first two parts.
+		{firstPart - 5, 10},
+		// Spanning all parts.
+		{firstPart - 5, secondPart + 10},
+		// Exactly the second part.
+		{firstPart, secondPart},
+		// Up to the end of the object.
+		{firstPart + 3, int64(len(data)) - firstPart - 3},
+		// Read to EOF.
+		{firstPart + secondPart - 2, -1},
+		{0, -1},
+		// Empty range.
+		{5, 0},
+	}
+	for i, testCase := range testCases {
+		reader, err := obj.GetObjectRange(bucket, object, testCase.startOffset, testCase.length)
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		got, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		end := int64(len(data))
+		if testCase.length >= 0 {
+			end = testCase.startOffset + testCase.length
+		}
+		if !bytes.Equal(got, data[testCase.startOffset:end]) {
+			t.Fatalf("%s: Test %d: Expected %d bytes at offset %d, got %d mismatching bytes", instanceType, i+1, end-testCase.startOffset, testCase.startOffset, len(got))
+		}
+	}
+}
+
+// Wrapper for calling GetObject offset validation tests for both XL multiple disks and single node setup.
+func TestGetObjectInvalidRange(t *testing.T) {
+	ExecObjectLayerTest(t, testGetObjectInvalidRange)
+}
+
+// Tests validate offsets beyond the end of simple and multipart
+// objects fail with InvalidRange, reading from the end is empty.
+func testGetObjectInvalidRange(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err := obj.PutObject(bucket, "empty", 0, bytes.NewReader(nil), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello, world")
+	if _, err := obj.PutObject(bucket, "simple", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	md5Sum, err := obj.PutObjectPart(bucket, "multipart", uploadID, 1, int64(len(data)), bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, "multipart", uploadID, []completePart{{PartNumber: 1, ETag: md5Sum}}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	size := int64(len(data))
+	testCases := []struct {
+		object      string
+		startOffset int64
+		valid       bool
+	}{
+		{"empty", 0, true},
+		{"empty", 1, false},
+		{"simple", size, true},
+		{"simple", size + 1, false},
+		{"simple", -1, false},
+		{"multipart", size, true},
+		{"multipart", size + 1, false},
+	}
+	for i, testCase := range testCases {
+		reader, err := obj.GetObject(bucket, testCase.object, testCase.startOffset)
+		if !testCase.valid {
+			if _, ok := err.(InvalidRange); !ok {
+				t.Fatalf("%s: Test %d: Expected InvalidRange, got %v", instanceType, i+1, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		got, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		if len(got) != 0 {
+			t.Fatalf("%s: Test %d: Expected no data, got %d bytes", instanceType, i+1, len(got))
+		}
+	}
+}
+
+// Wrapper for calling GetObjectTail tests for both XL multiple disks and single node setup.
+func TestGetObjectTail(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testGetObjectTail)
+}
+
+// Tests validate reading the last bytes of simple and multipart
+// objects, tails longer than the object read all of it.
+func testGetObjectTail(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	simpleData := []byte("hello, world")
+	if _, err := obj.PutObject(bucket, "simple", int64(len(simpleData)), bytes.NewReader(simpleData), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var multipartData []byte
+	var parts []completePart
+	for i, size := range []int{5 * 1024 * 1024, 100} {
+		partData := bytes.Repeat([]byte{byte('a' + i)}, size)
+		var md5Sum string
+		md5Sum, err = obj.PutObjectPart(bucket, "multipart", uploadID, i+1, int64(size), bytes.NewReader(partData), "")
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		parts = append(parts, completePart{PartNumber: i + 1, ETag: md5Sum})
+		multipartData = append(multipartData, partData...)
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, "multipart", uploadID, parts); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	testCases := []struct {
+		object string
+		data   []byte
+		n      int64
+	}{
+		{"simple", simpleData, 0},
+		{"simple", simpleData, 5},
+		{"simple", simpleData, int64(len(simpleData))},
+		// Longer than the object.
+		{"simple", simpleData, 1000},
+		// Within the last part.
+		{"multipart", multipartData, 10},
+		// Spanning both parts.
+		{"multipart", multipartData, 150},
+		{"multipart", multipartData, int64(len(multipartData)) + 1},
+	}
+	for i, testCase := range testCases {
+		reader, err := obj.GetObjectTail(bucket, testCase.object, testCase.n)
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		got, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		expected := testCase.data
+		if testCase.n < int64(len(expected)) {
+			expected = expected[int64(len(expected))-testCase.n:]
+		}
+		if !bytes.Equal(got, expected) {
+			t.Errorf("%s: Test %d: Expected the last %d bytes, got %d mismatching bytes", instanceType, i+1, len(expected), len(got))
+		}
+	}
+
+	if _, err = obj.GetObjectTail(bucket, "simple", -1); err == nil {
+		t.Errorf("%s: Expected InvalidRange for a negative tail", instanceType)
+	} else if _, ok := err.(InvalidRange); !ok {
+		t.Errorf("%s: Expected InvalidRange, got %v", instanceType, err)
+	}
+}
+
+// Wrapper for calling GetObjectTail overwrite tests for both XL multiple disks and single node setup.
+func TestGetObjectTailOverwrite(t *testing.T) {
+	initNSLock()
+	ExecObjectLayerTest(t, testGetObjectTailOverwrite)
+}
+
+// Tests validate the tail of an object overwritten concurrently is
+// that of one of its contents, never an offset computed from one
+// content applied to the other.
+func testGetObjectTailOverwrite(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	object := "minio-object"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	contents := [][]byte{bytes.Repeat([]byte("a"), 100), bytes.Repeat([]byte("b"), 50)}
+	if _, err := obj.PutObject(bucket, object, int64(len(contents[0])), bytes.NewReader(contents[0]), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	n := int64(80)
+	expected := [][]byte{contents[0][20:], contents[1]}
+
+	done := make(chan struct{})
+	overwritten := make(chan error, 1)
+	go func() {
+		for i := 1; ; i++ {
+			select {
+			case <-done:
+				overwritten <- nil
+				return
+			default:
+			}
+			data := contents[i%2]
+			if _, err := obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), nil); err != nil {
+				overwritten <- err
+				return
+			}
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		reader, err := obj.GetObjectTail(bucket, object, n)
+		if err != nil {
+			close(done)
+			t.Fatalf("%s: Read %d: %s", instanceType, i+1, err.Error())
+		}
+		got, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			close(done)
+			t.Fatalf("%s: Read %d: %s", instanceType, i+1, err.Error())
+		}
+		if !bytes.Equal(got, expected[0]) && !bytes.Equal(got, expected[1]) {
+			close(done)
+			t.Fatalf("%s: Read %d: Expected the tail of either content, got %q", instanceType, i+1, got)
+		}
+	}
+	close(done)
+	if err := <-overwritten; err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+}
+
+// Wrapper for calling GetObjectRanges tests for both XL multiple disks and single node setup.
+func TestGetObjectRanges(t *testing.T) {
+	ExecObjectLayerTest(t, testGetObjectRanges)
+}
+
+// Tests validate several ranges of simple and multipart objects are
+// read as one stream, ranges out of order or overlapping are sorted and
+// coalesced and invalid ranges fail with InvalidRange.
+func testGetObjectRanges(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	simpleData := []byte("0123456789abcdefghij")
+	if _, err := obj.PutObject(bucket, "simple", int64(len(simpleData)), bytes.NewReader(simpleData), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var multipartData []byte
+	var parts []completePart
+	for i, size := range []int{5 * 1024 * 1024, 100} {
+		partData := bytes.Repeat([]byte{byte('a' + i)}, size)
+		partData[size-1] = 'Z'
+		var md5Sum string
+		md5Sum, err = obj.PutObjectPart(bucket, "multipart", uploadID, i+1, int64(size), bytes.NewReader(partData), "")
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		parts = append(parts, completePart{PartNumber: i + 1, ETag: md5Sum})
+		multipartData = append(multipartData, partData...)
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, "multipart", uploadID, parts); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	firstPart := int64(5 * 1024 * 1024)
+	testCases := []struct {
+		object string
+		data   []byte
+		ranges [][2]int64
+		// Expected {start, length} of the ranges served.
+		served [][2]int64
+	}{
+		{"simple", simpleData, [][2]int64{{0, 3}, {10, 2}, {18, -1}}, [][2]int64{{0, 3}, {10, 2}, {18, 2}}},
+		{"simple", simpleData, [][2]int64{{0, -1}}, [][2]int64{{0, 20}}},
+		// Out of order.
+		{"simple", simpleData, [][2]int64{{10, 2}, {0, 3}}, [][2]int64{{0, 3}, {10, 2}}},
+		// Overlapping ranges are coalesced.
+		{"simple", simpleData, [][2]int64{{5, 5}, {0, 6}, {15, 2}, {16, 4}}, [][2]int64{{0, 10}, {15, 5}}},
+		// Within and across parts.
+		{"multipart", multipartData, [][2]int64{{0, 10}, {firstPart - 2, 4}, {firstPart + 99, 1}}, [][2]int64{{0, 10}, {firstPart - 2, 4}, {firstPart + 99, 1}}},
+	}
+	for i, testCase := range testCases {
+		reader, rangeParts, err := obj.GetObjectRanges(bucket, testCase.object, testCase.ranges)
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		got, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		if len(rangeParts) != len(testCase.served) {
+			t.Fatalf("%s: Test %d: Expected %d ranges, got %d", instanceType, i+1, len(testCase.served), len(rangeParts))
+		}
+		var expected []byte
+		for j, served := range testCase.served {
+			part := rangeParts[j]
+			if part.Start != served[0] || part.Length != served[1] || part.Offset != int64(len(expected)) {
+				t.Fatalf("%s: Test %d: Expected range %d at %d of %d bytes at offset %d, got %+v", instanceType, i+1, j+1, served[0], served[1], len(expected), part)
+			}
+			expected = append(expected, testCase.data[served[0]:served[0]+served[1]]...)
+		}
+		if !bytes.Equal(got, expected) {
+			t.Fatalf("%s: Test %d: Expected %d bytes, got %d mismatching bytes", instanceType, i+1, len(expected), len(got))
+		}
+	}
+
+	size := int64(len(simpleData))
+	for i, ranges := range [][][2]int64{
+		nil,
+		{{size, 1}},
+		{{-1, 2}},
+		{{0, 0}},
+		{{0, 2}, {size - 1, 2}},
+	} {
+		if _, _, err = obj.GetObjectRanges(bucket, "simple", ranges); err == nil {
+			t.Fatalf("%s: Test %d: Expected InvalidRange", instanceType, i+1)
+		} else if _, ok := err.(InvalidRange); !ok {
+			t.Fatalf("%s: Test %d: Expected InvalidRange, got %v", instanceType, i+1, err)
+		}
+	}
+}
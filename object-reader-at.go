@@ -0,0 +1,109 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "io"
+
+// readerAtStorage - optional capability of storage backends which can
+// verify data on random access reads, such as XL.
+type readerAtStorage interface {
+	OpenReaderAt(volume, path string) (io.ReaderAt, int64, error)
+}
+
+// storageReaderAt - random access on top of ReadFile, for storage
+// without redundancy to verify data against.
+type storageReaderAt struct {
+	storage StorageAPI
+	volume  string
+	path    string
+	size    int64
+}
+
+// ReadAt - implements io.ReaderAt.
+func (r storageReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errInvalidArgument
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	reader, err := r.storage.ReadFile(r.volume, r.path, off)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	if left := r.size - off; int64(len(p)) > left {
+		n, err := io.ReadFull(reader, p[:left])
+		if err == nil {
+			err = io.EOF
+		}
+		return n, err
+	}
+	return io.ReadFull(reader, p)
+}
+
+// openReaderAt - opens volume/path for random access, verified if the
+// storage supports it. Returns the reader and the size of the file.
+func openReaderAt(storage StorageAPI, volume, path string) (io.ReaderAt, int64, error) {
+	if s, ok := storage.(readerAtStorage); ok {
+		return s.OpenReaderAt(volume, path)
+	}
+	fi, err := storage.StatFile(volume, path)
+	if err != nil {
+		return nil, 0, err
+	}
+	return storageReaderAt{storage: storage, volume: volume, path: path, size: fi.Size}, fi.Size, nil
+}
+
+// multiReaderAt - random access across the parts of a multipart
+// object, read in order.
+type multiReaderAt struct {
+	readers []io.ReaderAt
+	sizes   []int64
+}
+
+// ReadAt - implements io.ReaderAt.
+func (r multiReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errInvalidArgument
+	}
+	n := 0
+	partOffset := off
+	for i, reader := range r.readers {
+		if n == len(p) {
+			return n, nil
+		}
+		if partOffset >= r.sizes[i] {
+			partOffset -= r.sizes[i]
+			continue
+		}
+		want := int64(len(p) - n)
+		if left := r.sizes[i] - partOffset; want > left {
+			want = left
+		}
+		m, err := reader.ReadAt(p[n:n+int(want)], partOffset)
+		n += m
+		if err != nil && !(err == io.EOF && int64(m) == want) {
+			return n, err
+		}
+		partOffset = 0
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,423 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path"
+
+	"github.com/skyrings/skyring-common/tools/uuid"
+)
+
+// Renames in progress are recorded inside minioMetaBucket under this
+// prefix, a record left behind by a crash is resolved by
+// FindDuplicateMoves.
+const renameIntentPrefix = "renames"
+
+// renameIntent - record of a rename in progress.
+type renameIntent struct {
+	Version   string `json:"version"`
+	Bucket    string `json:"bucket"`
+	SrcObject string `json:"srcObject"`
+	DstObject string `json:"dstObject"`
+	// ETag - ETag of the object being renamed, tells it apart from
+	// other objects found at either name.
+	ETag string `json:"etag"`
+}
+
+// writeRenameIntent - records a rename in progress, returns the path of
+// the record.
+func writeRenameIntent(storage StorageAPI, intent renameIntent) (string, error) {
+	id, err := uuid.New()
+	if err != nil {
+		return "", err
+	}
+	intent.Version = "1"
+	data, err := json.Marshal(intent)
+	if err != nil {
+		return "", err
+	}
+	intentPath := path.Join(renameIntentPrefix, intent.Bucket, id.String())
+	if err = writeSidecarFile(storage, intentPath, data); err != nil {
+		return "", err
+	}
+	return intentPath, nil
+}
+
+// readRenameIntent - reads the rename record at intentPath.
+func readRenameIntent(storage StorageAPI, intentPath string) (renameIntent, error) {
+	r, err := storage.ReadFile(minioMetaBucket, intentPath, 0)
+	if err != nil {
+		return renameIntent{}, err
+	}
+	defer r.Close()
+	var intent renameIntent
+	decoder := json.NewDecoder(r)
+	if err = decoder.Decode(&intent); err != nil {
+		return renameIntent{}, err
+	}
+	return intent, nil
+}
+
+// readObjectSidecar - reads the sidecar metadata file of an object as
+// is, nil if the object has none.
+func readObjectSidecar(storage StorageAPI, bucket, object string) ([]byte, error) {
+	r, err := storage.ReadFile(minioMetaBucket, objectMetaSidecar(bucket, object), 0)
+	if err != nil {
+		if err == errFileNotFound || err == errFileNameTooLong {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// writeObjectSidecar - replaces the sidecar metadata file of an object
+// with data read by readObjectSidecar, nil data removes it.
+func writeObjectSidecar(storage StorageAPI, bucket, object string, data []byte) error {
+	if data == nil {
+		return deleteObjectMetadata(storage, bucket, object)
+	}
+	return writeSidecarFile(storage, objectMetaSidecar(bucket, object), data)
+}
+
+// stageObjectSidecar - writes data read by readObjectSidecar to a
+// sidecar file at stagePath in minioMetaBucket, see replaceObject. Nil
+// data stages empty metadata, so that it replaces any sidecar of an
+// earlier object.
+func stageObjectSidecar(storage StorageAPI, stagePath string, data []byte) error {
+	if data == nil {
+		return stageObjectMetadata(storage, stagePath, nil)
+	}
+	return writeSidecarFile(storage, stagePath, data)
+}
+
+// writeSidecarFile - writes data as is to sidecar in minioMetaBucket.
+func writeSidecarFile(storage StorageAPI, sidecar string, data []byte) error {
+	w, err := storage.CreateFile(minioMetaBucket, sidecar)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, bytes.NewReader(data)); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	if err = w.Close(); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	return nil
+}
+
+// renameObjectCommon - common function to rename an object within a
+// bucket for both object layers, rename moves the object data of the
+// layer and then puts sidecar, the saved metadata of srcObject, in
+// place of the one of dstObject. An existing dstObject is only
+// replaced if overwrite is set. Metadata is carried over as saved,
+// encryption keys aren't bound to the object name so encrypted
+// objects stay readable.
+func renameObjectCommon(layer ObjectLayer, bucket, srcObject, dstObject string, overwrite bool, rename func(srcObject, dstObject string, sidecar []byte) error) error {
+	var storage StorageAPI
+	var bypassGovernance bool
+	var logger Logger = log
+	var stats *bucketStatsTracker
+	switch l := layer.(type) {
+	case xlObjects:
+		storage, bypassGovernance, logger, stats = l.storage, l.bypassGovernance, l.log(), l.bucketStats
+	case fsObjects:
+		storage, bypassGovernance, stats = l.storage, l.bypassGovernance, l.bucketStats
+	}
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(storage, bucket) {
+		return BucketNotFound{Bucket: bucket}
+	}
+	for _, object := range []string{srcObject, dstObject} {
+		if !IsValidObjectName(object) {
+			return ObjectNameInvalid{Bucket: bucket, Object: object}
+		}
+	}
+	meta, err := readBucketMetadata(storage, bucket)
+	if err != nil {
+		return toObjectErr(err, bucket)
+	}
+	// Verify if the new name is allowed by the bucket prefixes.
+	if err = checkObjectPrefixAllowed(meta, bucket, dstObject); err != nil {
+		return err
+	}
+	if err = checkKeyCollision(storage, bucket, dstObject); err != nil {
+		return err
+	}
+
+	// Locks are taken in the order of the names, so that renames in
+	// opposite directions don't deadlock.
+	first, second := srcObject, dstObject
+	if second < first {
+		first, second = second, first
+	}
+	unlock := lockObject(bucket, first)
+	defer unlock()
+	if second != first {
+		unlockSecond := lockObject(bucket, second)
+		defer unlockSecond()
+	}
+
+	srcInfo, err := layer.GetObjectInfo(bucket, srcObject)
+	if err != nil {
+		return err
+	}
+	if srcInfo.IsDir {
+		return ObjectNotFound{Bucket: bucket, Object: srcObject}
+	}
+	if srcObject == dstObject {
+		return nil
+	}
+	// Renaming deletes the object at its old name.
+	if err = checkObjectMutable(storage, bucket, srcObject, bypassGovernance); err != nil {
+		return err
+	}
+	dstExists := false
+	if dstInfo, err := layer.GetObjectInfo(bucket, dstObject); err == nil {
+		if dstInfo.IsDir {
+			return ObjectExistsAsDirectory{Bucket: bucket, Object: dstObject}
+		}
+		if !overwrite {
+			return ObjectAlreadyExists{Bucket: bucket, Object: dstObject}
+		}
+		if err = checkObjectMutable(storage, bucket, dstObject, bypassGovernance); err != nil {
+			return err
+		}
+		dstExists = true
+	} else if _, ok := err.(ObjectNotFound); !ok {
+		return err
+	}
+
+	// Account both names in the bucket stats.
+	defer stats.track(layer, bucket, srcObject)()
+	defer stats.track(layer, bucket, dstObject)()
+
+	// Metadata is put in place along with the data by rename, a
+	// reader doesn't find the object at its new name without it.
+	srcSidecar, err := readObjectSidecar(storage, bucket, srcObject)
+	if err != nil {
+		return toObjectErr(err, bucket, srcObject)
+	}
+	var dstRef dedupReference
+	var dstVersion *retainedVersion
+	if dstExists {
+		// A dedup blob the replaced object references loses a
+		// reference, the one of srcObject moves along.
+		if dstRef, err = lockDedupReference(storage, bucket, dstObject); err != nil {
+			return toObjectErr(err, bucket, dstObject)
+		}
+		defer dstRef.unlock()
+		// In a versioned bucket the replaced object is retained,
+		// before it is replaced.
+		if dstVersion, err = retainReplacedObject(layer, storage, meta, bucket, dstObject, false); err != nil {
+			return toObjectErr(err, bucket, dstObject)
+		}
+	}
+	// A crash from here on may leave the object at both names, the
+	// record of the rename is only removed once it is done.
+	srcETag, err := getObjectETag(layer, srcInfo)
+	if err != nil {
+		return err
+	}
+	intentPath, err := writeRenameIntent(storage, renameIntent{
+		Bucket:    bucket,
+		SrcObject: srcObject,
+		DstObject: dstObject,
+		ETag:      srcETag,
+	})
+	if err != nil {
+		return toObjectErr(err, bucket, srcObject)
+	}
+	if err = rename(srcObject, dstObject, srcSidecar); err != nil {
+		if rerr := dstVersion.restore(); rerr != nil {
+			logger.Errorf("Unable to restore %s/%s from its version: %s", bucket, dstObject, rerr)
+		}
+		if derr := storage.DeleteFile(minioMetaBucket, intentPath); derr != nil {
+			logger.Errorf("Unable to delete %s of the failed rename of %s/%s: %s", intentPath, bucket, srcObject, derr)
+		}
+		return toObjectErr(err, bucket, dstObject)
+	}
+	invalidateTreeWalks(layer, bucket, srcObject)
+	invalidateTreeWalks(layer, bucket, dstObject)
+	if err = deleteObjectMetadata(storage, bucket, srcObject); err != nil {
+		return toObjectErr(err, bucket, srcObject)
+	}
+	// One object less once an existing object is replaced.
+	if dstExists {
+		if err = releaseObjectSlot(storage, meta, bucket); err != nil {
+			return toObjectErr(err, bucket, dstObject)
+		}
+		// A retained version keeps referencing the dedup blob.
+		if dstVersion == nil {
+			if err = dstRef.release(); err != nil {
+				return toObjectErr(err, bucket, dstObject)
+			}
+		}
+	}
+	if err = storage.DeleteFile(minioMetaBucket, intentPath); err != nil {
+		return toObjectErr(err, minioMetaBucket, intentPath)
+	}
+	return nil
+}
+
+// DuplicateMove - an object found at both names of an interrupted
+// rename, the copy at SrcObject was removed.
+type DuplicateMove struct {
+	Bucket    string
+	SrcObject string
+	DstObject string
+}
+
+// findDuplicateMovesCommon - common function to resolve the renames
+// interrupted by a crash for both object layers, from their records
+// left behind. A rename which reached its destination is completed,
+// keeping the object at the destination and removing the one at the
+// source along with its metadata, the metadata is put in place at the
+// destination first if the crash came before. Renames which didn't
+// reach their destination, or whose names hold other objects since,
+// are left as is. remove deletes an object with the object write lock
+// held. Returns the objects which were found at both names.
+func findDuplicateMovesCommon(layer ObjectLayer, remove func(bucket, object string) error) ([]DuplicateMove, error) {
+	var storage StorageAPI
+	switch l := layer.(type) {
+	case xlObjects:
+		storage = l.storage
+	case fsObjects:
+		storage = l.storage
+	}
+	var intentPaths []string
+	if err := walkDirFiles(storage, minioMetaBucket, renameIntentPrefix+slashSeparator, func(intentPath string) error {
+		intentPaths = append(intentPaths, intentPath)
+		return nil
+	}); err != nil {
+		return nil, toObjectErr(err, minioMetaBucket, renameIntentPrefix)
+	}
+	var duplicates []DuplicateMove
+	for _, intentPath := range intentPaths {
+		intent, err := readRenameIntent(storage, intentPath)
+		if err != nil {
+			return duplicates, toObjectErr(err, minioMetaBucket, intentPath)
+		}
+		duplicate, err := resolveRenameIntent(layer, storage, intentPath, intent, remove)
+		if err != nil {
+			return duplicates, err
+		}
+		if duplicate {
+			duplicates = append(duplicates, DuplicateMove{
+				Bucket:    intent.Bucket,
+				SrcObject: intent.SrcObject,
+				DstObject: intent.DstObject,
+			})
+		}
+	}
+	return duplicates, nil
+}
+
+// resolveRenameIntent - resolves the rename recorded at intentPath, see
+// findDuplicateMovesCommon, returns if the object was at both names.
+func resolveRenameIntent(layer ObjectLayer, storage StorageAPI, intentPath string, intent renameIntent, remove func(bucket, object string) error) (bool, error) {
+	bucket, srcObject, dstObject := intent.Bucket, intent.SrcObject, intent.DstObject
+	// Same lock order as renameObjectCommon, a rename in progress
+	// removes its record before it lets go of both names.
+	first, second := srcObject, dstObject
+	if second < first {
+		first, second = second, first
+	}
+	unlock := lockObject(bucket, first)
+	defer unlock()
+	if second != first {
+		unlockSecond := lockObject(bucket, second)
+		defer unlockSecond()
+	}
+	if _, err := storage.StatFile(minioMetaBucket, intentPath); err != nil {
+		if err == errFileNotFound {
+			return false, nil
+		}
+		return false, toObjectErr(err, minioMetaBucket, intentPath)
+	}
+
+	// objectETag - returns the ETag of the object at a name, if any.
+	objectETag := func(object string) (etag string, found bool, err error) {
+		objInfo, err := layer.GetObjectInfo(bucket, object)
+		if err != nil {
+			if isObjectNotFoundErr(err) {
+				return "", false, nil
+			}
+			if _, ok := err.(BucketNotFound); ok {
+				return "", false, nil
+			}
+			return "", false, err
+		}
+		if objInfo.IsDir {
+			return "", false, nil
+		}
+		etag, err = getObjectETag(layer, objInfo)
+		return etag, err == nil, err
+	}
+	dstETag, moved, err := objectETag(dstObject)
+	if err != nil {
+		return false, err
+	}
+	duplicate := false
+	if moved && dstETag == intent.ETag {
+		srcETag, found, err := objectETag(srcObject)
+		if err != nil {
+			return false, err
+		}
+		duplicate = found && srcETag == intent.ETag
+		// Metadata at the source belongs to the renamed object unless
+		// another object was written there since.
+		if duplicate || !found {
+			sidecar, err := readObjectSidecar(storage, bucket, srcObject)
+			if err != nil {
+				return false, toObjectErr(err, bucket, srcObject)
+			}
+			if sidecar != nil {
+				if err = writeObjectSidecar(storage, bucket, dstObject, sidecar); err != nil {
+					return false, toObjectErr(err, bucket, dstObject)
+				}
+			}
+			if duplicate {
+				if err = remove(bucket, srcObject); err != nil {
+					return false, err
+				}
+			} else if err = deleteObjectMetadata(storage, bucket, srcObject); err != nil {
+				return false, toObjectErr(err, bucket, srcObject)
+			}
+			invalidateTreeWalks(layer, bucket, srcObject)
+			invalidateTreeWalks(layer, bucket, dstObject)
+		}
+	}
+	if err = storage.DeleteFile(minioMetaBucket, intentPath); err != nil {
+		return false, toObjectErr(err, minioMetaBucket, intentPath)
+	}
+	return duplicate, nil
+}
@@ -0,0 +1,404 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// Wrapper for calling RenameObject tests for both XL multiple disks and single node setup.
+func TestRenameObject(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testRenameObject)
+}
+
+// Tests validate renamed simple, multipart and encrypted objects read
+// back with their metadata at the new name only, and that existing
+// objects are replaced only when overwriting.
+func testRenameObject(obj ObjectLayer, instanceType string, t *testing.T) {
+	source, err := NewMasterKeySource("test-key", bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	bucket := "minio-bucket"
+	if err = obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	readObject := func(object string) []byte {
+		reader, err := obj.GetObject(bucket, object, 0)
+		if err != nil {
+			t.Fatalf("%s: %s: %s", instanceType, object, err)
+		}
+		defer reader.Close()
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("%s: %s: %s", instanceType, object, err)
+		}
+		return data
+	}
+	isNotFound := func(object string) bool {
+		_, err := obj.GetObjectInfo(bucket, object)
+		_, ok := err.(ObjectNotFound)
+		return ok
+	}
+
+	data := []byte("hello, world")
+	if _, err = obj.PutObject(bucket, "simple", int64(len(data)), bytes.NewReader(data), map[string]string{"content-type": "text/csv"}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err = obj.PutObjectTags(bucket, "simple", map[string]string{"team": "storage"}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var multipartData []byte
+	var parts []completePart
+	for i, size := range []int{5 * 1024 * 1024, 17} {
+		partData := bytes.Repeat([]byte{byte('a' + i)}, size)
+		var partMD5 string
+		partMD5, err = obj.PutObjectPart(bucket, "multipart", uploadID, i+1, int64(size), bytes.NewReader(partData), "")
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		parts = append(parts, completePart{PartNumber: i + 1, ETag: partMD5})
+		multipartData = append(multipartData, partData...)
+	}
+	multipartMD5, err := obj.CompleteMultipartUpload(bucket, "multipart", uploadID, parts)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	SetKeySource(source)
+	defer SetKeySource(nil)
+	secret := bytes.Repeat([]byte("top secret line\n"), 1000)
+	if _, err = obj.PutObject(bucket, "encrypted", int64(len(secret)), bytes.NewReader(secret), map[string]string{compressKey: "true"}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	testCases := []struct {
+		srcObject string
+		dstObject string
+		data      []byte
+	}{
+		{"simple", "dir/renamed", data},
+		{"multipart", "other/multipart", multipartData},
+		{"encrypted", "dir/encrypted", secret},
+	}
+	for i, testCase := range testCases {
+		if err = obj.RenameObject(bucket, testCase.srcObject, testCase.dstObject, false); err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err)
+		}
+		if !isNotFound(testCase.srcObject) {
+			t.Fatalf("%s: Test %d: Expected %s to be gone", instanceType, i+1, testCase.srcObject)
+		}
+		if !bytes.Equal(readObject(testCase.dstObject), testCase.data) {
+			t.Fatalf("%s: Test %d: Renamed data mismatch", instanceType, i+1)
+		}
+	}
+	objInfo, err := obj.GetObjectInfo(bucket, "dir/renamed")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if objInfo.ContentType != "text/csv" {
+		t.Fatalf("%s: Expected text/csv, got %s", instanceType, objInfo.ContentType)
+	}
+	tags, err := obj.GetObjectTags(bucket, "dir/renamed")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if tags["team"] != "storage" {
+		t.Fatalf("%s: Expected tags to be carried over, got %v", instanceType, tags)
+	}
+	if objInfo, err = obj.GetObjectInfo(bucket, "other/multipart"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, ok := obj.(xlObjects); ok && objInfo.MD5Sum != multipartMD5 {
+		t.Fatalf("%s: Expected MD5Sum %s, got %s", instanceType, multipartMD5, objInfo.MD5Sum)
+	}
+
+	// Existing objects are only replaced when overwriting.
+	if err = obj.RenameObject(bucket, "dir/renamed", "dir/encrypted", false); err == nil {
+		t.Fatalf("%s: Expected ObjectAlreadyExists", instanceType)
+	} else if _, ok := err.(ObjectAlreadyExists); !ok {
+		t.Fatalf("%s: Expected ObjectAlreadyExists, got %v", instanceType, err)
+	}
+	if !bytes.Equal(readObject("dir/encrypted"), secret) || !bytes.Equal(readObject("dir/renamed"), data) {
+		t.Fatalf("%s: Refused rename changed the objects", instanceType)
+	}
+	if err = obj.RenameObject(bucket, "dir/renamed", "dir/encrypted", true); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if !bytes.Equal(readObject("dir/encrypted"), data) || !isNotFound("dir/renamed") {
+		t.Fatalf("%s: Expected dir/encrypted to be replaced", instanceType)
+	}
+	if objInfo, err = obj.GetObjectInfo(bucket, "dir/encrypted"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if objInfo.ContentType != "text/csv" || objInfo.Size != int64(len(data)) {
+		t.Fatalf("%s: Expected the metadata of the renamed object, got %s of %d bytes", instanceType, objInfo.ContentType, objInfo.Size)
+	}
+
+	// Objects under legal hold stay where they are.
+	if err = obj.PutObjectLegalHold(bucket, "dir/encrypted", true); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err = obj.RenameObject(bucket, "dir/encrypted", "held", false); err == nil {
+		t.Fatalf("%s: Expected ObjectUnderLegalHold", instanceType)
+	} else if _, ok := err.(ObjectUnderLegalHold); !ok {
+		t.Fatalf("%s: Expected ObjectUnderLegalHold, got %v", instanceType, err)
+	}
+
+	errTestCases := []struct {
+		srcObject string
+		dstObject string
+		expected  error
+	}{
+		{"missing", "elsewhere", ObjectNotFound{Bucket: bucket, Object: "missing"}},
+		{"dir", "elsewhere", ObjectNotFound{Bucket: bucket, Object: "dir"}},
+		{"other/multipart", "dir", ObjectExistsAsDirectory{Bucket: bucket, Object: "dir"}},
+		{"other/multipart", "", ObjectNameInvalid{Bucket: bucket, Object: ""}},
+	}
+	for i, testCase := range errTestCases {
+		if err = obj.RenameObject(bucket, testCase.srcObject, testCase.dstObject, true); err != testCase.expected {
+			t.Fatalf("%s: Test %d: Expected %v, got %v", instanceType, i+1, testCase.expected, err)
+		}
+	}
+}
+
+// renameObservingStorage - records if an object has metadata at
+// dstObject by the time its data is renamed there.
+type renameObservingStorage struct {
+	StorageAPI
+	bucket, dstObject string
+	renamed           bool
+	sidecarErr        error
+}
+
+func (s *renameObservingStorage) RenameFile(srcVolume, srcPath, dstVolume, dstPath string) error {
+	if !s.renamed && dstVolume == s.bucket && dstPath == s.dstObject {
+		s.renamed = true
+		_, s.sidecarErr = s.StorageAPI.StatFile(minioMetaBucket, objectMetaSidecar(s.bucket, s.dstObject))
+	}
+	return s.StorageAPI.RenameFile(srcVolume, srcPath, dstVolume, dstPath)
+}
+
+// Wrapper for calling RenameObject metadata order tests for both XL multiple disks and single node setup.
+func TestRenameObjectMetadataOrder(t *testing.T) {
+	initNSLock()
+	ExecObjectLayerTest(t, testRenameObjectMetadataOrder)
+}
+
+// Tests validate metadata of a renamed object is put in place only
+// after its data, a reader never finds it at the new name alone.
+func testRenameObjectMetadataOrder(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello, world")
+	if _, err := obj.PutObject(bucket, "object", int64(len(data)), bytes.NewReader(data), map[string]string{"content-type": "text/csv"}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	// The metadata is read back through obj, the observing storage
+	// hides extended attributes of the storage it wraps.
+	storage := &renameObservingStorage{bucket: bucket, dstObject: "renamed"}
+	renamer := obj
+	switch layer := obj.(type) {
+	case fsObjects:
+		storage.StorageAPI = layer.storage
+		layer.storage = storage
+		renamer = layer
+	case xlObjects:
+		storage.StorageAPI = layer.storage
+		layer.storage = storage
+		renamer = layer
+	}
+	if err := renamer.RenameObject(bucket, "object", "renamed", false); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if !storage.renamed {
+		t.Fatalf("%s: Expected the object data to be renamed", instanceType)
+	}
+	if storage.sidecarErr != errFileNotFound {
+		t.Fatalf("%s: Expected no metadata at the new name before the data, got %v", instanceType, storage.sidecarErr)
+	}
+	objInfo, err := obj.GetObjectInfo(bucket, "renamed")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if objInfo.ContentType != "text/csv" {
+		t.Fatalf("%s: Expected text/csv, got %s", instanceType, objInfo.ContentType)
+	}
+}
+
+// errTestCrash - panic of crashingRenameStorage standing in for a crash.
+var errTestCrash = errors.New("test crash")
+
+// crashingRenameStorage - crashes while renaming the data of srcObject,
+// after copying it to the destination if copy is set, as a rename done
+// by copying would.
+type crashingRenameStorage struct {
+	StorageAPI
+	bucket, srcObject string
+	copy              bool
+}
+
+func (s crashingRenameStorage) RenameFile(srcVolume, srcPath, dstVolume, dstPath string) error {
+	if srcVolume != s.bucket || srcPath != s.srcObject {
+		return s.StorageAPI.RenameFile(srcVolume, srcPath, dstVolume, dstPath)
+	}
+	if s.copy {
+		r, err := s.StorageAPI.ReadFile(srcVolume, srcPath, 0)
+		if err != nil {
+			panic(err)
+		}
+		defer r.Close()
+		w, err := s.StorageAPI.CreateFile(dstVolume, dstPath)
+		if err != nil {
+			panic(err)
+		}
+		if _, err = io.Copy(w, r); err != nil {
+			panic(err)
+		}
+		if err = w.Close(); err != nil {
+			panic(err)
+		}
+	}
+	panic(errTestCrash)
+}
+
+// Wrapper for calling FindDuplicateMoves tests for both XL multiple disks and single node setup.
+func TestFindDuplicateMoves(t *testing.T) {
+	initNSLock()
+	ExecObjectLayerTest(t, testFindDuplicateMoves)
+}
+
+// Tests validate renames crashing before and after their data reached
+// the destination are resolved, an object at both names is kept at the
+// destination only, and records of completed renames don't linger.
+func testFindDuplicateMoves(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello, world")
+	otherData := []byte("other data")
+	objects := map[string][]byte{"copied": data, "kept": data, "renamed-kept": otherData, "done": data}
+	for object, objectData := range objects {
+		if _, err := obj.PutObject(bucket, object, int64(len(objectData)), bytes.NewReader(objectData), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	var storage StorageAPI
+	switch layer := obj.(type) {
+	case fsObjects:
+		storage = layer.storage
+	case xlObjects:
+		storage = layer.storage
+	}
+	countIntents := func() int {
+		count := 0
+		if err := walkDirFiles(storage, minioMetaBucket, renameIntentPrefix+slashSeparator, func(string) error {
+			count++
+			return nil
+		}); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		return count
+	}
+	if err := obj.RenameObject(bucket, "done", "renamed-done", false); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if count := countIntents(); count != 0 {
+		t.Fatalf("%s: Expected no rename records after a rename, got %d", instanceType, count)
+	}
+
+	crash := func(srcObject, dstObject string, copy bool) {
+		crashing := crashingRenameStorage{bucket: bucket, srcObject: srcObject, copy: copy}
+		renamer := obj
+		switch layer := obj.(type) {
+		case fsObjects:
+			crashing.StorageAPI = layer.storage
+			layer.storage = crashing
+			renamer = layer
+		case xlObjects:
+			crashing.StorageAPI = layer.storage
+			layer.storage = crashing
+			renamer = layer
+		}
+		defer func() {
+			if r := recover(); r != errTestCrash {
+				t.Fatalf("%s: Expected the rename of %s to crash, got %v", instanceType, srcObject, r)
+			}
+		}()
+		renamer.RenameObject(bucket, srcObject, dstObject, true)
+	}
+	// Crashes once the data is at the destination, the object is at
+	// both names.
+	crash("copied", "renamed-copied", true)
+	// Crashes before the data moves, the object at the destination is
+	// another one.
+	crash("kept", "renamed-kept", false)
+	if count := countIntents(); count != 2 {
+		t.Fatalf("%s: Expected 2 rename records after the crashes, got %d", instanceType, count)
+	}
+
+	duplicates, err := obj.FindDuplicateMoves()
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	expected := []DuplicateMove{{Bucket: bucket, SrcObject: "copied", DstObject: "renamed-copied"}}
+	if !reflect.DeepEqual(duplicates, expected) {
+		t.Fatalf("%s: Expected %v, got %v", instanceType, expected, duplicates)
+	}
+	if count := countIntents(); count != 0 {
+		t.Fatalf("%s: Expected the rename records to be resolved, got %d", instanceType, count)
+	}
+	if _, err = obj.GetObjectInfo(bucket, "copied"); err == nil {
+		t.Fatalf("%s: Expected the source of the duplicate to be removed", instanceType)
+	} else if _, ok := err.(ObjectNotFound); !ok {
+		t.Fatalf("%s: Expected ObjectNotFound, got %v", instanceType, err)
+	}
+	for object, expectedData := range map[string][]byte{"renamed-copied": data, "kept": data} {
+		reader, err := obj.GetObject(bucket, object, 0)
+		if err != nil {
+			t.Fatalf("%s: %s: %s", instanceType, object, err.Error())
+		}
+		readData, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("%s: %s: %s", instanceType, object, err.Error())
+		}
+		if !bytes.Equal(readData, expectedData) {
+			t.Fatalf("%s: %s: Expected %q, got %q", instanceType, object, expectedData, readData)
+		}
+	}
+
+	// Nothing is left to resolve.
+	if duplicates, err = obj.FindDuplicateMoves(); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	} else if len(duplicates) != 0 {
+		t.Fatalf("%s: Expected no duplicates, got %v", instanceType, duplicates)
+	}
+}
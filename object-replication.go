@@ -0,0 +1,320 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// Replication status of an object, saved in the object metadata.
+const (
+	ReplicationPending   = "PENDING"
+	ReplicationCompleted = "COMPLETED"
+	ReplicationFailed    = "FAILED"
+)
+
+const (
+	// Object metadata key of the replication status.
+	replicationStatusKey = "x-minio-replication-status"
+	// Number of replication attempts before an object is marked failed.
+	replicationMaxAttempts = 5
+	// Number of objects waiting for replication before new writes are
+	// marked failed right away.
+	replicationQueueSize = 10000
+)
+
+// Wait before retrying a failed replication, multiplied by the attempt.
+var replicationRetryInterval = time.Second
+
+// errReplicationTargetNotFound - replication rule names an unregistered target.
+var errReplicationTargetNotFound = errors.New("Replication target not found")
+
+// bucketReplication - replication rule of a bucket, saved in bucket
+// metadata. Objects written to the bucket are copied to TargetBucket
+// of the remote storage registered as Target.
+type bucketReplication struct {
+	Target       string `json:"target"`
+	TargetBucket string `json:"targetBucket"`
+}
+
+// Remote storages objects can be replicated to, by name.
+var globalReplicationTargets = struct {
+	sync.RWMutex
+	targets map[string]StorageAPI
+}{targets: make(map[string]StorageAPI)}
+
+// RegisterReplicationTarget - registers a remote storage as a
+// replication target, replaces any target with the same name.
+func RegisterReplicationTarget(name string, storage StorageAPI) {
+	globalReplicationTargets.Lock()
+	defer globalReplicationTargets.Unlock()
+	globalReplicationTargets.targets[name] = storage
+}
+
+// getReplicationTarget - returns the registered target storage.
+func getReplicationTarget(name string) (StorageAPI, error) {
+	globalReplicationTargets.RLock()
+	defer globalReplicationTargets.RUnlock()
+	storage, ok := globalReplicationTargets.targets[name]
+	if !ok {
+		return nil, errReplicationTargetNotFound
+	}
+	return storage, nil
+}
+
+// setBucketReplication - common function to save the replication rule
+// of a bucket for both object layers, an empty target removes the rule.
+func setBucketReplication(storage StorageAPI, bucket, target, targetBucket string) error {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(storage, bucket) {
+		return BucketNotFound{Bucket: bucket}
+	}
+	var replication *bucketReplication
+	if target != "" {
+		if !IsValidBucketName(targetBucket) {
+			return BucketNameInvalid{Bucket: targetBucket}
+		}
+		replication = &bucketReplication{Target: target, TargetBucket: targetBucket}
+	}
+	err := updateBucketMetadata(storage, bucket, func(meta *bucketMetadata) {
+		meta.Replication = replication
+	})
+	if err != nil {
+		return toObjectErr(err, bucket)
+	}
+	return nil
+}
+
+// replicationMetadata - marks the object metadata pending replication
+// if the bucket has a replication rule, returns the metadata to save
+// and whether the object must be queued for replication.
+func replicationMetadata(meta bucketMetadata, metadata map[string]string) (map[string]string, bool) {
+	if meta.Replication == nil {
+		return metadata, false
+	}
+	// Don't modify the caller's metadata.
+	objMetadata := make(map[string]string)
+	for k, v := range metadata {
+		objMetadata[k] = v
+	}
+	objMetadata[replicationStatusKey] = ReplicationPending
+	return objMetadata, true
+}
+
+// replicationJob - object waiting for replication.
+type replicationJob struct {
+	bucket string
+	object string
+	// ETag of the object replicated, set by the first attempt. The
+	// status of a replaced object is left to the replacement's job.
+	etag    string
+	attempt int
+}
+
+// replicator - copies objects to the replication target of their
+// bucket in background, never blocking the writes.
+type replicator struct {
+	storage StorageAPI
+	queue   chan replicationJob
+	once    *sync.Once
+	logger  Logger
+	// Object layer the objects are read from, set by start.
+	getObject     func(bucket, object string, startOffset int64) (io.ReadCloser, error)
+	getObjectInfo func(bucket, object string) (ObjectInfo, error)
+	// Closed to stop the worker, set by start.
+	doneCh <-chan struct{}
+}
+
+// newReplicator - initialize a new replicator for objects of storage,
+// failures are reported to logger.
+func newReplicator(storage StorageAPI, logger Logger) *replicator {
+	return &replicator{
+		storage: storage,
+		queue:   make(chan replicationJob, replicationQueueSize),
+		once:    &sync.Once{},
+		logger:  logger,
+	}
+}
+
+// start - sets the object layer objects are read from, the worker
+// stops once doneCh is closed.
+func (r *replicator) start(layer ObjectLayer, doneCh <-chan struct{}) {
+	r.getObject = layer.GetObject
+	r.getObjectInfo = layer.GetObjectInfo
+	r.doneCh = doneCh
+}
+
+// enqueue - queues an object for replication, the worker is started
+// on first use. Objects which don't fit in the queue are marked
+// failed so they can be retried later, objects queued after shutdown
+// are left pending.
+func (r *replicator) enqueue(job replicationJob) {
+	select {
+	case <-r.doneCh:
+		return
+	default:
+	}
+	r.once.Do(func() {
+		go r.run()
+	})
+	select {
+	case r.queue <- job:
+	default:
+		r.logger.Errorf("Replication queue full, unable to replicate %s/%s", job.bucket, job.object)
+		// Writers enqueue holding the object lock setStatus takes.
+		go r.setStatus(job.bucket, job.object, job.etag, ReplicationFailed)
+	}
+}
+
+// run - replicates queued objects, retrying failures with backoff,
+// until doneCh is closed.
+func (r *replicator) run() {
+	for {
+		var job replicationJob
+		select {
+		case <-r.doneCh:
+			return
+		case job = <-r.queue:
+		}
+		err := r.replicate(&job)
+		if err == errFileNotFound {
+			// Object deleted or replaced in the meantime.
+			continue
+		}
+		if err == nil {
+			r.setStatus(job.bucket, job.object, job.etag, ReplicationCompleted)
+			continue
+		}
+		job.attempt++
+		r.logger.Errorf("Replication of %s/%s failed, attempt %d: %s", job.bucket, job.object, job.attempt, err)
+		if job.attempt >= replicationMaxAttempts {
+			r.setStatus(job.bucket, job.object, job.etag, ReplicationFailed)
+			continue
+		}
+		retryJob := job
+		time.AfterFunc(time.Duration(job.attempt)*replicationRetryInterval, func() {
+			r.enqueue(retryJob)
+		})
+	}
+}
+
+// replicate - copies the object to the replication target of its
+// bucket, records the ETag of the object in the job.
+func (r *replicator) replicate(job *replicationJob) error {
+	bucket, object := job.bucket, job.object
+	meta, err := readBucketMetadata(r.storage, bucket)
+	if err != nil {
+		return err
+	}
+	if meta.Replication == nil {
+		// Replication rule removed after the object was written.
+		return errFileNotFound
+	}
+	objInfo, err := r.getObjectInfo(bucket, object)
+	if err != nil {
+		if _, ok := err.(ObjectNotFound); ok {
+			return errFileNotFound
+		}
+		return err
+	}
+	if job.etag == "" {
+		job.etag = objInfo.MD5Sum
+	} else if job.etag != objInfo.MD5Sum {
+		// Replaced in the meantime, the replacement is queued itself.
+		return errFileNotFound
+	}
+	target, err := getReplicationTarget(meta.Replication.Target)
+	if err != nil {
+		return err
+	}
+	writer, err := target.CreateFile(meta.Replication.TargetBucket, object)
+	if err != nil {
+		return err
+	}
+	reader, err := r.getObject(bucket, object, 0)
+	if err != nil {
+		safeCloseAndRemove(writer)
+		if _, ok := err.(ObjectNotFound); ok {
+			return errFileNotFound
+		}
+		return err
+	}
+	defer reader.Close()
+	if _, err = io.Copy(writer, reader); err != nil {
+		safeCloseAndRemove(writer)
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		safeCloseAndRemove(writer)
+		return err
+	}
+	return nil
+}
+
+// setStatus - saves the replication status in the object metadata,
+// unless the object was deleted or replaced by an object with an ETag
+// other than etag. An empty etag matches any object.
+func (r *replicator) setStatus(bucket, object, etag, status string) {
+	unlock := lockObject(bucket, object)
+	defer unlock()
+	objInfo, err := r.getObjectInfo(bucket, object)
+	if err != nil {
+		if _, ok := err.(ObjectNotFound); !ok {
+			r.logger.Errorf("Unable to read %s/%s: %s", bucket, object, err)
+		}
+		return
+	}
+	if etag != "" && objInfo.MD5Sum != etag {
+		return
+	}
+	metadata, err := readObjectMetadata(r.storage, bucket, object)
+	if err != nil {
+		r.logger.Errorf("Unable to read metadata of %s/%s: %s", bucket, object, err)
+		return
+	}
+	metadata[replicationStatusKey] = status
+	if err = writeObjectMetadata(r.storage, bucket, object, metadata); err != nil {
+		r.logger.Errorf("Unable to save replication status of %s/%s: %s", bucket, object, err)
+	}
+}
+
+// replicateObject - common function to queue an object for replication
+// again for both object layers, such as objects which failed.
+func replicateObject(layer ObjectLayer, storage StorageAPI, r *replicator, bucket, object string) error {
+	objInfo, err := layer.GetObjectInfo(bucket, object)
+	if err != nil {
+		return err
+	}
+	meta, err := readBucketMetadata(storage, bucket)
+	if err != nil {
+		return toObjectErr(err, bucket)
+	}
+	if meta.Replication == nil {
+		return ReplicationNotConfigured{Bucket: bucket}
+	}
+	if objInfo.ReplicationStatus != ReplicationPending {
+		r.setStatus(bucket, object, objInfo.MD5Sum, ReplicationPending)
+	}
+	r.enqueue(replicationJob{bucket: bucket, object: object, etag: objInfo.MD5Sum})
+	return nil
+}
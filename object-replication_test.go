@@ -0,0 +1,146 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// Waits for the replication status of an object to become status.
+func waitReplicationStatus(t *testing.T, obj ObjectLayer, bucket, object, status string) {
+	var objInfo ObjectInfo
+	var err error
+	for i := 0; i < 200; i++ {
+		objInfo, err = obj.GetObjectInfo(bucket, object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if objInfo.ReplicationStatus == status {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Expected replication status %s, got %s", status, objInfo.ReplicationStatus)
+}
+
+// Wrapper for calling replication tests for both XL multiple disks and single node setup.
+func TestObjectReplication(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testObjectReplication)
+}
+
+// Tests validate that objects are replicated in background and their
+// status is reported, failed replications can be retried.
+func testObjectReplication(obj ObjectLayer, instanceType string, t *testing.T) {
+	defer func(interval time.Duration) {
+		replicationRetryInterval = interval
+	}(replicationRetryInterval)
+	replicationRetryInterval = time.Millisecond
+
+	path, err := ioutil.TempDir(os.TempDir(), "minio-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	target, err := newPosix(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterReplicationTarget("dr-"+instanceType, target)
+
+	bucket := "minio-bucket"
+	if err = obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	// Objects of buckets without replication have no status.
+	if _, err = obj.PutObject(bucket, "local", 1, bytes.NewReader([]byte("a")), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, ok := obj.ReplicateObject(bucket, "local").(ReplicationNotConfigured); !ok {
+		t.Fatalf("%s: Expected ReplicationNotConfigured", instanceType)
+	}
+	if err = obj.SetBucketReplication(bucket, "dr-"+instanceType, "replica"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	// Target bucket doesn't exist yet, replication fails after retries.
+	data := []byte("replicated object")
+	if _, err = obj.PutObject(bucket, "dir/object", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	waitReplicationStatus(t, obj, bucket, "dir/object", ReplicationFailed)
+
+	// Retry once the target is available.
+	if err = target.MakeVol("replica"); err != nil {
+		t.Fatal(err)
+	}
+	if err = obj.ReplicateObject(bucket, "dir/object"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	waitReplicationStatus(t, obj, bucket, "dir/object", ReplicationCompleted)
+	replica, err := ioutil.ReadFile(path + "/replica/dir/object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(replica, data) {
+		t.Fatalf("%s: Replica data mismatch", instanceType)
+	}
+
+	// New writes are replicated right away.
+	if _, err = obj.PutObject(bucket, "object", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	waitReplicationStatus(t, obj, bucket, "object", ReplicationCompleted)
+
+	var r *replicator
+	var storage StorageAPI
+	switch layer := obj.(type) {
+	case fsObjects:
+		r, storage = layer.replicator, layer.storage
+	case xlObjects:
+		r, storage = layer.replicator, layer.storage
+	}
+	// Status of a replaced object is left to the replacement.
+	r.setStatus(bucket, "object", "00000000000000000000000000000000", ReplicationFailed)
+	waitReplicationStatus(t, obj, bucket, "object", ReplicationCompleted)
+	// Status of a deleted object is never saved.
+	if err = obj.DeleteObject(bucket, "object"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	r.setStatus(bucket, "object", "", ReplicationFailed)
+	if _, err = obj.GetObjectInfo(bucket, "object"); err == nil {
+		t.Fatalf("%s: Expected ObjectNotFound", instanceType)
+	}
+	if _, err = storage.StatFile(minioMetaBucket, objectMetaSidecar(bucket, "object")); err != errFileNotFound {
+		t.Fatalf("%s: Expected no sidecar, got %v", instanceType, err)
+	}
+
+	// Objects written after shutdown are left pending.
+	if err = obj.Shutdown(); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.PutObject(bucket, "late", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	time.Sleep(50 * time.Millisecond)
+	waitReplicationStatus(t, obj, bucket, "late", ReplicationPending)
+}
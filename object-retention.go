@@ -0,0 +1,126 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// Object metadata key of the retention mode.
+	retentionModeKey = "x-minio-retention-mode"
+	// Object metadata key of the end of the retention period, in
+	// RFC3339 format.
+	retainUntilKey = "x-minio-retain-until-date"
+
+	// RetentionGovernance - retention which callers allowed to bypass
+	// governance can override.
+	RetentionGovernance = "GOVERNANCE"
+	// RetentionCompliance - retention which nobody can override
+	// until it elapses.
+	RetentionCompliance = "COMPLIANCE"
+)
+
+// ObjectRetention - write-once retention of an object, neither
+// overwritten nor deleted until RetainUntil.
+type ObjectRetention struct {
+	Mode        string
+	RetainUntil time.Time
+}
+
+// getRetentionFromMetadata - returns the retention set in metadata, nil
+// if there is none. Returns errInvalidArgument if only one of the keys
+// is set, the mode is unknown or the date is malformed.
+func getRetentionFromMetadata(metadata map[string]string) (*ObjectRetention, error) {
+	mode, hasMode := metadata[retentionModeKey]
+	retainUntil, hasRetainUntil := metadata[retainUntilKey]
+	if !hasMode && !hasRetainUntil {
+		return nil, nil
+	}
+	if mode != RetentionGovernance && mode != RetentionCompliance {
+		return nil, errInvalidArgument
+	}
+	until, err := time.Parse(time.RFC3339, retainUntil)
+	if err != nil {
+		return nil, errInvalidArgument
+	}
+	return &ObjectRetention{Mode: mode, RetainUntil: until.UTC()}, nil
+}
+
+// checkRetentionMetadata - validates the retention requested in the
+// metadata of a new object.
+func checkRetentionMetadata(bucket, object string, metadata map[string]string) error {
+	if _, err := getRetentionFromMetadata(metadata); err != nil {
+		return InvalidObjectRetention{Bucket: bucket, Object: object}
+	}
+	return nil
+}
+
+// getObjectRetention - returns the retention of an object given its
+// metadata, falls back to the multipart meta file which records the
+// retention of multipart objects along with their parts. Returns nil
+// if the object has no retention. A corrupt multipart meta file fails
+// with CorruptMultipartMeta, the object stays locked until healed.
+func getObjectRetention(storage StorageAPI, bucket, object string, metadata map[string]string) (*ObjectRetention, error) {
+	retention, err := getRetentionFromMetadata(metadata)
+	if err != nil || retention != nil {
+		return retention, err
+	}
+	isMultipart, err := isMultipartObject(storage, bucket, object)
+	if err != nil || !isMultipart {
+		return nil, err
+	}
+	info, err := getMultipartObjectInfo(storage, bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	return info.Retention, nil
+}
+
+// checkObjectRetention - returns ObjectLocked if the retention of an
+// object hasn't elapsed, governance retention is ignored if
+// bypassGovernance is set.
+func checkObjectRetention(storage StorageAPI, bucket, object string, metadata map[string]string, bypassGovernance bool) error {
+	retention, err := getObjectRetention(storage, bucket, object, metadata)
+	if err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+	if retention == nil || !time.Now().UTC().Before(retention.RetainUntil) {
+		return nil
+	}
+	if retention.Mode == RetentionGovernance && bypassGovernance {
+		return nil
+	}
+	return ObjectLocked{Bucket: bucket, Object: object, RetainUntil: retention.RetainUntil}
+}
+
+// governanceBypassKey - context key of the governance bypass flag.
+type governanceBypassKey struct{}
+
+// WithGovernanceBypass - returns a copy of ctx allowing operations to
+// override governance retention, compliance retention still applies.
+func WithGovernanceBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, governanceBypassKey{}, true)
+}
+
+// GovernanceBypassFromContext - returns true if ctx allows overriding
+// governance retention.
+func GovernanceBypassFromContext(ctx context.Context) bool {
+	bypass, _ := ctx.Value(governanceBypassKey{}).(bool)
+	return bypass
+}
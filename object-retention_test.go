@@ -0,0 +1,183 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+// Wrapper for calling object retention tests for both XL multiple disks and single node setup.
+func TestObjectRetention(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testObjectRetention)
+}
+
+// Tests validate objects under retention can't be overwritten or
+// deleted until it elapses, governance retention can be bypassed and
+// compliance retention can't.
+func testObjectRetention(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello, world")
+	retention := func(mode string, until time.Time) map[string]string {
+		return map[string]string{
+			retentionModeKey: mode,
+			retainUntilKey:   until.Format(time.RFC3339),
+		}
+	}
+	future := time.Now().UTC().Add(time.Hour)
+	past := time.Now().UTC().Add(-time.Hour)
+	bypass := obj.WithContext(WithGovernanceBypass(context.Background()))
+
+	testCases := []struct {
+		object   string
+		metadata map[string]string
+		// Whether overwrites and deletes fail, without and with the
+		// governance bypass.
+		locked         bool
+		lockedOnBypass bool
+	}{
+		// Test case - 1.
+		// Compliance retention can't be bypassed.
+		{"compliance", retention(RetentionCompliance, future), true, true},
+		// Test case - 2.
+		// Governance retention can be bypassed.
+		{"governance", retention(RetentionGovernance, future), true, false},
+		// Test case - 3.
+		// Elapsed retention doesn't lock.
+		{"elapsed", retention(RetentionCompliance, past), false, false},
+		// Test case - 4.
+		// No retention.
+		{"unlocked", nil, false, false},
+	}
+	for i, testCase := range testCases {
+		if _, err := obj.PutObject(bucket, testCase.object, int64(len(data)), bytes.NewReader(data), testCase.metadata); err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		_, err := obj.PutObject(bucket, testCase.object, int64(len(data)), bytes.NewReader(data), testCase.metadata)
+		if _, ok := err.(ObjectLocked); ok != testCase.locked {
+			t.Fatalf("%s: Test %d: Expected overwrite to be locked %v, got %v", instanceType, i+1, testCase.locked, err)
+		}
+		err = obj.DeleteObject(bucket, testCase.object)
+		if _, ok := err.(ObjectLocked); ok != testCase.locked {
+			t.Fatalf("%s: Test %d: Expected delete to be locked %v, got %v", instanceType, i+1, testCase.locked, err)
+		}
+		if !testCase.locked {
+			continue
+		}
+		err = bypass.DeleteObject(bucket, testCase.object)
+		if _, ok := err.(ObjectLocked); ok != testCase.lockedOnBypass {
+			t.Fatalf("%s: Test %d: Expected bypassed delete to be locked %v, got %v", instanceType, i+1, testCase.lockedOnBypass, err)
+		}
+	}
+
+	// Retention metadata can't be changed by updating metadata.
+	if _, err := obj.CopyObject(bucket, "compliance", bucket, "compliance", retention(RetentionGovernance, past)); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err := bypass.DeleteObject(bucket, "compliance"); err == nil {
+		t.Fatalf("%s: Expected compliance retention to be kept", instanceType)
+	}
+
+	// A legal hold isn't lifted by the governance bypass.
+	if _, err := obj.PutObject(bucket, "held", int64(len(data)), bytes.NewReader(data), retention(RetentionGovernance, future)); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err := obj.PutObjectLegalHold(bucket, "held", true); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err := bypass.DeleteObject(bucket, "held"); err == nil {
+		t.Fatalf("%s: Expected ObjectUnderLegalHold", instanceType)
+	} else if _, ok := err.(ObjectUnderLegalHold); !ok {
+		t.Fatalf("%s: Expected ObjectUnderLegalHold, got %v", instanceType, err)
+	}
+
+	// Invalid retention is rejected.
+	invalid := []map[string]string{
+		{retentionModeKey: "FOREVER", retainUntilKey: future.Format(time.RFC3339)},
+		{retentionModeKey: RetentionCompliance, retainUntilKey: "tomorrow"},
+		{retentionModeKey: RetentionCompliance},
+	}
+	for i, metadata := range invalid {
+		_, err := obj.PutObject(bucket, "invalid", int64(len(data)), bytes.NewReader(data), metadata)
+		if _, ok := err.(InvalidObjectRetention); !ok {
+			t.Fatalf("%s: Test %d: Expected InvalidObjectRetention, got %v", instanceType, i+1, err)
+		}
+		_, err = obj.NewMultipartUpload(bucket, "invalid", metadata)
+		if _, ok := err.(InvalidObjectRetention); !ok {
+			t.Fatalf("%s: Test %d: Expected InvalidObjectRetention, got %v", instanceType, i+1, err)
+		}
+	}
+}
+
+// Wrapper for calling multipart object retention tests for both XL multiple disks and single node setup.
+func TestObjectRetentionMultipart(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testObjectRetentionMultipart)
+}
+
+// Tests validate retention given when a multipart upload starts locks
+// the completed object, on XL it is kept in the multipart meta file.
+func testObjectRetentionMultipart(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	object := "minio-object"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	until := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	uploadID, err := obj.NewMultipartUpload(bucket, object, map[string]string{
+		retentionModeKey: RetentionCompliance,
+		retainUntilKey:   until.Format(time.RFC3339),
+	})
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello, world")
+	md5Sum, err := obj.PutObjectPart(bucket, object, uploadID, 1, int64(len(data)), bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, object, uploadID, []completePart{{PartNumber: 1, ETag: md5Sum}}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err = obj.DeleteObject(bucket, object); err == nil {
+		t.Fatalf("%s: Expected ObjectLocked", instanceType)
+	} else if locked, ok := err.(ObjectLocked); !ok || !locked.RetainUntil.Equal(until) {
+		t.Fatalf("%s: Expected ObjectLocked until %s, got %v", instanceType, until, err)
+	}
+
+	xl, ok := obj.(xlObjects)
+	if !ok {
+		return
+	}
+	// The multipart meta file alone keeps the object locked.
+	if err = deleteObjectMetadata(xl.storage, bucket, object); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err = obj.DeleteObject(bucket, object); err == nil {
+		t.Fatalf("%s: Expected ObjectLocked", instanceType)
+	} else if _, ok = err.(ObjectLocked); !ok {
+		t.Fatalf("%s: Expected ObjectLocked, got %v", instanceType, err)
+	}
+}
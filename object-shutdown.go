@@ -0,0 +1,46 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "sync"
+
+// shutdownSignal - tells the background goroutines of an object layer,
+// such as the replicator and the stale uploads sweeper, to stop.
+type shutdownSignal struct {
+	once   *sync.Once
+	doneCh chan struct{}
+}
+
+// newShutdownSignal - initialize a new shutdown signal.
+func newShutdownSignal() *shutdownSignal {
+	return &shutdownSignal{
+		once:   &sync.Once{},
+		doneCh: make(chan struct{}),
+	}
+}
+
+// done - returns a channel closed once shutdown is signaled.
+func (s *shutdownSignal) done() <-chan struct{} {
+	return s.doneCh
+}
+
+// signal - signals shutdown, later calls do nothing.
+func (s *shutdownSignal) signal() {
+	s.once.Do(func() {
+		close(s.doneCh)
+	})
+}
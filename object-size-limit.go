@@ -0,0 +1,99 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io"
+	"sync"
+)
+
+// objectSizeLimit - largest object PutObject accepts, enforced while
+// reading when the size isn't known up front.
+type objectSizeLimit struct {
+	mutex   *sync.Mutex
+	maxSize int64
+}
+
+// newObjectSizeLimit - initialize the object size limit to the S3 limit.
+func newObjectSizeLimit() *objectSizeLimit {
+	return &objectSizeLimit{
+		mutex:   &sync.Mutex{},
+		maxSize: maxObjectSize,
+	}
+}
+
+// get - returns the maximum object size.
+func (l *objectSizeLimit) get() int64 {
+	// Object layers opened for recovery use the S3 limit.
+	if l == nil {
+		return maxObjectSize
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.maxSize
+}
+
+// set - changes the maximum object size, fails with errInvalidArgument
+// unless maxSize is positive.
+func (l *objectSizeLimit) set(maxSize int64) error {
+	if maxSize <= 0 {
+		return errInvalidArgument
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.maxSize = maxSize
+	return nil
+}
+
+// limit - validates a known size against the maximum object size
+// before anything is read. Data of unknown size, size <= 0, is
+// returned wrapped to fail with ObjectTooLarge once it goes over.
+func (l *objectSizeLimit) limit(bucket, object string, size int64, data io.Reader) (io.Reader, error) {
+	maxSize := l.get()
+	err := ObjectTooLarge{Bucket: bucket, Object: object, MaxSize: maxSize}
+	if size > 0 {
+		if size > maxSize {
+			return nil, err
+		}
+		return data, nil
+	}
+	return &objectSizeReader{reader: data, remaining: maxSize, err: err}, nil
+}
+
+// objectSizeReader - fails with err once more than remaining bytes
+// are read.
+type objectSizeReader struct {
+	reader    io.Reader
+	remaining int64
+	err       error
+}
+
+func (r *objectSizeReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, r.err
+	}
+	// Read no more than needed to tell the data is too large.
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, r.err
+	}
+	return n, err
+}
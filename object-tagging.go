@@ -0,0 +1,123 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net/url"
+	"unicode/utf8"
+)
+
+const (
+	// Object metadata key of the tag set, URL query encoded.
+	tagsKey = "x-minio-tags"
+	// Internal CopyObject metadata key, taggingDirectiveReplace leaves
+	// the copy untagged instead of copying the source tags.
+	taggingDirectiveKey     = "x-minio-tagging-directive"
+	taggingDirectiveReplace = "REPLACE"
+)
+
+// S3 limits of object tag sets.
+const (
+	maxObjectTags     = 10
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+// checkObjectTags - validates a tag set against the S3 limits.
+func checkObjectTags(bucket, object string, tags map[string]string) error {
+	if len(tags) > maxObjectTags {
+		return InvalidObjectTags{Bucket: bucket, Object: object, Reason: "more than 10 tags"}
+	}
+	for k, v := range tags {
+		if k == "" || utf8.RuneCountInString(k) > maxTagKeyLength {
+			return InvalidObjectTags{Bucket: bucket, Object: object, Reason: "tag key must be 1 to 128 characters"}
+		}
+		if utf8.RuneCountInString(v) > maxTagValueLength {
+			return InvalidObjectTags{Bucket: bucket, Object: object, Reason: "tag value longer than 256 characters"}
+		}
+	}
+	return nil
+}
+
+// formatTags - encodes a tag set for the object metadata.
+func formatTags(tags map[string]string) string {
+	values := make(url.Values)
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+// parseTags - decodes a tag set saved by formatTags.
+func parseTags(value string) (map[string]string, error) {
+	values, err := url.ParseQuery(value)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(values))
+	for k := range values {
+		tags[k] = values.Get(k)
+	}
+	return tags, nil
+}
+
+// putObjectTags - common function to replace the tag set of an object
+// for both object layers, an empty tag set removes the tags. Only the
+// metadata is rewritten.
+func putObjectTags(layer ObjectLayer, storage StorageAPI, bucket, object string, tags map[string]string) error {
+	if err := checkObjectTags(bucket, object, tags); err != nil {
+		return err
+	}
+	unlock := lockObject(bucket, object)
+	defer unlock()
+
+	// Verify if the object exists, also validates the names.
+	if _, err := layer.GetObjectInfo(bucket, object); err != nil {
+		return err
+	}
+	metadata, err := readObjectMetadata(storage, bucket, object)
+	if err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+	if len(tags) != 0 {
+		metadata[tagsKey] = formatTags(tags)
+	} else {
+		delete(metadata, tagsKey)
+	}
+	if err = saveObjectMetadata(storage, bucket, object, metadata); err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+	return nil
+}
+
+// getObjectTags - common function to get the tag set of an object for
+// both object layers, empty if it has no tags.
+func getObjectTags(layer ObjectLayer, storage StorageAPI, bucket, object string) (map[string]string, error) {
+	// Verify if the object exists, also validates the names.
+	if _, err := layer.GetObjectInfo(bucket, object); err != nil {
+		return nil, err
+	}
+	metadata, err := readObjectMetadata(storage, bucket, object)
+	if err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	tags, err := parseTags(metadata[tagsKey])
+	if err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	return tags, nil
+}
@@ -0,0 +1,142 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Wrapper for calling object tagging tests for both XL multiple disks and single node setup.
+func TestObjectTags(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testObjectTags)
+}
+
+// Tests validate tags are replaced without touching the data, are
+// limited as in S3, follow copies unless replaced and go away with
+// the object.
+func testObjectTags(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	object := "minio-object"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data := []byte("hello, world")
+	md5Sum, err := obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), map[string]string{"content-type": "text/plain"})
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	tags, err := obj.GetObjectTags(bucket, object)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(tags) != 0 {
+		t.Fatalf("%s: Expected no tags, got %v", instanceType, tags)
+	}
+
+	expected := map[string]string{"project": "minio", "cost center": "a&b=c", "empty": ""}
+	if err = obj.PutObjectTags(bucket, object, expected); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if tags, err = obj.GetObjectTags(bucket, object); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("%s: Expected tags %v, got %v", instanceType, expected, tags)
+	}
+	objInfo, err := obj.GetObjectInfo(bucket, object)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if objInfo.MD5Sum != md5Sum || objInfo.ContentType != "text/plain" {
+		t.Fatalf("%s: Expected MD5Sum %s and text/plain, got %s and %s", instanceType, md5Sum, objInfo.MD5Sum, objInfo.ContentType)
+	}
+
+	tooMany := make(map[string]string)
+	for i := 0; i <= maxObjectTags; i++ {
+		tooMany[string(rune('a'+i))] = "v"
+	}
+	invalidCases := []map[string]string{
+		tooMany,
+		{"": "v"},
+		{strings.Repeat("k", maxTagKeyLength+1): "v"},
+		{"k": strings.Repeat("v", maxTagValueLength+1)},
+	}
+	for i, invalid := range invalidCases {
+		err = obj.PutObjectTags(bucket, object, invalid)
+		if _, ok := err.(InvalidObjectTags); !ok {
+			t.Fatalf("%s: Test %d: Expected InvalidObjectTags, got %v", instanceType, i+1, err)
+		}
+	}
+	if err = obj.PutObjectTags(bucket, "missing", expected); err == nil {
+		t.Fatalf("%s: Expected ObjectNotFound", instanceType)
+	} else if _, ok := err.(ObjectNotFound); !ok {
+		t.Fatalf("%s: Expected ObjectNotFound, got %v", instanceType, err)
+	}
+
+	// Copies keep the tags unless told to replace them.
+	copyCases := []struct {
+		object   string
+		metadata map[string]string
+		tags     map[string]string
+	}{
+		{"copy", nil, expected},
+		{"copy-metadata", map[string]string{"content-type": "text/html"}, expected},
+		{"copy-replace", map[string]string{taggingDirectiveKey: taggingDirectiveReplace}, map[string]string{}},
+		// Onto itself.
+		{object, map[string]string{"content-type": "text/html"}, expected},
+	}
+	for i, testCase := range copyCases {
+		if _, err = obj.CopyObject(bucket, object, bucket, testCase.object, testCase.metadata); err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		if tags, err = obj.GetObjectTags(bucket, testCase.object); err != nil {
+			t.Fatalf("%s: Test %d: %s", instanceType, i+1, err.Error())
+		}
+		if !reflect.DeepEqual(tags, testCase.tags) {
+			t.Fatalf("%s: Test %d: Expected tags %v, got %v", instanceType, i+1, testCase.tags, tags)
+		}
+	}
+
+	if err = obj.DeleteObjectTags(bucket, object); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if tags, err = obj.GetObjectTags(bucket, object); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(tags) != 0 {
+		t.Fatalf("%s: Expected no tags, got %v", instanceType, tags)
+	}
+
+	// Deleting the object deletes its tags.
+	if err = obj.DeleteObject(bucket, "copy"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = obj.PutObject(bucket, "copy", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if tags, err = obj.GetObjectTags(bucket, "copy"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(tags) != 0 {
+		t.Fatalf("%s: Expected no tags on the new object, got %v", instanceType, tags)
+	}
+}
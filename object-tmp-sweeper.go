@@ -0,0 +1,104 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+var (
+	// Temp files untouched for this long belong to uploads which were
+	// abandoned, far longer than any upload in progress takes.
+	staleUploadExpiry = 24 * time.Hour
+	// Interval between two sweeps of tmpMetaPrefix.
+	staleUploadSweepInterval = time.Hour
+)
+
+// cleanupStaleUploads - deletes the files under tmpMetaPrefix which
+// weren't modified within expiry, returns the number of files deleted.
+// Files which can't be stat'ed, like ones still being written, are
+// left alone.
+func cleanupStaleUploads(storage StorageAPI, expiry time.Duration) (int, error) {
+	return cleanupTmpFiles(storage, retainSlash(pathJoin(tmpMetaPrefix)), expiry)
+}
+
+// cleanupTmpFiles - deletes the files under tmpPath inside
+// minioMetaBucket which weren't modified within expiry, returns the
+// number of files deleted.
+func cleanupTmpFiles(storage StorageAPI, tmpPath string, expiry time.Duration) (int, error) {
+	cutoff := time.Now().UTC().Add(-expiry)
+	deleted := 0
+	var sweepFunc func(string) error
+	sweepFunc = func(entryPath string) error {
+		if !strings.HasSuffix(entryPath, slashSeparator) {
+			fi, err := storage.StatFile(minioMetaBucket, entryPath)
+			if err != nil {
+				return nil
+			}
+			if fi.ModTime.After(cutoff) {
+				return nil
+			}
+			if err = storage.DeleteFile(minioMetaBucket, entryPath); err != nil {
+				if err == errFileNotFound {
+					// Renamed in place or deleted in the meantime.
+					return nil
+				}
+				return err
+			}
+			deleted++
+			return nil
+		}
+		entries, err := storage.ListDir(minioMetaBucket, entryPath)
+		if err != nil {
+			if err == errFileNotFound {
+				return nil
+			}
+			return err
+		}
+		for _, entry := range entries {
+			if err = sweepFunc(pathJoin(entryPath, entry)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err := sweepFunc(tmpPath)
+	return deleted, err
+}
+
+// runStaleUploadsSweeper - deletes stale temp files every
+// staleUploadSweepInterval, until doneCh is closed.
+func runStaleUploadsSweeper(logger Logger, storage StorageAPI, doneCh <-chan struct{}) {
+	ticker := time.NewTicker(staleUploadSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-doneCh:
+			return
+		case <-ticker.C:
+		}
+		deleted, err := cleanupStaleUploads(storage, staleUploadExpiry)
+		if err != nil {
+			logger.Errorf("Unable to cleanup stale temp files: %s", err)
+			continue
+		}
+		if deleted > 0 {
+			logger.Debugf("Deleted %d stale temp files", deleted)
+		}
+	}
+}
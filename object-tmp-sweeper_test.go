@@ -0,0 +1,79 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"path"
+	"testing"
+)
+
+// Wrapper for calling stale temp file cleanup tests for both XL multiple disks and single node setup.
+func TestCleanupStaleUploads(t *testing.T) {
+	ExecObjectLayerTest(t, testCleanupStaleUploads)
+}
+
+// Tests validate temp files are only deleted once they are older than
+// the expiry.
+func testCleanupStaleUploads(obj ObjectLayer, instanceType string, t *testing.T) {
+	var storage StorageAPI
+	switch layer := obj.(type) {
+	case fsObjects:
+		storage = layer.storage
+	case xlObjects:
+		storage = layer.storage
+	}
+	tmpFile := path.Join(tmpMetaPrefix, "bucket", "object", "upload.00001.tmp")
+	w, err := storage.CreateFile(minioMetaBucket, tmpFile)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = w.Write([]byte("abandoned")); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	// A fresh file is within the grace period.
+	deleted, err := cleanupStaleUploads(storage, staleUploadExpiry)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if deleted != 0 {
+		t.Fatalf("%s: Expected no files deleted, got %d", instanceType, deleted)
+	}
+	if _, err = storage.StatFile(minioMetaBucket, tmpFile); err != nil {
+		t.Fatalf("%s: Expected temp file to be kept, got %s", instanceType, err)
+	}
+
+	// Everything is stale without a grace period.
+	deleted, err = cleanupStaleUploads(storage, 0)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if deleted != 1 {
+		t.Fatalf("%s: Expected 1 file deleted, got %d", instanceType, deleted)
+	}
+	if _, err = storage.StatFile(minioMetaBucket, tmpFile); err != errFileNotFound {
+		t.Fatalf("%s: Expected errFileNotFound, got %v", instanceType, err)
+	}
+
+	// Sweeping an empty tmp directory is a no-op.
+	if deleted, err = cleanupStaleUploads(storage, 0); err != nil || deleted != 0 {
+		t.Fatalf("%s: Expected nothing to delete, got %d, %v", instanceType, deleted, err)
+	}
+}
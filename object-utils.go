@@ -17,7 +17,9 @@
 package main
 
 import (
+	"bytes"
 	"crypto/md5"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -85,9 +87,39 @@ func IsValidObjectName(object string) bool {
 	if strings.HasPrefix(object, slashSeparator) {
 		return false
 	}
+	// Reserved for sidecar metadata files.
+	if hasObjectMetaSuffix(object) {
+		return false
+	}
+	if hasReservedPathComponent(object) {
+		return false
+	}
 	return IsValidObjectPrefix(object)
 }
 
+// hasReservedPathComponent - returns true if the object name has a
+// component which doesn't resolve to itself once joined to the bucket
+// directory, or which collides with files kept alongside objects.
+func hasReservedPathComponent(object string) bool {
+	components := strings.Split(object, slashSeparator)
+	// The meta bucket tree holds format.json and other backend state.
+	if components[0] == minioMetaBucket {
+		return true
+	}
+	for _, component := range components {
+		switch {
+		case component == "", component == ".", component == "..":
+			// Cleaned away when joined, the name would alias another
+			// object or escape the bucket.
+			return true
+		case strings.HasSuffix(component, multipartSuffix):
+			// Reserved for the part files of XL objects.
+			return true
+		}
+	}
+	return false
+}
+
 // IsValidObjectPrefix verifies whether the prefix is a valid object name.
 // Its valid to have a empty prefix.
 func IsValidObjectPrefix(object string) bool {
@@ -101,6 +133,13 @@ func IsValidObjectPrefix(object string) bool {
 	if strings.ContainsAny(object, "`^*|\\\"") {
 		return false
 	}
+	// Reject prefixes whose directory leaves the bucket.
+	components := strings.Split(object, slashSeparator)
+	for _, component := range components[:len(components)-1] {
+		if component == "." || component == ".." {
+			return false
+		}
+	}
 	return true
 }
 
@@ -139,6 +178,47 @@ func completeMultipartMD5(parts ...completePart) (string, error) {
 	return s3MD5, nil
 }
 
+// expectedMD5 - MD5 the data of PutObject is expected to have.
+type expectedMD5 struct {
+	// Digest as given by the caller.
+	value string
+	// Given base64 encoded, like the Content-MD5 header.
+	base64 bool
+	sum    []byte
+}
+
+// getExpectedMD5 - returns the expected MD5 from metadata, hex encoded
+// in "md5Sum" or base64 encoded in "contentMd5", nil if neither is
+// set. Returns InvalidDigest if "contentMd5" isn't a base64 MD5.
+func getExpectedMD5(metadata map[string]string) (*expectedMD5, error) {
+	if md5Hex := metadata["md5Sum"]; md5Hex != "" {
+		// Malformed hex never matches, reported as BadDigest.
+		md5Sum, _ := hex.DecodeString(md5Hex)
+		return &expectedMD5{value: md5Hex, sum: md5Sum}, nil
+	}
+	if contentMD5 := metadata["contentMd5"]; contentMD5 != "" {
+		md5Sum, err := base64.StdEncoding.DecodeString(contentMD5)
+		if err != nil || len(md5Sum) != md5.Size {
+			return nil, InvalidDigest{ContentMD5: contentMD5}
+		}
+		return &expectedMD5{value: contentMD5, base64: true, sum: md5Sum}, nil
+	}
+	return nil, nil
+}
+
+// verify - returns BadDigest if md5Sum doesn't match, with the
+// calculated digest in the encoding the expected one was given in.
+func (e *expectedMD5) verify(md5Sum []byte) error {
+	if bytes.Equal(e.sum, md5Sum) {
+		return nil
+	}
+	calculated := hex.EncodeToString(md5Sum)
+	if e.base64 {
+		calculated = base64.StdEncoding.EncodeToString(md5Sum)
+	}
+	return BadDigest{e.value, calculated}
+}
+
 // byBucketName is a collection satisfying sort.Interface.
 type byBucketName []BucketInfo
 
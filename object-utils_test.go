@@ -93,6 +93,22 @@ func TestIsValidObjectName(t *testing.T) {
 		{"a/b/c/", false},
 		{"/a/b/c", false},
 		{string([]byte{0xff, 0xfe, 0xfd}), false},
+		// names escaping the bucket or aliasing other names.
+		{"..", false},
+		{"../object", false},
+		{"a/../../object", false},
+		{"a/./b", false},
+		{"a//b", false},
+		// names colliding with internal files.
+		{"object/00000.minio.multipart", false},
+		{"object.minio.multipart", false},
+		{".minio/format.json", false},
+		{".minio", false},
+		// reserved names only match whole components.
+		{"a..b", true},
+		{"...", true},
+		{".minio.object", true},
+		{"format.json", true},
 	}
 
 	for i, testCase := range testCases {
@@ -0,0 +1,593 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/skyrings/skyring-common/tools/uuid"
+)
+
+// Objects put in a bucket with versioning set get a version ID. An
+// object overwritten or deleted there isn't removed but moved inside
+// minioMetaBucket, to its name suffixed with its version ID, and added
+// to the version index of the object. Deletes add a delete marker to
+// the index too. Objects put while the bucket wasn't versioned have
+// the null version ID. Version indexes are only updated with the dedup
+// reference lock of the object held, which serializes replacing and
+// deleting the object.
+
+const (
+	// Retained versions and version indexes, inside minioMetaBucket.
+	versionsPrefix = "versions"
+	// Suffix of the version index of an object.
+	versionIndexSuffix = ".versions.json"
+	// Object metadata key of the version ID.
+	versionIDKey = "x-minio-version-id"
+	// Version ID of objects put while their bucket wasn't versioned.
+	nullVersionID = "null"
+)
+
+// objectVersionIndex - retained versions and delete markers of an
+// object, oldest first. The current object isn't part of it.
+type objectVersionIndex struct {
+	Versions []ObjectVersionInfo `json:"versions"`
+}
+
+// objectVersionPath - path of a retained version inside minioMetaBucket.
+func objectVersionPath(bucket, object, versionID string) string {
+	return path.Join(versionsPrefix, bucket, object) + "." + versionID
+}
+
+// objectVersionIndexPath - path of the version index of an object
+// inside minioMetaBucket.
+func objectVersionIndexPath(bucket, object string) string {
+	return path.Join(versionsPrefix, bucket, object) + versionIndexSuffix
+}
+
+// objectVersionID - version ID of an object from its info.
+func objectVersionID(objInfo ObjectInfo) string {
+	if objInfo.VersionID == "" {
+		return nullVersionID
+	}
+	return objInfo.VersionID
+}
+
+// readObjectVersions - reads the version index of an object, nil if
+// the object has no retained versions.
+func readObjectVersions(storage StorageAPI, bucket, object string) ([]ObjectVersionInfo, error) {
+	r, err := storage.ReadFile(minioMetaBucket, objectVersionIndexPath(bucket, object), 0)
+	if err != nil {
+		if err == errFileNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer r.Close()
+	var index objectVersionIndex
+	decoder := json.NewDecoder(r)
+	if err = decoder.Decode(&index); err != nil {
+		return nil, err
+	}
+	return index.Versions, nil
+}
+
+// writeObjectVersions - saves the version index of an object, an
+// empty index is removed.
+func writeObjectVersions(storage StorageAPI, bucket, object string, versions []ObjectVersionInfo) error {
+	indexPath := objectVersionIndexPath(bucket, object)
+	if len(versions) == 0 {
+		if err := storage.DeleteFile(minioMetaBucket, indexPath); err != nil && err != errFileNotFound {
+			return err
+		}
+		return nil
+	}
+	w, err := storage.CreateFile(minioMetaBucket, indexPath)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	if err = encoder.Encode(&objectVersionIndex{Versions: versions}); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	if err = w.Close(); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	return nil
+}
+
+// setBucketVersioning - common function to turn versioning of a bucket
+// on or off for both object layers. Versions retained so far are kept
+// either way.
+func setBucketVersioning(storage StorageAPI, bucket string, enabled bool) error {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(storage, bucket) {
+		return BucketNotFound{Bucket: bucket}
+	}
+	err := updateBucketMetadata(storage, bucket, func(meta *bucketMetadata) {
+		meta.Versioning = enabled
+	})
+	if err != nil {
+		return toObjectErr(err, bucket)
+	}
+	return nil
+}
+
+// newVersionID - returns a new unique version ID.
+func newVersionID() (string, error) {
+	id, err := uuid.New()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// withVersionID - returns the metadata of an object being written
+// with a new version ID if its bucket is versioned.
+func withVersionID(meta bucketMetadata, metadata map[string]string) (map[string]string, error) {
+	if !meta.Versioning {
+		return metadata, nil
+	}
+	versionID, err := newVersionID()
+	if err != nil {
+		return nil, err
+	}
+	objMetadata := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		objMetadata[k] = v
+	}
+	objMetadata[versionIDKey] = versionID
+	return objMetadata, nil
+}
+
+// retainedVersion - object moved aside as a version by
+// retainObjectVersion.
+type retainedVersion struct {
+	storage StorageAPI
+	bucket  string
+	object  string
+	info    ObjectVersionInfo
+	// markerID - version ID of the delete marker added along, if any.
+	markerID string
+}
+
+// retainObjectVersion - moves an object about to be overwritten or
+// deleted aside as a version and adds it to the version index of the
+// object, followed by a delete marker if deleted is set. objInfo is
+// the info of the object. An object with the null version ID replaces
+// any retained null version.
+func retainObjectVersion(storage StorageAPI, bucket, object string, objInfo ObjectInfo, deleted bool) (*retainedVersion, error) {
+	v := &retainedVersion{storage: storage, bucket: bucket, object: object}
+	v.info = ObjectVersionInfo{ObjectInfo: objInfo}
+	v.info.VersionID = objectVersionID(objInfo)
+	if v.info.VersionID == nullVersionID {
+		if err := dropObjectVersion(storage, bucket, object, nullVersionID); err != nil {
+			return nil, err
+		}
+	}
+	versions, err := readObjectVersions(storage, bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	versions = append(versions, v.info)
+	if deleted {
+		if v.markerID, err = newVersionID(); err != nil {
+			return nil, err
+		}
+		versions = append(versions, ObjectVersionInfo{
+			ObjectInfo: ObjectInfo{
+				Bucket:    bucket,
+				Name:      object,
+				ModTime:   time.Now().UTC(),
+				VersionID: v.markerID,
+			},
+			DeleteMarker: true,
+		})
+	}
+	metadata, err := readObjectMetadata(storage, bucket, object)
+	if err != nil {
+		return nil, err
+	}
+
+	versionPath := objectVersionPath(bucket, object, v.info.VersionID)
+	if err = storage.RenameFile(bucket, object, minioMetaBucket, versionPath); err != nil {
+		return nil, err
+	}
+	// Metadata of the object is saved for the version as well, the
+	// object may have a sidecar the caller still removes or replaces.
+	if err = writeObjectMetadata(storage, minioMetaBucket, versionPath, metadata); err == nil {
+		err = writeObjectVersions(storage, bucket, object, versions)
+	}
+	if err != nil {
+		if rerr := v.moveBack(); rerr != nil {
+			errorIf(rerr, "Unable to restore "+bucket+"/"+object+" from "+versionPath+".", nil)
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+// moveBack - puts the data of the object back in place.
+func (v *retainedVersion) moveBack() error {
+	versionPath := objectVersionPath(v.bucket, v.object, v.info.VersionID)
+	if err := v.storage.RenameFile(minioMetaBucket, versionPath, v.bucket, v.object); err != nil {
+		return err
+	}
+	return deleteObjectMetadata(v.storage, minioMetaBucket, versionPath)
+}
+
+// restore - puts the object back if it couldn't be replaced, and
+// removes the version and delete marker from the version index. Does
+// nothing for a nil version.
+func (v *retainedVersion) restore() error {
+	if v == nil {
+		return nil
+	}
+	if err := v.moveBack(); err != nil {
+		return err
+	}
+	versions, err := readObjectVersions(v.storage, v.bucket, v.object)
+	if err != nil {
+		return err
+	}
+	kept := versions[:0]
+	for _, version := range versions {
+		if version.VersionID != v.info.VersionID && version.VersionID != v.markerID {
+			kept = append(kept, version)
+		}
+	}
+	return writeObjectVersions(v.storage, v.bucket, v.object, kept)
+}
+
+// retainReplacedObject - retains an object about to be overwritten or
+// deleted as a version if its bucket is versioned, see
+// retainObjectVersion. Returns nil if the bucket isn't versioned or
+// there is no such object.
+func retainReplacedObject(layer ObjectLayer, storage StorageAPI, meta bucketMetadata, bucket, object string, deleted bool) (*retainedVersion, error) {
+	if !meta.Versioning {
+		return nil, nil
+	}
+	objInfo, err := layer.GetObjectInfo(bucket, object)
+	if err != nil {
+		if _, ok := err.(ObjectNotFound); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if objInfo.IsDir {
+		return nil, nil
+	}
+	return retainObjectVersion(storage, bucket, object, objInfo, deleted)
+}
+
+// retainDeletedObject - common function to delete an object of a
+// versioned bucket for both object layers, the object is retained as a
+// version followed by a delete marker and nothing is removed. A dryRun
+// only checks the object exists.
+func retainDeletedObject(layer ObjectLayer, storage StorageAPI, bucket, object string, dryRun bool) error {
+	objInfo, err := layer.GetObjectInfo(bucket, object)
+	if err != nil {
+		return err
+	}
+	if objInfo.IsDir {
+		return ObjectNotFound{Bucket: bucket, Object: object}
+	}
+	if dryRun {
+		return nil
+	}
+	if _, err = retainObjectVersion(storage, bucket, object, objInfo, true); err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+	if err = deleteObjectMetadata(storage, bucket, object); err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+	return nil
+}
+
+// dropObjectVersion - removes a retained version of an object along
+// with its data, if there is such a version.
+func dropObjectVersion(storage StorageAPI, bucket, object, versionID string) error {
+	versions, err := readObjectVersions(storage, bucket, object)
+	if err != nil {
+		return err
+	}
+	for i, version := range versions {
+		if version.VersionID != versionID {
+			continue
+		}
+		if !version.DeleteMarker {
+			if err = deleteObjectVersionData(storage, objectVersionPath(bucket, object, versionID)); err != nil {
+				return err
+			}
+		}
+		versions = append(versions[:i], versions[i+1:]...)
+		return writeObjectVersions(storage, bucket, object, versions)
+	}
+	return nil
+}
+
+// deleteObjectVersionData - removes the data and metadata of a
+// retained version, dropping its reference to a dedup blob.
+func deleteObjectVersionData(storage StorageAPI, versionPath string) error {
+	metadata, err := readObjectMetadata(storage, minioMetaBucket, versionPath)
+	if err != nil {
+		return err
+	}
+	multipart, err := isMultipartObject(storage, minioMetaBucket, versionPath)
+	if err != nil {
+		return err
+	}
+	plan, err := planObjectDelete(storage, minioMetaBucket, versionPath, multipart)
+	if err == nil {
+		err = plan.execute(storage, defaultDeletePartsConcurrency)
+	}
+	if err != nil && err != errFileNotFound {
+		return err
+	}
+	if sum := metadata[dedupBlobKey]; sum != "" {
+		return releaseDedupBlob(storage, sum)
+	}
+	return nil
+}
+
+// getObjectVersionInfoCommon - common function to look up a version of
+// an object for both object layers, an empty versionID is the current
+// object. current reports if the version is the current object.
+func getObjectVersionInfoCommon(layer ObjectLayer, storage StorageAPI, bucket, object, versionID string) (objInfo ObjectInfo, current bool, err error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return ObjectInfo{}, false, BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(storage, bucket) {
+		return ObjectInfo{}, false, BucketNotFound{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return ObjectInfo{}, false, ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	objInfo, err = layer.GetObjectInfo(bucket, object)
+	if err == nil {
+		if versionID == "" || objectVersionID(objInfo) == versionID {
+			return objInfo, true, nil
+		}
+	} else if _, ok := err.(ObjectNotFound); !ok || versionID == "" {
+		return ObjectInfo{}, false, err
+	}
+	versions, err := readObjectVersions(storage, bucket, object)
+	if err != nil {
+		return ObjectInfo{}, false, toObjectErr(err, bucket, object)
+	}
+	for _, version := range versions {
+		if version.VersionID != versionID {
+			continue
+		}
+		if version.DeleteMarker {
+			// A delete marker has no data, it reads like the object
+			// it deleted.
+			return ObjectInfo{}, false, ObjectNotFound{Bucket: bucket, Object: object}
+		}
+		return version.ObjectInfo, false, nil
+	}
+	return ObjectInfo{}, false, VersionNotFound{Bucket: bucket, Object: object, VersionID: versionID}
+}
+
+// getObjectVersionCommon - common function to read a version of an
+// object for both object layers, open reads a retained version at
+// versionPath inside minioMetaBucket.
+func getObjectVersionCommon(layer ObjectLayer, storage StorageAPI, bucket, object, versionID string, startOffset int64, open func(versionPath string, startOffset int64) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	objInfo, current, err := getObjectVersionInfoCommon(layer, storage, bucket, object, versionID)
+	if err != nil {
+		return nil, err
+	}
+	if current {
+		return layer.GetObject(bucket, object, startOffset)
+	}
+	if startOffset < 0 || startOffset > objInfo.Size {
+		return nil, InvalidRange{Bucket: bucket, Object: object, Offset: startOffset, Size: objInfo.Size}
+	}
+	return open(objectVersionPath(bucket, object, objInfo.VersionID), startOffset)
+}
+
+// versionedObjectKeys - sorted keys under prefix after marker with a
+// version index, the walk stops once done is closed.
+func versionedObjectKeys(layer ObjectLayer, bucket, prefix, marker string, done <-chan struct{}) ([]string, error) {
+	versionsDir := path.Join(versionsPrefix, bucket) + slashSeparator
+	walker := startCancelableTreeWalk(layer, minioMetaBucket, versionsDir+prefix, "", true, func(name string) bool {
+		return strings.HasSuffix(name, versionIndexSuffix)
+	}, done)
+	var keys []string
+	for walkResult := range walker.ch {
+		if walkResult.err != nil {
+			// File not found is a valid case.
+			if walkResult.err == errFileNotFound {
+				break
+			}
+			return nil, walkResult.err
+		}
+		key := strings.TrimSuffix(strings.TrimPrefix(walkResult.fileInfo.Name, versionsDir), versionIndexSuffix)
+		// Index names may match prefix where the key itself doesn't.
+		if strings.HasPrefix(key, prefix) && key > marker {
+			keys = append(keys, key)
+		}
+		if walkResult.end {
+			break
+		}
+	}
+	// Suffixed index names don't sort like the keys.
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// listObjectVersionsCommon - common function to list the versions of
+// objects under prefix for both object layers, by key and newest first
+// within a key. The listing starts after keyMarker, or after the
+// version versionIDMarker of keyMarker if set.
+func listObjectVersionsCommon(layer ObjectLayer, bucket, prefix, keyMarker, versionIDMarker string, maxKeys int) (ListObjectVersionsInfo, error) {
+	var storage StorageAPI
+	switch l := layer.(type) {
+	case xlObjects:
+		storage = l.storage
+	case fsObjects:
+		storage = l.storage
+	}
+
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return ListObjectVersionsInfo{}, BucketNameInvalid{Bucket: bucket}
+	}
+	// Verify whether the bucket exists.
+	if !isBucketExist(storage, bucket) {
+		return ListObjectVersionsInfo{}, BucketNotFound{Bucket: bucket}
+	}
+	if !IsValidObjectPrefix(prefix) {
+		return ListObjectVersionsInfo{}, ObjectNameInvalid{Bucket: bucket, Object: prefix}
+	}
+	// Verify if marker has prefix.
+	if keyMarker != "" && !strings.HasPrefix(keyMarker, prefix) {
+		return ListObjectVersionsInfo{}, InvalidMarkerPrefixCombination{
+			Marker: keyMarker,
+			Prefix: prefix,
+		}
+	}
+	if maxKeys == 0 {
+		return ListObjectVersionsInfo{}, nil
+	}
+	// Over flowing count - reset to maxObjectList.
+	if maxKeys < 0 || maxKeys > maxObjectList {
+		maxKeys = maxObjectList
+	}
+
+	// keyVersions - versions of a key, the current object first.
+	keyVersions := func(key string) ([]ObjectVersionInfo, error) {
+		var versions []ObjectVersionInfo
+		objInfo, err := layer.GetObjectInfo(bucket, key)
+		if err == nil && !objInfo.IsDir {
+			objInfo.VersionID = objectVersionID(objInfo)
+			versions = append(versions, ObjectVersionInfo{ObjectInfo: objInfo, IsLatest: true})
+		} else if err != nil {
+			if _, ok := err.(ObjectNotFound); !ok {
+				return nil, err
+			}
+		}
+		retained, err := readObjectVersions(storage, bucket, key)
+		if err != nil {
+			return nil, toObjectErr(err, bucket, key)
+		}
+		for i := len(retained) - 1; i >= 0; i-- {
+			version := retained[i]
+			version.IsLatest = len(versions) == 0
+			versions = append(versions, version)
+		}
+		return versions, nil
+	}
+	var result ListObjectVersionsInfo
+	// add - adds versions of key to the listing, false once full.
+	add := func(key string, versions []ObjectVersionInfo) bool {
+		for _, version := range versions {
+			if len(result.Versions) == maxKeys {
+				result.IsTruncated = true
+				return false
+			}
+			result.Versions = append(result.Versions, version)
+			result.NextKeyMarker = key
+			result.NextVersionIDMarker = version.VersionID
+		}
+		return true
+	}
+
+	// The walks are stopped once the listing is full.
+	done := make(chan struct{})
+	defer close(done)
+	keys, err := versionedObjectKeys(layer, bucket, prefix, keyMarker, done)
+	if err != nil {
+		return ListObjectVersionsInfo{}, toObjectErr(err, bucket, prefix)
+	}
+	objects := startCancelableTreeWalk(layer, bucket, prefix, keyMarker, true, nil, done)
+	// nextObject - next key of a current object, empty at the end.
+	nextObject := func() (string, error) {
+		walkResult, ok := <-objects.ch
+		if !ok {
+			return "", nil
+		}
+		if walkResult.err != nil {
+			// File not found is a valid case.
+			if walkResult.err == errFileNotFound {
+				return "", nil
+			}
+			return "", toObjectErr(walkResult.err, bucket, prefix)
+		}
+		return walkResult.fileInfo.Name, nil
+	}
+
+	full := false
+	if keyMarker != "" && versionIDMarker != "" {
+		// The rest of the versions of keyMarker come first.
+		versions, err := keyVersions(keyMarker)
+		if err != nil {
+			return ListObjectVersionsInfo{}, err
+		}
+		for i, version := range versions {
+			if version.VersionID == versionIDMarker {
+				full = !add(keyMarker, versions[i+1:])
+				break
+			}
+		}
+	}
+	object, err := nextObject()
+	if err != nil {
+		return ListObjectVersionsInfo{}, err
+	}
+	for !full && (object != "" || len(keys) > 0) {
+		// Merge the keys of current objects with those of retained
+		// versions.
+		var key string
+		if object == "" || (len(keys) > 0 && keys[0] <= object) {
+			key, keys = keys[0], keys[1:]
+		} else {
+			key = object
+		}
+		if key == object {
+			if object, err = nextObject(); err != nil {
+				return ListObjectVersionsInfo{}, err
+			}
+		}
+		versions, err := keyVersions(key)
+		if err != nil {
+			return ListObjectVersionsInfo{}, err
+		}
+		full = !add(key, versions)
+	}
+	if !result.IsTruncated {
+		result.NextKeyMarker = ""
+		result.NextVersionIDMarker = ""
+	}
+	return result, nil
+}
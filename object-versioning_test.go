@@ -0,0 +1,187 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+// Wrapper for calling versioning tests for both XL multiple disks and single node setup.
+func TestObjectVersioning(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testObjectVersioning)
+}
+
+// Tests validate that overwritten and deleted objects of a versioned
+// bucket stay readable by version ID and are listed newest first.
+func testObjectVersioning(obj ObjectLayer, instanceType string, t *testing.T) {
+	if err := obj.SetBucketVersioning("missing-bucket", true); err == nil {
+		t.Fatalf("%s: Expected BucketNotFound", instanceType)
+	} else if _, ok := err.(BucketNotFound); !ok {
+		t.Fatalf("%s: Expected BucketNotFound, got %v", instanceType, err)
+	}
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	putObject := func(object, content string) {
+		if _, err := obj.PutObject(bucket, object, int64(len(content)), bytes.NewReader([]byte(content)), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	currentVersionID := func(object string) string {
+		objInfo, err := obj.GetObjectInfo(bucket, object)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		return objInfo.VersionID
+	}
+	checkVersion := func(object, versionID, content string) {
+		objInfo, err := obj.GetObjectVersionInfo(bucket, object, versionID)
+		if err != nil {
+			t.Fatalf("%s: Version %s of %s: %s", instanceType, versionID, object, err)
+		}
+		if objInfo.Size != int64(len(content)) {
+			t.Fatalf("%s: Expected version %s of %s to be %d bytes, got %d", instanceType, versionID, object, len(content), objInfo.Size)
+		}
+		r, err := obj.GetObjectVersion(bucket, object, versionID, 1)
+		if err != nil {
+			t.Fatalf("%s: Version %s of %s: %s", instanceType, versionID, object, err)
+		}
+		got, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		if string(got) != content[1:] {
+			t.Fatalf("%s: Expected version %s of %s to read %q, got %q", instanceType, versionID, object, content[1:], got)
+		}
+	}
+
+	// Objects put before versioning is enabled have no version ID and
+	// become the null version.
+	putObject("a", "null version")
+	if versionID := currentVersionID("a"); versionID != "" {
+		t.Fatalf("%s: Expected no version ID, got %s", instanceType, versionID)
+	}
+	putObject("a", "unversioned overwrite")
+	result, err := obj.ListObjectVersions(bucket, "", "", "", 10)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(result.Versions) != 1 || result.Versions[0].VersionID != nullVersionID {
+		t.Fatalf("%s: Expected only the null version of an unversioned bucket, got %+v", instanceType, result.Versions)
+	}
+
+	if err = obj.SetBucketVersioning(bucket, true); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	putObject("a", "first version")
+	firstID := currentVersionID("a")
+	if firstID == "" {
+		t.Fatalf("%s: Expected a version ID in a versioned bucket", instanceType)
+	}
+	putObject("a", "second version")
+	secondID := currentVersionID("a")
+	putObject("b", "other object")
+	if err = obj.DeleteObject(bucket, "b"); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	checkVersion("a", nullVersionID, "unversioned overwrite")
+	checkVersion("a", firstID, "first version")
+	checkVersion("a", secondID, "second version")
+	checkVersion("a", "", "second version")
+	r, err := obj.GetObject(bucket, "a", 0)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	got, _ := ioutil.ReadAll(r)
+	r.Close()
+	if string(got) != "second version" {
+		t.Fatalf("%s: Expected the current object to read %q, got %q", instanceType, "second version", got)
+	}
+	if _, err = obj.GetObjectVersionInfo(bucket, "a", "missing-version"); err == nil {
+		t.Fatalf("%s: Expected VersionNotFound", instanceType)
+	} else if _, ok := err.(VersionNotFound); !ok {
+		t.Fatalf("%s: Expected VersionNotFound, got %v", instanceType, err)
+	}
+	// A deleted object reads like it is gone, its data is kept.
+	if _, err = obj.GetObject(bucket, "b", 0); err == nil {
+		t.Fatalf("%s: Expected ObjectNotFound for a deleted object", instanceType)
+	} else if _, ok := err.(ObjectNotFound); !ok {
+		t.Fatalf("%s: Expected ObjectNotFound, got %v", instanceType, err)
+	}
+
+	testCases := []struct {
+		versionID    string
+		isLatest     bool
+		deleteMarker bool
+	}{
+		{secondID, true, false},
+		{firstID, false, false},
+		{nullVersionID, false, false},
+		{"", true, true},
+		{"", false, false},
+	}
+	result, err = obj.ListObjectVersions(bucket, "", "", "", 10)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if result.IsTruncated {
+		t.Fatalf("%s: Expected a complete listing", instanceType)
+	}
+	if len(result.Versions) != len(testCases) {
+		t.Fatalf("%s: Expected %d versions, got %d", instanceType, len(testCases), len(result.Versions))
+	}
+	for i, testCase := range testCases {
+		version := result.Versions[i]
+		if testCase.versionID != "" && version.VersionID != testCase.versionID {
+			t.Errorf("%s: Test %d: Expected version %s, got %s", instanceType, i+1, testCase.versionID, version.VersionID)
+		}
+		if version.IsLatest != testCase.isLatest {
+			t.Errorf("%s: Test %d: Expected IsLatest %v, got %v", instanceType, i+1, testCase.isLatest, version.IsLatest)
+		}
+		if version.DeleteMarker != testCase.deleteMarker {
+			t.Errorf("%s: Test %d: Expected DeleteMarker %v, got %v", instanceType, i+1, testCase.deleteMarker, version.DeleteMarker)
+		}
+	}
+	// The deleted object is readable by the version ID it had.
+	checkVersion("b", result.Versions[4].VersionID, "other object")
+	if _, err = obj.GetObjectVersion(bucket, "b", result.Versions[3].VersionID, 0); err == nil {
+		t.Fatalf("%s: Expected ObjectNotFound for a delete marker", instanceType)
+	}
+
+	// Paging one version at a time gives the same listing.
+	var keyMarker, versionIDMarker string
+	for i := range testCases {
+		page, err := obj.ListObjectVersions(bucket, "", keyMarker, versionIDMarker, 1)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		if len(page.Versions) != 1 || page.Versions[0].VersionID != result.Versions[i].VersionID {
+			t.Fatalf("%s: Page %d: Expected version %s, got %+v", instanceType, i+1, result.Versions[i].VersionID, page.Versions)
+		}
+		if page.IsTruncated != (i < len(testCases)-1) {
+			t.Fatalf("%s: Page %d: Expected IsTruncated %v", instanceType, i+1, i < len(testCases)-1)
+		}
+		keyMarker, versionIDMarker = page.NextKeyMarker, page.NextVersionIDMarker
+	}
+}
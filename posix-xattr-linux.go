@@ -0,0 +1,64 @@
+// +build linux
+
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "syscall"
+
+// setXattr - sets extended attribute name on filePath.
+func setXattr(filePath, name string, value []byte) error {
+	err := syscall.Setxattr(filePath, name, value, 0)
+	if err == syscall.ENOTSUP || err == syscall.EOPNOTSUPP {
+		return errXattrNotSupported
+	}
+	// Values over the size limit of the kernel fail with E2BIG, those
+	// which don't fit the space the filesystem has for them with ENOSPC.
+	if err == syscall.E2BIG || err == syscall.ENOSPC {
+		return errXattrTooLarge
+	}
+	return err
+}
+
+// getXattr - returns extended attribute name of filePath, returns
+// errFileNotFound if the attribute is not set.
+func getXattr(filePath, name string) ([]byte, error) {
+	for {
+		// Query the size of the attribute first.
+		size, err := syscall.Getxattr(filePath, name, nil)
+		if err != nil {
+			if err == syscall.ENODATA {
+				return nil, errFileNotFound
+			}
+			if err == syscall.ENOTSUP || err == syscall.EOPNOTSUPP {
+				return nil, errXattrNotSupported
+			}
+			return nil, err
+		}
+		value := make([]byte, size)
+		size, err = syscall.Getxattr(filePath, name, value)
+		if err == syscall.ERANGE || err == syscall.ENODATA {
+			// Attribute grew or was removed since its size was
+			// queried.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return value[:size], nil
+	}
+}
@@ -17,9 +17,12 @@
 package main
 
 import (
+	"encoding/json"
 	"io"
+	"io/ioutil"
 	"os"
 	slashpath "path"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"syscall"
@@ -37,6 +40,12 @@ const (
 type fsStorage struct {
 	diskPath    string
 	minFreeDisk int64
+	// Set if the filesystem supports extended attributes, probed
+	// once at startup.
+	xattrSupported bool
+	// Set if the filesystem treats names differing only in case as
+	// the same file, probed once at startup.
+	caseInsensitive bool
 }
 
 // checkPathLength - returns error if given path name length more than 255
@@ -113,13 +122,29 @@ func newPosix(diskPath string) (StorageAPI, error) {
 		}).Debugf("Disk %s.", syscall.ENOTDIR)
 		return fs, syscall.ENOTDIR
 	}
+	fs.xattrSupported = probeXattr(diskPath)
+	fs.caseInsensitive = probeCaseInsensitive(diskPath)
 	log.WithFields(logrus.Fields{
-		"diskPath":    diskPath,
-		"minFreeDisk": fsMinSpacePercent,
+		"diskPath":        diskPath,
+		"minFreeDisk":     fsMinSpacePercent,
+		"xattrSupported":  fs.xattrSupported,
+		"caseInsensitive": fs.caseInsensitive,
 	}).Debugf("Successfully configured FS storage API.")
 	return fs, nil
 }
 
+// probeXattr - verifies if extended attributes can be set on files
+// created under diskPath.
+func probeXattr(diskPath string) bool {
+	f, err := ioutil.TempFile(diskPath, "$xattr-probe")
+	if err != nil {
+		return false
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	return setXattr(f.Name(), objectMetaXattr, []byte("{}")) == nil
+}
+
 // checkDiskFree verifies if disk path has sufficient minium free disk space.
 func checkDiskFree(diskPath string, minFreeDisk int64) (err error) {
 	if err = checkPathLength(diskPath); err != nil {
@@ -691,3 +716,132 @@ func (s fsStorage) RenameFile(srcVolume, srcPath, dstVolume, dstPath string) err
 	}
 	return nil
 }
+
+// probeCaseInsensitive - verifies if a file created under diskPath can
+// be found with its name in a different case.
+func probeCaseInsensitive(diskPath string) bool {
+	f, err := ioutil.TempFile(diskPath, "$CASE-PROBE")
+	if err != nil {
+		return false
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	lowerName := filepath.Join(filepath.Dir(f.Name()), strings.ToLower(filepath.Base(f.Name())))
+	_, err = os.Stat(lowerName)
+	return err == nil
+}
+
+// IsCaseInsensitive - returns true if names differing only in case
+// refer to the same file on this disk.
+func (s fsStorage) IsCaseInsensitive() bool {
+	return s.caseInsensitive
+}
+
+// SetFileMetadata - saves metadata as an extended attribute of the
+// file, returns errXattrNotSupported if the filesystem can't.
+func (s fsStorage) SetFileMetadata(volume, path string, metadata map[string]string) error {
+	if !s.xattrSupported {
+		return errXattrNotSupported
+	}
+	volumeDir, err := s.getVolumeDir(volume)
+	if err != nil {
+		return err
+	}
+	filePath := slashpath.Join(volumeDir, path)
+	if err = checkPathLength(filePath); err != nil {
+		return err
+	}
+	value, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	if err = setXattr(filePath, objectMetaXattr, value); err != nil {
+		if os.IsNotExist(err) {
+			return errFileNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// GetFileMetadata - reads metadata saved as an extended attribute of
+// the file, returns errFileNotFound if the file has none.
+func (s fsStorage) GetFileMetadata(volume, path string) (map[string]string, error) {
+	if !s.xattrSupported {
+		return nil, errXattrNotSupported
+	}
+	volumeDir, err := s.getVolumeDir(volume)
+	if err != nil {
+		return nil, err
+	}
+	filePath := slashpath.Join(volumeDir, path)
+	if err = checkPathLength(filePath); err != nil {
+		return nil, err
+	}
+	value, err := getXattr(filePath, objectMetaXattr)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errFileNotFound
+		}
+		return nil, err
+	}
+	metadata := make(map[string]string)
+	if err = json.Unmarshal(value, &metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// syncPath - fsyncs the file or directory at path.
+func syncPath(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// SyncFile - flushes the file and the directories leading to it from
+// the volume to stable storage, so a completed rename survives a crash.
+func (s fsStorage) SyncFile(volume, path string) error {
+	volumeDir, err := s.getVolumeDir(volume)
+	if err != nil {
+		return err
+	}
+	filePath := slashpath.Join(volumeDir, path)
+	if err = checkPathLength(filePath); err != nil {
+		return err
+	}
+	if err = syncPath(filePath); err != nil {
+		if os.IsNotExist(err) {
+			return errFileNotFound
+		}
+		log.WithFields(logrus.Fields{
+			"diskPath": s.diskPath,
+			"filePath": filePath,
+		}).Errorf("Sync failed with %s", err)
+		return err
+	}
+	// Directories can't be opened for fsync on MS Windows, renames are
+	// journaled by NTFS there.
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	volumeDir = slashpath.Clean(volumeDir)
+	for dir := slashpath.Dir(filePath); ; dir = slashpath.Dir(dir) {
+		if err = syncPath(dir); err != nil {
+			log.WithFields(logrus.Fields{
+				"diskPath": s.diskPath,
+				"dirPath":  dir,
+			}).Errorf("Sync failed with %s", err)
+			return err
+		}
+		if dir == volumeDir || len(dir) <= len(volumeDir) {
+			return nil
+		}
+	}
+}
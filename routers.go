@@ -24,19 +24,19 @@ import (
 
 // newObjectLayer - initialize any object layer depending on the
 // number of export paths.
-func newObjectLayer(exportPaths ...string) (ObjectLayer, error) {
+func newObjectLayer(dataBlocks, parityBlocks int, exportPaths ...string) (ObjectLayer, error) {
 	if len(exportPaths) == 1 {
 		exportPath := exportPaths[0]
 		// Initialize FS object layer.
 		return newFSObjects(exportPath)
 	}
 	// Initialize XL object layer.
-	return newXLObjects(exportPaths...)
+	return newXLObjectsWithErasure(dataBlocks, parityBlocks, exportPaths...)
 }
 
 // configureServer handler returns final handler for the http server.
 func configureServerHandler(srvCmdConfig serverCmdConfig) http.Handler {
-	objAPI, err := newObjectLayer(srvCmdConfig.exportPaths...)
+	objAPI, err := newObjectLayer(srvCmdConfig.dataBlocks, srvCmdConfig.parityBlocks, srvCmdConfig.exportPaths...)
 	fatalIf(err, "Initializing object layer failed.", nil)
 
 	// Initialize storage rpc server.
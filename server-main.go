@@ -39,6 +39,18 @@ var serverCmd = cli.Command{
 			Name:  "address",
 			Value: ":9000",
 		},
+		cli.IntFlag{
+			Name:  "data-blocks",
+			Usage: "Number of erasure data blocks, used when formatting new disks.",
+		},
+		cli.IntFlag{
+			Name:  "parity-blocks",
+			Usage: "Number of erasure parity blocks, used when formatting new disks.",
+		},
+		cli.BoolFlag{
+			Name:  "maintenance",
+			Usage: "Start in maintenance mode, allowing destructive admin operations.",
+		},
 	},
 	Action: serverMain,
 	CustomHelpTemplate: `NAME:
@@ -67,12 +79,25 @@ EXAMPLES:
   4. Start minio server 8 disks to enable erasure coded layer with 4 data and 4 parity.
       $ minio {{.Name}} /mnt/export1/backend /mnt/export2/backend /mnt/export3/backend /mnt/export4/backend \
           /mnt/export5/backend /mnt/export6/backend /mnt/export7/backend /mnt/export8/backend
+
+  5. Start minio server on 8 new disks with 6 data and 2 parity.
+      $ minio {{.Name}} --data-blocks 6 --parity-blocks 2 /mnt/export1/backend /mnt/export2/backend \
+          /mnt/export3/backend /mnt/export4/backend /mnt/export5/backend /mnt/export6/backend \
+          /mnt/export7/backend /mnt/export8/backend
+
+  6. Start minio server in maintenance mode, allowing destructive admin operations.
+      $ minio {{.Name}} --maintenance /mnt/export1/backend /mnt/export2/backend /mnt/export3/backend \
+          /mnt/export4/backend /mnt/export5/backend /mnt/export6/backend /mnt/export7/backend \
+          /mnt/export8/backend
 `,
 }
 
 type serverCmdConfig struct {
 	serverAddr  string
 	exportPaths []string
+	// Erasure split requested at the command-line, zero if unset.
+	dataBlocks   int
+	parityBlocks int
 }
 
 // configureServer configure a new server instance
@@ -263,10 +288,17 @@ func serverMain(c *cli.Context) {
 	// Save all command line args as export paths.
 	exportPaths := c.Args()
 
+	// Destructive admin operations only run in maintenance mode.
+	if c.Bool("maintenance") {
+		EnterMaintenanceMode()
+	}
+
 	// Configure server.
 	apiServer := configureServer(serverCmdConfig{
-		serverAddr:  serverAddress,
-		exportPaths: exportPaths,
+		serverAddr:   serverAddress,
+		exportPaths:  exportPaths,
+		dataBlocks:   c.Int("data-blocks"),
+		parityBlocks: c.Int("parity-blocks"),
 	})
 
 	// Credential.
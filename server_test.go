@@ -18,6 +18,7 @@ package main
 
 import (
 	"bytes"
+	"compress/gzip"
 	"crypto/md5"
 	"io"
 	"io/ioutil"
@@ -837,14 +838,14 @@ func (s *MyAPISuite) TestContentTypePersists(c *C) {
 
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
-	c.Assert(response.Header.Get("Content-Type"), Equals, "application/octet-stream")
+	c.Assert(response.Header.Get("Content-Type"), Equals, "application/json")
 
 	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/contenttype-persists/two", 0, nil)
 	c.Assert(err, IsNil)
 
 	response, err = client.Do(request)
 	c.Assert(err, IsNil)
-	c.Assert(response.Header.Get("Content-Type"), Equals, "application/octet-stream")
+	c.Assert(response.Header.Get("Content-Type"), Equals, "application/json")
 }
 
 func (s *MyAPISuite) TestPartialContent(c *C) {
@@ -1007,6 +1008,67 @@ func (s *MyAPISuite) TestGetObjectRangeErrors(c *C) {
 	verifyError(c, response, "InvalidRange", "The requested range cannot be satisfied.", http.StatusRequestedRangeNotSatisfiable)
 }
 
+func (s *MyAPISuite) TestGetObjectContentDecoding(c *C) {
+	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/getobjectcontentdecoding", 0, nil)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	var encoded bytes.Buffer
+	gzipWriter := gzip.NewWriter(&encoded)
+	_, err = gzipWriter.Write([]byte("Hello World"))
+	c.Assert(err, IsNil)
+	c.Assert(gzipWriter.Close(), IsNil)
+	buffer1 := bytes.NewReader(encoded.Bytes())
+	request, err = s.newRequest("PUT", testAPIFSCacheServer.URL+"/getobjectcontentdecoding/bar", int64(buffer1.Len()), buffer1)
+	c.Assert(err, IsNil)
+	request.Header.Set("Content-Encoding", "gzip")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// Clients not accepting gzip get the decoded content.
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/getobjectcontentdecoding/bar", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Accept-Encoding", "identity")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("Content-Encoding"), Equals, "")
+	object, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(object), Equals, "Hello World")
+
+	// Ranges of the stored content are served as stored.
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/getobjectcontentdecoding/bar", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Accept-Encoding", "gzip")
+	request.Header.Add("Range", "bytes=0-3")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusPartialContent)
+	c.Assert(response.Header.Get("Content-Encoding"), Equals, "gzip")
+	partialObject, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(partialObject, DeepEquals, encoded.Bytes()[:4])
+
+	// Ranges can't be served from the decoded content.
+	request, err = s.newRequest("GET", testAPIFSCacheServer.URL+"/getobjectcontentdecoding/bar", 0, nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("Accept-Encoding", "identity")
+	request.Header.Add("Range", "bytes=0-3")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidRange", "Ranges can't be requested from an object whose content encoding isn't accepted.", http.StatusRequestedRangeNotSatisfiable)
+}
+
 func (s *MyAPISuite) TestObjectMultipartAbort(c *C) {
 	request, err := s.newRequest("PUT", testAPIFSCacheServer.URL+"/objectmultipartabort", 0, nil)
 	c.Assert(err, IsNil)
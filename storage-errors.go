@@ -55,5 +55,23 @@ var errReadQuorum = errors.New("I/O error.  did not meet read quorum.")
 // errWriteQuorum - did not meet write quorum.
 var errWriteQuorum = errors.New("I/O error.  did not meet write quorum.")
 
+// errCorruptData - block failed verification and could not be reconstructed.
+var errCorruptData = errors.New("Block verification failed after reconstruction, data likely corrupted")
+
 // errDataCorrupt - err data corrupt.
 var errDataCorrupt = errors.New("data likely corrupted, all blocks are zero in length")
+
+// errXattrNotSupported - extended attributes not supported by the
+// underlying filesystem.
+var errXattrNotSupported = errors.New("extended attributes not supported")
+
+// errXattrTooLarge - extended attribute value too large for the
+// underlying filesystem to hold.
+var errXattrTooLarge = errors.New("extended attribute too large")
+
+// errLayoutMismatch - file was erasure coded with a different number of
+// data and parity blocks than the disks are read with.
+var errLayoutMismatch = errors.New("erasure layout doesn't match the layout the file was written with")
+
+// errSyncNotSupported - storage can't flush files to stable storage.
+var errSyncNotSupported = errors.New("syncing files to stable storage not supported")
@@ -51,7 +51,8 @@ type treeWalker struct {
 }
 
 // treeWalk walks FS directory tree recursively pushing fileInfo into the channel as and when it encounters files.
-func treeWalk(layer ObjectLayer, bucket, prefixDir, entryPrefixMatch, marker string, recursive bool, send func(treeWalkResult) bool, count *int) bool {
+// If filter is set only files whose full name matches it are sent, skipped without a StatFile.
+func treeWalk(layer ObjectLayer, bucket, prefixDir, entryPrefixMatch, marker string, recursive bool, filter func(string) bool, send func(treeWalkResult) bool, count *int) bool {
 	// Example:
 	// if prefixDir="one/two/three/" and marker="four/five.txt" treeWalk is recursively
 	// called with prefixDir="one/two/three/four/" and marker="five.txt"
@@ -181,12 +182,17 @@ func treeWalk(layer ObjectLayer, bucket, prefixDir, entryPrefixMatch, marker str
 			}
 			*count--
 			prefixMatch := "" // Valid only for first level treeWalk and empty for subdirectories.
-			if !treeWalk(layer, bucket, path.Join(prefixDir, entry), prefixMatch, markerArg, recursive, send, count) {
+			if !treeWalk(layer, bucket, path.Join(prefixDir, entry), prefixMatch, markerArg, recursive, filter, send, count) {
 				return false
 			}
 			continue
 		}
 		*count--
+		if filter != nil && !strings.HasSuffix(entry, slashSeparator) {
+			if !filter(path.Join(prefixDir, strings.TrimSuffix(entry, multipartSuffix))) {
+				continue
+			}
+		}
 		fileInfo, err := entryToFileInfo(entry)
 		if err != nil {
 			// The file got deleted in the interim between ListDir() and StatFile()
@@ -202,6 +208,19 @@ func treeWalk(layer ObjectLayer, bucket, prefixDir, entryPrefixMatch, marker str
 
 // Initiate a new treeWalk in a goroutine.
 func startTreeWalk(layer ObjectLayer, bucket, prefix, marker string, recursive bool) *treeWalker {
+	return startFilteredTreeWalk(layer, bucket, prefix, marker, recursive, nil)
+}
+
+// Initiate a new treeWalk in a goroutine, sending only the files
+// matching filter.
+func startFilteredTreeWalk(layer ObjectLayer, bucket, prefix, marker string, recursive bool, filter func(string) bool) *treeWalker {
+	return startCancelableTreeWalk(layer, bucket, prefix, marker, recursive, filter, nil)
+}
+
+// Initiate a new treeWalk in a goroutine which stops once done is
+// closed. Without done the walk times out if its results aren't read
+// for a while, as a walk saved for a later page may never be resumed.
+func startCancelableTreeWalk(layer ObjectLayer, bucket, prefix, marker string, recursive bool, filter func(string) bool, done <-chan struct{}) *treeWalker {
 	// Example 1
 	// If prefix is "one/two/three/" and marker is "one/two/three/four/five.txt"
 	// treeWalk is called with prefixDir="one/two/three/" and marker="four/five.txt"
@@ -229,16 +248,21 @@ func startTreeWalk(layer ObjectLayer, bucket, prefix, marker string, recursive b
 			if count == 0 {
 				walkResult.end = true
 			}
-			timer := time.After(time.Second * 60)
+			var timer <-chan time.Time
+			if done == nil {
+				timer = time.After(time.Second * 60)
+			}
 			select {
 			case ch <- walkResult:
 				return true
 			case <-timer:
 				walkNotify.timedOut = true
 				return false
+			case <-done:
+				return false
 			}
 		}
-		treeWalk(layer, bucket, prefixDir, entryPrefixMatch, marker, recursive, send, &count)
+		treeWalk(layer, bucket, prefixDir, entryPrefixMatch, marker, recursive, filter, send, &count)
 	}()
 	return &walkNotify
 }
@@ -316,3 +340,35 @@ func lookupTreeWalk(layer ObjectLayer, params listParams) *treeWalker {
 	}
 	return nil
 }
+
+// invalidateTreeWalks - drops the saved walks of listings object is
+// part of after object was added or removed. A walk reads ahead of the
+// listing, its next page could return deleted objects and miss new
+// ones. The next page of such a listing walks afresh from its marker
+// instead, it reflects all changes after the marker and never repeats
+// keys before it.
+func invalidateTreeWalks(layer ObjectLayer, bucket, object string) {
+	var listObjectMap map[listParams][]*treeWalker
+	var listObjectMapMutex *sync.Mutex
+	switch l := layer.(type) {
+	case xlObjects:
+		listObjectMap = l.listObjectMap
+		listObjectMapMutex = l.listObjectMapMutex
+	case fsObjects:
+		listObjectMap = l.listObjectMap
+		listObjectMapMutex = l.listObjectMapMutex
+	}
+	// Object layers built for one off operations save no walks.
+	if listObjectMapMutex == nil {
+		return
+	}
+	listObjectMapMutex.Lock()
+	defer listObjectMapMutex.Unlock()
+
+	for params := range listObjectMap {
+		// Listings past the object returned it already, if at all.
+		if params.bucket == bucket && strings.HasPrefix(object, params.prefix) && params.marker < object {
+			delete(listObjectMap, params)
+		}
+	}
+}
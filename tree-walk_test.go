@@ -0,0 +1,134 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// Wrapper for calling listing consistency tests for both XL multiple disks and single node setup.
+func TestListObjectsWithMutations(t *testing.T) {
+	initNSLock()
+	ExecObjectLayerTest(t, testListObjectsWithMutations)
+}
+
+// Tests validate that pages following deletes and writes reflect them
+// and never repeat a key.
+func testListObjectsWithMutations(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	putObject := func(object string) {
+		if _, err := obj.PutObject(bucket, object, 1, bytes.NewReader([]byte("a")), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	for i := 0; i < 20; i++ {
+		putObject(fmt.Sprintf("object%02d", i))
+	}
+
+	result, err := obj.ListObjects(bucket, "", "", "", 5)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	marker := result.Objects[len(result.Objects)-1].Name
+	// Delete objects the saved walk has read ahead, add a new one.
+	for _, object := range []string{"object07", "object08"} {
+		if err = obj.DeleteObject(bucket, object); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	putObject("object10a")
+
+	seen := make(map[string]bool)
+	for _, objInfo := range result.Objects {
+		seen[objInfo.Name] = true
+	}
+	for result.IsTruncated {
+		result, err = obj.ListObjects(bucket, "", marker, "", 5)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		for _, objInfo := range result.Objects {
+			if seen[objInfo.Name] {
+				t.Fatalf("%s: %s listed twice", instanceType, objInfo.Name)
+			}
+			seen[objInfo.Name] = true
+			marker = objInfo.Name
+		}
+	}
+	if seen["object07"] || seen["object08"] {
+		t.Fatalf("%s: Deleted objects were listed", instanceType)
+	}
+	if !seen["object10a"] {
+		t.Fatalf("%s: New object after the marker wasn't listed", instanceType)
+	}
+	if len(seen) != 19 {
+		t.Fatalf("%s: Expected 19 objects, got %d", instanceType, len(seen))
+	}
+}
+
+// Wrapper for calling concurrent listing tests for both XL multiple disks and single node setup.
+func TestListObjectsConcurrentDeletes(t *testing.T) {
+	initNSLock()
+	ExecObjectLayerTest(t, testListObjectsConcurrentDeletes)
+}
+
+// Tests validate paginating while objects are deleted concurrently
+// neither fails nor repeats keys.
+func testListObjectsConcurrentDeletes(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	for i := 0; i < 50; i++ {
+		if _, err := obj.PutObject(bucket, fmt.Sprintf("dir/object%02d", i), 1, bytes.NewReader([]byte("a")), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 49; i >= 0; i -= 3 {
+			obj.DeleteObject(bucket, fmt.Sprintf("dir/object%02d", i))
+		}
+	}()
+	seen := make(map[string]bool)
+	marker := ""
+	for {
+		result, err := obj.ListObjects(bucket, "dir/", marker, "", 4)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		for _, objInfo := range result.Objects {
+			if seen[objInfo.Name] {
+				t.Fatalf("%s: %s listed twice", instanceType, objInfo.Name)
+			}
+			seen[objInfo.Name] = true
+			marker = objInfo.Name
+		}
+		if !result.IsTruncated {
+			break
+		}
+	}
+	wg.Wait()
+}
@@ -40,6 +40,8 @@ const (
 	maxObjectSize = 1024 * 1024 * 1024 * 5
 	// minimum Part size for multipart upload is 5MB
 	minPartSize = 1024 * 1024 * 5
+	// maximum Part size for multipart upload is 5GiB
+	maxPartSize = 1024 * 1024 * 1024 * 5
 )
 
 // isMaxObjectSize - verify if max object size
@@ -47,11 +49,6 @@ func isMaxObjectSize(size int64) bool {
 	return size > maxObjectSize
 }
 
-// Check if part size is more than or equal to minimum allowed size.
-func isMinAllowedPartSize(size int64) bool {
-	return size >= minPartSize
-}
-
 func contains(stringList []string, element string) bool {
 	for _, e := range stringList {
 		if e == element {
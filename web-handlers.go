@@ -415,6 +415,10 @@ func writeWebErrorResponse(w http.ResponseWriter, err error) {
 		apiErrCode = ErrWriteQuorum
 	case InsufficientReadQuorum:
 		apiErrCode = ErrReadQuorum
+	case SlowDown:
+		apiErrCode = ErrSlowDown
+	case ObjectTooLarge:
+		apiErrCode = ErrEntityTooLarge
 	default:
 		apiErrCode = ErrInternalError
 	}
@@ -55,6 +55,11 @@ func (xl XL) errsToStorageErr(errs []error) error {
 	diskNotFoundCount := 0
 	diskAccessDeniedCount := 0
 	for _, err := range errs {
+		if err == errFileNameTooLong {
+			// Name length is a property of the path, not of the
+			// disk, treat errFileNameTooLong specially.
+			return errFileNameTooLong
+		}
 		if err == errFileNotFound {
 			notFoundCount++
 		} else if err == errDiskNotFound {
@@ -63,6 +68,11 @@ func (xl XL) errsToStorageErr(errs []error) error {
 			diskAccessDeniedCount++
 		}
 	}
+	if xl.recovery && notFoundCount > 0 {
+		// Lost disks can't be told apart from disks without the
+		// file while recovering.
+		notFoundCount += diskNotFoundCount
+	}
 	// If we have errors with 'file not found' greater than
 	// readQuroum, return as errFileNotFound.
 	if notFoundCount > len(xl.storageDisks)-xl.readQuorum {
@@ -72,7 +82,7 @@ func (xl XL) errsToStorageErr(errs []error) error {
 	// number of disks, return as errDiskNotFound.
 	if diskNotFoundCount == len(xl.storageDisks) {
 		return errDiskNotFound
-	} else if diskNotFoundCount > len(xl.storageDisks)-xl.readQuorum {
+	} else if diskNotFoundCount > len(xl.storageDisks)-xl.minReadAgreement() {
 		// If we have errors with 'disk not found' greater than
 		// readQuroum, return as errFileNotFound.
 		return errFileNotFound
@@ -95,48 +105,128 @@ func (xl XL) listOnlineDisks(volume, path string) (onlineDisks []StorageAPI, mda
 	if err = xl.errsToStorageErr(errs); err != nil {
 		return nil, xlMetaV1{}, false, err
 	}
-	highestVersion := int64(0)
 	onlineDisks = make([]StorageAPI, len(xl.storageDisks))
 	// List all the file versions from partsMetadata list.
 	versions := listFileVersions(partsMetadata, errs)
 
-	// Get highest file version.
-	highestVersion = highestInt(versions)
+	// Pick the highest version agreed upon by read quorum of disks. A
+	// present disk whose metadata disagrees with it is stale, either it
+	// holds an older version or a different size and modTime for the
+	// same version.
+	quorumIndex := -1
+	quorumCount := 0
+	for index, version := range versions {
+		if version < 0 {
+			continue
+		}
+		count := 0
+		for i := range versions {
+			if versions[i] >= 0 && isSameStat(partsMetadata[index], partsMetadata[i]) {
+				count++
+			}
+		}
+		if count < xl.minReadAgreement() {
+			continue
+		}
+		if quorumIndex == -1 || version > versions[quorumIndex] {
+			quorumIndex = index
+			quorumCount = count
+		}
+	}
+	if quorumIndex == -1 {
+		log.WithFields(logrus.Fields{
+			"volume":          volume,
+			"path":            path,
+			"readQuorumCount": xl.readQuorum,
+		}).Errorf("%s", errReadQuorum)
+		return nil, xlMetaV1{}, false, errReadQuorum
+	}
+	mdata = partsMetadata[quorumIndex]
+	// Blocks can only be decoded with the layout they were encoded
+	// with.
+	if mdata.Erasure.DataBlocks != xl.DataBlocks || mdata.Erasure.ParityBlocks != xl.ParityBlocks {
+		return nil, xlMetaV1{}, false, errLayoutMismatch
+	}
 
-	// Pick online disks with version set to highestVersion.
-	onlineDiskCount := 0
+	// Pick online disks agreeing with the quorum metadata.
 	for index, version := range versions {
-		if version == highestVersion {
-			mdata = partsMetadata[index]
+		if version >= 0 && isSameStat(mdata, partsMetadata[index]) {
 			onlineDisks[index] = xl.storageDisks[index]
-			onlineDiskCount++
 		} else {
 			onlineDisks[index] = nil
 		}
 	}
 
 	// If online disks count is lesser than configured disks, most
-	// probably we need to heal the file, additionally verify if the
-	// count is lesser than readQuorum, if not we throw an error.
-	if onlineDiskCount < len(xl.storageDisks) {
-		// Online disks lesser than total storage disks, needs to be
-		// healed. unless we do not have readQuorum.
+	// probably we need to heal the file. A file read below read
+	// quorum can't be healed.
+	if quorumCount < len(xl.storageDisks) && quorumCount >= xl.readQuorum {
 		heal = true
-		// Verify if online disks count are lesser than readQuorum
-		// threshold, return an error if yes.
-		if onlineDiskCount < xl.readQuorum {
-			log.WithFields(logrus.Fields{
-				"volume":          volume,
-				"path":            path,
-				"onlineDiskCount": onlineDiskCount,
-				"readQuorumCount": xl.readQuorum,
-			}).Errorf("%s", errReadQuorum)
-			return nil, xlMetaV1{}, false, errReadQuorum
-		}
 	}
 	return onlineDisks, mdata, heal, nil
 }
 
+// minReadAgreement - number of disks which must agree on a file to
+// read it, read quorum or one less for degraded reads.
+func (xl XL) minReadAgreement() int {
+	if xl.degraded != nil {
+		return xl.readQuorum - 1
+	}
+	return xl.readQuorum
+}
+
+// isSameStat - returns true if both metadata describe the same version
+// of a file.
+func isSameStat(m1, m2 xlMetaV1) bool {
+	return m1.Stat.Version == m2.Stat.Version &&
+		m1.Stat.Size == m2.Stat.Size &&
+		m1.Stat.ModTime.Equal(m2.Stat.ModTime)
+}
+
+// checkReadQuorum - verifies a read quorum of disks agree on the file
+// at path from their metadata alone. Unlike listOnlineDisks, disks
+// which are offline never make the file look missing, too many of them
+// fail with errReadQuorum.
+func (xl XL) checkReadQuorum(volume, path string) error {
+	nsMutex.RLock(volume, path)
+	partsMetadata, errs := xl.getPartsMetadata(volume, path)
+	nsMutex.RUnlock(volume, path)
+
+	notFoundCount := 0
+	diskNotFoundCount := 0
+	for _, err := range errs {
+		if err == errFileNotFound {
+			notFoundCount++
+		} else if err == errDiskNotFound {
+			diskNotFoundCount++
+		}
+	}
+	if xl.recovery && notFoundCount > 0 {
+		// Lost disks can't be told apart from disks without the
+		// file while recovering.
+		notFoundCount += diskNotFoundCount
+	}
+	if notFoundCount > len(xl.storageDisks)-xl.readQuorum {
+		return errFileNotFound
+	}
+	versions := listFileVersions(partsMetadata, errs)
+	for index, version := range versions {
+		if version < 0 {
+			continue
+		}
+		count := 0
+		for i := range versions {
+			if versions[i] >= 0 && isSameStat(partsMetadata[index], partsMetadata[i]) {
+				count++
+			}
+		}
+		if count >= xl.minReadAgreement() {
+			return nil
+		}
+	}
+	return errReadQuorum
+}
+
 // Get file.json metadata as a map slice.
 // Returns error slice indicating the failed metadata reads.
 // Read lockNS() should be done by caller.
@@ -206,6 +206,7 @@ func (xl XL) writeErasure(volume, path string, reader *io.PipeReader, wcloser *w
 				}
 				encodedData := dataBlocks[index]
 				_, err = writers[index].Write(encodedData)
+				xl.health.record(index, err)
 				if err != nil {
 					log.WithFields(logrus.Fields{
 						"volume":    volume,
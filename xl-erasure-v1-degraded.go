@@ -0,0 +1,92 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io"
+	"sync"
+)
+
+// degradedReads - byte ranges of the files read degraded by an XL
+// which could not be reconstructed, by volume and path.
+type degradedReads struct {
+	mu      sync.Mutex
+	missing map[string][]ObjectByteRange
+}
+
+// newDegradedReads - returns an empty degradedReads.
+func newDegradedReads() *degradedReads {
+	return &degradedReads{missing: make(map[string][]ObjectByteRange)}
+}
+
+// record - adds length bytes at offset of the file at volume, path to
+// the missing ranges, merged with the range before if adjacent.
+func (d *degradedReads) record(volume, path string, offset, length int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := pathJoin(volume, path)
+	ranges := d.missing[key]
+	if n := len(ranges); n > 0 && ranges[n-1].Offset+ranges[n-1].Length == offset {
+		ranges[n-1].Length += length
+		return
+	}
+	d.missing[key] = append(ranges, ObjectByteRange{Offset: offset, Length: length})
+}
+
+// ranges - returns the missing ranges of the file at volume, path in
+// order.
+func (d *degradedReads) ranges(volume, path string) []ObjectByteRange {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]ObjectByteRange(nil), d.missing[pathJoin(volume, path)]...)
+}
+
+// empty - returns true if nothing is missing so far.
+func (d *degradedReads) empty() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.missing) == 0
+}
+
+// withDegradedReads - returns XL reading files agreed upon by one disk
+// less than read quorum, blocks which can't be reconstructed are read
+// with their missing data blocks zeroed and recorded in reads.
+func (xl XL) withDegradedReads(reads *degradedReads) StorageAPI {
+	xl.degraded = reads
+	return xl
+}
+
+// fillMissingBlocks - zeroes the data blocks of a block at blockOffset
+// of the file at path whose part couldn't be read, and records their
+// ranges.
+func (xl XL) fillMissingBlocks(volume, path string, enBlocks [][]byte, readers []io.ReadCloser, blockOffset, blockSize int64) {
+	encBlockSize := getEncodedBlockLen(blockSize, xl.DataBlocks)
+	for index := 0; index < xl.DataBlocks; index++ {
+		if readers[index] != nil {
+			continue
+		}
+		enBlocks[index] = make([]byte, encBlockSize)
+		start := blockOffset + int64(index)*encBlockSize
+		end := start + encBlockSize
+		if end > blockOffset+blockSize {
+			end = blockOffset + blockSize
+		}
+		if start < end {
+			xl.degraded.record(volume, path, start, end-start)
+		}
+	}
+}
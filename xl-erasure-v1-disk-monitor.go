@@ -0,0 +1,315 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	// Consecutive failed calls after which a disk is taken offline.
+	diskOfflineAfter = 5
+	// Consecutive successful probes after which an offline disk is
+	// taken back online.
+	diskOnlineAfter = 3
+	// Default interval between two probes of an offline disk, calls
+	// to it in between fail without reaching the disk.
+	diskProbeInterval = 10 * time.Second
+	// Calls taking longer count as failed even if they succeed.
+	diskSlowCallThreshold = 10 * time.Second
+)
+
+// DiskStatus - runtime status of a disk of an XL.
+type DiskStatus struct {
+	// Offline disks are left out of reads and writes, and hence out
+	// of quorum, until they recover.
+	Online bool
+	// Number of failed calls since the server started.
+	Errors uint64
+	// Number of failed calls in a row, or successful probes in a row
+	// while offline.
+	ConsecutiveFailures  int
+	ConsecutiveSuccesses int
+	// Moving average of the call latency.
+	Latency time.Duration
+	// Disk took the place of a disk with a different identity, it
+	// holds none of the data until the objects are healed.
+	NeedsHeal bool
+}
+
+// diskMonitor - tracks failures and latency of calls to the disks of
+// an XL and takes disks failing persistently offline. Both directions
+// need several results in a row, so that a disk failing now and then
+// doesn't flap in and out of quorum.
+type diskMonitor struct {
+	mutex         *sync.Mutex
+	status        []DiskStatus
+	lastProbe     []time.Time
+	probeInterval time.Duration
+	logger        Logger
+}
+
+// newDiskMonitor - initialize monitoring of disks, all online.
+func newDiskMonitor(disks int) *diskMonitor {
+	status := make([]DiskStatus, disks)
+	for index := range status {
+		status[index].Online = true
+	}
+	return &diskMonitor{
+		mutex:         &sync.Mutex{},
+		status:        status,
+		lastProbe:     make([]time.Time, disks),
+		probeInterval: diskProbeInterval,
+		logger:        log,
+	}
+}
+
+// setProbeInterval - sets the interval between two probes of an
+// offline disk.
+func (m *diskMonitor) setProbeInterval(interval time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.probeInterval = interval
+}
+
+// setLogger - sets the logger disks going offline and back online
+// are reported to.
+func (m *diskMonitor) setLogger(logger Logger) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.logger = logger
+}
+
+// admit - reports if a call may reach disk index, offline disks are
+// only reached by a probe every probe interval.
+func (m *diskMonitor) admit(index int) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.status[index].Online {
+		return true
+	}
+	now := time.Now().UTC()
+	if now.Sub(m.lastProbe[index]) < m.probeInterval {
+		return false
+	}
+	m.lastProbe[index] = now
+	return true
+}
+
+// isDiskFailure - errors saying the disk itself fails, as opposed to
+// expected outcomes of a call like a missing file.
+func isDiskFailure(err error) bool {
+	if err == errDiskNotFound {
+		return true
+	}
+	switch e := err.(type) {
+	case *os.PathError:
+		err = e.Err
+	case *os.LinkError:
+		err = e.Err
+	case *os.SyscallError:
+		err = e.Err
+	}
+	return err == syscall.EIO || err == syscall.EROFS
+}
+
+// record - accounts the result and latency of a call to disk index.
+func (m *diskMonitor) record(index int, latency time.Duration, err error) {
+	failed := isDiskFailure(err) || latency > diskSlowCallThreshold
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	status := &m.status[index]
+	if status.Latency == 0 {
+		status.Latency = latency
+	} else {
+		status.Latency = (status.Latency*7 + latency) / 8
+	}
+	if failed {
+		status.Errors++
+		status.ConsecutiveFailures++
+		status.ConsecutiveSuccesses = 0
+		if status.Online && status.ConsecutiveFailures >= diskOfflineAfter {
+			status.Online = false
+			m.lastProbe[index] = time.Now().UTC()
+			m.logger.Errorf("Disk %d taken offline after %d consecutive failures, last failed with %v", index, status.ConsecutiveFailures, err)
+		}
+		return
+	}
+	status.ConsecutiveFailures = 0
+	status.ConsecutiveSuccesses++
+	if !status.Online && status.ConsecutiveSuccesses >= diskOnlineAfter {
+		status.Online = true
+		m.logger.Infof("Disk %d back online after %d successful probes", index, status.ConsecutiveSuccesses)
+	}
+}
+
+// markNeedsHeal - flags disk index as needing its data healed.
+func (m *diskMonitor) markNeedsHeal(index int) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.status[index].NeedsHeal = true
+}
+
+// statuses - status of all disks by index.
+func (m *diskMonitor) statuses() map[int]DiskStatus {
+	statuses := make(map[int]DiskStatus)
+	if m == nil {
+		return statuses
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for index, status := range m.status {
+		statuses[index] = status
+	}
+	return statuses
+}
+
+// monitoredDisk - a disk of an XL whose calls are accounted by the
+// disk monitor, calls to an offline disk fail with errDiskNotFound.
+type monitoredDisk struct {
+	disk    StorageAPI
+	index   int
+	monitor *diskMonitor
+}
+
+// call - runs fn against the disk if admitted and records its result.
+func (d monitoredDisk) call(fn func() error) error {
+	if !d.monitor.admit(d.index) {
+		return errDiskNotFound
+	}
+	start := time.Now()
+	err := fn()
+	d.monitor.record(d.index, time.Since(start), err)
+	return err
+}
+
+// MakeVol - make a volume.
+func (d monitoredDisk) MakeVol(volume string) error {
+	return d.call(func() error {
+		return d.disk.MakeVol(volume)
+	})
+}
+
+// ListVols - list volumes.
+func (d monitoredDisk) ListVols() (vols []VolInfo, err error) {
+	err = d.call(func() error {
+		vols, err = d.disk.ListVols()
+		return err
+	})
+	return vols, err
+}
+
+// StatVol - get volume info.
+func (d monitoredDisk) StatVol(volume string) (vol VolInfo, err error) {
+	err = d.call(func() error {
+		vol, err = d.disk.StatVol(volume)
+		return err
+	})
+	return vol, err
+}
+
+// DeleteVol - delete a volume.
+func (d monitoredDisk) DeleteVol(volume string) error {
+	return d.call(func() error {
+		return d.disk.DeleteVol(volume)
+	})
+}
+
+// ListDir - list a directory.
+func (d monitoredDisk) ListDir(volume, dirPath string) (entries []string, err error) {
+	err = d.call(func() error {
+		entries, err = d.disk.ListDir(volume, dirPath)
+		return err
+	})
+	return entries, err
+}
+
+// ReadFile - open a file for reading at offset.
+func (d monitoredDisk) ReadFile(volume string, path string, offset int64) (reader io.ReadCloser, err error) {
+	err = d.call(func() error {
+		reader, err = d.disk.ReadFile(volume, path, offset)
+		return err
+	})
+	return reader, err
+}
+
+// CreateFile - create a file for writing.
+func (d monitoredDisk) CreateFile(volume string, path string) (writer io.WriteCloser, err error) {
+	err = d.call(func() error {
+		writer, err = d.disk.CreateFile(volume, path)
+		return err
+	})
+	return writer, err
+}
+
+// StatFile - get file info.
+func (d monitoredDisk) StatFile(volume string, path string) (fi FileInfo, err error) {
+	err = d.call(func() error {
+		fi, err = d.disk.StatFile(volume, path)
+		return err
+	})
+	return fi, err
+}
+
+// DeleteFile - delete a file.
+func (d monitoredDisk) DeleteFile(volume string, path string) error {
+	return d.call(func() error {
+		return d.disk.DeleteFile(volume, path)
+	})
+}
+
+// RenameFile - rename a file.
+func (d monitoredDisk) RenameFile(srcVolume, srcPath, dstVolume, dstPath string) error {
+	return d.call(func() error {
+		return d.disk.RenameFile(srcVolume, srcPath, dstVolume, dstPath)
+	})
+}
+
+// SyncFile - flush a file to stable storage, if the disk can.
+func (d monitoredDisk) SyncFile(volume string, path string) error {
+	syncer, ok := d.disk.(fileSyncer)
+	if !ok {
+		return errSyncNotSupported
+	}
+	return d.call(func() error {
+		return syncer.SyncFile(volume, path)
+	})
+}
+
+// IsCaseInsensitive - passes through the case sensitivity of the disk.
+func (d monitoredDisk) IsCaseInsensitive() bool {
+	cs, ok := d.disk.(caseInsensitiveStorage)
+	return ok && cs.IsCaseInsensitive()
+}
+
+// diskStatusReporter - storage reporting the runtime status of its
+// disks.
+type diskStatusReporter interface {
+	DiskStatuses() map[int]DiskStatus
+}
+
+// DiskStatuses - runtime status of each disk by index.
+func (xl XL) DiskStatuses() map[int]DiskStatus {
+	return xl.monitor.statuses()
+}
@@ -0,0 +1,241 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Tests validate a disk is only taken offline after consecutive
+// failures and only back online after consecutive successful probes.
+func TestDiskMonitorFlapping(t *testing.T) {
+	eio := &os.PathError{Op: "open", Path: "file", Err: syscall.EIO}
+	monitor := newDiskMonitor(2)
+	monitor.setProbeInterval(time.Hour)
+	// Failures interrupted by a success don't add up.
+	for i := 0; i < 3; i++ {
+		for j := 0; j < diskOfflineAfter-1; j++ {
+			monitor.record(1, time.Millisecond, eio)
+		}
+		monitor.record(1, time.Millisecond, nil)
+	}
+	// Expected outcomes of calls aren't failures.
+	for i := 0; i < diskOfflineAfter; i++ {
+		monitor.record(1, time.Millisecond, errFileNotFound)
+	}
+	if status := monitor.statuses()[1]; !status.Online || status.Errors != uint64(3*(diskOfflineAfter-1)) {
+		t.Fatalf("Expected disk 1 online with %d errors, got %+v", 3*(diskOfflineAfter-1), status)
+	}
+
+	for i := 0; i < diskOfflineAfter; i++ {
+		monitor.record(1, time.Millisecond, eio)
+	}
+	if monitor.statuses()[1].Online {
+		t.Fatal("Expected disk 1 to be offline")
+	}
+	if monitor.admit(1) {
+		t.Fatal("Expected calls to offline disk 1 to be held back until the next probe")
+	}
+	if !monitor.admit(0) || !monitor.statuses()[0].Online {
+		t.Fatal("Expected disk 0 to be unaffected")
+	}
+
+	// Probes failing now and then keep the disk offline.
+	monitor.setProbeInterval(0)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < diskOnlineAfter-1; j++ {
+			if !monitor.admit(1) {
+				t.Fatal("Expected probe of disk 1 to be admitted")
+			}
+			monitor.record(1, time.Millisecond, nil)
+		}
+		monitor.record(1, time.Millisecond, eio)
+	}
+	if monitor.statuses()[1].Online {
+		t.Fatal("Expected disk 1 to stay offline")
+	}
+	// So do slow probes.
+	for i := 0; i < diskOnlineAfter; i++ {
+		monitor.record(1, 2*diskSlowCallThreshold, nil)
+	}
+	if monitor.statuses()[1].Online {
+		t.Fatal("Expected disk 1 to stay offline")
+	}
+	for i := 0; i < diskOnlineAfter; i++ {
+		monitor.record(1, time.Millisecond, nil)
+	}
+	if !monitor.statuses()[1].Online {
+		t.Fatal("Expected disk 1 to be back online")
+	}
+}
+
+// eioTestStorage - simulates a disk failing every call with EIO while
+// failing is set, counting the calls reaching it.
+type eioTestStorage struct {
+	StorageAPI
+	failing *int32
+	calls   *int64
+}
+
+func (s eioTestStorage) fail() error {
+	atomic.AddInt64(s.calls, 1)
+	if atomic.LoadInt32(s.failing) == 1 {
+		return &os.PathError{Op: "open", Path: "file", Err: syscall.EIO}
+	}
+	return nil
+}
+
+func (s eioTestStorage) MakeVol(volume string) error {
+	if err := s.fail(); err != nil {
+		return err
+	}
+	return s.StorageAPI.MakeVol(volume)
+}
+
+func (s eioTestStorage) StatVol(volume string) (VolInfo, error) {
+	if err := s.fail(); err != nil {
+		return VolInfo{}, err
+	}
+	return s.StorageAPI.StatVol(volume)
+}
+
+func (s eioTestStorage) ListDir(volume, dirPath string) ([]string, error) {
+	if err := s.fail(); err != nil {
+		return nil, err
+	}
+	return s.StorageAPI.ListDir(volume, dirPath)
+}
+
+func (s eioTestStorage) ReadFile(volume string, path string, offset int64) (io.ReadCloser, error) {
+	if err := s.fail(); err != nil {
+		return nil, err
+	}
+	return s.StorageAPI.ReadFile(volume, path, offset)
+}
+
+func (s eioTestStorage) CreateFile(volume string, path string) (io.WriteCloser, error) {
+	if err := s.fail(); err != nil {
+		return nil, err
+	}
+	return s.StorageAPI.CreateFile(volume, path)
+}
+
+func (s eioTestStorage) StatFile(volume string, path string) (FileInfo, error) {
+	if err := s.fail(); err != nil {
+		return FileInfo{}, err
+	}
+	return s.StorageAPI.StatFile(volume, path)
+}
+
+func (s eioTestStorage) DeleteFile(volume string, path string) error {
+	if err := s.fail(); err != nil {
+		return err
+	}
+	return s.StorageAPI.DeleteFile(volume, path)
+}
+
+func (s eioTestStorage) RenameFile(srcVolume, srcPath, dstVolume, dstPath string) error {
+	if err := s.fail(); err != nil {
+		return err
+	}
+	return s.StorageAPI.RenameFile(srcVolume, srcPath, dstVolume, dstPath)
+}
+
+// Tests validate a disk failing persistently is taken offline and not
+// accessed anymore while objects remain readable and writable, and is
+// taken back online once it recovers.
+func TestXLDiskMonitorExcludesDisk(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+	obj, err := newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xl := obj.(xlObjects)
+	storage := xl.storage.(*XL)
+	storage.monitor.setProbeInterval(time.Hour)
+	bucket := "bucket"
+	if err = obj.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+
+	failing := int32(1)
+	var calls int64
+	disk := storage.storageDisks[3].(monitoredDisk)
+	disk.disk = eioTestStorage{disk.disk, &failing, &calls}
+	storage.storageDisks[3] = disk
+
+	data := []byte("hello, world")
+	putGet := func(object string) {
+		if _, err := obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), nil); err != nil {
+			t.Fatalf("%s: %s", object, err)
+		}
+		reader, err := obj.GetObject(bucket, object, 0)
+		if err != nil {
+			t.Fatalf("%s: %s", object, err)
+		}
+		got, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil || !bytes.Equal(got, data) {
+			t.Fatalf("%s: Expected %q, got %q, %v", object, data, got, err)
+		}
+	}
+	putGet("object1")
+	status := xl.DiskStatuses()[3]
+	if status.Online || status.Errors < uint64(diskOfflineAfter) {
+		t.Fatalf("Expected disk 3 to be offline, got %+v", status)
+	}
+	if !xl.DiskStatuses()[2].Online {
+		t.Fatal("Expected disk 2 to be online")
+	}
+
+	// Left out until the next probe.
+	callsOffline := atomic.LoadInt64(&calls)
+	putGet("object2")
+	if atomic.LoadInt64(&calls) != callsOffline {
+		t.Fatalf("Expected no calls to offline disk 3, got %d", atomic.LoadInt64(&calls)-callsOffline)
+	}
+
+	// Recovered disk is probed back online.
+	atomic.StoreInt32(&failing, 0)
+	storage.monitor.setProbeInterval(0)
+	putGet("object3")
+	if !xl.DiskStatuses()[3].Online {
+		t.Fatalf("Expected disk 3 to be back online, got %+v", xl.DiskStatuses()[3])
+	}
+}
@@ -27,6 +27,15 @@ import (
 
 // healHeal - heals the file at path.
 func (xl XL) healFile(volume string, path string) error {
+	return xl.healFileParts(volume, path, nil)
+}
+
+// healFileParts - heals the file at path, parts marked in staleParts
+// are rewritten as well even though their metadata is up to date.
+func (xl XL) healFileParts(volume string, path string, staleParts []bool) error {
+	if xl.recovery || xl.noHeal {
+		return nil
+	}
 	totalBlocks := xl.DataBlocks + xl.ParityBlocks
 	needsHeal := make([]bool, totalBlocks)
 	var readers = make([]io.Reader, totalBlocks)
@@ -45,12 +54,17 @@ func (xl XL) healFile(volume string, path string) error {
 		}).Errorf("List online disks failed with %s", err)
 		return err
 	}
+	for index := range staleParts {
+		if staleParts[index] {
+			heal = true
+		}
+	}
 	if !heal {
 		return nil
 	}
 
 	for index, disk := range onlineDisks {
-		if disk == nil {
+		if disk == nil || (staleParts != nil && staleParts[index]) {
 			needsHeal[index] = true
 			continue
 		}
@@ -220,3 +234,34 @@ func (xl XL) healFile(volume string, path string) error {
 	}
 	return nil
 }
+
+// withoutHeal - returns XL leaving files needing heal as they are.
+func (xl XL) withoutHeal() StorageAPI {
+	xl.noHeal = true
+	return xl
+}
+
+// fileNeedsHeal - returns true if any disk is missing the file at path
+// or holds stale metadata for it.
+func (xl XL) fileNeedsHeal(volume, path string) (bool, error) {
+	nsMutex.RLock(volume, path)
+	_, _, heal, err := xl.listOnlineDisks(volume, path)
+	nsMutex.RUnlock(volume, path)
+	if err != nil {
+		return false, err
+	}
+	return heal && !xl.recovery, nil
+}
+
+// healFileIfNeeded - heals the file at path right away if any disk is
+// missing it or holds stale metadata, returns true if it was healed.
+func (xl XL) healFileIfNeeded(volume, path string) (bool, error) {
+	heal, err := xl.fileNeedsHeal(volume, path)
+	if err != nil || !heal {
+		return false, err
+	}
+	if err = xl.healFile(volume, path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
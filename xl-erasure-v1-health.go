@@ -0,0 +1,122 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// Disks scoring below diskHealthThreshold are only read from when the
+// other disks can't make up for them.
+const diskHealthThreshold = 0.5
+
+// Time it takes the penalty of a disk to halve without new errors.
+var diskHealthHalfLife = time.Minute
+
+// diskHealth - rolling health of the disks of an XL. Every I/O error
+// of a disk adds to its penalty which decays over time, a disk failing
+// now and then ends up below a disk which is cleanly offline, offline
+// disks aren't accessed at all.
+type diskHealth struct {
+	mutex   *sync.Mutex
+	penalty []float64
+	updated []time.Time
+}
+
+// newDiskHealth - initialize health tracking of disks, all healthy.
+func newDiskHealth(disks int) *diskHealth {
+	return &diskHealth{
+		mutex:   &sync.Mutex{},
+		penalty: make([]float64, disks),
+		updated: make([]time.Time, disks),
+	}
+}
+
+// isDiskHealthError - errors saying a disk is flaky, missing files and
+// disks are expected conditions handled by heal.
+func isDiskHealthError(err error) bool {
+	switch err {
+	case nil, io.EOF, io.ErrUnexpectedEOF, errFileNotFound, errDiskNotFound, errVolumeNotFound:
+		return false
+	}
+	return true
+}
+
+// decayedPenalty - penalty of a disk at now, the lock is held by the
+// caller.
+func (h *diskHealth) decayedPenalty(index int, now time.Time) float64 {
+	if h.penalty[index] == 0 {
+		return 0
+	}
+	elapsed := now.Sub(h.updated[index])
+	return h.penalty[index] * math.Pow(0.5, float64(elapsed)/float64(diskHealthHalfLife))
+}
+
+// record - accounts the result of an I/O on disk index.
+func (h *diskHealth) record(index int, err error) {
+	// XL opened for recovery doesn't track health.
+	if h == nil || !isDiskHealthError(err) {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	now := time.Now().UTC()
+	h.penalty[index] = h.decayedPenalty(index, now) + 1
+	h.updated[index] = now
+}
+
+// score - health of disk index between 0 and 1, a disk without recent
+// errors scores 1.
+func (h *diskHealth) score(index int) float64 {
+	if h == nil {
+		return 1
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return 1 / (1 + h.decayedPenalty(index, time.Now().UTC()))
+}
+
+// scores - health of all disks by index.
+func (h *diskHealth) scores() map[int]float64 {
+	scores := make(map[int]float64)
+	if h == nil {
+		return scores
+	}
+	for index := range h.penalty {
+		scores[index] = h.score(index)
+	}
+	return scores
+}
+
+// isHealthy - reports if disk index scores at least diskHealthThreshold.
+func (h *diskHealth) isHealthy(index int) bool {
+	return h.score(index) >= diskHealthThreshold
+}
+
+// diskHealthReporter - storage reporting the health of its disks.
+type diskHealthReporter interface {
+	DiskHealthScores() map[int]float64
+}
+
+// DiskHealthScores - health of each disk by index, from 1 for disks
+// without recent errors down towards 0 for disks failing often.
+func (xl XL) DiskHealthScores() map[int]float64 {
+	return xl.health.scores()
+}
@@ -0,0 +1,116 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Tests validate disk scores drop with errors and recover over time.
+func TestDiskHealthScores(t *testing.T) {
+	health := newDiskHealth(4)
+	health.record(1, errCorruptData)
+	health.record(1, errCorruptData)
+	// Expected conditions don't count.
+	health.record(2, errFileNotFound)
+	health.record(3, nil)
+
+	scores := health.scores()
+	if len(scores) != 4 {
+		t.Fatalf("Expected 4 scores, but instead found %d", len(scores))
+	}
+	for index, score := range scores {
+		if index == 1 {
+			if score >= diskHealthThreshold || health.isHealthy(1) {
+				t.Errorf("Expected disk 1 to be unhealthy, but instead found score %f", score)
+			}
+			continue
+		}
+		if score != 1 {
+			t.Errorf("Expected disk %d to score 1, but instead found %f", index, score)
+		}
+	}
+
+	// Three half lives later the penalty is down to a quarter.
+	health.updated[1] = health.updated[1].Add(-3 * diskHealthHalfLife)
+	if !health.isHealthy(1) {
+		t.Errorf("Expected disk 1 to recover, but instead found score %f", health.score(1))
+	}
+}
+
+// Tests validate reads leave out a flaky disk when the other disks
+// hold enough shards.
+func TestXLReadFileAvoidsUnhealthyDisk(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var disks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		disks = append(disks, path)
+	}
+	defer func() {
+		for _, disk := range disks {
+			os.RemoveAll(disk)
+		}
+	}()
+	storage, err := newXL(disks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.MakeVol("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("a"), 64*1024)
+	writeXLFile(t, storage, "bucket", "object", data)
+
+	xl := storage.(*XL)
+	for i := 0; i < 3; i++ {
+		xl.health.record(0, errCorruptData)
+	}
+	score := xl.DiskHealthScores()[0]
+	if score >= diskHealthThreshold {
+		t.Fatalf("Expected disk 0 to be unhealthy, but instead found score %f", score)
+	}
+
+	// Garbage on the flaky disk would be detected and penalized if
+	// it was read.
+	partPath := filepath.Join(disks[0], "bucket", "object", "file.0")
+	part, err := ioutil.ReadFile(partPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(partPath, bytes.Repeat([]byte("z"), len(part)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got := readXLFile(t, storage, "bucket", "object"); !bytes.Equal(got, data) {
+		t.Fatal("Expected data to be reconstructed from the healthy disks")
+	}
+	// Let a background repair, if any was wrongly scheduled, finish.
+	time.Sleep(100 * time.Millisecond)
+	if newScore := xl.DiskHealthScores()[0]; newScore < score {
+		t.Fatalf("Expected disk 0 not to be read, but its score dropped from %f to %f", score, newScore)
+	}
+}
@@ -0,0 +1,186 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	slashpath "path"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// xlReaderAt - random access to an XL file, only the blocks covering a
+// read are read from the disks, each one reconstructed and verified on
+// its own. Safe for concurrent use.
+type xlReaderAt struct {
+	xl          XL
+	volume      string
+	path        string
+	metadata    xlMetaV1
+	onlineDisks []StorageAPI
+}
+
+// OpenReaderAt - opens volume/path for verified random access, returns
+// the reader and the size of the file.
+func (xl XL) OpenReaderAt(volume, path string) (io.ReaderAt, int64, error) {
+	// Input validation.
+	if !isValidVolname(volume) {
+		return nil, 0, errInvalidArgument
+	}
+	if !isValidPath(path) {
+		return nil, 0, errInvalidArgument
+	}
+
+	// Acquire a read lock.
+	nsMutex.RLock(volume, path)
+	onlineDisks, metadata, heal, err := xl.listOnlineDisks(volume, path)
+	nsMutex.RUnlock(volume, path)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"volume": volume,
+			"path":   path,
+		}).Errorf("Get readable disks failed with %s", err)
+		return nil, 0, err
+	}
+	if heal {
+		// Heal in background safely, since we already have read
+		// quorum disks.
+		go func() {
+			if hErr := xl.healFile(volume, path); hErr != nil {
+				log.WithFields(logrus.Fields{
+					"volume": volume,
+					"path":   path,
+				}).Errorf("healFile failed with %s", hErr)
+			}
+		}()
+	}
+	return &xlReaderAt{
+		xl:          xl,
+		volume:      volume,
+		path:        path,
+		metadata:    metadata,
+		onlineDisks: onlineDisks,
+	}, metadata.Stat.Size, nil
+}
+
+// readBlock - reads, verifies and decodes the block at blockIndex.
+// Returns errCorruptData if the block can't be verified.
+func (r *xlReaderAt) readBlock(blockIndex int64) ([]byte, error) {
+	blockSize := r.metadata.Erasure.BlockSize
+	dataBlocks := r.metadata.Erasure.DataBlocks
+	curBlockSize := r.metadata.Stat.Size - blockIndex*blockSize
+	if curBlockSize > blockSize {
+		curBlockSize = blockSize
+	}
+	curEncBlockSize := getEncodedBlockLen(curBlockSize, dataBlocks)
+	// All blocks before this one are full sized.
+	offset := blockIndex * getEncodedBlockLen(blockSize, dataBlocks)
+
+	enBlocks := make([][]byte, len(r.xl.storageDisks))
+	present := make([]bool, len(r.xl.storageDisks))
+	missing := false
+	nsMutex.RLock(r.volume, r.path)
+	for index, disk := range r.onlineDisks {
+		if disk == nil {
+			missing = true
+			continue
+		}
+		erasurePart := slashpath.Join(r.path, fmt.Sprintf("file.%d", index))
+		reader, err := disk.ReadFile(r.volume, erasurePart, offset)
+		if err != nil {
+			missing = true
+			continue
+		}
+		block := make([]byte, curEncBlockSize)
+		_, err = io.ReadFull(reader, block)
+		reader.Close()
+		if err != nil {
+			missing = true
+			continue
+		}
+		enBlocks[index] = block
+		present[index] = true
+	}
+	nsMutex.RUnlock(r.volume, r.path)
+
+	ok := false
+	if !missing {
+		var err error
+		if ok, err = r.xl.ReedSolomon.Verify(enBlocks); err != nil {
+			return nil, err
+		}
+	}
+	if !ok {
+		// Reconstruct expects missing blocks to be nil.
+		if err := r.xl.ReedSolomon.Reconstruct(enBlocks); err != nil {
+			return nil, errCorruptData
+		}
+		var err error
+		if ok, err = r.xl.ReedSolomon.Verify(enBlocks); err != nil {
+			return nil, err
+		}
+	}
+	if !ok {
+		// Present blocks disagree, look for a stale part.
+		staleIndex, blocks := r.xl.findStalePart(enBlocks, present)
+		if staleIndex == -1 {
+			log.WithFields(logrus.Fields{
+				"volume": r.volume,
+				"path":   r.path,
+				"block":  blockIndex,
+			}).Errorf("%s", errCorruptData)
+			return nil, errCorruptData
+		}
+		staleParts := make([]bool, len(r.xl.storageDisks))
+		staleParts[staleIndex] = true
+		go func() {
+			if hErr := r.xl.healFileParts(r.volume, r.path, staleParts); hErr != nil {
+				log.WithFields(logrus.Fields{
+					"volume": r.volume,
+					"path":   r.path,
+				}).Errorf("healFileParts failed with %s", hErr)
+			}
+		}()
+		enBlocks = blocks
+	}
+	return getDataBlocks(enBlocks, dataBlocks, int(curBlockSize)), nil
+}
+
+// ReadAt - implements io.ReaderAt, a block failing verification fails
+// only the reads touching it.
+func (r *xlReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errInvalidArgument
+	}
+	size := r.metadata.Stat.Size
+	blockSize := r.metadata.Erasure.BlockSize
+	n := 0
+	for n < len(p) && off+int64(n) < size {
+		pos := off + int64(n)
+		blockIndex := pos / blockSize
+		block, err := r.readBlock(blockIndex)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], block[pos-blockIndex*blockSize:])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,115 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests validate that random access reads verify only the blocks they
+// touch, a corrupted block fails only the reads covering it.
+func TestXLReaderAtCorruptBlock(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var disks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		disks = append(disks, path)
+	}
+	defer func() {
+		for _, disk := range disks {
+			os.RemoveAll(disk)
+		}
+	}()
+	storage, err := newXL(disks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.MakeVol("bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Three blocks, the last one partial.
+	data := make([]byte, 2*erasureBlockSize+12345)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	writeXLFile(t, storage, "bucket", "object", data)
+
+	readerAt, size, err := storage.(*XL).OpenReaderAt("bucket", "object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(len(data)) {
+		t.Fatalf("Expected size %d, got %d", len(data), size)
+	}
+
+	// Corrupt the second block on more disks than can be told apart.
+	encBlockSize := getEncodedBlockLen(erasureBlockSize, len(disks)/2)
+	for _, index := range []int{0, 1} {
+		partPath := filepath.Join(disks[index], "bucket", "object", fmt.Sprintf("file.%d", index))
+		part, err := ioutil.ReadFile(partPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := encBlockSize; i < encBlockSize+100; i++ {
+			part[i] ^= 0xff
+		}
+		if err = ioutil.WriteFile(partPath, part, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		offset int64
+		length int
+		err    error
+	}{
+		// Ranges within the intact blocks.
+		{0, 1000, nil},
+		{erasureBlockSize - 10, 10, nil},
+		{2*erasureBlockSize + 100, 1000, nil},
+		// Ranges touching the corrupted block.
+		{erasureBlockSize, 10, errCorruptData},
+		{erasureBlockSize - 10, 20, errCorruptData},
+		// Reading past the end.
+		{int64(len(data)) - 10, 20, io.EOF},
+	}
+	for i, testCase := range testCases {
+		p := make([]byte, testCase.length)
+		n, err := readerAt.ReadAt(p, testCase.offset)
+		if err != testCase.err {
+			t.Fatalf("Test %d: Expected error %v, got %v", i+1, testCase.err, err)
+		}
+		if err == errCorruptData {
+			continue
+		}
+		if !bytes.Equal(p[:n], data[testCase.offset:testCase.offset+int64(n)]) {
+			t.Fatalf("Test %d: Range data mismatch", i+1)
+		}
+	}
+}
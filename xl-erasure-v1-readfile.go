@@ -23,6 +23,7 @@ import (
 	slashpath "path"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/klauspost/reedsolomon"
 )
 
 // ReadFile - read file
@@ -35,17 +36,63 @@ func (xl XL) ReadFile(volume, path string, startOffset int64) (io.ReadCloser, er
 		return nil, errInvalidArgument
 	}
 
-	// Acquire a read lock.
+	// Acquire a read lock, held until the part files are open so that
+	// they match the metadata even if the file is overwritten.
 	nsMutex.RLock(volume, path)
 	onlineDisks, metadata, heal, err := xl.listOnlineDisks(volume, path)
-	nsMutex.RUnlock(volume, path)
 	if err != nil {
+		nsMutex.RUnlock(volume, path)
 		log.WithFields(logrus.Fields{
 			"volume": volume,
 			"path":   path,
 		}).Errorf("Get readable disks failed with %s", err)
 		return nil, err
 	}
+	readers := make([]io.ReadCloser, len(xl.storageDisks))
+	openedCount := 0
+	openPart := func(index int, disk StorageAPI) {
+		erasurePart := slashpath.Join(path, fmt.Sprintf("file.%d", index))
+		// If disk.ReadFile returns error and we don't have read quorum it will be taken care as
+		// ReedSolomon.Reconstruct() will fail later.
+		reader, rErr := disk.ReadFile(volume, erasurePart, 0)
+		xl.health.record(index, rErr)
+		if rErr == nil {
+			readers[index] = reader
+			openedCount++
+		}
+	}
+	// Prefer healthy disks, shards of flaky disks are reconstructed
+	// from the other shards when there are enough of them.
+	for index, disk := range onlineDisks {
+		if disk != nil && xl.health.isHealthy(index) {
+			openPart(index, disk)
+		}
+	}
+	for index, disk := range onlineDisks {
+		if openedCount >= metadata.Erasure.DataBlocks {
+			break
+		}
+		if disk != nil && readers[index] == nil && !xl.health.isHealthy(index) {
+			openPart(index, disk)
+		}
+	}
+	nsMutex.RUnlock(volume, path)
+
+	// Blocks can't be decoded from fewer parts than data blocks, fail
+	// up front rather than on the first block, unless degraded reads
+	// serve what is left.
+	if openedCount < metadata.Erasure.DataBlocks && metadata.Stat.Size > 0 && xl.degraded == nil {
+		for _, reader := range readers {
+			if reader != nil {
+				reader.Close()
+			}
+		}
+		log.WithFields(logrus.Fields{
+			"volume": volume,
+			"path":   path,
+		}).Errorf("%s", errReadQuorum)
+		return nil, errReadQuorum
+	}
 
 	if heal {
 		// Heal in background safely, since we already have read
@@ -61,27 +108,12 @@ func (xl XL) ReadFile(volume, path string, startOffset int64) (io.ReadCloser, er
 		}()
 	}
 
-	// Acquire read lock again.
-	nsMutex.RLock(volume, path)
-	readers := make([]io.ReadCloser, len(xl.storageDisks))
-	for index, disk := range onlineDisks {
-		if disk == nil {
-			continue
-		}
-		erasurePart := slashpath.Join(path, fmt.Sprintf("file.%d", index))
-		// If disk.ReadFile returns error and we don't have read quorum it will be taken care as
-		// ReedSolomon.Reconstruct() will fail later.
-		var reader io.ReadCloser
-		offset := int64(0)
-		if reader, err = disk.ReadFile(volume, erasurePart, offset); err == nil {
-			readers[index] = reader
-		}
-	}
-	nsMutex.RUnlock(volume, path)
-
 	// Initialize pipe.
 	pipeReader, pipeWriter := io.Pipe()
 	go func() {
+		// Parts found stale while reading, repaired in background
+		// once the read is done.
+		staleParts := make([]bool, len(xl.storageDisks))
 		var totalLeft = metadata.Stat.Size
 		// Read until the totalLeft.
 		for totalLeft > 0 {
@@ -103,6 +135,7 @@ func (xl XL) ReadFile(volume, path string, startOffset int64) (io.ReadCloser, er
 					continue
 				}
 				_, err = io.ReadFull(reader, enBlocks[index])
+				xl.health.record(index, err)
 				if err != nil && err != io.ErrUnexpectedEOF {
 					readers[index] = nil
 				}
@@ -139,7 +172,12 @@ func (xl XL) ReadFile(volume, path string, startOffset int64) (io.ReadCloser, er
 					}
 				}
 				err = xl.ReedSolomon.Reconstruct(enBlocks)
-				if err != nil {
+				if err == reedsolomon.ErrTooFewShards && xl.degraded != nil {
+					// Too few parts are left to reconstruct, serve
+					// the data blocks still present.
+					xl.fillMissingBlocks(volume, path, enBlocks, readers, metadata.Stat.Size-totalLeft, curBlockSize)
+					ok = true
+				} else if err != nil {
 					log.WithFields(logrus.Fields{
 						"volume": volume,
 						"path":   path,
@@ -147,25 +185,47 @@ func (xl XL) ReadFile(volume, path string, startOffset int64) (io.ReadCloser, er
 					pipeWriter.CloseWithError(err)
 					return
 				}
-				// Verify reconstructed blocks again.
-				ok, err = xl.ReedSolomon.Verify(enBlocks)
-				if err != nil {
-					log.WithFields(logrus.Fields{
-						"volume": volume,
-						"path":   path,
-					}).Errorf("ReedSolomon verify failed with %s", err)
-					pipeWriter.CloseWithError(err)
-					return
-				}
 				if !ok {
-					// Blocks cannot be reconstructed, corrupted data.
-					err = errors.New("Verification failed after reconstruction, data likely corrupted.")
-					log.WithFields(logrus.Fields{
-						"volume": volume,
-						"path":   path,
-					}).Errorf("%s", err)
-					pipeWriter.CloseWithError(err)
-					return
+					// Verify reconstructed blocks again.
+					ok, err = xl.ReedSolomon.Verify(enBlocks)
+					if err != nil {
+						log.WithFields(logrus.Fields{
+							"volume": volume,
+							"path":   path,
+						}).Errorf("ReedSolomon verify failed with %s", err)
+						pipeWriter.CloseWithError(err)
+						return
+					}
+					if !ok {
+						// Present blocks disagree, look for a stale part.
+						present := make([]bool, len(readers))
+						for index, reader := range readers {
+							present[index] = reader != nil
+						}
+						if staleIndex, blocks := xl.findStalePart(enBlocks, present); staleIndex != -1 {
+							log.WithFields(logrus.Fields{
+								"volume": volume,
+								"path":   path,
+							}).Errorf("Stale part file.%d detected, scheduled for repair", staleIndex)
+							staleParts[staleIndex] = true
+							xl.health.record(staleIndex, errCorruptData)
+							// Remaining blocks of the stale part are stale too.
+							readers[staleIndex].Close()
+							readers[staleIndex] = nil
+							enBlocks = blocks
+							ok = true
+						}
+					}
+					if !ok {
+						// Blocks cannot be reconstructed, corrupted data.
+						err = errors.New("Verification failed after reconstruction, data likely corrupted.")
+						log.WithFields(logrus.Fields{
+							"volume": volume,
+							"path":   path,
+						}).Errorf("%s", err)
+						pipeWriter.CloseWithError(err)
+						return
+					}
 				}
 			}
 
@@ -214,8 +274,51 @@ func (xl XL) ReadFile(volume, path string, startOffset int64) (io.ReadCloser, er
 			}
 			reader.Close()
 		}
+
+		// Rewrite stale parts in background.
+		for _, stale := range staleParts {
+			if !stale {
+				continue
+			}
+			go func() {
+				if hErr := xl.healFileParts(volume, path, staleParts); hErr != nil {
+					log.WithFields(logrus.Fields{
+						"volume": volume,
+						"path":   path,
+					}).Errorf("healFileParts failed with %s", hErr)
+				}
+			}()
+			break
+		}
 	}()
 
 	// Return the pipe for the top level caller to start reading.
 	return pipeReader, nil
 }
+
+// findStalePart - identifies a present part whose block disagrees with
+// the other blocks, by leaving each present part out in turn until the
+// remaining blocks reconstruct and verify. Returns the index of the
+// stale part and the repaired blocks, -1 if none could be identified.
+func (xl XL) findStalePart(enBlocks [][]byte, present []bool) (int, [][]byte) {
+	for index := range enBlocks {
+		if !present[index] {
+			continue
+		}
+		blocks := make([][]byte, len(enBlocks))
+		for i := range enBlocks {
+			if i == index || !present[i] {
+				// Reconstruct expects missing blocks to be nil.
+				continue
+			}
+			blocks[i] = enBlocks[i]
+		}
+		if err := xl.ReedSolomon.Reconstruct(blocks); err != nil {
+			continue
+		}
+		if ok, err := xl.ReedSolomon.Verify(blocks); err == nil && ok {
+			return index, blocks
+		}
+	}
+	return -1, nil
+}
@@ -0,0 +1,137 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Writes data to volume/path of the XL storage.
+func writeXLFile(t *testing.T, storage StorageAPI, volume, path string, data []byte) {
+	w, err := storage.CreateFile(volume, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Reads volume/path of the XL storage fully.
+func readXLFile(t *testing.T, storage StorageAPI, volume, path string) []byte {
+	r, err := storage.ReadFile(volume, path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// Tests validate that present but stale parts are detected during
+// reads and rewritten in background.
+func TestXLReadRepairStalePart(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var disks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		disks = append(disks, path)
+	}
+	defer func() {
+		for _, disk := range disks {
+			os.RemoveAll(disk)
+		}
+	}()
+	storage, err := newXL(disks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.MakeVol("bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	oldData := bytes.Repeat([]byte("a"), 64*1024)
+	newData := bytes.Repeat([]byte("b"), 64*1024)
+	partPath := filepath.Join(disks[0], "bucket", "object", "file.0")
+	metaPath := filepath.Join(disks[0], "bucket", "object", "file.json")
+
+	testCases := []struct {
+		name string
+		// Restore the old metadata along with the old part, making
+		// the part stale by version instead of by content.
+		restoreMeta bool
+	}{
+		{"stale content", false},
+		{"stale version", true},
+	}
+	for _, testCase := range testCases {
+		writeXLFile(t, storage, "bucket", "object", oldData)
+		oldPart, err := ioutil.ReadFile(partPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		oldMeta, err := ioutil.ReadFile(metaPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeXLFile(t, storage, "bucket", "object", newData)
+
+		// Bring back the part written while the disk missed the update.
+		if err = ioutil.WriteFile(partPath, oldPart, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if testCase.restoreMeta {
+			if err = ioutil.WriteFile(metaPath, oldMeta, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if data := readXLFile(t, storage, "bucket", "object"); !bytes.Equal(data, newData) {
+			t.Fatalf("%s: Expected the quorum data to be read", testCase.name)
+		}
+
+		// Wait for the background repair of the stale part.
+		repaired := false
+		for i := 0; i < 100 && !repaired; i++ {
+			time.Sleep(20 * time.Millisecond)
+			part, err := ioutil.ReadFile(partPath)
+			repaired = err == nil && !bytes.Equal(part, oldPart)
+		}
+		if !repaired {
+			t.Fatalf("%s: Expected stale part to be repaired", testCase.name)
+		}
+		if data := readXLFile(t, storage, "bucket", "object"); !bytes.Equal(data, newData) {
+			t.Fatalf("%s: Expected data to be intact after repair", testCase.name)
+		}
+	}
+}
@@ -46,6 +46,18 @@ type XL struct {
 	storageDisks []StorageAPI
 	readQuorum   int
 	writeQuorum  int
+	// Set when opened for recovery, files needing heal are left as
+	// they are and missing disks count as not holding the file.
+	recovery bool
+	// Set to inspect files and volumes without healing them.
+	noHeal bool
+	// Set to read files one disk short of read quorum, collects the
+	// ranges which could not be reconstructed.
+	degraded *degradedReads
+	// I/O errors of each disk over time.
+	health *diskHealth
+	// Takes disks failing persistently offline.
+	monitor *diskMonitor
 }
 
 // newXL instantiate a new XL.
@@ -102,8 +114,18 @@ func newXL(disks ...string) (StorageAPI, error) {
 		}
 	}
 
+	// Account all calls to the disks, disks failing persistently are
+	// left out until they recover.
+	xl.monitor = newDiskMonitor(len(storageDisks))
+	for index, disk := range storageDisks {
+		if disk != nil {
+			storageDisks[index] = monitoredDisk{disk, index, xl.monitor}
+		}
+	}
+
 	// Save all the initialized storage disks.
 	xl.storageDisks = storageDisks
+	xl.health = newDiskHealth(len(storageDisks))
 
 	// Figure out read and write quorum based on number of storage disks.
 	// Read quorum should be always N/2 + 1 (due to Vandermonde matrix
@@ -121,6 +143,129 @@ func newXL(disks ...string) (StorageAPI, error) {
 	return xl, nil
 }
 
+// setLayout - switch XL to erasure code with dataBlocks data and
+// parityBlocks parity blocks, the sum has to match the number of disks.
+func (xl *XL) setLayout(dataBlocks, parityBlocks int) error {
+	if dataBlocks <= 0 || parityBlocks <= 0 {
+		return errInvalidArgument
+	}
+	if dataBlocks+parityBlocks != len(xl.storageDisks) {
+		return errInvalidArgument
+	}
+	rs, err := reedsolomon.New(dataBlocks, parityBlocks)
+	if err != nil {
+		return err
+	}
+	xl.DataBlocks = dataBlocks
+	xl.ParityBlocks = parityBlocks
+	xl.ReedSolomon = rs
+
+	// Metadata still needs N/2 + 1 disks agreeing, but a read can't
+	// be decoded from fewer than dataBlocks disks.
+	xl.readQuorum = len(xl.storageDisks)/2 + 1
+	if xl.readQuorum < dataBlocks {
+		xl.readQuorum = dataBlocks
+	}
+	// A write has to leave at least one parity block to spare.
+	xl.writeQuorum = len(xl.storageDisks)/2 + 3
+	if xl.writeQuorum < dataBlocks+1 {
+		xl.writeQuorum = dataBlocks + 1
+	}
+	if xl.writeQuorum > len(xl.storageDisks) {
+		xl.writeQuorum = len(xl.storageDisks)
+	}
+	return nil
+}
+
+// reorderDisks - put the disks at their formatted positions,
+// order[position] is the current index of the disk at position.
+func (xl *XL) reorderDisks(order []int) {
+	storageDisks := make([]StorageAPI, len(order))
+	for pos, index := range order {
+		disk := xl.storageDisks[index]
+		if md, ok := disk.(monitoredDisk); ok {
+			md.index = pos
+			disk = md
+		}
+		storageDisks[pos] = disk
+	}
+	xl.storageDisks = storageDisks
+}
+
+// hasWriteQuorum - reports if enough disks holding path are online for
+// a rewrite of it to reach write quorum.
+func (xl XL) hasWriteQuorum(volume, path string) bool {
+	_, errs := xl.getPartsMetadata(volume, path)
+	onlineDisks := 0
+	for _, err := range errs {
+		if err == nil {
+			onlineDisks++
+		}
+	}
+	return onlineDisks >= xl.writeQuorum
+}
+
+// canWrite - reports if enough disks are online to reach write quorum,
+// probing each disk. Disks taken offline by the monitor aren't reached
+// and count as offline. An error is returned only if quorum is missed
+// because of a disk failing in an unexpected way.
+func (xl XL) canWrite() (bool, error) {
+	errs := make([]error, len(xl.storageDisks))
+	var wg = &sync.WaitGroup{}
+	for index, disk := range xl.storageDisks {
+		if disk == nil {
+			errs[index] = errDiskNotFound
+			continue
+		}
+		wg.Add(1)
+		go func(index int, disk StorageAPI) {
+			defer wg.Done()
+			_, errs[index] = disk.StatVol(minioMetaBucket)
+		}(index, disk)
+	}
+	wg.Wait()
+	onlineDisks := 0
+	var unexpectedErr error
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			onlineDisks++
+		case err == errVolumeNotFound, err == errVolumeAccessDenied, isDiskFailure(err):
+			// Disk missing, unformatted or failing.
+		default:
+			if unexpectedErr == nil {
+				unexpectedErr = err
+			}
+		}
+	}
+	if onlineDisks >= xl.writeQuorum {
+		return true, nil
+	}
+	return false, unexpectedErr
+}
+
+// formatLayout - data and parity blocks format.json was erasure coded
+// with, as agreed upon by most disks. Returns false if no disk holds
+// format.json.
+func (xl XL) formatLayout() (dataBlocks, parityBlocks int, ok bool) {
+	partsMetadata, errs := xl.getPartsMetadata(minioMetaBucket, formatConfigFile)
+	layoutCount := make(map[[2]int]int)
+	for index, metadata := range partsMetadata {
+		if errs[index] != nil {
+			continue
+		}
+		layoutCount[[2]int{metadata.Erasure.DataBlocks, metadata.Erasure.ParityBlocks}]++
+	}
+	maxCount := 0
+	for layout, count := range layoutCount {
+		if count > maxCount {
+			dataBlocks, parityBlocks = layout[0], layout[1]
+			maxCount = count
+		}
+	}
+	return dataBlocks, parityBlocks, maxCount > 0
+}
+
 // MakeVol - make a volume.
 func (xl XL) MakeVol(volume string) error {
 	if !isValidVolname(volume) {
@@ -392,6 +537,9 @@ func (xl XL) listAllVolumeInfo(volume string) ([]VolInfo, bool, error) {
 
 // healVolume - heals any missing volumes.
 func (xl XL) healVolume(volume string) error {
+	if xl.noHeal {
+		return nil
+	}
 	// Acquire a read lock.
 	nsMutex.RLock(volume, "")
 	defer nsMutex.RUnlock(volume, "")
@@ -425,6 +573,17 @@ func (xl XL) healVolume(volume string) error {
 	return nil
 }
 
+// IsCaseInsensitive - returns true if any of the disks treats names
+// differing only in case as the same file.
+func (xl XL) IsCaseInsensitive() bool {
+	for _, disk := range xl.storageDisks {
+		if cs, ok := disk.(caseInsensitiveStorage); ok && cs.IsCaseInsensitive() {
+			return true
+		}
+	}
+	return false
+}
+
 // StatVol - get volume stat info.
 func (xl XL) StatVol(volume string) (volInfo VolInfo, err error) {
 	if !isValidVolname(volume) {
@@ -653,3 +812,43 @@ func (xl XL) RenameFile(srcVolume, srcPath, dstVolume, dstPath string) error {
 	}
 	return nil
 }
+
+// SyncFile - flushes the part and metadata files of a file to stable
+// storage on every disk.
+func (xl XL) SyncFile(volume, path string) error {
+	if !isValidVolname(volume) {
+		return errInvalidArgument
+	}
+	if !isValidPath(path) {
+		return errInvalidArgument
+	}
+
+	nsMutex.RLock(volume, path)
+	defer nsMutex.RUnlock(volume, path)
+
+	errCount := 0
+	for index, disk := range xl.storageDisks {
+		err := errSyncNotSupported
+		if syncer, ok := disk.(fileSyncer); ok {
+			err = syncer.SyncFile(volume, slashpath.Join(path, fmt.Sprintf("file.%d", index)))
+			if err == nil {
+				err = syncer.SyncFile(volume, slashpath.Join(path, xlMetaV1File))
+			}
+		}
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"volume": volume,
+				"path":   path,
+			}).Errorf("SyncFile failed with %s", err)
+
+			errCount++
+			// A file synced on a write quorum of disks survives a crash.
+			if errCount <= len(xl.storageDisks)-xl.writeQuorum {
+				continue
+			}
+
+			return err
+		}
+	}
+	return nil
+}
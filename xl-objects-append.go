@@ -0,0 +1,304 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"path"
+	"time"
+
+	"github.com/skyrings/skyring-common/tools/uuid"
+)
+
+// Most parts an object gets by appends, the S3 limit of parts of an
+// object. The next append collapses the object into a simple object.
+const maxAppendParts = 10000
+
+// AppendObject - appends data to an object. A multipart object gets
+// data as a new part without rewriting its existing parts, a simple
+// object is converted to a multipart object holding its data as first
+// part. Objects stored compressed or encrypted, objects of versioned
+// buckets, which keep every previous content as a version, and missing
+// objects are put again instead. Appends to an object are serialized.
+func (xl xlObjects) AppendObject(bucket, object string, data io.Reader) (ObjectInfo, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return ObjectInfo{}, BucketNameInvalid{Bucket: bucket}
+	}
+	if !xl.isBucketExist(bucket) {
+		return ObjectInfo{}, BucketNotFound{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return ObjectInfo{}, ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	unlock := lockObject(bucket, object)
+	defer unlock()
+
+	meta, err := readBucketMetadata(xl.storage, bucket)
+	if err != nil {
+		return ObjectInfo{}, toObjectErr(err, bucket)
+	}
+	objInfo, err := xl.getObjectInfo(bucket, object)
+	if err == errFileNotFound || err == nil && (meta.Versioning || len(objInfo.Parts) >= maxAppendParts) {
+		return appendObjectRewrite(xl, xl.storage, bucket, object, data, xl.putObject)
+	}
+	if err != nil {
+		return ObjectInfo{}, toObjectErr(err, bucket, object)
+	}
+	metadata, err := readObjectMetadata(xl.storage, bucket, object)
+	if err != nil {
+		return ObjectInfo{}, toObjectErr(err, bucket, object)
+	}
+	isMultipart, err := xl.isMultipart(bucket, object)
+	if err != nil {
+		return ObjectInfo{}, toObjectErr(err, bucket, object)
+	}
+	if !isMultipart {
+		// Parts are stored as uploaded, transformed data stays so.
+		compression, err := getObjectCompression(metadata)
+		if err != nil {
+			return ObjectInfo{}, toObjectErr(err, bucket, object)
+		}
+		if compression != nil || isEncrypted(metadata) {
+			return appendObjectRewrite(xl, xl.storage, bucket, object, data, xl.putObject)
+		}
+	}
+
+	// Objects under legal hold or retention can't be changed.
+	if err = checkObjectMutable(xl.storage, bucket, object, xl.bypassGovernance); err != nil {
+		return ObjectInfo{}, err
+	}
+	if err = xl.writeLimiter.acquire(context.Background()); err != nil {
+		return ObjectInfo{}, err
+	}
+	defer xl.writeLimiter.release()
+	// Account the size change in the bucket stats once written.
+	defer xl.bucketStats.track(xl, bucket, object)()
+
+	// Appended data can't take the object over the size limit.
+	maxSize := xl.sizeLimit.get()
+	data = &objectSizeReader{
+		reader:    data,
+		remaining: maxSize - objInfo.Size,
+		err:       ObjectTooLarge{Bucket: bucket, Object: object, MaxSize: maxSize},
+	}
+	if isMultipart {
+		err = xl.appendObjectPart(meta, bucket, object, data, metadata)
+	} else {
+		err = xl.appendSimpleObject(meta, bucket, object, data)
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return xl.GetObjectInfo(bucket, object)
+}
+
+// writeAppendPart - writes data to the part file at volume, partPath,
+// returns the info of the part numbered partNumber.
+func (xl xlObjects) writeAppendPart(bucket, object, volume, partPath string, partNumber int, data io.Reader) (MultipartPartInfo, error) {
+	fileWriter, err := xl.storage.CreateFile(volume, partPath)
+	if err != nil {
+		return MultipartPartInfo{}, toObjectErr(err, bucket, object)
+	}
+	md5Writer := md5.New()
+	sha256Writer := sha256.New()
+	// The part is stored encrypted at rest like uploaded parts.
+	atRest, err := newPartAtRestWriter(xl.scheduler.writer(fileWriter, xl.priority))
+	if err != nil {
+		if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
+			return MultipartPartInfo{}, toObjectErr(clErr, bucket, object)
+		}
+		return MultipartPartInfo{}, toObjectErr(err, bucket, object)
+	}
+	multiWriter := io.MultiWriter(md5Writer, sha256Writer, atRest)
+	n, err := io.Copy(multiWriter, data)
+	// Bytes received are accounted even if the append fails.
+	xl.bandwidth.addIngress(bucket, n)
+	if err != nil {
+		if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
+			return MultipartPartInfo{}, toObjectErr(clErr, bucket, object)
+		}
+		return MultipartPartInfo{}, toObjectErr(err, bucket, object)
+	}
+	encryption, err := atRest.finish(nil, n)
+	if err != nil {
+		if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
+			return MultipartPartInfo{}, toObjectErr(clErr, bucket, object)
+		}
+		return MultipartPartInfo{}, toObjectErr(err, bucket, object)
+	}
+	if err = fileWriter.Close(); err != nil {
+		if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
+			return MultipartPartInfo{}, toObjectErr(clErr, bucket, object)
+		}
+		return MultipartPartInfo{}, toObjectErr(err, bucket, object)
+	}
+	return MultipartPartInfo{
+		PartNumber: partNumber,
+		ETag:       hex.EncodeToString(md5Writer.Sum(nil)),
+		Size:       n,
+		Checksum:   hex.EncodeToString(sha256Writer.Sum(nil)),
+		Encryption: encryption,
+	}, nil
+}
+
+// addAppendPart - adds part to the parts of info, updating its size
+// and composite ETag.
+func addAppendPart(info *MultipartObjectInfo, part MultipartPartInfo) error {
+	info.Parts = append(info.Parts, part)
+	info.Size += part.Size
+	info.ModTime = time.Now().UTC()
+	parts := make([]completePart, len(info.Parts))
+	for i, part := range info.Parts {
+		parts[i] = completePart{PartNumber: part.PartNumber, ETag: part.ETag}
+	}
+	var err error
+	info.MD5Sum, err = completeMultipartMD5(parts...)
+	return err
+}
+
+// appendObjectPart - writes data as the next part of a multipart
+// object, in place. The part is only read once the multipart meta file
+// lists it, readers see the object either before or after the append.
+func (xl xlObjects) appendObjectPart(meta bucketMetadata, bucket, object string, data io.Reader, metadata map[string]string) error {
+	info, err := getMultipartObjectInfo(xl.storage, bucket, object)
+	if err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+	partNumber := info.Parts[len(info.Parts)-1].PartNumber + 1
+	partPath := pathJoin(object, partNumToPartFileName(partNumber))
+	part, err := xl.writeAppendPart(bucket, object, bucket, partPath, partNumber, data)
+	if err != nil {
+		return err
+	}
+	if err = addAppendPart(&info, part); err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+	if err = saveMultipartObjectInfo(xl.storage, bucket, object, info); err != nil {
+		if derr := xl.storage.DeleteFile(bucket, partPath); derr != nil {
+			return toObjectErr(derr, bucket, object)
+		}
+		return toObjectErr(err, bucket, object)
+	}
+	// Readers arriving from now on must not share in progress reads
+	// of the object before the append.
+	xl.readCoalescer.forget(bucket + "/" + object + "@")
+	invalidateTreeWalks(xl, bucket, object)
+	return xl.markAppendReplication(meta, bucket, object, metadata)
+}
+
+// appendSimpleObject - converts a simple object to a multipart object
+// with its data as first part and data as second part. The object is
+// staged in full, then replaces the simple object.
+func (xl xlObjects) appendSimpleObject(meta bucketMetadata, bucket, object string, data io.Reader) error {
+	metadata, err := copyObjectMetadata(xl.storage, bucket, object, nil)
+	if err != nil {
+		return err
+	}
+	stageID, err := uuid.New()
+	if err != nil {
+		return err
+	}
+	stagePath := path.Join(tmpMetaPrefix, bucket, object, stageID.String())
+	deleteStaged := func() {
+		if derr := cleanupDir(xl.storage, minioMetaBucket, stagePath); derr != nil {
+			xl.log().Errorf("Unable to delete %s staged to append to %s/%s: %s", stagePath, bucket, object, derr)
+		}
+	}
+	if err = xl.stageAppendedObject(bucket, object, stagePath, data); err != nil {
+		deleteStaged()
+		return err
+	}
+
+	// A dedup blob the simple object references loses a reference.
+	oldRef, err := lockDedupReference(xl.storage, bucket, object)
+	if err != nil {
+		deleteStaged()
+		return toObjectErr(err, bucket, object)
+	}
+	defer oldRef.unlock()
+	// Metadata describing how the simple object was stored goes along
+	// with it.
+	stageMeta := stagePath + objectMetaSuffix
+	if err = stageObjectMetadata(xl.storage, stageMeta, metadata); err != nil {
+		deleteStaged()
+		return toObjectErr(err, bucket, object)
+	}
+	retained, err := xl.replaceObject(minioMetaBucket, stagePath, stageMeta, bucket, object, meta.Versioning)
+	if err != nil {
+		if derr := xl.storage.DeleteFile(minioMetaBucket, stageMeta); derr != nil && derr != errFileNotFound {
+			xl.log().Errorf("Unable to delete %s staged to append to %s/%s: %s", stageMeta, bucket, object, derr)
+		}
+		deleteStaged()
+		return toObjectErr(err, bucket, object)
+	}
+	invalidateTreeWalks(xl, bucket, object)
+	// A retained version keeps referencing the dedup blob.
+	if !retained {
+		if err = oldRef.release(); err != nil {
+			return toObjectErr(err, bucket, object)
+		}
+	}
+	return xl.markAppendReplication(meta, bucket, object, metadata)
+}
+
+// stageAppendedObject - writes the data of a simple object followed by
+// data as a multipart object at stagePath in minioMetaBucket.
+func (xl xlObjects) stageAppendedObject(bucket, object, stagePath string, data io.Reader) error {
+	reader, err := xl.getObject(context.Background(), bucket, object, 0, -1)
+	if err != nil {
+		return err
+	}
+	var info MultipartObjectInfo
+	for partNumber, partData := range []io.Reader{reader, data} {
+		partNumber++
+		partPath := pathJoin(stagePath, partNumToPartFileName(partNumber))
+		part, err := xl.writeAppendPart(bucket, object, minioMetaBucket, partPath, partNumber, partData)
+		if partNumber == 1 {
+			reader.Close()
+		}
+		if err != nil {
+			return err
+		}
+		if err = addAppendPart(&info, part); err != nil {
+			return toObjectErr(err, bucket, object)
+		}
+	}
+	if err = saveMultipartObjectInfo(xl.storage, minioMetaBucket, stagePath, info); err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+	return nil
+}
+
+// markAppendReplication - marks an object pending replication after an
+// append if its bucket replicates.
+func (xl xlObjects) markAppendReplication(meta bucketMetadata, bucket, object string, metadata map[string]string) error {
+	metadata, replicate := replicationMetadata(meta, metadata)
+	if !replicate {
+		return nil
+	}
+	if err := saveObjectMetadata(xl.storage, bucket, object, metadata); err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+	xl.replicator.enqueue(replicationJob{bucket: bucket, object: object})
+	return nil
+}
@@ -0,0 +1,148 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	// How long a bucket is known to exist, bounds how long a bucket
+	// deleted by other servers goes unnoticed.
+	bucketCacheExpiry = 30 * time.Second
+	// How long a bucket is known not to exist, kept short so that a
+	// bucket made by other servers soon becomes visible.
+	bucketCacheNegativeExpiry = time.Second
+	// Maximum number of buckets whose existence is cached.
+	bucketCacheSize = 1000
+)
+
+// bucketCacheEntry - cached existence of a bucket.
+type bucketCacheEntry struct {
+	exists  bool
+	expires time.Time
+}
+
+// bucketCache - caches whether buckets exist, so that object
+// operations don't stat the bucket volume on every call. Buckets are
+// forgotten whenever this server makes or deletes them.
+type bucketCache struct {
+	mutex   *sync.Mutex
+	entries map[string]bucketCacheEntry
+	// Incremented by every forget, results of stats which started
+	// before a forget may be stale and aren't saved.
+	gen uint64
+}
+
+// newBucketCache - initialize an empty bucket cache.
+func newBucketCache() *bucketCache {
+	return &bucketCache{
+		mutex:   &sync.Mutex{},
+		entries: make(map[string]bucketCacheEntry),
+	}
+}
+
+// lookup - returns the cached existence of a bucket, ok is false if
+// it isn't cached or expired.
+func (c *bucketCache) lookup(bucket string) (exists bool, ok bool) {
+	if c == nil {
+		return false, false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry, ok := c.entries[bucket]
+	if !ok || time.Now().After(entry.expires) {
+		return false, false
+	}
+	return entry.exists, true
+}
+
+// generation - returns the generation to pass to save for a stat
+// starting now.
+func (c *bucketCache) generation() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.gen
+}
+
+// save - caches the existence of a bucket stat at gen, unless a
+// bucket was forgotten since.
+func (c *bucketCache) save(bucket string, exists bool, gen uint64) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if gen != c.gen {
+		return
+	}
+	now := time.Now()
+	if len(c.entries) >= bucketCacheSize {
+		for key, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, key)
+			}
+		}
+		if len(c.entries) >= bucketCacheSize {
+			// Still full, start over rather than track usage.
+			c.entries = make(map[string]bucketCacheEntry)
+		}
+	}
+	expiry := bucketCacheExpiry
+	if !exists {
+		expiry = bucketCacheNegativeExpiry
+	}
+	c.entries[bucket] = bucketCacheEntry{
+		exists:  exists,
+		expires: now.Add(expiry),
+	}
+}
+
+// forget - drops the cached existence of a bucket, called whenever
+// the bucket is made or deleted.
+func (c *bucketCache) forget(bucket string) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.entries, bucket)
+	c.gen++
+}
+
+// isBucketExist - isBucketExist answered from the bucket cache when
+// possible. Failed stats other than a missing bucket aren't cached.
+func (xl xlObjects) isBucketExist(bucket string) bool {
+	if exists, ok := xl.bucketCache.lookup(bucket); ok {
+		return exists
+	}
+	gen := xl.bucketCache.generation()
+	_, err := xl.storage.StatVol(bucket)
+	switch err {
+	case nil:
+		xl.bucketCache.save(bucket, true, gen)
+	case errVolumeNotFound:
+		xl.bucketCache.save(bucket, false, gen)
+	default:
+		xl.log().Errorf("StatVol failed with %s", err)
+	}
+	return err == nil
+}
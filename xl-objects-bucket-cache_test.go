@@ -0,0 +1,161 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// statVolCountingStorage - counts stats of volumes.
+type statVolCountingStorage struct {
+	StorageAPI
+	count *int64
+}
+
+func (s statVolCountingStorage) StatVol(volume string) (VolInfo, error) {
+	atomic.AddInt64(s.count, 1)
+	return s.StorageAPI.StatVol(volume)
+}
+
+// Tests validate buckets made or deleted on this server are seen at
+// once, also while their existence is looked up concurrently, and that
+// buckets made elsewhere are seen once the negative entry expires.
+func TestXLBucketCache(t *testing.T) {
+	xl, removeDisks := newTestXLWithOptions(t, 8)
+	defer removeDisks()
+	bucket := "bucket"
+	data := []byte("data")
+	putObject := func() error {
+		_, err := xl.PutObject(bucket, "object", int64(len(data)), bytes.NewReader(data), nil)
+		return err
+	}
+
+	if err := putObject(); err != (BucketNotFound{Bucket: bucket}) {
+		t.Fatalf("Expected BucketNotFound, got %v", err)
+	}
+	if err := xl.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	if err := putObject(); err != nil {
+		t.Fatal(err)
+	}
+	if err := xl.DeleteObject(bucket, "object"); err != nil {
+		t.Fatal(err)
+	}
+	if err := xl.DeleteBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	if err := putObject(); err != (BucketNotFound{Bucket: bucket}) {
+		t.Fatalf("Expected BucketNotFound, got %v", err)
+	}
+
+	// Lookups racing with makes and deletes never outlive them.
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					xl.isBucketExist(bucket)
+				}
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		if err := xl.MakeBucket(bucket); err != nil {
+			t.Fatal(err)
+		}
+		if !xl.isBucketExist(bucket) {
+			t.Fatalf("Round %d: Expected bucket to exist once made", i+1)
+		}
+		if err := xl.DeleteBucket(bucket); err != nil {
+			t.Fatal(err)
+		}
+		if xl.isBucketExist(bucket) {
+			t.Fatalf("Round %d: Expected bucket not to exist once deleted", i+1)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	// A bucket made by another server is seen once the negative entry
+	// expires.
+	defer func(expiry time.Duration) {
+		bucketCacheNegativeExpiry = expiry
+	}(bucketCacheNegativeExpiry)
+	bucketCacheNegativeExpiry = 50 * time.Millisecond
+	xl.bucketCache.forget(bucket)
+	if xl.isBucketExist(bucket) {
+		t.Fatal("Expected bucket not to exist")
+	}
+	if err := xl.storage.MakeVol(bucket); err != nil {
+		t.Fatal(err)
+	}
+	if xl.isBucketExist(bucket) {
+		t.Fatal("Expected bucket not to exist until the negative entry expires")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if !xl.isBucketExist(bucket) {
+		t.Fatal("Expected bucket to exist once the negative entry expired")
+	}
+}
+
+// Benchmark stats of the bucket volume by GetObjectInfo on XL, with and
+// without the bucket cache.
+func BenchmarkXLGetObjectInfoBucketStat(b *testing.B) {
+	obj, removeDisks := newTestXLWithOptions(b, 8)
+	defer removeDisks()
+	if err := obj.MakeBucket("bucket"); err != nil {
+		b.Fatal(err)
+	}
+	text := []byte("Jack and Jill went up the hill / To fetch a pail of water.")
+	if _, err := obj.PutObject("bucket", "object", int64(len(text)), bytes.NewReader(text), nil); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, cached := range []bool{false, true} {
+		name := "uncached"
+		if cached {
+			name = "cached"
+		}
+		b.Run(name, func(b *testing.B) {
+			var count int64
+			xl := obj
+			xl.storage = statVolCountingStorage{xl.storage, &count}
+			xl.bucketCache = nil
+			if cached {
+				xl.bucketCache = newBucketCache()
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := xl.GetObjectInfo("bucket", "object"); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(atomic.LoadInt64(&count))/float64(b.N), "statvols/op")
+		})
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "context"
+
+// degradedReader - storage able to read files below read quorum.
+type degradedReader interface {
+	withDegradedReads(reads *degradedReads) StorageAPI
+}
+
+// GetObjectWithOptions - get an object like GetObject, with
+// opts.AllowDegradedRead an object short of read quorum is served as
+// far as it can be reconstructed, see GetObjectOptions.
+func (xl xlObjects) GetObjectWithOptions(bucket, object string, startOffset int64, opts GetObjectOptions) (*DegradedReader, error) {
+	storage, ok := xl.storage.(degradedReader)
+	if !opts.AllowDegradedRead || !ok {
+		reader, err := xl.GetObject(bucket, object, startOffset)
+		if err != nil {
+			return nil, err
+		}
+		return newDegradedReader(reader, nil), nil
+	}
+	reads := newDegradedReads()
+	degraded := xl
+	degraded.storage = storage.withDegradedReads(reads)
+	objInfo, err := degraded.GetObjectInfo(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	if objInfo.Parts == nil {
+		metadata, err := readObjectMetadata(degraded.storage, bucket, object)
+		if err != nil {
+			return nil, toObjectErr(err, bucket, object)
+		}
+		r, err := newObjectReader(degraded.storage, bucket, object, objInfo.Size, metadata)
+		if err != nil {
+			return nil, toObjectErr(err, bucket, object)
+		}
+		if r.transformed {
+			// Zeroed bytes can't be decompressed or decrypted.
+			return xl.GetObjectWithOptions(bucket, object, startOffset, GetObjectOptions{})
+		}
+	} else {
+		info, err := getMultipartObjectInfo(degraded.storage, bucket, object)
+		if err != nil {
+			return nil, toObjectErr(err, bucket, object)
+		}
+		for _, part := range info.Parts {
+			if part.Encryption != nil {
+				return xl.GetObjectWithOptions(bucket, object, startOffset, GetObjectOptions{})
+			}
+		}
+	}
+	if !reads.empty() {
+		// Metadata is only served whole.
+		return nil, InsufficientReadQuorum{}
+	}
+	// Parts with bytes missing never match their checksum.
+	ctx := WithBitRotVerification(context.Background(), false)
+	reader, err := degraded.getObject(ctx, bucket, object, startOffset, -1)
+	if err != nil {
+		return nil, err
+	}
+	// missing - missing ranges of the data files as ranges of the
+	// object, from startOffset on.
+	missing := func() []ObjectByteRange {
+		var ranges []ObjectByteRange
+		if objInfo.Parts == nil {
+			ranges = reads.ranges(bucket, object)
+		} else {
+			for _, part := range objInfo.Parts {
+				for _, r := range reads.ranges(bucket, pathJoin(object, partNumToPartFileName(part.Number))) {
+					ranges = append(ranges, ObjectByteRange{Offset: part.Offset + r.Offset, Length: r.Length})
+				}
+			}
+		}
+		var clipped []ObjectByteRange
+		for _, r := range ranges {
+			if r.Offset+r.Length <= startOffset {
+				continue
+			}
+			if r.Offset < startOffset {
+				r.Length -= startOffset - r.Offset
+				r.Offset = startOffset
+			}
+			clipped = append(clipped, r)
+		}
+		return clipped
+	}
+	return newDegradedReader(xl.scheduler.reader(xl.bandwidth.egressReader(bucket, reader), xl.priority), missing), nil
+}
@@ -0,0 +1,252 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// fileHealer - storage able to rebuild a file from the disks holding it.
+type fileHealer interface {
+	withoutHeal() StorageAPI
+	fileNeedsHeal(volume, path string) (bool, error)
+	healFileIfNeeded(volume, path string) (bool, error)
+}
+
+// ObjectHealInfo - files of an object rebuilt by HealObject, empty if
+// the object was healthy on all disks.
+type ObjectHealInfo struct {
+	Bucket string
+	Object string
+	// Set if the object was uploaded in parts.
+	Multipart bool
+	// Set if the data of a simple object was rebuilt.
+	DataHealed bool
+	// Set if the multipart meta file was rebuilt.
+	MultipartMetaHealed bool
+	// Numbers of the parts rebuilt.
+	HealedParts []int
+	// Numbers of the parts whose size recorded in the multipart meta
+	// file disagreed with the part file, and was corrected.
+	MismatchedParts []int
+}
+
+// HealObject - rebuilds the files of an object missing or stale on some
+// disks from the remaining ones, as long as read quorum still holds
+// them. Multipart objects are healed part by part, including their
+// multipart meta file whose part sizes are recomputed. Heals a single
+// object, so runs outside maintenance mode too.
+func (xl xlObjects) HealObject(bucket, object string) (ObjectHealInfo, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return ObjectHealInfo{}, BucketNameInvalid{Bucket: bucket}
+	}
+	if !isBucketExist(xl.storage, bucket) {
+		return ObjectHealInfo{}, BucketNotFound{Bucket: bucket}
+	}
+	if !IsValidObjectName(object) {
+		return ObjectHealInfo{}, ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	return xl.healObject(bucket, object)
+}
+
+// healObject - HealObject of a valid object.
+func (xl xlObjects) healObject(bucket, object string) (ObjectHealInfo, error) {
+	info := ObjectHealInfo{Bucket: bucket, Object: object}
+	storage, ok := xl.storage.(fileHealer)
+	if !ok {
+		// Nothing to heal from.
+		return info, nil
+	}
+
+	// Keep the object from being replaced while its files are healed.
+	unlock := lockObject(bucket, object)
+	defer unlock()
+
+	healed, err := storage.healFileIfNeeded(bucket, pathJoin(object, multipartMetaFile))
+	if err == errFileNotFound {
+		// Not a multipart object.
+		info.DataHealed, err = storage.healFileIfNeeded(bucket, object)
+		if err != nil {
+			return ObjectHealInfo{}, toObjectErr(err, bucket, object)
+		}
+		return info, nil
+	}
+	if err != nil {
+		return ObjectHealInfo{}, toObjectErr(err, bucket, object)
+	}
+	info.Multipart = true
+	info.MultipartMetaHealed = healed
+	// Part offsets must line up with the part files before the parts
+	// can be found.
+	if info.MismatchedParts, err = xl.recomputeMultipartOffsets(bucket, object); err != nil {
+		return ObjectHealInfo{}, err
+	}
+
+	multipartInfo, err := getMultipartObjectInfo(xl.storage, bucket, object)
+	if err != nil {
+		return ObjectHealInfo{}, toObjectErr(err, bucket, object)
+	}
+	for _, part := range multipartInfo.Parts {
+		healed, err = storage.healFileIfNeeded(bucket, pathJoin(object, partNumToPartFileName(part.PartNumber)))
+		if err != nil {
+			return ObjectHealInfo{}, toObjectErr(err, bucket, object)
+		}
+		if healed {
+			info.HealedParts = append(info.HealedParts, part.PartNumber)
+		}
+	}
+	return info, nil
+}
+
+// objectMissingParts - number of files of an object below full
+// redundancy, its data file for simple objects or its parts and
+// multipart meta file for multipart objects.
+func (xl xlObjects) objectMissingParts(bucket, object string) (int, error) {
+	storage, ok := xl.storage.(fileHealer)
+	if !ok {
+		return 0, nil
+	}
+	missingParts := 0
+	heal, err := storage.fileNeedsHeal(bucket, pathJoin(object, multipartMetaFile))
+	if err == errFileNotFound {
+		// Not a multipart object.
+		if heal, err = storage.fileNeedsHeal(bucket, object); err != nil {
+			return 0, err
+		}
+		if heal {
+			missingParts++
+		}
+		return missingParts, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if heal {
+		missingParts++
+	}
+	multipartInfo, err := getMultipartObjectInfo(xl.storage, bucket, object)
+	if err != nil {
+		return 0, err
+	}
+	for _, part := range multipartInfo.Parts {
+		heal, err = storage.fileNeedsHeal(bucket, pathJoin(object, partNumToPartFileName(part.PartNumber)))
+		if err != nil {
+			return 0, err
+		}
+		if heal {
+			missingParts++
+		}
+	}
+	return missingParts, nil
+}
+
+// ListObjectsHeal - lists the objects below full redundancy like
+// ListObjects, without healing them. MissingParts of each object is set
+// to the number of its files needing heal.
+func (xl xlObjects) ListObjectsHeal(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return ListObjectsInfo{}, BucketNameInvalid{Bucket: bucket}
+	}
+	// Verify whether the bucket exists.
+	if !isBucketExist(xl.storage, bucket) {
+		return ListObjectsInfo{}, BucketNotFound{Bucket: bucket}
+	}
+	if !IsValidObjectPrefix(prefix) {
+		return ListObjectsInfo{}, ObjectNameInvalid{Bucket: bucket, Object: prefix}
+	}
+	// Verify if delimiter is anything other than '/', which we do not support.
+	if delimiter != "" && delimiter != slashSeparator {
+		return ListObjectsInfo{}, UnsupportedDelimiter{
+			Delimiter: delimiter,
+		}
+	}
+	// Verify if marker has prefix.
+	if marker != "" && !strings.HasPrefix(marker, prefix) {
+		return ListObjectsInfo{}, InvalidMarkerPrefixCombination{
+			Marker: marker,
+			Prefix: prefix,
+		}
+	}
+	if maxKeys == 0 {
+		return ListObjectsInfo{}, nil
+	}
+	// Over flowing count - reset to maxObjectList.
+	if maxKeys < 0 || maxKeys > maxObjectList {
+		maxKeys = maxObjectList
+	}
+	storage, ok := xl.storage.(fileHealer)
+	if !ok {
+		// Nothing is ever healed.
+		return ListObjectsInfo{}, nil
+	}
+	// Walking and inspecting objects would heal them otherwise.
+	xl.storage = storage.withoutHeal()
+
+	// Default is recursive, if delimiter is set then list non recursive.
+	recursive := delimiter != slashSeparator
+
+	// Healthy objects are skipped without counting towards maxKeys, the
+	// walk is never saved for reuse by ListObjects.
+	walker := startTreeWalk(xl, bucket, prefix, marker, recursive)
+	result := ListObjectsInfo{}
+	for len(result.Objects)+len(result.Prefixes) < maxKeys {
+		walkResult, ok := <-walker.ch
+		if !ok {
+			// Closed channel.
+			return result, nil
+		}
+		if walkResult.err != nil {
+			// File not found is a valid case.
+			if walkResult.err == errFileNotFound {
+				return ListObjectsInfo{}, nil
+			}
+			return ListObjectsInfo{}, toObjectErr(walkResult.err, bucket, prefix)
+		}
+		fileInfo := walkResult.fileInfo
+		result.NextMarker = fileInfo.Name
+		if fileInfo.Mode.IsDir() {
+			result.Prefixes = append(result.Prefixes, fileInfo.Name)
+		} else {
+			missingParts, err := xl.objectMissingParts(bucket, fileInfo.Name)
+			if err != nil {
+				// The object got deleted or is below read quorum,
+				// nothing to heal it from either way.
+				log.WithFields(logrus.Fields{
+					"bucket": bucket,
+					"object": fileInfo.Name,
+				}).Debugf("Unable to check object redundancy %s", err)
+			} else if missingParts > 0 {
+				result.Objects = append(result.Objects, ObjectInfo{
+					Bucket:       bucket,
+					Name:         fileInfo.Name,
+					ModTime:      fileInfo.ModTime,
+					Size:         fileInfo.Size,
+					MissingParts: missingParts,
+				})
+			}
+		}
+		if walkResult.end {
+			return result, nil
+		}
+	}
+	result.IsTruncated = true
+	return result, nil
+}
@@ -0,0 +1,292 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Tests validate part files and the multipart meta file lost on some
+// disks are rebuilt and reported by HealObject.
+func TestXLHealObject(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+	obj, err := newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xl := obj.(xlObjects)
+
+	bucket := "bucket"
+	if err = xl.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	uploadID, err := xl.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data []byte
+	var parts []completePart
+	for i, size := range []int{5 * 1024 * 1024, 5 * 1024 * 1024, 17} {
+		partData := bytes.Repeat([]byte{byte('a' + i)}, size)
+		var md5Sum string
+		md5Sum, err = xl.PutObjectPart(bucket, "multipart", uploadID, i+1, int64(size), bytes.NewReader(partData), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, completePart{PartNumber: i + 1, ETag: md5Sum})
+		data = append(data, partData...)
+	}
+	if _, err = xl.CompleteMultipartUpload(bucket, "multipart", uploadID, parts); err != nil {
+		t.Fatal(err)
+	}
+	simpleData := []byte("hello, world")
+	if _, err = xl.PutObject(bucket, "simple", int64(len(simpleData)), bytes.NewReader(simpleData), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Healthy objects have nothing to heal, heals run outside
+	// maintenance mode too.
+	info, err := xl.HealObject(bucket, "multipart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Multipart || info.MultipartMetaHealed || len(info.HealedParts) != 0 {
+		t.Fatalf("Expected nothing healed, got %+v", info)
+	}
+
+	// Lose the second part on three disks and the meta file on two.
+	lostFiles := []string{
+		filepath.Join(erasureDisks[0], bucket, "multipart", partNumToPartFileName(2)),
+		filepath.Join(erasureDisks[5], bucket, "multipart", partNumToPartFileName(2)),
+		filepath.Join(erasureDisks[9], bucket, "multipart", partNumToPartFileName(2)),
+		filepath.Join(erasureDisks[3], bucket, "multipart", multipartMetaFile),
+		filepath.Join(erasureDisks[12], bucket, "multipart", multipartMetaFile),
+		filepath.Join(erasureDisks[7], bucket, "simple"),
+	}
+	for _, lostFile := range lostFiles {
+		if err = os.RemoveAll(lostFile); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err = xl.HealObject(bucket, "multipart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ObjectHealInfo{
+		Bucket:              bucket,
+		Object:              "multipart",
+		Multipart:           true,
+		MultipartMetaHealed: true,
+		HealedParts:         []int{2},
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, info)
+	}
+	info, err = xl.HealObject(bucket, "simple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.DataHealed || info.Multipart {
+		t.Fatalf("Expected simple object data healed, got %+v", info)
+	}
+	for _, lostFile := range lostFiles {
+		if _, err = os.Stat(lostFile); err != nil {
+			t.Fatalf("Expected %s to be healed, %s", lostFile, err)
+		}
+	}
+
+	// Healed objects are healthy again and read back intact.
+	info, err = xl.HealObject(bucket, "multipart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.MultipartMetaHealed || len(info.HealedParts) != 0 {
+		t.Fatalf("Expected nothing healed, got %+v", info)
+	}
+	reader, err := xl.GetObject(bucket, "multipart", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	got, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("Healed multipart object data mismatch")
+	}
+
+	// Part sizes recorded wrong are corrected and reported.
+	multipartInfo, err := getMultipartObjectInfo(xl.storage, bucket, "multipart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	multipartInfo.Parts[2].Size += 100
+	multipartInfo.Size += 100
+	if err = saveMultipartObjectInfo(xl.storage, bucket, "multipart", multipartInfo); err != nil {
+		t.Fatal(err)
+	}
+	info, err = xl.HealObject(bucket, "multipart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(info.MismatchedParts, []int{3}) {
+		t.Fatalf("Expected part 3 mismatched, got %+v", info)
+	}
+	if objInfo, err := xl.GetObjectInfo(bucket, "multipart"); err != nil || objInfo.Size != int64(len(data)) {
+		t.Fatalf("Expected size %d once healed, got %d, %v", len(data), objInfo.Size, err)
+	}
+
+	// Missing objects fail.
+	if _, err = xl.HealObject(bucket, "missing"); err == nil {
+		t.Fatal("Expected ObjectNotFound")
+	} else if _, ok := err.(ObjectNotFound); !ok {
+		t.Fatalf("Expected ObjectNotFound, got %v", err)
+	}
+}
+
+// Tests validate only objects below full redundancy are listed by
+// ListObjectsHeal, with the number of their files needing heal, and
+// that listing leaves them unhealed.
+func TestXLListObjectsHeal(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+	obj, err := newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xl := obj.(xlObjects)
+
+	bucket := "bucket"
+	if err = xl.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("hello, world")
+	for _, object := range []string{"a", "b", "dir/c", "dir/d"} {
+		if _, err = xl.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	uploadID, err := xl.NewMultipartUpload(bucket, "dir/multipart", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md5Sum, err := xl.PutObjectPart(bucket, "dir/multipart", uploadID, 1, int64(len(data)), bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = xl.CompleteMultipartUpload(bucket, "dir/multipart", uploadID, []completePart{{PartNumber: 1, ETag: md5Sum}}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := xl.ListObjectsHeal(bucket, "", "", "", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Objects) != 0 {
+		t.Fatalf("Expected no objects needing heal, got %+v", result.Objects)
+	}
+
+	lostFiles := []string{
+		filepath.Join(erasureDisks[2], bucket, "b"),
+		filepath.Join(erasureDisks[4], bucket, "dir", "d"),
+		filepath.Join(erasureDisks[1], bucket, "dir", "multipart", partNumToPartFileName(1)),
+		filepath.Join(erasureDisks[6], bucket, "dir", "multipart", multipartMetaFile),
+	}
+	for _, lostFile := range lostFiles {
+		if err = os.RemoveAll(lostFile); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		prefix      string
+		marker      string
+		delimiter   string
+		maxKeys     int
+		objects     map[string]int
+		prefixes    []string
+		isTruncated bool
+	}{
+		{"", "", "", 1000, map[string]int{"b": 1, "dir/d": 1, "dir/multipart": 2}, nil, false},
+		{"", "", "", 2, map[string]int{"b": 1, "dir/d": 1}, nil, true},
+		{"", "dir/d", "", 2, map[string]int{"dir/multipart": 2}, nil, false},
+		{"", "", "/", 1000, map[string]int{"b": 1}, []string{"dir/"}, false},
+		{"dir/", "", "/", 1000, map[string]int{"dir/d": 1, "dir/multipart": 2}, nil, false},
+	}
+	for i, testCase := range testCases {
+		result, err = xl.ListObjectsHeal(bucket, testCase.prefix, testCase.marker, testCase.delimiter, testCase.maxKeys)
+		if err != nil {
+			t.Fatalf("Test %d: %s", i+1, err)
+		}
+		objects := make(map[string]int)
+		for _, objInfo := range result.Objects {
+			objects[objInfo.Name] = objInfo.MissingParts
+		}
+		if !reflect.DeepEqual(objects, testCase.objects) {
+			t.Errorf("Test %d: Expected objects %v, got %v", i+1, testCase.objects, objects)
+		}
+		if !reflect.DeepEqual(result.Prefixes, testCase.prefixes) {
+			t.Errorf("Test %d: Expected prefixes %v, got %v", i+1, testCase.prefixes, result.Prefixes)
+		}
+		if result.IsTruncated != testCase.isTruncated {
+			t.Errorf("Test %d: Expected truncated %v, got %v", i+1, testCase.isTruncated, result.IsTruncated)
+		}
+	}
+
+	// Listing left the objects unhealed.
+	for _, lostFile := range lostFiles {
+		if _, err = os.Stat(lostFile); !os.IsNotExist(err) {
+			t.Fatalf("Expected %s to remain missing, got %v", lostFile, err)
+		}
+	}
+}
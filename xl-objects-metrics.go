@@ -0,0 +1,149 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// ObjectOp - object operation measured by ObjectMetrics.
+type ObjectOp string
+
+// Object operations measured.
+const (
+	ObjectOpGet    ObjectOp = "GetObject"
+	ObjectOpPut    ObjectOp = "PutObject"
+	ObjectOpDelete ObjectOp = "DeleteObject"
+	ObjectOpList   ObjectOp = "ListObjects"
+)
+
+// ObjectOpStats - measurements of a single object operation.
+type ObjectOpStats struct {
+	Op     ObjectOp
+	Bucket string
+	// Object, or the prefix listed.
+	Object string
+	// Time until the operation finished, for GetObject until the
+	// reader returned is closed.
+	Duration time.Duration
+	// Bytes served by GetObject or read from the data of PutObject.
+	Bytes int64
+	// Objects and prefixes returned by ListObjects.
+	Keys int
+	// GetObject read a multipart object rather than a simple one.
+	Multipart bool
+	// PutObject data didn't match its Content-MD5 or SHA-256.
+	ChecksumMismatch bool
+	// Error the operation failed with, nil on success, and the S3
+	// error code it's reported by for grouping failures.
+	Err       error
+	ErrorCode string
+}
+
+// ObjectMetrics - receives the stats of every object operation of an
+// XL object layer, for instance to export them as counters and
+// histograms. Operations run concurrently and wait for ObserveObjectOp
+// to return, implementations must be safe for concurrent use and
+// shouldn't block.
+type ObjectMetrics interface {
+	ObserveObjectOp(stats ObjectOpStats)
+}
+
+// noopObjectMetrics - default metrics, operations aren't measured at
+// all.
+type noopObjectMetrics struct{}
+
+func (noopObjectMetrics) ObserveObjectOp(ObjectOpStats) {}
+
+// WithMetrics - report the stats of GetObject, PutObject, DeleteObject
+// and ListObjects calls to metrics.
+func WithMetrics(metrics ObjectMetrics) XLOption {
+	return func(opts *xlOptions) {
+		opts.metrics = metrics
+	}
+}
+
+// measured - reports whether operations are measured, nothing is
+// timed or counted with the default metrics.
+func (xl xlObjects) measured() bool {
+	if xl.metrics == nil {
+		return false
+	}
+	_, noop := xl.metrics.(noopObjectMetrics)
+	return !noop
+}
+
+// observe - reports stats of an operation started at start.
+func (xl xlObjects) observe(stats ObjectOpStats, start time.Time) {
+	stats.Duration = time.Since(start)
+	if stats.Err != nil {
+		stats.ErrorCode = getAPIError(toAPIErrorCode(stats.Err)).Code
+	}
+	xl.metrics.ObserveObjectOp(stats)
+}
+
+// isChecksumMismatch - reports whether err says the data written
+// didn't match the checksum it was sent with.
+func isChecksumMismatch(err error) bool {
+	switch err.(type) {
+	case BadDigest, SHA256Mismatch:
+		return true
+	}
+	return false
+}
+
+// countingReader - counts the bytes read from reader.
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// observedReadCloser - reports the stats of a GetObject once its
+// reader is closed, along with the bytes served and the first read
+// error other than io.EOF.
+type observedReadCloser struct {
+	io.ReadCloser
+	xl    xlObjects
+	stats ObjectOpStats
+	start time.Time
+	once  sync.Once
+}
+
+func (r *observedReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.stats.Bytes += int64(n)
+	if err != nil && err != io.EOF && r.stats.Err == nil {
+		r.stats.Err = err
+	}
+	return n, err
+}
+
+func (r *observedReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(func() {
+		r.xl.observe(r.stats, r.start)
+	})
+	return err
+}
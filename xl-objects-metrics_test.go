@@ -0,0 +1,142 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sync"
+	"testing"
+)
+
+// recordedMetrics - metrics keeping the stats of every operation.
+type recordedMetrics struct {
+	mutex *sync.Mutex
+	stats []ObjectOpStats
+}
+
+func (m *recordedMetrics) ObserveObjectOp(stats ObjectOpStats) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stats = append(m.stats, stats)
+}
+
+// last - stats of the last operation, fails unless it was op.
+func (m *recordedMetrics) last(t *testing.T, op ObjectOp) ObjectOpStats {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.stats) == 0 {
+		t.Fatalf("Expected %s to be measured, nothing was", op)
+	}
+	stats := m.stats[len(m.stats)-1]
+	if stats.Op != op {
+		t.Fatalf("Expected %s to be measured last, got %s", op, stats.Op)
+	}
+	return stats
+}
+
+// Tests validate the stats reported for object operations, and that
+// layers without metrics don't measure anything.
+func TestXLObjectMetrics(t *testing.T) {
+	xl, removeDisks := newTestXLWithOptions(t, 8)
+	measured := xl.measured()
+	removeDisks()
+	if measured {
+		t.Fatal("Expected operations not to be measured without metrics")
+	}
+
+	metrics := &recordedMetrics{mutex: &sync.Mutex{}}
+	xl, removeDisks = newTestXLWithOptions(t, 8, WithMetrics(metrics))
+	defer removeDisks()
+	bucket := "bucket"
+	if err := xl.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+
+	data := bytes.Repeat([]byte("a"), 1000)
+	if _, err := xl.PutObject(bucket, "simple", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatal(err)
+	}
+	stats := metrics.last(t, ObjectOpPut)
+	if stats.Bucket != bucket || stats.Object != "simple" || stats.Bytes != int64(len(data)) || stats.Err != nil || stats.ChecksumMismatch {
+		t.Fatalf("Unexpected PutObject stats %+v", stats)
+	}
+
+	// Data not matching its MD5 is reported as a checksum mismatch.
+	metadata := map[string]string{"md5Sum": "00000000000000000000000000000000"}
+	if _, err := xl.PutObject(bucket, "corrupt", int64(len(data)), bytes.NewReader(data), metadata); err == nil {
+		t.Fatal("Expected PutObject with a wrong MD5 to fail")
+	}
+	stats = metrics.last(t, ObjectOpPut)
+	if !stats.ChecksumMismatch || stats.ErrorCode != "BadDigest" || stats.Bytes != int64(len(data)) {
+		t.Fatalf("Unexpected PutObject stats %+v", stats)
+	}
+
+	// Reads are measured once their reader is closed.
+	reader, err := xl.GetObject(bucket, "simple", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ioutil.ReadAll(reader); err != nil {
+		t.Fatal(err)
+	}
+	if err = reader.Close(); err != nil {
+		t.Fatal(err)
+	}
+	stats = metrics.last(t, ObjectOpGet)
+	if stats.Bytes != int64(len(data))-100 || stats.Multipart || stats.Err != nil {
+		t.Fatalf("Unexpected GetObject stats %+v", stats)
+	}
+
+	multipartData, err := putTestMultipartObject(xl, bucket, "multipart", 2, 5*1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader, err = xl.GetObject(bucket, "multipart", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ioutil.ReadAll(reader); err != nil {
+		t.Fatal(err)
+	}
+	reader.Close()
+	stats = metrics.last(t, ObjectOpGet)
+	if stats.Bytes != int64(len(multipartData)) || !stats.Multipart {
+		t.Fatalf("Unexpected GetObject stats %+v", stats)
+	}
+
+	if _, err = xl.GetObject(bucket, "missing", 0); err == nil {
+		t.Fatal("Expected GetObject of a missing object to fail")
+	}
+	if stats = metrics.last(t, ObjectOpGet); stats.ErrorCode != "NoSuchKey" {
+		t.Fatalf("Expected NoSuchKey, got %+v", stats)
+	}
+
+	result, err := xl.ListObjects(bucket, "", "", "", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats = metrics.last(t, ObjectOpList); stats.Keys != len(result.Objects) || stats.Keys != 2 {
+		t.Fatalf("Expected 2 keys listed, got %+v", stats)
+	}
+
+	if err = xl.DeleteObject(bucket, "simple"); err != nil {
+		t.Fatal(err)
+	}
+	if stats = metrics.last(t, ObjectOpDelete); stats.Object != "simple" || stats.Err != nil {
+		t.Fatalf("Unexpected DeleteObject stats %+v", stats)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	// How long the multipart status of an object is cached, bounds
+	// how long changes made by other servers go unnoticed.
+	multipartCacheExpiry = 5 * time.Second
+	// Maximum number of objects whose multipart status is cached.
+	multipartCacheSize = 10000
+)
+
+// multipartCacheEntry - cached multipart status of an object.
+type multipartCacheEntry struct {
+	multipart bool
+	expires   time.Time
+}
+
+// multipartCache - caches whether objects are multipart objects, so
+// that a read stats the multipart meta file at most once. Objects are
+// forgotten whenever this server replaces or deletes them.
+type multipartCache struct {
+	mutex   *sync.Mutex
+	entries map[string]multipartCacheEntry
+	// Incremented by every forget, results of probes which started
+	// before a forget may be stale and aren't saved.
+	gen uint64
+}
+
+// newMultipartCache - initialize an empty multipart cache.
+func newMultipartCache() *multipartCache {
+	return &multipartCache{
+		mutex:   &sync.Mutex{},
+		entries: make(map[string]multipartCacheEntry),
+	}
+}
+
+// lookup - returns the cached multipart status of an object, ok is
+// false if it isn't cached or expired.
+func (c *multipartCache) lookup(bucket, object string) (multipart bool, ok bool) {
+	if c == nil {
+		return false, false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry, ok := c.entries[pathJoin(bucket, object)]
+	if !ok || time.Now().After(entry.expires) {
+		return false, false
+	}
+	return entry.multipart, true
+}
+
+// generation - returns the generation to pass to save for a probe
+// starting now.
+func (c *multipartCache) generation() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.gen
+}
+
+// save - caches the multipart status of an object probed at gen,
+// unless an object was forgotten since.
+func (c *multipartCache) save(bucket, object string, multipart bool, gen uint64) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if gen != c.gen {
+		return
+	}
+	now := time.Now()
+	if len(c.entries) >= multipartCacheSize {
+		for key, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, key)
+			}
+		}
+		if len(c.entries) >= multipartCacheSize {
+			// Still full, start over rather than track usage.
+			c.entries = make(map[string]multipartCacheEntry)
+		}
+	}
+	c.entries[pathJoin(bucket, object)] = multipartCacheEntry{
+		multipart: multipart,
+		expires:   now.Add(multipartCacheExpiry),
+	}
+}
+
+// forget - drops the cached multipart status of an object, called
+// whenever the object is replaced or deleted.
+func (c *multipartCache) forget(bucket, object string) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.entries, pathJoin(bucket, object))
+	c.gen++
+}
+
+// isMultipart - isMultipartObject answered from the multipart cache
+// when possible.
+func (xl xlObjects) isMultipart(bucket, object string) (bool, error) {
+	if multipart, ok := xl.multipartCache.lookup(bucket, object); ok {
+		return multipart, nil
+	}
+	gen := xl.multipartCache.generation()
+	multipart, err := isMultipartObject(xl.storage, bucket, object)
+	if err != nil {
+		return false, err
+	}
+	xl.multipartCache.save(bucket, object, multipart, gen)
+	return multipart, nil
+}
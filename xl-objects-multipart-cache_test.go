@@ -0,0 +1,173 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// multipartStatCountingStorage - counts stats of multipart meta files.
+type multipartStatCountingStorage struct {
+	StorageAPI
+	count *int64
+}
+
+func (s multipartStatCountingStorage) StatFile(volume, path string) (FileInfo, error) {
+	if strings.HasSuffix(path, multipartMetaFile) {
+		atomic.AddInt64(s.count, 1)
+	}
+	return s.StorageAPI.StatFile(volume, path)
+}
+
+// Tests validate objects replaced or deleted on this server are never
+// served with a stale multipart status.
+func TestXLMultipartCacheInvalidation(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+	obj, err := newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket := "bucket"
+	object := "object"
+	if err = obj.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	readObject := func() ([]byte, error) {
+		reader, err := obj.GetObject(bucket, object, 0)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return ioutil.ReadAll(reader)
+	}
+
+	// Simple object, its status is cached by the read.
+	simpleData := []byte("simple")
+	if _, err = obj.PutObject(bucket, object, int64(len(simpleData)), bytes.NewReader(simpleData), nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := readObject(); err != nil || !bytes.Equal(data, simpleData) {
+		t.Fatalf("Expected %q, got %q, %v", simpleData, data, err)
+	}
+
+	// Replaced by a multipart object.
+	uploadID, err := obj.NewMultipartUpload(bucket, object, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	multipartData := []byte("multipart")
+	md5Sum, err := obj.PutObjectPart(bucket, object, uploadID, 1, int64(len(multipartData)), bytes.NewReader(multipartData), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, object, uploadID, []completePart{{PartNumber: 1, ETag: md5Sum}}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := readObject(); err != nil || !bytes.Equal(data, multipartData) {
+		t.Fatalf("Expected %q, got %q, %v", multipartData, data, err)
+	}
+
+	// Deleted and replaced by a simple object again.
+	if err = obj.DeleteObject(bucket, object); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = readObject(); err == nil {
+		t.Fatal("Expected ObjectNotFound")
+	} else if _, ok := err.(ObjectNotFound); !ok {
+		t.Fatalf("Expected ObjectNotFound, got %v", err)
+	}
+	if _, err = obj.PutObject(bucket, object, int64(len(simpleData)), bytes.NewReader(simpleData), nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := readObject(); err != nil || !bytes.Equal(data, simpleData) {
+		t.Fatalf("Expected %q, got %q, %v", simpleData, data, err)
+	}
+}
+
+// Benchmark stats of the multipart meta file by GETs of a simple object
+// on XL, with and without the multipart cache.
+func BenchmarkXLGetObjectMultipartStat(b *testing.B) {
+	initNSLock()
+	var disks []string
+	for i := 0; i < 8; i++ {
+		directory, err := ioutil.TempDir("", "minio-benchmark-getobject")
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer os.RemoveAll(directory)
+		disks = append(disks, directory)
+	}
+	obj, err := newXLObjects(disks...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err = obj.MakeBucket("bucket"); err != nil {
+		b.Fatal(err)
+	}
+	text := []byte("Jack and Jill went up the hill / To fetch a pail of water.")
+	if _, err = obj.PutObject("bucket", "object", int64(len(text)), bytes.NewReader(text), nil); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, cached := range []bool{false, true} {
+		name := "uncached"
+		if cached {
+			name = "cached"
+		}
+		b.Run(name, func(b *testing.B) {
+			var count int64
+			xl := obj.(xlObjects)
+			xl.storage = multipartStatCountingStorage{xl.storage, &count}
+			xl.multipartCache = nil
+			if cached {
+				xl.multipartCache = newMultipartCache()
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				reader, err := xl.GetObject("bucket", "object", 0)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err = ioutil.ReadAll(reader); err != nil {
+					b.Fatal(err)
+				}
+				reader.Close()
+			}
+			b.ReportMetric(float64(atomic.LoadInt64(&count))/float64(b.N), "stats/op")
+		})
+	}
+}
@@ -24,6 +24,9 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/skyrings/skyring-common/tools/uuid"
 )
 
 // MultipartPartInfo Info of each part kept in the multipart metadata file after
@@ -32,6 +35,13 @@ type MultipartPartInfo struct {
 	PartNumber int
 	ETag       string
 	Size       int64
+	// Checksum - hex SHA-256 of the part data, empty for parts
+	// uploaded before checksums were kept.
+	Checksum string
+	// Encryption - metadata of the encryption at rest of the part,
+	// with its own data key and IV, nil if stored in cleartext. Size
+	// and Checksum are of the content.
+	Encryption map[string]string `json:",omitempty"`
 }
 
 // MultipartObjectInfo - contents of the multipart metadata file after
@@ -41,6 +51,9 @@ type MultipartObjectInfo struct {
 	ModTime time.Time
 	Size    int64
 	MD5Sum  string
+	// Retention is kept along with the parts, so that it applies as
+	// soon as the object is in place.
+	Retention *ObjectRetention `json:",omitempty"`
 }
 
 type byMultipartFiles []string
@@ -68,18 +81,91 @@ func (m MultipartObjectInfo) GetPartNumberOffset(offset int64) (partIndex int, p
 	return
 }
 
+// RecomputeMultipartOffsets - stats every part file of a multipart
+// object and rewrites the multipart meta file with the actual part
+// sizes, so that GetPartNumberOffset() lines up with the data on disk
+// again. Returns the part numbers whose recorded size disagreed, also
+// run by HealObject.
+func (xl xlObjects) RecomputeMultipartOffsets(bucket, object string) ([]int, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, BucketNameInvalid{Bucket: bucket}
+	}
+	if !xl.isBucketExist(bucket) {
+		return nil, BucketNotFound{Bucket: bucket}
+	}
+	if !IsValidObjectName(object) {
+		return nil, ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	// Keep the object from being replaced while its meta file is rewritten.
+	unlock := lockObject(bucket, object)
+	defer unlock()
+	return xl.recomputeMultipartOffsets(bucket, object)
+}
+
+// recomputeMultipartOffsets - RecomputeMultipartOffsets of an object
+// whose lock is held by the caller.
+func (xl xlObjects) recomputeMultipartOffsets(bucket, object string) ([]int, error) {
+	// The recorded size is recomputed, it may not add up yet.
+	info, err := readMultipartObjectInfo(xl.storage, bucket, object)
+	if err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	var mismatchedParts []int
+	var totalSize int64
+	for index, part := range info.Parts {
+		var fi FileInfo
+		fi, err = xl.storage.StatFile(bucket, pathJoin(object, partNumToPartFileName(part.PartNumber)))
+		if err != nil {
+			return nil, toObjectErr(err, bucket, object)
+		}
+		// Sizes of parts encrypted at rest are of their content.
+		if part.Encryption != nil {
+			if fi.Size, err = decryptedSize(fi.Size); err != nil {
+				return nil, toObjectErr(err, bucket, object)
+			}
+		}
+		if fi.Size != part.Size {
+			xl.log().Errorf("Part %d of %s/%s has size %d, recorded size was %d", part.PartNumber, bucket, object, fi.Size, part.Size)
+			mismatchedParts = append(mismatchedParts, part.PartNumber)
+			info.Parts[index].Size = fi.Size
+		}
+		totalSize += fi.Size
+	}
+	if len(mismatchedParts) == 0 && totalSize == info.Size {
+		// Nothing to rewrite.
+		return nil, nil
+	}
+	info.Size = totalSize
+	if err = saveMultipartObjectInfo(xl.storage, bucket, object, info); err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	return mismatchedParts, nil
+}
+
 func partNumToPartFileName(partNum int) string {
 	return fmt.Sprintf("%.5d%s", partNum, multipartSuffix)
 }
 
+// SetPartSizeLimits - change the sizes parts of multipart uploads have
+// to be within, the last part of an upload may be smaller than minSize.
+func (xl xlObjects) SetPartSizeLimits(minSize, maxSize int64) error {
+	return xl.partSizes.set(minSize, maxSize)
+}
+
+// PartSizeLimits - sizes parts of multipart uploads have to be within.
+func (xl xlObjects) PartSizeLimits() (minSize, maxSize int64) {
+	return xl.partSizes.get()
+}
+
 // ListMultipartUploads - list multipart uploads.
 func (xl xlObjects) ListMultipartUploads(bucket, prefix, keyMarker, uploadIDMarker, delimiter string, maxUploads int) (ListMultipartsInfo, error) {
 	return listMultipartUploadsCommon(xl, bucket, prefix, keyMarker, uploadIDMarker, delimiter, maxUploads)
 }
 
 // NewMultipartUpload - initialize a new multipart upload, returns a unique id.
-func (xl xlObjects) NewMultipartUpload(bucket, object string) (string, error) {
-	return newMultipartUploadCommon(xl.storage, bucket, object)
+func (xl xlObjects) NewMultipartUpload(bucket, object string, metadata map[string]string) (string, error) {
+	return newMultipartUploadCommon(xl.storage, bucket, object, metadata)
 }
 
 // PutObjectPart - writes the multipart upload chunks.
@@ -121,7 +207,7 @@ func (xl xlObjects) CompleteMultipartUpload(bucket string, object string, upload
 		return "", BucketNameInvalid{Bucket: bucket}
 	}
 	// Verify whether the bucket exists.
-	if !isBucketExist(xl.storage, bucket) {
+	if !xl.isBucketExist(bucket) {
 		return "", BucketNotFound{Bucket: bucket}
 	}
 	if !IsValidObjectName(object) {
@@ -133,6 +219,33 @@ func (xl xlObjects) CompleteMultipartUpload(bucket string, object string, upload
 	if !isUploadIDExists(xl.storage, bucket, object, uploadID) {
 		return "", InvalidUploadID{UploadID: uploadID}
 	}
+	if err := validateCompleteParts(uploadID, parts); err != nil {
+		return "", err
+	}
+	// Serialize with other writes and deletes of the object.
+	unlock := lockObject(bucket, object)
+	defer unlock()
+	// Objects under legal hold or retention can't be overwritten.
+	if err := checkObjectMutable(xl.storage, bucket, object, xl.bypassGovernance); err != nil {
+		return "", err
+	}
+	meta, err := readBucketMetadata(xl.storage, bucket)
+	if err != nil {
+		return "", toObjectErr(err, bucket)
+	}
+	// Account the object in the bucket stats once written.
+	defer xl.bucketStats.track(xl, bucket, object)()
+	// New objects count against the bucket object limit.
+	releaseSlot, err := reserveObjectSlot(xl, xl.storage, meta, bucket, object)
+	if err != nil {
+		return "", err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			releaseSlot()
+		}
+	}()
 
 	// Calculate s3 compatible md5sum for complete multipart.
 	s3MD5, err := completeMultipartMD5(parts...)
@@ -140,6 +253,16 @@ func (xl xlObjects) CompleteMultipartUpload(bucket string, object string, upload
 		return "", err
 	}
 
+	// Metadata given when the upload was started.
+	objMetadata, err := readUploadMetadata(xl.storage, bucket, object, uploadID)
+	if err != nil {
+		return "", toObjectErr(err, bucket, object)
+	}
+	// Objects of versioned buckets get a version ID.
+	if objMetadata, err = withVersionID(meta, objMetadata); err != nil {
+		return "", toObjectErr(err, bucket, object)
+	}
+
 	var metadata = MultipartObjectInfo{}
 	var errs = make([]error, len(parts))
 
@@ -150,24 +273,33 @@ func (xl xlObjects) CompleteMultipartUpload(bucket string, object string, upload
 	for i, part := range parts {
 		// Construct part suffix.
 		partSuffix := fmt.Sprintf("%.5d.%s", part.PartNumber, part.ETag)
-		multipartPartFile := path.Join(mpartMetaPrefix, bucket, object, uploadID, partSuffix)
 		var fi FileInfo
-		fi, err = xl.storage.StatFile(minioMetaBucket, multipartPartFile)
+		var encryption map[string]string
+		fi, encryption, err = statUploadedPart(xl.storage, bucket, object, uploadID, partSuffix)
 		if err != nil {
+			// Never uploaded, or uploaded with other data.
 			if err == errFileNotFound {
-				return "", InvalidPart{}
+				return "", InvalidPart{PartNumber: part.PartNumber, ETag: part.ETag}
 			}
 			return "", err
 		}
-		// All parts except the last part has to be atleast 5MB.
-		if (i < len(parts)-1) && !isMinAllowedPartSize(fi.Size) {
-			return "", PartTooSmall{}
+		// All parts except the last part has to be atleast the
+		// minimum part size.
+		if err = xl.partSizes.checkPart(part.PartNumber, fi.Size, i == len(parts)-1); err != nil {
+			return "", err
+		}
+		var checksum string
+		checksum, err = readPartChecksum(xl.storage, bucket, object, uploadID, partSuffix)
+		if err != nil {
+			return "", toObjectErr(err, bucket, object)
 		}
 		// Update metadata parts.
 		metadata.Parts = append(metadata.Parts, MultipartPartInfo{
 			PartNumber: part.PartNumber,
 			ETag:       part.ETag,
 			Size:       fi.Size,
+			Checksum:   checksum,
+			Encryption: encryption,
 		})
 		metadata.Size += fi.Size
 	}
@@ -181,6 +313,9 @@ func (xl xlObjects) CompleteMultipartUpload(bucket string, object string, upload
 	metadata.MD5Sum = s3MD5
 	// Save modTime as well as the current time.
 	metadata.ModTime = time.Now().UTC()
+	if metadata.Retention, err = getRetentionFromMetadata(objMetadata); err != nil {
+		return "", InvalidObjectRetention{Bucket: bucket, Object: object}
+	}
 
 	// Create temporary multipart meta file to write and then rename.
 	tempMultipartMetaFile := path.Join(tmpMetaPrefix, bucket, object, uploadID, multipartMetaFile)
@@ -224,7 +359,7 @@ func (xl xlObjects) CompleteMultipartUpload(bucket string, object string, upload
 			dst := path.Join(mpartMetaPrefix, bucket, object, uploadID, partNumToPartFileName(part.PartNumber))
 			errs[index] = xl.storage.RenameFile(minioMetaBucket, src, minioMetaBucket, dst)
 			if errs[index] != nil {
-				log.Errorf("Unable to rename file %s to %s, failed with %s", src, dst, errs[index])
+				xl.log().Errorf("Unable to rename file %s to %s, failed with %s", src, dst, errs[index])
 			}
 		}(index, part)
 	}
@@ -246,18 +381,40 @@ func (xl xlObjects) CompleteMultipartUpload(bucket string, object string, upload
 		return "", toObjectErr(err, minioMetaBucket, uploadIDPath)
 	}
 
-	// Delete if an object already exists.
-	// FIXME: rename it to tmp file and delete only after
-	// the newly uploaded file is renamed from tmp location to
-	// the original location.
-	err = xl.deleteObject(bucket, object)
-	if err != nil && err != errFileNotFound {
+	// Remove uploaded parts left out of the object, such as earlier
+	// uploads of a part, they would be orphaned in the object.
+	if err = xl.removeUnusedParts(bucket, object, uploadID, parts); err != nil {
+		return "", err
+	}
+
+	// A dedup blob the overwritten object references loses a reference.
+	oldRef, err := lockDedupReference(xl.storage, bucket, object)
+	if err != nil {
 		return "", toObjectErr(err, bucket, object)
 	}
+	defer oldRef.unlock()
 
-	if err = xl.storage.RenameFile(minioMetaBucket, path.Join(mpartMetaPrefix, bucket, object, uploadID), bucket, object); err != nil {
+	// Metadata is staged and renamed in along with the upload.
+	tempMeta := path.Join(tmpMetaPrefix, uploadID+objectMetaSuffix)
+	if err = stageObjectMetadata(xl.storage, tempMeta, objMetadata); err != nil {
+		return "", toObjectErr(err, bucket, object)
+	}
+	// Rename the upload in place of any existing object.
+	retained, err := xl.replaceObject(minioMetaBucket, path.Join(mpartMetaPrefix, bucket, object, uploadID), tempMeta, bucket, object, meta.Versioning)
+	if err != nil {
+		if derr := xl.storage.DeleteFile(minioMetaBucket, tempMeta); derr != nil && derr != errFileNotFound {
+			return "", toObjectErr(derr, bucket, object)
+		}
 		return "", toObjectErr(err, bucket, object)
 	}
+	committed = true
+	invalidateTreeWalks(xl, bucket, object)
+	// A retained version keeps referencing the dedup blob.
+	if !retained {
+		if err = oldRef.release(); err != nil {
+			return "", toObjectErr(err, bucket, object)
+		}
+	}
 	// Validate if there are other incomplete upload-id's present for
 	// the object, if yes do not attempt to delete 'uploads.json'.
 	var entries []string
@@ -277,7 +434,170 @@ func (xl xlObjects) CompleteMultipartUpload(bucket string, object string, upload
 	return s3MD5, nil
 }
 
+// removeUnusedParts - removes the parts of an upload not named in
+// parts, left after the used parts were renamed to their final names.
+func (xl xlObjects) removeUnusedParts(bucket, object, uploadID string, parts []completePart) error {
+	uploadIDDir := path.Join(mpartMetaPrefix, bucket, object, uploadID)
+	usedFiles := map[string]bool{multipartMetaFile: true}
+	for _, part := range parts {
+		usedFiles[partNumToPartFileName(part.PartNumber)] = true
+	}
+	entries, err := xl.storage.ListDir(minioMetaBucket, uploadIDDir)
+	if err != nil {
+		return toObjectErr(err, minioMetaBucket, uploadIDDir)
+	}
+	for _, entry := range entries {
+		if usedFiles[entry] {
+			continue
+		}
+		if err = xl.storage.DeleteFile(minioMetaBucket, path.Join(uploadIDDir, entry)); err != nil && err != errFileNotFound {
+			return toObjectErr(err, minioMetaBucket, path.Join(uploadIDDir, entry))
+		}
+	}
+	return nil
+}
+
+// CompleteMultipartUploadWithManifest - completes an upload only if
+// its parts match the sizes and md5sums listed in manifest.
+func (xl xlObjects) CompleteMultipartUploadWithManifest(bucket, object, uploadID string, parts []completePart, manifest []manifestPart) (string, error) {
+	return completeMultipartUploadWithManifest(xl, xl.storage, bucket, object, uploadID, parts, manifest)
+}
+
 // AbortMultipartUpload - aborts a multipart upload.
 func (xl xlObjects) AbortMultipartUpload(bucket, object, uploadID string) error {
 	return abortMultipartUploadCommon(xl.storage, bucket, object, uploadID)
 }
+
+// copyMultipartObject - copies a multipart object part by part on the
+// storage, keeping its parts and multipart MD5Sum.
+func (xl xlObjects) copyMultipartObject(srcBucket, srcObject, dstBucket, dstObject string, metadata map[string]string) (ObjectInfo, error) {
+	meta, err := readBucketMetadata(xl.storage, dstBucket)
+	if err != nil {
+		return ObjectInfo{}, toObjectErr(err, dstBucket)
+	}
+	// Verify if object name is allowed by the bucket prefixes.
+	if err = checkObjectPrefixAllowed(meta, dstBucket, dstObject); err != nil {
+		return ObjectInfo{}, err
+	}
+	// Verify if object name collides on a case-insensitive backend.
+	if err = checkKeyCollision(xl.storage, dstBucket, dstObject); err != nil {
+		return ObjectInfo{}, err
+	}
+	// Objects under legal hold or retention can't be overwritten.
+	if err = checkObjectMutable(xl.storage, dstBucket, dstObject, xl.bypassGovernance); err != nil {
+		return ObjectInfo{}, err
+	}
+	// Account the object in the bucket stats once written.
+	defer xl.bucketStats.track(xl, dstBucket, dstObject)()
+	// New objects count against the bucket object limit.
+	releaseSlot, err := reserveObjectSlot(xl, xl.storage, meta, dstBucket, dstObject)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			releaseSlot()
+		}
+	}()
+
+	info, err := getMultipartObjectInfo(xl.storage, srcBucket, srcObject)
+	if err != nil {
+		return ObjectInfo{}, toObjectErr(err, srcBucket, srcObject)
+	}
+	id, err := uuid.New()
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	// Parts and multipart meta file are staged together, then renamed
+	// in place at once.
+	tempObj := path.Join(tmpMetaPrefix, dstBucket, dstObject, id.String())
+	for _, part := range info.Parts {
+		partFileName := partNumToPartFileName(part.PartNumber)
+		if err = copyStorageFile(xl.storage, srcBucket, pathJoin(srcObject, partFileName), minioMetaBucket, pathJoin(tempObj, partFileName)); err != nil {
+			xl.deleteTempObject(tempObj, info)
+			return ObjectInfo{}, toObjectErr(err, srcBucket, srcObject)
+		}
+	}
+	// Carry the multipart MD5Sum forward, the parts are the same.
+	info.ModTime = time.Now().UTC()
+	if err = saveMultipartObjectInfo(xl.storage, minioMetaBucket, tempObj, info); err != nil {
+		xl.deleteTempObject(tempObj, info)
+		return ObjectInfo{}, toObjectErr(err, dstBucket, dstObject)
+	}
+
+	// check if an object is present as one of the parent dir.
+	if err = xl.parentDirIsObject(dstBucket, path.Dir(dstObject)); err != nil {
+		xl.deleteTempObject(tempObj, info)
+		return ObjectInfo{}, toObjectErr(err, dstBucket, dstObject)
+	}
+	// A dedup blob the overwritten object references loses a reference.
+	oldRef, err := lockDedupReference(xl.storage, dstBucket, dstObject)
+	if err != nil {
+		xl.deleteTempObject(tempObj, info)
+		return ObjectInfo{}, toObjectErr(err, dstBucket, dstObject)
+	}
+	defer oldRef.unlock()
+	// In a versioned bucket the existing object is retained instead,
+	// along with its dedup blob reference.
+	version, err := retainReplacedObject(xl, xl.storage, meta, dstBucket, dstObject, false)
+	if err != nil {
+		xl.deleteTempObject(tempObj, info)
+		return ObjectInfo{}, toObjectErr(err, dstBucket, dstObject)
+	}
+	restoreVersion := func() {
+		if rerr := version.restore(); rerr != nil {
+			xl.log().Errorf("Unable to restore %s/%s from its version: %s", dstBucket, dstObject, rerr)
+		}
+	}
+	// Delete if an object already exists.
+	if _, err = xl.deleteObject(dstBucket, dstObject, false); err != nil && err != errFileNotFound {
+		restoreVersion()
+		xl.deleteTempObject(tempObj, info)
+		return ObjectInfo{}, toObjectErr(err, dstBucket, dstObject)
+	}
+	if version == nil {
+		if err = oldRef.release(); err != nil {
+			xl.deleteTempObject(tempObj, info)
+			return ObjectInfo{}, toObjectErr(err, dstBucket, dstObject)
+		}
+	}
+	if err = xl.storage.RenameFile(minioMetaBucket, tempObj, dstBucket, dstObject); err != nil {
+		restoreVersion()
+		xl.deleteTempObject(tempObj, info)
+		return ObjectInfo{}, toObjectErr(err, dstBucket, dstObject)
+	}
+	// Object is in place, keep its slot even if saving metadata fails.
+	committed = true
+	xl.multipartCache.forget(dstBucket, dstObject)
+	invalidateTreeWalks(xl, dstBucket, dstObject)
+
+	// Mark the object pending replication if the bucket replicates.
+	metadata, replicate := replicationMetadata(meta, metadata)
+	if metadata, err = withVersionID(meta, metadata); err != nil {
+		return ObjectInfo{}, toObjectErr(err, dstBucket, dstObject)
+	}
+	if err = writeObjectMetadata(xl.storage, dstBucket, dstObject, metadata); err != nil {
+		return ObjectInfo{}, toObjectErr(err, dstBucket, dstObject)
+	}
+	if replicate {
+		xl.replicator.enqueue(replicationJob{bucket: dstBucket, object: dstObject})
+	}
+	return xl.GetObjectInfo(dstBucket, dstObject)
+}
+
+// deleteTempObject - removes the staged parts and multipart meta file
+// of a copy which failed, errors are only logged.
+func (xl xlObjects) deleteTempObject(tempObj string, info MultipartObjectInfo) {
+	files := []string{multipartMetaFile}
+	for _, part := range info.Parts {
+		files = append(files, partNumToPartFileName(part.PartNumber))
+	}
+	for _, file := range files {
+		if err := xl.storage.DeleteFile(minioMetaBucket, pathJoin(tempObj, file)); err != nil && err != errFileNotFound {
+			errorIf(err, "Unable to delete staged copy.", logrus.Fields{
+				"path": pathJoin(tempObj, file),
+			})
+		}
+	}
+}
@@ -0,0 +1,437 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Wrapper for calling RecomputeMultipartOffsets tests for XL multiple disks setup.
+func TestXLRecomputeMultipartOffsets(t *testing.T) {
+	// Initialize name space lock, used by the single node setup too.
+	initNSLock()
+	ExecObjectLayerTest(t, testXLRecomputeMultipartOffsets)
+}
+
+// Tests validate that a deliberately mis-sized part in the multipart
+// meta file is detected and its size, offsets recomputed.
+func testXLRecomputeMultipartOffsets(obj ObjectLayer, instanceType string, t *testing.T) {
+	xl, ok := obj.(xlObjects)
+	if !ok {
+		// Only applicable for XL.
+		return
+	}
+	bucket := "minio-bucket"
+	object := "minio-object"
+
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, object, nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	// All parts except the last one need to be at least 5MB.
+	partSizes := []int{5 * 1024 * 1024, 5*1024*1024 + 7, 11}
+	totalSize := int64(partSizes[0] + partSizes[1] + partSizes[2])
+	var parts []completePart
+	for i, size := range partSizes {
+		partID := i + 1
+		data := bytes.Repeat([]byte("a"), size)
+		var md5Sum string
+		md5Sum, err = obj.PutObjectPart(bucket, object, uploadID, partID, int64(size), bytes.NewReader(data), "")
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		parts = append(parts, completePart{PartNumber: partID, ETag: md5Sum})
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, object, uploadID, parts); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	// Nothing to fix on a freshly completed object.
+	mismatched, err := xl.RecomputeMultipartOffsets(bucket, object)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(mismatched) != 0 {
+		t.Fatalf("%s: Expected no mismatched parts, got %v", instanceType, mismatched)
+	}
+
+	// Corrupt the recorded size of the second part.
+	info, err := getMultipartObjectInfo(xl.storage, bucket, object)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	info.Parts[1].Size += 100
+	info.Size += 100
+	if err = saveMultipartObjectInfo(xl.storage, bucket, object, info); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	// Offsets in the last part are now resolved against the second part.
+	lastPartOffset := int64(partSizes[0] + partSizes[1])
+	if partIndex, _, _ := info.GetPartNumberOffset(lastPartOffset + 1); partIndex != 1 {
+		t.Fatalf("%s: Expected drifted part index 1, got %d", instanceType, partIndex)
+	}
+
+	mismatched, err = xl.RecomputeMultipartOffsets(bucket, object)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if len(mismatched) != 1 || mismatched[0] != 2 {
+		t.Fatalf("%s: Expected part 2 to be flagged, got %v", instanceType, mismatched)
+	}
+
+	info, err = getMultipartObjectInfo(xl.storage, bucket, object)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if info.Parts[1].Size != int64(partSizes[1]) {
+		t.Fatalf("%s: Expected part 2 size %d, got %d", instanceType, partSizes[1], info.Parts[1].Size)
+	}
+	if info.Size != totalSize {
+		t.Fatalf("%s: Expected object size %d, got %d", instanceType, totalSize, info.Size)
+	}
+	partIndex, offset, err := info.GetPartNumberOffset(lastPartOffset + 1)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if partIndex != 2 || offset != 1 {
+		t.Fatalf("%s: Expected part index 2 offset 1, got %d %d", instanceType, partIndex, offset)
+	}
+
+	// Non multipart object should fail.
+	if _, err = xl.RecomputeMultipartOffsets(bucket, "non-existent"); err == nil {
+		t.Fatalf("%s: Expected to fail for a non-existent object", instanceType)
+	}
+}
+
+// Benchmark deleting a multipart object with 10,000 parts, part
+// deletes are bounded by defaultDeletePartsConcurrency.
+func BenchmarkXLDeleteObjectParts(b *testing.B) {
+	// Make a temporary directory to use as the disk.
+	directory, err := ioutil.TempDir("", "minio-benchmark-deleteparts")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	storage, err := newPosix(directory)
+	if err != nil {
+		b.Fatal(err)
+	}
+	initObjectLayer(storage)
+	xl := xlObjects{
+		storage:                storage,
+		readCoalescer:          newReadCoalescer(),
+		deletePartsConcurrency: defaultDeletePartsConcurrency,
+	}
+	bucket := "bucket"
+	object := "object"
+	if err = storage.MakeVol(bucket); err != nil {
+		b.Fatal(err)
+	}
+
+	var info MultipartObjectInfo
+	for partNumber := 1; partNumber <= 10000; partNumber++ {
+		info.Parts = append(info.Parts, MultipartPartInfo{PartNumber: partNumber, Size: 1})
+		info.Size++
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		for _, part := range info.Parts {
+			w, err := storage.CreateFile(bucket, pathJoin(object, partNumToPartFileName(part.PartNumber)))
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err = w.Write([]byte("a")); err != nil {
+				b.Fatal(err)
+			}
+			if err = w.Close(); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err = saveMultipartObjectInfo(storage, bucket, object, info); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		if _, err = xl.deleteObject(bucket, object, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Wrapper for calling multipart read ahead tests for XL multiple disks setup.
+func TestXLGetObjectReadAhead(t *testing.T) {
+	ExecObjectLayerTest(t, testXLGetObjectReadAhead)
+}
+
+// Tests validate multipart objects read the same at any read ahead
+// depth, and a failing part ends the read with an error.
+func testXLGetObjectReadAhead(obj ObjectLayer, instanceType string, t *testing.T) {
+	xl, ok := obj.(xlObjects)
+	if !ok {
+		// Only applicable for XL.
+		return
+	}
+	bucket := "minio-bucket"
+	object := "minio-object"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, object, nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	var data []byte
+	var parts []completePart
+	for i := 0; i < 4; i++ {
+		// All parts except the last one need to be at least 5MB.
+		size := 5*1024*1024 + i
+		if i == 3 {
+			size = 100
+		}
+		partData := bytes.Repeat([]byte{byte('a' + i)}, size)
+		md5Sum, err := obj.PutObjectPart(bucket, object, uploadID, i+1, int64(size), bytes.NewReader(partData), "")
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		parts = append(parts, completePart{PartNumber: i + 1, ETag: md5Sum})
+		data = append(data, partData...)
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, object, uploadID, parts); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	defer func(readAhead int) {
+		multipartReadAhead = readAhead
+	}(multipartReadAhead)
+	for _, readAhead := range []int{0, 1, 8} {
+		multipartReadAhead = readAhead
+		reader, err := obj.GetObjectRange(bucket, object, 10, int64(len(data))-20)
+		if err != nil {
+			t.Fatalf("%s: Read ahead %d: %s", instanceType, readAhead, err.Error())
+		}
+		got, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("%s: Read ahead %d: %s", instanceType, readAhead, err.Error())
+		}
+		if !bytes.Equal(got, data[10:len(data)-10]) {
+			t.Fatalf("%s: Read ahead %d: Object data mismatch", instanceType, readAhead)
+		}
+	}
+
+	// Errors of a part read ahead are returned to the reader, parts
+	// are only opened once the previous one is read.
+	multipartReadAhead = 0
+	reader, err := obj.GetObject(bucket, object, 0)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	defer reader.Close()
+	if err = xl.storage.DeleteFile(bucket, pathJoin(object, partNumToPartFileName(3))); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err = ioutil.ReadAll(reader); err == nil {
+		t.Fatalf("%s: Expected reading a missing part to fail", instanceType)
+	}
+
+	// Parts missing before the read fail it up front.
+	if _, err = obj.GetObject(bucket, object, 0); err == nil {
+		t.Fatalf("%s: Expected reading a missing part to fail", instanceType)
+	}
+}
+
+// blockingReadStorage - reads of files ending in block never return,
+// until the reader is closed.
+type blockingReadStorage struct {
+	StorageAPI
+	block  string
+	closed chan struct{}
+}
+
+func (s blockingReadStorage) ReadFile(volume, path string, offset int64) (io.ReadCloser, error) {
+	if !strings.HasSuffix(path, s.block) {
+		return s.StorageAPI.ReadFile(volume, path, offset)
+	}
+	return blockedReader{s.closed}, nil
+}
+
+// blockedReader - blocks reads until closed.
+type blockedReader struct {
+	closed chan struct{}
+}
+
+func (r blockedReader) Read(p []byte) (int, error) {
+	<-r.closed
+	return 0, io.ErrClosedPipe
+}
+
+func (r blockedReader) Close() error {
+	close(r.closed)
+	return nil
+}
+
+// Tests validate closing a reader of a multipart object returns once
+// the parts read ahead are closed, even if a read of a part blocks.
+func TestXLGetObjectReadAheadClose(t *testing.T) {
+	xl, removeDisks := newTestXLWithOptions(t, 8)
+	defer removeDisks()
+	bucket := "minio-bucket"
+	object := "minio-object"
+	if err := xl.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	uploadID, err := xl.NewMultipartUpload(bucket, object, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts []completePart
+	for i, size := range []int{5 * 1024 * 1024, 100} {
+		partData := bytes.Repeat([]byte{byte('a' + i)}, size)
+		md5Sum, err := xl.PutObjectPart(bucket, object, uploadID, i+1, int64(size), bytes.NewReader(partData), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, completePart{PartNumber: i + 1, ETag: md5Sum})
+	}
+	if _, err = xl.CompleteMultipartUpload(bucket, object, uploadID, parts); err != nil {
+		t.Fatal(err)
+	}
+
+	closed := make(chan struct{})
+	xl.storage = blockingReadStorage{xl.storage, partNumToPartFileName(2), closed}
+	// Not shared with other readers.
+	reader, err := xl.GetObjectRange(bucket, object, 0, 5*1024*1024+50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = io.ReadFull(reader, make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+	reader.Close()
+	select {
+	case <-closed:
+	default:
+		t.Fatal("Expected the part read ahead closed once the reader is closed")
+	}
+}
+
+// Wrapper for calling corrupt multipart meta tests for XL multiple disks setup.
+func TestXLCorruptMultipartMeta(t *testing.T) {
+	initNSLock()
+	ExecObjectLayerTest(t, testXLCorruptMultipartMeta)
+}
+
+// Tests validate reads of a multipart object whose meta file was cut
+// short or doesn't add up fail with CorruptMultipartMeta until the meta
+// file is repaired.
+func testXLCorruptMultipartMeta(obj ObjectLayer, instanceType string, t *testing.T) {
+	xl, ok := obj.(xlObjects)
+	if !ok {
+		// Only applicable for XL.
+		return
+	}
+	bucket := "bucket"
+	object := "object"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if _, err := putTestMultipartObject(obj, bucket, object, 2, 5*1024*1024); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	info, err := getMultipartObjectInfo(xl.storage, bucket, object)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	metaData, err := json.Marshal(info)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	writeMeta := func(data []byte) {
+		w, err := xl.storage.CreateFile(bucket, pathJoin(object, multipartMetaFile))
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		if _, err = w.Write(data); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		if err = w.Close(); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+	checkCorrupt := func(name string) {
+		expected := CorruptMultipartMeta{Bucket: bucket, Object: object}
+		if _, err := obj.GetObjectInfo(bucket, object); err != expected {
+			t.Fatalf("%s: %s: Expected GetObjectInfo to fail with %v, got %v", instanceType, name, expected, err)
+		}
+		if _, err := obj.GetObject(bucket, object, 0); err != expected {
+			t.Fatalf("%s: %s: Expected GetObject to fail with %v, got %v", instanceType, name, expected, err)
+		}
+	}
+
+	inconsistent := info
+	inconsistent.Size++
+	inconsistentData, err := json.Marshal(inconsistent)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	writeMeta(inconsistentData)
+	checkCorrupt("inconsistent size")
+	// Recomputing the offsets repairs the size.
+	if _, err = xl.RecomputeMultipartOffsets(bucket, object); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if objInfo, err := obj.GetObjectInfo(bucket, object); err != nil || objInfo.Size != info.Size {
+		t.Fatalf("%s: Expected size %d once repaired, got %d, %v", instanceType, info.Size, objInfo.Size, err)
+	}
+
+	unordered := info
+	unordered.Parts = []MultipartPartInfo{info.Parts[1], info.Parts[0]}
+	unorderedData, err := json.Marshal(unordered)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	writeMeta(unorderedData)
+	checkCorrupt("unordered parts")
+
+	writeMeta([]byte(`{"Parts":[],"Size":0}`))
+	checkCorrupt("no parts")
+
+	writeMeta(metaData[:len(metaData)/2])
+	checkCorrupt("truncated")
+
+	// Neither overwritten nor deleted while its retention is unknown.
+	if err = obj.DeleteObject(bucket, object); err != (CorruptMultipartMeta{Bucket: bucket, Object: object}) {
+		t.Fatalf("%s: Expected DeleteObject to fail with CorruptMultipartMeta, got %v", instanceType, err)
+	}
+	// Reads succeed again once the meta file is restored.
+	writeMeta(metaData)
+	if objInfo, err := obj.GetObjectInfo(bucket, object); err != nil || objInfo.Size != info.Size {
+		t.Fatalf("%s: Expected size %d once restored, got %d, %v", instanceType, info.Size, objInfo.Size, err)
+	}
+}
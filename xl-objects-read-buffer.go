@@ -0,0 +1,112 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// Size of the chunks parts of multipart objects are read in unless
+	// configured otherwise. BenchmarkXLGetObjectReadBufferSize peaks
+	// between 128KiB and 512KiB, larger buffers fall out of the CPU
+	// caches and read slower.
+	defaultReadBufferSize = 128 * 1024
+	// Smallest read buffer size accepted.
+	minReadBufferSize = 4 * 1024
+	// Largest read buffer size accepted, a read holds up to
+	// (multipartReadAhead + 1) * readAheadChunks buffers.
+	maxReadBufferSize = 16 * 1024 * 1024
+)
+
+// readBufferSize - size of the chunks parts are read in by GetObject.
+type readBufferSize struct {
+	mutex *sync.Mutex
+	size  int64
+}
+
+// newReadBufferSize - initialize the read buffer size to the default.
+func newReadBufferSize() *readBufferSize {
+	return &readBufferSize{
+		mutex: &sync.Mutex{},
+		size:  defaultReadBufferSize,
+	}
+}
+
+// get - returns the read buffer size.
+func (b *readBufferSize) get() int64 {
+	// Object layers opened for recovery use the default.
+	if b == nil {
+		return defaultReadBufferSize
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.size
+}
+
+// set - changes the read buffer size, fails with errInvalidArgument
+// if size is out of bounds.
+func (b *readBufferSize) set(size int64) error {
+	if size < minReadBufferSize || size > maxReadBufferSize {
+		return errInvalidArgument
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.size = size
+	return nil
+}
+
+// readBufferSizeKey - context key overriding the read buffer size.
+type readBufferSizeKey struct{}
+
+// WithReadBufferSize - returns a copy of ctx for reads using buffers
+// of size bytes instead of the configured size, larger buffers suit
+// large sequential reads, smaller ones many small reads. The size is
+// clamped to the bounds SetReadBufferSize accepts.
+func WithReadBufferSize(ctx context.Context, size int64) context.Context {
+	return context.WithValue(ctx, readBufferSizeKey{}, size)
+}
+
+// forContext - returns the read buffer size ctx asks for, the
+// configured size if none.
+func (b *readBufferSize) forContext(ctx context.Context) int64 {
+	size, ok := ctx.Value(readBufferSizeKey{}).(int64)
+	if !ok {
+		return b.get()
+	}
+	if size < minReadBufferSize {
+		return minReadBufferSize
+	}
+	if size > maxReadBufferSize {
+		return maxReadBufferSize
+	}
+	return size
+}
+
+// SetReadBufferSize - change the size of the chunks parts of multipart
+// objects are read in, reads already started keep their size. Simple
+// objects are read straight from the backend by the caller.
+func (xl xlObjects) SetReadBufferSize(size int64) error {
+	return xl.readBuffer.set(size)
+}
+
+// ReadBufferSize - size of the chunks parts of multipart objects are
+// read in.
+func (xl xlObjects) ReadBufferSize() int64 {
+	return xl.readBuffer.get()
+}
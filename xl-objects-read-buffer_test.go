@@ -0,0 +1,150 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// putTestMultipartObject - uploads a multipart object of parts of
+// partSize bytes each, returns its data.
+func putTestMultipartObject(obj ObjectLayer, bucket, object string, parts int, partSize int) ([]byte, error) {
+	uploadID, err := obj.NewMultipartUpload(bucket, object, nil)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	var completeParts []completePart
+	for i := 0; i < parts; i++ {
+		partData := bytes.Repeat([]byte{byte('a' + i)}, partSize)
+		md5Sum, err := obj.PutObjectPart(bucket, object, uploadID, i+1, int64(partSize), bytes.NewReader(partData), "")
+		if err != nil {
+			return nil, err
+		}
+		completeParts = append(completeParts, completePart{PartNumber: i + 1, ETag: md5Sum})
+		data = append(data, partData...)
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, object, uploadID, completeParts); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Wrapper for calling read buffer size tests for XL multiple disks setup.
+func TestXLGetObjectReadBufferSize(t *testing.T) {
+	initNSLock()
+	ExecObjectLayerTest(t, testXLGetObjectReadBufferSize)
+}
+
+// Tests validate the read buffer size is bounded, and multipart objects
+// read the same whatever the configured or per-request size.
+func testXLGetObjectReadBufferSize(obj ObjectLayer, instanceType string, t *testing.T) {
+	xl, ok := obj.(xlObjects)
+	if !ok {
+		// Only applicable for XL.
+		return
+	}
+	if size := xl.ReadBufferSize(); size != defaultReadBufferSize {
+		t.Fatalf("%s: Expected default read buffer size %d, got %d", instanceType, defaultReadBufferSize, size)
+	}
+	for _, size := range []int64{0, minReadBufferSize - 1, maxReadBufferSize + 1} {
+		if err := xl.SetReadBufferSize(size); err != errInvalidArgument {
+			t.Fatalf("%s: Expected errInvalidArgument for size %d, got %v", instanceType, size, err)
+		}
+	}
+	bucket := "bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	data, err := putTestMultipartObject(obj, bucket, "object", 2, 5*1024*1024+3)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	readObject := func(ctx context.Context) []byte {
+		reader, err := xl.GetObjectWithContext(ctx, bucket, "object", 7)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		defer reader.Close()
+		got, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+		return got
+	}
+	if err = xl.SetReadBufferSize(minReadBufferSize); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if got := readObject(context.Background()); !bytes.Equal(got, data[7:]) {
+		t.Fatalf("%s: Object data mismatch with the configured read buffer size", instanceType)
+	}
+	// Per-request sizes out of bounds are clamped.
+	for _, size := range []int64{1, 64 * 1024, 1 << 40} {
+		if got := readObject(WithReadBufferSize(context.Background(), size)); !bytes.Equal(got, data[7:]) {
+			t.Fatalf("%s: Object data mismatch with a read buffer size of %d", instanceType, size)
+		}
+	}
+}
+
+// Benchmark reading a 64MiB multipart object on XL at several read
+// buffer sizes.
+func BenchmarkXLGetObjectReadBufferSize(b *testing.B) {
+	initNSLock()
+	var disks []string
+	for i := 0; i < 8; i++ {
+		directory, err := ioutil.TempDir("", "minio-benchmark-readbuffer")
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer os.RemoveAll(directory)
+		disks = append(disks, directory)
+	}
+	obj, err := newXLObjects(disks...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err = obj.MakeBucket("bucket"); err != nil {
+		b.Fatal(err)
+	}
+	data, err := putTestMultipartObject(obj, "bucket", "object", 4, 16*1024*1024)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, size := range []int64{32 * 1024, 128 * 1024, 512 * 1024, 1024 * 1024, 4 * 1024 * 1024, 16 * 1024 * 1024} {
+		b.Run(fmt.Sprintf("%dKiB", size/1024), func(b *testing.B) {
+			ctx := WithReadBufferSize(context.Background(), size)
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				reader, err := obj.GetObjectWithContext(ctx, "bucket", "object", 0)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err = io.Copy(ioutil.Discard, reader); err != nil {
+					b.Fatal(err)
+				}
+				reader.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	slashpath "path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// offlineFilesTestStorage - simulates a disk whose files can't be
+// reached, volumes are still reported.
+type offlineFilesTestStorage struct {
+	StorageAPI
+}
+
+func (offlineFilesTestStorage) ReadFile(volume, path string, offset int64) (io.ReadCloser, error) {
+	return nil, errDiskNotFound
+}
+
+func (offlineFilesTestStorage) StatFile(volume, path string) (FileInfo, error) {
+	return FileInfo{}, errDiskNotFound
+}
+
+// offlineDataTestStorage - simulates a disk whose data parts under
+// prefix of volume can't be read, their metadata still can.
+type offlineDataTestStorage struct {
+	StorageAPI
+	volume string
+	prefix string
+}
+
+func (s offlineDataTestStorage) ReadFile(volume, path string, offset int64) (io.ReadCloser, error) {
+	if volume == s.volume && strings.HasPrefix(path, s.prefix) && slashpath.Base(path) != xlMetaV1File {
+		return nil, errDiskNotFound
+	}
+	return s.StorageAPI.ReadFile(volume, path, offset)
+}
+
+// offlineTestStorage - simulates a disk which is unreachable
+// altogether.
+type offlineTestStorage struct {
+	StorageAPI
+}
+
+func (offlineTestStorage) StatVol(volume string) (VolInfo, error) {
+	return VolInfo{}, errDiskNotFound
+}
+
+// Tests validate reads fail with InsufficientReadQuorum instead of
+// ObjectNotFound once too many disks are offline.
+func TestXLGetObjectReadQuorum(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+	obj, err := newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xl := obj.(xlObjects)
+
+	bucket := "bucket"
+	if err = xl.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("hello, world")
+	if _, err = xl.PutObject(bucket, "simple", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatal(err)
+	}
+	uploadID, err := xl.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md5Sum, err := xl.PutObjectPart(bucket, "multipart", uploadID, 1, int64(len(data)), bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = xl.CompleteMultipartUpload(bucket, "multipart", uploadID, []completePart{{PartNumber: 1, ETag: md5Sum}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Take disks offline, one more than read quorum allows at the end.
+	storage := xl.storage.(*XL)
+	// Background heals read the disks swapped out below.
+	storage.noHeal = true
+	onlineDisks := make([]StorageAPI, len(storage.storageDisks))
+	copy(onlineDisks, storage.storageDisks)
+	defer copy(storage.storageDisks, onlineDisks)
+	for offline := 0; offline <= len(onlineDisks)-storage.readQuorum+1; offline++ {
+		for i := 0; i < offline; i++ {
+			storage.storageDisks[i] = offlineFilesTestStorage{onlineDisks[i]}
+		}
+		hasQuorum := len(onlineDisks)-offline >= storage.readQuorum
+		for _, object := range []string{"simple", "multipart"} {
+			reader, err := xl.GetObject(bucket, object, 0)
+			if !hasQuorum {
+				if _, ok := err.(InsufficientReadQuorum); !ok {
+					t.Fatalf("%d offline disks: %s: Expected InsufficientReadQuorum, got %v", offline, object, err)
+				}
+				if _, err = xl.GetObjectInfo(bucket, object); err == nil {
+					t.Fatalf("%d offline disks: %s: Expected GetObjectInfo to fail", offline, object)
+				} else if _, ok := err.(InsufficientReadQuorum); !ok {
+					t.Fatalf("%d offline disks: %s: Expected InsufficientReadQuorum, got %v", offline, object, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%d offline disks: %s: %s", offline, object, err)
+			}
+			got, err := ioutil.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				t.Fatalf("%d offline disks: %s: %s", offline, object, err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("%d offline disks: %s: Data mismatch", offline, object)
+			}
+		}
+	}
+
+	// Missing objects are still reported missing with all disks online.
+	copy(storage.storageDisks, onlineDisks)
+	if _, err = xl.GetObject(bucket, "missing", 0); err == nil {
+		t.Fatal("Expected ObjectNotFound")
+	} else if _, ok := err.(ObjectNotFound); !ok {
+		t.Fatalf("Expected ObjectNotFound, got %v", err)
+	}
+}
+
+// Tests validate BucketExists answers for existing and missing buckets
+// as long as read quorum is met, fails with InsufficientReadQuorum
+// instead of reporting a bucket missing otherwise.
+func TestXLBucketExistsReadQuorum(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+	obj, err := newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xl := obj.(xlObjects)
+	if err = xl.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = xl.BucketExists("Invalid_Bucket"); err == nil {
+		t.Fatal("Expected BucketNameInvalid")
+	} else if _, ok := err.(BucketNameInvalid); !ok {
+		t.Fatalf("Expected BucketNameInvalid, got %v", err)
+	}
+
+	storage := xl.storage.(*XL)
+	// Background heals read the disks swapped out below.
+	storage.noHeal = true
+	onlineDisks := make([]StorageAPI, len(storage.storageDisks))
+	copy(onlineDisks, storage.storageDisks)
+	defer copy(storage.storageDisks, onlineDisks)
+	for offline := 0; offline <= len(onlineDisks)-storage.readQuorum+1; offline++ {
+		for i := 0; i < offline; i++ {
+			storage.storageDisks[i] = offlineTestStorage{onlineDisks[i]}
+		}
+		online := len(onlineDisks) - offline
+		for bucket, expected := range map[string]bool{"bucket": true, "missing": false} {
+			// A bucket is missing for sure once more disks than read
+			// quorum can spare report it missing.
+			canTell := online >= storage.readQuorum || (!expected && online > len(onlineDisks)-storage.readQuorum)
+			exists, err := xl.BucketExists(bucket)
+			if !canTell {
+				if _, ok := err.(InsufficientReadQuorum); !ok {
+					t.Fatalf("%d offline disks: %s: Expected InsufficientReadQuorum, got %v", offline, bucket, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%d offline disks: %s: %s", offline, bucket, err)
+			}
+			if exists != expected {
+				t.Fatalf("%d offline disks: %s: Expected exists %t, got %t", offline, bucket, expected, exists)
+			}
+		}
+	}
+}
+
+// Tests validate GetObjectWithOptions serves objects one disk short of
+// read quorum only if degraded reads are allowed, zeroing and reporting
+// the bytes which can't be reconstructed.
+func TestXLGetObjectDegradedRead(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+	obj, err := newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xl := obj.(xlObjects)
+
+	bucket := "bucket"
+	if err = xl.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("degraded"), 125)
+	if _, err = xl.PutObject(bucket, "simple", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatal(err)
+	}
+	uploadID, err := xl.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md5Sum, err := xl.PutObjectPart(bucket, "multipart", uploadID, 1, int64(len(data)), bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = xl.CompleteMultipartUpload(bucket, "multipart", uploadID, []completePart{{PartNumber: 1, ETag: md5Sum}}); err != nil {
+		t.Fatal(err)
+	}
+	dataFiles := map[string]string{
+		"simple":    "simple/",
+		"multipart": pathJoin("multipart", partNumToPartFileName(1)) + "/",
+	}
+
+	storage := xl.storage.(*XL)
+	onlineDisks := make([]StorageAPI, len(storage.storageDisks))
+	copy(onlineDisks, storage.storageDisks)
+	defer copy(storage.storageDisks, onlineDisks)
+	// Data shard size of the single block of the objects.
+	shardSize := getEncodedBlockLen(int64(len(data)), storage.DataBlocks)
+
+	// read - reads object from startOffset, returns the data and the
+	// missing ranges.
+	read := func(object string, startOffset int64, opts GetObjectOptions) ([]byte, []ObjectByteRange, error) {
+		reader, err := xl.GetObjectWithOptions(bucket, object, startOffset, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer reader.Close()
+		if _, err = reader.MissingRanges(); err != errRangesIncomplete {
+			t.Fatalf("%s: Expected errRangesIncomplete before EOF, got %v", object, err)
+		}
+		got, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		missing, err := reader.MissingRanges()
+		if err != nil {
+			t.Fatalf("%s: %s", object, err)
+		}
+		return got, missing, nil
+	}
+	expectReadQuorum := func(object string, opts GetObjectOptions, err error) {
+		if _, ok := err.(InsufficientReadQuorum); !ok {
+			t.Fatalf("%s: AllowDegradedRead %t: Expected InsufficientReadQuorum, got %v", object, opts.AllowDegradedRead, err)
+		}
+	}
+	degraded := GetObjectOptions{AllowDegradedRead: true}
+
+	for object, prefix := range dataFiles {
+		// Too few data parts are readable while the metadata has read
+		// quorum, reads fail up front unless degraded.
+		for i := 0; i <= storage.ParityBlocks; i++ {
+			storage.storageDisks[i] = offlineDataTestStorage{onlineDisks[i], bucket, prefix}
+		}
+		_, _, err = read(object, 0, GetObjectOptions{})
+		expectReadQuorum(object, GetObjectOptions{}, err)
+		copy(storage.storageDisks, onlineDisks)
+
+		// One disk short of read quorum, the object is still whole.
+		for i := storage.readQuorum - 1; i < len(onlineDisks); i++ {
+			storage.storageDisks[i] = offlineFilesTestStorage{onlineDisks[i]}
+		}
+		_, _, err = read(object, 0, GetObjectOptions{})
+		expectReadQuorum(object, GetObjectOptions{}, err)
+		got, missing, err := read(object, 0, degraded)
+		if err != nil {
+			t.Fatalf("%s: %s", object, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("%s: Data mismatch", object)
+		}
+		if len(missing) != 0 {
+			t.Fatalf("%s: Expected no missing ranges, got %v", object, missing)
+		}
+
+		// The first data block can't be reconstructed anymore.
+		storage.storageDisks[0] = offlineDataTestStorage{onlineDisks[0], bucket, prefix}
+		for _, startOffset := range []int64{0, shardSize / 2, shardSize} {
+			got, missing, err = read(object, startOffset, degraded)
+			if err != nil {
+				t.Fatalf("%s: Offset %d: %s", object, startOffset, err)
+			}
+			if int64(len(got)) != int64(len(data))-startOffset {
+				t.Fatalf("%s: Offset %d: Expected %d bytes, got %d", object, startOffset, int64(len(data))-startOffset, len(got))
+			}
+			var expected []ObjectByteRange
+			if startOffset < shardSize {
+				expected = []ObjectByteRange{{Offset: startOffset, Length: shardSize - startOffset}}
+			}
+			if !reflect.DeepEqual(missing, expected) {
+				t.Fatalf("%s: Offset %d: Expected missing ranges %v, got %v", object, startOffset, expected, missing)
+			}
+			for i := range got {
+				offset := startOffset + int64(i)
+				if offset < shardSize && got[i] != 0 {
+					t.Fatalf("%s: Offset %d: Expected missing byte %d to read zero", object, startOffset, offset)
+				}
+				if offset >= shardSize && got[i] != data[offset] {
+					t.Fatalf("%s: Offset %d: Data mismatch at byte %d", object, startOffset, offset)
+				}
+			}
+		}
+
+		// Two disks short of read quorum fail either way.
+		storage.storageDisks[0] = offlineFilesTestStorage{onlineDisks[0]}
+		for _, opts := range []GetObjectOptions{{}, degraded} {
+			_, _, err = read(object, 0, opts)
+			expectReadQuorum(object, opts, err)
+		}
+		copy(storage.storageDisks, onlineDisks)
+	}
+}
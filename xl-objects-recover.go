@@ -0,0 +1,91 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"io"
+
+	"github.com/klauspost/reedsolomon"
+)
+
+// XLLayout - erasure layout of a set of XL disks, supplied by hand to
+// recover objects when format.json is lost.
+type XLLayout struct {
+	// Disks - export paths in the order the disks were formatted in,
+	// disks which are gone are listed by their former path.
+	Disks        []string
+	DataBlocks   int
+	ParityBlocks int
+}
+
+// newXLWithLayout - instantiate XL over disks with an explicit layout
+// for recovery, independent of format.json. Reads succeed as long as
+// enough shards remain to decode them and nothing is ever healed.
+func newXLWithLayout(layout XLLayout) (XL, error) {
+	if layout.DataBlocks <= 0 || layout.ParityBlocks <= 0 {
+		return XL{}, errInvalidArgument
+	}
+	if len(layout.Disks) != layout.DataBlocks+layout.ParityBlocks {
+		return XL{}, errInvalidArgument
+	}
+	rs, err := reedsolomon.New(layout.DataBlocks, layout.ParityBlocks)
+	if err != nil {
+		return XL{}, err
+	}
+	storageDisks := make([]StorageAPI, len(layout.Disks))
+	for index, disk := range layout.Disks {
+		// Missing disks are expected, reads treat their shards as
+		// lost.
+		storageDisks[index], err = newPosix(disk)
+		if err != nil && err != errDiskNotFound {
+			return XL{}, err
+		}
+	}
+	return XL{
+		ReedSolomon:  rs,
+		DataBlocks:   layout.DataBlocks,
+		ParityBlocks: layout.ParityBlocks,
+		storageDisks: storageDisks,
+		// Any DataBlocks shards are enough to decode.
+		readQuorum: layout.DataBlocks,
+		// Never written to.
+		writeQuorum: len(storageDisks) + 1,
+		recovery:    true,
+	}, nil
+}
+
+// ReconstructObjectRaw - last resort recovery of an object from the raw
+// shards on disks whose format.json is lost, with the data/parity ratio
+// and disk order given by layout. Reconstructs from whatever shards are
+// present, the disks aren't modified.
+func ReconstructObjectRaw(bucket, object string, layout XLLayout) (io.ReadCloser, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, BucketNameInvalid{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return nil, ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	storage, err := newXLWithLayout(layout)
+	if err != nil {
+		return nil, err
+	}
+	xl := xlObjects{storage: storage}
+	return xl.getObject(context.Background(), bucket, object, 0, -1)
+}
@@ -0,0 +1,153 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests validate simple and multipart objects are reconstructed from
+// the raw shards once format.json is gone, with up to ParityBlocks
+// disks lost, and that the disks are left as they are.
+func TestReconstructObjectRaw(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+	obj, err := newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket := "bucket"
+	if err = obj.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	simpleData := []byte("hello, world")
+	if _, err = obj.PutObject(bucket, "simple", int64(len(simpleData)), bytes.NewReader(simpleData), nil); err != nil {
+		t.Fatal(err)
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var multipartData []byte
+	var parts []completePart
+	for i, size := range []int{5 * 1024 * 1024, 17} {
+		data := bytes.Repeat([]byte{byte('a' + i)}, size)
+		var partMD5 string
+		partMD5, err = obj.PutObjectPart(bucket, "multipart", uploadID, i+1, int64(size), bytes.NewReader(data), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, completePart{PartNumber: i + 1, ETag: partMD5})
+		multipartData = append(multipartData, data...)
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, "multipart", uploadID, parts); err != nil {
+		t.Fatal(err)
+	}
+	if err = obj.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lose format.json on every disk, and ParityBlocks disks entirely.
+	for _, disk := range erasureDisks {
+		if err = os.RemoveAll(filepath.Join(disk, minioMetaBucket, formatConfigFile)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, disk := range erasureDisks[:8] {
+		if err = os.RemoveAll(disk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	layout := XLLayout{Disks: erasureDisks, DataBlocks: 8, ParityBlocks: 8}
+
+	testCases := []struct {
+		object string
+		data   []byte
+	}{
+		{"simple", simpleData},
+		{"multipart", multipartData},
+	}
+	for i, testCase := range testCases {
+		reader, err := ReconstructObjectRaw(bucket, testCase.object, layout)
+		if err != nil {
+			t.Fatalf("Test %d: %s", i+1, err)
+		}
+		data, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("Test %d: %s", i+1, err)
+		}
+		if !bytes.Equal(data, testCase.data) {
+			t.Fatalf("Test %d: Content mismatch", i+1)
+		}
+	}
+	// Nothing is healed onto the lost disks.
+	for _, disk := range erasureDisks[:8] {
+		if _, err = os.Stat(disk); !os.IsNotExist(err) {
+			t.Fatalf("Expected lost disk %s to remain lost, got %v", disk, err)
+		}
+	}
+
+	if _, err = ReconstructObjectRaw(bucket, "missing", layout); err == nil {
+		t.Fatal("Expected an error for a missing object")
+	} else if _, ok := err.(ObjectNotFound); !ok {
+		t.Fatalf("Expected ObjectNotFound, got %v", err)
+	}
+	if _, err = ReconstructObjectRaw("Invalid_Bucket", "simple", layout); err == nil {
+		t.Fatal("Expected BucketNameInvalid")
+	} else if _, ok := err.(BucketNameInvalid); !ok {
+		t.Fatalf("Expected BucketNameInvalid, got %v", err)
+	}
+
+	// Layouts not matching the disks are refused.
+	invalidLayouts := []XLLayout{
+		{Disks: erasureDisks, DataBlocks: 0, ParityBlocks: 16},
+		{Disks: erasureDisks[:15], DataBlocks: 8, ParityBlocks: 8},
+		{Disks: erasureDisks, DataBlocks: 12, ParityBlocks: 4},
+	}
+	for i, invalidLayout := range invalidLayouts {
+		if _, err = ReconstructObjectRaw(bucket, "simple", invalidLayout); err == nil {
+			t.Errorf("Test %d: Expected an error for layout %d/%d over %d disks", i+1, invalidLayout.DataBlocks, invalidLayout.ParityBlocks, len(invalidLayout.Disks))
+		}
+	}
+
+	// One more lost disk is too many to decode.
+	if err = os.RemoveAll(erasureDisks[8]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ReconstructObjectRaw(bucket, "simple", layout); err == nil {
+		t.Fatal("Expected an error with more than ParityBlocks disks lost")
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/skyrings/skyring-common/tools/uuid"
+)
+
+const (
+	// Objects being replaced are moved here under tmp until the new
+	// object is in place.
+	trashDir = "trash"
+	// Prefix of the locks serializing replacements of an object.
+	replaceLockPrefix = "replace"
+)
+
+// lookupObject - runs lookup of object holding off replacements of
+// the object, so that lookup finds the data and the metadata of the
+// same object throughout.
+func lookupObject(bucket, object string, lookup func() error) error {
+	lockPath := path.Join(replaceLockPrefix, bucket, object)
+	nsMutex.RLock(minioMetaBucket, lockPath)
+	defer nsMutex.RUnlock(minioMetaBucket, lockPath)
+	return lookup()
+}
+
+// isObjectNotFoundErr - returns if err is a storage or object layer
+// error for a missing object.
+func isObjectNotFoundErr(err error) bool {
+	if err == errFileNotFound {
+		return true
+	}
+	_, ok := err.(ObjectNotFound)
+	return ok
+}
+
+// replaceObject - renames srcVolume/srcPath in place of an object,
+// along with the metadata staged at srcMeta in minioMetaBucket unless
+// srcMeta is empty. An existing object and its metadata are renamed
+// aside to the trash first and deleted only once the new object is in
+// place, they are restored if the rename fails. In a versioned bucket
+// the existing object is retained as a version instead of being
+// deleted, retained reports if it was.
+func (xl xlObjects) replaceObject(srcVolume, srcPath, srcMeta, bucket, object string, versioned bool) (retained bool, err error) {
+	lockPath := path.Join(replaceLockPrefix, bucket, object)
+	nsMutex.Lock(minioMetaBucket, lockPath)
+	defer nsMutex.Unlock(minioMetaBucket, lockPath)
+
+	// Readers arriving from now on must not share in progress reads
+	// of the old object.
+	xl.readCoalescer.forget(fmt.Sprintf("%s/%s@", bucket, object))
+	defer xl.multipartCache.forget(bucket, object)
+
+	trashID, err := uuid.New()
+	if err != nil {
+		return false, err
+	}
+	var trashPath, metaTrashPath string
+	var version *retainedVersion
+	restore := func() {
+		if metaTrashPath != "" {
+			if rerr := xl.storage.RenameFile(minioMetaBucket, metaTrashPath, minioMetaBucket, objectMetaSidecar(bucket, object)); rerr != nil {
+				xl.log().Errorf("Unable to restore metadata of %s/%s from %s: %s", bucket, object, metaTrashPath, rerr)
+			}
+		}
+		if trashPath != "" {
+			if rerr := xl.storage.RenameFile(minioMetaBucket, trashPath, bucket, object); rerr != nil {
+				xl.log().Errorf("Unable to restore %s/%s from %s: %s", bucket, object, trashPath, rerr)
+			}
+		}
+		if version != nil {
+			if rerr := version.restore(); rerr != nil {
+				xl.log().Errorf("Unable to restore %s/%s from its version %s: %s", bucket, object, version.info.VersionID, rerr)
+			}
+		}
+	}
+	if objInfo, err := xl.getObjectInfo(bucket, object); err == nil {
+		if versioned {
+			if version, err = retainObjectVersion(xl.storage, bucket, object, objInfo, false); err != nil {
+				return false, err
+			}
+		} else {
+			trashPath = path.Join(tmpMetaPrefix, trashDir, trashID.String())
+			if err = xl.storage.RenameFile(bucket, object, minioMetaBucket, trashPath); err != nil {
+				return false, err
+			}
+		}
+	} else if err != errFileNotFound {
+		return false, err
+	}
+	if srcMeta != "" {
+		// Metadata of the existing object, if any, goes to the trash
+		// as well, a retained version has a copy of its own.
+		sidecar := objectMetaSidecar(bucket, object)
+		if _, err = xl.storage.StatFile(minioMetaBucket, sidecar); err == nil {
+			metaTrashPath = path.Join(tmpMetaPrefix, trashDir, trashID.String()+objectMetaSuffix)
+			if err = xl.storage.RenameFile(minioMetaBucket, sidecar, minioMetaBucket, metaTrashPath); err != nil {
+				metaTrashPath = ""
+			}
+		} else if err == errFileNotFound {
+			err = nil
+		}
+		if err != nil {
+			restore()
+			return false, err
+		}
+	}
+	if err = xl.storage.RenameFile(srcVolume, srcPath, bucket, object); err != nil {
+		restore()
+		return false, err
+	}
+	if srcMeta != "" {
+		if err = xl.storage.RenameFile(minioMetaBucket, srcMeta, minioMetaBucket, objectMetaSidecar(bucket, object)); err != nil {
+			if rerr := xl.storage.RenameFile(bucket, object, srcVolume, srcPath); rerr != nil {
+				xl.log().Errorf("Unable to move %s/%s back to %s: %s", bucket, object, srcPath, rerr)
+			}
+			restore()
+			return false, err
+		}
+	}
+	// The new object is in place, a failure only leaves garbage in
+	// tmp which is cleaned up on restart.
+	if metaTrashPath != "" {
+		if err = xl.storage.DeleteFile(minioMetaBucket, metaTrashPath); err != nil {
+			xl.log().Errorf("Unable to delete %s of the replaced %s/%s: %s", metaTrashPath, bucket, object, err)
+		}
+	}
+	if version != nil {
+		return true, nil
+	}
+	if trashPath != "" {
+		if _, err := xl.deleteObject(minioMetaBucket, trashPath, false); err != nil {
+			xl.log().Errorf("Unable to delete %s of the replaced %s/%s: %s", trashPath, bucket, object, err)
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,159 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+// VerifyResult - outcome of VerifyObject.
+type VerifyResult struct {
+	Bucket string
+	Object string
+	// Set if the object was uploaded in parts.
+	Multipart bool
+	// Set if all the data read back matches its saved checksums.
+	Intact bool
+	// Numbers of the parts not matching their ETag or checksum, or
+	// which couldn't be read back.
+	FailedParts []int
+	// Number of files of the object below full redundancy, the data
+	// is still readable from read quorum.
+	MissingParts int
+	// Set if every file of the object is on all disks.
+	QuorumHealthy bool
+}
+
+// isVerifyReadError - errors reading back the data of an object which
+// are reported as damage rather than failing the verification.
+func isVerifyReadError(err error) bool {
+	switch err.(type) {
+	case InsufficientReadQuorum, BucketNotFound, ObjectNotFound:
+		return false
+	}
+	// Data keys which can't be unsealed say nothing about the data.
+	return err != errReadQuorum && err != errFileNotFound && err != errKeySourceNotSet && err != errKeyNotFound
+}
+
+// hashData - streams r through md5Hasher and, if not nil, through
+// checksumHasher. Returns the number of bytes read.
+func hashData(r io.Reader, md5Hasher, checksumHasher hash.Hash) (int64, error) {
+	var w io.Writer = md5Hasher
+	if checksumHasher != nil {
+		w = io.MultiWriter(md5Hasher, checksumHasher)
+	}
+	return io.Copy(w, r)
+}
+
+// VerifyObject - reads back all the data of an object and compares it
+// against its saved MD5Sum, or the ETag and checksum of each part for
+// multipart objects. Nothing is modified, objects found damaged are
+// reported as not Intact rather than failing. Fails with
+// InsufficientReadQuorum if the object can't be read at all.
+func (xl xlObjects) VerifyObject(bucket, object string) (VerifyResult, error) {
+	// Reading the object back would heal it otherwise.
+	if storage, ok := xl.storage.(fileHealer); ok {
+		xl.storage = storage.withoutHeal()
+	}
+	objInfo, err := xl.GetObjectInfo(bucket, object)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	result := VerifyResult{Bucket: bucket, Object: object}
+	if result.MissingParts, err = xl.objectMissingParts(bucket, object); err != nil {
+		return VerifyResult{}, toObjectErr(err, bucket, object)
+	}
+	result.QuorumHealthy = result.MissingParts == 0
+
+	if objInfo.Parts == nil {
+		// Read through the object layer, the saved MD5Sum is the one
+		// of the content as uploaded.
+		var reader io.ReadCloser
+		reader, err = xl.GetObject(bucket, object, 0)
+		if err != nil {
+			return VerifyResult{}, err
+		}
+		defer reader.Close()
+		md5Hasher := md5.New()
+		if _, err = hashData(reader, md5Hasher, nil); err != nil {
+			if !isVerifyReadError(err) {
+				return VerifyResult{}, toObjectErr(err, bucket, object)
+			}
+			return result, nil
+		}
+		// Objects saved without an MD5Sum are only checked to be
+		// readable.
+		result.Intact = objInfo.MD5Sum == "" || hex.EncodeToString(md5Hasher.Sum(nil)) == objInfo.MD5Sum
+		return result, nil
+	}
+
+	result.Multipart = true
+	info, err := getMultipartObjectInfo(xl.storage, bucket, object)
+	if err != nil {
+		return VerifyResult{}, toObjectErr(err, bucket, object)
+	}
+	var parts []completePart
+	for _, part := range info.Parts {
+		intact, err := xl.verifyPart(bucket, object, part)
+		if err != nil {
+			return VerifyResult{}, toObjectErr(err, bucket, object)
+		}
+		if !intact {
+			result.FailedParts = append(result.FailedParts, part.PartNumber)
+		}
+		parts = append(parts, completePart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+	// The parts listed must also add up to the object ETag.
+	md5Sum, err := completeMultipartMD5(parts...)
+	result.Intact = len(result.FailedParts) == 0 && err == nil && md5Sum == info.MD5Sum
+	return result, nil
+}
+
+// verifyPart - reads back a part of a multipart object, reports if it
+// matches its ETag and checksum.
+func (xl xlObjects) verifyPart(bucket, object string, part MultipartPartInfo) (bool, error) {
+	reader, err := openPart(func(offset int64) (io.ReadCloser, error) {
+		return xl.storage.ReadFile(bucket, pathJoin(object, partNumToPartFileName(part.PartNumber)), offset)
+	}, part, 0)
+	if err != nil {
+		if isVerifyReadError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer reader.Close()
+	md5Hasher := md5.New()
+	var checksumHasher hash.Hash
+	if part.Checksum != "" {
+		checksumHasher = sha256.New()
+	}
+	n, err := hashData(reader, md5Hasher, checksumHasher)
+	if err != nil {
+		if isVerifyReadError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if n != part.Size || hex.EncodeToString(md5Hasher.Sum(nil)) != part.ETag {
+		return false, nil
+	}
+	return checksumHasher == nil || hex.EncodeToString(checksumHasher.Sum(nil)) == part.Checksum, nil
+}
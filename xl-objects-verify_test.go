@@ -0,0 +1,151 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Tests validate VerifyObject reports intact objects, objects below
+// full redundancy without healing them, and objects whose data no
+// longer matches its checksums.
+func TestXLVerifyObject(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+	obj, err := newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xl := obj.(xlObjects)
+
+	bucket := "bucket"
+	if err = xl.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	uploadID, err := xl.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partSizes := []int{5 * 1024 * 1024, 17}
+	var parts []completePart
+	for i, size := range partSizes {
+		partData := bytes.Repeat([]byte{byte('a' + i)}, size)
+		var md5Sum string
+		md5Sum, err = xl.PutObjectPart(bucket, "multipart", uploadID, i+1, int64(size), bytes.NewReader(partData), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, completePart{PartNumber: i + 1, ETag: md5Sum})
+	}
+	if _, err = xl.CompleteMultipartUpload(bucket, "multipart", uploadID, parts); err != nil {
+		t.Fatal(err)
+	}
+	simpleData := []byte("hello, world")
+	if _, err = xl.PutObject(bucket, "simple", int64(len(simpleData)), bytes.NewReader(simpleData), nil); err != nil {
+		t.Fatal(err)
+	}
+	compressedData := bytes.Repeat(simpleData, 10000)
+	if _, err = xl.PutObject(bucket, "compressed", int64(len(compressedData)), bytes.NewReader(compressedData), map[string]string{compressKey: "true"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, object := range []string{"simple", "compressed", "multipart"} {
+		result, err := xl.VerifyObject(bucket, object)
+		if err != nil {
+			t.Fatalf("%s: %s", object, err)
+		}
+		expected := VerifyResult{
+			Bucket:        bucket,
+			Object:        object,
+			Multipart:     object == "multipart",
+			Intact:        true,
+			QuorumHealthy: true,
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Fatalf("%s: Expected %+v, got %+v", object, expected, result)
+		}
+	}
+
+	// Lost on one disk, still intact and left as is.
+	lostFile := filepath.Join(erasureDisks[4], bucket, "multipart", partNumToPartFileName(2))
+	if err = os.RemoveAll(lostFile); err != nil {
+		t.Fatal(err)
+	}
+	result, err := xl.VerifyObject(bucket, "multipart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Intact || result.QuorumHealthy || result.MissingParts != 1 {
+		t.Fatalf("Expected an intact object missing one file, got %+v", result)
+	}
+	if _, err = os.Stat(lostFile); !os.IsNotExist(err) {
+		t.Fatalf("Expected %s not to be healed, %v", lostFile, err)
+	}
+
+	// Replace the data behind the object layer's back.
+	for _, file := range []string{"simple", pathJoin("multipart", partNumToPartFileName(2))} {
+		w, err := xl.storage.CreateFile(bucket, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write(bytes.Repeat([]byte("x"), 17)); err != nil {
+			t.Fatal(err)
+		}
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result, err = xl.VerifyObject(bucket, "multipart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Intact || !reflect.DeepEqual(result.FailedParts, []int{2}) {
+		t.Fatalf("Expected part 2 to fail, got %+v", result)
+	}
+	result, err = xl.VerifyObject(bucket, "simple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Intact {
+		t.Fatalf("Expected a damaged object, got %+v", result)
+	}
+
+	if _, err = xl.VerifyObject(bucket, "missing"); err == nil {
+		t.Fatal("Expected ObjectNotFound")
+	} else if _, ok := err.(ObjectNotFound); !ok {
+		t.Fatalf("Expected ObjectNotFound, got %v", err)
+	}
+}
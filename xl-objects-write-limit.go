@@ -0,0 +1,84 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Object writes allowed in flight per disk by default, each write
+// streams to every disk.
+const defaultWritesPerDisk = 2
+
+// writeLimiter - bounds the number of object writes in flight. Writes
+// over the limit wait for one to finish, unless maxQueued of them are
+// already waiting.
+type writeLimiter struct {
+	slots chan struct{}
+	// Zero lets any number of writes wait.
+	maxQueued int64
+	queued    int64 // Accessed atomically.
+}
+
+// newWriteLimiter - limiter of maxWrites writes in flight, nil for no
+// limit if maxWrites isn't positive.
+func newWriteLimiter(maxWrites, maxQueued int) *writeLimiter {
+	if maxWrites <= 0 {
+		return nil
+	}
+	if maxQueued < 0 {
+		maxQueued = 0
+	}
+	return &writeLimiter{
+		slots:     make(chan struct{}, maxWrites),
+		maxQueued: int64(maxQueued),
+	}
+}
+
+// acquire - waits for a write slot, fails with SlowDown right away if
+// the queue is full or with ctx.Err() once ctx is done. Every
+// successful acquire must be followed by a release.
+func (l *writeLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if queued := atomic.AddInt64(&l.queued, 1); l.maxQueued > 0 && queued > l.maxQueued {
+		atomic.AddInt64(&l.queued, -1)
+		return SlowDown{}
+	}
+	defer atomic.AddInt64(&l.queued, -1)
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release - frees the write slot taken by acquire.
+func (l *writeLimiter) release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
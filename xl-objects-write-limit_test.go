@@ -0,0 +1,216 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestXLWithOptions - XL object layer over numDisks temporary
+// disks, along with a function removing them.
+func newTestXLWithOptions(tb testing.TB, numDisks int, opts ...XLOption) (xlObjects, func()) {
+	initNSLock()
+	var disks []string
+	removeDisks := func() {
+		for _, disk := range disks {
+			os.RemoveAll(disk)
+		}
+	}
+	for i := 0; i < numDisks; i++ {
+		disk, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			removeDisks()
+			tb.Fatal(err)
+		}
+		disks = append(disks, disk)
+	}
+	obj, err := newXLObjectsWithOptions(0, 0, disks, opts...)
+	if err != nil {
+		removeDisks()
+		tb.Fatal(err)
+	}
+	return obj.(xlObjects), removeDisks
+}
+
+// Tests validate that writes over the limit wait for a slot, and fail
+// with SlowDown once the queue is full.
+func TestXLWriteLimit(t *testing.T) {
+	xl, removeDisks := newTestXLWithOptions(t, 8, WithWriteLimit(1, 1))
+	defer removeDisks()
+	if err := xl.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Holds the only write slot until data is written to the pipe.
+	pipeReader, pipeWriter := io.Pipe()
+	errs := make(chan error, 2)
+	go func() {
+		_, err := xl.PutObject("bucket", "first", 4, pipeReader, nil)
+		errs <- err
+	}()
+	for len(xl.writeLimiter.slots) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	go func() {
+		_, err := xl.PutObject("bucket", "second", 4, bytes.NewBufferString("data"), nil)
+		errs <- err
+	}()
+	for atomic.LoadInt64(&xl.writeLimiter.queued) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The queue is full.
+	if _, err := xl.PutObject("bucket", "third", 4, bytes.NewBufferString("data"), nil); err != (SlowDown{}) {
+		t.Fatalf("Expected %v, got %v", SlowDown{}, err)
+	}
+	if _, err := pipeWriter.Write([]byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	pipeWriter.Close()
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(xl.writeLimiter.slots) != 0 || atomic.LoadInt64(&xl.writeLimiter.queued) != 0 {
+		t.Fatal("Expected all write slots to be released")
+	}
+	for _, object := range []string{"first", "second"} {
+		if _, err := xl.GetObjectInfo("bucket", object); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Tests validate the writeLimiter defaults and its context handling.
+func TestWriteLimiter(t *testing.T) {
+	// Defaults relative to the number of disks.
+	xl, removeDisks := newTestXLWithOptions(t, 8)
+	defer removeDisks()
+	if cap(xl.writeLimiter.slots) != defaultWritesPerDisk*8 {
+		t.Fatalf("Expected %d write slots, got %d", defaultWritesPerDisk*8, cap(xl.writeLimiter.slots))
+	}
+	// No limit.
+	var limiter *writeLimiter
+	if limiter = newWriteLimiter(-1, 0); limiter != nil {
+		t.Fatal("Expected no limiter for a negative limit")
+	}
+	if err := limiter.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	limiter.release()
+
+	// Without a queue cap, waiting writes end with their context.
+	limiter = newWriteLimiter(1, 0)
+	if err := limiter.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	limiter.release()
+	if err := limiter.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	limiter.release()
+}
+
+// benchmarkXLWriteOverload - reads a small object while concurrent
+// PutObject calls flood the disks, reports the median and tail latency
+// of the reads.
+func benchmarkXLWriteOverload(b *testing.B, opts ...XLOption) {
+	xl, removeDisks := newTestXLWithOptions(b, 8, opts...)
+	defer removeDisks()
+	if err := xl.MakeBucket("bucket"); err != nil {
+		b.Fatal(err)
+	}
+	if _, err := xl.PutObject("bucket", "small", 4, bytes.NewBufferString("data"), nil); err != nil {
+		b.Fatal(err)
+	}
+
+	data := bytes.Repeat([]byte("a"), 256*1024)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			object := "object" + strconv.Itoa(i)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, err := xl.PutObject("bucket", object, int64(len(data)), bytes.NewReader(data), nil); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+
+	latencies := make([]time.Duration, b.N)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		reader, err := xl.GetObject("bucket", "small", 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err = io.Copy(ioutil.Discard, reader); err != nil {
+			b.Fatal(err)
+		}
+		reader.Close()
+		latencies[i] = time.Since(start)
+	}
+	b.StopTimer()
+	close(done)
+	wg.Wait()
+
+	sort.Sort(byDuration(latencies))
+	b.ReportMetric(float64(latencies[len(latencies)/2].Microseconds())/1000, "p50-ms")
+	b.ReportMetric(float64(latencies[len(latencies)*99/100].Microseconds())/1000, "p99-ms")
+}
+
+type byDuration []time.Duration
+
+func (d byDuration) Len() int           { return len(d) }
+func (d byDuration) Less(i, j int) bool { return d[i] < d[j] }
+func (d byDuration) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// Benchmark reads under a write overload without a write limit.
+func BenchmarkXLWriteOverloadUnlimited(b *testing.B) {
+	benchmarkXLWriteOverload(b, WithWriteLimit(-1, 0))
+}
+
+// Benchmark reads under a write overload with the default write limit.
+func BenchmarkXLWriteOverloadLimited(b *testing.B) {
+	benchmarkXLWriteOverload(b)
+}
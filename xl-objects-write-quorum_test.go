@@ -0,0 +1,84 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Tests validate CanWrite and PutObject fail once too many disks are
+// offline to reach write quorum, even though read quorum is still met.
+func TestXLCanWrite(t *testing.T) {
+	// Initialize name space lock.
+	initNSLock()
+
+	var erasureDisks []string
+	for i := 0; i < 16; i++ {
+		path, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		erasureDisks = append(erasureDisks, path)
+	}
+	defer func() {
+		for _, disk := range erasureDisks {
+			os.RemoveAll(disk)
+		}
+	}()
+	obj, err := newXLObjects(erasureDisks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xl := obj.(xlObjects)
+	if err = xl.MakeBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	storage := xl.storage.(*XL)
+	onlineDisks := make([]StorageAPI, len(storage.storageDisks))
+	copy(onlineDisks, storage.storageDisks)
+	defer copy(storage.storageDisks, onlineDisks)
+	data := []byte("hello, world")
+	for offline := 0; offline <= len(onlineDisks)-storage.writeQuorum+1; offline++ {
+		for i := 0; i < offline; i++ {
+			storage.storageDisks[i] = offlineTestStorage{onlineDisks[i]}
+		}
+		expected := len(onlineDisks)-offline >= storage.writeQuorum
+		ok, err := xl.CanWrite()
+		if err != nil {
+			t.Fatalf("%d offline disks: %s", offline, err)
+		}
+		if ok != expected {
+			t.Fatalf("%d offline disks: Expected CanWrite %t, got %t", offline, expected, ok)
+		}
+		_, err = xl.PutObject("bucket", "object", int64(len(data)), bytes.NewReader(data), nil)
+		if expected && err != nil {
+			t.Fatalf("%d offline disks: %s", offline, err)
+		}
+		if !expected {
+			if _, ok := err.(InsufficientWriteQuorum); !ok {
+				t.Fatalf("%d offline disks: Expected InsufficientWriteQuorum, got %v", offline, err)
+			}
+			if len(onlineDisks)-offline < storage.readQuorum {
+				t.Fatalf("%d offline disks: Expected read quorum to be met", offline)
+			}
+		}
+	}
+}
@@ -17,18 +17,21 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"io/ioutil"
 	"path"
-	"path/filepath"
-	"strings"
 	"sync"
+	"time"
 
-	"github.com/minio/minio/pkg/mimedb"
+	"github.com/skyrings/skyring-common/tools/uuid"
 )
 
 const (
@@ -42,38 +45,155 @@ type xlObjects struct {
 	storage            StorageAPI
 	listObjectMap      map[listParams][]*treeWalker
 	listObjectMapMutex *sync.Mutex
+	listCursors        *listCursors
+	readCoalescer      *readCoalescer
+	replicator         *replicator
+	bandwidth          *bandwidthAccounting
+	bucketStats        *bucketStatsTracker
+	scheduler          *priorityScheduler
+	multipartCache     *multipartCache
+	bucketCache        *bucketCache
+	// Priority storage access of this layer is scheduled at.
+	priority RequestPriority
+	// Overwrites and deletes override governance retention.
+	bypassGovernance bool
+	partSizes        *partSizeLimits
+	// Largest object PutObject accepts.
+	sizeLimit *objectSizeLimit
+	// Most keys a single listing returns.
+	listLimit *listKeysLimit
+	// Content types of object extensions overriding mimedb.
+	contentTypes *contentTypes
+	// Regions buckets can be made in.
+	regions *bucketRegions
+	// Stops the background goroutines of the layer.
+	shutdown *shutdownSignal
+	// Size of the chunks parts of multipart objects are read in.
+	readBuffer *readBufferSize
+	// Errors are reported here, the package-global log if nil.
+	logger Logger
+	// Bounds object writes in flight, no limit if nil.
+	writeLimiter *writeLimiter
+	// Stats of object operations are reported here.
+	metrics ObjectMetrics
+	// Most parts of a multipart object deleted in parallel.
+	deletePartsConcurrency int
 }
 
-// isValidFormat - validates input arguments with backend 'format.json'
-func isValidFormat(storage StorageAPI, exportPaths ...string) bool {
+// xlOptions - optional settings of an XL object layer.
+type xlOptions struct {
+	logger  Logger
+	metrics ObjectMetrics
+	// Zero maxWrites defaults relative to the number of disks.
+	maxWrites, maxQueuedWrites int
+	// Zero defaults to defaultDeletePartsConcurrency.
+	deletePartsConcurrency int
+}
+
+// XLOption - sets an optional setting of an XL object layer at
+// construction.
+type XLOption func(*xlOptions)
+
+// WithLogger - report errors of the XL object layer, including those
+// of its initialization, to logger instead of the package-global log.
+func WithLogger(logger Logger) XLOption {
+	return func(opts *xlOptions) {
+		opts.logger = logger
+	}
+}
+
+// WithWriteLimit - allow at most maxWrites object writes in flight,
+// further writes wait for one to finish. Once maxQueued writes are
+// waiting, PutObject fails with SlowDown instead, zero maxQueued never
+// does. A negative maxWrites removes the limit.
+func WithWriteLimit(maxWrites, maxQueued int) XLOption {
+	return func(opts *xlOptions) {
+		opts.maxWrites = maxWrites
+		opts.maxQueuedWrites = maxQueued
+	}
+}
+
+// WithDeleteConcurrency - delete at most n parts of a multipart object
+// in parallel, zero keeps the default.
+func WithDeleteConcurrency(n int) XLOption {
+	return func(opts *xlOptions) {
+		opts.deletePartsConcurrency = n
+	}
+}
+
+// log - logger errors of the layer are reported to.
+func (xl xlObjects) log() Logger {
+	if xl.logger == nil {
+		return log
+	}
+	return xl.logger
+}
+
+// isValidFormat - validates input arguments with backend 'format.json',
+// zero dataBlocks and parityBlocks accept any recorded erasure split.
+func isValidFormat(logger Logger, storage StorageAPI, dataBlocks, parityBlocks int, exportPaths ...string) bool {
 	// Load saved XL format.json and validate.
-	xl, err := loadFormatXL(storage)
+	xl, err := loadFormatXL(logger, storage)
 	if err != nil {
-		log.Errorf("loadFormatXL failed with %s", err)
+		logger.Errorf("loadFormatXL failed with %s", err)
 		return false
 	}
-	if xl.Version != "1" {
-		log.Errorf("Unsupported XL backend format found [%s]", xl.Version)
+	if xl.Version != formatXLVersion {
+		logger.Errorf("Unsupported XL backend format found [%s]", xl.Version)
 		return false
 	}
 	if len(exportPaths) != len(xl.Disks) {
-		log.Errorf("Number of disks %d passed at the command-line did not match the backend format %d", len(exportPaths), len(xl.Disks))
+		logger.Errorf("Number of disks %d passed at the command-line did not match the backend format %d", len(exportPaths), len(xl.Disks))
 		return false
 	}
+	// Disks with identities are ordered by them, paths only have to
+	// match for disks formatted without identities.
 	for index, disk := range xl.Disks {
-		if exportPaths[index] != disk {
-			log.Errorf("Invalid order of disks detected %s. Required order is %s.", exportPaths, xl.Disks)
+		if len(xl.DiskIDs) == 0 && exportPaths[index] != disk {
+			logger.Errorf("Invalid order of disks detected %s. Required order is %s.", exportPaths, xl.Disks)
 			return false
 		}
 	}
+	if dataBlocks == 0 && parityBlocks == 0 {
+		return true
+	}
+	formatData, formatParity := xl.DataBlocks, xl.ParityBlocks
+	if formatData == 0 && formatParity == 0 {
+		// Formatted before the split was recorded.
+		formatData, formatParity = len(xl.Disks)/2, len(xl.Disks)/2
+	}
+	if dataBlocks != formatData || parityBlocks != formatParity {
+		logger.Errorf("Erasure split %d data and %d parity blocks passed at the command-line did not match the backend format %d data and %d parity blocks", dataBlocks, parityBlocks, formatData, formatParity)
+		return false
+	}
 	return true
 }
 
 // newXLObjects - initialize new xl object layer.
 func newXLObjects(exportPaths ...string) (ObjectLayer, error) {
+	return newXLObjectsWithErasure(0, 0, exportPaths...)
+}
+
+// newXLObjectsWithErasure - initialize new xl object layer erasure
+// coding with dataBlocks data and parityBlocks parity blocks. The split
+// is recorded in format.json when the disks are first formatted, zero
+// values use the default N/2 split or whatever split is recorded.
+func newXLObjectsWithErasure(dataBlocks, parityBlocks int, exportPaths ...string) (ObjectLayer, error) {
+	return newXLObjectsWithOptions(dataBlocks, parityBlocks, exportPaths)
+}
+
+// newXLObjectsWithOptions - initialize new xl object layer like
+// newXLObjectsWithErasure with the optional settings opts.
+func newXLObjectsWithOptions(dataBlocks, parityBlocks int, exportPaths []string, opts ...XLOption) (ObjectLayer, error) {
+	options := xlOptions{logger: log}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	logger := options.logger
+
 	storage, err := newXL(exportPaths...)
 	if err != nil {
-		log.Errorf("newXL failed with %s", err)
+		logger.Errorf("newXL failed with %s", err)
 		return nil, err
 	}
 
@@ -81,60 +201,176 @@ func newXLObjects(exportPaths ...string) (ObjectLayer, error) {
 	// cleaning up tmp files etc.
 	initObjectLayer(storage)
 
+	// Physical disks of the XL storage, for disk identity markers.
+	xlStorage := storage.(*XL)
+	xlStorage.monitor.setLogger(logger)
+
+	// Put disks remounted at different paths back at their formatted
+	// positions, before anything erasure coded is read.
+	order, unknownDisks, err := orderDisksByID(xlStorage.storageDisks)
+	if err != nil {
+		logger.Errorf("%s", err)
+		return nil, err
+	}
+	xlStorage.reorderDisks(order)
+	storageDisks := xlStorage.storageDisks
+	diskPaths := make([]string, len(order))
+	for pos, index := range order {
+		diskPaths[pos] = exportPaths[index]
+	}
+
+	// format.json is erasure coded with the split it records, switch
+	// to it before reading it. New disks use the requested split.
+	if formatData, formatParity, ok := xlStorage.formatLayout(); ok {
+		err = xlStorage.setLayout(formatData, formatParity)
+	} else if dataBlocks != 0 || parityBlocks != 0 {
+		err = xlStorage.setLayout(dataBlocks, parityBlocks)
+	}
+	if err != nil {
+		logger.Errorf("Unable to set erasure split for %d disks, failed with %s", len(exportPaths), err)
+		return nil, err
+	}
+
 	err = checkFormat(storage)
 	if err != nil {
 		if err == errFileNotFound {
+			// Mark each disk with a new identity.
+			diskIDs, errIDs := writeDiskIDs(storageDisks)
+			if errIDs != nil {
+				logger.Errorf("writeDiskIDs failed with %s", errIDs)
+				return nil, errIDs
+			}
 			// Save new XL format.
 			errSave := saveFormatXL(storage, &xlFormat{
-				Version: "1",
-				Disks:   exportPaths,
+				Version:      formatXLVersion,
+				Disks:        diskPaths,
+				DiskIDs:      diskIDs,
+				DataBlocks:   xlStorage.DataBlocks,
+				ParityBlocks: xlStorage.ParityBlocks,
 			})
 			if errSave != nil {
-				log.Errorf("saveFormatXL failed with %s", errSave)
+				logger.Errorf("saveFormatXL failed with %s", errSave)
 				return nil, errSave
 			}
 		} else {
-			log.Errorf("Unable to check backend format %s", err)
-			if err == errReadQuorum {
-				errMsg := fmt.Sprintf("Not all disks %s are available, did not meet read quroum.", exportPaths)
-				err = errors.New(errMsg)
-			} else if err == errDiskNotFound {
-				errMsg := fmt.Sprintf("Disks %s not found.", exportPaths)
-				err = errors.New(errMsg)
-			} else if err == errVolumeAccessDenied {
-				errMsg := fmt.Sprintf("Disks %s access permission denied.", exportPaths)
-				err = errors.New(errMsg)
+			logger.Errorf("Unable to check backend format %s", err)
+			if err == errReadQuorum || err == errDiskNotFound || err == errVolumeAccessDenied {
+				err = InitError{Cause: err, Disks: exportPaths}
 			}
 			return nil, err
 		}
 	}
 
+	// Loading format.json migrates older versions, failures are
+	// reported as they are rather than as invalid arguments.
+	format, err := loadFormatXL(logger, storage)
+	if err != nil {
+		logger.Errorf("loadFormatXL failed with %s", err)
+		return nil, err
+	}
+
 	// Validate if format exists and input arguments are validated
 	// with backend format.
-	if !isValidFormat(storage, exportPaths...) {
+	if !isValidFormat(logger, storage, dataBlocks, parityBlocks, diskPaths...) {
 		return nil, fmt.Errorf("Command-line arguments %s is not valid.", exportPaths)
 	}
 
-	// Return successfully initialized object layer.
-	return xlObjects{
+	// Validate that the physical disks are at their formatted positions,
+	// a path could have been remounted with a different disk.
+	if err = updateDiskIDs(logger, xlStorage, format, diskPaths, unknownDisks); err != nil {
+		logger.Errorf("%s", err)
+		return nil, err
+	}
+
+	xl := xlObjects{
 		storage:            storage,
 		listObjectMap:      make(map[listParams][]*treeWalker),
 		listObjectMapMutex: &sync.Mutex{},
-	}, nil
+		listCursors:        newListCursors(),
+		readCoalescer:      newReadCoalescer(),
+		replicator:         newReplicator(storage, logger),
+		bandwidth:          newBandwidthAccounting(storage),
+		bucketStats:        newBucketStatsTracker(storage),
+		scheduler:          newPriorityScheduler(storageConcurrency),
+		partSizes:          newPartSizeLimits(),
+		sizeLimit:          newObjectSizeLimit(),
+		listLimit:          newListKeysLimit(),
+		contentTypes:       newContentTypes(),
+		regions:            newBucketRegions(),
+		shutdown:           newShutdownSignal(),
+		readBuffer:         newReadBufferSize(),
+		multipartCache:     newMultipartCache(),
+		bucketCache:        newBucketCache(),
+		logger:             logger,
+		metrics:            options.metrics,
+		// Negative counts are deleted one part at a time.
+		deletePartsConcurrency: options.deletePartsConcurrency,
+	}
+	if xl.metrics == nil {
+		xl.metrics = noopObjectMetrics{}
+	}
+	if xl.deletePartsConcurrency == 0 {
+		xl.deletePartsConcurrency = defaultDeletePartsConcurrency
+	}
+	maxWrites := options.maxWrites
+	if maxWrites == 0 {
+		maxWrites = defaultWritesPerDisk * len(exportPaths)
+	}
+	xl.writeLimiter = newWriteLimiter(maxWrites, options.maxQueuedWrites)
+	xl.replicator.start(xl.WithContext(WithRequestPriority(context.Background(), PriorityBackground)), xl.shutdown.done())
+	// Temp files of uploads abandoned while the server runs are only
+	// reclaimed by the sweeper, initObjectLayer clears them on start.
+	go runStaleUploadsSweeper(logger, storage, xl.shutdown.done())
+	go xl.bucketStats.run(xl.shutdown.done())
+
+	// Return successfully initialized object layer.
+	return xl, nil
 }
 
 /// Bucket operations
 
 // MakeBucket - make a bucket.
 func (xl xlObjects) MakeBucket(bucket string) error {
+	defer xl.bucketCache.forget(bucket)
 	return makeBucket(xl.storage, bucket)
 }
 
+// MakeBucketWithLocation - make a bucket in region, which must be one
+// of the allowed regions. An empty region is the default region.
+func (xl xlObjects) MakeBucketWithLocation(bucket, region string) error {
+	defer xl.bucketCache.forget(bucket)
+	return makeBucketWithLocation(xl.storage, xl.regions, bucket, region)
+}
+
+// GetBucketLocation - get the region of a bucket, us-east-1 for
+// buckets made without one.
+func (xl xlObjects) GetBucketLocation(bucket string) (string, error) {
+	return getBucketLocation(xl.storage, bucket)
+}
+
+// SetAllowedRegions - change the regions buckets can be made in, an
+// empty list allows the default region only.
+func (xl xlObjects) SetAllowedRegions(regions []string) error {
+	return xl.regions.set(regions)
+}
+
 // GetBucketInfo - get bucket info.
 func (xl xlObjects) GetBucketInfo(bucket string) (BucketInfo, error) {
 	return getBucketInfo(xl.storage, bucket)
 }
 
+// GetBucketStats - get the number of objects of a bucket and their
+// total size.
+func (xl xlObjects) GetBucketStats(bucket string) (BucketStats, error) {
+	return getBucketStats(xl, xl.storage, xl.bucketStats, bucket)
+}
+
+// BucketExists - check if a bucket exists, fails instead of answering
+// when it can't be determined.
+func (xl xlObjects) BucketExists(bucket string) (bool, error) {
+	return bucketExists(xl.storage, bucket)
+}
+
 // ListBuckets - list buckets.
 func (xl xlObjects) ListBuckets() ([]BucketInfo, error) {
 	return listBuckets(xl.storage)
@@ -142,317 +378,1675 @@ func (xl xlObjects) ListBuckets() ([]BucketInfo, error) {
 
 // DeleteBucket - delete a bucket.
 func (xl xlObjects) DeleteBucket(bucket string) error {
+	defer xl.bucketCache.forget(bucket)
+	defer xl.bucketStats.forget(bucket)
 	return deleteBucket(xl.storage, bucket)
 }
 
+// DeleteBucketForce - delete a bucket along with all its objects.
+func (xl xlObjects) DeleteBucketForce(bucket string) error {
+	defer xl.bucketCache.forget(bucket)
+	defer xl.bucketStats.forget(bucket)
+	_, err := deleteBucketForceCommon(xl, xl.storage, bucket, false)
+	return err
+}
+
+// DeleteBucketForceDryRun - return the storage paths DeleteBucketForce
+// would remove, without removing anything.
+func (xl xlObjects) DeleteBucketForceDryRun(bucket string) ([]string, error) {
+	return deleteBucketForceCommon(xl, xl.storage, bucket, true)
+}
+
+// SetBucketAllowedPrefixes - restrict object names in a bucket to the given prefixes.
+func (xl xlObjects) SetBucketAllowedPrefixes(bucket string, prefixes []string) error {
+	return setBucketAllowedPrefixes(xl.storage, bucket, prefixes)
+}
+
+// GetBucketAllowedPrefixes - get allowed object name prefixes of a bucket.
+func (xl xlObjects) GetBucketAllowedPrefixes(bucket string) ([]string, error) {
+	return getBucketAllowedPrefixes(xl.storage, bucket)
+}
+
+// ExportBucketConfig - serialize the bucket configuration.
+func (xl xlObjects) ExportBucketConfig(bucket string) ([]byte, error) {
+	return exportBucketConfig(xl.storage, bucket)
+}
+
+// ImportBucketConfig - restore a bucket configuration from ExportBucketConfig.
+func (xl xlObjects) ImportBucketConfig(bucket string, data []byte) error {
+	return importBucketConfig(xl.storage, xl.regions, bucket, data)
+}
+
+// SetBucketReplication - replicate objects of a bucket to targetBucket
+// of the registered replication target, an empty target disables it.
+func (xl xlObjects) SetBucketReplication(bucket, target, targetBucket string) error {
+	return setBucketReplication(xl.storage, bucket, target, targetBucket)
+}
+
+// SetBucketObjectLimit - reject new objects once a bucket holds
+// maxObjects objects, zero removes the limit.
+func (xl xlObjects) SetBucketObjectLimit(bucket string, maxObjects int64) error {
+	return setBucketObjectLimit(xl, xl.storage, bucket, maxObjects)
+}
+
+// SetBucketDedup - turn content deduplication of a bucket on or off,
+// objects put with identical content then share one stored copy.
+func (xl xlObjects) SetBucketDedup(bucket string, enabled bool) error {
+	return setBucketDedup(xl.storage, bucket, enabled)
+}
+
+// SetBucketVersioning - turn versioning of a bucket on or off, while
+// on overwritten and deleted objects are retained as versions.
+func (xl xlObjects) SetBucketVersioning(bucket string, enabled bool) error {
+	return setBucketVersioning(xl.storage, bucket, enabled)
+}
+
+// BucketBandwidthStats - bytes written to and read from a bucket
+// through PutObject and GetObject.
+func (xl xlObjects) BucketBandwidthStats(bucket string) (ingressBytes, egressBytes int64) {
+	return xl.bandwidth.stats(bucket)
+}
+
+// ResetBucketBandwidthStats - restart bandwidth accounting of a bucket.
+func (xl xlObjects) ResetBucketBandwidthStats(bucket string) {
+	xl.bandwidth.reset(bucket)
+}
+
+// DiskHealthScores - health of each disk by index, reads avoid disks
+// scoring below diskHealthThreshold.
+func (xl xlObjects) DiskHealthScores() map[int]float64 {
+	if reporter, ok := xl.storage.(diskHealthReporter); ok {
+		return reporter.DiskHealthScores()
+	}
+	return make(map[int]float64)
+}
+
+// DiskStatuses - runtime status of each disk by index, disks failing
+// persistently are offline and left out of quorum until they recover.
+func (xl xlObjects) DiskStatuses() map[int]DiskStatus {
+	if reporter, ok := xl.storage.(diskStatusReporter); ok {
+		return reporter.DiskStatuses()
+	}
+	return make(map[int]DiskStatus)
+}
+
+// writeReadinessChecker - storage able to tell if enough of its disks
+// are online for writes to reach write quorum.
+type writeReadinessChecker interface {
+	canWrite() (bool, error)
+}
+
+// CanWrite - reports if enough disks are online to reach write quorum
+// for the configured data and parity blocks. Read quorum can be met
+// while writes would be under replicated, so readiness to serve
+// writes is checked separately from liveness.
+func (xl xlObjects) CanWrite() (bool, error) {
+	if checker, ok := xl.storage.(writeReadinessChecker); ok {
+		return checker.canWrite()
+	}
+	return true, nil
+}
+
+// WithContext - returns the object layer scheduling its storage access
+// at the priority carried by ctx, overriding governance retention if
+// ctx allows it.
+func (xl xlObjects) WithContext(ctx context.Context) ObjectLayer {
+	xl.priority = RequestPriorityFromContext(ctx)
+	xl.bypassGovernance = GovernanceBypassFromContext(ctx)
+	return xl
+}
+
+// StorageQueueDepths - number of storage reads and writes waiting for
+// admission by priority.
+func (xl xlObjects) StorageQueueDepths() map[string]int {
+	return xl.scheduler.queueDepths()
+}
+
+// Shutdown - stops replicating objects and sweeping stale uploads in
+// background, bucket stats changes not saved yet are saved. Objects
+// still pending replication are left pending.
+func (xl xlObjects) Shutdown() error {
+	xl.shutdown.signal()
+	xl.bucketStats.flush()
+	return nil
+}
+
 /// Object Operations
 
 // GetObject - get an object.
 func (xl xlObjects) GetObject(bucket, object string, startOffset int64) (io.ReadCloser, error) {
+	return xl.GetObjectWithContext(context.Background(), bucket, object, startOffset)
+}
+
+// GetObjectWithContext - get an object, reads fail and reading of
+// further parts stops once ctx is done.
+func (xl xlObjects) GetObjectWithContext(ctx context.Context, bucket, object string, startOffset int64) (io.ReadCloser, error) {
+	if !xl.measured() {
+		return xl.getObjectWithContext(ctx, bucket, object, startOffset)
+	}
+	start := time.Now()
+	reader, err := xl.getObjectWithContext(ctx, bucket, object, startOffset)
+	stats := ObjectOpStats{Op: ObjectOpGet, Bucket: bucket, Object: object}
+	if err != nil {
+		stats.Err = err
+		xl.observe(stats, start)
+		return nil, err
+	}
+	stats.Multipart, _ = xl.isMultipart(bucket, object)
+	return &observedReadCloser{ReadCloser: reader, xl: xl, stats: stats, start: start}, nil
+}
+
+// getObjectWithContext - get an object for GetObjectWithContext.
+func (xl xlObjects) getObjectWithContext(ctx context.Context, bucket, object string, startOffset int64) (io.ReadCloser, error) {
 	// Verify if bucket is valid.
 	if !IsValidBucketName(bucket) {
 		return nil, BucketNameInvalid{Bucket: bucket}
 	}
-	if !isBucketExist(xl.storage, bucket) {
+	if !xl.isBucketExist(bucket) {
 		return nil, BucketNotFound{Bucket: bucket}
 	}
 	// Verify if object is valid.
 	if !IsValidObjectName(object) {
 		return nil, ObjectNameInvalid{Bucket: bucket, Object: object}
 	}
-	if ok, err := isMultipartObject(xl.storage, bucket, object); err != nil {
-		return nil, toObjectErr(err, bucket, object)
-	} else if !ok {
-		if _, err = xl.storage.StatFile(bucket, object); err == nil {
-			var reader io.ReadCloser
-			reader, err = xl.storage.ReadFile(bucket, object, startOffset)
-			if err != nil {
-				return nil, toObjectErr(err, bucket, object)
-			}
-			return reader, nil
-		}
-		return nil, toObjectErr(err, bucket, object)
-	}
-	fileReader, fileWriter := io.Pipe()
-	info, err := getMultipartObjectInfo(xl.storage, bucket, object)
-	if err != nil {
-		return nil, toObjectErr(err, bucket, object)
+	// Concurrent reads of small objects share a single backend read,
+	// only the first reader looks the object up and opens it.
+	key := fmt.Sprintf("%s/%s@%d", bucket, object, startOffset)
+	verify := BitRotVerificationFromContext(ctx)
+	if !verify {
+		// Only shared with readers skipping verification too.
+		key += "!"
 	}
-	partIndex, offset, err := info.GetPartNumberOffset(startOffset)
+	reader, err := xl.readCoalescer.get(ctx, key, func() (io.ReadCloser, bool, error) {
+		var read objectRead
+		err := lookupObject(bucket, object, func() (err error) {
+			read, err = xl.prepareObjectRead(bucket, object, startOffset, -1)
+			return err
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		if read.size > coalesceMaxObjectSize {
+			return xl.openRead(ctx, read), false, nil
+		}
+		// Shared with other readers, not cancelled with ctx.
+		return xl.openRead(WithBitRotVerification(context.Background(), verify), read), true, nil
+	})
 	if err != nil {
-		return nil, toObjectErr(err, bucket, object)
+		return nil, err
 	}
-	go func() {
-		for ; partIndex < len(info.Parts); partIndex++ {
-			part := info.Parts[partIndex]
-			r, err := xl.storage.ReadFile(bucket, pathJoin(object, partNumToPartFileName(part.PartNumber)), offset)
-			if err != nil {
-				fileWriter.CloseWithError(err)
-				return
-			}
-			// Reset offset to 0 as it would be non-0 only for the first loop if startOffset is non-0.
-			offset = 0
-			if _, err = io.Copy(fileWriter, r); err != nil {
-				switch reader := r.(type) {
-				case *io.PipeReader:
-					reader.CloseWithError(err)
-				case io.ReadCloser:
-					reader.Close()
-				}
-				fileWriter.CloseWithError(err)
-				return
-			}
-			// Close the readerCloser that reads multiparts of an object from the xl storage layer.
-			// Not closing leaks underlying file descriptors.
-			r.Close()
-		}
-		fileWriter.Close()
-	}()
-	return fileReader, nil
+	reader = newContextReadCloser(ctx, reader)
+	return xl.scheduler.reader(xl.bandwidth.egressReader(bucket, reader), xl.priority), nil
 }
 
-// Return the partsInfo of a special multipart object.
-func getMultipartObjectInfo(storage StorageAPI, bucket, object string) (info MultipartObjectInfo, err error) {
-	offset := int64(0)
-	r, err := storage.ReadFile(bucket, pathJoin(object, multipartMetaFile), offset)
-	if err != nil {
-		return MultipartObjectInfo{}, err
+// GetObjectRange - get length bytes of an object starting at
+// startOffset, a length of -1 reads to the end of the object.
+func (xl xlObjects) GetObjectRange(bucket, object string, startOffset, length int64) (io.ReadCloser, error) {
+	if length < 0 {
+		return xl.GetObject(bucket, object, startOffset)
 	}
-	decoder := json.NewDecoder(r)
-	err = decoder.Decode(&info)
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, BucketNameInvalid{Bucket: bucket}
+	}
+	if !xl.isBucketExist(bucket) {
+		return nil, BucketNotFound{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return nil, ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	reader, err := xl.getObject(context.Background(), bucket, object, startOffset, length)
 	if err != nil {
-		return MultipartObjectInfo{}, err
+		return nil, err
 	}
-	return info, nil
+	return xl.scheduler.reader(xl.bandwidth.egressReader(bucket, reader), xl.priority), nil
 }
 
-// Return ObjectInfo.
-func (xl xlObjects) getObjectInfo(bucket, object string) (ObjectInfo, error) {
-	// First see if the object was a simple-PUT upload.
-	fi, err := xl.storage.StatFile(bucket, object)
-	if err != nil {
-		if err != errFileNotFound {
-			return ObjectInfo{}, err
-		}
-		var info MultipartObjectInfo
-		// Check if the object was multipart upload.
-		info, err = getMultipartObjectInfo(xl.storage, bucket, object)
-		if err != nil {
-			return ObjectInfo{}, err
-		}
-		fi.Size = info.Size
-		fi.ModTime = info.ModTime
-		fi.MD5Sum = info.MD5Sum
-	}
-	contentType := "application/octet-stream"
-	if objectExt := filepath.Ext(object); objectExt != "" {
-		content, ok := mimedb.DB[strings.ToLower(strings.TrimPrefix(objectExt, "."))]
-		if ok {
-			contentType = content.ContentType
-		}
+// WriteObjectTo - write an object starting at startOffset to w,
+// returns the number of bytes written. Parts of multipart objects are
+// written to w as they are read, without a pipe in between, a failure
+// midway returns the bytes written so far along with the error.
+func (xl xlObjects) WriteObjectTo(bucket, object string, startOffset int64, w io.Writer) (int64, error) {
+	if !xl.measured() {
+		n, _, err := xl.writeObjectTo(bucket, object, startOffset, w)
+		return n, err
 	}
-	return ObjectInfo{
-		Bucket:      bucket,
-		Name:        object,
-		ModTime:     fi.ModTime,
-		Size:        fi.Size,
-		IsDir:       fi.Mode.IsDir(),
-		ContentType: contentType,
-		MD5Sum:      fi.MD5Sum,
-	}, nil
+	start := time.Now()
+	n, multipart, err := xl.writeObjectTo(bucket, object, startOffset, w)
+	xl.observe(ObjectOpStats{
+		Op:        ObjectOpGet,
+		Bucket:    bucket,
+		Object:    object,
+		Bytes:     n,
+		Multipart: multipart,
+		Err:       err,
+	}, start)
+	return n, err
 }
 
-// GetObjectInfo - get object info.
-func (xl xlObjects) GetObjectInfo(bucket, object string) (ObjectInfo, error) {
+// writeObjectTo - write an object to w for WriteObjectTo, reports if
+// it was a multipart object.
+func (xl xlObjects) writeObjectTo(bucket, object string, startOffset int64, w io.Writer) (int64, bool, error) {
 	// Verify if bucket is valid.
 	if !IsValidBucketName(bucket) {
-		return ObjectInfo{}, BucketNameInvalid{Bucket: bucket}
+		return 0, false, BucketNameInvalid{Bucket: bucket}
 	}
-	// Check whether the bucket exists.
-	if !isBucketExist(xl.storage, bucket) {
-		return ObjectInfo{}, BucketNotFound{Bucket: bucket}
+	if !xl.isBucketExist(bucket) {
+		return 0, false, BucketNotFound{Bucket: bucket}
 	}
 	// Verify if object is valid.
 	if !IsValidObjectName(object) {
-		return ObjectInfo{}, ObjectNameInvalid{Bucket: bucket, Object: object}
+		return 0, false, ObjectNameInvalid{Bucket: bucket, Object: object}
 	}
-	info, err := xl.getObjectInfo(bucket, object)
+	var read objectRead
+	err := lookupObject(bucket, object, func() (err error) {
+		read, err = xl.prepareObjectRead(bucket, object, startOffset, -1)
+		return err
+	})
 	if err != nil {
-		return ObjectInfo{}, toObjectErr(err, bucket, object)
+		return 0, false, err
 	}
-	return info, nil
+	if read.reader != nil {
+		reader := xl.scheduler.reader(xl.bandwidth.egressReader(bucket, read.reader), xl.priority)
+		defer reader.Close()
+		n, err := io.Copy(w, reader)
+		return n, false, err
+	}
+	n, err := xl.copyParts(context.Background(), read, w)
+	xl.bandwidth.addEgress(bucket, n)
+	return n, true, err
 }
 
-// PutObject - create an object.
-func (xl xlObjects) PutObject(bucket string, object string, size int64, data io.Reader, metadata map[string]string) (string, error) {
-	// Verify if bucket is valid.
-	if !IsValidBucketName(bucket) {
-		return "", BucketNameInvalid{Bucket: bucket}
-	}
-	// Check whether the bucket exists.
-	if !isBucketExist(xl.storage, bucket) {
-		return "", BucketNotFound{Bucket: bucket}
+// getObject - returns a reader reading length bytes of object data
+// directly from the backend until ctx is done, a negative length
+// reads to the end.
+func (xl xlObjects) getObject(ctx context.Context, bucket, object string, startOffset, length int64) (io.ReadCloser, error) {
+	var reader io.ReadCloser
+	err := lookupObject(bucket, object, func() (err error) {
+		reader, err = xl.openObject(ctx, bucket, object, startOffset, length)
+		return err
+	})
+	return reader, err
+}
+
+// readQuorumChecker - storage able to verify a read quorum of disks
+// agree on a file without reading it.
+type readQuorumChecker interface {
+	checkReadQuorum(volume, path string) error
+}
+
+// checkReadQuorum - verifies a read quorum of disks agree on path
+// before it is read, so that too many disks going away fails a read
+// instead of serving truncated data or reporting the object missing.
+func (xl xlObjects) checkReadQuorum(bucket, path string) error {
+	if checker, ok := xl.storage.(readQuorumChecker); ok {
+		return checker.checkReadQuorum(bucket, path)
 	}
-	if !IsValidObjectName(object) {
-		return "", ObjectNameInvalid{
-			Bucket: bucket,
-			Object: object,
-		}
+	return nil
+}
+
+// checkObjectReadQuorum - verifies a read quorum of disks agree on a
+// simple object, or on the multipart meta file of a multipart object.
+func (xl xlObjects) checkObjectReadQuorum(bucket, object string) error {
+	err := xl.checkReadQuorum(bucket, object)
+	if err != errFileNotFound {
+		return err
 	}
+	return xl.checkReadQuorum(bucket, pathJoin(object, multipartMetaFile))
+}
 
-	tempObj := path.Join(tmpMetaPrefix, bucket, object)
-	fileWriter, err := xl.storage.CreateFile(minioMetaBucket, tempObj)
+// openObject - opens the object data on the backend for getObject.
+func (xl xlObjects) openObject(ctx context.Context, bucket, object string, startOffset, length int64) (io.ReadCloser, error) {
+	read, err := xl.prepareObjectRead(bucket, object, startOffset, length)
 	if err != nil {
-		return "", toObjectErr(err, bucket, object)
+		return nil, err
 	}
+	return xl.openRead(ctx, read), nil
+}
 
-	// Initialize md5 writer.
-	md5Writer := md5.New()
+// openRead - opens a read prepared by prepareObjectRead.
+func (xl xlObjects) openRead(ctx context.Context, read objectRead) io.ReadCloser {
+	if read.reader != nil {
+		return newContextReadCloser(ctx, read.reader)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	fileReader, fileWriter := io.Pipe()
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+		_, err := xl.copyParts(ctx, read, fileWriter)
+		fileWriter.CloseWithError(err)
+	}()
+	return partsReadCloser{PipeReader: fileReader, cancel: cancel, copied: copied}
+}
 
-	// Instantiate a new multi writer.
-	multiWriter := io.MultiWriter(md5Writer, fileWriter)
+// partsReadCloser - reads the parts of a multipart object copied in
+// background, closing it stops the copy and waits for it to end.
+type partsReadCloser struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+	copied <-chan struct{}
+}
 
-	// Instantiate checksum hashers and create a multiwriter.
-	if size > 0 {
-		if _, err = io.CopyN(multiWriter, data, size); err != nil {
-			if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
-				return "", toObjectErr(clErr, bucket, object)
-			}
-			return "", toObjectErr(err, bucket, object)
+// Close - stops the copy of the parts, returns once no part is read
+// anymore.
+func (r partsReadCloser) Close() error {
+	r.cancel()
+	err := r.PipeReader.Close()
+	<-r.copied
+	return err
+}
+
+// objectRead - a read of object data prepared by prepareObjectRead,
+// either a reader of a simple object or the range of parts of a
+// multipart object to copy.
+type objectRead struct {
+	reader io.ReadCloser
+	bucket string
+	object string
+	info   MultipartObjectInfo
+	// Size of the whole object.
+	size int64
+	// Parts from partIndex to lastPartIndex are copied, starting at
+	// offset of the first. Only lastPartEnd bytes are copied from the
+	// last part, all of it if negative.
+	partIndex, lastPartIndex int
+	offset, lastPartEnd      int64
+}
+
+// prepareObjectRead - validates a read of length bytes of object data
+// starting at startOffset, a negative length reads to the end. Simple
+// objects are opened, parts of multipart objects are only checked to
+// be readable.
+func (xl xlObjects) prepareObjectRead(bucket, object string, startOffset, length int64) (objectRead, error) {
+	if ok, err := xl.isMultipart(bucket, object); err != nil {
+		return objectRead{}, toObjectErr(err, bucket, object)
+	} else if !ok {
+		// Offline disks can make a multipart object look simple.
+		if err = xl.checkObjectReadQuorum(bucket, object); err != nil {
+			return objectRead{}, toObjectErr(err, bucket, object)
 		}
-	} else {
-		if _, err = io.Copy(multiWriter, data); err != nil {
-			if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
-				return "", toObjectErr(clErr, bucket, object)
+		var fileInfo FileInfo
+		if fileInfo, err = xl.storage.StatFile(bucket, object); err == nil {
+			var objReader objectReader
+			if objReader, err = readObjectReader(xl.storage, bucket, object, fileInfo.Size); err != nil {
+				return objectRead{}, toObjectErr(err, bucket, object)
+			}
+			if startOffset < 0 || startOffset > objReader.size {
+				return objectRead{}, InvalidRange{Bucket: bucket, Object: object, Offset: startOffset, Size: objReader.size}
 			}
-			return "", toObjectErr(err, bucket, object)
+			var reader io.ReadCloser
+			reader, err = objReader.open(startOffset)
+			if err != nil {
+				return objectRead{}, toObjectErr(err, bucket, object)
+			}
+			return objectRead{reader: newLimitedReadCloser(reader, length), size: objReader.size}, nil
 		}
+		return objectRead{}, toObjectErr(err, bucket, object)
 	}
-
-	newMD5Hex := hex.EncodeToString(md5Writer.Sum(nil))
-	// md5Hex representation.
-	var md5Hex string
-	if len(metadata) != 0 {
-		md5Hex = metadata["md5Sum"]
-	}
-	if md5Hex != "" {
-		if newMD5Hex != md5Hex {
-			if err = safeCloseAndRemove(fileWriter); err != nil {
-				return "", toObjectErr(err, bucket, object)
-			}
-			return "", BadDigest{md5Hex, newMD5Hex}
+	info, err := getMultipartObjectInfo(xl.storage, bucket, object)
+	if err == errFileNotFound {
+		// The multipart status may be cached, the meta file can be
+		// gone or out of reach of too many disks since.
+		if err = xl.checkObjectReadQuorum(bucket, object); err == nil {
+			err = errFileNotFound
 		}
 	}
-	err = fileWriter.Close()
 	if err != nil {
-		if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
-			return "", toObjectErr(clErr, bucket, object)
-		}
-		return "", toObjectErr(err, bucket, object)
+		return objectRead{}, toObjectErr(err, bucket, object)
 	}
-
-	// check if an object is present as one of the parent dir.
-	if err = xl.parentDirIsObject(bucket, path.Dir(object)); err != nil {
-		return "", toObjectErr(err, bucket, object)
+	if startOffset < 0 || startOffset > info.Size {
+		return objectRead{}, InvalidRange{Bucket: bucket, Object: object, Offset: startOffset, Size: info.Size}
 	}
-
-	// Delete if an object already exists.
-	// FIXME: rename it to tmp file and delete only after
-	// the newly uploaded file is renamed from tmp location to
-	// the original location.
-	err = xl.deleteObject(bucket, object)
-	if err != nil && err != errFileNotFound {
-		return "", toObjectErr(err, bucket, object)
+	if startOffset == info.Size || length == 0 {
+		// Nothing left to read, no part holds the offset.
+		return objectRead{reader: ioutil.NopCloser(bytes.NewReader(nil)), size: info.Size}, nil
 	}
-	err = xl.storage.RenameFile(minioMetaBucket, tempObj, bucket, object)
+	partIndex, offset, err := info.GetPartNumberOffset(startOffset)
 	if err != nil {
-		if derr := xl.storage.DeleteFile(minioMetaBucket, tempObj); derr != nil {
-			return "", toObjectErr(derr, bucket, object)
+		return objectRead{}, toObjectErr(err, bucket, object)
+	}
+	// Parts after lastPartIndex are never opened, only lastPartEnd
+	// bytes are copied from the last part.
+	lastPartIndex, lastPartEnd := len(info.Parts)-1, int64(-1)
+	if length > 0 && startOffset+length < info.Size {
+		var lastOffset int64
+		lastPartIndex, lastOffset, err = info.GetPartNumberOffset(startOffset + length - 1)
+		if err != nil {
+			return objectRead{}, toObjectErr(err, bucket, object)
 		}
-		return "", toObjectErr(err, bucket, object)
+		lastPartEnd = lastOffset + 1
 	}
-
-	// Return md5sum, successfully wrote object.
-	return newMD5Hex, nil
+	for _, part := range info.Parts[partIndex : lastPartIndex+1] {
+		if err = xl.checkReadQuorum(bucket, pathJoin(object, partNumToPartFileName(part.PartNumber))); err != nil {
+			return objectRead{}, toObjectErr(err, bucket, object)
+		}
+	}
+	return objectRead{
+		bucket:        bucket,
+		object:        object,
+		info:          info,
+		size:          info.Size,
+		partIndex:     partIndex,
+		lastPartIndex: lastPartIndex,
+		offset:        offset,
+		lastPartEnd:   lastPartEnd,
+	}, nil
 }
 
-// isMultipartObject - verifies if an object is special multipart file.
-func isMultipartObject(storage StorageAPI, bucket, object string) (bool, error) {
-	_, err := storage.StatFile(bucket, pathJoin(object, multipartMetaFile))
+// copyParts - copies the parts of a multipart object read into w,
+// reading up to multipartReadAhead parts ahead, until ctx is done.
+// Returns the number of bytes written and the error ending the copy,
+// once the parts read ahead are not read anymore.
+func (xl xlObjects) copyParts(ctx context.Context, read objectRead, w io.Writer) (int64, error) {
+	// Stops read ahead of the parts once done.
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	defer func() {
+		close(done)
+		readers.Wait()
+	}()
+
+	verify := BitRotVerificationFromContext(ctx)
+	bufferSize := xl.readBuffer.forContext(ctx)
+	partIndex, offset := read.partIndex, read.offset
+	var written int64
+	// Parts being read, in order, at most multipartReadAhead parts
+	// besides the one drained into w.
+	var pending []<-chan partChunk
+	for partIndex <= read.lastPartIndex || len(pending) > 0 {
+		// Stop between parts once the reader went away.
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		for partIndex <= read.lastPartIndex && len(pending) <= multipartReadAhead {
+			part := read.info.Parts[partIndex]
+			partLength := int64(-1)
+			if partIndex == read.lastPartIndex && read.lastPartEnd >= 0 {
+				partLength = read.lastPartEnd - offset
+			}
+			pending = append(pending, xl.readPartAhead(read.bucket, read.object, part, offset, partLength, bufferSize, verify, done, &readers))
+			// Reset offset to 0 as it would be non-0 only for the first part if startOffset is non-0.
+			offset = 0
+			partIndex++
+		}
+		for {
+			var chunk partChunk
+			var ok bool
+			select {
+			case chunk, ok = <-pending[0]:
+			case <-ctx.Done():
+				return written, ctx.Err()
+			}
+			if !ok {
+				break
+			}
+			if chunk.err != nil {
+				return written, chunk.err
+			}
+			n, err := w.Write(chunk.data)
+			written += int64(n)
+			if err != nil {
+				return written, err
+			}
+		}
+		pending = pending[1:]
+	}
+	return written, nil
+}
+
+// Number of parts of a multipart object read ahead of the part being
+// returned, zero reads parts one after the other.
+var multipartReadAhead = 2
+
+// Chunks buffered per part read ahead, bounds the memory used by a
+// read to (multipartReadAhead + 1) * readAheadChunks chunks.
+const readAheadChunks = 4
+
+// partChunk - data read from a part, or the error ending the read.
+type partChunk struct {
+	data []byte
+	err  error
+}
+
+// readPartAhead - reads length bytes of a part starting at offset in
+// background, a negative length reads to the end. Chunks of up to
+// bufferSize bytes are returned in order on the channel, closed at the
+// end of the part. Reading stops early once done is closed, the part
+// is closed then even if a read of it is blocked. Parts read in full
+// are checked against their checksum if verify is set, the last chunk
+// is held back until the check passes. readers is done once the part
+// isn't read anymore.
+func (xl xlObjects) readPartAhead(bucket, object string, part MultipartPartInfo, offset, length, bufferSize int64, verify bool, done <-chan struct{}, readers *sync.WaitGroup) <-chan partChunk {
+	chunks := make(chan partChunk, readAheadChunks)
+	send := func(chunk partChunk) bool {
+		select {
+		case chunks <- chunk:
+			return true
+		case <-done:
+			return false
+		}
+	}
+	var hasher hash.Hash
+	if verify && part.Checksum != "" && offset == 0 && (length < 0 || length == part.Size) {
+		hasher = sha256.New()
+	}
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		defer close(chunks)
+		r, err := openPart(func(offset int64) (io.ReadCloser, error) {
+			return xl.storage.ReadFile(bucket, pathJoin(object, partNumToPartFileName(part.PartNumber)), offset)
+		}, part, offset)
+		if err != nil {
+			// Fails the read like opening a simple object would.
+			send(partChunk{err: toObjectErr(err, bucket, object)})
+			return
+		}
+		// Close the readerCloser that reads multiparts of an object from the xl storage layer.
+		// Not closing leaks underlying file descriptors.
+		var closeOnce sync.Once
+		closePart := func() {
+			closeOnce.Do(func() { r.Close() })
+		}
+		defer closePart()
+		// Unblocks a read of the part once done.
+		partRead, watched := make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(watched)
+			select {
+			case <-done:
+				closePart()
+			case <-partRead:
+			}
+		}()
+		defer func() {
+			close(partRead)
+			<-watched
+		}()
+		var reader io.Reader = r
+		if length >= 0 {
+			reader = io.LimitReader(r, length)
+		}
+		var total int64
+		var held []byte
+		for {
+			buf := make([]byte, bufferSize)
+			n, err := io.ReadFull(reader, buf)
+			if n > 0 {
+				total += int64(n)
+				if hasher == nil {
+					if !send(partChunk{data: buf[:n]}) {
+						return
+					}
+				} else {
+					hasher.Write(buf[:n])
+					if held != nil && !send(partChunk{data: held}) {
+						return
+					}
+					held = buf[:n]
+				}
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				if length >= 0 && total < length {
+					// Part is shorter than its metadata says.
+					send(partChunk{err: io.ErrUnexpectedEOF})
+					return
+				}
+				if hasher != nil && hex.EncodeToString(hasher.Sum(nil)) != part.Checksum {
+					send(partChunk{err: BitRotDetected{Bucket: bucket, Object: object, PartNumber: part.PartNumber}})
+					return
+				}
+				if held != nil {
+					send(partChunk{data: held})
+				}
+				return
+			}
+			if err != nil {
+				send(partChunk{err: err})
+				return
+			}
+		}
+	}()
+	return chunks
+}
+
+// Return the partsInfo of a special multipart object, fails with
+// CorruptMultipartMeta if the multipart meta file doesn't describe the
+// parts consistently.
+func getMultipartObjectInfo(storage StorageAPI, bucket, object string) (info MultipartObjectInfo, err error) {
+	info, err = readMultipartObjectInfo(storage, bucket, object)
 	if err != nil {
+		return MultipartObjectInfo{}, err
+	}
+	var size int64
+	for _, part := range info.Parts {
+		size += part.Size
+	}
+	if size != info.Size {
+		return MultipartObjectInfo{}, CorruptMultipartMeta{Bucket: bucket, Object: object}
+	}
+	return info, nil
+}
+
+// readMultipartObjectInfo - decodes the multipart meta file of an
+// object, fails with CorruptMultipartMeta if it is truncated, isn't
+// valid JSON or lists no parts or parts out of order. The size of the
+// object isn't checked against its parts.
+func readMultipartObjectInfo(storage StorageAPI, bucket, object string) (info MultipartObjectInfo, err error) {
+	offset := int64(0)
+	r, err := storage.ReadFile(bucket, pathJoin(object, multipartMetaFile), offset)
+	if err != nil {
+		return MultipartObjectInfo{}, err
+	}
+	defer r.Close()
+	decoder := json.NewDecoder(r)
+	err = decoder.Decode(&info)
+	if err != nil {
+		// A meta file cut short by a crash while it was written.
+		switch err.(type) {
+		case *json.SyntaxError, *json.UnmarshalTypeError:
+			return MultipartObjectInfo{}, CorruptMultipartMeta{Bucket: bucket, Object: object}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return MultipartObjectInfo{}, CorruptMultipartMeta{Bucket: bucket, Object: object}
+		}
+		return MultipartObjectInfo{}, err
+	}
+	if len(info.Parts) == 0 {
+		return MultipartObjectInfo{}, CorruptMultipartMeta{Bucket: bucket, Object: object}
+	}
+	for index, part := range info.Parts {
+		if part.PartNumber < 1 || part.Size < 0 || index > 0 && part.PartNumber <= info.Parts[index-1].PartNumber {
+			return MultipartObjectInfo{}, CorruptMultipartMeta{Bucket: bucket, Object: object}
+		}
+	}
+	return info, nil
+}
+
+// Save the partsInfo of a special multipart object, overwrites any
+// previous multipart meta file.
+func saveMultipartObjectInfo(storage StorageAPI, bucket, object string, info MultipartObjectInfo) error {
+	w, err := storage.CreateFile(bucket, pathJoin(object, multipartMetaFile))
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	if err = encoder.Encode(&info); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	if err = w.Close(); err != nil {
+		if clErr := safeCloseAndRemove(w); clErr != nil {
+			return clErr
+		}
+		return err
+	}
+	return nil
+}
+
+// Return ObjectInfo.
+func (xl xlObjects) getObjectInfo(bucket, object string) (ObjectInfo, error) {
+	// First see if the object was a simple-PUT upload.
+	var parts []ObjectPartInfo
+	fi, err := xl.storage.StatFile(bucket, object)
+	if err != nil {
+		if err != errFileNotFound {
+			return ObjectInfo{}, err
+		}
+		// Check if the object was multipart upload, stat the meta
+		// file first so that missing objects aren't decoded.
+		var ok bool
+		if ok, err = xl.isMultipart(bucket, object); err != nil {
+			return ObjectInfo{}, err
+		} else if !ok {
+			// Offline disks make both look missing, tell a missing
+			// object apart from one without read quorum.
+			if err = xl.checkObjectReadQuorum(bucket, object); err == nil {
+				err = errFileNotFound
+			}
+			return ObjectInfo{}, err
+		}
+		var info MultipartObjectInfo
+		info, err = getMultipartObjectInfo(xl.storage, bucket, object)
 		if err == errFileNotFound {
-			return false, nil
+			// A cached multipart status doesn't prove the meta
+			// file is still readable.
+			if err = xl.checkObjectReadQuorum(bucket, object); err == nil {
+				err = errFileNotFound
+			}
 		}
-		return false, err
+		if err != nil {
+			return ObjectInfo{}, err
+		}
+		fi.Size = info.Size
+		fi.ModTime = info.ModTime
+		fi.MD5Sum = info.MD5Sum
+		parts = objectParts(info.Parts)
 	}
-	return true, nil
+	metadata, err := readObjectMetadata(xl.storage, bucket, object)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	// Parts of multipart objects are stored as uploaded.
+	if parts == nil {
+		if fi.Size, err = objectContentSize(fi.Size, metadata); err != nil {
+			return ObjectInfo{}, err
+		}
+	}
+	// Composite ETag of a multipart object takes precedence over any
+	// saved ETag.
+	if fi.MD5Sum == "" {
+		fi.MD5Sum = metadata[etagKey]
+	}
+	return ObjectInfo{
+		Bucket:            bucket,
+		Name:              object,
+		ModTime:           fi.ModTime,
+		Size:              fi.Size,
+		IsDir:             fi.Mode.IsDir(),
+		ContentType:       getContentType(object, metadata, xl.contentTypes),
+		ContentEncoding:   metadata[contentEncodingKey],
+		ReplicationStatus: metadata[replicationStatusKey],
+		MD5Sum:            fi.MD5Sum,
+		Parts:             parts,
+		UserDefined:       userDefinedMetadata(metadata),
+		VersionID:         metadata[versionIDKey],
+	}, nil
 }
 
-// Deletes and object.
-func (xl xlObjects) deleteObject(bucket, object string) error {
-	// Verify if the object is a multipart object.
-	if ok, err := isMultipartObject(xl.storage, bucket, object); err != nil {
+// GetObjectTail - get the last n bytes of an object, all of it if the
+// object is shorter.
+func (xl xlObjects) GetObjectTail(bucket, object string, n int64) (io.ReadCloser, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, BucketNameInvalid{Bucket: bucket}
+	}
+	if !xl.isBucketExist(bucket) {
+		return nil, BucketNotFound{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return nil, ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	// The offset is computed from the size of the object read, it
+	// isn't replaced in between.
+	var read objectRead
+	err := lookupObject(bucket, object, func() error {
+		objInfo, err := xl.getObjectInfo(bucket, object)
+		if err != nil {
+			return toObjectErr(err, bucket, object)
+		}
+		startOffset, err := objectTailOffset(bucket, object, objInfo.Size, n)
+		if err != nil {
+			return err
+		}
+		read, err = xl.prepareObjectRead(bucket, object, startOffset, -1)
 		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	reader := xl.openRead(context.Background(), read)
+	return xl.scheduler.reader(xl.bandwidth.egressReader(bucket, reader), xl.priority), nil
+}
+
+// GetObjectRanges - get several ranges of an object given as
+// {startOffset, length} pairs as one stream, along with where each
+// range starts in it, such as for a multipart/byteranges response.
+func (xl xlObjects) GetObjectRanges(bucket, object string, ranges [][2]int64) (io.ReadCloser, []ObjectRangePart, error) {
+	return getObjectRangesCommon(xl, bucket, object, ranges)
+}
+
+// GetObjectWithHash - opt-in variant of GetObject for the whole
+// object, the returned reader computes the object hash while streaming.
+func (xl xlObjects) GetObjectWithHash(bucket, object string) (*ObjectHashReader, error) {
+	reader, err := xl.GetObject(bucket, object, 0)
+	if err != nil {
+		return nil, err
+	}
+	// Part boundaries are needed for the composite hash of multipart objects.
+	var partSizes []int64
+	if ok, _ := xl.isMultipart(bucket, object); ok {
+		info, err := getMultipartObjectInfo(xl.storage, bucket, object)
+		if err != nil {
+			reader.Close()
+			return nil, toObjectErr(err, bucket, object)
+		}
+		for _, part := range info.Parts {
+			partSizes = append(partSizes, part.Size)
+		}
+	}
+	return newObjectHashReader(reader, partSizes), nil
+}
+
+// GetObjectVerifiedReaderAt - random access to an object, each read
+// reconstructs and verifies only the blocks it touches and fails with
+// errCorruptData if one of them can't be verified.
+func (xl xlObjects) GetObjectVerifiedReaderAt(bucket, object string) (io.ReaderAt, int64, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, 0, BucketNameInvalid{Bucket: bucket}
+	}
+	if !xl.isBucketExist(bucket) {
+		return nil, 0, BucketNotFound{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return nil, 0, ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	if ok, err := xl.isMultipart(bucket, object); err != nil {
+		return nil, 0, toObjectErr(err, bucket, object)
 	} else if !ok {
-		if err = xl.storage.DeleteFile(bucket, object); err != nil {
-			return err
+		readerAt, size, err := openObjectReaderAt(xl.storage, bucket, object)
+		if err != nil {
+			return nil, 0, toObjectErr(err, bucket, object)
 		}
-		return nil
+		return readerAt, size, nil
 	}
-	// Get parts info.
 	info, err := getMultipartObjectInfo(xl.storage, bucket, object)
 	if err != nil {
+		return nil, 0, toObjectErr(err, bucket, object)
+	}
+	var readerAt multiReaderAt
+	for _, part := range info.Parts {
+		partReaderAt, size, err := openReaderAt(xl.storage, bucket, pathJoin(object, partNumToPartFileName(part.PartNumber)))
+		if err != nil {
+			return nil, 0, toObjectErr(err, bucket, object)
+		}
+		if part.Encryption != nil {
+			// Decrypted from the verified data.
+			storedReaderAt, storedSize := partReaderAt, size
+			partReaderAt = objectReader{
+				size: part.Size,
+				open: func(offset int64) (io.ReadCloser, error) {
+					return openPart(func(offset int64) (io.ReadCloser, error) {
+						return ioutil.NopCloser(io.NewSectionReader(storedReaderAt, offset, storedSize-offset)), nil
+					}, part, offset)
+				},
+			}
+			size = part.Size
+		}
+		readerAt.readers = append(readerAt.readers, partReaderAt)
+		readerAt.sizes = append(readerAt.sizes, size)
+	}
+	return readerAt, info.Size, nil
+}
+
+// GetObjectInfo - get object info.
+func (xl xlObjects) GetObjectInfo(bucket, object string) (ObjectInfo, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return ObjectInfo{}, BucketNameInvalid{Bucket: bucket}
+	}
+	// Check whether the bucket exists.
+	if !xl.isBucketExist(bucket) {
+		return ObjectInfo{}, BucketNotFound{Bucket: bucket}
+	}
+	return xl.objectInfo(bucket, object)
+}
+
+// GetObjectsInfo - get info of objects of a bucket, the bucket is
+// validated once for all of them. Infos and errors are returned by
+// position of the object.
+func (xl xlObjects) GetObjectsInfo(bucket string, objects []string) ([]ObjectInfo, []error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return bucketObjectsInfoErr(objects, BucketNameInvalid{Bucket: bucket})
+	}
+	// Check whether the bucket exists.
+	if !xl.isBucketExist(bucket) {
+		return bucketObjectsInfoErr(objects, BucketNotFound{Bucket: bucket})
+	}
+	return getObjectsInfo(bucket, objects, xl.objectInfo)
+}
+
+// objectInfo - get info of an object of a bucket known to exist.
+func (xl xlObjects) objectInfo(bucket, object string) (ObjectInfo, error) {
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return ObjectInfo{}, ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	var info ObjectInfo
+	err := lookupObject(bucket, object, func() (err error) {
+		info, err = xl.getObjectInfo(bucket, object)
 		return err
+	})
+	if err == errFileNotFound {
+		if prefixInfo, ok := getPrefixInfo(xl, bucket, object); ok {
+			return prefixInfo, nil
+		}
 	}
-	// Range through all files and delete it.
-	var wg = &sync.WaitGroup{}
-	var errs = make([]error, len(info.Parts))
-	for index, part := range info.Parts {
-		wg.Add(1)
-		// Start deleting parts in routine.
-		go func(index int, part MultipartPartInfo) {
-			defer wg.Done()
-			partFileName := partNumToPartFileName(part.PartNumber)
-			errs[index] = xl.storage.DeleteFile(bucket, pathJoin(object, partFileName))
-		}(index, part)
-	}
-	// Wait for all the deletes to finish.
-	wg.Wait()
-	// Loop through and validate if any errors, if we are unable to remove any part return
-	// "unexpected" error as returning any other error might be misleading. For ex.
-	// if DeleteFile() had returned errFileNotFound and we return it, then client would see
-	// ObjectNotFound which is misleading.
-	for _, err := range errs {
+	if err != nil {
+		return ObjectInfo{}, toObjectErr(err, bucket, object)
+	}
+	return info, nil
+}
+
+// GetObjectVersion - get a version of an object, an empty versionID
+// reads the current object.
+func (xl xlObjects) GetObjectVersion(bucket, object, versionID string, startOffset int64) (io.ReadCloser, error) {
+	return getObjectVersionCommon(xl, xl.storage, bucket, object, versionID, startOffset, func(versionPath string, startOffset int64) (io.ReadCloser, error) {
+		reader, err := xl.openObject(context.Background(), minioMetaBucket, versionPath, startOffset, -1)
 		if err != nil {
-			return errUnexpected
+			return nil, toObjectErr(err, bucket, object)
+		}
+		return xl.scheduler.reader(xl.bandwidth.egressReader(bucket, reader), xl.priority), nil
+	})
+}
+
+// GetObjectVersionInfo - get info of a version of an object, an empty
+// versionID is the current object.
+func (xl xlObjects) GetObjectVersionInfo(bucket, object, versionID string) (ObjectInfo, error) {
+	objInfo, _, err := getObjectVersionInfoCommon(xl, xl.storage, bucket, object, versionID)
+	return objInfo, err
+}
+
+// PutObject - create an object.
+func (xl xlObjects) PutObject(bucket string, object string, size int64, data io.Reader, metadata map[string]string) (string, error) {
+	return xl.PutObjectWithContext(context.Background(), bucket, object, size, data, metadata)
+}
+
+// PutObjectWithContext - create an object, the upload is aborted once
+// ctx is done.
+func (xl xlObjects) PutObjectWithContext(ctx context.Context, bucket string, object string, size int64, data io.Reader, metadata map[string]string) (string, error) {
+	result, err := xl.putObject(ctx, bucket, object, size, data, metadata, false, nil)
+	return result.ETag, err
+}
+
+// PutObjectWithChecksums - create an object, returns the checksums
+// requested through checksumAlgorithmKey along with its ETag.
+func (xl xlObjects) PutObjectWithChecksums(bucket string, object string, size int64, data io.Reader, metadata map[string]string) (PutObjectResult, error) {
+	return xl.putObject(context.Background(), bucket, object, size, data, metadata, false, nil)
+}
+
+// PutObjectFromFile - create an object from a file on the server, the
+// file is streamed like any other data as it's erasure coded.
+func (xl xlObjects) PutObjectFromFile(bucket, object, srcPath string, metadata map[string]string) (PutObjectResult, error) {
+	return putObjectFromFile(bucket, object, srcPath, metadata, xl.PutObjectWithChecksums)
+}
+
+// putObject - create an object, see putObjectFunc.
+func (xl xlObjects) putObject(ctx context.Context, bucket string, object string, size int64, data io.Reader, metadata map[string]string, lockHeld bool, precheck func() error) (PutObjectResult, error) {
+	if !xl.measured() {
+		return xl.writeObject(ctx, bucket, object, size, data, metadata, lockHeld, precheck)
+	}
+	start := time.Now()
+	counter := &countingReader{reader: data}
+	result, err := xl.writeObject(ctx, bucket, object, size, counter, metadata, lockHeld, precheck)
+	xl.observe(ObjectOpStats{
+		Op:               ObjectOpPut,
+		Bucket:           bucket,
+		Object:           object,
+		Bytes:            counter.n,
+		ChecksumMismatch: isChecksumMismatch(err),
+		Err:              err,
+	}, start)
+	return result, err
+}
+
+// writeObject - create an object for putObject.
+func (xl xlObjects) writeObject(ctx context.Context, bucket string, object string, size int64, data io.Reader, metadata map[string]string, lockHeld bool, precheck func() error) (PutObjectResult, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return PutObjectResult{}, BucketNameInvalid{Bucket: bucket}
+	}
+	// Check whether the bucket exists.
+	if !xl.isBucketExist(bucket) {
+		return PutObjectResult{}, BucketNotFound{Bucket: bucket}
+	}
+	if !IsValidObjectName(object) {
+		return PutObjectResult{}, ObjectNameInvalid{
+			Bucket: bucket,
+			Object: object,
 		}
 	}
-	err = xl.storage.DeleteFile(bucket, pathJoin(object, multipartMetaFile))
+	// The bucket configuration is read once for the whole write.
+	meta, err := readBucketMetadata(xl.storage, bucket)
 	if err != nil {
-		return err
+		return PutObjectResult{}, toObjectErr(err, bucket)
 	}
-	return nil
+	// Verify if object name is allowed by the bucket prefixes.
+	if err = checkObjectPrefixAllowed(meta, bucket, object); err != nil {
+		return PutObjectResult{}, err
+	}
+	if err = checkRetentionMetadata(bucket, object, metadata); err != nil {
+		return PutObjectResult{}, err
+	}
+	// A malformed Content-MD5 is rejected before any data is read.
+	md5Expected, err := getExpectedMD5(metadata)
+	if err != nil {
+		return PutObjectResult{}, err
+	}
+	hashers, err := getChecksumHashers(metadata)
+	if err != nil {
+		return PutObjectResult{}, err
+	}
+	if err = checkDurablePut(xl.storage, metadata); err != nil {
+		return PutObjectResult{}, err
+	}
+	// Fail before any data is read instead of writing an object which
+	// can't be read back once another disk goes away.
+	if ok, err := xl.CanWrite(); err != nil {
+		return PutObjectResult{}, toObjectErr(err, bucket, object)
+	} else if !ok {
+		return PutObjectResult{}, InsufficientWriteQuorum{}
+	}
+	// Objects over the size limit are refused before they are written
+	// in full, including those streamed without a known size.
+	if data, err = xl.sizeLimit.limit(bucket, object, size, data); err != nil {
+		return PutObjectResult{}, err
+	}
+	data = newContextReader(ctx, data)
+	// Bound the writes streaming to the disks at once, data isn't
+	// read until a write slot is free.
+	if err = xl.writeLimiter.acquire(ctx); err != nil {
+		return PutObjectResult{}, err
+	}
+	defer xl.writeLimiter.release()
+	// Stage data with fsync checkpoints, if requested, the staged data
+	// is stored as the object once complete.
+	if getCheckpointInterval(metadata) > 0 {
+		return putObjectCheckpointed(xl.storage, bucket, object, size, data, metadata, func(size int64, data io.Reader, metadata map[string]string) (PutObjectResult, error) {
+			return xl.storeObject(ctx, meta, bucket, object, size, data, metadata, md5Expected, hashers, lockHeld, precheck)
+		})
+	}
+	return xl.storeObject(ctx, meta, bucket, object, size, data, metadata, md5Expected, hashers, lockHeld, precheck)
+}
+
+// storeObject - stages data and puts it in place of the object, for
+// writeObject once the write is validated.
+func (xl xlObjects) storeObject(ctx context.Context, meta bucketMetadata, bucket, object string, size int64, data io.Reader, metadata map[string]string, md5Expected *expectedMD5, hashers map[string]hash.Hash, lockHeld bool, precheck func() error) (PutObjectResult, error) {
+	// Identical content is kept once in buckets with dedup set.
+	dedup := isDedupCandidate(meta, object, metadata)
+	// Concurrent writes of the object stage their data apart.
+	id, err := uuid.New()
+	if err != nil {
+		return PutObjectResult{}, err
+	}
+	tempObj := path.Join(tmpMetaPrefix, bucket, object, id.String())
+	fileWriter, err := xl.storage.CreateFile(minioMetaBucket, tempObj)
+	if err != nil {
+		return PutObjectResult{}, toObjectErr(err, bucket, object)
+	}
+
+	// Initialize md5 writer.
+	md5Writer := md5.New()
+	writers := []io.Writer{md5Writer}
+	atRest, err := newAtRestWriter(object, metadata, xl.scheduler.writer(fileWriter, xl.priority))
+	if err != nil {
+		if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
+			return PutObjectResult{}, toObjectErr(clErr, bucket, object)
+		}
+		return PutObjectResult{}, err
+	}
+	writers = append(writers, atRest)
+
+	// Initialize sha256 writer, only if there is a sha256 to verify.
+	var sha256Writer hash.Hash
+	sha256Hex := metadata["sha256Sum"]
+	if sha256Hex != "" {
+		sha256Writer = sha256.New()
+		writers = append(writers, sha256Writer)
+	}
+	// Additional checksums are computed in the same pass.
+	writers = appendChecksumWriters(writers, hashers)
+	// Dedup blobs are named after the SHA-256 of their content.
+	var dedupWriter hash.Hash
+	if dedup {
+		dedupWriter = sha256.New()
+		writers = append(writers, dedupWriter)
+	}
+
+	// Instantiate a new multi writer.
+	multiWriter := io.MultiWriter(writers...)
+
+	// Instantiate checksum hashers and create a multiwriter.
+	var n int64
+	if size > 0 {
+		n, err = io.CopyN(multiWriter, data, size)
+	} else {
+		n, err = io.Copy(multiWriter, data)
+	}
+	// Bytes received are accounted even if the upload fails.
+	xl.bandwidth.addIngress(bucket, n)
+	if err != nil {
+		if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
+			return PutObjectResult{}, toObjectErr(clErr, bucket, object)
+		}
+		return PutObjectResult{}, toObjectErr(err, bucket, object)
+	}
+
+	newMD5Hex := hex.EncodeToString(md5Writer.Sum(nil))
+	if md5Expected != nil {
+		if err = md5Expected.verify(md5Writer.Sum(nil)); err != nil {
+			if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
+				return PutObjectResult{}, toObjectErr(clErr, bucket, object)
+			}
+			return PutObjectResult{}, err
+		}
+	}
+	if sha256Writer != nil {
+		newSHA256Hex := hex.EncodeToString(sha256Writer.Sum(nil))
+		if newSHA256Hex != sha256Hex {
+			if err = safeCloseAndRemove(fileWriter); err != nil {
+				return PutObjectResult{}, toObjectErr(err, bucket, object)
+			}
+			return PutObjectResult{}, SHA256Mismatch{sha256Hex, newSHA256Hex}
+		}
+	}
+	// The ETag and checksums remain those of the uploaded data.
+	if metadata, err = atRest.finish(metadata, n); err != nil {
+		if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
+			return PutObjectResult{}, toObjectErr(clErr, bucket, object)
+		}
+		return PutObjectResult{}, toObjectErr(err, bucket, object)
+	}
+	err = fileWriter.Close()
+	if err != nil {
+		if clErr := safeCloseAndRemove(fileWriter); clErr != nil {
+			return PutObjectResult{}, toObjectErr(clErr, bucket, object)
+		}
+		return PutObjectResult{}, toObjectErr(err, bucket, object)
+	}
+	// Staged data is removed if the object isn't put in place.
+	removeStaged := func(err error) (PutObjectResult, error) {
+		if derr := xl.storage.DeleteFile(minioMetaBucket, tempObj); derr != nil && derr != errFileNotFound {
+			return PutObjectResult{}, toObjectErr(derr, bucket, object)
+		}
+		return PutObjectResult{}, err
+	}
+
+	// Serialize with other writes and deletes of the object from the
+	// checks against the current object on, the data is staged apart
+	// without the lock.
+	if !lockHeld {
+		unlock := lockObject(bucket, object)
+		defer unlock()
+	}
+	if precheck != nil {
+		if err = precheck(); err != nil {
+			return removeStaged(err)
+		}
+	}
+	// Verify if object name collides on a case-insensitive backend.
+	if err = checkKeyCollision(xl.storage, bucket, object); err != nil {
+		return removeStaged(err)
+	}
+	// Objects under legal hold or retention can't be overwritten.
+	bypassGovernance := xl.bypassGovernance || GovernanceBypassFromContext(ctx)
+	if err = checkObjectMutable(xl.storage, bucket, object, bypassGovernance); err != nil {
+		return removeStaged(err)
+	}
+	// check if an object is present as one of the parent dir.
+	if err = xl.parentDirIsObject(bucket, path.Dir(object)); err != nil {
+		return removeStaged(toObjectErr(err, bucket, object))
+	}
+	// Account the object in the bucket stats once written.
+	defer xl.bucketStats.track(xl, bucket, object)()
+	// New objects count against the bucket object limit.
+	releaseSlot, err := reserveObjectSlot(xl, xl.storage, meta, bucket, object)
+	if err != nil {
+		return removeStaged(err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			releaseSlot()
+		}
+	}()
+
+	// Objects of versioned buckets get a version ID.
+	if metadata, err = withVersionID(meta, metadata); err != nil {
+		return removeStaged(toObjectErr(err, bucket, object))
+	}
+	// A dedup blob the overwritten object references loses a reference.
+	oldRef, err := lockDedupReference(xl.storage, bucket, object)
+	if err != nil {
+		return removeStaged(toObjectErr(err, bucket, object))
+	}
+	defer oldRef.unlock()
+	releaseBlob := func() {}
+	if dedup {
+		sha256Sum := hex.EncodeToString(dedupWriter.Sum(nil))
+		if metadata, releaseBlob, err = dedupStaged(xl.storage, tempObj, n, newMD5Hex, sha256Sum, metadata); err != nil {
+			return removeStaged(toObjectErr(err, bucket, object))
+		}
+	}
+
+	// Mark the object pending replication if the bucket replicates.
+	metadata, replicate := replicationMetadata(meta, withETag(metadata, newMD5Hex))
+	// Metadata is staged next to the data and renamed in along with it.
+	tempMeta := tempObj + objectMetaSuffix
+	if err = stageObjectMetadata(xl.storage, tempMeta, metadata); err != nil {
+		releaseBlob()
+		return removeStaged(toObjectErr(err, bucket, object))
+	}
+
+	// Replace an existing object, readers find either the old or the
+	// new object throughout.
+	retained, err := xl.replaceObject(minioMetaBucket, tempObj, tempMeta, bucket, object, meta.Versioning)
+	if err != nil {
+		releaseBlob()
+		if derr := xl.storage.DeleteFile(minioMetaBucket, tempMeta); derr != nil && derr != errFileNotFound {
+			return PutObjectResult{}, toObjectErr(derr, bucket, object)
+		}
+		return removeStaged(toObjectErr(err, bucket, object))
+	}
+	committed = true
+	invalidateTreeWalks(xl, bucket, object)
+	// A retained version keeps referencing the dedup blob.
+	if !retained {
+		if err = oldRef.release(); err != nil {
+			return PutObjectResult{}, toObjectErr(err, bucket, object)
+		}
+	}
+	// Flush the object to stable storage only if asked to.
+	if isDurablePut(metadata) {
+		if err = syncObject(xl.storage, bucket, object, metadata); err != nil {
+			return PutObjectResult{}, toObjectErr(err, bucket, object)
+		}
+	}
+	if replicate {
+		xl.replicator.enqueue(replicationJob{bucket: bucket, object: object})
+	}
+
+	// Return md5sum, successfully wrote object.
+	return PutObjectResult{ETag: newMD5Hex, Checksums: checksumsHex(hashers)}, nil
+}
+
+// isMultipartObject - verifies if an object is special multipart file.
+func isMultipartObject(storage StorageAPI, bucket, object string) (bool, error) {
+	_, err := storage.StatFile(bucket, pathJoin(object, multipartMetaFile))
+	if err != nil {
+		if err == errFileNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Deletes and object, returns the files removed. A dryRun only
+// resolves the files without removing anything.
+func (xl xlObjects) deleteObject(bucket, object string, dryRun bool) (deletePlan, error) {
+	if !dryRun {
+		// Readers arriving from now on must not share in progress reads
+		// of the old object.
+		xl.readCoalescer.forget(fmt.Sprintf("%s/%s@", bucket, object))
+		defer xl.multipartCache.forget(bucket, object)
+	}
+
+	// Verify if the object is a multipart object.
+	multipart, err := xl.isMultipart(bucket, object)
+	if err != nil {
+		return deletePlan{}, err
+	}
+	plan, err := planObjectDelete(xl.storage, bucket, object, multipart)
+	if err != nil || dryRun {
+		return plan, err
+	}
+	return plan, plan.execute(xl.storage, xl.deletePartsConcurrency)
+}
+
+// GetPutObjectCheckpoint - returns the offset an interrupted
+// checkpointed PutObject can be resumed from.
+func (xl xlObjects) GetPutObjectCheckpoint(bucket, object string) (int64, error) {
+	return getPutObjectCheckpoint(xl.storage, bucket, object)
+}
+
+// ReplicateObject - queue an object for replication again, such as
+// an object whose replication failed.
+func (xl xlObjects) ReplicateObject(bucket, object string) error {
+	return replicateObject(xl, xl.storage, xl.replicator, bucket, object)
+}
+
+// CopyObject - copy an object on the server, a nil metadata keeps the
+// source metadata. Copying an object onto itself replaces only its
+// metadata.
+func (xl xlObjects) CopyObject(srcBucket, srcObject, dstBucket, dstObject string, metadata map[string]string) (ObjectInfo, error) {
+	return copyObjectCommon(xl, srcBucket, srcObject, dstBucket, dstObject, metadata)
+}
+
+// RenameObject - rename an object within a bucket without copying its
+// data, all parts of a multipart object move along. An existing
+// dstObject is replaced only if overwrite is set, it is renamed aside
+// until the object is in place.
+func (xl xlObjects) RenameObject(bucket, srcObject, dstObject string, overwrite bool) error {
+	return renameObjectCommon(xl, bucket, srcObject, dstObject, overwrite, func(srcObject, dstObject string, sidecar []byte) error {
+		// check if an object is present as one of the parent dir.
+		if err := xl.parentDirIsObject(bucket, path.Dir(dstObject)); err != nil {
+			return err
+		}
+		// Readers arriving from now on must not share in progress
+		// reads of the object at its old name.
+		xl.readCoalescer.forget(fmt.Sprintf("%s/%s@", bucket, srcObject))
+		defer xl.multipartCache.forget(bucket, srcObject)
+		// Metadata is staged and renamed in after the data, under the
+		// replace lock of dstObject.
+		id, err := uuid.New()
+		if err != nil {
+			return err
+		}
+		tempMeta := path.Join(tmpMetaPrefix, bucket, dstObject, id.String()) + objectMetaSuffix
+		if err = stageObjectSidecar(xl.storage, tempMeta, sidecar); err != nil {
+			return err
+		}
+		// A replaced object of a versioned bucket is retained by
+		// renameObjectCommon already.
+		if _, err = xl.replaceObject(bucket, srcObject, tempMeta, bucket, dstObject, false); err != nil {
+			if derr := xl.storage.DeleteFile(minioMetaBucket, tempMeta); derr != nil && derr != errFileNotFound {
+				xl.log().Errorf("Unable to delete staged metadata %s of %s/%s: %s", tempMeta, bucket, dstObject, derr)
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// FindDuplicateMoves - complete the renames interrupted by a crash,
+// keeping an object found at both names at the destination only.
+func (xl xlObjects) FindDuplicateMoves() ([]DuplicateMove, error) {
+	return findDuplicateMovesCommon(xl, func(bucket, object string) error {
+		_, err := xl.removeLockedObject(bucket, object, false)
+		return err
+	})
+}
+
+// GetObjectConditional - read an object only if its ETag and mod time
+// satisfy cond, fails with PreconditionFailed or NotModified otherwise.
+func (xl xlObjects) GetObjectConditional(bucket, object string, startOffset int64, cond ObjectConditions) (io.ReadCloser, error) {
+	return getObjectConditionalCommon(xl, bucket, object, startOffset, cond)
+}
+
+// PutObjectConditional - create or overwrite an object only if its
+// current ETag and mod time satisfy cond, fails with PreconditionFailed
+// otherwise.
+func (xl xlObjects) PutObjectConditional(bucket, object string, size int64, data io.Reader, metadata map[string]string, cond ObjectConditions) (string, error) {
+	return putObjectConditionalCommon(xl, bucket, object, size, data, metadata, cond, xl.putObject)
+}
+
+// CompareAndSwapObject - atomically replace an object only if its
+// current ETag is expectedETag, an empty expectedETag creates the
+// object only if it doesn't exist.
+func (xl xlObjects) CompareAndSwapObject(bucket, object string, expectedETag string, newData io.Reader, size int64) (string, error) {
+	return compareAndSwapObjectCommon(xl, bucket, object, expectedETag, newData, size, xl.putObject)
+}
+
+// UpdateMetadataPrefix - update metadata of all objects under prefix
+// without rewriting their data, update returns the keys to change for
+// each object, an empty value removes the key.
+func (xl xlObjects) UpdateMetadataPrefix(bucket, prefix string, update func(ObjectInfo) map[string]string, workers int) ([]MetadataUpdateResult, error) {
+	return updateMetadataPrefixCommon(xl, bucket, prefix, update, workers)
+}
+
+// PutObjectLegalHold - place or remove the legal hold of an object, an
+// object under legal hold can't be overwritten or deleted.
+func (xl xlObjects) PutObjectLegalHold(bucket, object string, on bool) error {
+	return putObjectLegalHold(xl, xl.storage, bucket, object, on)
+}
+
+// GetObjectLegalHold - get the legal hold status of an object.
+func (xl xlObjects) GetObjectLegalHold(bucket, object string) (bool, error) {
+	return getObjectLegalHold(xl, xl.storage, bucket, object)
+}
+
+// PutObjectTags - replace the tag set of an object without rewriting
+// its data.
+func (xl xlObjects) PutObjectTags(bucket, object string, tags map[string]string) error {
+	return putObjectTags(xl, xl.storage, bucket, object, tags)
+}
+
+// GetObjectTags - get the tag set of an object.
+func (xl xlObjects) GetObjectTags(bucket, object string) (map[string]string, error) {
+	return getObjectTags(xl, xl.storage, bucket, object)
+}
+
+// DeleteObjectTags - remove all tags of an object.
+func (xl xlObjects) DeleteObjectTags(bucket, object string) error {
+	return putObjectTags(xl, xl.storage, bucket, object, nil)
+}
+
+// RegisterContentType - map objects with extension ext to contentType
+// when they were saved without a content type, instead of the type
+// mimedb knows the extension by. Extensions match regardless of case,
+// an empty contentType removes the mapping.
+func (xl xlObjects) RegisterContentType(ext, contentType string) error {
+	return xl.contentTypes.register(ext, contentType)
+}
+
+// SetMaxObjectSize - change the largest object PutObject accepts, data
+// of unknown size is refused once it goes over.
+func (xl xlObjects) SetMaxObjectSize(maxSize int64) error {
+	return xl.sizeLimit.set(maxSize)
+}
+
+// MaxObjectSize - largest object PutObject accepts.
+func (xl xlObjects) MaxObjectSize() int64 {
+	return xl.sizeLimit.get()
+}
+
+// SetMaxListKeys - change the most keys a single listing returns,
+// listings asking for more are clamped and come back truncated.
+func (xl xlObjects) SetMaxListKeys(maxKeys int) error {
+	return xl.listLimit.set(maxKeys)
+}
+
+// MaxListKeys - most keys a single listing returns.
+func (xl xlObjects) MaxListKeys() int {
+	return xl.listLimit.get()
 }
 
 // DeleteObject - delete the object.
 func (xl xlObjects) DeleteObject(bucket, object string) error {
+	if !xl.measured() {
+		_, err := xl.deleteObjectCommon(bucket, object, false)
+		return err
+	}
+	start := time.Now()
+	_, err := xl.deleteObjectCommon(bucket, object, false)
+	xl.observe(ObjectOpStats{Op: ObjectOpDelete, Bucket: bucket, Object: object, Err: err}, start)
+	return err
+}
+
+// DeleteObjectDryRun - validate the delete of an object and return the
+// storage paths it would remove, without removing anything.
+func (xl xlObjects) DeleteObjectDryRun(bucket, object string) ([]string, error) {
+	return xl.deleteObjectCommon(bucket, object, true)
+}
+
+// deleteObjectCommon - validates the bucket of an object to delete.
+func (xl xlObjects) deleteObjectCommon(bucket, object string, dryRun bool) ([]string, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, BucketNameInvalid{Bucket: bucket}
+	}
+	if !xl.isBucketExist(bucket) {
+		return nil, BucketNotFound{Bucket: bucket}
+	}
+	return xl.removeObject(bucket, object, dryRun)
+}
+
+// DeleteObjects - delete objects of a bucket, the bucket is validated
+// once for all of them. Errors are returned by position of the object,
+// objects which don't exist aren't an error.
+func (xl xlObjects) DeleteObjects(bucket string, objects []string) ([]error, error) {
+	_, errs, err := xl.deleteObjectsCommon(bucket, objects, false)
+	return errs, err
+}
+
+// DeleteObjectsDryRun - validate the delete of objects of a bucket and
+// return the storage paths it would remove, without removing anything.
+func (xl xlObjects) DeleteObjectsDryRun(bucket string, objects []string) ([]string, []error, error) {
+	return xl.deleteObjectsCommon(bucket, objects, true)
+}
+
+// deleteObjectsCommon - validates the bucket of objects to delete.
+func (xl xlObjects) deleteObjectsCommon(bucket string, objects []string, dryRun bool) ([]string, []error, error) {
 	// Verify if bucket is valid.
 	if !IsValidBucketName(bucket) {
-		return BucketNameInvalid{Bucket: bucket}
+		return nil, nil, BucketNameInvalid{Bucket: bucket}
 	}
-	if !isBucketExist(xl.storage, bucket) {
-		return BucketNotFound{Bucket: bucket}
+	if !xl.isBucketExist(bucket) {
+		return nil, nil, BucketNotFound{Bucket: bucket}
 	}
+	paths, errs := deleteObjects(bucket, objects, dryRun, xl.removeObject)
+	return paths, errs, nil
+}
+
+// removeObject - delete an object of a bucket known to exist, returns
+// the storage paths removed. A dryRun runs all the checks of a delete
+// and returns the paths it would remove.
+func (xl xlObjects) removeObject(bucket, object string, dryRun bool) ([]string, error) {
 	if !IsValidObjectName(object) {
-		return ObjectNameInvalid{Bucket: bucket, Object: object}
+		return nil, ObjectNameInvalid{Bucket: bucket, Object: object}
 	}
-	if err := xl.deleteObject(bucket, object); err != nil {
-		return toObjectErr(err, bucket, object)
+	if !dryRun {
+		// Serialize with other writes and deletes of the object.
+		unlock := lockObject(bucket, object)
+		defer unlock()
 	}
-	return nil
+	return xl.removeLockedObject(bucket, object, dryRun)
+}
+
+// removeLockedObject - removeObject past taking the object write lock,
+// for callers holding it already.
+func (xl xlObjects) removeLockedObject(bucket, object string, dryRun bool) ([]string, error) {
+	meta, err := readBucketMetadata(xl.storage, bucket)
+	if err != nil {
+		return nil, toObjectErr(err, bucket)
+	}
+	if err = checkObjectPrefixAllowed(meta, bucket, object); err != nil {
+		return nil, err
+	}
+	// Objects under legal hold or retention can't be deleted.
+	if err = checkObjectMutable(xl.storage, bucket, object, xl.bypassGovernance); err != nil {
+		return nil, err
+	}
+	var ref dedupReference
+	if !dryRun {
+		// Account the deletion in the bucket stats.
+		defer xl.bucketStats.track(xl, bucket, object)()
+		// The dedup blob of the object, if any, loses a reference.
+		if ref, err = lockDedupReference(xl.storage, bucket, object); err != nil {
+			return nil, toObjectErr(err, bucket, object)
+		}
+		defer ref.unlock()
+	}
+	if meta.Versioning {
+		// The object is retained along with its dedup blob reference,
+		// nothing is removed.
+		if !dryRun {
+			xl.readCoalescer.forget(fmt.Sprintf("%s/%s@", bucket, object))
+			defer xl.multipartCache.forget(bucket, object)
+		}
+		if err = retainDeletedObject(xl, xl.storage, bucket, object, dryRun); err != nil || dryRun {
+			return nil, err
+		}
+		invalidateTreeWalks(xl, bucket, object)
+		if err = releaseObjectSlot(xl.storage, meta, bucket); err != nil {
+			return nil, toObjectErr(err, bucket, object)
+		}
+		return nil, nil
+	}
+	plan, err := xl.deleteObject(bucket, object, dryRun)
+	if err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	if dryRun {
+		return plan.paths(), nil
+	}
+	invalidateTreeWalks(xl, bucket, object)
+	if err = ref.release(); err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	if err = releaseObjectSlot(xl.storage, meta, bucket); err != nil {
+		return nil, toObjectErr(err, bucket, object)
+	}
+	return plan.paths(), nil
 }
 
 // ListObjects - list all objects at prefix, delimited by '/'.
 func (xl xlObjects) ListObjects(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error) {
-	return listObjectsCommon(xl, bucket, prefix, marker, delimiter, maxKeys)
+	if !xl.measured() {
+		return listObjectsCommon(xl, bucket, prefix, marker, delimiter, maxKeys, false)
+	}
+	start := time.Now()
+	result, err := listObjectsCommon(xl, bucket, prefix, marker, delimiter, maxKeys, false)
+	xl.observe(ObjectOpStats{
+		Op:     ObjectOpList,
+		Bucket: bucket,
+		Object: prefix,
+		Keys:   len(result.Objects) + len(result.Prefixes),
+		Err:    err,
+	}, start)
+	return result, err
+}
+
+// ListObjectsWithMetadata - opt-in variant of ListObjects which also
+// returns the content type, ETag and tags of the objects, at the cost
+// of reading the metadata of each one.
+func (xl xlObjects) ListObjectsWithMetadata(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error) {
+	return listObjectsCommon(xl, bucket, prefix, marker, delimiter, maxKeys, true)
+}
+
+// ListObjectVersions - list the versions and delete markers of the
+// objects at prefix, in key order and newest first per key.
+func (xl xlObjects) ListObjectVersions(bucket, prefix, keyMarker, versionIDMarker string, maxKeys int) (ListObjectVersionsInfo, error) {
+	return listObjectVersionsCommon(xl, bucket, prefix, keyMarker, versionIDMarker, maxKeys)
+}
+
+// ListObjectShards - split keys at prefix into ranges listable in parallel.
+func (xl xlObjects) ListObjectShards(bucket, prefix string, numShards int) ([]KeyRange, error) {
+	return listObjectShardsCommon(xl, bucket, prefix, numShards)
+}
+
+// ListObjectsV2 - list objects with continuation tokens instead of
+// markers.
+func (xl xlObjects) ListObjectsV2(bucket, prefix, continuationToken, startAfter, delimiter string, maxKeys int) (ListObjectsV2Info, error) {
+	return listObjectsV2Common(xl, bucket, prefix, continuationToken, startAfter, delimiter, maxKeys)
+}
+
+// OpenListCursor - open a listing of bucket kept on the server, pages
+// are read with ListNext until the cursor is closed or expires.
+func (xl xlObjects) OpenListCursor(bucket, prefix, delimiter string) (string, error) {
+	return openListCursorCommon(xl, bucket, prefix, delimiter)
+}
+
+// ListNext - list the next maxKeys entries of a list cursor.
+func (xl xlObjects) ListNext(cursorID string, maxKeys int) (ListObjectsInfo, error) {
+	return listNextCommon(xl, cursorID, maxKeys)
+}
+
+// CloseListCursor - close a list cursor, releasing its tree walk.
+func (xl xlObjects) CloseListCursor(cursorID string) error {
+	return closeListCursorCommon(xl, cursorID)
+}
+
+// ListObjectsFiltered - recursively list objects under prefix matching
+// filter, starting after marker.
+func (xl xlObjects) ListObjectsFiltered(bucket, prefix, marker string, filter func(name string) bool, maxKeys int) (ListObjectsInfo, error) {
+	return listObjectsFilteredCommon(xl, bucket, prefix, marker, filter, maxKeys)
+}
+
+// ListObjectsGlob - recursively list objects under prefix whose name
+// after prefix matches pattern, starting after marker. Supports '*'
+// and '?' wildcards.
+func (xl xlObjects) ListObjectsGlob(bucket, prefix, marker, pattern string, maxKeys int) (ListObjectsInfo, error) {
+	return listObjectsFilteredCommon(xl, bucket, prefix, marker, globFilter(prefix, pattern), maxKeys)
+}
+
+// WalkObjects - streams the objects under prefix recursively until the
+// walk ends or ctx is cancelled.
+func (xl xlObjects) WalkObjects(ctx context.Context, bucket, prefix string) (<-chan ObjectInfo, <-chan error) {
+	return walkObjectsCommon(ctx, xl, bucket, prefix)
+}
+
+// CoalescedReads - returns the number of GetObject calls which shared
+// an in progress backend read with a concurrent GetObject.
+func (xl xlObjects) CoalescedReads() int64 {
+	return xl.readCoalescer.coalescedReads()
 }